@@ -51,6 +51,8 @@ type ClientService interface {
 
 	CreateRule(params *CreateRuleParams) (*CreateRuleCreated, error)
 
+	CreateScheduledQuery(params *CreateScheduledQueryParams) (*CreateScheduledQueryCreated, error)
+
 	DeletePolicies(params *DeletePoliciesParams) (*DeletePoliciesOK, error)
 
 	GetEnabledPolicies(params *GetEnabledPoliciesParams) (*GetEnabledPoliciesOK, error)
@@ -59,14 +61,20 @@ type ClientService interface {
 
 	GetRule(params *GetRuleParams) (*GetRuleOK, error)
 
+	GetScheduledQuery(params *GetScheduledQueryParams) (*GetScheduledQueryOK, error)
+
 	ListPolicies(params *ListPoliciesParams) (*ListPoliciesOK, error)
 
 	ListRules(params *ListRulesParams) (*ListRulesOK, error)
 
+	ListScheduledQueries(params *ListScheduledQueriesParams) (*ListScheduledQueriesOK, error)
+
 	ModifyPolicy(params *ModifyPolicyParams) (*ModifyPolicyOK, error)
 
 	ModifyRule(params *ModifyRuleParams) (*ModifyRuleOK, error)
 
+	ModifyScheduledQuery(params *ModifyScheduledQueryParams) (*ModifyScheduledQueryOK, error)
+
 	Suppress(params *SuppressParams) (*SuppressOK, error)
 
 	TestPolicy(params *TestPolicyParams) (*TestPolicyOK, error)
@@ -176,6 +184,40 @@ func (a *Client) CreateRule(params *CreateRuleParams) (*CreateRuleCreated, error
 	panic(msg)
 }
 
+/*
+  CreateScheduledQuery creates a new scheduled query
+*/
+func (a *Client) CreateScheduledQuery(params *CreateScheduledQueryParams) (*CreateScheduledQueryCreated, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewCreateScheduledQueryParams()
+	}
+
+	result, err := a.transport.Submit(&runtime.ClientOperation{
+		ID:                 "CreateScheduledQuery",
+		Method:             "POST",
+		PathPattern:        "/query",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"https"},
+		Params:             params,
+		Reader:             &CreateScheduledQueryReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+	success, ok := result.(*CreateScheduledQueryCreated)
+	if ok {
+		return success, nil
+	}
+	// unexpected success response
+	// safeguard: normally, absent a default response, unknown success responses return an error above: so this is a codegen issue
+	msg := fmt.Sprintf("unexpected success response for CreateScheduledQuery: API contract not enforced by server. Client expected to get an error, but got: %T", result)
+	panic(msg)
+}
+
 /*
   DeletePolicies deletes one or more policies rules
 */
@@ -312,6 +354,40 @@ func (a *Client) GetRule(params *GetRuleParams) (*GetRuleOK, error) {
 	panic(msg)
 }
 
+/*
+  GetScheduledQuery gets scheduled query details
+*/
+func (a *Client) GetScheduledQuery(params *GetScheduledQueryParams) (*GetScheduledQueryOK, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewGetScheduledQueryParams()
+	}
+
+	result, err := a.transport.Submit(&runtime.ClientOperation{
+		ID:                 "GetScheduledQuery",
+		Method:             "GET",
+		PathPattern:        "/query",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"https"},
+		Params:             params,
+		Reader:             &GetScheduledQueryReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+	success, ok := result.(*GetScheduledQueryOK)
+	if ok {
+		return success, nil
+	}
+	// unexpected success response
+	// safeguard: normally, absent a default response, unknown success responses return an error above: so this is a codegen issue
+	msg := fmt.Sprintf("unexpected success response for GetScheduledQuery: API contract not enforced by server. Client expected to get an error, but got: %T", result)
+	panic(msg)
+}
+
 /*
   ListPolicies pages through policies in a customer s account
 */
@@ -380,6 +456,40 @@ func (a *Client) ListRules(params *ListRulesParams) (*ListRulesOK, error) {
 	panic(msg)
 }
 
+/*
+  ListScheduledQueries pages through scheduled queries in a customer s account
+*/
+func (a *Client) ListScheduledQueries(params *ListScheduledQueriesParams) (*ListScheduledQueriesOK, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewListScheduledQueriesParams()
+	}
+
+	result, err := a.transport.Submit(&runtime.ClientOperation{
+		ID:                 "ListScheduledQueries",
+		Method:             "GET",
+		PathPattern:        "/query/list",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"https"},
+		Params:             params,
+		Reader:             &ListScheduledQueriesReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+	success, ok := result.(*ListScheduledQueriesOK)
+	if ok {
+		return success, nil
+	}
+	// unexpected success response
+	// safeguard: normally, absent a default response, unknown success responses return an error above: so this is a codegen issue
+	msg := fmt.Sprintf("unexpected success response for ListScheduledQueries: API contract not enforced by server. Client expected to get an error, but got: %T", result)
+	panic(msg)
+}
+
 /*
   ModifyPolicy modifies an existing policy
 */
@@ -448,6 +558,40 @@ func (a *Client) ModifyRule(params *ModifyRuleParams) (*ModifyRuleOK, error) {
 	panic(msg)
 }
 
+/*
+  ModifyScheduledQuery modifies an existing scheduled query
+*/
+func (a *Client) ModifyScheduledQuery(params *ModifyScheduledQueryParams) (*ModifyScheduledQueryOK, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewModifyScheduledQueryParams()
+	}
+
+	result, err := a.transport.Submit(&runtime.ClientOperation{
+		ID:                 "ModifyScheduledQuery",
+		Method:             "POST",
+		PathPattern:        "/query/update",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"https"},
+		Params:             params,
+		Reader:             &ModifyScheduledQueryReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+	success, ok := result.(*ModifyScheduledQueryOK)
+	if ok {
+		return success, nil
+	}
+	// unexpected success response
+	// safeguard: normally, absent a default response, unknown success responses return an error above: so this is a codegen issue
+	msg := fmt.Sprintf("unexpected success response for ModifyScheduledQuery: API contract not enforced by server. Client expected to get an error, but got: %T", result)
+	panic(msg)
+}
+
 /*
   Suppress suppresses resource patterns across one or more policies
 */
@@ -41,6 +41,7 @@ type Config struct {
 	Tags                      []string          `yaml:"Tags"`
 	Tests                     []Test            `yaml:"Tests"`
 	DedupPeriodMinutes        int               `yaml:"DedupPeriodMinutes"`
+	Threshold                 int               `yaml:"Threshold"`
 }
 
 // Test is a unit test definition when parsing policies in a bulk upload.
@@ -43,6 +43,9 @@ const (
 
 	// AnalysisTypeRULE captures enum value "RULE"
 	AnalysisTypeRULE AnalysisType = "RULE"
+
+	// AnalysisTypeSCHEDULEDQUERY captures enum value "SCHEDULED_QUERY"
+	AnalysisTypeSCHEDULEDQUERY AnalysisType = "SCHEDULED_QUERY"
 )
 
 // for schema
@@ -50,7 +53,7 @@ var analysisTypeEnum []interface{}
 
 func init() {
 	var res []AnalysisType
-	if err := json.Unmarshal([]byte(`["POLICY","RULE"]`), &res); err != nil {
+	if err := json.Unmarshal([]byte(`["POLICY","RULE","SCHEDULED_QUERY"]`), &res); err != nil {
 		panic(err)
 	}
 	for _, v := range res {
@@ -37,6 +37,9 @@ type EnabledPolicy struct {
 	// body
 	Body Body `json:"body,omitempty"`
 
+	// dedup columns
+	DedupColumns DedupColumns `json:"dedupColumns,omitempty"`
+
 	// dedup period minutes
 	DedupPeriodMinutes DedupPeriodMinutes `json:"dedupPeriodMinutes,omitempty"`
 
@@ -46,9 +49,15 @@ type EnabledPolicy struct {
 	// resource types
 	ResourceTypes TypeSet `json:"resourceTypes,omitempty"`
 
+	// schedule
+	Schedule Schedule `json:"schedule,omitempty"`
+
 	// severity
 	Severity Severity `json:"severity,omitempty"`
 
+	// sql
+	SQL SQL `json:"sql,omitempty"`
+
 	// suppressions
 	Suppressions Suppressions `json:"suppressions,omitempty"`
 
@@ -64,6 +73,10 @@ func (m *EnabledPolicy) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateDedupColumns(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateDedupPeriodMinutes(formats); err != nil {
 		res = append(res, err)
 	}
@@ -76,10 +89,18 @@ func (m *EnabledPolicy) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateSchedule(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateSeverity(formats); err != nil {
 		res = append(res, err)
 	}
 
+	if err := m.validateSQL(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateSuppressions(formats); err != nil {
 		res = append(res, err)
 	}
@@ -110,6 +131,22 @@ func (m *EnabledPolicy) validateBody(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *EnabledPolicy) validateDedupColumns(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.DedupColumns) { // not required
+		return nil
+	}
+
+	if err := m.DedupColumns.Validate(formats); err != nil {
+		if ve, ok := err.(*errors.Validation); ok {
+			return ve.ValidateName("dedupColumns")
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (m *EnabledPolicy) validateDedupPeriodMinutes(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.DedupPeriodMinutes) { // not required
@@ -158,6 +195,22 @@ func (m *EnabledPolicy) validateResourceTypes(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *EnabledPolicy) validateSchedule(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Schedule) { // not required
+		return nil
+	}
+
+	if err := m.Schedule.Validate(formats); err != nil {
+		if ve, ok := err.(*errors.Validation); ok {
+			return ve.ValidateName("schedule")
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (m *EnabledPolicy) validateSeverity(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.Severity) { // not required
@@ -174,6 +227,22 @@ func (m *EnabledPolicy) validateSeverity(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *EnabledPolicy) validateSQL(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.SQL) { // not required
+		return nil
+	}
+
+	if err := m.SQL.Validate(formats); err != nil {
+		if ve, ok := err.(*errors.Validation); ok {
+			return ve.ValidateName("sql")
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (m *EnabledPolicy) validateSuppressions(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.Suppressions) { // not required
@@ -101,6 +101,10 @@ type Rule struct {
 	// Required: true
 	Tests TestSuite `json:"tests"`
 
+	// threshold
+	// Required: true
+	Threshold Threshold `json:"threshold"`
+
 	// version Id
 	// Required: true
 	VersionID VersionID `json:"versionId"`
@@ -174,6 +178,10 @@ func (m *Rule) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateThreshold(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateVersionID(formats); err != nil {
 		res = append(res, err)
 	}
@@ -388,6 +396,18 @@ func (m *Rule) validateTests(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Rule) validateThreshold(formats strfmt.Registry) error {
+
+	if err := m.Threshold.Validate(formats); err != nil {
+		if ve, ok := err.(*errors.Validation); ok {
+			return ve.ValidateName("threshold")
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (m *Rule) validateVersionID(formats strfmt.Registry) error {
 
 	if err := m.VersionID.Validate(formats); err != nil {
@@ -22,10 +22,38 @@ import "time"
 
 // LambdaInput is the request structure for the alerts-api Lambda function.
 type LambdaInput struct {
-	GetAlert   *GetAlertInput   `json:"getAlert"`
-	ListAlerts *ListAlertsInput `json:"listAlerts"`
+	GetAlert          *GetAlertInput          `json:"getAlert"`
+	ListAlerts        *ListAlertsInput        `json:"listAlerts"`
+	UpdateAlertStatus *UpdateAlertStatusInput `json:"updateAlertStatus"`
+	AssignAlert       *AssignAlertInput       `json:"assignAlert"`
+	AddAlertComment   *AddAlertCommentInput   `json:"addAlertComment"`
+	ListAlertActivity *ListAlertActivityInput `json:"listAlertActivity"`
+	CreateSuppression *CreateSuppressionInput `json:"createSuppression"`
+	ListSuppressions  *ListSuppressionsInput  `json:"listSuppressions"`
+	ExpireSuppression *ExpireSuppressionInput `json:"expireSuppression"`
+	GetIncident       *GetIncidentInput       `json:"getIncident"`
+	ListIncidents     *ListIncidentsInput     `json:"listIncidents"`
+	GetAlertAnalytics *GetAlertAnalyticsInput `json:"getAlertAnalytics"`
+	ResendAlert       *ResendAlertInput       `json:"resendAlert"`
 }
 
+// Alert triage states, alerts without a status are open
+const (
+	StatusOpen          = "OPEN"
+	StatusTriaged       = "TRIAGED"
+	StatusClosed        = "CLOSED"
+	StatusFalsePositive = "FALSE_POSITIVE"
+	// Set by the alert forwarder on alerts matching an active suppression, these alerts are not delivered
+	StatusSuppressed = "SUPPRESSED"
+)
+
+// Alert activity types
+const (
+	ActivityStatus  = "STATUS"
+	ActivityAssign  = "ASSIGN"
+	ActivityComment = "COMMENT"
+)
+
 // GetAlertInput retrieves details for a single alert.
 //
 // The response will contain by definition all of the events associated with the alert.
@@ -46,15 +74,22 @@ type GetAlertInput struct {
 // GetAlertOutput retrieves details for a single alert.
 type GetAlertOutput = Alert
 
-// ListAlertsInput lists the alerts in reverse-chronological order (newest to oldest)
+// ListAlertsInput lists the alerts, by default in reverse-chronological order (newest to oldest)
 // If "ruleId" is not set, we return all the alerts for the organization
 // If the "exclusiveStartKey" is not set, we return alerts starting from the most recent one. If it is set,
 // the output will return alerts starting from the "exclusiveStartKey" exclusive.
 //
+// The alerts can be filtered and sorted by creation or update time. The time range of the sort field bounds the
+// alerts read, the other filters are applied to the alerts in the time range.
+//
 //
 // {
 //     "listAlerts": {
 //         "ruleId": "My.Rule",
+//         "severity": ["HIGH", "CRITICAL"],
+//         "status": ["OPEN"],
+//         "createdAtAfter": "2020-04-01T00:00:00Z",
+//         "sortBy": "updateTime",
 //         "pageSize": 25
 //     }
 // }
@@ -62,12 +97,32 @@ type ListAlertsInput struct {
 	RuleID            *string `json:"ruleId,omitempty"`
 	PageSize          *int    `json:"pageSize,omitempty"  validate:"omitempty,min=1,max=50"`
 	ExclusiveStartKey *string `json:"exclusiveStartKey,omitempty"`
+
+	// Filtering
+	Severity        []*string  `json:"severity,omitempty" validate:"omitempty,dive,oneof=INFO LOW MEDIUM HIGH CRITICAL"`
+	Status          []*string  `json:"status,omitempty" validate:"omitempty,dive,oneof=OPEN TRIAGED CLOSED FALSE_POSITIVE SUPPRESSED"`
+	LogType         *string    `json:"logType,omitempty" validate:"omitempty,min=1"`
+	CreatedAtAfter  *time.Time `json:"createdAtAfter,omitempty"`
+	CreatedAtBefore *time.Time `json:"createdAtBefore,omitempty"`
+	UpdatedAtAfter  *time.Time `json:"updatedAtAfter,omitempty"`
+	UpdatedAtBefore *time.Time `json:"updatedAtBefore,omitempty"`
+	EventCountMin   *int       `json:"eventCountMin,omitempty" validate:"omitempty,min=1"`
+	TitleContains   *string    `json:"titleContains,omitempty" validate:"omitempty,min=1,max=200"` // case-sensitive
+
+	// Sorting
+	SortBy  *string `json:"sortBy,omitempty" validate:"omitempty,oneof=creationTime updateTime"` // defaults to creationTime
+	SortDir *string `json:"sortDir,omitempty" validate:"omitempty,oneof=ascending descending"`   // defaults to descending
 }
 
+// Alert sort fields
+const (
+	SortByCreationTime = "creationTime"
+	SortByUpdateTime   = "updateTime"
+)
+
 // ListAlertsOutput is the returned alert list.
 type ListAlertsOutput struct {
-	// Alerts is a list of alerts sorted by timestamp descending.
-	// Alerts with the same timestamp are returned in ascending order of alert ID.
+	// Alerts is a list of alerts sorted by the sort field.
 	Alerts []*AlertSummary `json:"alertSummaries"`
 	// LastEvaluatedKey contains the last evaluated alert Id.
 	// If it is populated it means there are more alerts available
@@ -87,6 +142,15 @@ type AlertSummary struct {
 	EventsMatched   *int       `json:"eventsMatched" validate:"required"`
 	Severity        *string    `json:"severity" validate:"required"`
 	Title           *string    `json:"title" validate:"required"`
+	Status          *string    `json:"status" validate:"required"`
+	AssigneeID      *string    `json:"assigneeId,omitempty"`
+	// The user who last changed the status or the assignee, and when
+	LastUpdatedBy     *string    `json:"lastUpdatedBy,omitempty"`
+	LastUpdatedByTime *time.Time `json:"lastUpdatedByTime,omitempty"`
+	// The suppression which matched the alert when it was created
+	SuppressionID *string `json:"suppressionId,omitempty"`
+	// The incident the alert was correlated into
+	IncidentID *string `json:"incidentId,omitempty"`
 }
 
 // Alert contains the details of an alert
@@ -94,4 +158,318 @@ type Alert struct {
 	AlertSummary
 	Events                 []*string `json:"events" validate:"required"`
 	EventsLastEvaluatedKey *string   `json:"eventsLastEvaluatedKey,omitempty"`
+	// The attempts to send the alert to its outputs, oldest first
+	DeliveryResponses []*DeliveryResponse `json:"deliveryResponses" validate:"required"`
+}
+
+// DeliveryResponse is the outcome of an attempt to send an alert to an output.
+type DeliveryResponse struct {
+	OutputID    *string    `json:"outputId" validate:"required"`
+	DeliveredAt *time.Time `json:"deliveredAt" validate:"required"`
+	Success     *bool      `json:"success" validate:"required"`
+	// The HTTP status of the response of the output, set if the output responded with an error
+	StatusCode *int    `json:"statusCode,omitempty"`
+	Error      *string `json:"error,omitempty"`
+	NeedsRetry *bool   `json:"needsRetry" validate:"required"`
+	RetryCount *int    `json:"retryCount" validate:"required"`
+}
+
+// ResendAlertInput sends an alert again to some of its outputs. The outcome is added to the delivery responses of
+// the alert. The event samples are those of the first notification, alerts stored before they were kept have none.
+//
+// Example:
+// {
+//     "resendAlert": {
+//         "alertId": "6e9cd8a4d6f5fcbfd4e2ef0a9efdf5a2",
+//         "outputIds": ["8c1b7b0e-3f0f-4c4e-9f57-3a5c8e2d1b6a"],
+//         "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7"
+//     }
+// }
+type ResendAlertInput struct {
+	AlertID   *string   `json:"alertId" validate:"required,hexadecimal,len=32"`
+	OutputIDs []*string `json:"outputIds" validate:"required,min=1,dive,uuid4"`
+	UserID    *string   `json:"userId" validate:"required,uuid4"`
+}
+
+// ResendAlertOutput is the alert which was queued for delivery.
+type ResendAlertOutput = AlertSummary
+
+// UpdateAlertStatusInput sets the triage status of an alert.
+//
+// Example:
+// {
+//     "updateAlertStatus": {
+//         "alertId": "6e9cd8a4d6f5fcbfd4e2ef0a9efdf5a2",
+//         "status": "TRIAGED",
+//         "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7"
+//     }
+// }
+type UpdateAlertStatusInput struct {
+	AlertID *string `json:"alertId" validate:"required,hexadecimal,len=32"`
+	Status  *string `json:"status" validate:"required,oneof=OPEN TRIAGED CLOSED FALSE_POSITIVE"`
+	UserID  *string `json:"userId" validate:"required,uuid4"`
+}
+
+// UpdateAlertStatusOutput is the updated alert.
+type UpdateAlertStatusOutput = AlertSummary
+
+// AssignAlertInput assigns an alert to a user, the alert is unassigned if "assigneeId" is not set.
+//
+// Example:
+// {
+//     "assignAlert": {
+//         "alertId": "6e9cd8a4d6f5fcbfd4e2ef0a9efdf5a2",
+//         "assigneeId": "3a4e5b6c-1d2e-4f3a-8b4c-5d6e7f8a9b0c",
+//         "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7"
+//     }
+// }
+type AssignAlertInput struct {
+	AlertID    *string `json:"alertId" validate:"required,hexadecimal,len=32"`
+	AssigneeID *string `json:"assigneeId,omitempty" validate:"omitempty,uuid4"`
+	UserID     *string `json:"userId" validate:"required,uuid4"`
+}
+
+// AssignAlertOutput is the updated alert.
+type AssignAlertOutput = AlertSummary
+
+// AddAlertCommentInput adds a comment to the activity of an alert.
+//
+// Example:
+// {
+//     "addAlertComment": {
+//         "alertId": "6e9cd8a4d6f5fcbfd4e2ef0a9efdf5a2",
+//         "comment": "Expected, this is the weekly backup job",
+//         "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7"
+//     }
+// }
+type AddAlertCommentInput struct {
+	AlertID *string `json:"alertId" validate:"required,hexadecimal,len=32"`
+	Comment *string `json:"comment" validate:"required,min=1,max=10000"`
+	UserID  *string `json:"userId" validate:"required,uuid4"`
+}
+
+// AddAlertCommentOutput is the added activity.
+type AddAlertCommentOutput = AlertActivity
+
+// ListAlertActivityInput lists the activity of an alert in chronological order (oldest to newest).
+//
+// Example:
+// {
+//     "listAlertActivity": {
+//         "alertId": "6e9cd8a4d6f5fcbfd4e2ef0a9efdf5a2",
+//         "pageSize": 25
+//     }
+// }
+type ListAlertActivityInput struct {
+	AlertID           *string `json:"alertId" validate:"required,hexadecimal,len=32"`
+	PageSize          *int    `json:"pageSize,omitempty" validate:"omitempty,min=1,max=50"`
+	ExclusiveStartKey *string `json:"exclusiveStartKey,omitempty"`
+}
+
+// ListAlertActivityOutput is a page of the activity of an alert.
+type ListAlertActivityOutput struct {
+	Activity []*AlertActivity `json:"activity"`
+	// LastEvaluatedKey is set if there is more activity
+	LastEvaluatedKey *string `json:"lastEvaluatedKey,omitempty"`
+}
+
+// AlertActivity is an entry of the append-only activity log of an alert
+type AlertActivity struct {
+	AlertID   *string    `json:"alertId" validate:"required"`
+	Type      *string    `json:"type" validate:"required"`
+	UserID    *string    `json:"userId" validate:"required"`
+	CreatedAt *time.Time `json:"createdAt" validate:"required"`
+	// Set for STATUS activity
+	Status *string `json:"status,omitempty"`
+	// Set for ASSIGN activity, unset if the alert was unassigned
+	AssigneeID *string `json:"assigneeId,omitempty"`
+	// Set for COMMENT activity
+	Comment *string `json:"comment,omitempty"`
+}
+
+// CreateSuppressionInput snoozes the alerts of a rule, or of a single dedup string of the rule,
+// until "expiresAt" or for "durationMinutes".
+//
+// Example:
+// {
+//     "createSuppression": {
+//         "ruleId": "AWS.CloudTrail.ConsoleLoginFailed",
+//         "dedupString": "admin",
+//         "durationMinutes": 120,
+//         "reason": "Password rotation change window",
+//         "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7"
+//     }
+// }
+type CreateSuppressionInput struct {
+	RuleID          *string    `json:"ruleId" validate:"required,min=1"`
+	DedupString     *string    `json:"dedupString,omitempty" validate:"omitempty,min=1"` // all alerts of the rule if not set
+	ExpiresAt       *time.Time `json:"expiresAt,omitempty" validate:"required_without=DurationMinutes"`
+	DurationMinutes *int       `json:"durationMinutes,omitempty" validate:"omitempty,min=1,max=525600"` // up to a year
+	Reason          *string    `json:"reason" validate:"required,min=1,max=1000"`
+	UserID          *string    `json:"userId" validate:"required,uuid4"`
+}
+
+// CreateSuppressionOutput is the created suppression.
+type CreateSuppressionOutput = Suppression
+
+// ListSuppressionsInput lists the active suppressions, soonest to expire first.
+//
+// Example:
+// {
+//     "listSuppressions": {
+//         "ruleId": "AWS.CloudTrail.ConsoleLoginFailed",
+//         "includeExpired": true
+//     }
+// }
+type ListSuppressionsInput struct {
+	RuleID         *string `json:"ruleId,omitempty" validate:"omitempty,min=1"` // all rules if not set
+	IncludeExpired *bool   `json:"includeExpired,omitempty"`
+}
+
+// ListSuppressionsOutput is the list of suppressions.
+type ListSuppressionsOutput struct {
+	Suppressions []*Suppression `json:"suppressions"`
+}
+
+// ExpireSuppressionInput ends an active suppression now.
+//
+// Example:
+// {
+//     "expireSuppression": {
+//         "ruleId": "AWS.CloudTrail.ConsoleLoginFailed",
+//         "suppressionId": "0b0d4b53-8a8e-4c4a-9a3a-58ad1a6f5e4c",
+//         "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7"
+//     }
+// }
+type ExpireSuppressionInput struct {
+	RuleID        *string `json:"ruleId" validate:"required,min=1"`
+	SuppressionID *string `json:"suppressionId" validate:"required,uuid4"`
+	UserID        *string `json:"userId" validate:"required,uuid4"`
+}
+
+// ExpireSuppressionOutput is the expired suppression.
+type ExpireSuppressionOutput = Suppression
+
+// Suppression stops the delivery of the alerts of a rule, or of a single dedup string of the rule, until it expires
+type Suppression struct {
+	SuppressionID *string    `json:"suppressionId" validate:"required"`
+	RuleID        *string    `json:"ruleId" validate:"required"`
+	DedupString   *string    `json:"dedupString,omitempty"`
+	Reason        *string    `json:"reason" validate:"required"`
+	CreatedBy     *string    `json:"createdBy" validate:"required"`
+	CreatedAt     *time.Time `json:"createdAt" validate:"required"`
+	ExpiresAt     *time.Time `json:"expiresAt" validate:"required"`
+	// Set if the suppression was expired early
+	ExpiredBy *string `json:"expiredBy,omitempty"`
+}
+
+// GetIncidentInput retrieves an incident with the summaries of its alerts.
+//
+// Example:
+// {
+//     "getIncident": {
+//         "incidentId": "0f6e6c1f8a0d2d7b1c6ad0a3e5b7f7a4"
+//     }
+// }
+type GetIncidentInput struct {
+	IncidentID *string `json:"incidentId" validate:"required,hexadecimal,len=32"` // IncidentID is an MD5 hash
+}
+
+// GetIncidentOutput is the incident with its alerts.
+type GetIncidentOutput = Incident
+
+// ListIncidentsInput lists the incidents in reverse-chronological order (newest to oldest).
+//
+// Example:
+// {
+//     "listIncidents": {
+//         "pageSize": 25
+//     }
+// }
+type ListIncidentsInput struct {
+	PageSize          *int    `json:"pageSize,omitempty" validate:"omitempty,min=1,max=50"`
+	ExclusiveStartKey *string `json:"exclusiveStartKey,omitempty"`
+}
+
+// ListIncidentsOutput is a page of incidents.
+type ListIncidentsOutput struct {
+	Incidents []*IncidentSummary `json:"incidentSummaries"`
+	// LastEvaluatedKey is set if there are more incidents
+	LastEvaluatedKey *string `json:"lastEvaluatedKey,omitempty"`
+}
+
+// IncidentSummary groups the alerts of different rules which shared entities within the correlation window
+type IncidentSummary struct {
+	IncidentID   *string    `json:"incidentId" validate:"required"`
+	CreationTime *time.Time `json:"creationTime" validate:"required"`
+	UpdateTime   *time.Time `json:"updateTime" validate:"required"`
+	// The highest severity of the alerts
+	Severity *string   `json:"severity" validate:"required"`
+	AlertIDs []*string `json:"alertIds" validate:"required"`
+	RuleIDs  []*string `json:"ruleIds" validate:"required"`
+	// The entities of all the alerts
+	IPAddresses    []*string `json:"ipAddresses"`
+	AWSAccountIDs  []*string `json:"awsAccountIds"`
+	AWSInstanceIDs []*string `json:"awsInstanceIds"`
+}
+
+// Incident contains the details of an incident
+type Incident struct {
+	IncidentSummary
+	Alerts []*AlertSummary `json:"alertSummaries" validate:"required"`
+}
+
+// Alert analytics groupings and intervals
+const (
+	GroupByRuleID   = "ruleId"
+	GroupBySeverity = "severity"
+	GroupByLogType  = "logType"
+
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+)
+
+// GetAlertAnalyticsInput counts the alerts created in a time range, and their events, by time interval and group.
+//
+// Example:
+// {
+//     "getAlertAnalytics": {
+//         "createdAtAfter": "2020-04-01T00:00:00Z",
+//         "createdAtBefore": "2020-05-01T00:00:00Z",
+//         "interval": "day",
+//         "groupBy": "severity",
+//         "topRules": 5
+//     }
+// }
+type GetAlertAnalyticsInput struct {
+	CreatedAtAfter  *time.Time `json:"createdAtAfter" validate:"required"`
+	CreatedAtBefore *time.Time `json:"createdAtBefore" validate:"required,gtfield=CreatedAtAfter"`
+	Interval        *string    `json:"interval" validate:"required,oneof=hour day"`
+	GroupBy         *string    `json:"groupBy" validate:"required,oneof=ruleId severity logType"`
+	TopRules        *int       `json:"topRules,omitempty" validate:"omitempty,min=1,max=100"` // defaults to 10
+}
+
+// GetAlertAnalyticsOutput has the alert counts of each interval of the time range, and the rules with the most alerts.
+type GetAlertAnalyticsOutput struct {
+	Buckets  []*AlertAnalyticsBucket `json:"buckets"`
+	TopRules []*AlertCount           `json:"topRules"`
+	Total    *AlertCount             `json:"total"`
+	// The number of alerts closed or marked as false positive, and the mean time from their creation to their
+	// resolution
+	ResolvedCount            *int     `json:"resolvedCount"`
+	MeanTimeToResolveSeconds *float64 `json:"meanTimeToResolveSeconds,omitempty"`
+}
+
+// AlertAnalyticsBucket has the alert counts of an interval, by group
+type AlertAnalyticsBucket struct {
+	StartTime *time.Time    `json:"startTime"`
+	Groups    []*AlertCount `json:"groups"`
+}
+
+// AlertCount is the number of alerts of a group and the number of events matched by them.
+// Alerts with events of several log types are counted in the group of each log type.
+type AlertCount struct {
+	Key        *string `json:"key,omitempty"`
+	AlertCount *int    `json:"alertCount"`
+	EventCount *int    `json:"eventCount"`
 }
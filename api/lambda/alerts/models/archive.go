@@ -0,0 +1,61 @@
+package models
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers/timestamp"
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+// AlertsTable is the Glue table of the archived alerts
+var AlertsTable = awsglue.NewAlertsTableMetadata(
+	"alerts", "Log alerts archived from the panther-log-alert-info table after their retention", &ArchivedAlert{})
+
+// ArchivedAlert is an alert written to S3 as a JSON line
+type ArchivedAlert struct {
+	AlertID           string             `json:"id" validate:"required" description:"The id of the alert"`
+	RuleID            string             `json:"ruleId" validate:"required" description:"The id of the rule or scheduled query"`
+	RuleVersion       string             `json:"ruleVersion" description:"The version of the rule"`
+	RuleDisplayName   *string            `json:"ruleDisplayName,omitempty" description:"The display name of the rule"`
+	Title             *string            `json:"title,omitempty" description:"The title of the alert"`
+	DedupString       string             `json:"dedup" description:"The deduplication string of the alert"`
+	CreationTime      *timestamp.RFC3339 `json:"creationTime" validate:"required" description:"The time the alert was created"`
+	UpdateTime        *timestamp.RFC3339 `json:"updateTime" description:"The time of the last event of the alert"`
+	Severity          string             `json:"severity" description:"The severity of the alert"`
+	EventCount        int                `json:"eventCount" description:"The number of events of the alert"`
+	LogTypes          []string           `json:"logTypes" description:"The log types of the events of the alert"`
+	Status            string             `json:"status,omitempty" description:"The triage status of the alert"`
+	AssigneeID        *string            `json:"assigneeId,omitempty" description:"The user the alert is assigned to"`
+	LastUpdatedBy     *string            `json:"lastUpdatedBy,omitempty" description:"The last user who triaged the alert"`
+	LastUpdatedByTime *timestamp.RFC3339 `json:"lastUpdatedByTime,omitempty" description:"The last time the alert was triaged"`
+	ResolvedAt        *timestamp.RFC3339 `json:"resolvedAt,omitempty" description:"The time the alert was closed or marked as false positive"`
+	SuppressionID     *string            `json:"suppressionId,omitempty" description:"The suppression of the alert"`
+	IncidentID        *string            `json:"incidentId,omitempty" description:"The incident of the alert"`
+	Activity          []ArchivedActivity `json:"activity,omitempty" description:"The triage activity of the alert, oldest first"`
+}
+
+// ArchivedActivity is an entry of the activity log of an archived alert
+type ArchivedActivity struct {
+	Type       string             `json:"type" description:"The type of the activity: STATUS, ASSIGN or COMMENT"`
+	UserID     string             `json:"userId" description:"The user who triaged the alert"`
+	CreatedAt  *timestamp.RFC3339 `json:"createdAt" description:"The time of the activity"`
+	Status     *string            `json:"status,omitempty" description:"The status the alert was set to"`
+	AssigneeID *string            `json:"assigneeId,omitempty" description:"The user the alert was assigned to"`
+	Comment    *string            `json:"comment,omitempty" description:"The comment added to the alert"`
+}
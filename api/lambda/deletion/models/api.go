@@ -0,0 +1,141 @@
+package models
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import "time"
+
+// Deletion states
+const (
+	DeletionQuerying  = "querying"  // finding the objects with the indicator
+	DeletionDeleting  = "deleting"  // rewriting the objects without the matching rows
+	DeletionSucceeded = "succeeded" // the signed report is available
+	DeletionFailed    = "failed"
+)
+
+// LambdaInput is the request structure for the data-deletion Lambda function.
+type LambdaInput struct {
+	StartDataDeletion *StartDataDeletionInput `json:"startDataDeletion"`
+	GetDataDeletion   *GetDataDeletionInput   `json:"getDataDeletion"`
+	// ProcessDataDeletions is sent every minute by a CloudWatch schedule
+	ProcessDataDeletions *ProcessDataDeletionsInput `json:"processDataDeletions"`
+}
+
+// StartDataDeletionInput starts deleting the rows of the log and rule match tables with the indicator in their
+// p_any fields and an event time in [startTime, endTime). It returns without waiting.
+//
+// Example:
+//
+//	{
+//	    "startDataDeletion": {
+//	        "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7",
+//	        "indicatorType": "ip_address",
+//	        "indicator": "192.0.2.10",
+//	        "startTime": "2020-01-01T00:00:00Z",
+//	        "endTime": "2020-04-01T00:00:00Z",
+//	        "reason": "GDPR request 1234"
+//	    }
+//	}
+type StartDataDeletionInput struct {
+	UserID *string `json:"userId" validate:"required,uuid4"`
+	// nolint(lll)
+	IndicatorType *string    `json:"indicatorType" validate:"required,oneof=ip_address domain_name sha1_hash md5_hash aws_account_id aws_instance_id aws_arn aws_tag"`
+	Indicator     *string    `json:"indicator" validate:"required,min=1,max=2048"`
+	StartTime     *time.Time `json:"startTime" validate:"required"`
+	EndTime       *time.Time `json:"endTime" validate:"required,gtfield=StartTime"`
+	Reason        *string    `json:"reason" validate:"required,min=1,max=1024"`
+}
+
+// StartDataDeletionOutput is the started deletion.
+type StartDataDeletionOutput = DataDeletion
+
+// GetDataDeletionInput returns a deletion, with a link to its report once it succeeded.
+type GetDataDeletionInput struct {
+	DeletionID *string `json:"deletionId" validate:"required,uuid4"`
+}
+
+// GetDataDeletionOutput is the deletion.
+type GetDataDeletionOutput = DataDeletion
+
+// ProcessDataDeletionsInput advances the running deletions.
+type ProcessDataDeletionsInput struct{}
+
+// DataDeletion describes a deletion and its progress
+type DataDeletion struct {
+	DeletionID    *string    `json:"deletionId" validate:"required"`
+	UserID        *string    `json:"userId" validate:"required"`
+	IndicatorType *string    `json:"indicatorType" validate:"required"`
+	Indicator     *string    `json:"indicator" validate:"required"`
+	StartTime     *time.Time `json:"startTime" validate:"required"`
+	EndTime       *time.Time `json:"endTime" validate:"required"`
+	Reason        *string    `json:"reason" validate:"required"`
+	CreatedAt     *time.Time `json:"createdAt" validate:"required"`
+	CompletedAt   *time.Time `json:"completedAt,omitempty"`
+	// Status is one of querying, deleting, succeeded or failed
+	Status *string `json:"status" validate:"required"`
+	// Error explains why a deletion failed
+	Error *string `json:"error,omitempty"`
+	// ObjectCount is the number of objects with matching rows, known once deleting
+	ObjectCount *int64 `json:"objectCount,omitempty"`
+	DeletedRows *int64 `json:"deletedRows,omitempty"`
+	// ReportURL is a presigned link to the report of a succeeded deletion, valid for an hour
+	ReportURL *string `json:"reportUrl,omitempty"`
+	// ReportSignature is the base64 encoded KMS signature of the SHA-256 digest of the report
+	ReportSignature *string `json:"reportSignature,omitempty"`
+	// SigningKeyID is the KMS key to verify the signature with
+	SigningKeyID *string `json:"signingKeyId,omitempty"`
+}
+
+// DeletionReport is the record of a succeeded deletion, it is signed with a KMS key
+type DeletionReport struct {
+	DeletionID    string           `json:"deletionId"`
+	RequestedBy   string           `json:"requestedBy"`
+	Reason        string           `json:"reason"`
+	IndicatorType string           `json:"indicatorType"`
+	Indicator     string           `json:"indicator"`
+	StartTime     time.Time        `json:"startTime"`
+	EndTime       time.Time        `json:"endTime"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	CompletedAt   time.Time        `json:"completedAt"`
+	DeletedRows   int64            `json:"deletedRows"`
+	Objects       []*DeletedObject `json:"objects"`
+	// PurgedPrefixes are the partitions of the objects whose noncurrent versions were deleted, they include the
+	// objects merged by the compactor
+	PurgedPrefixes []string `json:"purgedPrefixes"`
+	// OutOfScope describes the copies of the data the deletion does not cover
+	OutOfScope string `json:"outOfScope"`
+}
+
+// DeletionOutOfScope is the OutOfScope of the reports
+const DeletionOutOfScope = "Only the log and rule match tables of the processed data bucket are covered. " +
+	"Copies outside of it are not: query results and query result exports in the Athena results bucket " +
+	"and the outputs of retrohunts. Objects archived in Glacier by the log retention settings are not covered either: " +
+	"deletions reaching them are rejected and archived objects fail the deletion."
+
+// DeletedObject is an object of the data lake that had matching rows
+type DeletedObject struct {
+	Path        string `json:"path"`
+	Rows        int64  `json:"rows"` // before the deletion
+	DeletedRows int64  `json:"deletedRows"`
+	// Removed is true if all rows matched and the object was deleted
+	Removed bool `json:"removed"`
+	// Missing is true if the object was gone when it was rewritten, such as merged by the compactor.
+	// The current objects of its partition were rewritten instead, they are listed as well.
+	Missing bool `json:"missing,omitempty"`
+	Done    bool `json:"done"`
+}
@@ -37,4 +37,16 @@ type GeneralSettings struct {
 	DisplayName           *string `json:"displayName" validate:"omitempty,min=1"`
 	Email                 *string `genericapi:"redact" json:"email" validate:"omitempty,email"`
 	ErrorReportingConsent *bool   `json:"errorReportingConsent"`
+
+	// Replaces the retention of all log types, the processed data of other log types is kept forever.
+	LogRetention []*LogRetention `json:"logRetention" validate:"omitempty,dive,required"`
+}
+
+// LogRetention defines how long the processed data of a log type is kept.
+//
+// Data transitioned to Glacier can no longer be queried with Athena.
+type LogRetention struct {
+	LogType     *string `json:"logType" validate:"required,min=1"`
+	ExpireDays  *int64  `json:"expireDays" validate:"required,min=1"`
+	GlacierDays *int64  `json:"glacierDays" validate:"omitempty,min=1"` // must be less than ExpireDays
 }
@@ -0,0 +1,354 @@
+package models
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import "time"
+
+// Query states
+const (
+	QueryRunning   = "running"
+	QuerySucceeded = "succeeded"
+	QueryFailed    = "failed"
+	QueryCancelled = "cancelled"
+)
+
+// LambdaInput is the request structure for the query-api Lambda function.
+type LambdaInput struct {
+	ExecuteQuery    *ExecuteQueryInput    `json:"executeQuery"`
+	GetQueryStatus  *GetQueryStatusInput  `json:"getQueryStatus"`
+	GetQueryResults *GetQueryResultsInput `json:"getQueryResults"`
+	CancelQuery     *CancelQueryInput     `json:"cancelQuery"`
+	ListQueries     *ListQueriesInput     `json:"listQueries"`
+
+	ExportQueryResults *ExportQueryResultsInput `json:"exportQueryResults"`
+	GetQueryExport     *GetQueryExportInput     `json:"getQueryExport"`
+	RunQueryExport     *RunQueryExportInput     `json:"runQueryExport"`
+
+	SearchIndicators          *SearchIndicatorsInput          `json:"searchIndicators"`
+	GetIndicatorSearchResults *GetIndicatorSearchResultsInput `json:"getIndicatorSearchResults"`
+
+	QueryEntityTimeline      *QueryEntityTimelineInput      `json:"queryEntityTimeline"`
+	GetEntityTimelineResults *GetEntityTimelineResultsInput `json:"getEntityTimelineResults"`
+}
+
+// ExecuteQueryInput starts a read-only query against one of the Panther databases, it returns without waiting.
+//
+// Only single SELECT, WITH, SHOW and DESCRIBE statements are allowed.
+// Example:
+// {
+//     "executeQuery": {
+//         "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7",
+//         "databaseName": "panther_logs",
+//         "sql": "SELECT * FROM aws_cloudtrail WHERE year=2020 AND month=4 LIMIT 10"
+//     }
+// }
+type ExecuteQueryInput struct {
+	UserID       *string `json:"userId" validate:"required,uuid4"`
+	DatabaseName *string `json:"databaseName" validate:"required,oneof=panther_logs panther_views"`
+	SQL          *string `json:"sql" validate:"required,min=1,max=262144"`
+}
+
+// ExecuteQueryOutput is the status of the started query.
+type ExecuteQueryOutput = QueryStatus
+
+// GetQueryStatusInput polls the status of a query of the user.
+type GetQueryStatusInput struct {
+	UserID  *string `json:"userId" validate:"required,uuid4"`
+	QueryID *string `json:"queryId" validate:"required,uuid"`
+}
+
+// GetQueryStatusOutput is the status of the query.
+type GetQueryStatusOutput = QueryStatus
+
+// GetQueryResultsInput returns a page of the results of a succeeded query of the user.
+//
+// If "paginationToken" is not set the first page is returned, the token of the next page
+// is returned with the results.
+// Example:
+// {
+//     "getQueryResults": {
+//         "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7",
+//         "queryId": "6b8ab9f1-16b6-4cc8-9bd6-35e5a1b4b3e3",
+//         "pageSize": 100
+//     }
+// }
+type GetQueryResultsInput struct {
+	UserID          *string `json:"userId" validate:"required,uuid4"`
+	QueryID         *string `json:"queryId" validate:"required,uuid"`
+	PageSize        *int64  `json:"pageSize,omitempty" validate:"omitempty,min=1,max=1000"`
+	PaginationToken *string `json:"paginationToken,omitempty"`
+}
+
+// GetQueryResultsOutput has the query status and, if the query succeeded, a page of results.
+type GetQueryResultsOutput struct {
+	QueryStatus
+	Columns []*Column `json:"columns"`
+	// Rows holds the values of each row in the order of the columns, null values are nil
+	Rows [][]*string `json:"rows"`
+	// PaginationToken is set if there are more results
+	PaginationToken *string `json:"paginationToken,omitempty"`
+}
+
+// CancelQueryInput stops a running query of the user.
+type CancelQueryInput struct {
+	UserID  *string `json:"userId" validate:"required,uuid4"`
+	QueryID *string `json:"queryId" validate:"required,uuid"`
+}
+
+// CancelQueryOutput is the status of the query after cancelling it.
+type CancelQueryOutput = QueryStatus
+
+// ListQueriesInput lists the queries of the user, newest first.
+//
+// {
+//     "listQueries": {
+//         "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7",
+//         "pageSize": 25
+//     }
+// }
+type ListQueriesInput struct {
+	UserID            *string `json:"userId" validate:"required,uuid4"`
+	PageSize          *int    `json:"pageSize,omitempty" validate:"omitempty,min=1,max=50"`
+	ExclusiveStartKey *string `json:"exclusiveStartKey,omitempty"`
+}
+
+// ListQueriesOutput is a page of the query history of the user.
+type ListQueriesOutput struct {
+	Queries []*QueryStatus `json:"queries"`
+	// LastEvaluatedKey is set if there are more queries
+	LastEvaluatedKey *string `json:"lastEvaluatedKey,omitempty"`
+}
+
+// Export formats
+const (
+	ExportCSV     = "csv"
+	ExportJSONL   = "jsonl"
+	ExportParquet = "parquet"
+)
+
+// ExportQueryResultsInput writes all the results of a succeeded query of the user to the results bucket and
+// returns time-limited download links. Every export is recorded with the user who made it.
+//
+// Exports return while running and getQueryExport returns the links once done. CSV and JSON lines exports are
+// written from the stored results of the query, "compress" gzips them. Parquet exports run the query again with
+// an Athena UNLOAD: the query reads the data as it is when exporting, so the exported rows can differ from the
+// results of the query, e.g. when new data arrived or the query uses now() or an unordered LIMIT.
+//
+// The links are signed with the temporary credentials of the API and stop working when they expire: the link
+// expiration is capped at their remaining lifetime, or at an hour when they do not report it, and getQueryExport
+// returns new links. Values of CSV exports starting with =, +, - or @ are prefixed with ' so that spreadsheets
+// do not evaluate them as formulas.
+// Example:
+// {
+//     "exportQueryResults": {
+//         "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7",
+//         "queryId": "6b8ab9f1-16b6-4cc8-9bd6-35e5a1b4b3e3",
+//         "format": "csv",
+//         "compress": true,
+//         "linkExpirationMinutes": 60
+//     }
+// }
+type ExportQueryResultsInput struct {
+	UserID                *string `json:"userId" validate:"required,uuid4"`
+	QueryID               *string `json:"queryId" validate:"required,uuid"`
+	Format                *string `json:"format" validate:"required,oneof=csv jsonl parquet"`
+	Compress              bool    `json:"compress"`
+	LinkExpirationMinutes *int64  `json:"linkExpirationMinutes,omitempty" validate:"omitempty,min=1,max=720"`
+}
+
+// ExportQueryResultsOutput is the started or finished export.
+type ExportQueryResultsOutput = QueryExport
+
+// GetQueryExportInput returns an export of the user with new download links.
+type GetQueryExportInput struct {
+	UserID                *string `json:"userId" validate:"required,uuid4"`
+	ExportID              *string `json:"exportId" validate:"required,uuid4"`
+	LinkExpirationMinutes *int64  `json:"linkExpirationMinutes,omitempty" validate:"omitempty,min=1,max=720"`
+}
+
+// GetQueryExportOutput is the export.
+type GetQueryExportOutput = QueryExport
+
+// RunQueryExportInput writes the files of a CSV or JSON lines export, the query API invokes itself
+// asynchronously with it when the export is recorded.
+type RunQueryExportInput struct {
+	ExportID *string `json:"exportId" validate:"required,uuid4"`
+}
+
+// QueryExport describes the exported results of a query
+type QueryExport struct {
+	ExportID   *string    `json:"exportId" validate:"required"`
+	QueryID    *string    `json:"queryId" validate:"required"`
+	Format     *string    `json:"format" validate:"required"`
+	Compressed bool       `json:"compressed"`
+	CreatedAt  *time.Time `json:"createdAt" validate:"required"`
+	// Status is one of running, succeeded or failed
+	Status *string `json:"status" validate:"required"`
+	// Error explains why an export failed
+	Error *string `json:"error,omitempty"`
+	// RowCount is the number of exported rows, it is not known for Parquet exports, whose rows can differ
+	// from the results of the query
+	RowCount *int64 `json:"rowCount,omitempty"`
+	// Files can be downloaded until the links expire, Parquet exports can have several files
+	Files          []*ExportFile `json:"files"`
+	LinkExpiration *time.Time    `json:"linkExpiration,omitempty"`
+}
+
+// ExportFile is a file of exported results
+type ExportFile struct {
+	URL       *string `json:"url" validate:"required"`
+	SizeBytes *int64  `json:"sizeBytes" validate:"required"`
+}
+
+// SearchIndicatorsInput starts a query for the indicators across all log tables with the matching p_any fields,
+// it returns without waiting. The query is part of the query history of the user.
+//
+// Example:
+// {
+//     "searchIndicators": {
+//         "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7",
+//         "indicators": {
+//             "ipAddresses": ["192.0.2.10"],
+//             "domainNames": ["malicious.example.com"]
+//         },
+//         "startTime": "2020-04-01T00:00:00Z",
+//         "endTime": "2020-04-08T00:00:00Z"
+//     }
+// }
+type SearchIndicatorsInput struct {
+	UserID     *string     `json:"userId" validate:"required,uuid4"`
+	Indicators *Indicators `json:"indicators" validate:"required"`
+	StartTime  *time.Time  `json:"startTime" validate:"required"`
+	EndTime    *time.Time  `json:"endTime" validate:"required,gtfield=StartTime"`
+}
+
+// Indicators to search for, grouped by the p_any field they are matched against. At least one is required.
+type Indicators struct {
+	IPAddresses   []string `json:"ipAddresses,omitempty" validate:"max=100,dive,ip"`
+	DomainNames   []string `json:"domainNames,omitempty" validate:"max=100,dive,fqdn"`
+	SHA1Hashes    []string `json:"sha1Hashes,omitempty" validate:"max=100,dive,hexadecimal,len=40"`
+	MD5Hashes     []string `json:"md5Hashes,omitempty" validate:"max=100,dive,hexadecimal,len=32"`
+	AWSARNs       []string `json:"awsArns,omitempty" validate:"max=100,dive,startswith=arn:,max=2048"`
+	AWSAccountIDs []string `json:"awsAccountIds,omitempty" validate:"max=100,dive,numeric,len=12"`
+}
+
+// SearchIndicatorsOutput is the status of the started search.
+type SearchIndicatorsOutput = QueryStatus
+
+// GetIndicatorSearchResultsInput returns a page of the hits of a succeeded indicator search of the user.
+type GetIndicatorSearchResultsInput struct {
+	UserID          *string `json:"userId" validate:"required,uuid4"`
+	QueryID         *string `json:"queryId" validate:"required,uuid"`
+	PageSize        *int64  `json:"pageSize,omitempty" validate:"omitempty,min=1,max=1000"`
+	PaginationToken *string `json:"paginationToken,omitempty"`
+}
+
+// GetIndicatorSearchResultsOutput has the search status and, if the search succeeded, a page of hits grouped by log type.
+//
+// Hits are ordered by log type so a log type can continue on the next page.
+type GetIndicatorSearchResultsOutput struct {
+	QueryStatus
+	LogTypes []*LogTypeHits `json:"logTypes"`
+	// PaginationToken is set if there are more hits
+	PaginationToken *string `json:"paginationToken,omitempty"`
+}
+
+// LogTypeHits are the events of a log type that matched the indicators, newest first
+type LogTypeHits struct {
+	LogType *string         `json:"logType" validate:"required"`
+	Hits    []*IndicatorHit `json:"hits"`
+}
+
+// IndicatorHit is an event that matched, the event can be read from its table by p_row_id
+type IndicatorHit struct {
+	EventTime *time.Time `json:"eventTime" validate:"required"`
+	RowID     *string    `json:"rowId" validate:"required"`
+	// Matches are the indicators found in the event
+	Matches []string `json:"matches"`
+}
+
+// QueryEntityTimelineInput starts a query for the events of all log tables with the entity in their p_any fields,
+// it returns without waiting. The query is part of the query history of the user.
+//
+// Example:
+// {
+//     "queryEntityTimeline": {
+//         "userId": "f6cfad0a-9bb0-4681-9503-02c54cc979c7",
+//         "entityType": "ip_address",
+//         "entity": "192.0.2.10",
+//         "startTime": "2020-04-01T00:00:00Z",
+//         "endTime": "2020-04-08T00:00:00Z"
+//     }
+// }
+type QueryEntityTimelineInput struct {
+	UserID *string `json:"userId" validate:"required,uuid4"`
+	// nolint(lll)
+	EntityType *string    `json:"entityType" validate:"required,oneof=ip_address domain_name sha1_hash md5_hash aws_account_id aws_instance_id aws_arn aws_tag"`
+	Entity     *string    `json:"entity" validate:"required,min=1,max=2048"`
+	StartTime  *time.Time `json:"startTime" validate:"required"`
+	EndTime    *time.Time `json:"endTime" validate:"required,gtfield=StartTime"`
+}
+
+// QueryEntityTimelineOutput is the status of the started query.
+type QueryEntityTimelineOutput = QueryStatus
+
+// GetEntityTimelineResultsInput returns a page of the events of a succeeded entity timeline query of the user.
+type GetEntityTimelineResultsInput struct {
+	UserID          *string `json:"userId" validate:"required,uuid4"`
+	QueryID         *string `json:"queryId" validate:"required,uuid"`
+	PageSize        *int64  `json:"pageSize,omitempty" validate:"omitempty,min=1,max=1000"`
+	PaginationToken *string `json:"paginationToken,omitempty"`
+}
+
+// GetEntityTimelineResultsOutput has the query status and, if the query succeeded, a page of the events of the entity
+// oldest first.
+type GetEntityTimelineResultsOutput struct {
+	QueryStatus
+	Events []*TimelineEvent `json:"events"`
+	// PaginationToken is set if there are more events
+	PaginationToken *string `json:"paginationToken,omitempty"`
+}
+
+// TimelineEvent is an event of the entity, the event can be read from the table of its log type by p_row_id
+type TimelineEvent struct {
+	EventTime *time.Time `json:"eventTime" validate:"required"`
+	LogType   *string    `json:"logType" validate:"required"`
+	RowID     *string    `json:"rowId" validate:"required"`
+}
+
+// QueryStatus describes a query and its progress
+type QueryStatus struct {
+	QueryID      *string    `json:"queryId" validate:"required"`
+	DatabaseName *string    `json:"databaseName" validate:"required"`
+	SQL          *string    `json:"sql" validate:"required"`
+	StartTime    *time.Time `json:"startTime" validate:"required"`
+	EndTime      *time.Time `json:"endTime,omitempty"`
+	// Status is one of running, succeeded, failed or cancelled
+	Status *string `json:"status" validate:"required"`
+	// SQLError explains why a query failed
+	SQLError           *string `json:"sqlError,omitempty"`
+	DataScannedBytes   *int64  `json:"dataScannedBytes,omitempty"`
+	ExecutionTimeMilli *int64  `json:"executionTimeMilli,omitempty"`
+}
+
+// Column describes a column of the results
+type Column struct {
+	Name *string `json:"name" validate:"required"`
+	Type *string `json:"type" validate:"required"`
+}
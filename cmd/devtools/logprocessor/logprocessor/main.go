@@ -1,32 +1,40 @@
 package main
 
 import (
-	"compress/gzip"
 	"flag"
 	"log"
 	"os"
 	"runtime"
 	"runtime/pprof"
+	"sort"
 	"strconv"
+	"time"
 
 	"go.uber.org/zap"
 
-	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/classification"
 	"github.com/panther-labs/panther/internal/log_analysis/log_processor/destinations"
 	"github.com/panther-labs/panther/internal/log_analysis/log_processor/processor"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/registry"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/sources"
 )
 
 /*
 Run log processor locally for profiling purposes.
 
 Profiles can then be visualized with the pprof tool: go tool pprof cpu.prof
+
+To run entirely offline use -output to write the processed data to a local directory instead of S3, e.g.:
+
+	logprocessor -file ./samples -logtype AWS.CloudTrail -output ./processed
 */
 
 var (
 	BUCKET     = flag.String("bucket", "", "The bucket to write to.")
 	TOPICARN   = flag.String("topic", "", "The arn for log processor notifications")
-	FILE       = flag.String("file", "", "The file to process (assumed to be gzipped).")
-	LOGTYPE    = flag.String("logtype", "", "The logType.")
+	OUTPUT     = flag.String("output", "", "The local directory to write to instead of a bucket (offline mode).")
+	FILE       = flag.String("file", "", "The file or directory of files to process (plain text or gzipped).")
+	LOGTYPE    = flag.String("logtype", "", "The logType, if set all data is assumed to be of this type.")
 	MEMORYSIZE = flag.Int("lambdaSize", 1024, "The memory size of the lambda")
 
 	VERBOSE = flag.Bool("verbose", false, "verbose logging")
@@ -38,11 +46,23 @@ var (
 func main() {
 	flag.Parse()
 
-	if *BUCKET == "" {
-		log.Fatal("-bucket not set")
+	if *FILE == "" {
+		log.Fatal("-file not set")
+	}
+	if *OUTPUT == "" {
+		if *BUCKET == "" {
+			log.Fatal("-bucket not set")
+		}
+		if *TOPICARN == "" {
+			log.Fatal("-topic not set")
+		}
 	}
-	if *TOPICARN == "" {
-		log.Fatal("-topic not set")
+	var logType *string
+	if *LOGTYPE != "" {
+		if _, found := registry.AvailableParsers().Elements()[*LOGTYPE]; !found {
+			log.Fatalf("unknown -logtype %s", *LOGTYPE)
+		}
+		logType = LOGTYPE
 	}
 
 	os.Setenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", strconv.Itoa(*MEMORYSIZE))
@@ -52,15 +72,16 @@ func main() {
 	log.Printf("cores: %d", runtime.NumCPU())
 	log.Printf("input %s", *FILE)
 
-	fileReader, err := os.Open(*FILE)
+	dataStreams, files, err := sources.ReadFiles(*FILE, logType)
 	if err != nil {
 		log.Fatal(err)
 	}
-	gzipReader, err := gzip.NewReader(fileReader)
-	if err != nil {
-		log.Fatal(err)
-	}
-	dataStreams := []*common.DataStream{{Reader: gzipReader, LogType: LOGTYPE}}
+	defer func() {
+		for _, f := range files {
+			f.Close() // nolint:errcheck
+		}
+	}()
+	log.Printf("files: %d", len(dataStreams))
 
 	if *CPUPROFILE != "" {
 		f, err := os.Create(*CPUPROFILE)
@@ -86,10 +107,20 @@ func main() {
 	}
 	zap.ReplaceGlobals(logger)
 
-	err = processor.Process(dataStreams, destinations.CreateDestination())
+	var destination destinations.Destination
+	if *OUTPUT != "" {
+		log.Printf("output %s", *OUTPUT)
+		destination = destinations.CreateFileDestination(*OUTPUT)
+	} else {
+		destination = destinations.CreateDestination()
+	}
+
+	startTime := time.Now()
+	stats, parserStats, err := processor.ProcessWithStats(dataStreams, destination)
 	if err != nil {
 		log.Fatal(err)
 	}
+	printStats(time.Since(startTime), stats, parserStats)
 
 	if *MEMPROFILE != "" {
 		f, err := os.Create(*MEMPROFILE)
@@ -103,3 +134,24 @@ func main() {
 		}
 	}
 }
+
+func printStats(elapsed time.Duration, stats *classification.ClassifierStats,
+	parserStats map[string]*classification.ParserStats) {
+
+	log.Printf("elapsed: %v", elapsed)
+	log.Printf("classifier: lines=%d events=%d bytes=%d classified=%d failures=%d",
+		stats.LogLineCount, stats.EventCount, stats.BytesProcessedCount,
+		stats.SuccessfullyClassifiedCount, stats.ClassificationFailureCount)
+
+	logTypes := make([]string, 0, len(parserStats))
+	for logType := range parserStats {
+		logTypes = append(logTypes, logType)
+	}
+	sort.Strings(logTypes)
+	for _, logType := range logTypes {
+		ps := parserStats[logType]
+		log.Printf("parser %s: lines=%d events=%d bytes=%d parseTime=%v",
+			logType, ps.LogLineCount, ps.EventCount, ps.BytesProcessedCount,
+			time.Duration(ps.ParserTimeMicroseconds)*time.Microsecond)
+	}
+}
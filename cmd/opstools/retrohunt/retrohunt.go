@@ -0,0 +1,454 @@
+package retrohunt
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	enginemodels "github.com/panther-labs/panther/api/gateway/analysis"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/registry"
+	"github.com/panther-labs/panther/pkg/awsathena"
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+const (
+	// matched events and the report are written under this prefix, away from the "rules" prefix
+	// of panther_rule_matches so a retro-hunt never creates partitions or alerts
+	OutputPrefix = "retrohunt"
+
+	DefaultBatchSize = 500
+
+	maxBatchBytes   = 4 * 1024 * 1024  // stay well under the 6MB limit of a synchronous lambda invocation
+	maxOutputBytes  = 50 * 1024 * 1024 // uncompressed bytes of matched events per output object
+	maxReportErrors = 10               // rule errors are usually the same error repeated, keep a few as examples
+
+	eventTimeLayout  = "2006-01-02 15:04:05.000000000" // how p_event_time is stored
+	athenaTimeLayout = "2006-01-02 15:04:05.000"
+	readBufferSize   = 64 * 1024
+)
+
+// Hunt describes running a rule over historical data
+type Hunt struct {
+	ID        string // unique id of this hunt, used in the output keys
+	Rule      enginemodels.Rule
+	Start     time.Time // inclusive
+	End       time.Time // exclusive
+	BatchSize int       // events per rules engine invocation
+	Bucket    string    // where matched events and the report are written
+}
+
+// Report summarizes the results of a hunt, it is written as JSON next to the matched events
+type Report struct {
+	HuntID        string           `json:"huntId"`
+	RuleID        string           `json:"ruleId"`
+	Start         time.Time        `json:"start"`
+	End           time.Time        `json:"end"`
+	LogTypes      []*LogTypeReport `json:"logTypes"`
+	EventsScanned uint64           `json:"eventsScanned"`
+	EventsMatched uint64           `json:"eventsMatched"`
+	EventsErrored uint64           `json:"eventsErrored"`
+	Errors        []string         `json:"errors,omitempty"`
+	MatchObjects  []string         `json:"matchObjects"`
+	ReportObject  string           `json:"-"`
+}
+
+// LogTypeReport has the results for one of the log types of the rule
+type LogTypeReport struct {
+	LogType       string `json:"logType"`
+	QueryID       string `json:"queryId"`
+	Objects       uint64 `json:"objects"`
+	EventsScanned uint64 `json:"eventsScanned"`
+	EventsMatched uint64 `json:"eventsMatched"`
+	EventsErrored uint64 `json:"eventsErrored"`
+}
+
+// Hunter runs hunts
+type Hunter struct {
+	athenaClient athenaiface.AthenaAPI
+	lambdaClient lambdaiface.LambdaAPI
+	s3Client     s3iface.S3API
+	rulesEngine  string // name of the rules engine lambda
+}
+
+func NewHunter(sess *session.Session, rulesEngine string) *Hunter {
+	return &Hunter{
+		athenaClient: athena.New(sess),
+		lambdaClient: lambda.New(sess),
+		s3Client:     s3.New(sess),
+		rulesEngine:  rulesEngine,
+	}
+}
+
+// Run executes the hunt and writes the matched events and the report to the hunt bucket.
+// No alerts are created.
+func (h *Hunter) Run(hunt *Hunt) (*Report, error) {
+	if err := hunt.validate(); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		HuntID:       hunt.ID,
+		RuleID:       hunt.Rule.ID,
+		Start:        hunt.Start,
+		End:          hunt.End,
+		MatchObjects: []string{},
+	}
+	for _, logType := range hunt.Rule.LogTypes {
+		parserMetadata, found := registry.AvailableParsers().Elements()[logType]
+		if !found {
+			return nil, errors.Errorf("rule %s has unknown log type %s", hunt.Rule.ID, logType)
+		}
+		if err := h.huntTable(hunt, parserMetadata.GlueTableMetadata, report); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.writeReport(hunt, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (hunt *Hunt) validate() error {
+	if hunt.ID == "" {
+		return errors.New("hunt id is required")
+	}
+	if hunt.Rule.ID == "" || hunt.Rule.Body == "" {
+		return errors.New("rule id and body are required")
+	}
+	if len(hunt.Rule.LogTypes) == 0 {
+		return errors.Errorf("rule %s has no log types", hunt.Rule.ID)
+	}
+	if !hunt.Start.Before(hunt.End) {
+		return errors.Errorf("start %s is not before end %s", hunt.Start, hunt.End)
+	}
+	if hunt.Bucket == "" {
+		return errors.New("output bucket is required")
+	}
+	if hunt.BatchSize <= 0 {
+		hunt.BatchSize = DefaultBatchSize
+	}
+	return nil
+}
+
+// outputPrefix returns the S3 prefix for the output of the hunt
+func (hunt *Hunt) outputPrefix() string {
+	return fmt.Sprintf("%s/rule_id=%s/hunt_id=%s/", OutputPrefix, hunt.Rule.ID, hunt.ID)
+}
+
+// huntTable runs the rule against the events of one log type in the time range
+func (h *Hunter) huntTable(hunt *Hunt, table *awsglue.GlueTableMetadata, report *Report) error {
+	stats := &LogTypeReport{LogType: table.LogType()}
+	report.LogTypes = append(report.LogTypes, stats)
+
+	paths, queryID, err := h.queryObjects(table, hunt.Start, hunt.End)
+	stats.QueryID = queryID
+	if err != nil {
+		return err
+	}
+	zap.L().Info("hunting",
+		zap.String("logType", table.LogType()),
+		zap.String("queryId", queryID),
+		zap.Int("objects", len(paths)))
+
+	matches := &matchWriter{
+		s3Client: h.s3Client,
+		bucket:   hunt.Bucket,
+		prefix:   hunt.outputPrefix() + table.TableName() + "/",
+	}
+	batch := &eventBatch{logType: table.LogType()}
+	for _, path := range paths {
+		err = h.scanObject(path, hunt.Start, hunt.End, func(event []byte) error {
+			stats.EventsScanned++
+			batch.add(event)
+			if len(batch.events) >= hunt.BatchSize || batch.bytes >= maxBatchBytes {
+				return h.analyzeBatch(hunt, batch, matches, stats, report)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		stats.Objects++
+	}
+	if err = h.analyzeBatch(hunt, batch, matches, stats, report); err != nil {
+		return err
+	}
+	if err = matches.flush(); err != nil {
+		return err
+	}
+
+	report.EventsScanned += stats.EventsScanned
+	report.EventsMatched += stats.EventsMatched
+	report.EventsErrored += stats.EventsErrored
+	report.MatchObjects = append(report.MatchObjects, matches.objects...)
+	return nil
+}
+
+// queryObjects returns the S3 paths of the objects of the table holding events in the time range
+func (h *Hunter) queryObjects(table *awsglue.GlueTableMetadata, start, end time.Time) (paths []string, queryID string, err error) {
+	sql := objectsQuery(table, start, end)
+	zap.L().Debug("querying objects", zap.String("sql", sql))
+	startOutput, err := awsathena.StartQuery(h.athenaClient, table.DatabaseName(), sql, nil)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to start query for %s", table.LogType())
+	}
+	queryID = *startOutput.QueryExecutionId
+
+	results, err := awsathena.WaitForResults(h.athenaClient, queryID)
+	if err != nil {
+		return nil, queryID, errors.Wrapf(err, "query %s for %s failed", queryID, table.LogType())
+	}
+	executionOutput, err := awsathena.Status(h.athenaClient, queryID)
+	if err != nil {
+		return nil, queryID, err
+	}
+	if state := aws.StringValue(executionOutput.QueryExecution.Status.State); state != athena.QueryExecutionStateSucceeded {
+		return nil, queryID, errors.Errorf("query %s for %s did not succeed: %s %s", queryID, table.LogType(),
+			state, aws.StringValue(executionOutput.QueryExecution.Status.StateChangeReason))
+	}
+
+	header := true
+	for {
+		for _, row := range results.ResultSet.Rows {
+			if header { // first row of the first page holds the column names
+				header = false
+				continue
+			}
+			if len(row.Data) > 0 && row.Data[0].VarCharValue != nil {
+				paths = append(paths, *row.Data[0].VarCharValue)
+			}
+		}
+		if results.NextToken == nil {
+			return paths, queryID, nil
+		}
+		results, err = awsathena.Results(h.athenaClient, queryID, results.NextToken, nil)
+		if err != nil {
+			return nil, queryID, err
+		}
+	}
+}
+
+// objectsQuery returns the SQL listing the objects of the table with events in the time range
+func objectsQuery(table *awsglue.GlueTableMetadata, start, end time.Time) string {
+	return fmt.Sprintf(`SELECT DISTINCT "$path" FROM %s.%s WHERE %s AND p_event_time >= timestamp '%s' AND p_event_time < timestamp '%s'`,
+		table.DatabaseName(), table.TableName(), table.PartitionPredicate(start, end),
+		start.UTC().Format(athenaTimeLayout), end.UTC().Format(athenaTimeLayout))
+}
+
+// scanObject calls handleEvent for each event of the gzip'd JSON lines object with p_event_time in [start, end)
+func (h *Hunter) scanObject(path string, start, end time.Time, handleEvent func(event []byte) error) error {
+	bucket, key, err := parseS3Path(path)
+	if err != nil {
+		return err
+	}
+	output, err := h.s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get %s", path)
+	}
+	defer output.Body.Close()
+
+	gzipReader, err := gzip.NewReader(output.Body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", path)
+	}
+	reader := bufio.NewReaderSize(gzipReader, readBufferSize)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			eventTime, parseErr := time.Parse(eventTimeLayout, jsoniter.Get(line, "p_event_time").ToString())
+			// objects can have events outside the range (e.g. the first and last hour)
+			if parseErr == nil && !eventTime.Before(start) && eventTime.Before(end) {
+				if handleErr := handleEvent(line); handleErr != nil {
+					return handleErr
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", path)
+		}
+	}
+}
+
+func parseS3Path(path string) (bucket, key string, err error) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "s3://"), "/", 2)
+	if !strings.HasPrefix(path, "s3://") || len(parts) != 2 {
+		return "", "", errors.Errorf("not an s3 path: %s", path)
+	}
+	return parts[0], parts[1], nil
+}
+
+// eventBatch holds the events sent to the rules engine in one invocation
+type eventBatch struct {
+	logType string
+	events  [][]byte
+	bytes   int
+}
+
+func (b *eventBatch) add(event []byte) {
+	b.events = append(b.events, event)
+	b.bytes += len(event)
+}
+
+func (b *eventBatch) reset() {
+	b.events = nil
+	b.bytes = 0
+}
+
+// analyzeBatch runs the rule over the batch using the direct analysis mode of the rules engine,
+// matched events are passed to the match writer. The batch is empty on return.
+func (h *Hunter) analyzeBatch(hunt *Hunt, batch *eventBatch, matches *matchWriter,
+	stats *LogTypeReport, report *Report) error {
+
+	if len(batch.events) == 0 {
+		return nil
+	}
+	defer batch.reset()
+
+	input := enginemodels.RulesEngineInput{
+		Rules:  []enginemodels.Rule{hunt.Rule},
+		Events: make([]enginemodels.Event, len(batch.events)),
+	}
+	for i, event := range batch.events {
+		input.Events[i] = enginemodels.Event{
+			Data: jsoniter.RawMessage(event),
+			ID:   strconv.Itoa(i),
+			Type: batch.logType,
+		}
+	}
+	payload, err := jsoniter.Marshal(&input)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal rules engine input")
+	}
+
+	response, err := h.lambdaClient.Invoke(&lambda.InvokeInput{FunctionName: &h.rulesEngine, Payload: payload})
+	if err != nil {
+		return errors.Wrapf(err, "failed to invoke %s", h.rulesEngine)
+	}
+	if response.FunctionError != nil {
+		return errors.Errorf("%s failed: %s: %s", h.rulesEngine, *response.FunctionError, string(response.Payload))
+	}
+	var output enginemodels.RulesEngineOutput
+	if err = jsoniter.Unmarshal(response.Payload, &output); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal %s response", h.rulesEngine)
+	}
+
+	for _, result := range output.Events {
+		index, err := strconv.Atoi(result.ID)
+		if err != nil || index < 0 || index >= len(batch.events) {
+			return errors.Errorf("%s returned unexpected event id %s", h.rulesEngine, result.ID)
+		}
+		switch {
+		case len(result.Errored) > 0:
+			stats.EventsErrored++
+			if len(report.Errors) < maxReportErrors {
+				report.Errors = append(report.Errors, result.Errored[0].Message)
+			}
+		case len(result.Matched) > 0:
+			stats.EventsMatched++
+			if err = matches.write(batch.events[index]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchWriter writes matched events as gzip'd JSON lines under prefix
+type matchWriter struct {
+	s3Client s3iface.S3API
+	bucket   string
+	prefix   string
+	objects  []string // the paths written
+
+	buffer     bytes.Buffer
+	gzipWriter *gzip.Writer
+	bytes      int
+}
+
+func (w *matchWriter) write(event []byte) error {
+	if w.gzipWriter == nil {
+		w.gzipWriter = gzip.NewWriter(&w.buffer)
+	}
+	event = bytes.TrimRight(event, "\n")
+	if _, err := w.gzipWriter.Write(event); err != nil {
+		return errors.Wrap(err, "failed to compress matched event")
+	}
+	if _, err := w.gzipWriter.Write([]byte{'\n'}); err != nil {
+		return errors.Wrap(err, "failed to compress matched event")
+	}
+	w.bytes += len(event) + 1
+	if w.bytes >= maxOutputBytes {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered events to a new object
+func (w *matchWriter) flush() error {
+	if w.gzipWriter == nil {
+		return nil
+	}
+	if err := w.gzipWriter.Close(); err != nil {
+		return errors.Wrap(err, "failed to compress matched events")
+	}
+
+	key := fmt.Sprintf("%s%d.json.gz", w.prefix, len(w.objects))
+	_, err := w.s3Client.PutObject(&s3.PutObjectInput{
+		Bucket:          &w.bucket,
+		Key:             &key,
+		Body:            bytes.NewReader(w.buffer.Bytes()),
+		ContentEncoding: aws.String("gzip"),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to write s3://%s/%s", w.bucket, key)
+	}
+	w.objects = append(w.objects, "s3://"+w.bucket+"/"+key)
+
+	w.buffer.Reset()
+	w.gzipWriter = nil
+	w.bytes = 0
+	return nil
+}
+
+func (h *Hunter) writeReport(hunt *Hunt, report *Report) error {
+	body, err := jsoniter.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal report")
+	}
+	key := hunt.outputPrefix() + "report.json"
+	_, err = h.s3Client.PutObject(&s3.PutObjectInput{
+		Bucket:      &hunt.Bucket,
+		Key:         &key,
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to write s3://%s/%s", hunt.Bucket, key)
+	}
+	report.ReportObject = "s3://" + hunt.Bucket + "/" + key
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	enginemodels "github.com/panther-labs/panther/api/gateway/analysis"
+	analysisclient "github.com/panther-labs/panther/api/gateway/analysis/client"
+	"github.com/panther-labs/panther/api/gateway/analysis/client/operations"
+	"github.com/panther-labs/panther/cmd/opstools/retrohunt"
+	"github.com/panther-labs/panther/pkg/gatewayapi"
+)
+
+const (
+	banner = "runs a rule over historical log data, writing the matched events and a report to S3 (no alerts are created)"
+)
+
+var (
+	REGION      = flag.String("region", "", "The AWS region (optional, defaults to session env vars) where Panther is deployed.")
+	RULE        = flag.String("rule", "", "The id of the rule to run.")
+	START       = flag.String("start", "", "The start of the time range (RFC3339, inclusive), e.g. 2020-01-01T00:00:00Z")
+	END         = flag.String("end", "", "The end of the time range (RFC3339, exclusive), defaults to now.")
+	BUCKET      = flag.String("bucket", "", "The S3 bucket to write the matched events and report (e.g., the processed data bucket).")
+	ANALYSISAPI = flag.String("analysis-api", "", "The host of the analysis api (the AnalysisApiEndpoint output of the deployment).")
+	ENGINE      = flag.String("rules-engine", "panther-rules-engine", "The name of the rules engine lambda.")
+	BATCH       = flag.Int("batch", retrohunt.DefaultBatchSize, "The number of events sent to the rules engine per invocation.")
+	HUNTID      = flag.String("id", "", "The id of the hunt (optional, defaults to the current time), used in the output keys.")
+	VERBOSE     = flag.Bool("verbose", false, "Enable verbose logging")
+
+	logger *zap.SugaredLogger
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(),
+		"%s %s\nUsage:\n",
+		filepath.Base(os.Args[0]), banner)
+	flag.PrintDefaults()
+}
+
+func init() {
+	flag.Usage = usage
+
+	config := zap.NewDevelopmentConfig() // DEBUG by default
+	if !*VERBOSE {
+		// In normal mode, hide DEBUG messages and file/line numbers
+		config.DisableCaller = true
+		config.Level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	}
+
+	// Always disable error traces and use color-coded log levels and short timestamps
+	config.DisableStacktrace = true
+	config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+
+	rawLogger, err := config.Build()
+	if err != nil {
+		log.Fatalf("failed to build logger: %s", err)
+	}
+	zap.ReplaceGlobals(rawLogger)
+	logger = rawLogger.Sugar()
+}
+
+func main() {
+	flag.Parse()
+
+	start, end := validateFlags()
+
+	sess, err := session.NewSession()
+	if err != nil {
+		logger.Fatal(err)
+		return
+	}
+
+	if *REGION != "" { //override
+		sess.Config.Region = REGION
+	}
+
+	rule, err := getRule(sess)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	hunt := &retrohunt.Hunt{
+		ID:        *HUNTID,
+		Rule:      *rule,
+		Start:     start,
+		End:       end,
+		BatchSize: *BATCH,
+		Bucket:    *BUCKET,
+	}
+	if hunt.ID == "" {
+		hunt.ID = time.Now().UTC().Format("20060102T150405Z")
+	}
+
+	startTime := time.Now()
+	logger.Infof("running rule %s over %v to %v", hunt.Rule.ID, hunt.Start, hunt.End)
+	report, err := retrohunt.NewHunter(sess, *ENGINE).Run(hunt)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("scanned %d events, %d matched, %d errored in %v",
+		report.EventsScanned, report.EventsMatched, report.EventsErrored, time.Since(startTime))
+	for _, message := range report.Errors {
+		logger.Warnf("rule error: %s", message)
+	}
+	logger.Infof("report written to %s", report.ReportObject)
+}
+
+// getRule reads the rule from the analysis api
+func getRule(sess *session.Session) (*enginemodels.Rule, error) {
+	apiClient := analysisclient.NewHTTPClientWithConfig(nil, analysisclient.DefaultTransportConfig().
+		WithBasePath("/v1").WithHost(*ANALYSISAPI))
+	response, err := apiClient.Operations.GetRule(&operations.GetRuleParams{
+		RuleID:     *RULE,
+		HTTPClient: gatewayapi.GatewayClient(sess),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rule %s: %v", *RULE, err)
+	}
+	return &enginemodels.Rule{
+		Body:     string(response.Payload.Body),
+		ID:       string(response.Payload.ID),
+		LogTypes: response.Payload.LogTypes,
+	}, nil
+}
+
+func validateFlags() (start, end time.Time) {
+	var err error
+	defer func() {
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			flag.Usage()
+			os.Exit(-2)
+		}
+	}()
+
+	if *RULE == "" {
+		err = errors.New("-rule not set")
+		return
+	}
+	if *BUCKET == "" {
+		err = errors.New("-bucket not set")
+		return
+	}
+	if *ANALYSISAPI == "" {
+		err = errors.New("-analysis-api not set")
+		return
+	}
+	if start, err = time.Parse(time.RFC3339, *START); err != nil {
+		err = fmt.Errorf("-start is not a valid time: %v", err)
+		return
+	}
+	end = time.Now().UTC()
+	if *END != "" {
+		if end, err = time.Parse(time.RFC3339, *END); err != nil {
+			err = fmt.Errorf("-end is not a valid time: %v", err)
+			return
+		}
+	}
+	if !start.Before(end) {
+		err = errors.New("-start must be before -end")
+		return
+	}
+	return start.UTC(), end.UTC()
+}
@@ -0,0 +1,225 @@
+package retrohunt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	enginemodels "github.com/panther-labs/panther/api/gateway/analysis"
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+const (
+	testBucket  = "panther-bootstrap-processeddata-test"
+	testQueryID = "test-query-id"
+	testPath    = "s3://" + testBucket + "/logs/aws_vpcflow/year=2020/month=01/day=31/hour=23/20200131T230000Z-uuid.json.gz"
+)
+
+var (
+	testHourlyTable = awsglue.NewGlueTableMetadata(models.LogData, "Test.Log", "test", awsglue.GlueTableHourly, struct{}{})
+	testDailyTable  = awsglue.NewGlueTableMetadata(models.LogData, "Test.Log", "test", awsglue.GlueTableDaily, struct{}{})
+)
+
+func TestObjectsQuery(t *testing.T) {
+	start := time.Date(2020, 1, 31, 23, 0, 0, 0, time.UTC)
+	end := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t,
+		`SELECT DISTINCT "$path" FROM panther_logs.test_log WHERE ((year=2020 AND month=1 AND day=31 AND hour=23)) `+
+			`AND p_event_time >= timestamp '2020-01-31 23:00:00.000' AND p_event_time < timestamp '2020-02-01 00:00:00.000'`,
+		objectsQuery(testHourlyTable, start, end))
+}
+
+func TestRun(t *testing.T) {
+	events := []string{
+		`{"srcAddr":"10.0.0.1","p_log_type":"AWS.VPCFlow","p_event_time":"2020-01-31 23:00:00.000000000"}`,
+		`{"srcAddr":"10.0.0.2","p_log_type":"AWS.VPCFlow","p_event_time":"2020-01-31 23:10:00.000000000"}`,
+		`{"srcAddr":"10.0.0.3","p_log_type":"AWS.VPCFlow","p_event_time":"2020-02-01 00:00:00.000000000"}`, // after end
+		`{"srcAddr":"10.0.0.4","p_log_type":"AWS.VPCFlow","p_event_time":"2020-01-31 23:20:00.000000000"}`,
+	}
+
+	athenaClient := &mockAthena{}
+	athenaClient.On("StartQueryExecution", mock.Anything).Return(&athena.StartQueryExecutionOutput{
+		QueryExecutionId: aws.String(testQueryID),
+	}, nil).Once()
+	athenaClient.On("GetQueryExecution", mock.Anything).Return(&athena.GetQueryExecutionOutput{
+		QueryExecution: &athena.QueryExecution{
+			QueryExecutionId: aws.String(testQueryID),
+			Status:           &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateSucceeded)},
+		},
+	}, nil)
+	athenaClient.On("GetQueryResults", mock.Anything).Return(&athena.GetQueryResultsOutput{
+		ResultSet: &athena.ResultSet{
+			Rows: []*athena.Row{
+				{Data: []*athena.Datum{{VarCharValue: aws.String("$path")}}},
+				{Data: []*athena.Datum{{VarCharValue: aws.String(testPath)}}},
+			},
+		},
+	}, nil).Once()
+
+	s3Client := &mockS3{}
+	s3Client.On("GetObject", mock.Anything).Return(&s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewReader(gzipLines(t, events))),
+	}, nil).Once()
+	s3Client.On("PutObject", mock.Anything).Return(&s3.PutObjectOutput{}, nil).Twice() // matches and report
+
+	lambdaClient := &mockLambda{}
+	lambdaClient.On("Invoke", mock.Anything).Return(engineOutput(t, enginemodels.EventAnalysis{
+		ID:      "0",
+		Matched: []string{"Test.Rule"},
+	}, enginemodels.EventAnalysis{
+		ID:         "1",
+		NotMatched: []string{"Test.Rule"},
+	}), nil).Once()
+	lambdaClient.On("Invoke", mock.Anything).Return(engineOutput(t, enginemodels.EventAnalysis{
+		ID:      "0",
+		Errored: []enginemodels.PolicyError{{ID: "Test.Rule", Message: "KeyError: 'dstAddr'"}},
+	}), nil).Once()
+
+	hunter := &Hunter{
+		athenaClient: athenaClient,
+		lambdaClient: lambdaClient,
+		s3Client:     s3Client,
+		rulesEngine:  "panther-rules-engine",
+	}
+	hunt := &Hunt{
+		ID: "test-hunt",
+		Rule: enginemodels.Rule{
+			ID:       "Test.Rule",
+			Body:     "def rule(event): return True",
+			LogTypes: []string{"AWS.VPCFlow"},
+		},
+		Start:     time.Date(2020, 1, 31, 23, 0, 0, 0, time.UTC),
+		End:       time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		BatchSize: 2,
+		Bucket:    testBucket,
+	}
+	report, err := hunter.Run(hunt)
+	require.NoError(t, err)
+	athenaClient.AssertExpectations(t)
+	s3Client.AssertExpectations(t)
+	lambdaClient.AssertExpectations(t)
+
+	assert.Equal(t, uint64(3), report.EventsScanned)
+	assert.Equal(t, uint64(1), report.EventsMatched)
+	assert.Equal(t, uint64(1), report.EventsErrored)
+	assert.Equal(t, []string{"KeyError: 'dstAddr'"}, report.Errors)
+	require.Len(t, report.LogTypes, 1)
+	assert.Equal(t, &LogTypeReport{
+		LogType:       "AWS.VPCFlow",
+		QueryID:       testQueryID,
+		Objects:       1,
+		EventsScanned: 3,
+		EventsMatched: 1,
+		EventsErrored: 1,
+	}, report.LogTypes[0])
+	assert.Equal(t, []string{"s3://" + testBucket + "/retrohunt/rule_id=Test.Rule/hunt_id=test-hunt/aws_vpcflow/0.json.gz"},
+		report.MatchObjects)
+	assert.Equal(t, "s3://"+testBucket+"/retrohunt/rule_id=Test.Rule/hunt_id=test-hunt/report.json", report.ReportObject)
+
+	// the events are passed to the rules engine as is
+	firstInvoke := lambdaClient.Calls[0].Arguments.Get(0).(*lambda.InvokeInput)
+	assert.Equal(t, "10.0.0.2", jsoniter.Get(firstInvoke.Payload, "events", 1, "data", "srcAddr").ToString())
+	assert.Equal(t, "AWS.VPCFlow", jsoniter.Get(firstInvoke.Payload, "events", 1, "type").ToString())
+	assert.Equal(t, "Test.Rule", jsoniter.Get(firstInvoke.Payload, "rules", 0, "id").ToString())
+
+	// only the matched event is written
+	matchesInput := s3Client.Calls[1].Arguments.Get(0).(*s3.PutObjectInput)
+	gzipReader, err := gzip.NewReader(matchesInput.Body)
+	require.NoError(t, err)
+	matched, err := ioutil.ReadAll(gzipReader)
+	require.NoError(t, err)
+	assert.Equal(t, events[0]+"\n", string(matched))
+}
+
+func TestRunUnknownLogType(t *testing.T) {
+	hunt := &Hunt{
+		ID: "test-hunt",
+		Rule: enginemodels.Rule{
+			ID:       "Test.Rule",
+			Body:     "def rule(event): return True",
+			LogTypes: []string{"Unknown.Log"},
+		},
+		Start:  time.Date(2020, 1, 31, 23, 0, 0, 0, time.UTC),
+		End:    time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		Bucket: testBucket,
+	}
+	_, err := (&Hunter{}).Run(hunt)
+	require.Error(t, err)
+}
+
+func gzipLines(t *testing.T, lines []string) []byte {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	for _, line := range lines {
+		_, err := writer.Write([]byte(line + "\n"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func engineOutput(t *testing.T, results ...enginemodels.EventAnalysis) *lambda.InvokeOutput {
+	payload, err := jsoniter.Marshal(&enginemodels.RulesEngineOutput{Events: results})
+	require.NoError(t, err)
+	return &lambda.InvokeOutput{Payload: payload}
+}
+
+type mockAthena struct {
+	athenaiface.AthenaAPI
+	mock.Mock
+}
+
+func (m *mockAthena) StartQueryExecution(input *athena.StartQueryExecutionInput) (*athena.StartQueryExecutionOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.StartQueryExecutionOutput), args.Error(1)
+}
+
+func (m *mockAthena) GetQueryExecution(input *athena.GetQueryExecutionInput) (*athena.GetQueryExecutionOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.GetQueryExecutionOutput), args.Error(1)
+}
+
+func (m *mockAthena) GetQueryResults(input *athena.GetQueryResultsInput) (*athena.GetQueryResultsOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.GetQueryResultsOutput), args.Error(1)
+}
+
+type mockS3 struct {
+	s3iface.S3API
+	mock.Mock
+}
+
+func (m *mockS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*s3.GetObjectOutput), args.Error(1)
+}
+
+func (m *mockS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*s3.PutObjectOutput), args.Error(1)
+}
+
+type mockLambda struct {
+	lambdaiface.LambdaAPI
+	mock.Mock
+}
+
+func (m *mockLambda) Invoke(input *lambda.InvokeInput) (*lambda.InvokeOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*lambda.InvokeOutput), args.Error(1)
+}
@@ -20,6 +20,8 @@ package delivery
 
 import (
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
 	"github.com/aws/aws-sdk-go/service/sqs"
@@ -38,6 +40,9 @@ var (
 
 	// Lazy-load the SQS client - we only need it to retry failed alerts
 	sqsClient sqsiface.SQSAPI
+
+	// Lazy-load the DynamoDB client - we only need it to record the delivery attempts of alerts with an ID
+	ddbClient dynamodbiface.DynamoDBAPI
 )
 
 func getSQSClient() sqsiface.SQSAPI {
@@ -46,3 +51,10 @@ func getSQSClient() sqsiface.SQSAPI {
 	}
 	return sqsClient
 }
+
+func getDynamoDBClient() dynamodbiface.DynamoDBAPI {
+	if ddbClient == nil {
+		ddbClient = dynamodb.New(awsSession)
+	}
+	return ddbClient
+}
@@ -19,6 +19,8 @@ package delivery
  */
 
 import (
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"go.uber.org/zap"
 
@@ -32,6 +34,8 @@ type outputStatus struct {
 	outputID   string
 	success    bool
 	needsRetry bool
+	statusCode int
+	message    string
 }
 
 // Send an alert to one specific output (run as a child goroutine).
@@ -47,7 +51,8 @@ func send(alert *alertmodels.Alert, output *outputmodels.AlertOutput, statusChan
 		// Otherwise, the main routine will wait forever for this to finish.
 		if r := recover(); r != nil {
 			zap.L().Error("panic sending alert", append(commonFields, zap.Any("panic", r))...)
-			statusChannel <- outputStatus{outputID: *output.OutputID, success: false, needsRetry: false}
+			statusChannel <- outputStatus{outputID: *output.OutputID, success: false, needsRetry: false,
+				message: "panic sending alert"}
 		}
 	}()
 
@@ -78,13 +83,15 @@ func send(alert *alertmodels.Alert, output *outputmodels.AlertOutput, statusChan
 		alertDeliveryError = outputClient.Asana(alert, output.OutputConfig.Asana)
 	default:
 		zap.L().Warn("unsupported output type", commonFields...)
-		statusChannel <- outputStatus{outputID: *output.OutputID, success: false, needsRetry: false}
+		statusChannel <- outputStatus{outputID: *output.OutputID, success: false, needsRetry: false,
+			message: "unsupported output type " + *output.OutputType}
 		return
 	}
 	if alertDeliveryError != nil {
 		zap.L().Warn("failed to send alert", append(commonFields, zap.Error(alertDeliveryError))...)
 		statusChannel <- outputStatus{
-			outputID: *output.OutputID, success: false, needsRetry: !alertDeliveryError.Permanent}
+			outputID: *output.OutputID, success: false, needsRetry: !alertDeliveryError.Permanent,
+			statusCode: alertDeliveryError.StatusCode, message: alertDeliveryError.Message}
 		return
 	}
 
@@ -124,8 +131,10 @@ func dispatch(alert *alertmodels.Alert) bool {
 
 	// Wait until all outputs have finished, gathering any that need to be retried.
 	var retryOutputs []*string
-	for range outputs {
+	statuses := make([]outputStatus, len(outputs))
+	for i := range outputs {
 		status := <-statusChannel
+		statuses[i] = status
 		if status.needsRetry {
 			retryOutputs = append(retryOutputs, aws.String(status.outputID))
 		} else if !status.success {
@@ -136,6 +145,10 @@ func dispatch(alert *alertmodels.Alert) bool {
 		}
 	}
 
+	if alert.AlertID != nil {
+		recordDeliveryAttempts(alert, statuses, time.Now().UTC())
+	}
+
 	if len(retryOutputs) > 0 {
 		alert.OutputIDs = retryOutputs // Replace the outputs with the set that failed
 		return false
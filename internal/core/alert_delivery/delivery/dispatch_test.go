@@ -23,6 +23,8 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/lambda"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
@@ -32,6 +34,7 @@ import (
 	outputmodels "github.com/panther-labs/panther/api/lambda/outputs/models"
 	alertmodels "github.com/panther-labs/panther/internal/core/alert_delivery/models"
 	"github.com/panther-labs/panther/internal/core/alert_delivery/outputs"
+	"github.com/panther-labs/panther/pkg/testutils"
 )
 
 func sampleAlert() *alertmodels.Alert {
@@ -69,7 +72,7 @@ func TestSendPanic(t *testing.T) {
 		panic("panicking")
 	})
 	go send(sampleAlert(), alertOutput, ch)
-	require.Equal(t, outputStatus{outputID: *alertOutput.OutputID}, <-ch)
+	require.Equal(t, outputStatus{outputID: *alertOutput.OutputID, message: "panic sending alert"}, <-ch)
 	mockOutputsClient.AssertExpectations(t)
 }
 
@@ -80,7 +83,7 @@ func TestSendUnsupportedOutput(t *testing.T) {
 	ch := make(chan outputStatus, 1)
 
 	send(sampleAlert(), alertOutput, ch)
-	assert.Equal(t, outputStatus{outputID: *alertOutput.OutputID}, <-ch)
+	assert.Equal(t, outputStatus{outputID: *alertOutput.OutputID, message: "panic sending alert"}, <-ch)
 	mockClient.AssertExpectations(t)
 }
 
@@ -89,10 +92,12 @@ func TestSendTransientFailure(t *testing.T) {
 	outputClient = mockClient
 	setCaches()
 	ch := make(chan outputStatus, 1)
-	mockClient.On("Slack", mock.Anything, mock.Anything).Return(&outputs.AlertDeliveryError{})
+	mockClient.On("Slack", mock.Anything, mock.Anything).Return(
+		&outputs.AlertDeliveryError{Message: "request failed: 503", StatusCode: 503})
 
 	send(sampleAlert(), alertOutput, ch)
-	assert.Equal(t, outputStatus{outputID: *alertOutput.OutputID, needsRetry: true}, <-ch)
+	assert.Equal(t, outputStatus{outputID: *alertOutput.OutputID, needsRetry: true, statusCode: 503,
+		message: "request failed: 503"}, <-ch)
 	mockClient.AssertExpectations(t)
 }
 
@@ -126,6 +131,42 @@ func TestDispatchSuccess(t *testing.T) {
 	assert.True(t, dispatch(sampleAlert()))
 }
 
+func TestDispatchRecordsAttempts(t *testing.T) {
+	mockClient := &mockOutputsClient{}
+	outputClient = mockClient
+	mockDynamoDB := &testutils.DynamoDBMock{}
+	ddbClient = mockDynamoDB
+	setCaches()
+	mockClient.On("Slack", mock.Anything, mock.Anything).Return(
+		&outputs.AlertDeliveryError{Message: "request failed: 500", StatusCode: 500})
+	mockDynamoDB.On("BatchWriteItem", mock.Anything).Return(&dynamodb.BatchWriteItemOutput{}, nil).Once()
+
+	alert := sampleAlert()
+	alert.AlertID = aws.String("alert-id")
+	alert.RetryCount = 2
+	assert.False(t, dispatch(alert))
+	mockClient.AssertExpectations(t)
+	mockDynamoDB.AssertExpectations(t)
+
+	input := mockDynamoDB.Calls[0].Arguments.Get(0).(*dynamodb.BatchWriteItemInput)
+	var writes []*dynamodb.WriteRequest
+	for _, tableWrites := range input.RequestItems {
+		writes = append(writes, tableWrites...)
+	}
+	require.Len(t, writes, 1)
+	var attempt alertmodels.DeliveryAttempt
+	require.NoError(t, dynamodbattribute.UnmarshalMap(writes[0].PutRequest.Item, &attempt))
+	assert.Equal(t, "alert-id", attempt.AlertID)
+	assert.Equal(t, "output-id", attempt.OutputID)
+	assert.False(t, attempt.Success)
+	assert.True(t, attempt.NeedsRetry)
+	assert.Equal(t, 500, attempt.StatusCode)
+	assert.Equal(t, "request failed: 500", attempt.Error)
+	assert.Equal(t, 2, attempt.RetryCount)
+	assert.Contains(t, attempt.AttemptID, "#output-id")
+	assert.Zero(t, attempt.ExpiresAt) // expired when the alert is archived
+}
+
 func TestDispatchUseCachedDefault(t *testing.T) {
 	mockLambdaClient := &mockLambdaClient{}
 	lambdaClient = mockLambdaClient
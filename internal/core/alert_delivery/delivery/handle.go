@@ -62,6 +62,7 @@ func HandleAlerts(alerts []*models.Alert) {
 					zap.String("policyId", *alert.PolicyID),
 					zap.String("severity", *alert.Severity),
 				)
+				alert.RetryCount++
 				failedAlerts = append(failedAlerts, alert)
 			}
 		}
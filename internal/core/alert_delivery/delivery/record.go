@@ -0,0 +1,101 @@
+package delivery
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"go.uber.org/zap"
+
+	alertmodels "github.com/panther-labs/panther/internal/core/alert_delivery/models"
+	"github.com/panther-labs/panther/pkg/awsbatch/dynamodbbatch"
+)
+
+const (
+	maxDeliveryWriteBackoff = 10 * time.Second
+
+	// attempt ids start with the delivery time to sort the attempts of an alert chronologically
+	attemptTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+	// maxErrorLength is the number of bytes of the error of an output kept in its attempt, outputs can respond
+	// with whole HTML pages
+	maxErrorLength = 4096
+)
+
+func getAttemptRetention() time.Duration {
+	return time.Duration(mustParseInt(os.Getenv("ALERT_RETENTION_DAYS"))) * 24 * time.Hour
+}
+
+// recordDeliveryAttempts stores the outcome of sending an alert to each of its outputs.
+//
+// Failing to record the attempts does not fail the delivery, the outcome is still logged.
+func recordDeliveryAttempts(alert *alertmodels.Alert, statuses []outputStatus, deliveredAt time.Time) {
+	// the attempts of log alerts are expired by the alerts archiver, policy alerts are never archived
+	var expiresAt int64
+	if aws.StringValue(alert.Type) == alertmodels.PolicyType {
+		expiresAt = deliveredAt.Add(getAttemptRetention()).Unix()
+	}
+
+	writes := make([]*dynamodb.WriteRequest, len(statuses))
+	for i, status := range statuses {
+		attempt := &alertmodels.DeliveryAttempt{
+			AlertID:     *alert.AlertID,
+			AttemptID:   deliveredAt.Format(attemptTimeLayout) + "#" + status.outputID,
+			OutputID:    status.outputID,
+			DeliveredAt: deliveredAt,
+			Success:     status.success,
+			StatusCode:  status.statusCode,
+			Error:       truncateError(status.message),
+			NeedsRetry:  status.needsRetry,
+			RetryCount:  alert.RetryCount,
+			ExpiresAt:   expiresAt,
+		}
+		item, err := dynamodbattribute.MarshalMap(attempt)
+		if err != nil {
+			zap.L().Error("failed to marshal delivery attempt", zap.String("alertId", *alert.AlertID), zap.Error(err))
+			return
+		}
+		writes[i] = &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}}
+	}
+
+	input := &dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{os.Getenv("DELIVERIES_TABLE_NAME"): writes},
+	}
+	if err := dynamodbbatch.BatchWriteItem(getDynamoDBClient(), maxDeliveryWriteBackoff, input); err != nil {
+		zap.L().Error("failed to record delivery attempts", zap.String("alertId", *alert.AlertID),
+			zap.Strings("outputIds", aws.StringValueSlice(alert.OutputIDs)), zap.Error(err))
+	}
+}
+
+// truncateError shortens the error to maxErrorLength bytes without splitting a character
+func truncateError(message string) string {
+	if len(message) <= maxErrorLength {
+		return message
+	}
+	end := maxErrorLength
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end] + "... (truncated)"
+}
@@ -0,0 +1,74 @@
+package delivery
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	alertmodels "github.com/panther-labs/panther/internal/core/alert_delivery/models"
+	"github.com/panther-labs/panther/pkg/testutils"
+)
+
+func TestRecordDeliveryAttemptsPolicy(t *testing.T) {
+	mockDynamoDB := &testutils.DynamoDBMock{}
+	ddbClient = mockDynamoDB
+	os.Setenv("ALERT_RETENTION_DAYS", "30")
+	mockDynamoDB.On("BatchWriteItem", mock.Anything).Return(&dynamodb.BatchWriteItemOutput{}, nil).Once()
+
+	alert := sampleAlert()
+	alert.AlertID = aws.String("alert-id")
+	alert.Type = aws.String(alertmodels.PolicyType)
+	deliveredAt := time.Date(2020, 5, 10, 12, 0, 0, 0, time.UTC)
+	recordDeliveryAttempts(alert, []outputStatus{{
+		outputID: "output-id",
+		message:  strings.Repeat("<html>", 1000),
+	}}, deliveredAt)
+	mockDynamoDB.AssertExpectations(t)
+
+	input := mockDynamoDB.Calls[0].Arguments.Get(0).(*dynamodb.BatchWriteItemInput)
+	var writes []*dynamodb.WriteRequest
+	for _, tableWrites := range input.RequestItems {
+		writes = append(writes, tableWrites...)
+	}
+	require.Len(t, writes, 1)
+	var attempt alertmodels.DeliveryAttempt
+	require.NoError(t, dynamodbattribute.UnmarshalMap(writes[0].PutRequest.Item, &attempt))
+	assert.Equal(t, deliveredAt.AddDate(0, 0, 30).Unix(), attempt.ExpiresAt)
+	assert.True(t, strings.HasSuffix(attempt.Error, "... (truncated)"))
+	assert.Len(t, attempt.Error, maxErrorLength+len("... (truncated)"))
+}
+
+func TestTruncateError(t *testing.T) {
+	assert.Equal(t, "request failed: 500", truncateError("request failed: 500"))
+	// a character is not split
+	truncated := truncateError("a" + strings.Repeat("é", maxErrorLength))
+	assert.True(t, utf8.ValidString(truncated))
+	assert.Equal(t, "a"+strings.Repeat("é", maxErrorLength/2-1)+"... (truncated)", truncated)
+}
@@ -26,6 +26,9 @@ const RuleType = "RULE"
 // PolicyType identifies the Alert to be for a Policy
 const PolicyType = "POLICY"
 
+// IncidentType identifies the Alert to be for an incident of correlated rule alerts
+const IncidentType = "INCIDENT"
+
 // Alert is the schema for each row in the Dynamo alerts table.
 type Alert struct {
 
@@ -59,9 +62,45 @@ type Alert struct {
 	// AlertID specifies the alertId that this Alert is associated with.
 	AlertID *string `json:"alertId,omitempty"`
 
-	// Type specifies if an alert is for a policy or a rule
-	Type *string `json:"type,omitempty" validate:"omitempty,oneof=RULE POLICY"`
+	// Type specifies if an alert is for a policy, a rule or an incident
+	Type *string `json:"type,omitempty" validate:"omitempty,oneof=RULE POLICY INCIDENT"`
+
+	// IncidentID specifies the incident of an alert of type INCIDENT
+	IncidentID *string `json:"incidentId,omitempty"`
+
+	// IncidentUpdate describes how an existing incident changed, it is empty for a new incident
+	IncidentUpdate *string `json:"incidentUpdate,omitempty"`
 
 	// Title is the optional title for the alert
 	Title *string `json:"title,omitempty"`
+
+	// DedupString is the deduplication string of a rule alert
+	DedupString *string `json:"dedupString,omitempty"`
+
+	// LogTypes are the log types of the events matched by a rule alert
+	LogTypes []*string `json:"logTypes,omitempty"`
+
+	// EventSamples are the first events matched by a rule alert as JSON, with the fields selected by the rules engine
+	EventSamples []*string `json:"eventSamples,omitempty"`
+
+	// RetryCount is the number of times the delivery of the alert was retried
+	RetryCount int `json:"retryCount,omitempty"`
+}
+
+// DeliveryAttempt is the outcome of sending an alert to one of its outputs, stored in the deliveries table.
+type DeliveryAttempt struct {
+	AlertID string `json:"alertId"`
+	// AttemptID starts with the time of the attempt to sort the attempts of an alert chronologically
+	AttemptID   string    `json:"id"`
+	OutputID    string    `json:"outputId"`
+	DeliveredAt time.Time `json:"deliveredAt"`
+	Success     bool      `json:"success"`
+	// StatusCode is the HTTP status of the response of the output, if it responded
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+	NeedsRetry bool   `json:"needsRetry"`
+	RetryCount int    `json:"retryCount"`
+	// ExpiresAt is when DynamoDB deletes the attempt (seconds since epoch), the attempts of log alerts are expired
+	// when they are archived
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
 }
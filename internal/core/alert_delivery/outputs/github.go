@@ -44,10 +44,22 @@ func (client *OutputClient) Github(
 	runBook := "\n **Runbook:** " + aws.StringValue(alert.Runbook)
 	severity := "\n **Severity:** " + aws.StringValue(alert.Severity)
 	tags := "\n **Tags:** " + strings.Join(tagsItem, ", ")
+	var context string
+	if alert.DedupString != nil {
+		context += "\n **Dedup String:** " + *alert.DedupString
+	}
+	if len(alert.LogTypes) > 0 {
+		context += "\n **Log Types:** " + joinLogTypes(alert)
+	}
+	if len(alert.EventSamples) > 0 {
+		samples := joinEventSamples(alert)
+		fence := markdownFence(samples)
+		context += "\n **Event Samples:**\n" + fence + "json\n" + samples + "\n" + fence
+	}
 
 	githubRequest := map[string]interface{}{
 		"title": generateAlertTitle(alert),
-		"body":  description + link + runBook + severity + tags,
+		"body":  description + link + runBook + severity + tags + context,
 	}
 
 	token := "token " + *config.Token
@@ -63,3 +75,21 @@ func (client *OutputClient) Github(
 	}
 	return client.httpWrapper.post(postInput)
 }
+
+// markdownFence returns a code fence longer than the backtick runs of the text, so the text cannot close it
+func markdownFence(text string) string {
+	longest, run := 0, 0
+	for _, c := range text {
+		if c != '`' {
+			run = 0
+			continue
+		}
+		if run++; run > longest {
+			longest = run
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
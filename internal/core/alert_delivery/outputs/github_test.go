@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	outputmodels "github.com/panther-labs/panther/api/lambda/outputs/models"
@@ -68,3 +69,9 @@ func TestGithubAlert(t *testing.T) {
 	require.Nil(t, client.Github(alert, githubConfig))
 	httpWrapper.AssertExpectations(t)
 }
+
+func TestMarkdownFence(t *testing.T) {
+	assert.Equal(t, "```", markdownFence(`{"a":"`+"``"+`"}`))
+	// the samples cannot close the fence
+	assert.Equal(t, "`````", markdownFence(`{"a":"`+"````"+`","b":"`+"```"+`"}`))
+}
@@ -43,10 +43,20 @@ func (client *OutputClient) Jira(
 	runBook := "\n *Runbook:* " + aws.StringValue(alert.Runbook)
 	severity := "\n *Severity:* " + aws.StringValue(alert.Severity)
 	tags := "\n *Tags:* " + strings.Join(tagsItem, ", ")
+	var context string
+	if alert.DedupString != nil {
+		context += "\n *Dedup String:* " + *alert.DedupString
+	}
+	if len(alert.LogTypes) > 0 {
+		context += "\n *Log Types:* " + joinLogTypes(alert)
+	}
+	if len(alert.EventSamples) > 0 {
+		context += "\n *Event Samples:*\n{code:json}\n" + escapeJiraCode(joinEventSamples(alert)) + "\n{code}"
+	}
 
 	fields := map[string]interface{}{
 		"summary":     generateAlertTitle(alert),
-		"description": description + link + runBook + severity + tags,
+		"description": description + link + runBook + severity + tags + context,
 		"project": map[string]*string{
 			"key": config.ProjectKey,
 		},
@@ -79,3 +89,8 @@ func (client *OutputClient) Jira(
 	}
 	return client.httpWrapper.post(postInput)
 }
+
+// escapeJiraCode breaks the {code macros of the text with a zero width space, so the text cannot close the code block
+func escapeJiraCode(text string) string {
+	return strings.ReplaceAll(text, "{code", "{\u200bcode")
+}
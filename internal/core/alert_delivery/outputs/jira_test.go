@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	outputmodels "github.com/panther-labs/panther/api/lambda/outputs/models"
@@ -86,3 +87,9 @@ func TestJiraAlert(t *testing.T) {
 	require.Nil(t, client.Jira(alert, jiraConfig))
 	httpWrapper.AssertExpectations(t)
 }
+
+func TestEscapeJiraCode(t *testing.T) {
+	assert.Equal(t, `{"a":1}`, escapeJiraCode(`{"a":1}`))
+	// the samples cannot close the code block
+	assert.Equal(t, "{\"a\":\"{\u200bcode}\"}", escapeJiraCode(`{"a":"{code}"}`))
+}
@@ -38,6 +38,21 @@ func (client *OutputClient) MsTeams(
 	ruleDescription := aws.StringValue(alert.PolicyDescription)
 	severity := aws.StringValue(alert.Severity)
 	tags := strings.Join(tagsItem, ", ")
+	facts := []interface{}{
+		map[string]string{"name": "Description", "value": ruleDescription},
+		map[string]string{"name": "Runbook", "value": runBook},
+		map[string]string{"name": "Severity", "value": severity},
+		map[string]string{"name": "Tags", "value": tags},
+	}
+	if alert.DedupString != nil {
+		facts = append(facts, map[string]string{"name": "Dedup String", "value": *alert.DedupString})
+	}
+	if len(alert.LogTypes) > 0 {
+		facts = append(facts, map[string]string{"name": "Log Types", "value": joinLogTypes(alert)})
+	}
+	if len(alert.EventSamples) > 0 {
+		facts = append(facts, map[string]string{"name": "Event Samples", "value": joinEventSamples(alert)})
+	}
 
 	msTeamsRequestBody := map[string]interface{}{
 		"@context": "http://schema.org/extensions",
@@ -45,13 +60,8 @@ func (client *OutputClient) MsTeams(
 		"text":     generateAlertTitle(alert),
 		"sections": []interface{}{
 			map[string]interface{}{
-				"facts": []interface{}{
-					map[string]string{"name": "Description", "value": ruleDescription},
-					map[string]string{"name": "Runbook", "value": runBook},
-					map[string]string{"name": "Severity", "value": severity},
-					map[string]string{"name": "Tags", "value": tags},
-				},
-				"text": link,
+				"facts": facts,
+				"text":  link,
 			},
 		},
 		"potentialAction": []interface{}{
@@ -19,6 +19,8 @@ package outputs
  */
 
 import (
+	"html"
+
 	"github.com/aws/aws-sdk-go/aws"
 
 	outputmodels "github.com/panther-labs/panther/api/lambda/outputs/models"
@@ -47,10 +49,20 @@ func (client *OutputClient) Opsgenie(
 	link := "\n<a href=\"" + generateURL(alert) + "\">Click here to view in the Panther UI</a>"
 	runBook := "\n <strong>Runbook:</strong> " + aws.StringValue(alert.Runbook)
 	severity := "\n <strong>Severity:</strong> " + aws.StringValue(alert.Severity)
+	var context string
+	if alert.DedupString != nil {
+		context += "\n <strong>Dedup String:</strong> " + html.EscapeString(*alert.DedupString)
+	}
+	if len(alert.LogTypes) > 0 {
+		context += "\n <strong>Log Types:</strong> " + joinLogTypes(alert)
+	}
+	if len(alert.EventSamples) > 0 {
+		context += "\n <strong>Event Samples:</strong><pre>" + html.EscapeString(joinEventSamples(alert)) + "</pre>"
+	}
 
 	opsgenieRequest := map[string]interface{}{
 		"message":     generateAlertTitle(alert),
-		"description": description + link + runBook + severity,
+		"description": description + link + runBook + severity + context,
 		"tags":        tagsItem,
 		"priority":    pantherToOpsGeniePriority[aws.StringValue(alert.Severity)],
 	}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -98,7 +99,13 @@ func New(sess *session.Session) *OutputClient {
 const detailedMessageTemplate = "%s\nFor more details please visit: %s\nSeverity: %s\nRunbook: %s\nDescription:%s"
 
 func generateAlertMessage(alert *alertmodels.Alert) string {
-	if aws.StringValue(alert.Type) == alertmodels.RuleType {
+	switch aws.StringValue(alert.Type) {
+	case alertmodels.IncidentType:
+		if alert.IncidentUpdate != nil {
+			return "Incident " + aws.StringValue(alert.IncidentID) + " was updated: " + *alert.IncidentUpdate
+		}
+		return "Alerts of several rules were correlated into incident " + aws.StringValue(alert.IncidentID)
+	case alertmodels.RuleType:
 		return getDisplayName(alert) + " failed"
 	}
 	return getDisplayName(alert) + " failed on new resources"
@@ -112,10 +119,40 @@ func generateDetailedAlertMessage(alert *alertmodels.Alert) string {
 		aws.StringValue(alert.Severity),
 		aws.StringValue(alert.Runbook),
 		aws.StringValue(alert.PolicyDescription),
-	)
+	) + generateAlertContext(alert)
+}
+
+// generateAlertContext returns the dedup string, log types and event samples of a rule alert, if any
+func generateAlertContext(alert *alertmodels.Alert) string {
+	var context string
+	if alert.DedupString != nil {
+		context += "\nDedup String: " + *alert.DedupString
+	}
+	if len(alert.LogTypes) > 0 {
+		context += "\nLog Types: " + joinLogTypes(alert)
+	}
+	if len(alert.EventSamples) > 0 {
+		context += "\nEvent Samples:\n" + joinEventSamples(alert)
+	}
+	return context
+}
+
+func joinLogTypes(alert *alertmodels.Alert) string {
+	return strings.Join(aws.StringValueSlice(alert.LogTypes), ", ")
+}
+
+// joinEventSamples returns the JSON event samples of the alert, one per line
+func joinEventSamples(alert *alertmodels.Alert) string {
+	return strings.Join(aws.StringValueSlice(alert.EventSamples), "\n")
 }
 
 func generateAlertTitle(alert *alertmodels.Alert) string {
+	if aws.StringValue(alert.Type) == alertmodels.IncidentType {
+		if alert.IncidentUpdate != nil {
+			return "Updated Incident: " + aws.StringValue(alert.Title)
+		}
+		return "New Incident: " + aws.StringValue(alert.Title)
+	}
 	if alert.Title != nil {
 		return "New Alert: " + *alert.Title
 	}
@@ -133,7 +170,8 @@ func getDisplayName(alert *alertmodels.Alert) string {
 }
 
 func generateURL(alert *alertmodels.Alert) string {
-	if aws.StringValue(alert.Type) == alertmodels.RuleType {
+	// Incidents link to the alert which started them
+	if aws.StringValue(alert.Type) == alertmodels.RuleType || aws.StringValue(alert.Type) == alertmodels.IncidentType {
 		return alertURLPrefix + *alert.AlertID
 	}
 	return policyURLPrefix + *alert.PolicyID
@@ -82,3 +82,47 @@ func TestGenerateAlertTitlePolicyId(t *testing.T) {
 	}
 	assert.Equal(t, "Policy Failure: policy.id", generateAlertTitle(alert))
 }
+
+func TestGenerateAlertTitleIncident(t *testing.T) {
+	alert := &alertModel.Alert{
+		Type:       aws.String(alertModel.IncidentType),
+		Title:      aws.String("my title"),
+		IncidentID: aws.String("incident-id"),
+	}
+	assert.Equal(t, "New Incident: my title", generateAlertTitle(alert))
+}
+
+func TestGenerateAlertTitleIncidentUpdate(t *testing.T) {
+	alert := &alertModel.Alert{
+		Type:           aws.String(alertModel.IncidentType),
+		Title:          aws.String("my title"),
+		IncidentID:     aws.String("incident-id"),
+		IncidentUpdate: aws.String("Rule rule.id joined the incident"),
+	}
+	assert.Equal(t, "Updated Incident: my title", generateAlertTitle(alert))
+	assert.Equal(t, "Incident incident-id was updated: Rule rule.id joined the incident", generateAlertMessage(alert))
+}
+
+func TestGenerateURLIncident(t *testing.T) {
+	alert := &alertModel.Alert{
+		Type:       aws.String(alertModel.IncidentType),
+		AlertID:    aws.String("alert-id"),
+		IncidentID: aws.String("incident-id"),
+	}
+	assert.Equal(t, "https://panther.io/alerts/alert-id", generateURL(alert))
+	assert.Equal(t, "Alerts of several rules were correlated into incident incident-id", generateAlertMessage(alert))
+}
+
+func TestGenerateAlertContext(t *testing.T) {
+	alert := &alertModel.Alert{
+		Type:         aws.String(alertModel.RuleType),
+		DedupString:  aws.String("dedup"),
+		LogTypes:     aws.StringSlice([]string{"AWS.CloudTrail", "AWS.S3ServerAccess"}),
+		EventSamples: aws.StringSlice([]string{`{"a":1}`, `{"a":2}`}),
+	}
+	expected := "\nDedup String: dedup" +
+		"\nLog Types: AWS.CloudTrail, AWS.S3ServerAccess" +
+		"\nEvent Samples:\n{\"a\":1}\n{\"a\":2}"
+	assert.Equal(t, expected, generateAlertContext(alert))
+	assert.Equal(t, "", generateAlertContext(&alertModel.Alert{}))
+}
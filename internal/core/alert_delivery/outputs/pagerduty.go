@@ -54,15 +54,26 @@ func (client *OutputClient) PagerDuty(alert *alertmodels.Alert, config *outputmo
 		return err
 	}
 
+	customDetails := map[string]string{
+		"description": aws.StringValue(alert.PolicyDescription),
+		"runbook":     aws.StringValue(alert.Runbook),
+	}
+	if alert.DedupString != nil {
+		customDetails["dedupString"] = *alert.DedupString
+	}
+	if len(alert.LogTypes) > 0 {
+		customDetails["logTypes"] = joinLogTypes(alert)
+	}
+	if len(alert.EventSamples) > 0 {
+		customDetails["eventSamples"] = joinEventSamples(alert)
+	}
+
 	payload := map[string]interface{}{
-		"summary":   generateAlertTitle(alert),
-		"severity":  aws.StringValue(severity),
-		"timestamp": alert.CreatedAt.Format(time.RFC3339),
-		"source":    "pantherlabs",
-		"custom_details": map[string]string{
-			"description": aws.StringValue(alert.PolicyDescription),
-			"runbook":     aws.StringValue(alert.Runbook),
-		},
+		"summary":        generateAlertTitle(alert),
+		"severity":       aws.StringValue(severity),
+		"timestamp":      alert.CreatedAt.Format(time.RFC3339),
+		"source":         "pantherlabs",
+		"custom_details": customDetails,
 	}
 
 	pagerDutyRequest := map[string]interface{}{
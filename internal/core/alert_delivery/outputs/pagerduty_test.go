@@ -84,3 +84,46 @@ func TestSendPagerDutyAlertPostError(t *testing.T) {
 	require.Error(t, outputClient.PagerDuty(pagerDutyAlert, pagerDutyConfig))
 	httpWrapper.AssertExpectations(t)
 }
+
+func TestSendPagerDutyAlertWithRuleContext(t *testing.T) {
+	httpWrapper := &mockHTTPWrapper{}
+	outputClient := &OutputClient{httpWrapper: httpWrapper}
+	alert := &alertmodels.Alert{
+		Type:         aws.String(alertmodels.RuleType),
+		PolicyName:   aws.String("ruleName"),
+		PolicyID:     aws.String("ruleId"),
+		Severity:     aws.String("INFO"),
+		Runbook:      aws.String("runbook"),
+		CreatedAt:    &createdAtTime,
+		DedupString:  aws.String("dedup"),
+		LogTypes:     aws.StringSlice([]string{"AWS.CloudTrail", "AWS.S3ServerAccess"}),
+		EventSamples: aws.StringSlice([]string{`{"a":1}`, `{"a":2}`}),
+	}
+
+	expectedPostPayload := map[string]interface{}{
+		"event_action": "trigger",
+		"payload": map[string]interface{}{
+			"custom_details": map[string]string{
+				"description":  "",
+				"runbook":      "runbook",
+				"dedupString":  "dedup",
+				"logTypes":     "AWS.CloudTrail, AWS.S3ServerAccess",
+				"eventSamples": "{\"a\":1}\n{\"a\":2}",
+			},
+			"severity":  "info",
+			"source":    "pantherlabs",
+			"summary":   "New Alert: ruleName",
+			"timestamp": "2019-05-03T11:40:13Z",
+		},
+		"routing_key": "integrationKey",
+	}
+	expectedPostInput := &PostInput{
+		url:  "https://events.pagerduty.com/v2/enqueue",
+		body: expectedPostPayload,
+	}
+
+	httpWrapper.On("post", expectedPostInput).Return((*AlertDeliveryError)(nil))
+
+	assert.Nil(t, outputClient.PagerDuty(alert, pagerDutyConfig))
+	httpWrapper.AssertExpectations(t)
+}
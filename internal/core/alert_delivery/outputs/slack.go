@@ -57,17 +57,39 @@ func (client *OutputClient) Slack(alert *alertmodels.Alert, config *outputmodels
 			"short": true,
 		},
 	}
+	if alert.DedupString != nil {
+		fields = append(fields, map[string]interface{}{
+			"title": "Dedup String",
+			"value": *alert.DedupString,
+			"short": true,
+		})
+	}
+	if len(alert.LogTypes) > 0 {
+		fields = append(fields, map[string]interface{}{
+			"title": "Log Types",
+			"value": joinLogTypes(alert),
+			"short": true,
+		})
+	}
 
-	payload := map[string]interface{}{
-		"attachments": []map[string]interface{}{
-			{
-				"fallback": generateAlertTitle(alert),
-				"color":    severityColors[aws.StringValue(alert.Severity)],
-				"title":    generateAlertTitle(alert),
-				"fields":   fields,
-			},
+	attachments := []map[string]interface{}{
+		{
+			"fallback": generateAlertTitle(alert),
+			"color":    severityColors[aws.StringValue(alert.Severity)],
+			"title":    generateAlertTitle(alert),
+			"fields":   fields,
 		},
 	}
+	if len(alert.EventSamples) > 0 {
+		attachments = append(attachments, map[string]interface{}{
+			"color":  severityColors[aws.StringValue(alert.Severity)],
+			"blocks": eventSampleBlocks(alert),
+		})
+	}
+
+	payload := map[string]interface{}{
+		"attachments": attachments,
+	}
 	requestEndpoint := *config.WebhookURL
 	postInput := &PostInput{
 		url:  requestEndpoint,
@@ -76,3 +98,28 @@ func (client *OutputClient) Slack(alert *alertmodels.Alert, config *outputmodels
 
 	return client.httpWrapper.post(postInput)
 }
+
+// eventSampleBlocks returns the event samples as preformatted rich text, which Slack displays as is: the samples
+// can contain backticks and other markup
+func eventSampleBlocks(alert *alertmodels.Alert) []map[string]interface{} {
+	blocks := []map[string]interface{}{
+		{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": "*Event Samples*"},
+		},
+	}
+	for _, sample := range alert.EventSamples {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "rich_text",
+			"elements": []map[string]interface{}{
+				{
+					"type": "rich_text_preformatted",
+					"elements": []map[string]interface{}{
+						{"type": "text", "text": aws.StringValue(sample)},
+					},
+				},
+			},
+		})
+	}
+	return blocks
+}
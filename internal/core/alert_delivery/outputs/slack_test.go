@@ -79,3 +79,97 @@ func TestSlackAlert(t *testing.T) {
 	require.Nil(t, client.Slack(alert, slackConfig))
 	httpWrapper.AssertExpectations(t)
 }
+
+func TestSlackAlertWithRuleContext(t *testing.T) {
+	httpWrapper := &mockHTTPWrapper{}
+	client := &OutputClient{httpWrapper: httpWrapper}
+
+	createdAtTime := time.Now()
+	alert := &alertmodels.Alert{
+		AlertID:      aws.String("alertId"),
+		Type:         aws.String(alertmodels.RuleType),
+		PolicyID:     aws.String("ruleId"),
+		CreatedAt:    &createdAtTime,
+		OutputIDs:    aws.StringSlice([]string{"output-id"}),
+		PolicyName:   aws.String("ruleName"),
+		Severity:     aws.String("INFO"),
+		DedupString:  aws.String("dedup"),
+		LogTypes:     aws.StringSlice([]string{"AWS.CloudTrail"}),
+		EventSamples: aws.StringSlice([]string{`{"a":1}`, "{\"a\":\"```\"}"}),
+	}
+
+	expectedPostPayload := map[string]interface{}{
+		"attachments": []map[string]interface{}{
+			{"color": "#47b881",
+				"fallback": "New Alert: ruleName",
+				"fields": []map[string]interface{}{
+					{
+						"short": false,
+						"value": "<https://panther.io/alerts/alertId|Click here to view in the Panther UI>",
+					},
+					{
+						"short": false,
+						"title": "Runbook",
+						"value": "",
+					},
+					{
+						"short": true,
+						"title": "Severity",
+						"value": "INFO",
+					},
+					{
+						"short": true,
+						"title": "Dedup String",
+						"value": "dedup",
+					},
+					{
+						"short": true,
+						"title": "Log Types",
+						"value": "AWS.CloudTrail",
+					},
+				},
+				"title": "New Alert: ruleName",
+			},
+			{
+				"color": "#47b881",
+				"blocks": []map[string]interface{}{
+					{
+						"type": "section",
+						"text": map[string]interface{}{"type": "mrkdwn", "text": "*Event Samples*"},
+					},
+					{
+						"type": "rich_text",
+						"elements": []map[string]interface{}{
+							{
+								"type": "rich_text_preformatted",
+								"elements": []map[string]interface{}{
+									{"type": "text", "text": `{"a":1}`},
+								},
+							},
+						},
+					},
+					{
+						"type": "rich_text",
+						"elements": []map[string]interface{}{
+							{
+								"type": "rich_text_preformatted",
+								"elements": []map[string]interface{}{
+									{"type": "text", "text": "{\"a\":\"```\"}"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	expectedPostInput := &PostInput{
+		url:  "slack-channel-url",
+		body: expectedPostPayload,
+	}
+
+	httpWrapper.On("post", expectedPostInput).Return((*AlertDeliveryError)(nil))
+
+	require.Nil(t, client.Slack(alert, slackConfig))
+	httpWrapper.AssertExpectations(t)
+}
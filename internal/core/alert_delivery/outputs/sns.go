@@ -46,6 +46,10 @@ type snsDefaultMessage struct {
 	Runbook     *string   `json:"runbook,omitempty"`
 	Severity    *string   `json:"severity"`
 	Tags        []*string `json:"tags,omitempty"`
+	// The context of rule alerts
+	DedupString  *string   `json:"dedupString,omitempty"`
+	LogTypes     []*string `json:"logTypes,omitempty"`
+	EventSamples []*string `json:"eventSamples,omitempty"`
 }
 
 // Sns sends an alert to an SNS Topic.
@@ -59,6 +63,10 @@ func (client *OutputClient) Sns(alert *alertmodels.Alert, config *outputmodels.S
 		Runbook:     alert.Runbook,
 		Severity:    alert.Severity,
 		Tags:        alert.Tags,
+
+		DedupString:  alert.DedupString,
+		LogTypes:     alert.LogTypes,
+		EventSamples: alert.EventSamples,
 	}
 
 	serializedDefaultMessage, err := jsoniter.MarshalToString(snsDefaultMessage)
@@ -42,6 +42,10 @@ func (client *OutputClient) Sqs(alert *alertmodels.Alert, config *outputmodels.S
 		Runbook:     alert.Runbook,
 		Severity:    alert.Severity,
 		Tags:        alert.Tags,
+
+		DedupString:  alert.DedupString,
+		LogTypes:     alert.LogTypes,
+		EventSamples: alert.EventSamples,
 	}
 
 	serializedMessage, err := jsoniter.MarshalToString(outputMessage)
@@ -74,6 +78,10 @@ type sqsOutputMessage struct {
 	Runbook     *string   `json:"runbook,omitempty"`
 	Severity    *string   `json:"severity"`
 	Tags        []*string `json:"tags,omitempty"`
+	// The context of rule alerts
+	DedupString  *string   `json:"dedupString,omitempty"`
+	LogTypes     []*string `json:"logTypes,omitempty"`
+	EventSamples []*string `json:"eventSamples,omitempty"`
 }
 
 func (client *OutputClient) getSqsClient(queueURL string) sqsiface.SQSAPI {
@@ -220,6 +220,11 @@ func extractZipFile(input *models.BulkUpload) (map[models.ID]*tableItem, error)
 			} else {
 				analysisItem.DedupPeriodMinutes = models.DedupPeriodMinutes(config.DedupPeriodMinutes)
 			}
+			if config.Threshold == 0 {
+				analysisItem.Threshold = defaultThreshold
+			} else {
+				analysisItem.Threshold = models.Threshold(config.Threshold)
+			}
 
 			// These "syntax sugar" re-mappings are to make managing rules from the CLI more intuitive
 			if config.PolicyID == "" {
@@ -31,6 +31,7 @@ import (
 
 const (
 	defaultDedupPeriodMinutes = 60
+	defaultThreshold          = 1
 )
 
 // CreateRule adds a new rule to the Dynamo table.
@@ -54,6 +55,7 @@ func CreateRule(request *events.APIGatewayProxyRequest) *events.APIGatewayProxyR
 		Tests:              input.Tests,
 		Type:               typeRule,
 		DedupPeriodMinutes: input.DedupPeriodMinutes,
+		Threshold:          input.Threshold,
 	}
 
 	if _, err := writeItem(item, input.UserID, aws.Bool(false)); err != nil {
@@ -77,6 +79,9 @@ func parseUpdateRule(request *events.APIGatewayProxyRequest) (*models.UpdateRule
 	if result.DedupPeriodMinutes == 0 {
 		result.DedupPeriodMinutes = defaultDedupPeriodMinutes
 	}
+	if result.Threshold == 0 {
+		result.Threshold = defaultThreshold
+	}
 
 	if err := result.Validate(nil); err != nil {
 		return nil, err
@@ -37,6 +37,7 @@ import (
 const (
 	typePolicy       = string(models.AnalysisTypePOLICY)
 	typeRule         = string(models.AnalysisTypeRULE)
+	typeQuery        = string(models.AnalysisTypeSCHEDULEDQUERY)
 	maxDynamoBackoff = 30 * time.Second
 )
 
@@ -66,8 +67,14 @@ type tableItem struct {
 	Tests                     []*models.UnitTest               `json:"tests,omitempty"`
 	VersionID                 models.VersionID                 `json:"versionId,omitempty"`
 	DedupPeriodMinutes        models.DedupPeriodMinutes        `json:"dedupPeriodMinutes,omitempty"`
+	Threshold                 models.Threshold                 `json:"threshold,omitempty"`
 
-	// Logic type (policy or rule)
+	// Scheduled queries only
+	DedupColumns models.DedupColumns `json:"dedupColumns,omitempty"`
+	Schedule     models.Schedule     `json:"schedule,omitempty"`
+	SQL          models.SQL          `json:"sql,omitempty"`
+
+	// Logic type (policy, rule, or scheduled query)
 	Type string `json:"type"`
 
 	// Lowercase versions of string fields for easy filtering
@@ -160,6 +167,38 @@ func (r *tableItem) Rule() *models.Rule {
 		Tests:              r.Tests,
 		VersionID:          r.VersionID,
 		DedupPeriodMinutes: r.DedupPeriodMinutes,
+		Threshold:          r.Threshold,
+	}
+	// Rules created before thresholds existed alert on every match
+	if result.Threshold == 0 {
+		result.Threshold = defaultThreshold
+	}
+	gatewayapi.ReplaceMapSliceNils(result)
+	return result
+}
+
+// ScheduledQuery converts a Dynamo row into a ScheduledQuery external model.
+func (r *tableItem) ScheduledQuery() *models.ScheduledQuery {
+	r.normalize()
+	result := &models.ScheduledQuery{
+		CreatedAt:          r.CreatedAt,
+		CreatedBy:          r.CreatedBy,
+		DedupColumns:       r.DedupColumns,
+		DedupPeriodMinutes: r.DedupPeriodMinutes,
+		Description:        r.Description,
+		DisplayName:        r.DisplayName,
+		Enabled:            r.Enabled,
+		ID:                 r.ID,
+		LastModified:       r.LastModified,
+		LastModifiedBy:     r.LastModifiedBy,
+		LogTypes:           r.ResourceTypes,
+		Reference:          r.Reference,
+		Runbook:            r.Runbook,
+		Schedule:           r.Schedule,
+		Severity:           r.Severity,
+		SQL:                r.SQL,
+		Tags:               r.Tags,
+		VersionID:          r.VersionID,
 	}
 	gatewayapi.ReplaceMapSliceNils(result)
 	return result
@@ -45,7 +45,12 @@ func GetRule(request *events.APIGatewayProxyRequest) *events.APIGatewayProxyResp
 	return handleGet(request, typeRule)
 }
 
-// Handle GET request for GetPolicy and GetRule
+// GetScheduledQuery retrieves a scheduled query from Dynamo or S3.
+func GetScheduledQuery(request *events.APIGatewayProxyRequest) *events.APIGatewayProxyResponse {
+	return handleGet(request, typeQuery)
+}
+
+// Handle GET request for GetPolicy, GetRule and GetScheduledQuery
 func handleGet(request *events.APIGatewayProxyRequest, codeType string) *events.APIGatewayProxyResponse {
 	input, err := parseGet(request, codeType)
 	if err != nil {
@@ -81,19 +86,25 @@ func handleGet(request *events.APIGatewayProxyRequest, codeType string) *events.
 		}
 		return gatewayapi.MarshalResponse(item.Policy(status.Status), http.StatusOK)
 	}
+	if codeType == typeQuery {
+		return gatewayapi.MarshalResponse(item.ScheduledQuery(), http.StatusOK)
+	}
 
 	return gatewayapi.MarshalResponse(item.Rule(), http.StatusOK)
 }
 
-// Parse GET parameters for GetPolicy and GetRule
+// Parse GET parameters for GetPolicy, GetRule and GetScheduledQuery
 func parseGet(request *events.APIGatewayProxyRequest, codeType string) (*getParams, error) {
 	params := &getParams{
 		VersionID: models.VersionID(request.QueryStringParameters["versionId"]),
 	}
 
 	idKey := "policyId"
-	if codeType == typeRule {
+	switch codeType {
+	case typeRule:
 		idKey = "ruleId"
+	case typeQuery:
+		idKey = "queryId"
 	}
 	id, err := url.QueryUnescape(request.QueryStringParameters[idKey])
 	if err != nil {
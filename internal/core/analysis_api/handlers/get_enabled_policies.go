@@ -54,6 +54,9 @@ func GetEnabledPolicies(request *events.APIGatewayProxyRequest) *events.APIGatew
 			Suppressions:       policy.Suppressions,
 			VersionID:          policy.VersionID,
 			DedupPeriodMinutes: policy.DedupPeriodMinutes,
+			DedupColumns:       policy.DedupColumns,
+			Schedule:           policy.Schedule,
+			SQL:                policy.SQL,
 		})
 		return nil
 	})
@@ -85,6 +88,9 @@ func buildEnabledScan(ruleType string) (*dynamodb.ScanInput, error) {
 		expression.Name("suppressions"),
 		expression.Name("versionId"),
 		expression.Name("dedupPeriodMinutes"),
+		expression.Name("dedupColumns"),
+		expression.Name("schedule"),
+		expression.Name("sql"),
 	)
 
 	expr, err := expression.NewBuilder().
@@ -277,6 +277,7 @@ func buildListScan(params *listParams, codeType string) (*dynamodb.ScanInput, er
 		expression.Name("id"),
 		expression.Name("lastModified"),
 		expression.Name("resourceTypes"),
+		expression.Name("schedule"),
 		expression.Name("severity"),
 		expression.Name("suppressions"),
 		expression.Name("tags"),
@@ -349,8 +350,8 @@ func buildListScan(params *listParams, codeType string) (*dynamodb.ScanInput, er
 func listFiltered(scanInput *dynamodb.ScanInput, params *listParams) ([]*models.PolicySummary, error) {
 	var result []*models.PolicySummary
 	err := scanPages(scanInput, func(item *tableItem) error {
-		if item.Type == typeRule {
-			// Log analysis rules do not have a compliance status
+		if item.Type != typePolicy {
+			// Log analysis rules and scheduled queries do not have a compliance status
 			result = append(result, item.PolicySummary(""))
 			return nil
 		}
@@ -48,6 +48,7 @@ func ModifyRule(request *events.APIGatewayProxyRequest) *events.APIGatewayProxyR
 		Tests:              input.Tests,
 		Type:               typeRule,
 		DedupPeriodMinutes: input.DedupPeriodMinutes,
+		Threshold:          input.Threshold,
 	}
 
 	if _, err := writeItem(item, input.UserID, aws.Bool(true)); err != nil {
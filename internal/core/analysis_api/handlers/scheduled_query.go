@@ -0,0 +1,171 @@
+package handlers
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/panther-labs/panther/api/gateway/analysis/models"
+	"github.com/panther-labs/panther/pkg/awsathena"
+	"github.com/panther-labs/panther/pkg/cron"
+	"github.com/panther-labs/panther/pkg/gatewayapi"
+)
+
+// CreateScheduledQuery adds a new scheduled query to the Dynamo table.
+func CreateScheduledQuery(request *events.APIGatewayProxyRequest) *events.APIGatewayProxyResponse {
+	input, err := parseUpdateScheduledQuery(request)
+	if err != nil {
+		return badRequest(err)
+	}
+
+	item := scheduledQueryItem(input)
+	if _, err := writeItem(item, input.UserID, aws.Bool(false)); err != nil {
+		if err == errExists {
+			return &events.APIGatewayProxyResponse{StatusCode: http.StatusConflict}
+		}
+		return &events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}
+	}
+
+	return gatewayapi.MarshalResponse(item.ScheduledQuery(), http.StatusCreated)
+}
+
+// ModifyScheduledQuery updates an existing scheduled query.
+func ModifyScheduledQuery(request *events.APIGatewayProxyRequest) *events.APIGatewayProxyResponse {
+	input, err := parseUpdateScheduledQuery(request)
+	if err != nil {
+		return badRequest(err)
+	}
+
+	item := scheduledQueryItem(input)
+	if _, err := writeItem(item, input.UserID, aws.Bool(true)); err != nil {
+		if err == errNotExists || err == errWrongType {
+			// errWrongType means we tried to modify a scheduled query which is actually a rule or policy.
+			return &events.APIGatewayProxyResponse{StatusCode: http.StatusNotFound}
+		}
+		return &events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}
+	}
+
+	return gatewayapi.MarshalResponse(item.ScheduledQuery(), http.StatusOK)
+}
+
+// ListScheduledQueries pages through scheduled queries.
+func ListScheduledQueries(request *events.APIGatewayProxyRequest) *events.APIGatewayProxyResponse {
+	params, err := parseList(request, typeQuery)
+	if err != nil {
+		return badRequest(err)
+	}
+
+	scanInput, err := buildListScan(params, typeQuery)
+	if err != nil {
+		return &events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}
+	}
+
+	// Share the sort and paging logic of rules and policies, keeping track of the schedules on the side
+	var summaries []*models.PolicySummary
+	schedules := make(map[models.ID]models.Schedule)
+	err = scanPages(scanInput, func(item *tableItem) error {
+		summaries = append(summaries, item.PolicySummary(""))
+		schedules[item.ID] = item.Schedule
+		return nil
+	})
+	if err != nil {
+		return &events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}
+	}
+
+	if len(summaries) == 0 {
+		paging := &models.Paging{
+			ThisPage:   aws.Int64(0),
+			TotalItems: aws.Int64(0),
+			TotalPages: aws.Int64(0),
+		}
+		return gatewayapi.MarshalResponse(
+			&models.ScheduledQueryList{Paging: paging, Queries: []*models.ScheduledQuerySummary{}}, http.StatusOK)
+	}
+
+	sortPolicies(summaries, params.sortBy, params.sortAscending)
+	result := pagePolicies(summaries, params.pageSize, params.page)
+
+	queryResult := &models.ScheduledQueryList{
+		Paging:  result.Paging,
+		Queries: make([]*models.ScheduledQuerySummary, len(result.Policies)),
+	}
+	for i, policy := range result.Policies {
+		queryResult.Queries[i] = &models.ScheduledQuerySummary{
+			DisplayName:  policy.DisplayName,
+			Enabled:      policy.Enabled,
+			ID:           policy.ID,
+			LastModified: policy.LastModified,
+			Schedule:     schedules[policy.ID],
+			Severity:     policy.Severity,
+			Tags:         policy.Tags,
+		}
+	}
+	return gatewayapi.MarshalResponse(queryResult, http.StatusOK)
+}
+
+// body parsing shared by CreateScheduledQuery and ModifyScheduledQuery
+func parseUpdateScheduledQuery(request *events.APIGatewayProxyRequest) (*models.UpdateScheduledQuery, error) {
+	var result models.UpdateScheduledQuery
+	if err := jsoniter.UnmarshalFromString(request.Body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.DedupPeriodMinutes == 0 {
+		result.DedupPeriodMinutes = defaultDedupPeriodMinutes
+	}
+
+	if err := result.Validate(nil); err != nil {
+		return nil, err
+	}
+
+	if _, err := cron.Parse(string(result.Schedule)); err != nil {
+		return nil, errors.New("invalid schedule: " + err.Error())
+	}
+
+	// the scheduler runs the query as is, it must pass the same checks as the queries of the query API
+	if err := awsathena.ValidateReadOnlySQL(string(result.SQL)); err != nil {
+		return nil, errors.New("invalid sql: " + err.Error())
+	}
+
+	return &result, nil
+}
+
+func scheduledQueryItem(input *models.UpdateScheduledQuery) *tableItem {
+	return &tableItem{
+		DedupColumns:       input.DedupColumns,
+		DedupPeriodMinutes: input.DedupPeriodMinutes,
+		Description:        input.Description,
+		DisplayName:        input.DisplayName,
+		Enabled:            input.Enabled,
+		ID:                 input.ID,
+		Reference:          input.Reference,
+		ResourceTypes:      input.LogTypes,
+		Runbook:            input.Runbook,
+		Schedule:           input.Schedule,
+		Severity:           input.Severity,
+		SQL:                input.SQL,
+		Tags:               input.Tags,
+		Type:               typeQuery,
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/s3"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/gateway/analysis/models"
+	"github.com/panther-labs/panther/pkg/testutils"
+)
+
+const scheduledQueryUserID = "f6cfad0a-9bb0-4681-9503-02c54cc979c7"
+
+func scheduledQueryRequest(t *testing.T, modify func(query *models.UpdateScheduledQuery)) *events.APIGatewayProxyRequest {
+	query := &models.UpdateScheduledQuery{
+		ID:       "Failed.Logins",
+		Enabled:  true,
+		Schedule: "0 * * * *",
+		Severity: models.SeverityHIGH,
+		SQL:      "SELECT sourceIPAddress FROM aws_cloudtrail WHERE errorCode = 'AccessDenied'",
+		UserID:   scheduledQueryUserID,
+	}
+	if modify != nil {
+		modify(query)
+	}
+	body, err := jsoniter.MarshalToString(query)
+	require.NoError(t, err)
+	return &events.APIGatewayProxyRequest{Body: body}
+}
+
+func TestCreateScheduledQuery(t *testing.T) {
+	dynamoMock := &testutils.DynamoDBMock{}
+	dynamoClient = dynamoMock
+	s3Mock := &testutils.S3Mock{}
+	s3Client = s3Mock
+
+	dynamoMock.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{}, nil).Once()
+	s3Mock.On("PutObject", mock.Anything).Return(&s3.PutObjectOutput{VersionId: aws.String("v1")}, nil).Once()
+	dynamoMock.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil).Once()
+
+	response := CreateScheduledQuery(scheduledQueryRequest(t, nil))
+	require.Equal(t, http.StatusCreated, response.StatusCode, response.Body)
+	dynamoMock.AssertExpectations(t)
+	s3Mock.AssertExpectations(t)
+
+	var stored tableItem
+	putInput := dynamoMock.Calls[1].Arguments.Get(0).(*dynamodb.PutItemInput)
+	require.NoError(t, dynamodbattribute.UnmarshalMap(putInput.Item, &stored))
+	assert.Equal(t, typeQuery, stored.Type)
+	assert.Equal(t, models.DedupPeriodMinutes(defaultDedupPeriodMinutes), stored.DedupPeriodMinutes)
+	assert.Equal(t, models.VersionID("v1"), stored.VersionID)
+	assert.Equal(t, models.UserID(scheduledQueryUserID), stored.CreatedBy)
+
+	var result models.ScheduledQuery
+	require.NoError(t, jsoniter.UnmarshalFromString(response.Body, &result))
+	assert.Equal(t, models.ID("Failed.Logins"), result.ID)
+	assert.Equal(t, models.Schedule("0 * * * *"), result.Schedule)
+}
+
+func TestCreateScheduledQueryExists(t *testing.T) {
+	dynamoMock := &testutils.DynamoDBMock{}
+	dynamoClient = dynamoMock
+
+	existing, err := dynamodbattribute.MarshalMap(&tableItem{ID: "Failed.Logins", Type: typeQuery})
+	require.NoError(t, err)
+	dynamoMock.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{Item: existing}, nil).Once()
+
+	response := CreateScheduledQuery(scheduledQueryRequest(t, nil))
+	assert.Equal(t, http.StatusConflict, response.StatusCode)
+	dynamoMock.AssertExpectations(t)
+}
+
+func TestCreateScheduledQueryInvalid(t *testing.T) {
+	for name, modify := range map[string]func(query *models.UpdateScheduledQuery){
+		"schedule":   func(query *models.UpdateScheduledQuery) { query.Schedule = "every hour" },
+		"write":      func(query *models.UpdateScheduledQuery) { query.SQL = "DROP TABLE aws_cloudtrail" },
+		"statements": func(query *models.UpdateScheduledQuery) { query.SQL = "SELECT 1; DELETE FROM aws_cloudtrail" },
+		"database":   func(query *models.UpdateScheduledQuery) { query.SQL = "SELECT * FROM panther_rule_matches.aws_cloudtrail" },
+		"emptySql":   func(query *models.UpdateScheduledQuery) { query.SQL = "" },
+	} {
+		dynamoMock := &testutils.DynamoDBMock{}
+		dynamoClient = dynamoMock
+
+		response := CreateScheduledQuery(scheduledQueryRequest(t, modify))
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode, name)
+		dynamoMock.AssertExpectations(t) // nothing is read or stored
+	}
+}
+
+func TestModifyScheduledQueryNotFound(t *testing.T) {
+	dynamoMock := &testutils.DynamoDBMock{}
+	dynamoClient = dynamoMock
+	dynamoMock.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{}, nil).Once()
+
+	response := ModifyScheduledQuery(scheduledQueryRequest(t, nil))
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	dynamoMock.AssertExpectations(t)
+}
+
+func TestModifyScheduledQueryWrongType(t *testing.T) {
+	dynamoMock := &testutils.DynamoDBMock{}
+	dynamoClient = dynamoMock
+
+	existing, err := dynamodbattribute.MarshalMap(&tableItem{ID: "Failed.Logins", Type: typeRule})
+	require.NoError(t, err)
+	dynamoMock.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{Item: existing}, nil).Once()
+
+	response := ModifyScheduledQuery(scheduledQueryRequest(t, nil))
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	dynamoMock.AssertExpectations(t)
+}
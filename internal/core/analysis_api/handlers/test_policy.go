@@ -47,6 +47,9 @@ func TestPolicy(request *events.APIGatewayProxyRequest) *events.APIGatewayProxyR
 	if err != nil {
 		return badRequest(err)
 	}
+	if input.AnalysisType == models.AnalysisTypeSCHEDULEDQUERY {
+		return badRequest(errors.New("scheduled queries do not have unit tests"))
+	}
 
 	var results *enginemodels.PolicyEngineOutput
 	// Build the policy engine request
@@ -165,7 +165,7 @@ func policiesEqual(first, second *tableItem) (bool, error) {
 	return reflect.DeepEqual(p1, p2), nil
 }
 
-// Create/update a policy or rule.
+// Create/update a policy, rule, or scheduled query.
 //
 // The following fields are set automatically (need not be set by the caller):
 //     CreatedAt, CreatedBy, LastModified, LastModifiedBy, VersionID
@@ -226,7 +226,7 @@ func writeItem(item *tableItem, userID models.UserID, mustExist *bool) (int, err
 		return changeType, err
 	}
 
-	if item.Type == typeRule {
+	if item.Type != typePolicy {
 		return changeType, nil
 	}
 
@@ -196,6 +196,7 @@ var (
 		Severity:           "HIGH",
 		Tests:              []*models.UnitTest{},
 		DedupPeriodMinutes: 1440,
+		Threshold:          10,
 	}
 )
 
@@ -542,6 +543,7 @@ func createRuleSuccess(t *testing.T) {
 			Severity:           rule.Severity,
 			UserID:             userID,
 			DedupPeriodMinutes: rule.DedupPeriodMinutes,
+			Threshold:          rule.Threshold,
 		},
 		HTTPClient: httpClient,
 	})
@@ -702,6 +704,7 @@ func modifyRule(t *testing.T) {
 			Severity:           rule.Severity,
 			UserID:             userID,
 			DedupPeriodMinutes: rule.DedupPeriodMinutes,
+			Threshold:          rule.Threshold,
 		},
 		HTTPClient: httpClient,
 	})
@@ -40,7 +40,13 @@ var methodHandlers = map[string]gatewayapi.RequestHandler{
 	"GET /rule/list":    handlers.ListRules,
 	"POST /rule/update": handlers.ModifyRule,
 
-	// Rules and Policies
+	// Scheduled queries only
+	"GET /query":         handlers.GetScheduledQuery,
+	"POST /query":        handlers.CreateScheduledQuery,
+	"GET /query/list":    handlers.ListScheduledQueries,
+	"POST /query/update": handlers.ModifyScheduledQuery,
+
+	// Rules, Policies and Scheduled queries
 	"POST /delete": handlers.DeletePolicies,
 	"GET /enabled": handlers.GetEnabledPolicies,
 	"POST /test":   handlers.TestPolicy,
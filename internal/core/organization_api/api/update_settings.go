@@ -18,9 +18,33 @@ package api
  * along with this program.  If not, see <https://www.gnu.org/licenses/>.
  */
 
-import "github.com/panther-labs/panther/api/lambda/organization/models"
+import (
+	"github.com/panther-labs/panther/api/lambda/organization/models"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
 
 // UpdateSettings updates account settings.
 func (API) UpdateSettings(input *models.UpdateSettingsInput) (*models.GeneralSettings, error) {
+	if err := validateLogRetention(input.LogRetention); err != nil {
+		return nil, err
+	}
 	return orgTable.Update(input)
 }
+
+// The log types must be unique, and data must be moved to Glacier before it expires
+func validateLogRetention(retention []*models.LogRetention) error {
+	logTypes := make(map[string]bool, len(retention))
+	for _, logRetention := range retention {
+		logType := *logRetention.LogType
+		if logTypes[logType] {
+			return &genericapi.InvalidInputError{Message: "duplicate retention for log type " + logType}
+		}
+		logTypes[logType] = true
+
+		if logRetention.GlacierDays != nil && *logRetention.GlacierDays >= *logRetention.ExpireDays {
+			return &genericapi.InvalidInputError{
+				Message: "glacierDays must be less than expireDays for log type " + logType}
+		}
+	}
+	return nil
+}
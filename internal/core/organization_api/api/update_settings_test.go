@@ -0,0 +1,58 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/panther-labs/panther/api/lambda/organization/models"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+func TestValidateLogRetention(t *testing.T) {
+	assert.NoError(t, validateLogRetention(nil))
+	assert.NoError(t, validateLogRetention([]*models.LogRetention{
+		{LogType: aws.String("AWS.VPCFlow"), ExpireDays: aws.Int64(90)},
+		{LogType: aws.String("AWS.CloudTrail"), ExpireDays: aws.Int64(365), GlacierDays: aws.Int64(90)},
+	}))
+
+	err := validateLogRetention([]*models.LogRetention{
+		{LogType: aws.String("AWS.VPCFlow"), ExpireDays: aws.Int64(90)},
+		{LogType: aws.String("AWS.VPCFlow"), ExpireDays: aws.Int64(30)},
+	})
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+
+	err = validateLogRetention([]*models.LogRetention{
+		{LogType: aws.String("AWS.CloudTrail"), ExpireDays: aws.Int64(90), GlacierDays: aws.Int64(90)},
+	})
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+}
+
+func TestLogRetentionRequired(t *testing.T) {
+	// rejected by the router before validateLogRetention dereferences the items
+	input := &models.UpdateSettingsInput{LogRetention: []*models.LogRetention{nil}}
+	assert.Error(t, validator.New().Struct(input))
+
+	input.LogRetention = []*models.LogRetention{{LogType: aws.String("AWS.VPCFlow"), ExpireDays: aws.Int64(90)}}
+	assert.NoError(t, validator.New().Struct(input))
+}
@@ -89,6 +89,15 @@ func buildUpdateExpression(settings *models.GeneralSettings) (expression.Express
 		}
 	}
 
+	if settings.LogRetention != nil {
+		if updateInitialized {
+			update = update.Set(expression.Name("logRetention"), expression.Value(settings.LogRetention))
+		} else {
+			update = expression.Set(expression.Name("logRetention"), expression.Value(settings.LogRetention))
+			updateInitialized = true
+		}
+	}
+
 	var expr expression.Expression
 	if !updateInitialized {
 		return expr, &genericapi.InvalidInputError{
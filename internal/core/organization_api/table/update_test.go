@@ -111,3 +111,36 @@ func TestUpdate(t *testing.T) {
 	expected := &models.GeneralSettings{}
 	assert.Equal(t, expected, result)
 }
+
+func TestUpdateLogRetention(t *testing.T) {
+	mockClient := &mockDynamoClient{}
+	newSettings := &models.GeneralSettings{
+		LogRetention: []*models.LogRetention{
+			{LogType: aws.String("AWS.VPCFlow"), ExpireDays: aws.Int64(90)},
+			{LogType: aws.String("AWS.CloudTrail"), ExpireDays: aws.Int64(365), GlacierDays: aws.Int64(90)},
+		},
+	}
+	output := &dynamodb.UpdateItemOutput{Attributes: DynamoItem{
+		"logRetention": {L: []*dynamodb.AttributeValue{{M: DynamoItem{
+			"logType":    {S: aws.String("AWS.VPCFlow")},
+			"expireDays": {N: aws.String("90")},
+		}}}},
+	}}
+
+	expectedUpdate := expression.Set(expression.Name("logRetention"), expression.Value(newSettings.LogRetention))
+	expectedExpression, _ := expression.NewBuilder().WithUpdate(expectedUpdate).Build()
+	mockClient.On("UpdateItem", mock.Anything).Return(output, nil)
+	table := &OrganizationsTable{client: mockClient, Name: aws.String("test-table")}
+
+	result, err := table.Update(newSettings)
+	mockClient.AssertExpectations(t)
+	require.NoError(t, err)
+	input := mockClient.Calls[0].Arguments.Get(0).(*dynamodb.UpdateItemInput)
+	assert.Equal(t, expectedExpression.Update(), input.UpdateExpression)
+	assert.Equal(t, expectedExpression.Values(), input.ExpressionAttributeValues)
+
+	expected := &models.GeneralSettings{
+		LogRetention: []*models.LogRetention{{LogType: aws.String("AWS.VPCFlow"), ExpireDays: aws.Int64(90)}},
+	}
+	assert.Equal(t, expected, result)
+}
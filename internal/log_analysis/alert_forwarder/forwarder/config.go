@@ -44,10 +44,16 @@ var (
 )
 
 type envConfig struct {
-	AlertsTable      string `required:"true" split_words:"true"`
-	AlertingQueueURL string `required:"true" split_words:"true"`
-	AnalysisAPIHost  string `required:"true" split_words:"true"`
-	AnalysisAPIPath  string `required:"true" split_words:"true"`
+	AlertsTable              string `required:"true" split_words:"true"`
+	SuppressionsTable        string `required:"true" split_words:"true"`
+	IncidentsTable           string `required:"true" split_words:"true"`
+	EntitiesTable            string `required:"true" split_words:"true"`
+	CorrelationWindowMinutes int    `default:"60" split_words:"true"`
+	IncidentLifetimeMinutes  int    `default:"1440" split_words:"true"`
+	CorrelateAWSAccountIDs   bool   `default:"false" envconfig:"CORRELATE_AWS_ACCOUNT_IDS"`
+	AlertingQueueURL         string `required:"true" split_words:"true"`
+	AnalysisAPIHost          string `required:"true" split_words:"true"`
+	AnalysisAPIPath          string `required:"true" split_words:"true"`
 }
 
 // Setup parses the environment and builds the AWS and http clients.
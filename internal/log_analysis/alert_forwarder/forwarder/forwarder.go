@@ -24,20 +24,22 @@ import (
 	"strconv"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 
 	policiesoperations "github.com/panther-labs/panther/api/gateway/analysis/client/operations"
 	"github.com/panther-labs/panther/api/gateway/analysis/models"
+	alertsmodels "github.com/panther-labs/panther/api/lambda/alerts/models"
 	alertModel "github.com/panther-labs/panther/internal/core/alert_delivery/models"
+	alertstable "github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
 )
 
-const defaultTimePartition = "defaultPartition"
-
 func Handle(oldAlertDedupEvent, newAlertDedupEvent *AlertDedupEvent) error {
 	if needToCreateNewAlert(oldAlertDedupEvent, newAlertDedupEvent) {
 		return handleNewAlert(newAlertDedupEvent)
@@ -55,10 +57,69 @@ func handleNewAlert(event *AlertDedupEvent) error {
 		return errors.Wrap(err, "failed to get rule information")
 	}
 
-	if err := storeNewAlert(ruleInfo, event); err != nil {
+	// Events below the rule threshold are only counted in the dedup table,
+	// the alert is created once enough events have been seen in the same dedup period
+	if event.EventCount < getRuleThreshold(ruleInfo) {
+		return nil
+	}
+
+	suppression, err := getActiveSuppression(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to get rule suppressions")
+	}
+
+	alert := newAlert(ruleInfo, event, suppression)
+	if suppression != nil {
+		stored, err := storeNewAlert(alert)
+		if err != nil {
+			return errors.Wrap(err, "failed to store new alert in DDB")
+		}
+		if !stored {
+			return updateExistingAlert(event)
+		}
+		zap.L().Info("alert suppressed", zap.String("ruleId", event.RuleID),
+			zap.String("suppressionId", suppression.SuppressionID))
+		return nil
+	}
+
+	incident, change, err := correlateAlert(alert)
+	if err != nil {
+		return errors.Wrap(err, "failed to correlate alert")
+	}
+	stored, err := storeNewAlert(alert)
+	if err != nil {
 		return errors.Wrap(err, "failed to store new alert in DDB")
 	}
-	return sendAlertNotification(ruleInfo, event)
+	if !stored {
+		// a retry of an event already handled, the alert was delivered
+		return updateExistingAlert(event)
+	}
+
+	switch {
+	case incident == nil:
+		return sendAlertNotification(ruleInfo, event)
+	case change == incidentStarted:
+		return sendIncidentNotification(incident, alert, nil)
+	case change == incidentSeverityRaised:
+		return sendIncidentNotification(incident, alert,
+			aws.String("Severity raised to "+incident.Severity+" by rule "+alert.RuleID))
+	case change == incidentRuleJoined:
+		return sendIncidentNotification(incident, alert, aws.String("Rule "+alert.RuleID+" joined the incident"))
+	default:
+		// The incident was already delivered, its alerts are listed by the alerts API
+		zap.L().Info("alert added to incident", zap.String("alertId", alert.ID),
+			zap.String("incidentId", incident.ID))
+		return nil
+	}
+}
+
+// getActiveSuppression returns a suppression of the rule matching the dedup string at the time of the event, if any
+func getActiveSuppression(event *AlertDedupEvent) (*alertstable.SuppressionItem, error) {
+	suppressionsTable := &alertstable.AlertsTable{
+		SuppressionsTableName: env.SuppressionsTable,
+		Client:                ddbClient,
+	}
+	return suppressionsTable.GetActiveSuppression(event.RuleID, event.DeduplicationString, event.UpdateTime)
 }
 
 func updateExistingAlert(event *AlertDedupEvent) error {
@@ -70,7 +131,19 @@ func updateExistingAlert(event *AlertDedupEvent) error {
 		Set(expression.Name(alertTableEventCountAttribute), expression.Value(aws.Int64(event.EventCount))).
 		Set(expression.Name(alertTableLogTypesAttribute), expression.Value(aws.StringSlice(event.LogTypes))).
 		Set(expression.Name(alertTableUpdateTimeAttribute), expression.Value(aws.Time(event.UpdateTime)))
-	expr, err := expression.NewBuilder().WithUpdate(updateExpression).Build()
+	// The rules engine keeps the entities of all the events of the alert
+	if len(event.IPAddresses) > 0 {
+		updateExpression = updateExpression.Set(expression.Name("ipAddresses"), expression.Value(stringSet(event.IPAddresses)))
+	}
+	if len(event.AWSAccountIDs) > 0 {
+		updateExpression = updateExpression.Set(expression.Name("awsAccountIds"), expression.Value(stringSet(event.AWSAccountIDs)))
+	}
+	if len(event.AWSInstanceIDs) > 0 {
+		updateExpression = updateExpression.Set(expression.Name("awsInstanceIds"), expression.Value(stringSet(event.AWSInstanceIDs)))
+	}
+	// The alert doesn't exist yet if the rule threshold has not been reached
+	conditionExpression := expression.AttributeExists(expression.Name(alertTablePartitionKey))
+	expr, err := expression.NewBuilder().WithUpdate(updateExpression).WithCondition(conditionExpression).Build()
 	if err != nil {
 		return errors.Wrap(err, "failed to build update expression")
 	}
@@ -78,6 +151,7 @@ func updateExistingAlert(event *AlertDedupEvent) error {
 	updateInput := &dynamodb.UpdateItemInput{
 		TableName:                 aws.String(env.AlertsTable),
 		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		Key: map[string]*dynamodb.AttributeValue{
@@ -87,34 +161,58 @@ func updateExistingAlert(event *AlertDedupEvent) error {
 
 	_, err = ddbClient.UpdateItem(updateInput)
 	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return handleNewAlert(event)
+		}
 		return errors.Wrap(err, "failed to update alert")
 	}
 	return nil
 }
 
-func storeNewAlert(rule *models.Rule, alertDedup *AlertDedupEvent) error {
+func newAlert(rule *models.Rule, alertDedup *AlertDedupEvent, suppression *alertstable.SuppressionItem) *Alert {
+	alertID := generateAlertID(alertDedup)
 	alert := &Alert{
-		ID:              generateAlertID(alertDedup),
-		TimePartition:   defaultTimePartition,
+		ID:              alertID,
+		TimePartition:   alertstable.TimePartition(alertID),
+		Status:          alertsmodels.StatusOpen,
 		Severity:        string(rule.Severity),
 		RuleDisplayName: getRuleDisplayName(rule),
 		Title:           getAlertTitle(rule, alertDedup),
 		AlertDedupEvent: *alertDedup,
 	}
+	if suppression != nil {
+		alert.Status = alertsmodels.StatusSuppressed
+		alert.SuppressionID = &suppression.SuppressionID
+	}
+	return alert
+}
 
+// storeNewAlert creates the alert, it returns false if the alert exists already: a retry must not reset its triage
+func storeNewAlert(alert *Alert) (bool, error) {
 	marshaledAlert, err := dynamodbattribute.MarshalMap(alert)
 	if err != nil {
-		return errors.Wrap(err, "failed to marshal alert")
+		return false, errors.Wrap(err, "failed to marshal alert")
+	}
+	expr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name(alertTablePartitionKey))).
+		Build()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to build condition expression")
 	}
 	putItemRequest := &dynamodb.PutItemInput{
-		Item:      marshaledAlert,
-		TableName: aws.String(env.AlertsTable),
+		ConditionExpression:      expr.Condition(),
+		ExpressionAttributeNames: expr.Names(),
+		Item:                     marshaledAlert,
+		TableName:                aws.String(env.AlertsTable),
 	}
 	_, err = ddbClient.PutItem(putItemRequest)
 	if err != nil {
-		return errors.Wrap(err, "failed to update store alert")
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "failed to update store alert")
 	}
-	return nil
+	return true, nil
 }
 
 func sendAlertNotification(rule *models.Rule, alertDedup *AlertDedupEvent) error {
@@ -130,6 +228,9 @@ func sendAlertNotification(rule *models.Rule, alertDedup *AlertDedupEvent) error
 		Type:              aws.String(alertModel.RuleType),
 		AlertID:           aws.String(generateAlertID(alertDedup)),
 		Title:             aws.String(getAlertTitle(rule, alertDedup)),
+		DedupString:       aws.String(alertDedup.DeduplicationString),
+		LogTypes:          aws.StringSlice(alertDedup.LogTypes),
+		EventSamples:      aws.StringSlice(alertDedup.EventSamples),
 	}
 
 	msgBody, err := jsoniter.MarshalToString(alertNotification)
@@ -148,6 +249,36 @@ func sendAlertNotification(rule *models.Rule, alertDedup *AlertDedupEvent) error
 	return nil
 }
 
+// sendIncidentNotification delivers a new incident with the alert which started it, or an update of an incident with
+// the alert which changed it
+func sendIncidentNotification(incident *Incident, alert *Alert, update *string) error {
+	incidentNotification := &alertModel.Alert{
+		CreatedAt:      aws.Time(incident.CreationTime),
+		PolicyID:       aws.String(alert.RuleID),
+		PolicyName:     alert.RuleDisplayName,
+		Severity:       aws.String(incident.Severity),
+		Type:           aws.String(alertModel.IncidentType),
+		AlertID:        aws.String(alert.ID),
+		IncidentID:     aws.String(incident.ID),
+		IncidentUpdate: update,
+		Title:          aws.String(alert.Title),
+	}
+
+	msgBody, err := jsoniter.MarshalToString(incidentNotification)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal incident notification")
+	}
+
+	input := &sqs.SendMessageInput{
+		QueueUrl:    aws.String(env.AlertingQueueURL),
+		MessageBody: aws.String(msgBody),
+	}
+	if _, err = sqsClient.SendMessage(input); err != nil {
+		return errors.Wrap(err, "failed to send notification")
+	}
+	return nil
+}
+
 func getAlertTitle(rule *models.Rule, alertDedup *AlertDedupEvent) string {
 	if alertDedup.GeneratedTitle != nil {
 		return *alertDedup.GeneratedTitle
@@ -166,13 +297,35 @@ func getRuleDisplayName(rule *models.Rule) *string {
 	return nil
 }
 
+// getRuleThreshold returns the number of events needed to create an alert, rules without a threshold alert on every match
+func getRuleThreshold(rule *models.Rule) int64 {
+	if rule.Threshold < 1 {
+		return 1
+	}
+	return int64(rule.Threshold)
+}
+
 func generateAlertID(event *AlertDedupEvent) string {
 	key := event.RuleID + ":" + strconv.FormatInt(event.AlertCount, 10) + ":" + event.DeduplicationString
 	keyHash := md5.Sum([]byte(key)) // nolint(gosec)
 	return hex.EncodeToString(keyHash[:])
 }
 
+// rules fetched during the invocation by rule id and version, events below the threshold of their rule would otherwise
+// fetch it on every dedup update
+var ruleCache = map[string]*models.Rule{}
+
+// ResetRuleCache forgets the rules fetched by the previous invocation
+func ResetRuleCache() {
+	ruleCache = map[string]*models.Rule{}
+}
+
 func getRuleInfo(event *AlertDedupEvent) (*models.Rule, error) {
+	cacheKey := event.RuleID + ":" + event.RuleVersion
+	if rule, ok := ruleCache[cacheKey]; ok {
+		return rule, nil
+	}
+
 	rule, err := policyClient.Operations.GetRule(&policiesoperations.GetRuleParams{
 		RuleID:     event.RuleID,
 		VersionID:  aws.String(event.RuleVersion),
@@ -183,5 +336,6 @@ func getRuleInfo(event *AlertDedupEvent) (*models.Rule, error) {
 		return nil, errors.Wrapf(err, "failed to fetch information for ruleID [%s], version [%s]",
 			event.RuleID, event.RuleVersion)
 	}
+	ruleCache[cacheKey] = rule.Payload
 	return rule.Payload, nil
 }
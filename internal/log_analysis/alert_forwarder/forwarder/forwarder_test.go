@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
@@ -39,6 +40,7 @@ import (
 	policiesclient "github.com/panther-labs/panther/api/gateway/analysis/client"
 	"github.com/panther-labs/panther/api/gateway/analysis/models"
 	alertModel "github.com/panther-labs/panther/internal/core/alert_delivery/models"
+	alertstable "github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
 	"github.com/panther-labs/panther/pkg/testutils"
 )
 
@@ -75,6 +77,7 @@ var (
 		EventCount:          oldAlertDedupEvent.EventCount,
 		LogTypes:            oldAlertDedupEvent.LogTypes,
 		GeneratedTitle:      oldAlertDedupEvent.GeneratedTitle,
+		EventSamples:        []string{`{"key": "value"}`},
 	}
 
 	testRuleResponse = &models.Rule{
@@ -89,6 +92,7 @@ var (
 
 func init() {
 	env.AlertsTable = "alertsTable"
+	env.SuppressionsTable = "suppressionsTable"
 	env.AlertingQueueURL = "queueUrl"
 }
 
@@ -105,6 +109,7 @@ func TestHandleStoreAndSendNotification(t *testing.T) {
 		WithHost("host").
 		WithBasePath("path")
 	policyClient = policiesclient.NewHTTPClientWithConfig(nil, policyConfig)
+	ResetRuleCache()
 
 	expectedAlertNotification := &alertModel.Alert{
 		CreatedAt:         aws.Time(newAlertDedupEvent.CreationTime),
@@ -118,6 +123,9 @@ func TestHandleStoreAndSendNotification(t *testing.T) {
 		Type:              aws.String(alertModel.RuleType),
 		AlertID:           aws.String("b25dc23fb2a0b362da8428dbec1381a8"),
 		Title:             newAlertDedupEvent.GeneratedTitle,
+		DedupString:       aws.String(newAlertDedupEvent.DeduplicationString),
+		LogTypes:          aws.StringSlice(newAlertDedupEvent.LogTypes),
+		EventSamples:      aws.StringSlice(newAlertDedupEvent.EventSamples),
 	}
 	expectedMarshaledAlertNotification, err := jsoniter.MarshalToString(expectedAlertNotification)
 	require.NoError(t, err)
@@ -131,7 +139,8 @@ func TestHandleStoreAndSendNotification(t *testing.T) {
 
 	expectedAlert := &Alert{
 		ID:              "b25dc23fb2a0b362da8428dbec1381a8",
-		TimePartition:   "defaultPartition",
+		TimePartition:   alertstable.TimePartition("b25dc23fb2a0b362da8428dbec1381a8"),
+		Status:          "OPEN",
 		Severity:        string(testRuleResponse.Severity),
 		RuleDisplayName: aws.String(string(testRuleResponse.DisplayName)),
 		Title:           aws.StringValue(newAlertDedupEvent.GeneratedTitle),
@@ -142,10 +151,13 @@ func TestHandleStoreAndSendNotification(t *testing.T) {
 	assert.NoError(t, err)
 
 	expectedPutItemRequest := &dynamodb.PutItemInput{
-		Item:      expectedMarshaledAlert,
-		TableName: aws.String("alertsTable"),
+		ConditionExpression:      aws.String("attribute_not_exists (#0)"),
+		ExpressionAttributeNames: map[string]*string{"#0": aws.String("id")},
+		Item:                     expectedMarshaledAlert,
+		TableName:                aws.String("alertsTable"),
 	}
 
+	ddbMock.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
 	ddbMock.On("PutItem", expectedPutItemRequest).Return(&dynamodb.PutItemOutput{}, nil)
 	assert.NoError(t, Handle(oldAlertDedupEvent, newAlertDedupEvent))
 
@@ -167,6 +179,7 @@ func TestHandleStoreAndSendNotificationNoRuleDisplayNameNoTitle(t *testing.T) {
 		WithHost("host").
 		WithBasePath("path")
 	policyClient = policiesclient.NewHTTPClientWithConfig(nil, policyConfig)
+	ResetRuleCache()
 
 	newAlertDedupEventWithoutTitle := &AlertDedupEvent{
 		RuleID:              oldAlertDedupEvent.RuleID,
@@ -190,6 +203,9 @@ func TestHandleStoreAndSendNotificationNoRuleDisplayNameNoTitle(t *testing.T) {
 		Type:              aws.String(alertModel.RuleType),
 		AlertID:           aws.String("b25dc23fb2a0b362da8428dbec1381a8"),
 		Title:             aws.String(newAlertDedupEventWithoutTitle.RuleID),
+		DedupString:       aws.String(newAlertDedupEventWithoutTitle.DeduplicationString),
+		LogTypes:          aws.StringSlice(newAlertDedupEventWithoutTitle.LogTypes),
+		EventSamples:      aws.StringSlice(newAlertDedupEventWithoutTitle.EventSamples),
 	}
 	expectedMarshaledAlertNotification, err := jsoniter.MarshalToString(expectedAlertNotification)
 	require.NoError(t, err)
@@ -211,7 +227,8 @@ func TestHandleStoreAndSendNotificationNoRuleDisplayNameNoTitle(t *testing.T) {
 
 	expectedAlert := &Alert{
 		ID:              "b25dc23fb2a0b362da8428dbec1381a8",
-		TimePartition:   "defaultPartition",
+		TimePartition:   alertstable.TimePartition("b25dc23fb2a0b362da8428dbec1381a8"),
+		Status:          "OPEN",
 		Severity:        string(testRuleResponse.Severity),
 		Title:           newAlertDedupEventWithoutTitle.RuleID,
 		AlertDedupEvent: *newAlertDedupEventWithoutTitle,
@@ -221,10 +238,13 @@ func TestHandleStoreAndSendNotificationNoRuleDisplayNameNoTitle(t *testing.T) {
 	assert.NoError(t, err)
 
 	expectedPutItemRequest := &dynamodb.PutItemInput{
-		Item:      expectedMarshaledAlert,
-		TableName: aws.String("alertsTable"),
+		ConditionExpression:      aws.String("attribute_not_exists (#0)"),
+		ExpressionAttributeNames: map[string]*string{"#0": aws.String("id")},
+		Item:                     expectedMarshaledAlert,
+		TableName:                aws.String("alertsTable"),
 	}
 
+	ddbMock.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
 	ddbMock.On("PutItem", expectedPutItemRequest).Return(&dynamodb.PutItemOutput{}, nil)
 	assert.NoError(t, Handle(oldAlertDedupEvent, newAlertDedupEventWithoutTitle))
 
@@ -246,6 +266,7 @@ func TestHandleStoreAndSendNotificationNoGeneratedTitle(t *testing.T) {
 		WithHost("host").
 		WithBasePath("path")
 	policyClient = policiesclient.NewHTTPClientWithConfig(nil, policyConfig)
+	ResetRuleCache()
 
 	expectedAlertNotification := &alertModel.Alert{
 		CreatedAt:         aws.Time(newAlertDedupEvent.CreationTime),
@@ -259,6 +280,9 @@ func TestHandleStoreAndSendNotificationNoGeneratedTitle(t *testing.T) {
 		Type:              aws.String(alertModel.RuleType),
 		AlertID:           aws.String("b25dc23fb2a0b362da8428dbec1381a8"),
 		Title:             aws.String("DisplayName"),
+		DedupString:       aws.String(newAlertDedupEvent.DeduplicationString),
+		LogTypes:          aws.StringSlice(newAlertDedupEvent.LogTypes),
+		EventSamples:      aws.StringSlice(newAlertDedupEvent.EventSamples),
 	}
 	expectedMarshaledAlertNotification, err := jsoniter.MarshalToString(expectedAlertNotification)
 	require.NoError(t, err)
@@ -272,7 +296,8 @@ func TestHandleStoreAndSendNotificationNoGeneratedTitle(t *testing.T) {
 
 	expectedAlert := &Alert{
 		ID:              "b25dc23fb2a0b362da8428dbec1381a8",
-		TimePartition:   "defaultPartition",
+		TimePartition:   alertstable.TimePartition("b25dc23fb2a0b362da8428dbec1381a8"),
+		Status:          "OPEN",
 		Severity:        string(testRuleResponse.Severity),
 		RuleDisplayName: aws.String(string(testRuleResponse.DisplayName)),
 		Title:           "DisplayName",
@@ -283,8 +308,10 @@ func TestHandleStoreAndSendNotificationNoGeneratedTitle(t *testing.T) {
 	assert.NoError(t, err)
 
 	expectedPutItemRequest := &dynamodb.PutItemInput{
-		Item:      expectedMarshaledAlert,
-		TableName: aws.String("alertsTable"),
+		ConditionExpression:      aws.String("attribute_not_exists (#0)"),
+		ExpressionAttributeNames: map[string]*string{"#0": aws.String("id")},
+		Item:                     expectedMarshaledAlert,
+		TableName:                aws.String("alertsTable"),
 	}
 
 	dedupEventWithoutTitle := &AlertDedupEvent{
@@ -296,8 +323,10 @@ func TestHandleStoreAndSendNotificationNoGeneratedTitle(t *testing.T) {
 		UpdateTime:          newAlertDedupEvent.UpdateTime,
 		EventCount:          newAlertDedupEvent.EventCount,
 		LogTypes:            newAlertDedupEvent.LogTypes,
+		EventSamples:        newAlertDedupEvent.EventSamples,
 	}
 
+	ddbMock.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
 	ddbMock.On("PutItem", expectedPutItemRequest).Return(&dynamodb.PutItemOutput{}, nil)
 	assert.NoError(t, Handle(oldAlertDedupEvent, dedupEventWithoutTitle))
 
@@ -319,6 +348,7 @@ func TestHandleStoreAndSendNotificationNilOldDedup(t *testing.T) {
 		WithHost("host").
 		WithBasePath("path")
 	policyClient = policiesclient.NewHTTPClientWithConfig(nil, policyConfig)
+	ResetRuleCache()
 
 	expectedAlertNotification := &alertModel.Alert{
 		CreatedAt:         aws.Time(newAlertDedupEvent.CreationTime),
@@ -332,6 +362,9 @@ func TestHandleStoreAndSendNotificationNilOldDedup(t *testing.T) {
 		Type:              aws.String(alertModel.RuleType),
 		AlertID:           aws.String("b25dc23fb2a0b362da8428dbec1381a8"),
 		Title:             newAlertDedupEvent.GeneratedTitle,
+		DedupString:       aws.String(newAlertDedupEvent.DeduplicationString),
+		LogTypes:          aws.StringSlice(newAlertDedupEvent.LogTypes),
+		EventSamples:      aws.StringSlice(newAlertDedupEvent.EventSamples),
 	}
 	expectedMarshaledAlertNotification, err := jsoniter.MarshalToString(expectedAlertNotification)
 	require.NoError(t, err)
@@ -345,7 +378,8 @@ func TestHandleStoreAndSendNotificationNilOldDedup(t *testing.T) {
 
 	expectedAlert := &Alert{
 		ID:              "b25dc23fb2a0b362da8428dbec1381a8",
-		TimePartition:   "defaultPartition",
+		TimePartition:   alertstable.TimePartition("b25dc23fb2a0b362da8428dbec1381a8"),
+		Status:          "OPEN",
 		Severity:        string(testRuleResponse.Severity),
 		Title:           aws.StringValue(newAlertDedupEvent.GeneratedTitle),
 		RuleDisplayName: aws.String(string(testRuleResponse.DisplayName)),
@@ -356,10 +390,13 @@ func TestHandleStoreAndSendNotificationNilOldDedup(t *testing.T) {
 	require.NoError(t, err)
 
 	expectedPutItemRequest := &dynamodb.PutItemInput{
-		Item:      expectedMarshaledAlert,
-		TableName: aws.String("alertsTable"),
+		ConditionExpression:      aws.String("attribute_not_exists (#0)"),
+		ExpressionAttributeNames: map[string]*string{"#0": aws.String("id")},
+		Item:                     expectedMarshaledAlert,
+		TableName:                aws.String("alertsTable"),
 	}
 
+	ddbMock.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
 	ddbMock.On("PutItem", expectedPutItemRequest).Return(&dynamodb.PutItemOutput{}, nil)
 	require.NoError(t, Handle(nil, newAlertDedupEvent))
 
@@ -368,6 +405,38 @@ func TestHandleStoreAndSendNotificationNilOldDedup(t *testing.T) {
 	mockRoundTripper.AssertExpectations(t)
 }
 
+func TestHandleNewAlertRetried(t *testing.T) {
+	ddbMock := &testutils.DynamoDBMock{}
+	ddbClient = ddbMock
+
+	sqsMock := &testutils.SqsMock{}
+	sqsClient = sqsMock
+
+	mockRoundTripper := &mockRoundTripper{}
+	httpClient = &http.Client{Transport: mockRoundTripper}
+	policyConfig = policiesclient.DefaultTransportConfig().
+		WithHost("host").
+		WithBasePath("path")
+	policyClient = policiesclient.NewHTTPClientWithConfig(nil, policyConfig)
+	ResetRuleCache()
+
+	// the alert was stored by an earlier run, its triage is kept and it is not delivered again
+	mockRoundTripper.On("RoundTrip", mock.Anything).Return(generateResponse(testRuleResponse, http.StatusOK), nil).Once()
+	ddbMock.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
+	ddbMock.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{},
+		awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil)).Once()
+	ddbMock.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+	require.NoError(t, Handle(nil, newAlertDedupEvent))
+
+	ddbMock.AssertExpectations(t)
+	sqsMock.AssertNotCalled(t, "SendMessage", mock.Anything)
+	updateInput := ddbMock.Calls[2].Arguments.Get(0).(*dynamodb.UpdateItemInput)
+	assert.Equal(t, "b25dc23fb2a0b362da8428dbec1381a8", *updateInput.Key["id"].S)
+	for _, name := range updateInput.ExpressionAttributeNames {
+		assert.NotEqual(t, "status", *name)
+	}
+}
+
 func TestHandleUpdateAlert(t *testing.T) {
 	ddbMock := &testutils.DynamoDBMock{}
 	ddbClient = ddbMock
@@ -388,7 +457,8 @@ func TestHandleUpdateAlert(t *testing.T) {
 		Set(expression.Name("eventCount"), expression.Value(aws.Int64(dedupEventWithUpdatedFields.EventCount))).
 		Set(expression.Name("logTypes"), expression.Value(aws.StringSlice(dedupEventWithUpdatedFields.LogTypes))).
 		Set(expression.Name("updateTime"), expression.Value(aws.Time(dedupEventWithUpdatedFields.UpdateTime)))
-	expr, err := expression.NewBuilder().WithUpdate(updateExpression).Build()
+	conditionExpression := expression.AttributeExists(expression.Name("id"))
+	expr, err := expression.NewBuilder().WithUpdate(updateExpression).WithCondition(conditionExpression).Build()
 	require.NoError(t, err)
 
 	expectedUpdateItemInput := &dynamodb.UpdateItemInput{
@@ -397,6 +467,7 @@ func TestHandleUpdateAlert(t *testing.T) {
 			"id": {S: aws.String("b25dc23fb2a0b362da8428dbec1381a8")},
 		},
 		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
 		ExpressionAttributeValues: expr.Values(),
 		ExpressionAttributeNames:  expr.Names(),
 	}
@@ -427,6 +498,171 @@ func TestHandleUpdateAlertDDBError(t *testing.T) {
 	assert.Error(t, Handle(newAlertDedupEvent, dedupEventWithUpdatedFields))
 }
 
+func TestHandleBelowThreshold(t *testing.T) {
+	ddbMock := &testutils.DynamoDBMock{}
+	ddbClient = ddbMock
+
+	sqsMock := &testutils.SqsMock{}
+	sqsClient = sqsMock
+
+	mockRoundTripper := &mockRoundTripper{}
+	httpClient = &http.Client{Transport: mockRoundTripper}
+	policyConfig = policiesclient.DefaultTransportConfig().
+		WithHost("host").
+		WithBasePath("path")
+	policyClient = policiesclient.NewHTTPClientWithConfig(nil, policyConfig)
+	ResetRuleCache()
+
+	thresholdRule := *testRuleResponse
+	thresholdRule.Threshold = models.Threshold(newAlertDedupEvent.EventCount + 1)
+	mockRoundTripper.On("RoundTrip", mock.Anything).Return(generateResponse(&thresholdRule, http.StatusOK), nil).Once()
+
+	// Neither an alert is stored nor a notification is sent
+	require.NoError(t, Handle(oldAlertDedupEvent, newAlertDedupEvent))
+	// the rule is fetched once per invocation
+	require.NoError(t, Handle(oldAlertDedupEvent, newAlertDedupEvent))
+
+	ddbMock.AssertExpectations(t)
+	sqsMock.AssertExpectations(t)
+	mockRoundTripper.AssertExpectations(t)
+}
+
+func TestHandleUpdateAlertReachesThreshold(t *testing.T) {
+	ddbMock := &testutils.DynamoDBMock{}
+	ddbClient = ddbMock
+
+	sqsMock := &testutils.SqsMock{}
+	sqsClient = sqsMock
+
+	mockRoundTripper := &mockRoundTripper{}
+	httpClient = &http.Client{Transport: mockRoundTripper}
+	policyConfig = policiesclient.DefaultTransportConfig().
+		WithHost("host").
+		WithBasePath("path")
+	policyClient = policiesclient.NewHTTPClientWithConfig(nil, policyConfig)
+	ResetRuleCache()
+
+	dedupEventWithUpdatedFields := &AlertDedupEvent{
+		RuleID:              newAlertDedupEvent.RuleID,
+		RuleVersion:         newAlertDedupEvent.RuleVersion,
+		DeduplicationString: newAlertDedupEvent.DeduplicationString,
+		AlertCount:          newAlertDedupEvent.AlertCount,
+		CreationTime:        newAlertDedupEvent.CreationTime,
+		UpdateTime:          newAlertDedupEvent.UpdateTime.Add(1 * time.Minute),
+		EventCount:          newAlertDedupEvent.EventCount + 10,
+		LogTypes:            newAlertDedupEvent.LogTypes,
+		GeneratedTitle:      newAlertDedupEvent.GeneratedTitle,
+	}
+
+	thresholdRule := *testRuleResponse
+	thresholdRule.Threshold = models.Threshold(dedupEventWithUpdatedFields.EventCount)
+	mockRoundTripper.On("RoundTrip", mock.Anything).Return(generateResponse(&thresholdRule, http.StatusOK), nil).Once()
+
+	// The alert doesn't exist yet, so it is created and delivered
+	ddbMock.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{},
+		awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil)).Once()
+
+	expectedAlert := &Alert{
+		ID:              "b25dc23fb2a0b362da8428dbec1381a8",
+		TimePartition:   alertstable.TimePartition("b25dc23fb2a0b362da8428dbec1381a8"),
+		Status:          "OPEN",
+		Severity:        string(testRuleResponse.Severity),
+		RuleDisplayName: aws.String(string(testRuleResponse.DisplayName)),
+		Title:           aws.StringValue(dedupEventWithUpdatedFields.GeneratedTitle),
+		AlertDedupEvent: *dedupEventWithUpdatedFields,
+	}
+	expectedMarshaledAlert, err := dynamodbattribute.MarshalMap(expectedAlert)
+	require.NoError(t, err)
+	ddbMock.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
+	ddbMock.On("PutItem", &dynamodb.PutItemInput{
+		ConditionExpression:      aws.String("attribute_not_exists (#0)"),
+		ExpressionAttributeNames: map[string]*string{"#0": aws.String("id")},
+		Item:                     expectedMarshaledAlert,
+		TableName:                aws.String("alertsTable"),
+	}).Return(&dynamodb.PutItemOutput{}, nil).Once()
+	sqsMock.On("SendMessage", mock.Anything).Return(&sqs.SendMessageOutput{}, nil).Once()
+
+	require.NoError(t, Handle(newAlertDedupEvent, dedupEventWithUpdatedFields))
+
+	ddbMock.AssertExpectations(t)
+	sqsMock.AssertExpectations(t)
+	mockRoundTripper.AssertExpectations(t)
+}
+
+func TestHandleSuppressedAlert(t *testing.T) {
+	ddbMock := &testutils.DynamoDBMock{}
+	ddbClient = ddbMock
+
+	sqsMock := &testutils.SqsMock{}
+	sqsClient = sqsMock
+
+	mockRoundTripper := &mockRoundTripper{}
+	httpClient = &http.Client{Transport: mockRoundTripper}
+	policyConfig = policiesclient.DefaultTransportConfig().
+		WithHost("host").
+		WithBasePath("path")
+	policyClient = policiesclient.NewHTTPClientWithConfig(nil, policyConfig)
+	ResetRuleCache()
+
+	mockRoundTripper.On("RoundTrip", mock.Anything).Return(generateResponse(testRuleResponse, http.StatusOK), nil).Once()
+
+	// Only the active suppression of the same dedup string applies
+	suppressions := []*alertstable.SuppressionItem{
+		{
+			RuleID:        newAlertDedupEvent.RuleID,
+			SuppressionID: "expired",
+			ExpiresAt:     newAlertDedupEvent.UpdateTime.Add(-time.Minute),
+		},
+		{
+			RuleID:        newAlertDedupEvent.RuleID,
+			SuppressionID: "otherDedup",
+			DedupString:   aws.String("otherDedupString"),
+			ExpiresAt:     newAlertDedupEvent.UpdateTime.Add(time.Hour),
+		},
+		{
+			RuleID:        newAlertDedupEvent.RuleID,
+			SuppressionID: "active",
+			DedupString:   aws.String(newAlertDedupEvent.DeduplicationString),
+			ExpiresAt:     newAlertDedupEvent.UpdateTime.Add(time.Hour),
+		},
+	}
+	items := make([]map[string]*dynamodb.AttributeValue, len(suppressions))
+	for i, suppression := range suppressions {
+		item, err := dynamodbattribute.MarshalMap(suppression)
+		require.NoError(t, err)
+		items[i] = item
+	}
+	ddbMock.On("Query", mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return *input.TableName == "suppressionsTable" && *input.ExpressionAttributeValues[":0"].S == newAlertDedupEvent.RuleID
+	})).Return(&dynamodb.QueryOutput{Items: items}, nil).Once()
+
+	expectedAlert := &Alert{
+		ID:              "b25dc23fb2a0b362da8428dbec1381a8",
+		TimePartition:   alertstable.TimePartition("b25dc23fb2a0b362da8428dbec1381a8"),
+		Status:          "SUPPRESSED",
+		SuppressionID:   aws.String("active"),
+		Severity:        string(testRuleResponse.Severity),
+		RuleDisplayName: aws.String(string(testRuleResponse.DisplayName)),
+		Title:           aws.StringValue(newAlertDedupEvent.GeneratedTitle),
+		AlertDedupEvent: *newAlertDedupEvent,
+	}
+	expectedMarshaledAlert, err := dynamodbattribute.MarshalMap(expectedAlert)
+	require.NoError(t, err)
+	ddbMock.On("PutItem", &dynamodb.PutItemInput{
+		ConditionExpression:      aws.String("attribute_not_exists (#0)"),
+		ExpressionAttributeNames: map[string]*string{"#0": aws.String("id")},
+		Item:                     expectedMarshaledAlert,
+		TableName:                aws.String("alertsTable"),
+	}).Return(&dynamodb.PutItemOutput{}, nil).Once()
+
+	// The alert is stored but no notification is sent
+	require.NoError(t, Handle(oldAlertDedupEvent, newAlertDedupEvent))
+
+	ddbMock.AssertExpectations(t)
+	sqsMock.AssertExpectations(t)
+	mockRoundTripper.AssertExpectations(t)
+}
+
 func generateResponse(body interface{}, httpCode int) *http.Response {
 	serializedBody, _ := jsoniter.MarshalToString(body)
 	return &http.Response{StatusCode: httpCode, Body: ioutil.NopCloser(strings.NewReader(serializedBody))}
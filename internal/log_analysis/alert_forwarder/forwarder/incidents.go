@@ -0,0 +1,470 @@
+package forwarder
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"crypto/md5" // nolint(gosec)
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/pkg/errors"
+
+	alertstable "github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/pkg/awsbatch/dynamodbbatch"
+)
+
+const (
+	incidentTablePartitionKey = "id"
+	entityTablePartitionKey   = "entity"
+
+	// Only the first entities of an alert are correlated, the limit of a single BatchGetItem call
+	maxCorrelatedEntities = 100
+	maxEntityWriteBackoff = 30 * time.Second
+)
+
+// Severities from lowest to highest, the incident severity is the highest severity of its alerts
+var severities = []string{"INFO", "LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// Incident groups the alerts of different rules which share entities within the correlation window
+type Incident struct {
+	ID            string    `dynamodbav:"id,string"`
+	TimePartition string    `dynamodbav:"timePartition,string"`
+	CreationTime  time.Time `dynamodbav:"creationTime"`
+	UpdateTime    time.Time `dynamodbav:"updateTime"`
+	Severity      string    `dynamodbav:"severity,string"`
+	AlertIDs      []string  `dynamodbav:"alertIds,stringset"`
+	RuleIDs       []string  `dynamodbav:"ruleIds,stringset"`
+	Entities
+}
+
+// entityItem points an entity to the last alert it was seen in, and to the incident of that alert
+type entityItem struct {
+	Entity     string  `dynamodbav:"entity,string"`
+	AlertID    string  `dynamodbav:"alertId,string"`
+	RuleID     string  `dynamodbav:"ruleId,string"`
+	IncidentID *string `dynamodbav:"incidentId,string,omitempty"`
+	// The incident closes for new alerts once it is older than the incident lifetime
+	IncidentCreationTime *time.Time `dynamodbav:"incidentCreationTime,omitempty"`
+	LastSeen             time.Time  `dynamodbav:"lastSeen"`
+	ExpiresAt            int64      `dynamodbav:"expiresAt,number"` // the DDB TTL, entities are only correlated within the window
+}
+
+// incidentChange is how correlating an alert changed its incident, the changes are delivered
+type incidentChange int
+
+const (
+	incidentUnchanged incidentChange = iota
+	incidentStarted
+	incidentSeverityRaised
+	incidentRuleJoined
+)
+
+// stringSet marshals to a DDB string set, as needed by ADD update expressions
+type stringSet []string
+
+func (s stringSet) MarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	av.SS = aws.StringSlice(s)
+	return nil
+}
+
+// entityKeys returns the keys of the entities in the entities table. AWS account ids are only correlated if enabled:
+// most CloudTrail alerts of a single account deployment share the account id.
+func (entities *Entities) entityKeys() []string {
+	var keys []string
+	for _, value := range entities.IPAddresses {
+		keys = append(keys, "ip:"+value)
+	}
+	if env.CorrelateAWSAccountIDs {
+		for _, value := range entities.AWSAccountIDs {
+			keys = append(keys, "awsAccount:"+value)
+		}
+	}
+	for _, value := range entities.AWSInstanceIDs {
+		keys = append(keys, "awsInstance:"+value)
+	}
+	if len(keys) > maxCorrelatedEntities {
+		keys = keys[:maxCorrelatedEntities]
+	}
+	return keys
+}
+
+// merge adds the entities which are not already present
+func (entities *Entities) merge(other *Entities) {
+	entities.IPAddresses = union(entities.IPAddresses, other.IPAddresses)
+	entities.AWSAccountIDs = union(entities.AWSAccountIDs, other.AWSAccountIDs)
+	entities.AWSInstanceIDs = union(entities.AWSInstanceIDs, other.AWSInstanceIDs)
+}
+
+// correlateAlert adds a new alert to an incident if an alert of another rule shared one of its entities within the
+// correlation window. It returns the incident, or nil if the alert was not correlated, and how the alert changed it.
+func correlateAlert(alert *Alert) (*Incident, incidentChange, error) {
+	keys := alert.entityKeys()
+	if len(keys) == 0 {
+		return nil, incidentUnchanged, nil
+	}
+	entities, err := getEntities(keys)
+	if err != nil {
+		return nil, incidentUnchanged, err
+	}
+
+	var incident, previous *Incident
+	var other *Alert
+	var writes []*entityItem
+	if match := findCorrelatedEntity(alert, entities); match != nil {
+		if match.IncidentID != nil {
+			if incident, previous, err = addToIncident(*match.IncidentID, alert); err != nil {
+				return nil, incidentUnchanged, err
+			}
+		} else {
+			// The alert of the other rule is not in an incident yet, start one with both alerts
+			if other, err = getAlert(match.AlertID); err != nil {
+				return nil, incidentUnchanged, err
+			}
+			if other != nil {
+				if incident, _, err = addToIncident(generateIncidentID(other.ID), other, alert); err != nil {
+					return nil, incidentUnchanged, err
+				}
+				other.IncidentID = &incident.ID
+				writes = newEntityItems(other, incident)
+			}
+		}
+	}
+
+	if incident != nil {
+		alert.IncidentID = &incident.ID
+	}
+	// The entities of the new alert are written last, they point to the latest alert
+	writes = append(writes, newEntityItems(alert, incident)...)
+	if err = putEntities(writes); err != nil {
+		return nil, incidentUnchanged, err
+	}
+
+	if other != nil && incident != nil {
+		if err = setAlertIncident(other.ID, incident.ID); err != nil {
+			return nil, incidentUnchanged, err
+		}
+		return incident, incidentStarted, nil
+	}
+	return incident, getIncidentChange(previous, alert), nil
+}
+
+// getIncidentChange returns how an alert joining an existing incident changed it, a higher severity takes precedence
+func getIncidentChange(previous *Incident, alert *Alert) incidentChange {
+	switch {
+	case previous == nil:
+		return incidentUnchanged
+	case severityRank(alert.Severity) > severityRank(previous.Severity):
+		return incidentSeverityRaised
+	case len(union(previous.RuleIDs, []string{alert.RuleID})) > len(previous.RuleIDs):
+		return incidentRuleJoined
+	default:
+		return incidentUnchanged
+	}
+}
+
+// findCorrelatedEntity returns the entity seen most recently in an alert of another rule, entities already
+// in an incident are preferred so that the alert joins the incident instead of starting a new one.
+// Incidents older than the incident lifetime are closed: their entities are ignored.
+func findCorrelatedEntity(alert *Alert, entities []*entityItem) *entityItem {
+	windowStart := alert.UpdateTime.Add(-correlationWindow())
+	incidentsStart := alert.UpdateTime.Add(-incidentLifetime())
+	var result *entityItem
+	for _, entity := range entities {
+		if entity.AlertID == alert.ID || entity.LastSeen.Before(windowStart) {
+			continue
+		}
+		if entity.IncidentID == nil && entity.RuleID == alert.RuleID {
+			continue
+		}
+		if entity.IncidentID != nil && entity.IncidentCreationTime != nil && entity.IncidentCreationTime.Before(incidentsStart) {
+			continue
+		}
+		if result == nil ||
+			(entity.IncidentID != nil && result.IncidentID == nil) ||
+			((entity.IncidentID != nil) == (result.IncidentID != nil) && entity.LastSeen.After(result.LastSeen)) {
+
+			result = entity
+		}
+	}
+	return result
+}
+
+func correlationWindow() time.Duration {
+	return time.Duration(env.CorrelationWindowMinutes) * time.Minute
+}
+
+func incidentLifetime() time.Duration {
+	return time.Duration(env.IncidentLifetimeMinutes) * time.Minute
+}
+
+// newEntityItems returns the entities of an alert, incident is the incident of the alert or nil
+func newEntityItems(alert *Alert, incident *Incident) []*entityItem {
+	var incidentCreationTime *time.Time
+	if incident != nil {
+		incidentCreationTime = &incident.CreationTime
+	}
+	keys := alert.entityKeys()
+	result := make([]*entityItem, len(keys))
+	for i, key := range keys {
+		result[i] = &entityItem{
+			Entity:               key,
+			AlertID:              alert.ID,
+			RuleID:               alert.RuleID,
+			IncidentID:           alert.IncidentID,
+			IncidentCreationTime: incidentCreationTime,
+			LastSeen:             alert.UpdateTime,
+			ExpiresAt:            alert.UpdateTime.Add(correlationWindow()).Unix(),
+		}
+	}
+	return result
+}
+
+func getEntities(keys []string) ([]*entityItem, error) {
+	requestKeys := make([]map[string]*dynamodb.AttributeValue, len(keys))
+	for i, key := range keys {
+		requestKeys[i] = map[string]*dynamodb.AttributeValue{entityTablePartitionKey: {S: aws.String(key)}}
+	}
+	output, err := dynamodbbatch.BatchGetItem(ddbClient, &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			env.EntitiesTable: {Keys: requestKeys},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get entities")
+	}
+
+	var result []*entityItem
+	if err = dynamodbattribute.UnmarshalListOfMaps(output.Responses[env.EntitiesTable], &result); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal entities")
+	}
+	return result, nil
+}
+
+func putEntities(entities []*entityItem) error {
+	// A batch cannot write the same key twice, the last write of an entity wins
+	latest := make(map[string]*entityItem, len(entities))
+	for _, entity := range entities {
+		latest[entity.Entity] = entity
+	}
+
+	writes := make([]*dynamodb.WriteRequest, 0, len(latest))
+	for _, entity := range latest {
+		item, err := dynamodbattribute.MarshalMap(entity)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal entity")
+		}
+		writes = append(writes, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
+	}
+	sort.Slice(writes, func(i, j int) bool {
+		return *writes[i].PutRequest.Item[entityTablePartitionKey].S < *writes[j].PutRequest.Item[entityTablePartitionKey].S
+	})
+
+	input := &dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{env.EntitiesTable: writes},
+	}
+	if err := dynamodbbatch.BatchWriteItem(ddbClient, maxEntityWriteBackoff, input); err != nil {
+		return errors.Wrap(err, "failed to write entities")
+	}
+	return nil
+}
+
+// addToIncident adds the alerts to an incident, creating the incident if needed. It returns the updated incident and
+// the incident before the update, nil if it was created.
+func addToIncident(incidentID string, alerts ...*Alert) (*Incident, *Incident, error) {
+	var alertIDs, ruleIDs []string
+	var entities Entities
+	updateTime := alerts[0].UpdateTime
+	severity := alerts[0].Severity
+	for _, alert := range alerts {
+		alertIDs = append(alertIDs, alert.ID)
+		ruleIDs = union(ruleIDs, []string{alert.RuleID})
+		entities.merge(&alert.Entities)
+		if alert.UpdateTime.After(updateTime) {
+			updateTime = alert.UpdateTime
+		}
+		if severityRank(alert.Severity) > severityRank(severity) {
+			severity = alert.Severity
+		}
+	}
+
+	update := expression.
+		Set(expression.Name("creationTime"), expression.IfNotExists(expression.Name("creationTime"), expression.Value(updateTime))).
+		Set(expression.Name("timePartition"), expression.Value(alertstable.TimePartition(incidentID))).
+		Set(expression.Name("updateTime"), expression.Value(updateTime)).
+		Set(expression.Name("severity"), expression.IfNotExists(expression.Name("severity"), expression.Value(severity))).
+		Add(expression.Name("alertIds"), expression.Value(stringSet(alertIDs))).
+		Add(expression.Name("ruleIds"), expression.Value(stringSet(ruleIDs)))
+	if len(entities.IPAddresses) > 0 {
+		update = update.Add(expression.Name("ipAddresses"), expression.Value(stringSet(entities.IPAddresses)))
+	}
+	if len(entities.AWSAccountIDs) > 0 {
+		update = update.Add(expression.Name("awsAccountIds"), expression.Value(stringSet(entities.AWSAccountIDs)))
+	}
+	if len(entities.AWSInstanceIDs) > 0 {
+		update = update.Add(expression.Name("awsInstanceIds"), expression.Value(stringSet(entities.AWSInstanceIDs)))
+	}
+	expr, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to build update expression")
+	}
+
+	output, err := ddbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		Key:                       map[string]*dynamodb.AttributeValue{incidentTablePartitionKey: {S: aws.String(incidentID)}},
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllOld),
+		TableName:                 aws.String(env.IncidentsTable),
+		UpdateExpression:          expr.Update(),
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to update incident %s", incidentID)
+	}
+
+	incident := &Incident{
+		ID:            incidentID,
+		TimePartition: alertstable.TimePartition(incidentID),
+		CreationTime:  updateTime,
+		UpdateTime:    updateTime,
+		Severity:      severity,
+		AlertIDs:      alertIDs,
+		RuleIDs:       ruleIDs,
+		Entities:      entities,
+	}
+	if len(output.Attributes) == 0 {
+		return incident, nil, nil
+	}
+
+	previous := &Incident{}
+	if err = dynamodbattribute.UnmarshalMap(output.Attributes, previous); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to unmarshal incident")
+	}
+	incident.CreationTime = previous.CreationTime
+	incident.Severity = previous.Severity
+	incident.AlertIDs = union(previous.AlertIDs, alertIDs)
+	incident.RuleIDs = union(previous.RuleIDs, ruleIDs)
+	incident.Entities = previous.Entities
+	incident.Entities.merge(&entities)
+	if severityRank(severity) > severityRank(incident.Severity) {
+		if err = raiseIncidentSeverity(incident, severity); err != nil {
+			return nil, nil, err
+		}
+	}
+	return incident, previous, nil
+}
+
+// raiseIncidentSeverity sets a higher severity on an incident, unless it was changed concurrently
+func raiseIncidentSeverity(incident *Incident, severity string) error {
+	condition := expression.Name("severity").Equal(expression.Value(incident.Severity))
+	update := expression.Set(expression.Name("severity"), expression.Value(severity))
+	expr, err := expression.NewBuilder().WithCondition(condition).WithUpdate(update).Build()
+	if err != nil {
+		return errors.Wrap(err, "failed to build update expression")
+	}
+
+	_, err = ddbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		Key:                       map[string]*dynamodb.AttributeValue{incidentTablePartitionKey: {S: aws.String(incident.ID)}},
+		TableName:                 aws.String(env.IncidentsTable),
+		UpdateExpression:          expr.Update(),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to update severity of incident %s", incident.ID)
+	}
+	incident.Severity = severity
+	return nil
+}
+
+// getAlert returns an alert, or nil if it doesn't exist
+func getAlert(alertID string) (*Alert, error) {
+	output, err := ddbClient.GetItem(&dynamodb.GetItemInput{
+		Key:       map[string]*dynamodb.AttributeValue{alertTablePartitionKey: {S: aws.String(alertID)}},
+		TableName: aws.String(env.AlertsTable),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get alert %s", alertID)
+	}
+	if len(output.Item) == 0 {
+		return nil, nil
+	}
+	alert := &Alert{}
+	if err = dynamodbattribute.UnmarshalMap(output.Item, alert); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal alert")
+	}
+	return alert, nil
+}
+
+func setAlertIncident(alertID, incidentID string) error {
+	update := expression.Set(expression.Name(alertTableIncidentIDAttribute), expression.Value(incidentID))
+	expr, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return errors.Wrap(err, "failed to build update expression")
+	}
+	_, err = ddbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		Key:                       map[string]*dynamodb.AttributeValue{alertTablePartitionKey: {S: aws.String(alertID)}},
+		TableName:                 aws.String(env.AlertsTable),
+		UpdateExpression:          expr.Update(),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to set incident of alert %s", alertID)
+	}
+	return nil
+}
+
+// generateIncidentID derives the incident id from the first alert, retries update the same incident
+func generateIncidentID(firstAlertID string) string {
+	keyHash := md5.Sum([]byte("incident:" + firstAlertID)) // nolint(gosec)
+	return hex.EncodeToString(keyHash[:])
+}
+
+func severityRank(severity string) int {
+	for i, value := range severities {
+		if value == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// union returns the values of both slices without duplicates, in order of first appearance
+func union(left, right []string) []string {
+	seen := make(map[string]struct{}, len(left)+len(right))
+	var result []string
+	for _, values := range [][]string{left, right} {
+		for _, value := range values {
+			if _, ok := seen[value]; !ok {
+				seen[value] = struct{}{}
+				result = append(result, value)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,330 @@
+package forwarder
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	policiesclient "github.com/panther-labs/panther/api/gateway/analysis/client"
+	alertModel "github.com/panther-labs/panther/internal/core/alert_delivery/models"
+	alertstable "github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/pkg/testutils"
+)
+
+func init() {
+	env.IncidentsTable = "incidentsTable"
+	env.EntitiesTable = "entitiesTable"
+	env.CorrelationWindowMinutes = 60
+	env.IncidentLifetimeMinutes = 24 * 60
+}
+
+func setupCorrelationTest(t *testing.T) (*testutils.DynamoDBMock, *testutils.SqsMock, *AlertDedupEvent) {
+	ddbMock := &testutils.DynamoDBMock{}
+	ddbClient = ddbMock
+
+	sqsMock := &testutils.SqsMock{}
+	sqsClient = sqsMock
+
+	mockRoundTripper := &mockRoundTripper{}
+	httpClient = &http.Client{Transport: mockRoundTripper}
+	policyConfig = policiesclient.DefaultTransportConfig().
+		WithHost("host").
+		WithBasePath("path")
+	policyClient = policiesclient.NewHTTPClientWithConfig(nil, policyConfig)
+	ResetRuleCache()
+	mockRoundTripper.On("RoundTrip", mock.Anything).Return(generateResponse(testRuleResponse, http.StatusOK), nil).Once()
+
+	event := *newAlertDedupEvent
+	event.Entities = Entities{IPAddresses: []string{"1.2.3.4"}}
+	ddbMock.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
+	return ddbMock, sqsMock, &event
+}
+
+func marshalItem(t *testing.T, item interface{}) map[string]*dynamodb.AttributeValue {
+	result, err := dynamodbattribute.MarshalMap(item)
+	require.NoError(t, err)
+	return result
+}
+
+func matchTable(tableName string) interface{} {
+	return mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+		return *input.TableName == tableName
+	})
+}
+
+func TestHandleStartsIncident(t *testing.T) {
+	ddbMock, sqsMock, event := setupCorrelationTest(t)
+
+	otherAlert := &Alert{
+		ID:            "otherAlertId",
+		TimePartition: alertstable.TimePartition("otherAlertId"),
+		Status:        "OPEN",
+		Severity:      "HIGH",
+		Title:         "other title",
+		AlertDedupEvent: AlertDedupEvent{
+			RuleID:       "otherRuleId",
+			CreationTime: event.UpdateTime.Add(-time.Minute),
+			UpdateTime:   event.UpdateTime.Add(-time.Minute),
+			Entities:     Entities{IPAddresses: []string{"1.2.3.4"}},
+		},
+	}
+	ddbMock.On("BatchGetItemPages", mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]*dynamodb.AttributeValue{
+			"entitiesTable": {marshalItem(t, &entityItem{
+				Entity:   "ip:1.2.3.4",
+				AlertID:  otherAlert.ID,
+				RuleID:   otherAlert.RuleID,
+				LastSeen: otherAlert.UpdateTime,
+			})},
+		},
+	}, nil).Once()
+	ddbMock.On("GetItem", &dynamodb.GetItemInput{
+		Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String(otherAlert.ID)}},
+		TableName: aws.String("alertsTable"),
+	}).Return(&dynamodb.GetItemOutput{Item: marshalItem(t, otherAlert)}, nil).Once()
+
+	// The incident is created
+	incidentID := generateIncidentID(otherAlert.ID)
+	ddbMock.On("UpdateItem", mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+		return *input.TableName == "incidentsTable" && *input.Key["id"].S == incidentID
+	})).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+	// The entity of both alerts is the same, it points to the new alert
+	ddbMock.On("BatchWriteItem", mock.MatchedBy(func(input *dynamodb.BatchWriteItemInput) bool {
+		writes := input.RequestItems["entitiesTable"]
+		return len(writes) == 1 &&
+			*writes[0].PutRequest.Item["alertId"].S == "b25dc23fb2a0b362da8428dbec1381a8" &&
+			*writes[0].PutRequest.Item["incidentId"].S == incidentID &&
+			writes[0].PutRequest.Item["incidentCreationTime"] != nil
+	})).Return(&dynamodb.BatchWriteItemOutput{}, nil).Once()
+	ddbMock.On("UpdateItem", mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+		return *input.TableName == "alertsTable" && *input.Key["id"].S == otherAlert.ID
+	})).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+	ddbMock.On("PutItem", mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+		return *input.Item["incidentId"].S == incidentID
+	})).Return(&dynamodb.PutItemOutput{}, nil).Once()
+
+	expectedNotification, err := jsoniter.MarshalToString(&alertModel.Alert{
+		CreatedAt:  aws.Time(event.UpdateTime),
+		PolicyID:   aws.String(event.RuleID),
+		PolicyName: aws.String(string(testRuleResponse.DisplayName)),
+		Severity:   aws.String("HIGH"),
+		Type:       aws.String(alertModel.IncidentType),
+		AlertID:    aws.String("b25dc23fb2a0b362da8428dbec1381a8"),
+		IncidentID: aws.String(incidentID),
+		Title:      event.GeneratedTitle,
+	})
+	require.NoError(t, err)
+	sqsMock.On("SendMessage", &sqs.SendMessageInput{
+		MessageBody: aws.String(expectedNotification),
+		QueueUrl:    aws.String("queueUrl"),
+	}).Return(&sqs.SendMessageOutput{}, nil).Once()
+
+	require.NoError(t, Handle(oldAlertDedupEvent, event))
+
+	ddbMock.AssertExpectations(t)
+	sqsMock.AssertExpectations(t)
+}
+
+func TestHandleJoinsIncident(t *testing.T) {
+	ddbMock, sqsMock, event := setupCorrelationTest(t)
+
+	// Alerts of the same rule are correlated once they are in an incident
+	ddbMock.On("BatchGetItemPages", mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]*dynamodb.AttributeValue{
+			"entitiesTable": {marshalItem(t, &entityItem{
+				Entity:     "ip:1.2.3.4",
+				AlertID:    "otherAlertId",
+				RuleID:     event.RuleID,
+				IncidentID: aws.String("incidentId"),
+				LastSeen:   event.UpdateTime.Add(-time.Minute),
+			})},
+		},
+	}, nil).Once()
+	previous := &Incident{
+		ID:       "incidentId",
+		Severity: "INFO",
+		AlertIDs: []string{"otherAlertId"},
+		RuleIDs:  []string{"anotherRuleId", event.RuleID},
+	}
+	ddbMock.On("UpdateItem", matchTable("incidentsTable")).
+		Return(&dynamodb.UpdateItemOutput{Attributes: marshalItem(t, previous)}, nil).Once()
+	ddbMock.On("BatchWriteItem", mock.Anything).Return(&dynamodb.BatchWriteItemOutput{}, nil).Once()
+	ddbMock.On("PutItem", mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+		return *input.Item["incidentId"].S == "incidentId"
+	})).Return(&dynamodb.PutItemOutput{}, nil).Once()
+
+	// The incident was already delivered, the alert neither raises its severity nor adds a rule
+	require.NoError(t, Handle(oldAlertDedupEvent, event))
+
+	ddbMock.AssertExpectations(t)
+	sqsMock.AssertExpectations(t)
+}
+
+func TestHandleJoinsIncidentNewRule(t *testing.T) {
+	ddbMock, sqsMock, event := setupCorrelationTest(t)
+
+	ddbMock.On("BatchGetItemPages", mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]*dynamodb.AttributeValue{
+			"entitiesTable": {marshalItem(t, &entityItem{
+				Entity:     "ip:1.2.3.4",
+				AlertID:    "otherAlertId",
+				RuleID:     "otherRuleId",
+				IncidentID: aws.String("incidentId"),
+				LastSeen:   event.UpdateTime.Add(-time.Minute),
+			})},
+		},
+	}, nil).Once()
+	previous := &Incident{
+		ID:           "incidentId",
+		CreationTime: event.UpdateTime.Add(-time.Hour),
+		Severity:     "INFO",
+		AlertIDs:     []string{"otherAlertId", "anotherAlertId"},
+		RuleIDs:      []string{"otherRuleId", "anotherRuleId"},
+	}
+	ddbMock.On("UpdateItem", matchTable("incidentsTable")).
+		Return(&dynamodb.UpdateItemOutput{Attributes: marshalItem(t, previous)}, nil).Once()
+	ddbMock.On("BatchWriteItem", mock.Anything).Return(&dynamodb.BatchWriteItemOutput{}, nil).Once()
+	ddbMock.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil).Once()
+
+	expectedNotification, err := jsoniter.MarshalToString(&alertModel.Alert{
+		CreatedAt:      aws.Time(previous.CreationTime),
+		PolicyID:       aws.String(event.RuleID),
+		PolicyName:     aws.String(string(testRuleResponse.DisplayName)),
+		Severity:       aws.String("INFO"),
+		Type:           aws.String(alertModel.IncidentType),
+		AlertID:        aws.String("b25dc23fb2a0b362da8428dbec1381a8"),
+		IncidentID:     aws.String("incidentId"),
+		IncidentUpdate: aws.String("Rule " + event.RuleID + " joined the incident"),
+		Title:          event.GeneratedTitle,
+	})
+	require.NoError(t, err)
+	sqsMock.On("SendMessage", &sqs.SendMessageInput{
+		MessageBody: aws.String(expectedNotification),
+		QueueUrl:    aws.String("queueUrl"),
+	}).Return(&sqs.SendMessageOutput{}, nil).Once()
+
+	require.NoError(t, Handle(oldAlertDedupEvent, event))
+
+	ddbMock.AssertExpectations(t)
+	sqsMock.AssertExpectations(t)
+}
+
+func TestGetIncidentChange(t *testing.T) {
+	previous := &Incident{Severity: "MEDIUM", RuleIDs: []string{"ruleId"}}
+	alert := func(ruleID, severity string) *Alert {
+		return &Alert{Severity: severity, AlertDedupEvent: AlertDedupEvent{RuleID: ruleID}}
+	}
+	assert.Equal(t, incidentUnchanged, getIncidentChange(nil, alert("otherRuleId", "HIGH")))
+	assert.Equal(t, incidentUnchanged, getIncidentChange(previous, alert("ruleId", "LOW")))
+	assert.Equal(t, incidentSeverityRaised, getIncidentChange(previous, alert("ruleId", "CRITICAL")))
+	assert.Equal(t, incidentSeverityRaised, getIncidentChange(previous, alert("otherRuleId", "HIGH")))
+	assert.Equal(t, incidentRuleJoined, getIncidentChange(previous, alert("otherRuleId", "MEDIUM")))
+}
+
+func TestEntityKeys(t *testing.T) {
+	entities := &Entities{
+		IPAddresses:    []string{"1.2.3.4"},
+		AWSAccountIDs:  []string{"123456789012"},
+		AWSInstanceIDs: []string{"i-1234"},
+	}
+	assert.Equal(t, []string{"ip:1.2.3.4", "awsInstance:i-1234"}, entities.entityKeys())
+
+	env.CorrelateAWSAccountIDs = true
+	defer func() { env.CorrelateAWSAccountIDs = false }()
+	assert.Equal(t, []string{"ip:1.2.3.4", "awsAccount:123456789012", "awsInstance:i-1234"}, entities.entityKeys())
+}
+
+func TestHandleNotCorrelated(t *testing.T) {
+	ddbMock, sqsMock, event := setupCorrelationTest(t)
+
+	// The entity was last seen in an alert of the same rule
+	ddbMock.On("BatchGetItemPages", mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]*dynamodb.AttributeValue{
+			"entitiesTable": {marshalItem(t, &entityItem{
+				Entity:   "ip:1.2.3.4",
+				AlertID:  "otherAlertId",
+				RuleID:   event.RuleID,
+				LastSeen: event.UpdateTime.Add(-time.Minute),
+			})},
+		},
+	}, nil).Once()
+	ddbMock.On("BatchWriteItem", mock.Anything).Return(&dynamodb.BatchWriteItemOutput{}, nil).Once()
+	ddbMock.On("PutItem", mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+		return input.Item["incidentId"] == nil
+	})).Return(&dynamodb.PutItemOutput{}, nil).Once()
+	sqsMock.On("SendMessage", mock.Anything).Return(&sqs.SendMessageOutput{}, nil).Once()
+
+	require.NoError(t, Handle(oldAlertDedupEvent, event))
+
+	ddbMock.AssertExpectations(t)
+	sqsMock.AssertExpectations(t)
+}
+
+func TestFindCorrelatedEntity(t *testing.T) {
+	now := time.Now().UTC()
+	alert := &Alert{ID: "alertId", AlertDedupEvent: AlertDedupEvent{RuleID: "ruleId", UpdateTime: now}}
+
+	own := &entityItem{AlertID: "alertId", RuleID: "otherRuleId", LastSeen: now}
+	expired := &entityItem{AlertID: "expired", RuleID: "otherRuleId", LastSeen: now.Add(-2 * time.Hour)}
+	sameRule := &entityItem{AlertID: "sameRule", RuleID: "ruleId", LastSeen: now}
+	older := &entityItem{AlertID: "older", RuleID: "otherRuleId", LastSeen: now.Add(-time.Minute)}
+	latest := &entityItem{AlertID: "latest", RuleID: "otherRuleId", LastSeen: now}
+	inIncident := &entityItem{
+		AlertID: "inIncident", RuleID: "ruleId", IncidentID: aws.String("incidentId"), LastSeen: now.Add(-time.Hour),
+	}
+	// the incident is older than its lifetime, it no longer takes alerts even if its entities keep being seen
+	inClosedIncident := &entityItem{
+		AlertID: "inClosedIncident", RuleID: "otherRuleId", IncidentID: aws.String("closedIncidentId"),
+		IncidentCreationTime: aws.Time(now.Add(-25 * time.Hour)), LastSeen: now,
+	}
+
+	assert.Nil(t, findCorrelatedEntity(alert, []*entityItem{own, expired, sameRule, inClosedIncident}))
+	assert.Equal(t, latest, findCorrelatedEntity(alert, []*entityItem{older, latest, sameRule}))
+	assert.Equal(t, inIncident, findCorrelatedEntity(alert, []*entityItem{latest, inIncident}))
+}
+
+func TestAddToIncidentRaisesSeverity(t *testing.T) {
+	ddbMock := &testutils.DynamoDBMock{}
+	ddbClient = ddbMock
+
+	alert := &Alert{ID: "alertId", Severity: "CRITICAL", AlertDedupEvent: AlertDedupEvent{RuleID: "ruleId"}}
+	ddbMock.On("UpdateItem", mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+		return input.ConditionExpression == nil
+	})).Return(&dynamodb.UpdateItemOutput{Attributes: marshalItem(t, &Incident{ID: "incidentId", Severity: "LOW"})}, nil).Once()
+	ddbMock.On("UpdateItem", mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+		return input.ConditionExpression != nil && *input.ExpressionAttributeValues[":0"].S == "LOW"
+	})).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+
+	incident, previous, err := addToIncident("incidentId", alert)
+	require.NoError(t, err)
+	assert.Equal(t, "CRITICAL", incident.Severity)
+	assert.Equal(t, "LOW", previous.Severity)
+	ddbMock.AssertExpectations(t)
+}
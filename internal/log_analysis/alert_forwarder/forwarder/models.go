@@ -31,6 +31,7 @@ const (
 	alertTableLogTypesAttribute   = "logTypes"
 	alertTableEventCountAttribute = "eventCount"
 	alertTableUpdateTimeAttribute = "updateTime"
+	alertTableIncidentIDAttribute = "incidentId"
 )
 
 // AlertDedupEvent represents the event stored in the alert dedup DDB table by the rules engine
@@ -38,18 +39,31 @@ type AlertDedupEvent struct {
 	RuleID              string    `dynamodbav:"ruleId,string"`
 	RuleVersion         string    `dynamodbav:"ruleVersion,string"`
 	DeduplicationString string    `dynamodbav:"dedup,string"`
-	CreationTime        time.Time `dynamodbav:"creationTime,string"`
-	UpdateTime          time.Time `dynamodbav:"updateTime,string"`
+	CreationTime        time.Time `dynamodbav:"creationTime"`
+	UpdateTime          time.Time `dynamodbav:"updateTime"`
 	EventCount          int64     `dynamodbav:"eventCount,number"`
 	LogTypes            []string  `dynamodbav:"logTypes,stringset"`
 	GeneratedTitle      *string   `dynamodbav:"-"` // The title that was generated dynamically using Python. Might be null.
 	AlertCount          int64     `dynamodbav:"-"` // There is no need to store this item in DDB
+	// The first matched events, stored in the alert so they are delivered again when it is resent
+	EventSamples []string `dynamodbav:"eventSamples,omitempty"`
+	Entities
+}
+
+// Entities are the indicators of the matched events used to correlate the alerts of different rules
+type Entities struct {
+	IPAddresses    []string `dynamodbav:"ipAddresses,stringset,omitempty"`
+	AWSAccountIDs  []string `dynamodbav:"awsAccountIds,stringset,omitempty"`
+	AWSInstanceIDs []string `dynamodbav:"awsInstanceIds,stringset,omitempty"`
 }
 
 // Alert contains all the fields associated to the alert stored in DDB
 type Alert struct {
 	ID              string  `dynamodbav:"id,string"`
 	TimePartition   string  `dynamodbav:"timePartition,string"`
+	Status          string  `dynamodbav:"status,string"`                  // The triage status, new alerts are open
+	SuppressionID   *string `dynamodbav:"suppressionId,string,omitempty"` // Set if the alert was suppressed
+	IncidentID      *string `dynamodbav:"incidentId,string,omitempty"`    // Set if the alert was correlated into an incident
 	Severity        string  `dynamodbav:"severity,string"`
 	RuleDisplayName *string `dynamodbav:"ruleDisplayName,string"`
 	Title           string  `dynamodbav:"title,string"` // The alert title. It will be the Python-generated title or a default one if
@@ -127,6 +141,21 @@ func FromDynamodDBAttribute(input map[string]events.DynamoDBAttributeValue) (eve
 	if generatedTitle != nil {
 		result.GeneratedTitle = aws.String(generatedTitle.String())
 	}
+
+	if ipAddresses := getOptionalAttribute("ipAddresses", input); ipAddresses != nil {
+		result.IPAddresses = ipAddresses.StringSet()
+	}
+	if accountIDs := getOptionalAttribute("awsAccountIds", input); accountIDs != nil {
+		result.AWSAccountIDs = accountIDs.StringSet()
+	}
+	if instanceIDs := getOptionalAttribute("awsInstanceIds", input); instanceIDs != nil {
+		result.AWSInstanceIDs = instanceIDs.StringSet()
+	}
+	if samples := getOptionalAttribute("eventSamples", input); samples != nil {
+		for _, sample := range samples.List() {
+			result.EventSamples = append(result.EventSamples, sample.String())
+		}
+	}
 	return result, nil
 }
 
@@ -38,6 +38,12 @@ func TestConvertAttribute(t *testing.T) {
 		EventCount:          100,
 		LogTypes:            []string{"Log.Type.1", "Log.Type.2"},
 		GeneratedTitle:      aws.String("test title"),
+		EventSamples:        []string{`{"key": "value1"}`, `{"key": "value2"}`},
+		Entities: Entities{
+			IPAddresses:    []string{"10.0.0.1"},
+			AWSAccountIDs:  []string{"123456789012"},
+			AWSInstanceIDs: []string{"i-0123456789abcdef0"},
+		},
 	}
 
 	alertDedupEvent, err := FromDynamodDBAttribute(getNewTestCase())
@@ -65,6 +71,10 @@ func TestConvertAttributeWithoutOptionalFields(t *testing.T) {
 
 	ddbItem := getNewTestCase()
 	delete(ddbItem, "title")
+	delete(ddbItem, "ipAddresses")
+	delete(ddbItem, "awsAccountIds")
+	delete(ddbItem, "awsInstanceIds")
+	delete(ddbItem, "eventSamples")
 	alertDedupEvent, err := FromDynamodDBAttribute(ddbItem)
 	require.NoError(t, err)
 	require.Equal(t, expectedAlertDedup, alertDedupEvent)
@@ -153,5 +163,12 @@ func getNewTestCase() map[string]events.DynamoDBAttributeValue {
 		"eventCount":        events.NewNumberAttribute("100"),
 		"logTypes":          events.NewStringSetAttribute([]string{"Log.Type.1", "Log.Type.2"}),
 		"title":             events.NewStringAttribute("test title"),
+		"ipAddresses":       events.NewStringSetAttribute([]string{"10.0.0.1"}),
+		"awsAccountIds":     events.NewStringSetAttribute([]string{"123456789012"}),
+		"awsInstanceIds":    events.NewStringSetAttribute([]string{"i-0123456789abcdef0"}),
+		"eventSamples": events.NewListAttribute([]events.DynamoDBAttributeValue{
+			events.NewStringAttribute(`{"key": "value1"}`),
+			events.NewStringAttribute(`{"key": "value2"}`),
+		}),
 	}
 }
@@ -52,6 +52,9 @@ func reporterHandler(lc *lambdacontext.LambdaContext, event events.DynamoDBEvent
 		operation.Stop().Log(err, zap.Int("messageCount", len(event.Records)))
 	}()
 
+	// The rules of the events are fetched once per invocation
+	forwarder.ResetRuleCache()
+
 	// Note that if there is an error in processing any of the messages in the batch, the whole batch will be retried.
 	for _, record := range event.Records {
 		oldAlertDedupEvent, unmarshalErr := forwarder.FromDynamodDBAttribute(record.Change.OldImage)
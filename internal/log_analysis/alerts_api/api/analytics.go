@@ -0,0 +1,180 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	"github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
+	"github.com/panther-labs/panther/pkg/gatewayapi"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+const (
+	maxAnalyticsBuckets = 1000
+	defaultTopRules     = 10
+)
+
+var analyticsIntervals = map[string]time.Duration{
+	models.IntervalHour: time.Hour,
+	models.IntervalDay:  24 * time.Hour,
+}
+
+// GetAlertAnalytics counts the alerts created in a time range by interval and group
+func (API) GetAlertAnalytics(input *models.GetAlertAnalyticsInput) (result *models.GetAlertAnalyticsOutput, err error) {
+	operation := common.OpLogManager.Start("getAlertAnalytics")
+	defer func() {
+		operation.Stop()
+		operation.Log(err, zap.String("interval", *input.Interval), zap.String("groupBy", *input.GroupBy))
+	}()
+
+	interval := analyticsIntervals[*input.Interval]
+	start := input.CreatedAtAfter.UTC().Truncate(interval)
+	numBuckets := int(input.CreatedAtBefore.Sub(start)/interval) + 1
+	if numBuckets > maxAnalyticsBuckets {
+		return nil, &genericapi.InvalidInputError{
+			Message: "time range has more than " + strconv.Itoa(maxAnalyticsBuckets) + " intervals"}
+	}
+
+	alertItems, err := alertsDB.ListCreatedBetween(*input.CreatedAtAfter, *input.CreatedAtBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]map[string]*models.AlertCount, numBuckets)
+	for i := range buckets {
+		buckets[i] = make(map[string]*models.AlertCount)
+	}
+	rules := make(map[string]*models.AlertCount)
+	total := newAlertCount(nil)
+	resolvedCount, timedCount, resolveSeconds := 0, 0, 0.0
+	for _, item := range alertItems {
+		addAlert(total, item)
+		addAlert(groupCount(rules, item.RuleID), item)
+
+		index := int(item.CreationTime.Sub(start) / interval)
+		if index >= 0 && index < numBuckets {
+			for _, key := range groupKeys(item, *input.GroupBy) {
+				addAlert(groupCount(buckets[index], key), item)
+			}
+		}
+
+		if isResolved(item) {
+			resolvedCount++
+			// alerts resolved before resolvedAt was stored have no resolution time
+			if item.ResolvedAt != nil {
+				timedCount++
+				resolveSeconds += item.ResolvedAt.Sub(item.CreationTime).Seconds()
+			}
+		}
+	}
+
+	result = &models.GetAlertAnalyticsOutput{
+		Buckets:       make([]*models.AlertAnalyticsBucket, numBuckets),
+		TopRules:      topRules(rules, input.TopRules),
+		Total:         total,
+		ResolvedCount: aws.Int(resolvedCount),
+	}
+	for i, groups := range buckets {
+		result.Buckets[i] = &models.AlertAnalyticsBucket{
+			StartTime: aws.Time(start.Add(time.Duration(i) * interval)),
+			Groups:    sortedGroups(groups),
+		}
+	}
+	if timedCount > 0 {
+		result.MeanTimeToResolveSeconds = aws.Float64(resolveSeconds / float64(timedCount))
+	}
+
+	gatewayapi.ReplaceMapSliceNils(result)
+	return result, nil
+}
+
+// groupKeys returns the groups of the alert, an alert with several log types is in the group of each one
+func groupKeys(item *table.AlertItem, groupBy string) []string {
+	switch groupBy {
+	case models.GroupBySeverity:
+		return []string{item.Severity}
+	case models.GroupByLogType:
+		return item.LogTypes
+	default:
+		return []string{item.RuleID}
+	}
+}
+
+// isResolved returns true if the alert was closed or marked as false positive
+func isResolved(item *table.AlertItem) bool {
+	return item.Status == models.StatusClosed || item.Status == models.StatusFalsePositive
+}
+
+func newAlertCount(key *string) *models.AlertCount {
+	return &models.AlertCount{Key: key, AlertCount: aws.Int(0), EventCount: aws.Int(0)}
+}
+
+func groupCount(groups map[string]*models.AlertCount, key string) *models.AlertCount {
+	count, ok := groups[key]
+	if !ok {
+		count = newAlertCount(aws.String(key))
+		groups[key] = count
+	}
+	return count
+}
+
+func addAlert(count *models.AlertCount, item *table.AlertItem) {
+	*count.AlertCount++
+	*count.EventCount += item.EventCount
+}
+
+// sortedGroups returns the group counts ordered by key
+func sortedGroups(groups map[string]*models.AlertCount) []*models.AlertCount {
+	result := make([]*models.AlertCount, 0, len(groups))
+	for _, count := range groups {
+		result = append(result, count)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return *result[i].Key < *result[j].Key
+	})
+	return result
+}
+
+// topRules returns the rules with the most alerts, then the most events
+func topRules(rules map[string]*models.AlertCount, limit *int) []*models.AlertCount {
+	result := sortedGroups(rules)
+	sort.SliceStable(result, func(i, j int) bool {
+		if *result[i].AlertCount != *result[j].AlertCount {
+			return *result[i].AlertCount > *result[j].AlertCount
+		}
+		return *result[i].EventCount > *result[j].EventCount
+	})
+
+	n := defaultTopRules
+	if limit != nil {
+		n = *limit
+	}
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
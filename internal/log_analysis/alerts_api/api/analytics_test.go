@@ -0,0 +1,99 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	"github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+func TestGetAlertAnalytics(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	after := time.Date(2020, 5, 1, 10, 30, 0, 0, time.UTC)
+	before := time.Date(2020, 5, 1, 12, 30, 0, 0, time.UTC)
+	resolvedAt := time.Date(2020, 5, 1, 11, 0, 0, 0, time.UTC)
+	alerts := []*table.AlertItem{
+		{AlertID: "alert1", RuleID: "rule1", Severity: "HIGH", EventCount: 5, LogTypes: []string{"AWS.CloudTrail"},
+			CreationTime: time.Date(2020, 5, 1, 10, 40, 0, 0, time.UTC), Status: models.StatusClosed,
+			LastUpdatedByTime: &resolvedAt, ResolvedAt: &resolvedAt},
+		{AlertID: "alert2", RuleID: "rule2", Severity: "LOW", EventCount: 1,
+			LogTypes:     []string{"AWS.CloudTrail", "AWS.VPCFlow"},
+			CreationTime: time.Date(2020, 5, 1, 10, 50, 0, 0, time.UTC)},
+		// resolved before resolvedAt was stored
+		{AlertID: "alert3", RuleID: "rule2", Severity: "LOW", EventCount: 2, LogTypes: []string{"AWS.VPCFlow"},
+			CreationTime: time.Date(2020, 5, 1, 12, 10, 0, 0, time.UTC), Status: models.StatusFalsePositive},
+	}
+	tableMock.On("ListCreatedBetween", after, before).Return(alerts, nil).Once()
+
+	result, err := API{}.GetAlertAnalytics(&models.GetAlertAnalyticsInput{
+		CreatedAtAfter:  &after,
+		CreatedAtBefore: &before,
+		Interval:        aws.String(models.IntervalHour),
+		GroupBy:         aws.String(models.GroupByLogType),
+		TopRules:        aws.Int(1),
+	})
+	require.NoError(t, err)
+	tableMock.AssertExpectations(t)
+
+	// Buckets start at the beginning of the interval, empty intervals are included
+	require.Len(t, result.Buckets, 3)
+	assert.Equal(t, time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC), *result.Buckets[0].StartTime)
+	assert.Equal(t, []*models.AlertCount{
+		{Key: aws.String("AWS.CloudTrail"), AlertCount: aws.Int(2), EventCount: aws.Int(6)},
+		{Key: aws.String("AWS.VPCFlow"), AlertCount: aws.Int(1), EventCount: aws.Int(1)},
+	}, result.Buckets[0].Groups)
+	assert.Equal(t, []*models.AlertCount{}, result.Buckets[1].Groups)
+	assert.Equal(t, []*models.AlertCount{
+		{Key: aws.String("AWS.VPCFlow"), AlertCount: aws.Int(1), EventCount: aws.Int(2)},
+	}, result.Buckets[2].Groups)
+
+	assert.Equal(t, []*models.AlertCount{
+		{Key: aws.String("rule2"), AlertCount: aws.Int(2), EventCount: aws.Int(3)},
+	}, result.TopRules)
+	assert.Equal(t, &models.AlertCount{AlertCount: aws.Int(3), EventCount: aws.Int(8)}, result.Total)
+	assert.Equal(t, 2, *result.ResolvedCount)
+	assert.Equal(t, 20*time.Minute.Seconds(), *result.MeanTimeToResolveSeconds)
+}
+
+func TestGetAlertAnalyticsTooManyBuckets(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	after := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	result, err := API{}.GetAlertAnalytics(&models.GetAlertAnalyticsInput{
+		CreatedAtAfter:  &after,
+		CreatedAtBefore: &before,
+		Interval:        aws.String(models.IntervalHour),
+		GroupBy:         aws.String(models.GroupBySeverity),
+	})
+	assert.Nil(t, result)
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+	tableMock.AssertExpectations(t)
+}
@@ -26,6 +26,8 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/kelseyhightower/envconfig"
 
@@ -40,15 +42,25 @@ var (
 	awsSession *session.Session
 	alertsDB   table.API
 	s3Client   s3iface.S3API
+	sqsClient  sqsiface.SQSAPI
 )
 
 type envConfig struct {
-	AnalysisAPIHost     string `required:"true" split_words:"true"`
-	AnalysisAPIPath     string `required:"true" split_words:"true"`
-	AlertsTableName     string `required:"true" split_words:"true"`
-	RuleIndexName       string `required:"true" split_words:"true"`
-	TimeIndexName       string `required:"true" split_words:"true"`
-	ProcessedDataBucket string `required:"true" split_words:"true"`
+	AnalysisAPIHost       string `required:"true" split_words:"true"`
+	AnalysisAPIPath       string `required:"true" split_words:"true"`
+	AlertsTableName       string `required:"true" split_words:"true"`
+	ActivityTableName     string `required:"true" split_words:"true"`
+	SuppressionsTableName string `required:"true" split_words:"true"`
+	IncidentsTableName    string `required:"true" split_words:"true"`
+	IncidentsIndexName    string `required:"true" split_words:"true"`
+	AlertEventsTableName  string `required:"true" split_words:"true"`
+	DeliveriesTableName   string `required:"true" split_words:"true"`
+	AlertingQueueURL      string `required:"true" split_words:"true"`
+	RuleIndexName         string `required:"true" split_words:"true"`
+	TimeIndexName         string `required:"true" split_words:"true"`
+	UpdateTimeIndexName   string `required:"true" split_words:"true"`
+	ProcessedDataBucket   string `required:"true" split_words:"true"`
+	AlertRetentionDays    int    `required:"true" split_words:"true"`
 }
 
 // Setup parses the environment and builds the AWS and http clients.
@@ -58,19 +70,30 @@ func Setup() {
 	awsSession = session.Must(session.NewSession())
 	alertsDB = &table.AlertsTable{
 		AlertsTableName:                    env.AlertsTableName,
+		ActivityTableName:                  env.ActivityTableName,
+		SuppressionsTableName:              env.SuppressionsTableName,
+		IncidentsTableName:                 env.IncidentsTableName,
+		IncidentsTimePartitionIndexName:    env.IncidentsIndexName,
+		AlertEventsTableName:               env.AlertEventsTableName,
+		DeliveriesTableName:                env.DeliveriesTableName,
 		Client:                             dynamodb.New(awsSession),
 		RuleIDCreationTimeIndexName:        env.RuleIndexName,
 		TimePartitionCreationTimeIndexName: env.TimeIndexName,
+		TimePartitionUpdateTimeIndexName:   env.UpdateTimeIndexName,
+		RetentionDays:                      env.AlertRetentionDays,
 	}
 	s3Client = s3.New(awsSession)
+	sqsClient = sqs.New(awsSession)
 }
 
 // Token used for paginating through the events in an alert
 type EventPaginationToken struct {
-	LogTypeToToken map[string]*LogTypeToken `json:"logTypeToToken"`
+	LogTypeToToken map[string]*LogTypeToken `json:"logTypeToToken,omitempty"`
+	// Set if the events are read from the alert events index
+	Indexed *LogTypeToken `json:"indexed,omitempty"`
 }
 
-// Token used for paginating in the events of a specific log type
+// Token used for paginating in the events of a specific log type, or in the objects of the alert events index
 type LogTypeToken struct {
 	S3ObjectKey string `json:"s3ObjectKey"`
 	EventIndex  int    `json:"eventIndex"`
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"go.uber.org/zap"
 
@@ -67,6 +68,20 @@ func (API) GetAlert(input *models.GetAlertInput) (result *models.GetAlertOutput,
 	}
 
 	var events []string
+	if input.EventsExclusiveStartKey == nil || token.Indexed != nil {
+		// The events of alerts matched since the alert events index exists are read from the objects in the index
+		indexedEvents, indexToken, indexed, err := getIndexedEvents(alertItem.AlertID, token.Indexed, *input.EventsPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if indexed {
+			return alertWithEvents(alertItem, indexedEvents, &EventPaginationToken{Indexed: indexToken})
+		}
+	}
+	if token.LogTypeToToken == nil {
+		token.LogTypeToToken = make(map[string]*LogTypeToken)
+	}
+
 	for _, logType := range alertItem.LogTypes {
 		// Each alert can contain events from multiple log types.
 		// Retrieve results from each log type.
@@ -85,31 +100,92 @@ func (API) GetAlert(input *models.GetAlertInput) (result *models.GetAlertOutput,
 		}
 	}
 
+	return alertWithEvents(alertItem, events, token)
+}
+
+func alertWithEvents(alertItem *table.AlertItem, events []string, token *EventPaginationToken) (*models.Alert, error) {
 	encodedToken, err := token.encode()
 	if err != nil {
 		return nil, err
 	}
-	result = &models.Alert{
-		AlertSummary: models.AlertSummary{
-			AlertID:         &alertItem.AlertID,
-			RuleID:          &alertItem.RuleID,
-			DedupString:     &alertItem.DedupString,
-			CreationTime:    &alertItem.CreationTime,
-			UpdateTime:      &alertItem.UpdateTime,
-			EventsMatched:   &alertItem.EventCount,
-			RuleDisplayName: alertItem.RuleDisplayName,
-			Title:           getAlertTitle(alertItem),
-			RuleVersion:     &alertItem.RuleVersion,
-			Severity:        &alertItem.Severity,
-		},
+	attempts, err := alertsDB.ListDeliveryAttempts(alertItem.AlertID)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.Alert{
+		AlertSummary:           *alertItemToAlertSummary(alertItem),
 		Events:                 aws.StringSlice(events),
 		EventsLastEvaluatedKey: aws.String(encodedToken),
+		DeliveryResponses:      make([]*models.DeliveryResponse, len(attempts)),
+	}
+	for i, attempt := range attempts {
+		result.DeliveryResponses[i] = deliveryAttemptToDeliveryResponse(attempt)
 	}
 
 	gatewayapi.ReplaceMapSliceNils(result)
 	return result, nil
 }
 
+// getIndexedEvents returns up to maxResults events of an alert from the objects of the alert events index,
+// continuing after the token. It returns false if the alert has no objects in the index, alerts matched before the
+// index existed are read by scanning the partitions of the alert.
+//
+// The index has no row offsets: every row of an indexed object is an event of the alert, the object is read from
+// the start or from the event index of the token.
+func getIndexedEvents(alertID string, token *LogTypeToken, maxResults int) (
+	result []string, resultToken *LogTypeToken, indexed bool, err error) {
+
+	var startAfter *string
+	if token != nil {
+		// continue in the object of the last returned event
+		events, index, err := queryIndexedObject(token.S3ObjectKey, alertID, token.EventIndex, maxResults)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		result = append(result, events...)
+		resultToken = &LogTypeToken{S3ObjectKey: token.S3ObjectKey, EventIndex: index}
+		startAfter = &token.S3ObjectKey
+	}
+	if len(result) >= maxResults {
+		return result, resultToken, true, nil
+	}
+
+	// Each object has at least one event of the alert, no more objects are needed to fill the page
+	objects, err := alertsDB.ListAlertEventObjects(alertID, startAfter, maxResults-len(result))
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if token == nil && len(objects) == 0 {
+		return nil, nil, false, nil
+	}
+
+	for _, object := range objects {
+		events, index, err := queryIndexedObject(object.ObjectKey, alertID, 0, maxResults-len(result))
+		if err != nil {
+			return nil, nil, false, err
+		}
+		result = append(result, events...)
+		resultToken = &LogTypeToken{S3ObjectKey: object.ObjectKey, EventIndex: index}
+		if len(result) >= maxResults {
+			break
+		}
+	}
+	return result, resultToken, true, nil
+}
+
+// queryIndexedObject queries an object of the alert events index, objects removed by the data retention have no events
+func queryIndexedObject(key, alertID string, exclusiveStartIndex, maxResults int) ([]string, int, error) {
+	events, index, err := queryS3Object(key, alertID, exclusiveStartIndex, maxResults)
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			zap.L().Warn("alert events object does not exist", zap.String("key", key))
+			return nil, exclusiveStartIndex, nil
+		}
+		return nil, 0, err
+	}
+	return events, index, nil
+}
+
 // Method required for backwards compatibility
 // In case the alert title is empty, return custom title
 func getAlertTitle(alert *table.AlertItem) *string {
@@ -19,16 +19,20 @@ package api
  */
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	deliverymodels "github.com/panther-labs/panther/internal/core/alert_delivery/models"
 	"github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
 )
 
@@ -79,19 +83,80 @@ func (m *tableMock) GetAlert(input *string) (*table.AlertItem, error) {
 	return args.Get(0).(*table.AlertItem), args.Error(1)
 }
 
-func (m *tableMock) ListByRule(rule string, startKey *string, pageSize *int) ([]*table.AlertItem, *string, error) {
-	args := m.Called(rule, startKey, pageSize)
+func (m *tableMock) ListAll(input *models.ListAlertsInput) ([]*table.AlertItem, *string, error) {
+	args := m.Called(input)
 	return args.Get(0).([]*table.AlertItem), args.Get(1).(*string), args.Error(2)
 }
 
-func (m *tableMock) ListAll(startKey *string, pageSize *int) ([]*table.AlertItem, *string, error) {
+func (m *tableMock) ListCreatedBetween(after, before time.Time) ([]*table.AlertItem, error) {
+	args := m.Called(after, before)
+	return args.Get(0).([]*table.AlertItem), args.Error(1)
+}
+
+func (m *tableMock) UpdateStatus(alertID, status, userID string, updateTime time.Time) error {
+	args := m.Called(alertID, status, userID, updateTime)
+	return args.Error(0)
+}
+
+func (m *tableMock) UpdateAssignee(alertID string, assigneeID *string, userID string, updateTime time.Time) error {
+	args := m.Called(alertID, assigneeID, userID, updateTime)
+	return args.Error(0)
+}
+
+func (m *tableMock) AddComment(alertID, comment, userID string, createdAt time.Time) (*table.ActivityItem, error) {
+	args := m.Called(alertID, comment, userID, createdAt)
+	return args.Get(0).(*table.ActivityItem), args.Error(1)
+}
+
+func (m *tableMock) ListActivity(alertID string, startKey *string, pageSize *int) ([]*table.ActivityItem, *string, error) {
+	args := m.Called(alertID, startKey, pageSize)
+	return args.Get(0).([]*table.ActivityItem), args.Get(1).(*string), args.Error(2)
+}
+
+func (m *tableMock) PutSuppression(suppression *table.SuppressionItem) error {
+	args := m.Called(suppression)
+	return args.Error(0)
+}
+
+func (m *tableMock) ListSuppressions(ruleID *string) ([]*table.SuppressionItem, error) {
+	args := m.Called(ruleID)
+	return args.Get(0).([]*table.SuppressionItem), args.Error(1)
+}
+
+func (m *tableMock) ExpireSuppression(ruleID, suppressionID, userID string, expireTime time.Time) (*table.SuppressionItem, error) {
+	args := m.Called(ruleID, suppressionID, userID, expireTime)
+	return args.Get(0).(*table.SuppressionItem), args.Error(1)
+}
+
+func (m *tableMock) GetIncident(incidentID string) (*table.IncidentItem, error) {
+	args := m.Called(incidentID)
+	return args.Get(0).(*table.IncidentItem), args.Error(1)
+}
+
+func (m *tableMock) ListIncidents(startKey *string, pageSize *int) ([]*table.IncidentItem, *string, error) {
 	args := m.Called(startKey, pageSize)
-	return args.Get(0).([]*table.AlertItem), args.Get(1).(*string), args.Error(2)
+	return args.Get(0).([]*table.IncidentItem), args.Get(1).(*string), args.Error(2)
+}
+
+func (m *tableMock) GetAlerts(alertIDs []string) ([]*table.AlertItem, error) {
+	args := m.Called(alertIDs)
+	return args.Get(0).([]*table.AlertItem), args.Error(1)
+}
+
+func (m *tableMock) ListAlertEventObjects(alertID string, startAfter *string, limit int) ([]*table.AlertEventObject, error) {
+	args := m.Called(alertID, startAfter, limit)
+	return args.Get(0).([]*table.AlertEventObject), args.Error(1)
+}
+
+func (m *tableMock) ListDeliveryAttempts(alertID string) ([]*deliverymodels.DeliveryAttempt, error) {
+	args := m.Called(alertID)
+	return args.Get(0).([]*deliverymodels.DeliveryAttempt), args.Error(1)
 }
 
 func init() {
 	env = envConfig{
 		ProcessedDataBucket: "bucket",
+		AlertingQueueURL:    "queueUrl",
 	}
 }
 
@@ -151,6 +216,16 @@ func TestGetAlert(t *testing.T) {
 	}
 
 	tableMock.On("GetAlert", aws.String("alertId")).Return(alertItem, nil)
+	// Alerts matched before the alert events index have no objects in the index
+	tableMock.On("ListAlertEventObjects", "alertId", (*string)(nil), 5).Return([]*table.AlertEventObject{}, nil)
+	tableMock.On("ListDeliveryAttempts", "alertId").Return([]*deliverymodels.DeliveryAttempt{{
+		AlertID:     "alertId",
+		OutputID:    "outputId",
+		DeliveredAt: time.Date(2020, 1, 1, 1, 0, 5, 0, time.UTC),
+		StatusCode:  503,
+		Error:       "request failed: 503 Service Unavailable",
+		NeedsRetry:  true,
+	}}, nil)
 	s3Mock.On("ListObjectsV2Pages", expectedListObjectsRequest, mock.Anything).Return(nil)
 	s3Mock.On("SelectObjectContent", expectedSelectObjectInput).Return(selectObjectOutput, nil)
 	mockS3EventReader.On("Events").Return(eventChannel)
@@ -169,8 +244,18 @@ func TestGetAlert(t *testing.T) {
 			CreationTime:  aws.Time(time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC)),
 			UpdateTime:    aws.Time(time.Date(2020, 1, 1, 1, 59, 0, 0, time.UTC)),
 			EventsMatched: aws.Int(5),
+			Status:        aws.String("OPEN"),
 		},
 		Events: aws.StringSlice([]string{"testEvent"}),
+		DeliveryResponses: []*models.DeliveryResponse{{
+			OutputID:    aws.String("outputId"),
+			DeliveredAt: aws.Time(time.Date(2020, 1, 1, 1, 0, 5, 0, time.UTC)),
+			Success:     aws.Bool(false),
+			StatusCode:  aws.Int(503),
+			Error:       aws.String("request failed: 503 Service Unavailable"),
+			NeedsRetry:  aws.Bool(true),
+			RetryCount:  aws.Int(0),
+		}},
 		EventsLastEvaluatedKey:
 		// nolint
 		aws.String("eyJsb2dUeXBlVG9Ub2tlbiI6eyJsb2d0eXBlIjp7InMzT2JqZWN0S2V5IjoicnVsZXMvbG9ndHlwZS95ZWFyPTIwMjAvbW9udGg9MDEvZGF5PTAxL2hvdXI9MDEvMjAyMDAxMDFUMDEwMTAwWi11dWlkNC5qc29uLmd6IiwiZXZlbnRJbmRleCI6MX19fQ=="),
@@ -223,6 +308,9 @@ func TestGetAlertFilterOutS3KeysOutsideTheTimePeriod(t *testing.T) {
 	}
 
 	tableMock.On("GetAlert", aws.String("alertId")).Return(alertItem, nil)
+	// Alerts matched before the alert events index have no objects in the index
+	tableMock.On("ListAlertEventObjects", "alertId", (*string)(nil), 5).Return([]*table.AlertEventObject{}, nil)
+	tableMock.On("ListDeliveryAttempts", "alertId").Return([]*deliverymodels.DeliveryAttempt{}, nil)
 	s3Mock.On("ListObjectsV2Pages", mock.Anything, mock.Anything).Return(nil)
 	s3Mock.On("SelectObjectContent", mock.Anything).Return(selectObjectOutput, nil)
 	mockS3EventReader.On("Events").Return(eventChannel)
@@ -239,16 +327,102 @@ func TestGetAlertFilterOutS3KeysOutsideTheTimePeriod(t *testing.T) {
 			CreationTime:  aws.Time(time.Date(2020, 1, 1, 1, 5, 0, 0, time.UTC)),
 			UpdateTime:    aws.Time(time.Date(2020, 1, 1, 1, 6, 0, 0, time.UTC)),
 			EventsMatched: aws.Int(5),
+			Status:        aws.String("OPEN"),
 			Severity:      aws.String("INFO"),
 			DedupString:   aws.String("dedupString"),
 		},
-		Events: aws.StringSlice([]string{"testEvent"}),
+		Events:            aws.StringSlice([]string{"testEvent"}),
+		DeliveryResponses: []*models.DeliveryResponse{},
 		EventsLastEvaluatedKey:
 		// nolint
 		aws.String("eyJsb2dUeXBlVG9Ub2tlbiI6eyJsb2d0eXBlIjp7InMzT2JqZWN0S2V5IjoicnVsZXMvbG9ndHlwZS95ZWFyPTIwMjAvbW9udGg9MDEvZGF5PTAxL2hvdXI9MDEvMjAyMDAxMDFUMDEwNTAwWi11dWlkNC5qc29uLmd6IiwiZXZlbnRJbmRleCI6MX19fQ=="),
 	}, result)
 }
 
+func TestGetAlertIndexedEvents(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+	s3Mock := &s3Mock{}
+	s3Client = s3Mock
+
+	alertItem := &table.AlertItem{
+		AlertID:      "alertId",
+		RuleID:       "ruleId",
+		CreationTime: time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC),
+		UpdateTime:   time.Date(2020, 1, 3, 1, 0, 0, 0, time.UTC),
+		Severity:     "INFO",
+		EventCount:   3,
+		LogTypes:     []string{"logtype"},
+	}
+	objects := []*table.AlertEventObject{
+		{AlertID: "alertId", ObjectKey: "rules/logtype/object1.json.gz", LogType: "logtype", EventCount: 1},
+		{AlertID: "alertId", ObjectKey: "rules/logtype/object2.json.gz", LogType: "logtype", EventCount: 2},
+	}
+	tableMock.On("GetAlert", aws.String("alertId")).Return(alertItem, nil)
+	tableMock.On("ListAlertEventObjects", "alertId", (*string)(nil), 2).Return(objects, nil).Once()
+	tableMock.On("ListDeliveryAttempts", "alertId").Return([]*deliverymodels.DeliveryAttempt{}, nil)
+	for i, object := range objects {
+		key := object.ObjectKey
+		reader := &s3SelectStreamReaderMock{}
+		reader.On("Events").Return(getChannel(fmt.Sprintf("event%d\n", i+1)))
+		reader.On("Err").Return(nil)
+		s3Mock.On("SelectObjectContent", mock.MatchedBy(func(input *s3.SelectObjectContentInput) bool {
+			return *input.Key == key
+		})).Return(&s3.SelectObjectContentOutput{EventStream: &s3.SelectObjectContentEventStream{Reader: reader}}, nil).Once()
+	}
+
+	// The objects of the index are read directly, the partitions of the alert are not listed
+	result, err := API{}.GetAlert(&models.GetAlertInput{AlertID: aws.String("alertId"), EventsPageSize: aws.Int(2)})
+	require.NoError(t, err)
+	assert.Equal(t, aws.StringSlice([]string{"event1", "event2"}), result.Events)
+	token, err := decodePaginationToken(*result.EventsLastEvaluatedKey)
+	require.NoError(t, err)
+	assert.Equal(t, &EventPaginationToken{Indexed: &LogTypeToken{S3ObjectKey: objects[1].ObjectKey, EventIndex: 1}}, token)
+	tableMock.AssertExpectations(t)
+	s3Mock.AssertExpectations(t)
+}
+
+func TestGetAlertIndexedEventsNextPage(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+	s3Mock := &s3Mock{}
+	s3Client = s3Mock
+
+	alertItem := &table.AlertItem{AlertID: "alertId", RuleID: "ruleId", LogTypes: []string{"logtype"}}
+	tableMock.On("GetAlert", aws.String("alertId")).Return(alertItem, nil)
+	tableMock.On("ListAlertEventObjects", "alertId", aws.String("rules/logtype/object2.json.gz"), 1).
+		Return([]*table.AlertEventObject{{AlertID: "alertId", ObjectKey: "rules/logtype/object3.json.gz"}}, nil).Once()
+	tableMock.On("ListDeliveryAttempts", "alertId").Return([]*deliverymodels.DeliveryAttempt{}, nil)
+
+	reader := &s3SelectStreamReaderMock{}
+	reader.On("Events").Return(getChannel("event2\nevent3\n"))
+	reader.On("Err").Return(nil)
+	s3Mock.On("SelectObjectContent", mock.MatchedBy(func(input *s3.SelectObjectContentInput) bool {
+		return *input.Key == "rules/logtype/object2.json.gz"
+	})).Return(&s3.SelectObjectContentOutput{EventStream: &s3.SelectObjectContentEventStream{Reader: reader}}, nil).Once()
+	// The object was removed by the data retention
+	s3Mock.On("SelectObjectContent", mock.MatchedBy(func(input *s3.SelectObjectContentInput) bool {
+		return *input.Key == "rules/logtype/object3.json.gz"
+	})).Return((*s3.SelectObjectContentOutput)(nil), awserr.New(s3.ErrCodeNoSuchKey, "no such key", nil)).Once()
+
+	startToken, err := (&EventPaginationToken{
+		Indexed: &LogTypeToken{S3ObjectKey: "rules/logtype/object2.json.gz", EventIndex: 1},
+	}).encode()
+	require.NoError(t, err)
+	result, err := API{}.GetAlert(&models.GetAlertInput{
+		AlertID:                 aws.String("alertId"),
+		EventsPageSize:          aws.Int(2),
+		EventsExclusiveStartKey: aws.String(startToken),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, aws.StringSlice([]string{"event3"}), result.Events)
+	token, err := decodePaginationToken(*result.EventsLastEvaluatedKey)
+	require.NoError(t, err)
+	assert.Equal(t, &EventPaginationToken{Indexed: &LogTypeToken{S3ObjectKey: "rules/logtype/object3.json.gz"}}, token)
+	tableMock.AssertExpectations(t)
+	s3Mock.AssertExpectations(t)
+}
+
 // Returns an channel that emulated S3 Select channel
 func getChannel(events ...string) <-chan s3.SelectObjectContentEventStreamEvent {
 	channel := make(chan s3.SelectObjectContentEventStreamEvent, len(events))
@@ -0,0 +1,103 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	"github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
+	"github.com/panther-labs/panther/pkg/gatewayapi"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+// GetIncident retrieves an incident with the summaries of its alerts
+func (API) GetIncident(input *models.GetIncidentInput) (result *models.GetIncidentOutput, err error) {
+	operation := common.OpLogManager.Start("getIncident")
+	defer func() {
+		operation.Stop()
+		operation.Log(err, zap.String("incidentId", *input.IncidentID))
+	}()
+
+	item, err := alertsDB.GetIncident(*input.IncidentID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, &genericapi.DoesNotExistError{Message: "incident " + *input.IncidentID + " does not exist"}
+	}
+
+	alertItems, err := alertsDB.GetAlerts(item.AlertIDs)
+	if err != nil {
+		return nil, err
+	}
+	// The alerts in the order they were created
+	sort.Slice(alertItems, func(i, j int) bool {
+		return alertItems[i].CreationTime.Before(alertItems[j].CreationTime)
+	})
+
+	result = &models.Incident{
+		IncidentSummary: *incidentItemToIncidentSummary(item),
+		Alerts:          alertItemsToAlertSummary(alertItems),
+	}
+	gatewayapi.ReplaceMapSliceNils(result)
+	return result, nil
+}
+
+// ListIncidents retrieves a page of incidents, newest first
+func (API) ListIncidents(input *models.ListIncidentsInput) (result *models.ListIncidentsOutput, err error) {
+	operation := common.OpLogManager.Start("listIncidents")
+	defer func() {
+		operation.Stop()
+		operation.Log(err)
+	}()
+
+	items, lastEvaluatedKey, err := alertsDB.ListIncidents(input.ExclusiveStartKey, input.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result = &models.ListIncidentsOutput{
+		Incidents:        make([]*models.IncidentSummary, len(items)),
+		LastEvaluatedKey: lastEvaluatedKey,
+	}
+	for i, item := range items {
+		result.Incidents[i] = incidentItemToIncidentSummary(item)
+	}
+	gatewayapi.ReplaceMapSliceNils(result)
+	return result, nil
+}
+
+func incidentItemToIncidentSummary(item *table.IncidentItem) *models.IncidentSummary {
+	return &models.IncidentSummary{
+		IncidentID:     &item.IncidentID,
+		CreationTime:   &item.CreationTime,
+		UpdateTime:     &item.UpdateTime,
+		Severity:       &item.Severity,
+		AlertIDs:       aws.StringSlice(item.AlertIDs),
+		RuleIDs:        aws.StringSlice(item.RuleIDs),
+		IPAddresses:    aws.StringSlice(item.IPAddresses),
+		AWSAccountIDs:  aws.StringSlice(item.AWSAccountIDs),
+		AWSInstanceIDs: aws.StringSlice(item.AWSInstanceIDs),
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	"github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+const incidentID = "0f6e6c1f8a0d2d7b1c6ad0a3e5b7f7a4"
+
+var testIncident = &table.IncidentItem{
+	IncidentID:   incidentID,
+	CreationTime: time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+	UpdateTime:   time.Date(2020, 5, 1, 10, 5, 0, 0, time.UTC),
+	Severity:     "HIGH",
+	AlertIDs:     []string{"alert1", "alert2"},
+	RuleIDs:      []string{"rule1", "rule2"},
+	IPAddresses:  []string{"1.2.3.4"},
+}
+
+func TestGetIncident(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	alerts := []*table.AlertItem{
+		{AlertID: "alert2", RuleID: "rule2", Severity: "HIGH", CreationTime: testIncident.UpdateTime},
+		{AlertID: "alert1", RuleID: "rule1", Severity: "LOW", CreationTime: testIncident.CreationTime},
+	}
+	tableMock.On("GetIncident", incidentID).Return(testIncident, nil).Once()
+	tableMock.On("GetAlerts", []string{"alert1", "alert2"}).Return(alerts, nil).Once()
+
+	result, err := API{}.GetIncident(&models.GetIncidentInput{IncidentID: aws.String(incidentID)})
+	require.NoError(t, err)
+	tableMock.AssertExpectations(t)
+
+	assert.Equal(t, incidentID, *result.IncidentID)
+	assert.Equal(t, "HIGH", *result.Severity)
+	assert.Equal(t, aws.StringSlice([]string{"1.2.3.4"}), result.IPAddresses)
+	assert.Equal(t, []*string{}, result.AWSAccountIDs)
+	// Alerts are sorted by creation time
+	require.Len(t, result.Alerts, 2)
+	assert.Equal(t, "alert1", *result.Alerts[0].AlertID)
+	assert.Equal(t, "alert2", *result.Alerts[1].AlertID)
+}
+
+func TestGetIncidentDoesNotExist(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+	tableMock.On("GetIncident", incidentID).Return((*table.IncidentItem)(nil), nil).Once()
+
+	result, err := API{}.GetIncident(&models.GetIncidentInput{IncidentID: aws.String(incidentID)})
+	assert.Nil(t, result)
+	assert.IsType(t, &genericapi.DoesNotExistError{}, err)
+	tableMock.AssertExpectations(t)
+}
+
+func TestListIncidents(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+	tableMock.On("ListIncidents", aws.String("startKey"), aws.Int(10)).
+		Return([]*table.IncidentItem{testIncident}, aws.String("lastKey"), nil).Once()
+
+	result, err := API{}.ListIncidents(&models.ListIncidentsInput{
+		ExclusiveStartKey: aws.String("startKey"),
+		PageSize:          aws.Int(10),
+	})
+	require.NoError(t, err)
+	tableMock.AssertExpectations(t)
+
+	require.Len(t, result.Incidents, 1)
+	assert.Equal(t, incidentID, *result.Incidents[0].IncidentID)
+	assert.Equal(t, aws.StringSlice([]string{"rule1", "rule2"}), result.Incidents[0].RuleIDs)
+	assert.Equal(t, "lastKey", *result.LastEvaluatedKey)
+}
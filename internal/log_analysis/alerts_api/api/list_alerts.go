@@ -25,7 +25,7 @@ import (
 	"github.com/panther-labs/panther/pkg/gatewayapi"
 )
 
-// ListAlerts retrieves alert and event details.
+// ListAlerts retrieves the alerts matching the filters of the input.
 func (API) ListAlerts(input *models.ListAlertsInput) (result *models.ListAlertsOutput, err error) {
 	operation := common.OpLogManager.Start("listAlerts")
 	defer func() {
@@ -34,17 +34,13 @@ func (API) ListAlerts(input *models.ListAlertsInput) (result *models.ListAlertsO
 	}()
 
 	result = &models.ListAlertsOutput{}
-	var alertItems []*table.AlertItem
-	if input.RuleID != nil { // list per specific ruleId
-		alertItems, result.LastEvaluatedKey, err = alertsDB.ListByRule(*input.RuleID, input.ExclusiveStartKey, input.PageSize)
-	} else { // list all alerts time desc order
-		alertItems, result.LastEvaluatedKey, err = alertsDB.ListAll(input.ExclusiveStartKey, input.PageSize)
-	}
+	alertItems, lastEvaluatedKey, err := alertsDB.ListAll(input)
 	if err != nil {
 		return nil, err
 	}
 
 	result.Alerts = alertItemsToAlertSummary(alertItems)
+	result.LastEvaluatedKey = lastEvaluatedKey
 
 	gatewayapi.ReplaceMapSliceNils(result)
 	return result, nil
@@ -55,19 +51,33 @@ func alertItemsToAlertSummary(items []*table.AlertItem) []*models.AlertSummary {
 	result := make([]*models.AlertSummary, len(items))
 
 	for i, item := range items {
-		result[i] = &models.AlertSummary{
-			AlertID:         &item.AlertID,
-			RuleID:          &item.RuleID,
-			DedupString:     &item.DedupString,
-			CreationTime:    &item.CreationTime,
-			Severity:        &item.Severity,
-			UpdateTime:      &item.UpdateTime,
-			EventsMatched:   &item.EventCount,
-			RuleDisplayName: item.RuleDisplayName,
-			Title:           getAlertTitle(item),
-			RuleVersion:     &item.RuleVersion,
-		}
+		result[i] = alertItemToAlertSummary(item)
 	}
 
 	return result
 }
+
+func alertItemToAlertSummary(item *table.AlertItem) *models.AlertSummary {
+	status := item.Status
+	if status == "" { // alerts created before triage
+		status = models.StatusOpen
+	}
+	return &models.AlertSummary{
+		AlertID:           &item.AlertID,
+		RuleID:            &item.RuleID,
+		DedupString:       &item.DedupString,
+		CreationTime:      &item.CreationTime,
+		Severity:          &item.Severity,
+		UpdateTime:        &item.UpdateTime,
+		EventsMatched:     &item.EventCount,
+		RuleDisplayName:   item.RuleDisplayName,
+		Title:             getAlertTitle(item),
+		RuleVersion:       &item.RuleVersion,
+		Status:            &status,
+		AssigneeID:        item.AssigneeID,
+		SuppressionID:     item.SuppressionID,
+		IncidentID:        item.IncidentID,
+		LastUpdatedBy:     item.LastUpdatedBy,
+		LastUpdatedByTime: item.LastUpdatedByTime,
+	}
+}
@@ -60,6 +60,7 @@ var (
 			Severity:        aws.String("INFO"),
 			DedupString:     aws.String("dedupString"),
 			EventsMatched:   aws.Int(100),
+			Status:          aws.String("OPEN"),
 			Title:           aws.String("title"),
 		},
 	}
@@ -75,7 +76,7 @@ func TestListAlertsForRule(t *testing.T) {
 		ExclusiveStartKey: aws.String("startKey"),
 	}
 
-	tableMock.On("ListByRule", "ruleId", aws.String("startKey"), aws.Int(10)).
+	tableMock.On("ListAll", input).
 		Return(alertItems, aws.String("lastKey"), nil)
 	result, err := API{}.ListAlerts(input)
 	require.NoError(t, err)
@@ -95,7 +96,7 @@ func TestListAllAlerts(t *testing.T) {
 		ExclusiveStartKey: aws.String("startKey"),
 	}
 
-	tableMock.On("ListAll", aws.String("startKey"), aws.Int(10)).
+	tableMock.On("ListAll", input).
 		Return(alertItems, aws.String("lastKey"), nil)
 	result, err := API{}.ListAlerts(input)
 	require.NoError(t, err)
@@ -148,6 +149,7 @@ func TestListAllAlertsWithoutTitle(t *testing.T) {
 			Severity:      aws.String("INFO"),
 			DedupString:   aws.String("dedupString"),
 			EventsMatched: aws.Int(100),
+			Status:        aws.String("OPEN"),
 			Title:         aws.String("ruleId"),
 		},
 		{
@@ -159,6 +161,7 @@ func TestListAllAlertsWithoutTitle(t *testing.T) {
 			Severity:        aws.String("INFO"),
 			DedupString:     aws.String("dedupString"),
 			EventsMatched:   aws.Int(100),
+			Status:          aws.String("OPEN"),
 			RuleDisplayName: aws.String("ruleDisplayName"),
 			// Since there is no dynamically generated title,
 			// we return the display name
@@ -171,7 +174,7 @@ func TestListAllAlertsWithoutTitle(t *testing.T) {
 		ExclusiveStartKey: aws.String("startKey"),
 	}
 
-	tableMock.On("ListAll", aws.String("startKey"), aws.Int(10)).
+	tableMock.On("ListAll", input).
 		Return(alertItems, aws.String("lastKey"), nil)
 	result, err := API{}.ListAlerts(input)
 	require.NoError(t, err)
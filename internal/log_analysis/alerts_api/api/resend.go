@@ -0,0 +1,95 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	deliverymodels "github.com/panther-labs/panther/internal/core/alert_delivery/models"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+// ResendAlert queues an alert for delivery to the given outputs
+func (API) ResendAlert(input *models.ResendAlertInput) (result *models.ResendAlertOutput, err error) {
+	operation := common.OpLogManager.Start("resendAlert")
+	defer func() {
+		operation.Stop()
+		operation.Log(err, zap.String("alertId", *input.AlertID), zap.String("userId", *input.UserID),
+			zap.Strings("outputIds", aws.StringValueSlice(input.OutputIDs)))
+	}()
+
+	alertItem, err := alertsDB.GetAlert(input.AlertID)
+	if err != nil {
+		return nil, err
+	}
+	if alertItem.AlertID == "" {
+		return nil, &genericapi.DoesNotExistError{Message: "alert " + *input.AlertID + " does not exist"}
+	}
+
+	// Alerts created before the max retry duration of the alert delivery are sent once, without retries
+	alert := &deliverymodels.Alert{
+		CreatedAt:       aws.Time(alertItem.CreationTime),
+		OutputIDs:       input.OutputIDs,
+		PolicyID:        aws.String(alertItem.RuleID),
+		PolicyVersionID: aws.String(alertItem.RuleVersion),
+		PolicyName:      alertItem.RuleDisplayName,
+		Severity:        aws.String(alertItem.Severity),
+		Type:            aws.String(deliverymodels.RuleType),
+		AlertID:         aws.String(alertItem.AlertID),
+		Title:           getAlertTitle(alertItem),
+		DedupString:     aws.String(alertItem.DedupString),
+		LogTypes:        aws.StringSlice(alertItem.LogTypes),
+		EventSamples:    aws.StringSlice(alertItem.EventSamples),
+	}
+	body, err := jsoniter.MarshalToString(alert)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal alert")
+	}
+	_, err = sqsClient.SendMessage(&sqs.SendMessageInput{
+		QueueUrl:    aws.String(env.AlertingQueueURL),
+		MessageBody: aws.String(body),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to queue alert for delivery")
+	}
+	return alertItemToAlertSummary(alertItem), nil
+}
+
+func deliveryAttemptToDeliveryResponse(attempt *deliverymodels.DeliveryAttempt) *models.DeliveryResponse {
+	result := &models.DeliveryResponse{
+		OutputID:    aws.String(attempt.OutputID),
+		DeliveredAt: aws.Time(attempt.DeliveredAt),
+		Success:     aws.Bool(attempt.Success),
+		NeedsRetry:  aws.Bool(attempt.NeedsRetry),
+		RetryCount:  aws.Int(attempt.RetryCount),
+	}
+	if attempt.StatusCode != 0 {
+		result.StatusCode = aws.Int(attempt.StatusCode)
+	}
+	if attempt.Error != "" {
+		result.Error = aws.String(attempt.Error)
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	deliverymodels "github.com/panther-labs/panther/internal/core/alert_delivery/models"
+	"github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/pkg/genericapi"
+	"github.com/panther-labs/panther/pkg/testutils"
+)
+
+var resendInput = &models.ResendAlertInput{
+	AlertID:   aws.String("alertId"),
+	OutputIDs: aws.StringSlice([]string{"outputId"}),
+	UserID:    aws.String("userId"),
+}
+
+func TestResendAlert(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+	sqsMock := &testutils.SqsMock{}
+	sqsClient = sqsMock
+
+	alertItem := &table.AlertItem{
+		AlertID:      "alertId",
+		RuleID:       "ruleId",
+		RuleVersion:  "ruleVersion",
+		CreationTime: time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC),
+		Severity:     "HIGH",
+		DedupString:  "dedup",
+		LogTypes:     []string{"AWS.CloudTrail"},
+		EventSamples: []string{`{"eventName":"ConsoleLogin"}`},
+	}
+	tableMock.On("GetAlert", aws.String("alertId")).Return(alertItem, nil).Once()
+	sqsMock.On("SendMessage", mock.Anything).Return(&sqs.SendMessageOutput{}, nil).Once()
+
+	result, err := API{}.ResendAlert(resendInput)
+	require.NoError(t, err)
+	assert.Equal(t, "alertId", *result.AlertID)
+	tableMock.AssertExpectations(t)
+	sqsMock.AssertExpectations(t)
+
+	input := sqsMock.Calls[0].Arguments.Get(0).(*sqs.SendMessageInput)
+	assert.Equal(t, "queueUrl", *input.QueueUrl)
+	var alert deliverymodels.Alert
+	require.NoError(t, jsoniter.UnmarshalFromString(*input.MessageBody, &alert))
+	assert.Equal(t, &deliverymodels.Alert{
+		CreatedAt:       aws.Time(alertItem.CreationTime),
+		OutputIDs:       aws.StringSlice([]string{"outputId"}),
+		PolicyID:        aws.String("ruleId"),
+		PolicyVersionID: aws.String("ruleVersion"),
+		Severity:        aws.String("HIGH"),
+		Type:            aws.String(deliverymodels.RuleType),
+		AlertID:         aws.String("alertId"),
+		Title:           aws.String("ruleId"),
+		DedupString:     aws.String("dedup"),
+		LogTypes:        aws.StringSlice([]string{"AWS.CloudTrail"}),
+		EventSamples:    aws.StringSlice([]string{`{"eventName":"ConsoleLogin"}`}),
+	}, &alert)
+}
+
+func TestResendAlertDoesNotExist(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+	sqsMock := &testutils.SqsMock{}
+	sqsClient = sqsMock
+	tableMock.On("GetAlert", aws.String("alertId")).Return(&table.AlertItem{}, nil).Once()
+
+	result, err := API{}.ResendAlert(resendInput)
+	assert.Nil(t, result)
+	assert.IsType(t, &genericapi.DoesNotExistError{}, err)
+	tableMock.AssertExpectations(t)
+	sqsMock.AssertExpectations(t)
+}
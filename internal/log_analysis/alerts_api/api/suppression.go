@@ -0,0 +1,126 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	"github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
+	"github.com/panther-labs/panther/pkg/gatewayapi"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+// CreateSuppression snoozes the alerts of a rule, or of a single dedup string of the rule
+func (API) CreateSuppression(input *models.CreateSuppressionInput) (result *models.CreateSuppressionOutput, err error) {
+	operation := common.OpLogManager.Start("createSuppression")
+	defer func() {
+		operation.Stop()
+		operation.Log(err, zap.String("ruleId", *input.RuleID), zap.String("userId", *input.UserID))
+	}()
+
+	if input.ExpiresAt != nil && input.DurationMinutes != nil {
+		return nil, &genericapi.InvalidInputError{Message: "only one of expiresAt and durationMinutes can be set"}
+	}
+
+	now := time.Now().UTC()
+	item := &table.SuppressionItem{
+		RuleID:        *input.RuleID,
+		SuppressionID: uuid.New().String(),
+		DedupString:   input.DedupString,
+		Reason:        *input.Reason,
+		CreatedBy:     *input.UserID,
+		CreatedAt:     now,
+	}
+	if input.ExpiresAt != nil {
+		item.ExpiresAt = input.ExpiresAt.UTC()
+	} else {
+		item.ExpiresAt = now.Add(time.Duration(*input.DurationMinutes) * time.Minute)
+	}
+	if !item.Active(now) {
+		return nil, &genericapi.InvalidInputError{Message: "expiresAt must be in the future"}
+	}
+
+	if err = alertsDB.PutSuppression(item); err != nil {
+		return nil, err
+	}
+	return suppressionItemToSuppression(item), nil
+}
+
+// ListSuppressions returns the active suppressions, soonest to expire first
+func (API) ListSuppressions(input *models.ListSuppressionsInput) (result *models.ListSuppressionsOutput, err error) {
+	operation := common.OpLogManager.Start("listSuppressions")
+	defer func() {
+		operation.Stop()
+		operation.Log(err, zap.String("ruleId", aws.StringValue(input.RuleID)))
+	}()
+
+	items, err := alertsDB.ListSuppressions(input.RuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	result = &models.ListSuppressionsOutput{}
+	for _, item := range items {
+		if aws.BoolValue(input.IncludeExpired) || item.Active(now) {
+			result.Suppressions = append(result.Suppressions, suppressionItemToSuppression(item))
+		}
+	}
+	sort.Slice(result.Suppressions, func(i, j int) bool {
+		return result.Suppressions[i].ExpiresAt.Before(*result.Suppressions[j].ExpiresAt)
+	})
+	gatewayapi.ReplaceMapSliceNils(result)
+	return result, nil
+}
+
+// ExpireSuppression ends an active suppression now
+func (API) ExpireSuppression(input *models.ExpireSuppressionInput) (result *models.ExpireSuppressionOutput, err error) {
+	operation := common.OpLogManager.Start("expireSuppression")
+	defer func() {
+		operation.Stop()
+		operation.Log(err, zap.String("ruleId", *input.RuleID), zap.String("suppressionId", *input.SuppressionID),
+			zap.String("userId", *input.UserID))
+	}()
+
+	item, err := alertsDB.ExpireSuppression(*input.RuleID, *input.SuppressionID, *input.UserID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return suppressionItemToSuppression(item), nil
+}
+
+func suppressionItemToSuppression(item *table.SuppressionItem) *models.Suppression {
+	return &models.Suppression{
+		SuppressionID: &item.SuppressionID,
+		RuleID:        &item.RuleID,
+		DedupString:   item.DedupString,
+		Reason:        &item.Reason,
+		CreatedBy:     &item.CreatedBy,
+		CreatedAt:     &item.CreatedAt,
+		ExpiresAt:     &item.ExpiresAt,
+		ExpiredBy:     item.ExpiredBy,
+	}
+}
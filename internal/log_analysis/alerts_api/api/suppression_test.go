@@ -0,0 +1,140 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	"github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+const suppressionID = "0b0d4b53-8a8e-4c4a-9a3a-58ad1a6f5e4c"
+
+func TestCreateSuppressionDuration(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+	tableMock.On("PutSuppression", mock.Anything).Return(nil)
+
+	start := time.Now().UTC()
+	result, err := API{}.CreateSuppression(&models.CreateSuppressionInput{
+		RuleID:          aws.String("ruleId"),
+		DedupString:     aws.String("admin"),
+		DurationMinutes: aws.Int(30),
+		Reason:          aws.String("change window"),
+		UserID:          aws.String(triageUserID),
+	})
+	require.NoError(t, err)
+	tableMock.AssertExpectations(t)
+
+	item := tableMock.Calls[0].Arguments.Get(0).(*table.SuppressionItem)
+	assert.Equal(t, "ruleId", item.RuleID)
+	assert.Equal(t, "admin", *item.DedupString)
+	assert.Equal(t, triageUserID, item.CreatedBy)
+	assert.Equal(t, item.CreatedAt.Add(30*time.Minute), item.ExpiresAt)
+	assert.False(t, item.CreatedAt.Before(start))
+	assert.Equal(t, item.SuppressionID, *result.SuppressionID)
+	assert.Equal(t, "change window", *result.Reason)
+}
+
+func TestCreateSuppressionExpired(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	result, err := API{}.CreateSuppression(&models.CreateSuppressionInput{
+		RuleID:    aws.String("ruleId"),
+		ExpiresAt: aws.Time(time.Now().Add(-time.Hour)),
+		Reason:    aws.String("change window"),
+		UserID:    aws.String(triageUserID),
+	})
+	assert.Nil(t, result)
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+	tableMock.AssertExpectations(t)
+}
+
+func TestCreateSuppressionExpiresAtAndDuration(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	result, err := API{}.CreateSuppression(&models.CreateSuppressionInput{
+		RuleID:          aws.String("ruleId"),
+		ExpiresAt:       aws.Time(time.Now().Add(time.Hour)),
+		DurationMinutes: aws.Int(30),
+		Reason:          aws.String("change window"),
+		UserID:          aws.String(triageUserID),
+	})
+	assert.Nil(t, result)
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+	tableMock.AssertExpectations(t)
+}
+
+func TestListSuppressions(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	now := time.Now().UTC()
+	later := &table.SuppressionItem{SuppressionID: "later", ExpiresAt: now.Add(2 * time.Hour)}
+	sooner := &table.SuppressionItem{SuppressionID: "sooner", ExpiresAt: now.Add(time.Hour)}
+	expired := &table.SuppressionItem{SuppressionID: "expired", ExpiresAt: now.Add(-time.Hour)}
+	tableMock.On("ListSuppressions", aws.String("ruleId")).Return([]*table.SuppressionItem{later, expired, sooner}, nil)
+
+	result, err := API{}.ListSuppressions(&models.ListSuppressionsInput{RuleID: aws.String("ruleId")})
+	require.NoError(t, err)
+	require.Len(t, result.Suppressions, 2)
+	assert.Equal(t, "sooner", *result.Suppressions[0].SuppressionID)
+	assert.Equal(t, "later", *result.Suppressions[1].SuppressionID)
+
+	result, err = API{}.ListSuppressions(&models.ListSuppressionsInput{
+		RuleID:         aws.String("ruleId"),
+		IncludeExpired: aws.Bool(true),
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Suppressions, 3)
+	assert.Equal(t, "expired", *result.Suppressions[0].SuppressionID)
+}
+
+func TestExpireSuppression(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	expired := &table.SuppressionItem{
+		RuleID:        "ruleId",
+		SuppressionID: suppressionID,
+		ExpiresAt:     timeInTest,
+		ExpiredBy:     aws.String(triageUserID),
+	}
+	tableMock.On("ExpireSuppression", "ruleId", suppressionID, triageUserID, mock.Anything).Return(expired, nil)
+
+	result, err := API{}.ExpireSuppression(&models.ExpireSuppressionInput{
+		RuleID:        aws.String("ruleId"),
+		SuppressionID: aws.String(suppressionID),
+		UserID:        aws.String(triageUserID),
+	})
+	require.NoError(t, err)
+	tableMock.AssertExpectations(t)
+	assert.Equal(t, triageUserID, *result.ExpiredBy)
+	assert.Equal(t, timeInTest, *result.ExpiresAt)
+}
@@ -0,0 +1,119 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	"github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
+	"github.com/panther-labs/panther/pkg/gatewayapi"
+)
+
+// UpdateAlertStatus sets the triage status of an alert
+func (API) UpdateAlertStatus(input *models.UpdateAlertStatusInput) (result *models.UpdateAlertStatusOutput, err error) {
+	operation := common.OpLogManager.Start("updateAlertStatus")
+	defer func() {
+		operation.Stop()
+		operation.Log(err, zap.String("alertId", *input.AlertID), zap.String("userId", *input.UserID),
+			zap.String("status", *input.Status))
+	}()
+
+	if err = alertsDB.UpdateStatus(*input.AlertID, *input.Status, *input.UserID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return getAlertSummary(input.AlertID)
+}
+
+// AssignAlert assigns an alert to a user, or unassigns it
+func (API) AssignAlert(input *models.AssignAlertInput) (result *models.AssignAlertOutput, err error) {
+	operation := common.OpLogManager.Start("assignAlert")
+	defer func() {
+		operation.Stop()
+		operation.Log(err, zap.String("alertId", *input.AlertID), zap.String("userId", *input.UserID),
+			zap.String("assigneeId", aws.StringValue(input.AssigneeID)))
+	}()
+
+	if err = alertsDB.UpdateAssignee(*input.AlertID, input.AssigneeID, *input.UserID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return getAlertSummary(input.AlertID)
+}
+
+// AddAlertComment adds a comment to the activity of an alert
+func (API) AddAlertComment(input *models.AddAlertCommentInput) (result *models.AddAlertCommentOutput, err error) {
+	operation := common.OpLogManager.Start("addAlertComment")
+	defer func() {
+		operation.Stop()
+		operation.Log(err, zap.String("alertId", *input.AlertID), zap.String("userId", *input.UserID))
+	}()
+
+	activity, err := alertsDB.AddComment(*input.AlertID, *input.Comment, *input.UserID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return activityItemToAlertActivity(activity), nil
+}
+
+// ListAlertActivity returns the activity of an alert, oldest first
+func (API) ListAlertActivity(input *models.ListAlertActivityInput) (result *models.ListAlertActivityOutput, err error) {
+	operation := common.OpLogManager.Start("listAlertActivity")
+	defer func() {
+		operation.Stop()
+		operation.Log(err)
+	}()
+
+	items, lastEvaluatedKey, err := alertsDB.ListActivity(*input.AlertID, input.ExclusiveStartKey, input.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	result = &models.ListAlertActivityOutput{
+		Activity:         make([]*models.AlertActivity, len(items)),
+		LastEvaluatedKey: lastEvaluatedKey,
+	}
+	for i, item := range items {
+		result.Activity[i] = activityItemToAlertActivity(item)
+	}
+	gatewayapi.ReplaceMapSliceNils(result)
+	return result, nil
+}
+
+func getAlertSummary(alertID *string) (*models.AlertSummary, error) {
+	alertItem, err := alertsDB.GetAlert(alertID)
+	if err != nil {
+		return nil, err
+	}
+	return alertItemToAlertSummary(alertItem), nil
+}
+
+func activityItemToAlertActivity(item *table.ActivityItem) *models.AlertActivity {
+	return &models.AlertActivity{
+		AlertID:    &item.AlertID,
+		Type:       &item.Type,
+		UserID:     &item.UserID,
+		CreatedAt:  &item.CreatedAt,
+		Status:     item.Status,
+		AssigneeID: item.AssigneeID,
+		Comment:    item.Comment,
+	}
+}
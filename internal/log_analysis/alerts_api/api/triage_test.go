@@ -0,0 +1,202 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	"github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+const (
+	triageAlertID = "6e9cd8a4d6f5fcbfd4e2ef0a9efdf5a2"
+	triageUserID  = "f6cfad0a-9bb0-4681-9503-02c54cc979c7"
+	assigneeID    = "3a4e5b6c-1d2e-4f3a-8b4c-5d6e7f8a9b0c"
+)
+
+func triagedAlertItem() *table.AlertItem {
+	return &table.AlertItem{
+		AlertID:           triageAlertID,
+		RuleID:            "ruleId",
+		RuleVersion:       "ruleVersion",
+		DedupString:       "dedupString",
+		CreationTime:      timeInTest,
+		UpdateTime:        timeInTest,
+		Severity:          "INFO",
+		EventCount:        1,
+		Status:            models.StatusTriaged,
+		AssigneeID:        aws.String(assigneeID),
+		LastUpdatedBy:     aws.String(triageUserID),
+		LastUpdatedByTime: aws.Time(timeInTest),
+	}
+}
+
+func TestUpdateAlertStatus(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	tableMock.On("UpdateStatus", triageAlertID, models.StatusTriaged, triageUserID, mock.Anything).Return(nil)
+	tableMock.On("GetAlert", aws.String(triageAlertID)).Return(triagedAlertItem(), nil)
+
+	result, err := API{}.UpdateAlertStatus(&models.UpdateAlertStatusInput{
+		AlertID: aws.String(triageAlertID),
+		Status:  aws.String(models.StatusTriaged),
+		UserID:  aws.String(triageUserID),
+	})
+	require.NoError(t, err)
+	tableMock.AssertExpectations(t)
+	assert.Equal(t, models.StatusTriaged, *result.Status)
+	assert.Equal(t, assigneeID, *result.AssigneeID)
+	assert.Equal(t, triageUserID, *result.LastUpdatedBy)
+}
+
+func TestUpdateAlertStatusDoesNotExist(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	notFound := &genericapi.DoesNotExistError{Message: "alertId=" + triageAlertID + " does not exist"}
+	tableMock.On("UpdateStatus", triageAlertID, models.StatusClosed, triageUserID, mock.Anything).Return(notFound)
+
+	_, err := API{}.UpdateAlertStatus(&models.UpdateAlertStatusInput{
+		AlertID: aws.String(triageAlertID),
+		Status:  aws.String(models.StatusClosed),
+		UserID:  aws.String(triageUserID),
+	})
+	assert.Equal(t, notFound, err)
+	tableMock.AssertNotCalled(t, "GetAlert", mock.Anything)
+}
+
+func TestAssignAlert(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	tableMock.On("UpdateAssignee", triageAlertID, aws.String(assigneeID), triageUserID, mock.Anything).Return(nil)
+	tableMock.On("GetAlert", aws.String(triageAlertID)).Return(triagedAlertItem(), nil)
+
+	result, err := API{}.AssignAlert(&models.AssignAlertInput{
+		AlertID:    aws.String(triageAlertID),
+		AssigneeID: aws.String(assigneeID),
+		UserID:     aws.String(triageUserID),
+	})
+	require.NoError(t, err)
+	tableMock.AssertExpectations(t)
+	assert.Equal(t, assigneeID, *result.AssigneeID)
+}
+
+func TestUnassignAlert(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	alertItem := triagedAlertItem()
+	alertItem.AssigneeID = nil
+	tableMock.On("UpdateAssignee", triageAlertID, (*string)(nil), triageUserID, mock.Anything).Return(nil)
+	tableMock.On("GetAlert", aws.String(triageAlertID)).Return(alertItem, nil)
+
+	result, err := API{}.AssignAlert(&models.AssignAlertInput{
+		AlertID: aws.String(triageAlertID),
+		UserID:  aws.String(triageUserID),
+	})
+	require.NoError(t, err)
+	tableMock.AssertExpectations(t)
+	assert.Nil(t, result.AssigneeID)
+}
+
+func TestAddAlertComment(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	createdAt := time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC)
+	tableMock.On("AddComment", triageAlertID, "expected", triageUserID, mock.Anything).Return(&table.ActivityItem{
+		AlertID:    triageAlertID,
+		ActivityID: "2020-01-01T01:00:00.000000000Z#id",
+		Type:       models.ActivityComment,
+		UserID:     triageUserID,
+		CreatedAt:  createdAt,
+		Comment:    aws.String("expected"),
+	}, nil)
+
+	result, err := API{}.AddAlertComment(&models.AddAlertCommentInput{
+		AlertID: aws.String(triageAlertID),
+		Comment: aws.String("expected"),
+		UserID:  aws.String(triageUserID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &models.AlertActivity{
+		AlertID:   aws.String(triageAlertID),
+		Type:      aws.String(models.ActivityComment),
+		UserID:    aws.String(triageUserID),
+		CreatedAt: aws.Time(createdAt),
+		Comment:   aws.String("expected"),
+	}, result)
+}
+
+func TestListAlertActivity(t *testing.T) {
+	tableMock := &tableMock{}
+	alertsDB = tableMock
+
+	createdAt := time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC)
+	tableMock.On("ListActivity", triageAlertID, aws.String("startKey"), aws.Int(10)).Return([]*table.ActivityItem{
+		{
+			AlertID:   triageAlertID,
+			Type:      models.ActivityStatus,
+			UserID:    triageUserID,
+			CreatedAt: createdAt,
+			Status:    aws.String(models.StatusClosed),
+		},
+		{
+			AlertID:   triageAlertID,
+			Type:      models.ActivityAssign,
+			UserID:    triageUserID,
+			CreatedAt: createdAt.Add(time.Minute),
+		},
+	}, aws.String("lastKey"), nil)
+
+	result, err := API{}.ListAlertActivity(&models.ListAlertActivityInput{
+		AlertID:           aws.String(triageAlertID),
+		PageSize:          aws.Int(10),
+		ExclusiveStartKey: aws.String("startKey"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &models.ListAlertActivityOutput{
+		Activity: []*models.AlertActivity{
+			{
+				AlertID:   aws.String(triageAlertID),
+				Type:      aws.String(models.ActivityStatus),
+				UserID:    aws.String(triageUserID),
+				CreatedAt: aws.Time(createdAt),
+				Status:    aws.String(models.StatusClosed),
+			},
+			{
+				AlertID:   aws.String(triageAlertID),
+				Type:      aws.String(models.ActivityAssign),
+				UserID:    aws.String(triageUserID),
+				CreatedAt: aws.Time(createdAt.Add(time.Minute)),
+			},
+		},
+		LastEvaluatedKey: aws.String("lastKey"),
+	}, result)
+}
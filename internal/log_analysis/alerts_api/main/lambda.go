@@ -35,8 +35,12 @@ func lambdaHandler(ctx context.Context, input *models.LambdaInput) (interface{},
 	lambdalogger.ConfigureGlobal(ctx, nil)
 	event, err := router.Handle(input)
 	if err != nil {
-		// wrap for api, InternalError the only kind of error from this lambda
-		err = &genericapi.InternalError{Message: err.Error()}
+		switch err.(type) {
+		case *genericapi.InvalidInputError, *genericapi.DoesNotExistError:
+			// client errors are returned as is
+		default:
+			err = &genericapi.InternalError{Message: err.Error()}
+		}
 	}
 	return event, err
 }
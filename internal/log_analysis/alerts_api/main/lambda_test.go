@@ -20,13 +20,41 @@ package main
 
 import (
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	"github.com/panther-labs/panther/pkg/genericapi"
 )
 
 // The handler signatures must match those in the LambdaInput struct.
 func TestRouter(t *testing.T) {
 	assert.Nil(t, router.VerifyHandlers(&models.LambdaInput{}))
 }
+
+func TestCreateSuppressionValidation(t *testing.T) {
+	input := func(expiresAt *time.Time, durationMinutes *int) *models.LambdaInput {
+		return &models.LambdaInput{CreateSuppression: &models.CreateSuppressionInput{
+			RuleID:          aws.String("ruleId"),
+			ExpiresAt:       expiresAt,
+			DurationMinutes: durationMinutes,
+			Reason:          aws.String("change window"),
+			UserID:          aws.String("f6cfad0a-9bb0-4681-9503-02c54cc979c7"),
+		}}
+	}
+
+	// neither expiresAt nor durationMinutes
+	_, err := router.Handle(input(nil, nil))
+	require.Error(t, err)
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+	assert.Contains(t, err.Error(), "required_without")
+
+	// both are rejected by the handler, before any table access
+	_, err = router.Handle(input(aws.Time(time.Now().Add(time.Hour)), aws.Int(30)))
+	require.Error(t, err)
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+	assert.Contains(t, err.Error(), "only one of expiresAt and durationMinutes")
+}
@@ -0,0 +1,231 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+const (
+	ActivityAlertIDKey = "alertId"
+	ActivityIDKey      = "id"
+
+	// activity ids start with the creation time to sort the activity of an alert chronologically
+	activityTimeLayout = "2006-01-02T15:04:05.000000000Z"
+)
+
+// ActivityItem is an entry of the activity log of an alert. The activity is never updated or deleted.
+type ActivityItem struct {
+	AlertID    string    `json:"alertId"`
+	ActivityID string    `json:"id"`
+	Type       string    `json:"type"`
+	UserID     string    `json:"userId"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Status     *string   `json:"status,omitempty"`
+	AssigneeID *string   `json:"assigneeId,omitempty"`
+	Comment    *string   `json:"comment,omitempty"`
+}
+
+func newActivityItem(alertID, activityType, userID string, createdAt time.Time) *ActivityItem {
+	return &ActivityItem{
+		AlertID:    alertID,
+		ActivityID: createdAt.UTC().Format(activityTimeLayout) + "#" + uuid.New().String(),
+		Type:       activityType,
+		UserID:     userID,
+		CreatedAt:  createdAt,
+	}
+}
+
+// UpdateStatus sets the status and the resolution time of an alert and adds the change to its activity
+func (table *AlertsTable) UpdateStatus(alertID, status, userID string, updateTime time.Time) error {
+	update := expression.Set(expression.Name("status"), expression.Value(status))
+	if status == models.StatusClosed || status == models.StatusFalsePositive {
+		// an alert moved from one resolved status to the other keeps the time it was resolved
+		update = update.Set(expression.Name("resolvedAt"),
+			expression.IfNotExists(expression.Name("resolvedAt"), expression.Value(updateTime)))
+	} else {
+		update = update.Remove(expression.Name("resolvedAt"))
+	}
+	activity := newActivityItem(alertID, models.ActivityStatus, userID, updateTime)
+	activity.Status = aws.String(status)
+	return table.updateAlert(alertID, update, userID, activity)
+}
+
+// UpdateAssignee assigns an alert, or unassigns it if assigneeID is nil, and adds the change to its activity
+func (table *AlertsTable) UpdateAssignee(alertID string, assigneeID *string, userID string, updateTime time.Time) error {
+	var update expression.UpdateBuilder
+	if assigneeID == nil {
+		update = expression.Remove(expression.Name("assigneeId"))
+	} else {
+		update = expression.Set(expression.Name("assigneeId"), expression.Value(*assigneeID))
+	}
+	activity := newActivityItem(alertID, models.ActivityAssign, userID, updateTime)
+	activity.AssigneeID = assigneeID
+	return table.updateAlert(alertID, update, userID, activity)
+}
+
+// updateAlert applies the update to an existing alert and stores the activity in the same transaction
+func (table *AlertsTable) updateAlert(alertID string, update expression.UpdateBuilder, userID string,
+	activity *ActivityItem) error {
+
+	update = update.
+		Set(expression.Name("lastUpdatedBy"), expression.Value(userID)).
+		Set(expression.Name("lastUpdatedByTime"), expression.Value(activity.CreatedAt))
+	updateExpression, err := expression.NewBuilder().
+		WithCondition(expression.AttributeExists(expression.Name(AlertIDKey))).
+		WithUpdate(update).
+		Build()
+	if err != nil {
+		return errors.Wrap(err, "failed to build update expression")
+	}
+	put, err := table.putActivity(activity)
+	if err != nil {
+		return err
+	}
+
+	return table.transactWrite(alertID, &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			ConditionExpression:       updateExpression.Condition(),
+			ExpressionAttributeNames:  updateExpression.Names(),
+			ExpressionAttributeValues: updateExpression.Values(),
+			Key:                       DynamoItem{AlertIDKey: {S: aws.String(alertID)}},
+			TableName:                 aws.String(table.AlertsTableName),
+			UpdateExpression:          updateExpression.Update(),
+		},
+	}, put)
+}
+
+// AddComment adds a comment to the activity of an existing alert
+func (table *AlertsTable) AddComment(alertID, comment, userID string, createdAt time.Time) (*ActivityItem, error) {
+	activity := newActivityItem(alertID, models.ActivityComment, userID, createdAt)
+	activity.Comment = aws.String(comment)
+	put, err := table.putActivity(activity)
+	if err != nil {
+		return nil, err
+	}
+
+	condition, err := expression.NewBuilder().
+		WithCondition(expression.AttributeExists(expression.Name(AlertIDKey))).
+		Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build condition expression")
+	}
+	err = table.transactWrite(alertID, &dynamodb.TransactWriteItem{
+		ConditionCheck: &dynamodb.ConditionCheck{
+			ConditionExpression:      condition.Condition(),
+			ExpressionAttributeNames: condition.Names(),
+			Key:                      DynamoItem{AlertIDKey: {S: aws.String(alertID)}},
+			TableName:                aws.String(table.AlertsTableName),
+		},
+	}, put)
+	if err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// putActivity returns the write of a new activity item
+func (table *AlertsTable) putActivity(activity *ActivityItem) (*dynamodb.TransactWriteItem, error) {
+	item, err := dynamodbattribute.MarshalMap(activity)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal activity")
+	}
+	condition, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name(ActivityIDKey))).
+		Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build condition expression")
+	}
+	return &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			ConditionExpression:      condition.Condition(),
+			ExpressionAttributeNames: condition.Names(),
+			Item:                     item,
+			TableName:                aws.String(table.ActivityTableName),
+		},
+	}, nil
+}
+
+// transactWrite writes the alert and its activity, the alert write is the first item
+func (table *AlertsTable) transactWrite(alertID string, items ...*dynamodb.TransactWriteItem) error {
+	_, err := table.Client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err == nil {
+		return nil
+	}
+	if canceledErr, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		reasons := canceledErr.CancellationReasons
+		if len(reasons) > 0 && aws.StringValue(reasons[0].Code) == "ConditionalCheckFailed" {
+			return &genericapi.DoesNotExistError{Message: "alertId=" + alertID + " does not exist"}
+		}
+	}
+	return errors.Wrapf(err, "failed to update alert %s", alertID)
+}
+
+// ListActivity returns a page of the activity of an alert, oldest first
+func (table *AlertsTable) ListActivity(alertID string, exclusiveStartKey *string, pageSize *int) (
+	activity []*ActivityItem, lastEvaluatedKey *string, err error) {
+
+	keyCondition := expression.Key(ActivityAlertIDKey).Equal(expression.Value(alertID))
+	queryExpression, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to build expression")
+	}
+
+	queryInput := &dynamodb.QueryInput{
+		ExpressionAttributeNames:  queryExpression.Names(),
+		ExpressionAttributeValues: queryExpression.Values(),
+		KeyConditionExpression:    queryExpression.KeyCondition(),
+		TableName:                 aws.String(table.ActivityTableName),
+	}
+	if pageSize != nil {
+		queryInput.Limit = aws.Int64(int64(*pageSize))
+	}
+	if exclusiveStartKey != nil {
+		if err = jsoniter.UnmarshalFromString(*exclusiveStartKey, &queryInput.ExclusiveStartKey); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to Unmarshal ExclusiveStartKey")
+		}
+	}
+
+	queryOutput, err := table.Client.Query(queryInput)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to query activity of alert %s", alertID)
+	}
+	if err = dynamodbattribute.UnmarshalListOfMaps(queryOutput.Items, &activity); err != nil {
+		return nil, nil, errors.Wrap(err, "UnmarshalListOfMaps() failed")
+	}
+	if len(queryOutput.LastEvaluatedKey) > 0 {
+		key, err := jsoniter.MarshalToString(queryOutput.LastEvaluatedKey)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to Marshal LastEvaluatedKey")
+		}
+		lastEvaluatedKey = &key
+	}
+	return activity, lastEvaluatedKey, nil
+}
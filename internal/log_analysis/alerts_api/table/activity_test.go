@@ -0,0 +1,175 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+func (m *mockDynamoDB) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.TransactWriteItemsOutput), args.Error(1)
+}
+
+func (m *mockDynamoDB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.QueryOutput), args.Error(1)
+}
+
+var activityTime = time.Date(2020, 1, 1, 1, 2, 3, 0, time.UTC)
+
+func activityTable(client *mockDynamoDB) *AlertsTable {
+	return &AlertsTable{
+		AlertsTableName:   "alertsTableName",
+		ActivityTableName: "activityTableName",
+		Client:            client,
+	}
+}
+
+// activityWrite returns the transaction and the activity item written
+func activityWrite(t *testing.T, client *mockDynamoDB) (*dynamodb.TransactWriteItemsInput, *ActivityItem) {
+	input := client.Calls[0].Arguments.Get(0).(*dynamodb.TransactWriteItemsInput)
+	require.Len(t, input.TransactItems, 2)
+	put := input.TransactItems[1].Put
+	assert.Equal(t, "activityTableName", *put.TableName)
+	assert.Equal(t, "attribute_not_exists (#0)", *put.ConditionExpression)
+	activity := &ActivityItem{}
+	require.NoError(t, dynamodbattribute.UnmarshalMap(put.Item, activity))
+	return input, activity
+}
+
+func TestUpdateStatus(t *testing.T) {
+	client := &mockDynamoDB{}
+	client.On("TransactWriteItems", mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	require.NoError(t, activityTable(client).UpdateStatus("alertId", models.StatusClosed, "userId", activityTime))
+	input, activity := activityWrite(t, client)
+
+	update := input.TransactItems[0].Update
+	assert.Equal(t, "alertsTableName", *update.TableName)
+	assert.Equal(t, "alertId", *update.Key["id"].S)
+	assert.Equal(t, "attribute_exists (#0)", *update.ConditionExpression)
+	assert.Equal(t, "SET #1 = :0, #2 = if_not_exists(#2, :1), #3 = :2, #4 = :3\n", *update.UpdateExpression)
+	assert.Equal(t, map[string]*string{
+		"#0": aws.String("id"),
+		"#1": aws.String("status"),
+		"#2": aws.String("resolvedAt"),
+		"#3": aws.String("lastUpdatedBy"),
+		"#4": aws.String("lastUpdatedByTime"),
+	}, update.ExpressionAttributeNames)
+	assert.Equal(t, models.StatusClosed, *update.ExpressionAttributeValues[":0"].S)
+	assert.Equal(t, "2020-01-01T01:02:03Z", *update.ExpressionAttributeValues[":1"].S)
+
+	assert.Equal(t, "alertId", activity.AlertID)
+	assert.Equal(t, models.ActivityStatus, activity.Type)
+	assert.Equal(t, "userId", activity.UserID)
+	assert.Equal(t, activityTime, activity.CreatedAt)
+	assert.Equal(t, models.StatusClosed, *activity.Status)
+	assert.Regexp(t, "^2020-01-01T01:02:03.000000000Z#", activity.ActivityID)
+}
+
+func TestUpdateStatusReopen(t *testing.T) {
+	client := &mockDynamoDB{}
+	client.On("TransactWriteItems", mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	require.NoError(t, activityTable(client).UpdateStatus("alertId", models.StatusOpen, "userId", activityTime))
+	input, _ := activityWrite(t, client)
+
+	update := input.TransactItems[0].Update
+	assert.Equal(t, "REMOVE #1\nSET #2 = :0, #3 = :1, #4 = :2\n", *update.UpdateExpression)
+	assert.Equal(t, "resolvedAt", *update.ExpressionAttributeNames["#1"])
+}
+
+func TestUpdateAssigneeUnassign(t *testing.T) {
+	client := &mockDynamoDB{}
+	client.On("TransactWriteItems", mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	require.NoError(t, activityTable(client).UpdateAssignee("alertId", nil, "userId", activityTime))
+	input, activity := activityWrite(t, client)
+
+	assert.Equal(t, "REMOVE #1\nSET #2 = :0, #3 = :1\n", *input.TransactItems[0].Update.UpdateExpression)
+	assert.Equal(t, "assigneeId", *input.TransactItems[0].Update.ExpressionAttributeNames["#1"])
+	assert.Equal(t, models.ActivityAssign, activity.Type)
+	assert.Nil(t, activity.AssigneeID)
+}
+
+func TestAddComment(t *testing.T) {
+	client := &mockDynamoDB{}
+	client.On("TransactWriteItems", mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	result, err := activityTable(client).AddComment("alertId", "comment", "userId", activityTime)
+	require.NoError(t, err)
+	input, activity := activityWrite(t, client)
+
+	check := input.TransactItems[0].ConditionCheck
+	assert.Equal(t, "alertsTableName", *check.TableName)
+	assert.Equal(t, "attribute_exists (#0)", *check.ConditionExpression)
+	assert.Equal(t, result, activity)
+	assert.Equal(t, "comment", *activity.Comment)
+}
+
+func TestAddCommentAlertDoesNotExist(t *testing.T) {
+	client := &mockDynamoDB{}
+	canceled := &dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String("ConditionalCheckFailed")},
+			{Code: aws.String("None")},
+		},
+	}
+	client.On("TransactWriteItems", mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, canceled)
+
+	_, err := activityTable(client).AddComment("alertId", "comment", "userId", activityTime)
+	assert.Equal(t, &genericapi.DoesNotExistError{Message: "alertId=alertId does not exist"}, err)
+}
+
+func TestListActivity(t *testing.T) {
+	client := &mockDynamoDB{}
+	expected := &ActivityItem{
+		AlertID:    "alertId",
+		ActivityID: "2020-01-01T01:02:03.000000000Z#id",
+		Type:       models.ActivityComment,
+		UserID:     "userId",
+		CreatedAt:  activityTime,
+		Comment:    aws.String("comment"),
+	}
+	item, err := dynamodbattribute.MarshalMap(expected)
+	require.NoError(t, err)
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{item}}, nil)
+
+	result, lastEvaluatedKey, err := activityTable(client).ListActivity("alertId", nil, aws.Int(10))
+	require.NoError(t, err)
+	assert.Equal(t, []*ActivityItem{expected}, result)
+	assert.Nil(t, lastEvaluatedKey)
+
+	input := client.Calls[0].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, "activityTableName", *input.TableName)
+	assert.Equal(t, int64(10), *input.Limit)
+	assert.Nil(t, input.ScanIndexForward) // oldest first
+}
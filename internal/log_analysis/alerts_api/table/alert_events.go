@@ -0,0 +1,73 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/pkg/errors"
+)
+
+const (
+	AlertEventsAlertIDKey   = "alertId"
+	AlertEventsObjectKeyKey = "objectKey"
+)
+
+// AlertEventObject is an S3 object with matched events of an alert, written by the rules engine.
+// The rules engine writes the events of a single alert in each object.
+type AlertEventObject struct {
+	AlertID    string `json:"alertId"`
+	ObjectKey  string `json:"objectKey"`
+	LogType    string `json:"logType"`
+	EventCount int    `json:"eventCount"`
+}
+
+// ListAlertEventObjects returns up to limit objects with events of an alert, in object key order, starting after
+// the given object key
+func (table *AlertsTable) ListAlertEventObjects(alertID string, startAfter *string, limit int) (
+	[]*AlertEventObject, error) {
+
+	keyCondition := expression.Key(AlertEventsAlertIDKey).Equal(expression.Value(alertID))
+	if startAfter != nil {
+		keyCondition = keyCondition.And(expression.Key(AlertEventsObjectKeyKey).GreaterThan(expression.Value(*startAfter)))
+	}
+	queryExpression, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build expression")
+	}
+
+	queryOutput, err := table.Client.Query(&dynamodb.QueryInput{
+		ExpressionAttributeNames:  queryExpression.Names(),
+		ExpressionAttributeValues: queryExpression.Values(),
+		KeyConditionExpression:    queryExpression.KeyCondition(),
+		Limit:                     aws.Int64(int64(limit)),
+		TableName:                 aws.String(table.AlertEventsTableName),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query event objects of alert %s", alertID)
+	}
+
+	var result []*AlertEventObject
+	if err = dynamodbattribute.UnmarshalListOfMaps(queryOutput.Items, &result); err != nil {
+		return nil, errors.Wrap(err, "UnmarshalListOfMaps() failed")
+	}
+	return result, nil
+}
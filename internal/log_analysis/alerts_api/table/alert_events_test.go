@@ -0,0 +1,60 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAlertEventObjects(t *testing.T) {
+	client := &mockDynamoDB{}
+	table := &AlertsTable{AlertEventsTableName: "alertEventsTableName", Client: client}
+	client.On("Query", &dynamodb.QueryInput{
+		ExpressionAttributeNames: map[string]*string{"#0": aws.String("alertId"), "#1": aws.String("objectKey")},
+		ExpressionAttributeValues: DynamoItem{
+			":0": {S: aws.String("alertId")},
+			":1": {S: aws.String("rules/logtype/object1.json.gz")},
+		},
+		KeyConditionExpression: aws.String("(#0 = :0) AND (#1 > :1)"),
+		Limit:                  aws.Int64(5),
+		TableName:              aws.String("alertEventsTableName"),
+	}).Return(&dynamodb.QueryOutput{
+		Items: []DynamoItem{{
+			"alertId":    {S: aws.String("alertId")},
+			"objectKey":  {S: aws.String("rules/logtype/object2.json.gz")},
+			"logType":    {S: aws.String("logtype")},
+			"eventCount": {N: aws.String("3")},
+		}},
+	}, nil).Once()
+
+	result, err := table.ListAlertEventObjects("alertId", aws.String("rules/logtype/object1.json.gz"), 5)
+	require.NoError(t, err)
+	assert.Equal(t, []*AlertEventObject{{
+		AlertID:    "alertId",
+		ObjectKey:  "rules/logtype/object2.json.gz",
+		LogType:    "logtype",
+		EventCount: 3,
+	}}, result)
+	client.AssertExpectations(t)
+}
@@ -0,0 +1,67 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/pkg/errors"
+
+	deliverymodels "github.com/panther-labs/panther/internal/core/alert_delivery/models"
+)
+
+const (
+	DeliveriesAlertIDKey = "alertId"
+	DeliveriesIDKey      = "id"
+)
+
+// ListDeliveryAttempts returns the attempts of the alert delivery to send an alert to its outputs, oldest first
+func (table *AlertsTable) ListDeliveryAttempts(alertID string) ([]*deliverymodels.DeliveryAttempt, error) {
+	keyCondition := expression.Key(DeliveriesAlertIDKey).Equal(expression.Value(alertID))
+	queryExpression, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build expression")
+	}
+
+	queryInput := &dynamodb.QueryInput{
+		ExpressionAttributeNames:  queryExpression.Names(),
+		ExpressionAttributeValues: queryExpression.Values(),
+		KeyConditionExpression:    queryExpression.KeyCondition(),
+		TableName:                 aws.String(table.DeliveriesTableName),
+	}
+	var result []*deliverymodels.DeliveryAttempt
+	var unmarshalErr error
+	err = table.Client.QueryPages(queryInput, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		var attempts []*deliverymodels.DeliveryAttempt
+		if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &attempts); unmarshalErr != nil {
+			return false
+		}
+		result = append(result, attempts...)
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query delivery attempts of alert %s", alertID)
+	}
+	if unmarshalErr != nil {
+		return nil, errors.Wrap(unmarshalErr, "UnmarshalListOfMaps() failed")
+	}
+	return result, nil
+}
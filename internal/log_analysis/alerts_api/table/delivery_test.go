@@ -0,0 +1,59 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	deliverymodels "github.com/panther-labs/panther/internal/core/alert_delivery/models"
+)
+
+func TestListDeliveryAttempts(t *testing.T) {
+	client := &mockDynamoDB{}
+	attempt := &deliverymodels.DeliveryAttempt{
+		AlertID:     "alertId",
+		AttemptID:   "2020-01-01T00:00:00.000000000Z#outputId",
+		OutputID:    "outputId",
+		DeliveredAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		StatusCode:  500,
+		Error:       "request failed: 500 Internal Server Error",
+		NeedsRetry:  true,
+	}
+	item, err := dynamodbattribute.MarshalMap(attempt)
+	require.NoError(t, err)
+	client.On("QueryPages", mock.Anything).Return(&dynamodb.QueryOutput{
+		Items: []map[string]*dynamodb.AttributeValue{item}}, nil)
+
+	table := &AlertsTable{DeliveriesTableName: "deliveriesTable", Client: client}
+	result, err := table.ListDeliveryAttempts("alertId")
+	require.NoError(t, err)
+	assert.Equal(t, []*deliverymodels.DeliveryAttempt{attempt}, result)
+
+	input := client.Calls[0].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, "deliveriesTable", *input.TableName)
+	assert.Equal(t, map[string]*dynamodb.AttributeValue{":0": {S: aws.String("alertId")}}, input.ExpressionAttributeValues)
+}
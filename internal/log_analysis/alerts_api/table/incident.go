@@ -0,0 +1,181 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+
+	"github.com/panther-labs/panther/pkg/awsbatch/dynamodbbatch"
+)
+
+const IncidentIDKey = "id"
+
+// IncidentItem is a DDB representation of an incident, written by the alert forwarder
+type IncidentItem struct {
+	IncidentID     string    `json:"id"`
+	CreationTime   time.Time `json:"creationTime"`
+	UpdateTime     time.Time `json:"updateTime"`
+	Severity       string    `json:"severity"`
+	AlertIDs       []string  `json:"alertIds"`
+	RuleIDs        []string  `json:"ruleIds"`
+	IPAddresses    []string  `json:"ipAddresses,omitempty"`
+	AWSAccountIDs  []string  `json:"awsAccountIds,omitempty"`
+	AWSInstanceIDs []string  `json:"awsInstanceIds,omitempty"`
+}
+
+// GetIncident returns an incident, or nil if it doesn't exist
+func (table *AlertsTable) GetIncident(incidentID string) (*IncidentItem, error) {
+	output, err := table.Client.GetItem(&dynamodb.GetItemInput{
+		Key:       map[string]*dynamodb.AttributeValue{IncidentIDKey: {S: aws.String(incidentID)}},
+		TableName: aws.String(table.IncidentsTableName),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get incident %s", incidentID)
+	}
+	if len(output.Item) == 0 {
+		return nil, nil
+	}
+
+	item := &IncidentItem{}
+	if err = dynamodbattribute.UnmarshalMap(output.Item, item); err != nil {
+		return nil, errors.Wrap(err, "UnmarshalMap() failed for: "+incidentID)
+	}
+	return item, nil
+}
+
+// ListIncidents returns a page of incidents, newest first, and the last evaluated key. Incidents are spread on the
+// partitions of the time index like alerts, every partition is read from the last returned incident and merged.
+func (table *AlertsTable) ListIncidents(exclusiveStartKey *string, pageSize *int) (
+	incidents []*IncidentItem, lastEvaluatedKey *string, err error) {
+
+	// isBefore returns true if the incident a is listed before the incident b
+	isBefore := func(a, b *IncidentItem) bool {
+		if a.CreationTime.Equal(b.CreationTime) {
+			return a.IncidentID > b.IncidentID
+		}
+		return a.CreationTime.After(b.CreationTime)
+	}
+
+	var cursor *IncidentItem
+	if exclusiveStartKey != nil {
+		cursor = &IncidentItem{}
+		if err = jsoniter.UnmarshalFromString(*exclusiveStartKey, cursor); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to Unmarshal ExclusiveStartKey")
+		}
+	}
+
+	// the last incident read from a partition with more incidents, the incidents listed after it may not be in order
+	var boundary *IncidentItem
+	for _, partition := range TimePartitions() {
+		keyCondition := expression.Key(TimePartitionKey).Equal(expression.Value(partition))
+		if cursor != nil {
+			// the range includes the incidents created at the same time as the cursor
+			keyCondition = keyCondition.And(expression.Key("creationTime").LessThanEqual(expression.Value(cursor.CreationTime)))
+		}
+		queryExpression, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to build expression")
+		}
+		queryInput := &dynamodb.QueryInput{
+			ExpressionAttributeNames:  queryExpression.Names(),
+			ExpressionAttributeValues: queryExpression.Values(),
+			IndexName:                 aws.String(table.IncidentsTimePartitionIndexName),
+			KeyConditionExpression:    queryExpression.KeyCondition(),
+			ScanIndexForward:          aws.Bool(false),
+			TableName:                 aws.String(table.IncidentsTableName),
+		}
+		if pageSize != nil {
+			queryInput.Limit = aws.Int64(int64(*pageSize))
+		}
+
+		queryOutput, err := table.Client.Query(queryInput)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to query incidents of partition %s", partition)
+		}
+		var partitionIncidents []*IncidentItem
+		if err = dynamodbattribute.UnmarshalListOfMaps(queryOutput.Items, &partitionIncidents); err != nil {
+			return nil, nil, errors.Wrap(err, "UnmarshalListOfMaps() failed")
+		}
+		for _, incident := range partitionIncidents {
+			if cursor == nil || isBefore(cursor, incident) {
+				incidents = append(incidents, incident)
+			}
+		}
+		if len(queryOutput.LastEvaluatedKey) > 0 && len(partitionIncidents) > 0 {
+			last := partitionIncidents[len(partitionIncidents)-1]
+			if boundary == nil || isBefore(last, boundary) {
+				boundary = last
+			}
+		}
+	}
+
+	sort.Slice(incidents, func(i, j int) bool { return isBefore(incidents[i], incidents[j]) })
+	more := boundary != nil
+	if boundary != nil {
+		end := sort.Search(len(incidents), func(i int) bool { return isBefore(boundary, incidents[i]) })
+		incidents = incidents[:end]
+	}
+	if pageSize != nil && len(incidents) > *pageSize {
+		incidents = incidents[:*pageSize]
+		more = true
+	}
+	if !more || len(incidents) == 0 {
+		return incidents, nil, nil
+	}
+
+	last := incidents[len(incidents)-1]
+	key, err := jsoniter.MarshalToString(&IncidentItem{IncidentID: last.IncidentID, CreationTime: last.CreationTime})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to Marshal LastEvaluatedKey")
+	}
+	return incidents, &key, nil
+}
+
+// GetAlerts returns the alerts which exist out of the given alerts, in no particular order
+func (table *AlertsTable) GetAlerts(alertIDs []string) ([]*AlertItem, error) {
+	if len(alertIDs) == 0 {
+		return nil, nil
+	}
+	keys := make([]map[string]*dynamodb.AttributeValue, len(alertIDs))
+	for i, alertID := range alertIDs {
+		keys[i] = map[string]*dynamodb.AttributeValue{AlertIDKey: {S: aws.String(alertID)}}
+	}
+	output, err := dynamodbbatch.BatchGetItem(table.Client, &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			table.AlertsTableName: {Keys: keys},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get alerts")
+	}
+
+	var alerts []*AlertItem
+	if err = dynamodbattribute.UnmarshalListOfMaps(output.Responses[table.AlertsTableName], &alerts); err != nil {
+		return nil, errors.Wrap(err, "UnmarshalListOfMaps() failed")
+	}
+	return alerts, nil
+}
@@ -0,0 +1,158 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func (m *mockDynamoDB) BatchGetItemPages(
+	input *dynamodb.BatchGetItemInput,
+	pageFunc func(*dynamodb.BatchGetItemOutput, bool) bool,
+) error {
+
+	args := m.Called(input)
+	pageFunc(args.Get(0).(*dynamodb.BatchGetItemOutput), true)
+	return args.Error(1)
+}
+
+func incidentTable(client *mockDynamoDB) *AlertsTable {
+	return &AlertsTable{
+		AlertsTableName:                 "alertsTableName",
+		IncidentsTableName:              "incidentsTableName",
+		IncidentsTimePartitionIndexName: "incidentsIndexName",
+		Client:                          client,
+	}
+}
+
+func TestGetIncident(t *testing.T) {
+	client := &mockDynamoDB{}
+	incident := &IncidentItem{
+		IncidentID:   "incidentId",
+		CreationTime: activityTime,
+		UpdateTime:   activityTime,
+		Severity:     "HIGH",
+		AlertIDs:     []string{"alert1", "alert2"},
+		RuleIDs:      []string{"rule1", "rule2"},
+	}
+	item, err := dynamodbattribute.MarshalMap(incident)
+	require.NoError(t, err)
+	client.On("GetItem", &dynamodb.GetItemInput{
+		Key:       DynamoItem{"id": {S: aws.String("incidentId")}},
+		TableName: aws.String("incidentsTableName"),
+	}).Return(&dynamodb.GetItemOutput{Item: item}, nil).Once()
+
+	result, err := incidentTable(client).GetIncident("incidentId")
+	require.NoError(t, err)
+	assert.Equal(t, incident, result)
+	client.AssertExpectations(t)
+}
+
+func TestGetIncidentDoesNotExist(t *testing.T) {
+	client := &mockDynamoDB{}
+	client.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{}, nil).Once()
+
+	result, err := incidentTable(client).GetIncident("incidentId")
+	require.NoError(t, err)
+	assert.Nil(t, result)
+	client.AssertExpectations(t)
+}
+
+func marshalIncidents(t *testing.T, incidents ...*IncidentItem) []DynamoItem {
+	items := make([]DynamoItem, len(incidents))
+	for i, incident := range incidents {
+		item, err := dynamodbattribute.MarshalMap(incident)
+		require.NoError(t, err)
+		items[i] = item
+	}
+	return items
+}
+
+func TestListIncidents(t *testing.T) {
+	client := &mockDynamoDB{}
+	client.On("Query", mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return *input.IndexName == "incidentsIndexName" && !*input.ScanIndexForward && *input.Limit == 1
+	})).Return(&dynamodb.QueryOutput{
+		Items: []DynamoItem{{"id": {S: aws.String("incidentId")}}},
+	}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+	result, lastEvaluatedKey, err := incidentTable(client).ListIncidents(nil, aws.Int(1))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "incidentId", result[0].IncidentID)
+	assert.Nil(t, lastEvaluatedKey)
+	// every shard and the legacy partition
+	client.AssertNumberOfCalls(t, "Query", TimePartitionShards+1)
+}
+
+// The incidents of the partitions are merged in creation time order
+func TestListIncidentsMergesPartitions(t *testing.T) {
+	client := &mockDynamoDB{}
+	first := &IncidentItem{IncidentID: "first", CreationTime: activityTime.Add(2 * time.Minute)}
+	second := &IncidentItem{IncidentID: "second", CreationTime: activityTime.Add(time.Minute)}
+	third := &IncidentItem{IncidentID: "third", CreationTime: activityTime}
+	// the first shard has more incidents after the second
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{
+		Items:            marshalIncidents(t, second),
+		LastEvaluatedKey: DynamoItem{"id": {S: aws.String("second")}},
+	}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{Items: marshalIncidents(t, first, third)}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+	result, lastEvaluatedKey, err := incidentTable(client).ListIncidents(nil, aws.Int(1))
+	require.NoError(t, err)
+	assert.Equal(t, []*IncidentItem{first}, result)
+	require.NotNil(t, lastEvaluatedKey)
+
+	// the next page starts after the first incident
+	client = &mockDynamoDB{}
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{Items: marshalIncidents(t, second)}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{Items: marshalIncidents(t, first, third)}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+	result, lastEvaluatedKey, err = incidentTable(client).ListIncidents(lastEvaluatedKey, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []*IncidentItem{second, third}, result)
+	assert.Nil(t, lastEvaluatedKey)
+	queryInput := client.Calls[0].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, "(#0 = :0) AND (#1 <= :1)", *queryInput.KeyConditionExpression)
+}
+
+func TestGetAlerts(t *testing.T) {
+	client := &mockDynamoDB{}
+	client.On("BatchGetItemPages", mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]DynamoItem{
+			"alertsTableName": {{"id": {S: aws.String("alert1")}}},
+		},
+	}, nil).Once()
+
+	result, err := incidentTable(client).GetAlerts([]string{"alert1", "alert2"})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "alert1", result[0].AlertID)
+	client.AssertExpectations(t)
+}
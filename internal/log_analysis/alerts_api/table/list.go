@@ -19,6 +19,9 @@ package table
  */
 
 import (
+	"sort"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
@@ -26,91 +29,402 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+)
+
+const (
+	// maxListQueries bounds the queries made to fill a page of the alerts of a rule when most alerts in the time range
+	// are filtered out, a page can have fewer alerts than requested even if there are more alerts.
+	maxListQueries = 10
+	// maxPartitionQueries bounds the queries made in each partition of the time indexes to fill a page, a page reads
+	// at most TimePartitionShards+1 times this many pages from DynamoDB whatever the retention and the filters.
+	maxPartitionQueries = 2
 )
 
-func (table *AlertsTable) ListByRule(ruleID string, exclusiveStartKey *string, pageSize *int) (
+// ListAll returns a page of the alerts matching the filters, sorted by creation or update time, the last evaluated
+// key, any error.
+//
+// The alerts are read from the index of the sort field, the time range of the sort field is a key condition and
+// the other filters are applied to the alerts read. The time indexes are sharded: every partition is read from the
+// last returned alert and the alerts are merged. Alerts older than the retention are not listed.
+func (table *AlertsTable) ListAll(input *models.ListAlertsInput) (
 	summaries []*AlertItem, lastEvaluatedKey *string, err error) {
 
-	return table.list(RuleIDKey, ruleID, exclusiveStartKey, pageSize)
+	sortBy := aws.StringValue(input.SortBy)
+	if sortBy == "" {
+		sortBy = models.SortByCreationTime
+	}
+
+	var startKey DynamoItem
+	if input.ExclusiveStartKey != nil {
+		if err = jsoniter.UnmarshalFromString(*input.ExclusiveStartKey, &startKey); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to Unmarshal ExclusiveStartKey")
+		}
+	}
+
+	// alerts older than the retention are archived
+	input = table.withinRetention(input)
+
+	var lastKey DynamoItem
+	switch {
+	case sortBy == models.SortByCreationTime && input.RuleID != nil:
+		summaries, lastKey, err = table.listByRule(input, startKey)
+	case sortBy == models.SortByCreationTime:
+		summaries, lastKey, err = table.listMerged(input, startKey, table.TimePartitionCreationTimeIndexName, sortBy)
+	case sortBy == models.SortByUpdateTime:
+		summaries, lastKey, err = table.listMerged(input, startKey, table.TimePartitionUpdateTimeIndexName, sortBy)
+	default:
+		return nil, nil, errors.New("unknown sort field " + sortBy)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// If DDB returned a LastEvaluatedKey (the "primary key of the item where the operation stopped"),
+	// it means there are more alerts to be returned. Return populated `lastEvaluatedKey` JSON blob in the response.
+	if len(lastKey) > 0 {
+		lastEvaluatedKeySerialized, err := jsoniter.MarshalToString(lastKey)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to Marshal LastEvaluatedKey)")
+		}
+		lastEvaluatedKey = &lastEvaluatedKeySerialized
+	}
+
+	return summaries, lastEvaluatedKey, nil
 }
 
-func (table *AlertsTable) ListAll(exclusiveStartKey *string, pageSize *int) (
-	summaries []*AlertItem, lastEvaluatedKey *string, err error) {
+// withinRetention returns the input with the creation time range limited to the retention
+func (table *AlertsTable) withinRetention(input *models.ListAlertsInput) *models.ListAlertsInput {
+	oldest := table.currentTime().AddDate(0, 0, -table.RetentionDays)
+	if input.CreatedAtAfter != nil && input.CreatedAtAfter.After(oldest) {
+		return input
+	}
+	limited := *input
+	limited.CreatedAtAfter = &oldest
+	return &limited
+}
+
+// listByRule returns a page of the alerts of a rule, the rule index is not sharded
+func (table *AlertsTable) listByRule(input *models.ListAlertsInput, startKey DynamoItem) ([]*AlertItem, DynamoItem, error) {
+	queryInput, err := table.listQuery(input, table.RuleIDCreationTimeIndexName, RuleIDKey, *input.RuleID)
+	if err != nil {
+		return nil, nil, err
+	}
+	queryInput.ExclusiveStartKey = startKey
 
-	return table.list(TimePartitionKey, TimePartitionValue, exclusiveStartKey, pageSize)
+	items, lastKey, err := table.queryPartition(queryInput, input.PageSize, maxListQueries)
+	if err != nil {
+		return nil, nil, err
+	}
+	return unmarshalPage(items, lastKey, input.PageSize, RuleIDKey, models.SortByCreationTime)
 }
 
-// list returns a page of alerts ordered by creationTime, last evaluated key, any error
-func (table *AlertsTable) list(ddbKey, ddbValue string, exclusiveStartKey *string, pageSize *int) (
-	summaries []*AlertItem, lastEvaluatedKey *string, err error) {
+// listMerged returns a page of the alerts sorted by creation or update time, reading every partition of the index
+// from the last returned alert and merging them.
+//
+// The last evaluated key is the id and sort field of the last returned alert. A page can have fewer alerts than
+// requested when most alerts are filtered out, it ends where the partitions were read.
+func (table *AlertsTable) listMerged(input *models.ListAlertsInput, startKey DynamoItem, index, sortBy string) (
+	[]*AlertItem, DynamoItem, error) {
 
-	// pick index
-	var index string
-	if ddbKey == RuleIDKey {
-		index = table.RuleIDCreationTimeIndexName
-	} else if ddbKey == TimePartitionKey {
-		index = table.TimePartitionCreationTimeIndexName
-	} else {
-		return nil, nil, errors.New("unknown key" + ddbKey)
+	sortTime := func(alert *AlertItem) time.Time {
+		if sortBy == models.SortByUpdateTime {
+			return alert.UpdateTime
+		}
+		return alert.CreationTime
+	}
+	ascending := aws.StringValue(input.SortDir) == "ascending"
+	// isBefore returns true if the alert a is listed before the alert b
+	isBefore := func(a, b *AlertItem) bool {
+		if ascending {
+			a, b = b, a
+		}
+		if sortTime(a).Equal(sortTime(b)) {
+			return a.AlertID > b.AlertID
+		}
+		return sortTime(a).After(sortTime(b))
 	}
 
-	// queries require and = condition on primary key
-	keyCondition := expression.Key(ddbKey).Equal(expression.Value(&ddbValue))
+	var cursor *AlertItem
+	if len(startKey) > 0 {
+		cursor = &AlertItem{}
+		if err := dynamodbattribute.UnmarshalMap(startKey, cursor); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to Unmarshal ExclusiveStartKey")
+		}
+		// the range includes the alerts with the same sort time as the cursor
+		cursorTime := sortTime(cursor)
+		cursorInput := *input
+		switch {
+		case sortBy == models.SortByUpdateTime && ascending:
+			cursorInput.UpdatedAtAfter = &cursorTime
+		case sortBy == models.SortByUpdateTime:
+			cursorInput.UpdatedAtBefore = &cursorTime
+		case ascending:
+			cursorInput.CreatedAtAfter = &cursorTime
+		default:
+			cursorInput.CreatedAtBefore = &cursorTime
+		}
+		input = &cursorInput
+	}
 
-	queryExpression, err := expression.NewBuilder().
-		WithKeyCondition(keyCondition).
-		Build()
+	var alerts []*AlertItem
+	// the last alert read from a partition with more alerts, the alerts listed after it may not be in order
+	var boundary *AlertItem
+	for _, partition := range TimePartitions() {
+		queryInput, err := table.listQuery(input, index, TimePartitionKey, partition)
+		if err != nil {
+			return nil, nil, err
+		}
+		items, lastKey, err := table.queryPartition(queryInput, input.PageSize, maxPartitionQueries)
+		if err != nil {
+			return nil, nil, err
+		}
+		var partitionAlerts []*AlertItem
+		if err = dynamodbattribute.UnmarshalListOfMaps(items, &partitionAlerts); err != nil {
+			return nil, nil, errors.Wrap(err, "UnmarshalListOfMaps() failed")
+		}
+		for _, alert := range partitionAlerts {
+			if cursor == nil || isBefore(cursor, alert) {
+				alerts = append(alerts, alert)
+			}
+		}
+		if len(lastKey) > 0 {
+			last := &AlertItem{}
+			if err = dynamodbattribute.UnmarshalMap(lastKey, last); err != nil {
+				return nil, nil, errors.Wrap(err, "UnmarshalMap() failed")
+			}
+			if boundary == nil || isBefore(last, boundary) {
+				boundary = last
+			}
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool { return isBefore(alerts[i], alerts[j]) })
+	more := boundary != nil
+	if boundary != nil {
+		end := sort.Search(len(alerts), func(i int) bool { return isBefore(boundary, alerts[i]) })
+		alerts = alerts[:end]
+	}
+	if input.PageSize != nil && len(alerts) > *input.PageSize {
+		alerts = alerts[:*input.PageSize]
+		more = true
+	}
+	if !more {
+		return alerts, nil, nil
+	}
 
+	last := boundary
+	if len(alerts) > 0 {
+		last = alerts[len(alerts)-1]
+	}
+	lastTime, err := dynamodbattribute.Marshal(sortTime(last))
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to build expression")
+		return nil, nil, errors.Wrap(err, "failed to marshal "+sortBy)
 	}
+	return alerts, DynamoItem{AlertIDKey: {S: aws.String(last.AlertID)}, sortBy: lastTime}, nil
+}
 
-	var queryResultsLimit *int64
-	if pageSize != nil {
-		queryResultsLimit = aws.Int64(int64(*pageSize))
+// queryPartition queries until the page is full, all the alerts were read or maxQueries queries were made, it
+// returns the alerts read and the last evaluated key
+func (table *AlertsTable) queryPartition(queryInput *dynamodb.QueryInput, pageSize *int, maxQueries int) (
+	items []DynamoItem, lastKey DynamoItem, err error) {
+
+	for queries := 0; queries < maxQueries; queries++ {
+		queryOutput, err := table.Client.Query(queryInput)
+		if err != nil {
+			// this deserves detailed logging for debugging
+			zap.L().Error("Query()", zap.Error(err), zap.Any("input", queryInput))
+			return nil, nil, errors.Wrapf(err, "QueryInput() failed for %s", *queryInput.IndexName)
+		}
+		items = append(items, queryOutput.Items...)
+		lastKey = queryOutput.LastEvaluatedKey
+		if len(lastKey) == 0 || pageSize == nil || len(items) >= *pageSize {
+			break
+		}
+		queryInput.ExclusiveStartKey = lastKey
+	}
+	return items, lastKey, nil
+}
+
+// unmarshalPage returns the alerts of the page and the key to continue after the last alert
+func unmarshalPage(items []DynamoItem, lastKey DynamoItem, pageSize *int, hashKey, sortBy string) (
+	[]*AlertItem, DynamoItem, error) {
+
+	if pageSize != nil && len(items) > *pageSize {
+		// continue after the last returned alert
+		items = items[:*pageSize]
+		lastKey = make(DynamoItem, 3)
+		for _, attribute := range []string{AlertIDKey, hashKey, sortBy} {
+			lastKey[attribute] = items[len(items)-1][attribute]
+		}
 	}
 
-	var queryExclusiveStartKey map[string]*dynamodb.AttributeValue
-	if exclusiveStartKey != nil {
-		queryExclusiveStartKey = make(map[string]*dynamodb.AttributeValue)
-		err = jsoniter.UnmarshalFromString(*exclusiveStartKey, &queryExclusiveStartKey)
+	var summaries []*AlertItem
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, &summaries); err != nil {
+		return nil, nil, errors.Wrap(err, "UnmarshalListOfMaps() failed")
+	}
+	return summaries, lastKey, nil
+}
+
+// ListCreatedBetween returns all the alerts created in the time range, with the attributes needed to count them
+func (table *AlertsTable) ListCreatedBetween(after, before time.Time) ([]*AlertItem, error) {
+	if oldest := table.currentTime().AddDate(0, 0, -table.RetentionDays); after.Before(oldest) {
+		after = oldest
+	}
+	projection := expression.NamesList(
+		expression.Name(AlertIDKey),
+		expression.Name(RuleIDKey),
+		expression.Name("severity"),
+		expression.Name("logTypes"),
+		expression.Name("eventCount"),
+		expression.Name("creationTime"),
+		expression.Name("status"),
+		expression.Name("resolvedAt"),
+	)
+
+	var result []*AlertItem
+	for _, partition := range TimePartitions() {
+		keyCondition := expression.Key(TimePartitionKey).Equal(expression.Value(partition))
+		keyCondition = withRange(keyCondition, models.SortByCreationTime, &after, &before)
+		queryExpression, err := expression.NewBuilder().WithKeyCondition(keyCondition).WithProjection(projection).Build()
 		if err != nil {
-			return nil, nil, errors.Wrap(err, "failed to Unmarshal ExclusiveStartKey")
+			return nil, errors.Wrap(err, "failed to build expression")
 		}
+
+		queryInput := &dynamodb.QueryInput{
+			ExpressionAttributeNames:  queryExpression.Names(),
+			ExpressionAttributeValues: queryExpression.Values(),
+			IndexName:                 aws.String(table.TimePartitionCreationTimeIndexName),
+			KeyConditionExpression:    queryExpression.KeyCondition(),
+			ProjectionExpression:      queryExpression.Projection(),
+			TableName:                 aws.String(table.AlertsTableName),
+		}
+		var unmarshalErr error
+		err = table.Client.QueryPages(queryInput, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+			var items []*AlertItem
+			if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &items); unmarshalErr != nil {
+				return false
+			}
+			result = append(result, items...)
+			return true
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to query alerts")
+		}
+		if unmarshalErr != nil {
+			return nil, errors.Wrap(unmarshalErr, "UnmarshalListOfMaps() failed")
+		}
+	}
+	return result, nil
+}
+
+// listQuery returns the query of the alerts of a partition of the index
+func (table *AlertsTable) listQuery(input *models.ListAlertsInput, index, hashKey, hashValue string) (
+	*dynamodb.QueryInput, error) {
+
+	sortBy := aws.StringValue(input.SortBy)
+	if sortBy == "" {
+		sortBy = models.SortByCreationTime
+	}
+
+	var filters []expression.ConditionBuilder
+	if sortBy == models.SortByUpdateTime && input.RuleID != nil {
+		filters = append(filters, expression.Name(RuleIDKey).Equal(expression.Value(*input.RuleID)))
+	}
+
+	// queries require and = condition on primary key, the time range of the sort field is a range condition
+	keyCondition := expression.Key(hashKey).Equal(expression.Value(hashValue))
+	createdAfter, createdBefore := input.CreatedAtAfter, input.CreatedAtBefore
+	updatedAfter, updatedBefore := input.UpdatedAtAfter, input.UpdatedAtBefore
+	if sortBy == models.SortByCreationTime {
+		keyCondition = withRange(keyCondition, models.SortByCreationTime, createdAfter, createdBefore)
+		filters = appendTimeFilter(filters, models.SortByUpdateTime, updatedAfter, updatedBefore)
+	} else {
+		keyCondition = withRange(keyCondition, models.SortByUpdateTime, updatedAfter, updatedBefore)
+		filters = appendTimeFilter(filters, models.SortByCreationTime, createdAfter, createdBefore)
+	}
+	if len(input.Severity) > 0 {
+		filters = append(filters, in("severity", aws.StringValueSlice(input.Severity)))
+	}
+	if len(input.Status) > 0 {
+		statuses := aws.StringValueSlice(input.Status)
+		statusFilter := in("status", statuses)
+		for _, status := range statuses {
+			if status == models.StatusOpen { // alerts created before triage have no status
+				statusFilter = statusFilter.Or(expression.AttributeNotExists(expression.Name("status")))
+				break
+			}
+		}
+		filters = append(filters, statusFilter)
+	}
+	if input.LogType != nil {
+		filters = append(filters, expression.Contains(expression.Name("logTypes"), *input.LogType))
+	}
+	if input.EventCountMin != nil {
+		filters = append(filters, expression.Name("eventCount").GreaterThanEqual(expression.Value(*input.EventCountMin)))
+	}
+	if input.TitleContains != nil {
+		filters = append(filters, expression.Contains(expression.Name("title"), *input.TitleContains))
+	}
+
+	builder := expression.NewBuilder().WithKeyCondition(keyCondition)
+	if len(filters) == 1 {
+		builder = builder.WithFilter(filters[0])
+	} else if len(filters) > 1 {
+		builder = builder.WithFilter(expression.And(filters[0], filters[1], filters[2:]...))
+	}
+	queryExpression, err := builder.Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build expression")
+	}
+
+	var queryResultsLimit *int64
+	if input.PageSize != nil {
+		queryResultsLimit = aws.Int64(int64(*input.PageSize))
 	}
 
 	var queryInput = &dynamodb.QueryInput{
 		TableName:                 &table.AlertsTableName,
-		ScanIndexForward:          aws.Bool(false),
+		ScanIndexForward:          aws.Bool(aws.StringValue(input.SortDir) == "ascending"),
 		ExpressionAttributeNames:  queryExpression.Names(),
 		ExpressionAttributeValues: queryExpression.Values(),
 		KeyConditionExpression:    queryExpression.KeyCondition(),
-		ExclusiveStartKey:         queryExclusiveStartKey,
+		FilterExpression:          queryExpression.Filter(),
 		IndexName:                 aws.String(index),
 		Limit:                     queryResultsLimit,
 	}
+	return queryInput, nil
+}
 
-	queryOutput, err := table.Client.Query(queryInput)
-	if err != nil {
-		// this deserves detailed logging for debugging
-		zap.L().Error("Query()", zap.Error(err), zap.Any("input", queryInput), zap.Any("startKey", queryExclusiveStartKey))
-		return nil, nil, errors.Wrapf(err, "QueryInput() failed for %s,%s", ddbKey, ddbValue)
+func withRange(keyCondition expression.KeyConditionBuilder, key string, after, before *time.Time) expression.KeyConditionBuilder {
+	switch {
+	case after != nil && before != nil:
+		return keyCondition.And(expression.Key(key).Between(expression.Value(after), expression.Value(before)))
+	case after != nil:
+		return keyCondition.And(expression.Key(key).GreaterThanEqual(expression.Value(after)))
+	case before != nil:
+		return keyCondition.And(expression.Key(key).LessThanEqual(expression.Value(before)))
+	default:
+		return keyCondition
 	}
+}
 
-	err = dynamodbattribute.UnmarshalListOfMaps(queryOutput.Items, &summaries)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "UnmarshalListOfMaps() failed")
+func appendTimeFilter(filters []expression.ConditionBuilder, name string, after, before *time.Time) []expression.ConditionBuilder {
+	if after != nil {
+		filters = append(filters, expression.Name(name).GreaterThanEqual(expression.Value(after)))
 	}
-
-	// If DDB returned a LastEvaluatedKey (the "primary key of the item where the operation stopped"),
-	// it means there are more alerts to be returned. Return populated `lastEvaluatedKey` JSON blob in the response.
-	if len(queryOutput.LastEvaluatedKey) > 0 {
-		lastEvaluatedKeySerialized, err := jsoniter.MarshalToString(queryOutput.LastEvaluatedKey)
-		if err != nil {
-			return nil, nil, errors.Wrap(err, "failed to Marshal LastEvaluatedKey)")
-		}
-		lastEvaluatedKey = &lastEvaluatedKeySerialized
+	if before != nil {
+		filters = append(filters, expression.Name(name).LessThanEqual(expression.Value(before)))
 	}
+	return filters
+}
 
-	return summaries, lastEvaluatedKey, nil
+// in returns the condition that the attribute has one of the values
+func in(name string, values []string) expression.ConditionBuilder {
+	operands := make([]expression.OperandBuilder, len(values))
+	for i, value := range values {
+		operands[i] = expression.Value(value)
+	}
+	return expression.Name(name).In(operands[0], operands[1:]...)
 }
@@ -0,0 +1,328 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+)
+
+// listNow is the time of the list tests, alerts created since 2020-01-01T12:00:00Z are listed
+var listNow = time.Date(2020, 1, 3, 12, 0, 0, 0, time.UTC)
+
+func listTable(client *mockDynamoDB) *AlertsTable {
+	return &AlertsTable{
+		AlertsTableName:                    "alertsTableName",
+		RuleIDCreationTimeIndexName:        "ruleIDCreationTimeIndexName",
+		TimePartitionCreationTimeIndexName: "timePartitionCreationTimeIndexName",
+		TimePartitionUpdateTimeIndexName:   "timePartitionUpdateTimeIndexName",
+		RetentionDays:                      2,
+		Client:                             client,
+		now:                                func() time.Time { return listNow },
+	}
+}
+
+func marshalAlerts(t *testing.T, alerts ...*AlertItem) []map[string]*dynamodb.AttributeValue {
+	items := make([]map[string]*dynamodb.AttributeValue, len(alerts))
+	for i, alert := range alerts {
+		item, err := dynamodbattribute.MarshalMap(alert)
+		require.NoError(t, err)
+		items[i] = item
+	}
+	return items
+}
+
+// queriedPartition returns the time partition of a query
+func queriedPartition(t *testing.T, client *mockDynamoDB, call int) string {
+	input := client.Calls[call].Arguments.Get(0).(*dynamodb.QueryInput)
+	for name, attribute := range input.ExpressionAttributeNames {
+		if *attribute == "timePartition" {
+			condition := strings.TrimPrefix(*input.KeyConditionExpression, "(")
+			require.True(t, strings.HasPrefix(condition, name+" = "))
+			value := strings.Fields(strings.TrimPrefix(condition, name+" = "))[0]
+			return *input.ExpressionAttributeValues[strings.TrimSuffix(value, ")")].S
+		}
+	}
+	require.Fail(t, "no time partition")
+	return ""
+}
+
+func TestTimePartition(t *testing.T) {
+	partitions := TimePartitions()
+	require.Len(t, partitions, TimePartitionShards+1)
+	assert.Equal(t, "shard0", partitions[0])
+	assert.Equal(t, LegacyTimePartition, partitions[TimePartitionShards])
+
+	// the alerts are spread on every shard
+	used := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		partition := TimePartition(strconv.Itoa(i))
+		assert.Contains(t, partitions[:TimePartitionShards], partition)
+		used[partition] = true
+	}
+	assert.Len(t, used, TimePartitionShards)
+	assert.Equal(t, TimePartition("alertId"), TimePartition("alertId"))
+}
+
+func TestListAllDefault(t *testing.T) {
+	client := &mockDynamoDB{}
+	alert := &AlertItem{AlertID: "alertId", RuleID: "ruleId", Severity: "INFO", LogTypes: []string{"logtype"}}
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{Items: marshalAlerts(t, alert)}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+	result, lastEvaluatedKey, err := listTable(client).ListAll(&models.ListAlertsInput{})
+	require.NoError(t, err)
+	assert.Equal(t, []*AlertItem{alert}, result)
+	assert.Nil(t, lastEvaluatedKey)
+
+	// every shard is read, then the alerts created before sharding, whatever the retention
+	client.AssertNumberOfCalls(t, "Query", TimePartitionShards+1)
+	for i, partition := range TimePartitions() {
+		assert.Equal(t, partition, queriedPartition(t, client, i))
+	}
+
+	input := client.Calls[0].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, "timePartitionCreationTimeIndexName", *input.IndexName)
+	assert.Equal(t, "(#0 = :0) AND (#1 >= :1)", *input.KeyConditionExpression)
+	assert.Equal(t, "2020-01-01T12:00:00Z", *input.ExpressionAttributeValues[":1"].S)
+	assert.False(t, *input.ScanIndexForward)
+	assert.Nil(t, input.FilterExpression)
+	assert.Nil(t, input.Limit)
+}
+
+func TestListAllByRuleWithFilters(t *testing.T) {
+	client := &mockDynamoDB{}
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+	createdAfter := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	_, _, err := listTable(client).ListAll(&models.ListAlertsInput{
+		RuleID:         aws.String("ruleId"),
+		PageSize:       aws.Int(10),
+		Severity:       aws.StringSlice([]string{"HIGH", "CRITICAL"}),
+		Status:         aws.StringSlice([]string{models.StatusOpen}),
+		LogType:        aws.String("AWS.CloudTrail"),
+		CreatedAtAfter: aws.Time(createdAfter),
+		EventCountMin:  aws.Int(10),
+		TitleContains:  aws.String("admin"),
+		SortDir:        aws.String("ascending"),
+	})
+	require.NoError(t, err)
+
+	// the rule index is not sharded
+	client.AssertNumberOfCalls(t, "Query", 1)
+	input := client.Calls[0].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, "ruleIDCreationTimeIndexName", *input.IndexName)
+	// the filter placeholders come first
+	assert.Equal(t, "(#5 = :6) AND (#6 >= :7)", *input.KeyConditionExpression)
+	assert.Equal(t, "(#0 IN (:0, :1)) AND ((#1 IN (:2)) OR (attribute_not_exists (#1))) AND "+
+		"(contains (#2, :3)) AND (#3 >= :4) AND (contains (#4, :5))", *input.FilterExpression)
+	assert.Equal(t, map[string]*string{
+		"#0": aws.String("severity"),
+		"#1": aws.String("status"),
+		"#2": aws.String("logTypes"),
+		"#3": aws.String("eventCount"),
+		"#4": aws.String("title"),
+		"#5": aws.String("ruleId"),
+		"#6": aws.String("creationTime"),
+	}, input.ExpressionAttributeNames)
+	assert.Equal(t, "ruleId", *input.ExpressionAttributeValues[":6"].S)
+	assert.Equal(t, createdAfter.Format(time.RFC3339Nano), *input.ExpressionAttributeValues[":7"].S)
+	assert.True(t, *input.ScanIndexForward)
+	assert.Equal(t, int64(10), *input.Limit)
+}
+
+func TestListAllByUpdateTime(t *testing.T) {
+	client := &mockDynamoDB{}
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+	createdBefore := time.Date(2020, 1, 2, 6, 0, 0, 0, time.UTC)
+	_, _, err := listTable(client).ListAll(&models.ListAlertsInput{
+		RuleID:          aws.String("ruleId"),
+		CreatedAtBefore: aws.Time(createdBefore),
+		UpdatedAtAfter:  aws.Time(createdBefore.Add(-time.Hour)),
+		UpdatedAtBefore: aws.Time(createdBefore.Add(time.Hour)),
+		SortBy:          aws.String(models.SortByUpdateTime),
+	})
+	require.NoError(t, err)
+
+	client.AssertNumberOfCalls(t, "Query", TimePartitionShards+1)
+	input := client.Calls[0].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, "timePartitionUpdateTimeIndexName", *input.IndexName)
+	assert.Equal(t, "(#2 = :3) AND (#3 BETWEEN :4 AND :5)", *input.KeyConditionExpression)
+	// the retention limits the creation time
+	assert.Equal(t, "(#0 = :0) AND (#1 >= :1) AND (#1 <= :2)", *input.FilterExpression)
+	assert.Equal(t, map[string]*string{
+		"#0": aws.String("ruleId"),
+		"#1": aws.String("creationTime"),
+		"#2": aws.String("timePartition"),
+		"#3": aws.String("updateTime"),
+	}, input.ExpressionAttributeNames)
+	assert.Equal(t, "2020-01-01T12:00:00Z", *input.ExpressionAttributeValues[":1"].S)
+	assert.Equal(t, "shard0", *input.ExpressionAttributeValues[":3"].S)
+	assert.False(t, *input.ScanIndexForward)
+}
+
+// The queries of a partition continue until the page is full when the filters leave out alerts
+func TestListAllFillsPage(t *testing.T) {
+	client := &mockDynamoDB{}
+	first := &AlertItem{AlertID: "first", CreationTime: listNow.Add(-time.Minute), LogTypes: []string{"logtype"}}
+	second := &AlertItem{AlertID: "second", CreationTime: listNow.Add(-2 * time.Minute), LogTypes: []string{"logtype"}}
+	firstKey, err := dynamodbattribute.MarshalMap(first)
+	require.NoError(t, err)
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{
+		Items:            marshalAlerts(t, first),
+		LastEvaluatedKey: firstKey,
+	}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{Items: marshalAlerts(t, second)}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+	result, lastEvaluatedKey, err := listTable(client).ListAll(&models.ListAlertsInput{
+		PageSize: aws.Int(3),
+		Severity: aws.StringSlice([]string{"HIGH"}),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*AlertItem{first, second}, result)
+	assert.Nil(t, lastEvaluatedKey)
+	client.AssertNumberOfCalls(t, "Query", TimePartitionShards+2)
+	assert.Equal(t, "shard0", queriedPartition(t, client, 1))
+	assert.Equal(t, firstKey, client.Calls[1].Arguments.Get(0).(*dynamodb.QueryInput).ExclusiveStartKey)
+	assert.Equal(t, "shard1", queriedPartition(t, client, 2))
+}
+
+// The queries of a partition are bounded, the page ends where the partition was read
+func TestListAllPartitionQueryLimit(t *testing.T) {
+	client := &mockDynamoDB{}
+	alert := &AlertItem{AlertID: "alert", CreationTime: listNow.Add(-time.Hour), LogTypes: []string{"logtype"}}
+	filtered := &AlertItem{AlertID: "filtered", CreationTime: listNow.Add(-time.Minute)}
+	filteredKey, err := dynamodbattribute.MarshalMap(filtered)
+	require.NoError(t, err)
+	// the first shard only has alerts left out by the filters
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{LastEvaluatedKey: filteredKey}, nil).Times(maxPartitionQueries)
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{Items: marshalAlerts(t, alert)}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+	table := listTable(client)
+	input := table.withinRetention(&models.ListAlertsInput{
+		PageSize: aws.Int(10),
+		Severity: aws.StringSlice([]string{"HIGH"}),
+	})
+	index := table.TimePartitionCreationTimeIndexName
+	result, lastKey, err := table.listMerged(input, nil, index, models.SortByCreationTime)
+	require.NoError(t, err)
+	client.AssertNumberOfCalls(t, "Query", TimePartitionShards+maxPartitionQueries)
+	// the alert of the other shard is older than where the first shard stopped
+	assert.Empty(t, result)
+	assert.Equal(t, "filtered", *lastKey[AlertIDKey].S)
+	assert.Equal(t, "2020-01-03T11:59:00Z", *lastKey[models.SortByCreationTime].S)
+
+	// the next page continues from there
+	client = &mockDynamoDB{}
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{Items: marshalAlerts(t, alert)}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+	table.Client = client
+	result, lastKey, err = table.listMerged(input, lastKey, index, models.SortByCreationTime)
+	require.NoError(t, err)
+	assert.Equal(t, []*AlertItem{alert}, result)
+	assert.Nil(t, lastKey)
+	queryInput := client.Calls[0].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, "(#1 = :1) AND (#2 BETWEEN :2 AND :3)", *queryInput.KeyConditionExpression)
+	assert.Equal(t, "2020-01-03T11:59:00Z", *queryInput.ExpressionAttributeValues[":3"].S)
+}
+
+// The alerts of the partitions are merged in update time order
+func TestListMergesPartitions(t *testing.T) {
+	client := &mockDynamoDB{}
+	updateTime := time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)
+	first := &AlertItem{AlertID: "first", UpdateTime: updateTime.Add(2 * time.Minute), LogTypes: []string{"logtype"}}
+	second := &AlertItem{AlertID: "second", UpdateTime: updateTime.Add(time.Minute), LogTypes: []string{"logtype"}}
+	third := &AlertItem{AlertID: "third", UpdateTime: updateTime, LogTypes: []string{"logtype"}}
+	secondKey, err := dynamodbattribute.MarshalMap(&AlertItem{AlertID: "second", UpdateTime: second.UpdateTime})
+	require.NoError(t, err)
+	// the first shard has more alerts after the second
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{
+		Items:            marshalAlerts(t, second),
+		LastEvaluatedKey: secondKey,
+	}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{Items: marshalAlerts(t, first, third)}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+	table := listTable(client)
+	input := table.withinRetention(&models.ListAlertsInput{
+		PageSize: aws.Int(1),
+		SortBy:   aws.String(models.SortByUpdateTime),
+	})
+	result, lastKey, err := table.listMerged(input, nil, table.TimePartitionUpdateTimeIndexName, models.SortByUpdateTime)
+	require.NoError(t, err)
+	assert.Equal(t, []*AlertItem{first}, result)
+	client.AssertNumberOfCalls(t, "Query", TimePartitionShards+1)
+
+	// the next page starts after the first alert
+	client = &mockDynamoDB{}
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{Items: marshalAlerts(t, second)}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{Items: marshalAlerts(t, first, third)}, nil).Once()
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+	table.Client = client
+	input.PageSize = nil
+	result, lastKey, err = table.listMerged(input, lastKey, table.TimePartitionUpdateTimeIndexName, models.SortByUpdateTime)
+	require.NoError(t, err)
+	assert.Equal(t, []*AlertItem{second, third}, result)
+	assert.Nil(t, lastKey)
+	queryInput := client.Calls[0].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, first.UpdateTime.Format(time.RFC3339Nano), *queryInput.ExpressionAttributeValues[":2"].S)
+}
+
+func (m *mockDynamoDB) QueryPages(input *dynamodb.QueryInput, pageFunc func(*dynamodb.QueryOutput, bool) bool) error {
+	args := m.Called(input)
+	pageFunc(args.Get(0).(*dynamodb.QueryOutput), true)
+	return args.Error(1)
+}
+
+func TestListCreatedBetween(t *testing.T) {
+	client := &mockDynamoDB{}
+	alert := &AlertItem{AlertID: "alertId", RuleID: "ruleId", Severity: "INFO", LogTypes: []string{"logtype"}}
+	client.On("QueryPages", mock.Anything).Return(&dynamodb.QueryOutput{Items: marshalAlerts(t, alert)}, nil)
+
+	after := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)
+	result, err := listTable(client).ListCreatedBetween(after, before)
+	require.NoError(t, err)
+	assert.Len(t, result, TimePartitionShards+1)
+	client.AssertNumberOfCalls(t, "QueryPages", TimePartitionShards+1)
+	assert.Equal(t, "shard0", queriedPartition(t, client, 0))
+	assert.Equal(t, "defaultPartition", queriedPartition(t, client, TimePartitionShards))
+
+	input := client.Calls[0].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, "timePartitionCreationTimeIndexName", *input.IndexName)
+	assert.Equal(t, "(#0 = :0) AND (#1 BETWEEN :1 AND :2)", *input.KeyConditionExpression)
+	assert.Equal(t, "2020-01-02T00:00:00Z", *input.ExpressionAttributeValues[":1"].S)
+	assert.Equal(t, "2020-01-03T00:00:00Z", *input.ExpressionAttributeValues[":2"].S)
+	assert.NotNil(t, input.ProjectionExpression)
+}
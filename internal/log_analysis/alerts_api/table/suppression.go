@@ -0,0 +1,203 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/pkg/errors"
+
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+const (
+	SuppressionRuleIDKey = "ruleId"
+	SuppressionIDKey     = "id"
+)
+
+// SuppressionItem is a DDB representation of a suppression of the alerts of a rule
+type SuppressionItem struct {
+	RuleID        string    `json:"ruleId"`
+	SuppressionID string    `json:"id"`
+	DedupString   *string   `json:"dedup,omitempty"` // all alerts of the rule are suppressed if not set
+	Reason        string    `json:"reason"`
+	CreatedBy     string    `json:"createdBy"`
+	CreatedAt     time.Time `json:"createdAt"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+	ExpiredBy     *string   `json:"expiredBy,omitempty"`
+}
+
+// Active returns true if the suppression has not expired at the given time
+func (item *SuppressionItem) Active(now time.Time) bool {
+	return now.Before(item.ExpiresAt)
+}
+
+// Matches returns true if the suppression applies to alerts with the given dedup string
+func (item *SuppressionItem) Matches(dedupString string) bool {
+	return item.DedupString == nil || *item.DedupString == dedupString
+}
+
+// GetActiveSuppression returns a suppression of the rule matching the dedup string at the given time, if any.
+//
+// The alerts of rules and of scheduled queries are suppressed alike, ruleID is the ID of either.
+func (table *AlertsTable) GetActiveSuppression(ruleID, dedupString string, now time.Time) (*SuppressionItem, error) {
+	suppressions, err := table.ListSuppressions(&ruleID)
+	if err != nil {
+		return nil, err
+	}
+	for _, suppression := range suppressions {
+		if suppression.Active(now) && suppression.Matches(dedupString) {
+			return suppression, nil
+		}
+	}
+	return nil, nil
+}
+
+// PutSuppression stores a new suppression
+func (table *AlertsTable) PutSuppression(suppression *SuppressionItem) error {
+	item, err := dynamodbattribute.MarshalMap(suppression)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal suppression")
+	}
+	_, err = table.Client.PutItem(&dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(table.SuppressionsTableName),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to store suppression of rule %s", suppression.RuleID)
+	}
+	return nil
+}
+
+// ListSuppressions returns all the suppressions of a rule, or of all rules if ruleID is nil
+func (table *AlertsTable) ListSuppressions(ruleID *string) ([]*SuppressionItem, error) {
+	var suppressions []*SuppressionItem
+	var exclusiveStartKey DynamoItem
+	for {
+		var items []DynamoItem
+		var err error
+		if ruleID == nil {
+			items, exclusiveStartKey, err = table.scanSuppressions(exclusiveStartKey)
+		} else {
+			items, exclusiveStartKey, err = table.querySuppressions(*ruleID, exclusiveStartKey)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var page []*SuppressionItem
+		if err = dynamodbattribute.UnmarshalListOfMaps(items, &page); err != nil {
+			return nil, errors.Wrap(err, "UnmarshalListOfMaps() failed")
+		}
+		suppressions = append(suppressions, page...)
+
+		if len(exclusiveStartKey) == 0 {
+			return suppressions, nil
+		}
+	}
+}
+
+func (table *AlertsTable) querySuppressions(ruleID string, exclusiveStartKey DynamoItem) ([]DynamoItem, DynamoItem, error) {
+	keyCondition := expression.Key(SuppressionRuleIDKey).Equal(expression.Value(ruleID))
+	queryExpression, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to build expression")
+	}
+	output, err := table.Client.Query(&dynamodb.QueryInput{
+		ExclusiveStartKey:         exclusiveStartKey,
+		ExpressionAttributeNames:  queryExpression.Names(),
+		ExpressionAttributeValues: queryExpression.Values(),
+		KeyConditionExpression:    queryExpression.KeyCondition(),
+		TableName:                 aws.String(table.SuppressionsTableName),
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to query suppressions of rule %s", ruleID)
+	}
+	return output.Items, output.LastEvaluatedKey, nil
+}
+
+func (table *AlertsTable) scanSuppressions(exclusiveStartKey DynamoItem) ([]DynamoItem, DynamoItem, error) {
+	output, err := table.Client.Scan(&dynamodb.ScanInput{
+		ExclusiveStartKey: exclusiveStartKey,
+		TableName:         aws.String(table.SuppressionsTableName),
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to scan suppressions")
+	}
+	return output.Items, output.LastEvaluatedKey, nil
+}
+
+// ExpireSuppression ends an active suppression at the given time
+func (table *AlertsTable) ExpireSuppression(ruleID, suppressionID, userID string, expireTime time.Time) (*SuppressionItem, error) {
+	key := DynamoItem{
+		SuppressionRuleIDKey: {S: aws.String(ruleID)},
+		SuppressionIDKey:     {S: aws.String(suppressionID)},
+	}
+	output, err := table.Client.GetItem(&dynamodb.GetItemInput{
+		Key:       key,
+		TableName: aws.String(table.SuppressionsTableName),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get suppression %s", suppressionID)
+	}
+	if len(output.Item) == 0 {
+		return nil, &genericapi.DoesNotExistError{Message: "suppressionId=" + suppressionID + " does not exist"}
+	}
+	suppression := &SuppressionItem{}
+	if err = dynamodbattribute.UnmarshalMap(output.Item, suppression); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal suppression")
+	}
+	if !suppression.Active(expireTime) {
+		return nil, &genericapi.InvalidInputError{Message: "suppressionId=" + suppressionID + " has already expired"}
+	}
+
+	// The condition fails if the suppression was changed since we read it
+	update := expression.
+		Set(expression.Name("expiresAt"), expression.Value(expireTime)).
+		Set(expression.Name("expiredBy"), expression.Value(userID))
+	condition := expression.Name("expiresAt").Equal(expression.Value(suppression.ExpiresAt))
+	updateExpression, err := expression.NewBuilder().WithCondition(condition).WithUpdate(update).Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build update expression")
+	}
+	_, err = table.Client.UpdateItem(&dynamodb.UpdateItemInput{
+		ConditionExpression:       updateExpression.Condition(),
+		ExpressionAttributeNames:  updateExpression.Names(),
+		ExpressionAttributeValues: updateExpression.Values(),
+		Key:                       key,
+		TableName:                 aws.String(table.SuppressionsTableName),
+		UpdateExpression:          updateExpression.Update(),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil, &genericapi.InvalidInputError{
+				Message: "suppressionId=" + suppressionID + " was changed by another request, try again"}
+		}
+		return nil, errors.Wrapf(err, "failed to expire suppression %s", suppressionID)
+	}
+
+	suppression.ExpiresAt = expireTime
+	suppression.ExpiredBy = &userID
+	return suppression, nil
+}
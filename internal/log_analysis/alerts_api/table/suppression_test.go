@@ -0,0 +1,190 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+func (m *mockDynamoDB) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.ScanOutput), args.Error(1)
+}
+
+func (m *mockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.UpdateItemOutput), args.Error(1)
+}
+
+func suppressionTable(client *mockDynamoDB) *AlertsTable {
+	return &AlertsTable{
+		SuppressionsTableName: "suppressionsTableName",
+		Client:                client,
+	}
+}
+
+func testSuppression(t *testing.T, id string) (*SuppressionItem, DynamoItem) {
+	suppression := &SuppressionItem{
+		RuleID:        "ruleId",
+		SuppressionID: id,
+		Reason:        "reason",
+		CreatedBy:     "userId",
+		CreatedAt:     activityTime,
+		ExpiresAt:     activityTime.Add(time.Hour),
+	}
+	item, err := dynamodbattribute.MarshalMap(suppression)
+	require.NoError(t, err)
+	return suppression, item
+}
+
+func TestSuppressionMatches(t *testing.T) {
+	suppression, _ := testSuppression(t, "id")
+	assert.True(t, suppression.Matches("anything"))
+	assert.True(t, suppression.Active(activityTime))
+	assert.False(t, suppression.Active(suppression.ExpiresAt))
+
+	suppression.DedupString = aws.String("admin")
+	assert.True(t, suppression.Matches("admin"))
+	assert.False(t, suppression.Matches("root"))
+}
+
+func TestListSuppressionsQueriesAllPages(t *testing.T) {
+	client := &mockDynamoDB{}
+	first, firstItem := testSuppression(t, "first")
+	second, secondItem := testSuppression(t, "second")
+	lastKey := DynamoItem{"id": {S: aws.String("first")}, "ruleId": {S: aws.String("ruleId")}}
+	client.On("Query", mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return input.ExclusiveStartKey == nil
+	})).Return(&dynamodb.QueryOutput{Items: []DynamoItem{firstItem}, LastEvaluatedKey: lastKey}, nil).Once()
+	client.On("Query", mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return input.ExclusiveStartKey != nil
+	})).Return(&dynamodb.QueryOutput{Items: []DynamoItem{secondItem}}, nil).Once()
+
+	result, err := suppressionTable(client).ListSuppressions(aws.String("ruleId"))
+	require.NoError(t, err)
+	client.AssertExpectations(t)
+	assert.Equal(t, []*SuppressionItem{first, second}, result)
+
+	input := client.Calls[0].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, "suppressionsTableName", *input.TableName)
+	assert.Equal(t, "#0 = :0", *input.KeyConditionExpression)
+	assert.Equal(t, "ruleId", *input.ExpressionAttributeValues[":0"].S)
+}
+
+func TestListSuppressionsScansAllRules(t *testing.T) {
+	client := &mockDynamoDB{}
+	suppression, item := testSuppression(t, "id")
+	client.On("Scan", &dynamodb.ScanInput{TableName: aws.String("suppressionsTableName")}).
+		Return(&dynamodb.ScanOutput{Items: []DynamoItem{item}}, nil).Once()
+
+	result, err := suppressionTable(client).ListSuppressions(nil)
+	require.NoError(t, err)
+	client.AssertExpectations(t)
+	assert.Equal(t, []*SuppressionItem{suppression}, result)
+}
+
+func TestGetActiveSuppression(t *testing.T) {
+	client := &mockDynamoDB{}
+	expired, expiredItem := testSuppression(t, "expired")
+	expired.ExpiresAt = activityTime
+	expiredItem, err := dynamodbattribute.MarshalMap(expired)
+	require.NoError(t, err)
+	other, otherItem := testSuppression(t, "other")
+	other.DedupString = aws.String("other")
+	otherItem, err = dynamodbattribute.MarshalMap(other)
+	require.NoError(t, err)
+	matching, matchingItem := testSuppression(t, "matching")
+	client.On("Query", mock.Anything).Return(
+		&dynamodb.QueryOutput{Items: []DynamoItem{expiredItem, otherItem, matchingItem}}, nil).Once()
+
+	result, err := suppressionTable(client).GetActiveSuppression("ruleId", "dedup", activityTime.Add(time.Minute))
+	require.NoError(t, err)
+	client.AssertExpectations(t)
+	assert.Equal(t, matching, result)
+
+	// none active
+	client.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{Items: []DynamoItem{expiredItem}}, nil).Once()
+	result, err = suppressionTable(client).GetActiveSuppression("ruleId", "dedup", activityTime.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestExpireSuppression(t *testing.T) {
+	client := &mockDynamoDB{}
+	_, item := testSuppression(t, "id")
+	client.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{Item: item}, nil).Once()
+	client.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+
+	expireTime := activityTime.Add(time.Minute)
+	result, err := suppressionTable(client).ExpireSuppression("ruleId", "id", "expiringUser", expireTime)
+	require.NoError(t, err)
+	client.AssertExpectations(t)
+	assert.Equal(t, expireTime, result.ExpiresAt)
+	assert.Equal(t, "expiringUser", *result.ExpiredBy)
+
+	update := client.Calls[1].Arguments.Get(0).(*dynamodb.UpdateItemInput)
+	assert.Equal(t, "suppressionsTableName", *update.TableName)
+	assert.Equal(t, "ruleId", *update.Key["ruleId"].S)
+	assert.Equal(t, "id", *update.Key["id"].S)
+	assert.Equal(t, "#0 = :0", *update.ConditionExpression)
+	assert.Equal(t, item["expiresAt"], update.ExpressionAttributeValues[":0"])
+}
+
+func TestExpireSuppressionAlreadyExpired(t *testing.T) {
+	client := &mockDynamoDB{}
+	suppression, item := testSuppression(t, "id")
+	client.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{Item: item}, nil).Once()
+
+	_, err := suppressionTable(client).ExpireSuppression("ruleId", "id", "expiringUser", suppression.ExpiresAt)
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+	client.AssertExpectations(t)
+}
+
+// The suppression was expired by another request since it was read
+func TestExpireSuppressionChanged(t *testing.T) {
+	client := &mockDynamoDB{}
+	_, item := testSuppression(t, "id")
+	client.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{Item: item}, nil).Once()
+	client.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{},
+		awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil)).Once()
+
+	_, err := suppressionTable(client).ExpireSuppression("ruleId", "id", "expiringUser", activityTime.Add(time.Minute))
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+	client.AssertExpectations(t)
+}
+
+func TestExpireSuppressionDoesNotExist(t *testing.T) {
+	client := &mockDynamoDB{}
+	client.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{}, nil).Once()
+
+	_, err := suppressionTable(client).ExpireSuppression("ruleId", "id", "expiringUser", activityTime)
+	assert.IsType(t, &genericapi.DoesNotExistError{}, err)
+	client.AssertExpectations(t)
+}
@@ -20,24 +20,65 @@ package table
  */
 
 import (
+	"hash/fnv"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	deliverymodels "github.com/panther-labs/panther/internal/core/alert_delivery/models"
 )
 
 const (
-	RuleIDKey          = "ruleId"
-	AlertIDKey         = "id"
-	TimePartitionKey   = "timePartition"
-	TimePartitionValue = "defaultPartition"
+	RuleIDKey        = "ruleId"
+	AlertIDKey       = "id"
+	TimePartitionKey = "timePartition"
+	ExpiresAtKey     = "expiresAt"
+
+	// LegacyTimePartition is the partition of the alerts and incidents created before they were sharded
+	LegacyTimePartition = "defaultPartition"
+
+	// TimePartitionShards is the number of partitions of the time indexes, every page of alerts sorted by time reads
+	// all of them
+	TimePartitionShards = 8
 )
 
+// TimePartition returns the partition of the alert or incident in the time indexes, they are spread on a fixed number
+// of partitions so the time indexes are not a single hot partition
+func TimePartition(id string) string {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(id))
+	return "shard" + strconv.Itoa(int(hash.Sum32()%TimePartitionShards))
+}
+
+// TimePartitions returns all the partitions of the time indexes
+func TimePartitions() []string {
+	partitions := make([]string, 0, TimePartitionShards+1)
+	for i := 0; i < TimePartitionShards; i++ {
+		partitions = append(partitions, "shard"+strconv.Itoa(i))
+	}
+	return append(partitions, LegacyTimePartition)
+}
+
 // API defines the interface for the alerts table which can be used for mocking.
 type API interface {
 	GetAlert(*string) (*AlertItem, error)
-	ListByRule(string, *string, *int) ([]*AlertItem, *string, error)
-	ListAll(*string, *int) ([]*AlertItem, *string, error)
+	ListAll(*models.ListAlertsInput) ([]*AlertItem, *string, error)
+	ListCreatedBetween(after, before time.Time) ([]*AlertItem, error)
+	ListDeliveryAttempts(alertID string) ([]*deliverymodels.DeliveryAttempt, error)
+	UpdateStatus(alertID, status, userID string, updateTime time.Time) error
+	UpdateAssignee(alertID string, assigneeID *string, userID string, updateTime time.Time) error
+	AddComment(alertID, comment, userID string, createdAt time.Time) (*ActivityItem, error)
+	ListActivity(alertID string, exclusiveStartKey *string, pageSize *int) ([]*ActivityItem, *string, error)
+	PutSuppression(*SuppressionItem) error
+	ListSuppressions(ruleID *string) ([]*SuppressionItem, error)
+	ExpireSuppression(ruleID, suppressionID, userID string, expireTime time.Time) (*SuppressionItem, error)
+	GetIncident(incidentID string) (*IncidentItem, error)
+	ListIncidents(exclusiveStartKey *string, pageSize *int) ([]*IncidentItem, *string, error)
+	GetAlerts(alertIDs []string) ([]*AlertItem, error)
+	ListAlertEventObjects(alertID string, startAfter *string, limit int) ([]*AlertEventObject, error)
 }
 
 // AlertsTable encapsulates a connection to the Dynamo alerts table.
@@ -45,7 +86,18 @@ type AlertsTable struct {
 	AlertsTableName                    string
 	RuleIDCreationTimeIndexName        string
 	TimePartitionCreationTimeIndexName string
-	Client                             dynamodbiface.DynamoDBAPI
+	TimePartitionUpdateTimeIndexName   string
+	ActivityTableName                  string
+	SuppressionsTableName              string
+	IncidentsTableName                 string
+	IncidentsTimePartitionIndexName    string
+	AlertEventsTableName               string
+	DeliveriesTableName                string
+	// Alerts older than the retention are archived, they are not listed
+	RetentionDays int
+	Client        dynamodbiface.DynamoDBAPI
+
+	now func() time.Time // for testing
 }
 
 // The AlertsTable must satisfy the API interface.
@@ -67,4 +119,24 @@ type AlertItem struct {
 	Severity        string    `json:"severity"`
 	EventCount      int       `json:"eventCount"`
 	LogTypes        []string  `json:"logTypes"`
+	// The first matched events of rule alerts as JSON, delivered again when the alert is resent
+	EventSamples []string `json:"eventSamples,omitempty"`
+	// Triage fields, older alerts have no status
+	Status            string     `json:"status,omitempty"`
+	AssigneeID        *string    `json:"assigneeId,omitempty"`
+	LastUpdatedBy     *string    `json:"lastUpdatedBy,omitempty"`
+	LastUpdatedByTime *time.Time `json:"lastUpdatedByTime,omitempty"`
+	// Set when the alert is closed or marked as false positive, removed when it is reopened
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	// Set by the alert forwarder if a suppression matched the alert
+	SuppressionID *string `json:"suppressionId,omitempty"`
+	// Set by the alert forwarder if the alert was correlated into an incident
+	IncidentID *string `json:"incidentId,omitempty"`
+}
+
+func (table *AlertsTable) currentTime() time.Time {
+	if table.now != nil {
+		return table.now()
+	}
+	return time.Now().UTC()
 }
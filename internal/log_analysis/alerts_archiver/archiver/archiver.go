@@ -0,0 +1,362 @@
+package archiver
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/api/lambda/alerts/models"
+	alertstable "github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers/timestamp"
+)
+
+const (
+	// the number of alerts read, archived and expired at a time
+	pageSize = 500
+	// the time left to finish the page being archived when Archive stops
+	deadlineMargin = time.Minute
+)
+
+// Archiver moves the alerts older than the retention from the alerts table to the processed data bucket
+type Archiver struct {
+	AlertsTableName        string
+	TimeIndexName          string
+	ActivityTableName      string
+	AlertEventsTableName   string
+	DeliveriesTableName    string
+	IncidentsTableName     string
+	IncidentsTimeIndexName string
+	RetentionDays          int
+	Bucket                 string
+	DynamoClient           dynamodbiface.DynamoDBAPI
+	Uploader               s3manageriface.UploaderAPI
+}
+
+// Archive writes the alerts created before the day of the retention to S3 with their triage activity, one object per
+// alert in the partition of its creation day, and expires them in the alerts table with their activity, the index of
+// their events and their delivery attempts. The incidents of archived alerts are expired as well.
+// DynamoDB deletes expired items through TTL.
+//
+// Each page is expired right after it is uploaded. The object key is made of the alert ID, so a run retrying an alert
+// which could not be expired overwrites its object. Archive stops before the deadline of the context, the next run
+// archives the remaining alerts.
+func (a *Archiver) Archive(ctx context.Context, now time.Time) error {
+	oldest := now.AddDate(0, 0, -a.RetentionDays).UTC()
+	before := time.Date(oldest.Year(), oldest.Month(), oldest.Day(), 0, 0, 0, 0, time.UTC)
+
+	for _, partition := range alertstable.TimePartitions() {
+		queryInput, err := a.queryInput(partition, before)
+		if err != nil {
+			return err
+		}
+		count := 0
+		for {
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < deadlineMargin {
+				zap.L().Warn("stopped archiving before the deadline",
+					zap.String("partition", partition), zap.Int("count", count))
+				return nil
+			}
+			output, err := a.DynamoClient.Query(queryInput)
+			if err != nil {
+				return errors.Wrapf(err, "failed to query alerts of partition %s", partition)
+			}
+			var alerts []*alertstable.AlertItem
+			if err = dynamodbattribute.UnmarshalListOfMaps(output.Items, &alerts); err != nil {
+				return errors.Wrap(err, "UnmarshalListOfMaps() failed")
+			}
+			if err = a.archive(alerts, now); err != nil {
+				return err
+			}
+			count += len(alerts)
+			if len(output.LastEvaluatedKey) == 0 {
+				break
+			}
+			queryInput.ExclusiveStartKey = output.LastEvaluatedKey
+		}
+		if count > 0 {
+			zap.L().Info("archived alerts", zap.String("partition", partition), zap.Int("count", count))
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < deadlineMargin {
+		zap.L().Warn("stopped archiving before expiring incidents")
+		return nil
+	}
+	return a.expireIncidents(before, now)
+}
+
+// expireIncidents expires the incidents whose alerts are all archived: an incident is updated when an alert joins it
+func (a *Archiver) expireIncidents(before, now time.Time) error {
+	var incidentIDs []string
+	for _, partition := range alertstable.TimePartitions() {
+		keyCondition := expression.Key(alertstable.TimePartitionKey).Equal(expression.Value(partition)).
+			And(expression.Key("creationTime").LessThan(expression.Value(before)))
+		filter := expression.Name("updateTime").LessThan(expression.Value(before)).
+			And(expression.AttributeNotExists(expression.Name(alertstable.ExpiresAtKey)))
+		queryExpression, err := expression.NewBuilder().WithKeyCondition(keyCondition).WithFilter(filter).Build()
+		if err != nil {
+			return errors.Wrap(err, "failed to build expression")
+		}
+
+		err = a.DynamoClient.QueryPages(&dynamodb.QueryInput{
+			ExpressionAttributeNames:  queryExpression.Names(),
+			ExpressionAttributeValues: queryExpression.Values(),
+			FilterExpression:          queryExpression.Filter(),
+			IndexName:                 aws.String(a.IncidentsTimeIndexName),
+			KeyConditionExpression:    queryExpression.KeyCondition(),
+			TableName:                 aws.String(a.IncidentsTableName),
+		}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+			for _, item := range page.Items {
+				incidentIDs = append(incidentIDs, aws.StringValue(item[alertstable.IncidentIDKey].S))
+			}
+			return true
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to query incidents of partition %s", partition)
+		}
+	}
+
+	for _, incidentID := range incidentIDs {
+		key := map[string]*dynamodb.AttributeValue{alertstable.IncidentIDKey: {S: aws.String(incidentID)}}
+		if err := a.expireItem(a.IncidentsTableName, alertstable.IncidentIDKey, key, now); err != nil {
+			return err
+		}
+	}
+	if len(incidentIDs) > 0 {
+		zap.L().Info("expired incidents", zap.Int("count", len(incidentIDs)))
+	}
+	return nil
+}
+
+// queryInput returns the query of the alerts of the partition created before the time which are not archived yet
+func (a *Archiver) queryInput(partition string, before time.Time) (*dynamodb.QueryInput, error) {
+	keyCondition := expression.Key(alertstable.TimePartitionKey).Equal(expression.Value(partition)).
+		And(expression.Key("creationTime").LessThan(expression.Value(before)))
+	// archived alerts are kept until DynamoDB deletes them
+	filter := expression.AttributeNotExists(expression.Name(alertstable.ExpiresAtKey))
+	queryExpression, err := expression.NewBuilder().WithKeyCondition(keyCondition).WithFilter(filter).Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build expression")
+	}
+
+	return &dynamodb.QueryInput{
+		ExpressionAttributeNames:  queryExpression.Names(),
+		ExpressionAttributeValues: queryExpression.Values(),
+		FilterExpression:          queryExpression.Filter(),
+		IndexName:                 aws.String(a.TimeIndexName),
+		KeyConditionExpression:    queryExpression.KeyCondition(),
+		Limit:                     aws.Int64(pageSize),
+		TableName:                 aws.String(a.AlertsTableName),
+	}, nil
+}
+
+// archive uploads each alert of a page to the partition of its creation day, then expires them
+func (a *Archiver) archive(alerts []*alertstable.AlertItem, now time.Time) error {
+	for _, alert := range alerts {
+		archived := newArchivedAlert(alert)
+		activity, err := a.listActivity(alert.AlertID)
+		if err != nil {
+			return err
+		}
+		for _, item := range activity {
+			archived.Activity = append(archived.Activity, newArchivedActivity(item))
+		}
+		body, err := marshalAlerts([]*models.ArchivedAlert{archived})
+		if err != nil {
+			return err
+		}
+		key := models.AlertsTable.GetPartitionPrefix(alert.CreationTime.UTC()) + alert.AlertID + ".json.gz"
+		_, err = a.Uploader.Upload(&s3manager.UploadInput{
+			Bucket: aws.String(a.Bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(body),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to upload %s", key)
+		}
+	}
+
+	// with the items of the alert in the other tables first, the alert is archived again if they could not be expired
+	for _, alert := range alerts {
+		if err := a.expireAlertItems(alert.AlertID, now); err != nil {
+			return err
+		}
+		key := map[string]*dynamodb.AttributeValue{alertstable.AlertIDKey: {S: aws.String(alert.AlertID)}}
+		if err := a.expireItem(a.AlertsTableName, alertstable.AlertIDKey, key, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listActivity returns the activity of an alert, oldest first
+func (a *Archiver) listActivity(alertID string) ([]*alertstable.ActivityItem, error) {
+	activityTable := &alertstable.AlertsTable{ActivityTableName: a.ActivityTableName, Client: a.DynamoClient}
+	var activity []*alertstable.ActivityItem
+	var exclusiveStartKey *string
+	for {
+		page, lastEvaluatedKey, err := activityTable.ListActivity(alertID, exclusiveStartKey, nil)
+		if err != nil {
+			return nil, err
+		}
+		activity = append(activity, page...)
+		if lastEvaluatedKey == nil {
+			return activity, nil
+		}
+		exclusiveStartKey = lastEvaluatedKey
+	}
+}
+
+// expireAlertItems expires the activity, the event objects index and the delivery attempts of an alert
+func (a *Archiver) expireAlertItems(alertID string, now time.Time) error {
+	tables := []struct{ name, hashKey, rangeKey string }{
+		{a.ActivityTableName, alertstable.ActivityAlertIDKey, alertstable.ActivityIDKey},
+		{a.AlertEventsTableName, alertstable.AlertEventsAlertIDKey, alertstable.AlertEventsObjectKeyKey},
+		{a.DeliveriesTableName, alertstable.DeliveriesAlertIDKey, alertstable.DeliveriesIDKey},
+	}
+	for _, table := range tables {
+		keyCondition := expression.Key(table.hashKey).Equal(expression.Value(alertID))
+		filter := expression.AttributeNotExists(expression.Name(alertstable.ExpiresAtKey))
+		projection := expression.NamesList(expression.Name(table.hashKey), expression.Name(table.rangeKey))
+		queryExpression, err := expression.NewBuilder().
+			WithKeyCondition(keyCondition).
+			WithFilter(filter).
+			WithProjection(projection).
+			Build()
+		if err != nil {
+			return errors.Wrap(err, "failed to build expression")
+		}
+
+		var keys []map[string]*dynamodb.AttributeValue
+		err = a.DynamoClient.QueryPages(&dynamodb.QueryInput{
+			ExpressionAttributeNames:  queryExpression.Names(),
+			ExpressionAttributeValues: queryExpression.Values(),
+			FilterExpression:          queryExpression.Filter(),
+			KeyConditionExpression:    queryExpression.KeyCondition(),
+			ProjectionExpression:      queryExpression.Projection(),
+			TableName:                 aws.String(table.name),
+		}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+			keys = append(keys, page.Items...)
+			return true
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to query %s of alert %s", table.name, alertID)
+		}
+		for _, key := range keys {
+			if err := a.expireItem(table.name, table.hashKey, key, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expireItem sets the TTL attribute of an existing item
+func (a *Archiver) expireItem(tableName, hashKey string, key map[string]*dynamodb.AttributeValue, now time.Time) error {
+	update := expression.Set(expression.Name(alertstable.ExpiresAtKey), expression.Value(now.Unix()))
+	condition := expression.AttributeExists(expression.Name(hashKey))
+	updateExpression, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return errors.Wrap(err, "failed to build expression")
+	}
+	_, err = a.DynamoClient.UpdateItem(&dynamodb.UpdateItemInput{
+		ConditionExpression:       updateExpression.Condition(),
+		ExpressionAttributeNames:  updateExpression.Names(),
+		ExpressionAttributeValues: updateExpression.Values(),
+		Key:                       key,
+		TableName:                 aws.String(tableName),
+		UpdateExpression:          updateExpression.Update(),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to expire item of %s", tableName)
+	}
+	return nil
+}
+
+// marshalAlerts returns the alerts as gzip'd JSON lines
+func marshalAlerts(alerts []*models.ArchivedAlert) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	for _, alert := range alerts {
+		line, err := jsoniter.Marshal(alert)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal alert "+alert.AlertID)
+		}
+		if _, err = writer.Write(append(line, '\n')); err != nil {
+			return nil, errors.Wrap(err, "failed to compress alerts")
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to compress alerts")
+	}
+	return buffer.Bytes(), nil
+}
+
+func newArchivedAlert(alert *alertstable.AlertItem) *models.ArchivedAlert {
+	archived := &models.ArchivedAlert{
+		AlertID:         alert.AlertID,
+		RuleID:          alert.RuleID,
+		RuleVersion:     alert.RuleVersion,
+		RuleDisplayName: alert.RuleDisplayName,
+		Title:           alert.Title,
+		DedupString:     alert.DedupString,
+		CreationTime:    (*timestamp.RFC3339)(&alert.CreationTime),
+		UpdateTime:      (*timestamp.RFC3339)(&alert.UpdateTime),
+		Severity:        alert.Severity,
+		EventCount:      alert.EventCount,
+		LogTypes:        alert.LogTypes,
+		Status:          alert.Status,
+		AssigneeID:      alert.AssigneeID,
+		LastUpdatedBy:   alert.LastUpdatedBy,
+		SuppressionID:   alert.SuppressionID,
+		IncidentID:      alert.IncidentID,
+	}
+	if alert.LastUpdatedByTime != nil {
+		archived.LastUpdatedByTime = (*timestamp.RFC3339)(alert.LastUpdatedByTime)
+	}
+	if alert.ResolvedAt != nil {
+		archived.ResolvedAt = (*timestamp.RFC3339)(alert.ResolvedAt)
+	}
+	return archived
+}
+
+func newArchivedActivity(activity *alertstable.ActivityItem) models.ArchivedActivity {
+	return models.ArchivedActivity{
+		Type:       activity.Type,
+		UserID:     activity.UserID,
+		CreatedAt:  (*timestamp.RFC3339)(&activity.CreatedAt),
+		Status:     activity.Status,
+		AssigneeID: activity.AssigneeID,
+		Comment:    activity.Comment,
+	}
+}
@@ -0,0 +1,293 @@
+package archiver
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	alertstable "github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/pkg/testutils"
+)
+
+type mockUploader struct {
+	s3manageriface.UploaderAPI
+	mock.Mock
+}
+
+func (m *mockUploader) Upload(input *s3manager.UploadInput, f ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*s3manager.UploadOutput), args.Error(1)
+}
+
+// inPartition matches the queries of a time partition
+func inPartition(partition string) interface{} {
+	return mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return *input.ExpressionAttributeValues[":0"].S == partition
+	})
+}
+
+func queryOutput(t *testing.T, alert *alertstable.AlertItem) *dynamodb.QueryOutput {
+	item, err := dynamodbattribute.MarshalMap(alert)
+	require.NoError(t, err)
+	return &dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{item}}
+}
+
+// tableCalls returns the inputs of the calls of a method on a table
+func tableCalls(client *testutils.DynamoDBMock, method, table string) (inputs []interface{}) {
+	for _, call := range client.Calls {
+		if call.Method != method {
+			continue
+		}
+		var tableName *string
+		switch input := call.Arguments.Get(0).(type) {
+		case *dynamodb.QueryInput:
+			tableName = input.TableName
+		case *dynamodb.UpdateItemInput:
+			tableName = input.TableName
+		}
+		if aws.StringValue(tableName) == table {
+			inputs = append(inputs, call.Arguments.Get(0))
+		}
+	}
+	return inputs
+}
+
+// onTable matches the inputs of a table, and of an alert if alertID is set
+func onTable(table, alertID string) interface{} {
+	return mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return *input.TableName == table && (alertID == "" || *input.ExpressionAttributeValues[":0"].S == alertID)
+	})
+}
+
+func testArchiver(dynamoClient *testutils.DynamoDBMock, uploader *mockUploader) *Archiver {
+	return &Archiver{
+		AlertsTableName:        "alertsTable",
+		TimeIndexName:          "timeIndex",
+		ActivityTableName:      "activityTable",
+		AlertEventsTableName:   "eventsTable",
+		DeliveriesTableName:    "deliveriesTable",
+		IncidentsTableName:     "incidentsTable",
+		IncidentsTimeIndexName: "incidentsIndex",
+		RetentionDays:          30,
+		Bucket:                 "bucket",
+		DynamoClient:           dynamoClient,
+		Uploader:               uploader,
+	}
+}
+
+func gunzip(t *testing.T, input *s3manager.UploadInput) string {
+	reader, err := gzip.NewReader(input.Body)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestArchive(t *testing.T) {
+	now := time.Date(2020, 5, 10, 12, 0, 0, 0, time.UTC)
+	legacyAlert := &alertstable.AlertItem{
+		AlertID:      "legacy",
+		RuleID:       "ruleId",
+		CreationTime: time.Date(2019, 1, 5, 10, 0, 0, 0, time.UTC),
+		UpdateTime:   time.Date(2019, 1, 5, 11, 0, 0, 0, time.UTC),
+		Severity:     "HIGH",
+		EventCount:   1,
+		LogTypes:     []string{"AWS.CloudTrail"},
+	}
+	alert := &alertstable.AlertItem{
+		AlertID:      "alertId",
+		RuleID:       "ruleId",
+		CreationTime: time.Date(2020, 4, 9, 3, 0, 0, 0, time.UTC),
+		UpdateTime:   time.Date(2020, 4, 9, 3, 0, 0, 0, time.UTC),
+		Severity:     "INFO",
+		EventCount:   2,
+		LogTypes:     []string{"AWS.CloudTrail"},
+		Status:       "CLOSED",
+	}
+
+	activity, err := dynamodbattribute.MarshalMap(&alertstable.ActivityItem{
+		AlertID:    "alertId",
+		ActivityID: "activityId",
+		Type:       "COMMENT",
+		UserID:     "userId",
+		CreatedAt:  time.Date(2020, 4, 9, 4, 0, 0, 0, time.UTC),
+		Comment:    aws.String("false positive"),
+	})
+	require.NoError(t, err)
+	eventObjectKey := map[string]*dynamodb.AttributeValue{
+		"alertId":   {S: aws.String("alertId")},
+		"objectKey": {S: aws.String("rules/key.json.gz")},
+	}
+	incidentKey := map[string]*dynamodb.AttributeValue{"id": {S: aws.String("incidentId")}}
+
+	dynamoClient := &testutils.DynamoDBMock{}
+	dynamoClient.On("Query", inPartition("defaultPartition")).Return(queryOutput(t, legacyAlert), nil)
+	dynamoClient.On("Query", inPartition(alertstable.TimePartition("alertId"))).Return(queryOutput(t, alert), nil)
+	dynamoClient.On("Query", onTable("activityTable", "alertId")).Return(
+		&dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{activity}}, nil)
+	dynamoClient.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+	dynamoClient.On("QueryPages", onTable("eventsTable", "alertId")).Return(
+		&dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{eventObjectKey}}, nil)
+	dynamoClient.On("QueryPages", mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return *input.TableName == "incidentsTable" &&
+			strings.Contains(input.String(), `"`+alertstable.TimePartition("incidentId")+`"`)
+	})).Return(
+		&dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{incidentKey}}, nil)
+	dynamoClient.On("QueryPages", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+	dynamoClient.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil)
+	uploader := &mockUploader{}
+	uploader.On("Upload", mock.Anything).Return(&s3manager.UploadOutput{}, nil)
+
+	require.NoError(t, testArchiver(dynamoClient, uploader).Archive(context.Background(), now))
+
+	// every shard and the legacy partition
+	alertQueries := tableCalls(dynamoClient, "Query", "alertsTable")
+	require.Len(t, alertQueries, alertstable.TimePartitionShards+1)
+	query := alertQueries[len(alertQueries)-1].(*dynamodb.QueryInput)
+	assert.Equal(t, "timeIndex", *query.IndexName)
+	assert.Equal(t, "defaultPartition", *query.ExpressionAttributeValues[":0"].S)
+	assert.Equal(t, "2020-04-10T00:00:00Z", *query.ExpressionAttributeValues[":1"].S)
+	assert.Equal(t, "attribute_not_exists (#0)", *query.FilterExpression)
+	assert.Equal(t, int64(pageSize), *query.Limit)
+
+	uploader.AssertNumberOfCalls(t, "Upload", 2)
+	legacyUpload := uploader.Calls[1].Arguments.Get(0).(*s3manager.UploadInput)
+	assert.Equal(t, "bucket", *legacyUpload.Bucket)
+	assert.Equal(t, "alerts/alerts/year=2019/month=01/day=05/legacy.json.gz", *legacyUpload.Key)
+	upload := uploader.Calls[0].Arguments.Get(0).(*s3manager.UploadInput)
+	assert.Equal(t, "alerts/alerts/year=2020/month=04/day=09/alertId.json.gz", *upload.Key)
+	lines := gunzip(t, upload)
+	assert.True(t, strings.HasSuffix(lines, "\n"))
+	assert.JSONEq(t, `{
+		"id": "alertId",
+		"ruleId": "ruleId",
+		"ruleVersion": "",
+		"dedup": "",
+		"creationTime": "2020-04-09 03:00:00.000000000",
+		"updateTime": "2020-04-09 03:00:00.000000000",
+		"severity": "INFO",
+		"eventCount": 2,
+		"logTypes": ["AWS.CloudTrail"],
+		"status": "CLOSED",
+		"activity": [{
+			"type": "COMMENT",
+			"userId": "userId",
+			"createdAt": "2020-04-09 04:00:00.000000000",
+			"comment": "false positive"
+		}]
+	}`, lines)
+
+	// the archived alerts are expired
+	alertUpdates := tableCalls(dynamoClient, "UpdateItem", "alertsTable")
+	require.Len(t, alertUpdates, 2)
+	update := alertUpdates[0].(*dynamodb.UpdateItemInput)
+	assert.Equal(t, "alertId", *update.Key["id"].S) // the shards are archived before the legacy partition
+	assert.Equal(t, strconv.FormatInt(now.Unix(), 10), *update.ExpressionAttributeValues[":0"].N)
+	assert.Equal(t, aws.String("expiresAt"), update.ExpressionAttributeNames["#1"])
+
+	// with their items in the other tables
+	for _, table := range []string{"activityTable", "eventsTable", "deliveriesTable"} {
+		queries := tableCalls(dynamoClient, "QueryPages", table)
+		require.Len(t, queries, 2, table)
+		assert.Equal(t, "attribute_not_exists (#0)", *queries[0].(*dynamodb.QueryInput).FilterExpression)
+	}
+	eventUpdates := tableCalls(dynamoClient, "UpdateItem", "eventsTable")
+	require.Len(t, eventUpdates, 1)
+	assert.Equal(t, eventObjectKey, eventUpdates[0].(*dynamodb.UpdateItemInput).Key)
+	assert.Empty(t, tableCalls(dynamoClient, "UpdateItem", "activityTable")) // already expired or no activity
+
+	// and the incidents of archived alerts
+	incidentQueries := tableCalls(dynamoClient, "QueryPages", "incidentsTable")
+	require.Len(t, incidentQueries, alertstable.TimePartitionShards+1)
+	incidentQuery := incidentQueries[0].(*dynamodb.QueryInput)
+	assert.Equal(t, "incidentsIndex", *incidentQuery.IndexName)
+	assert.Contains(t, *incidentQuery.KeyConditionExpression, "<")
+	assert.Contains(t, *incidentQuery.FilterExpression, "<") // the alerts of the incident are all archived
+	incidentUpdates := tableCalls(dynamoClient, "UpdateItem", "incidentsTable")
+	require.Len(t, incidentUpdates, 1)
+	assert.Equal(t, incidentKey, incidentUpdates[0].(*dynamodb.UpdateItemInput).Key)
+}
+
+func TestArchivePages(t *testing.T) {
+	now := time.Date(2020, 5, 10, 12, 0, 0, 0, time.UTC)
+	first := queryOutput(t, &alertstable.AlertItem{AlertID: "first", CreationTime: time.Date(2020, 4, 8, 3, 0, 0, 0, time.UTC)})
+	first.LastEvaluatedKey = first.Items[0]
+	second := queryOutput(t, &alertstable.AlertItem{AlertID: "second", CreationTime: time.Date(2020, 4, 8, 4, 0, 0, 0, time.UTC)})
+
+	dynamoClient := &testutils.DynamoDBMock{}
+	dynamoClient.On("Query", mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return *input.ExpressionAttributeValues[":0"].S == "defaultPartition" && input.ExclusiveStartKey == nil
+	})).Return(first, nil).Once()
+	dynamoClient.On("Query", inPartition("defaultPartition")).Return(second, nil).Once()
+	dynamoClient.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+	dynamoClient.On("QueryPages", mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+	dynamoClient.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil)
+	uploader := &mockUploader{}
+	uploader.On("Upload", mock.Anything).Return(&s3manager.UploadOutput{}, nil)
+
+	require.NoError(t, testArchiver(dynamoClient, uploader).Archive(context.Background(), now))
+
+	// every alert is uploaded to its own object and expired before the next page is read
+	assert.Len(t, tableCalls(dynamoClient, "Query", "alertsTable"), alertstable.TimePartitionShards+2)
+	uploader.AssertNumberOfCalls(t, "Upload", 2)
+	prefix := "alerts/alerts/year=2020/month=04/day=08/"
+	assert.Equal(t, prefix+"first.json.gz", *uploader.Calls[0].Arguments.Get(0).(*s3manager.UploadInput).Key)
+	assert.Equal(t, prefix+"second.json.gz", *uploader.Calls[1].Arguments.Get(0).(*s3manager.UploadInput).Key)
+	var methods []string
+	for _, call := range dynamoClient.Calls {
+		switch input := call.Arguments.Get(0).(type) {
+		case *dynamodb.QueryInput:
+			if *input.TableName == "alertsTable" && *input.ExpressionAttributeValues[":0"].S == "defaultPartition" {
+				methods = append(methods, call.Method)
+			}
+		case *dynamodb.UpdateItemInput:
+			if *input.TableName == "alertsTable" {
+				methods = append(methods, call.Method)
+			}
+		}
+	}
+	assert.Equal(t, []string{"Query", "UpdateItem", "Query", "UpdateItem"}, methods)
+}
+
+func TestArchiveDeadline(t *testing.T) {
+	dynamoClient := &testutils.DynamoDBMock{}
+	uploader := &mockUploader{}
+	ctx, cancel := context.WithTimeout(context.Background(), deadlineMargin/2)
+	defer cancel()
+
+	require.NoError(t, testArchiver(dynamoClient, uploader).Archive(ctx, time.Now()))
+	dynamoClient.AssertNotCalled(t, "Query", mock.Anything)
+	dynamoClient.AssertNotCalled(t, "QueryPages", mock.Anything)
+	uploader.AssertNotCalled(t, "Upload", mock.Anything)
+}
@@ -0,0 +1,82 @@
+package main
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/kelseyhightower/envconfig"
+
+	"github.com/panther-labs/panther/internal/log_analysis/alerts_archiver/archiver"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
+	"github.com/panther-labs/panther/pkg/lambdalogger"
+)
+
+type envConfig struct {
+	AlertsTableName      string `required:"true" split_words:"true"`
+	TimeIndexName        string `required:"true" split_words:"true"`
+	ActivityTableName    string `required:"true" split_words:"true"`
+	AlertEventsTableName string `required:"true" split_words:"true"`
+	DeliveriesTableName  string `required:"true" split_words:"true"`
+	IncidentsTableName   string `required:"true" split_words:"true"`
+	IncidentsIndexName   string `required:"true" split_words:"true"`
+	AlertRetentionDays   int    `required:"true" split_words:"true"`
+	ProcessedDataBucket  string `required:"true" split_words:"true"`
+}
+
+var alertsArchiver *archiver.Archiver
+
+func main() {
+	var env envConfig
+	envconfig.MustProcess("", &env)
+
+	awsSession := session.Must(session.NewSession())
+	alertsArchiver = &archiver.Archiver{
+		AlertsTableName:        env.AlertsTableName,
+		TimeIndexName:          env.TimeIndexName,
+		ActivityTableName:      env.ActivityTableName,
+		AlertEventsTableName:   env.AlertEventsTableName,
+		DeliveriesTableName:    env.DeliveriesTableName,
+		IncidentsTableName:     env.IncidentsTableName,
+		IncidentsTimeIndexName: env.IncidentsIndexName,
+		RetentionDays:          env.AlertRetentionDays,
+		Bucket:                 env.ProcessedDataBucket,
+		DynamoClient:           dynamodb.New(awsSession),
+		Uploader:               s3manager.NewUploader(awsSession),
+	}
+	lambda.Start(handle)
+}
+
+// handle is invoked every day by a CloudWatch schedule
+func handle(ctx context.Context, _ events.CloudWatchEvent) (err error) {
+	lc, _ := lambdalogger.ConfigureGlobal(ctx, nil)
+	operation := common.OpLogManager.Start(lc.InvokedFunctionArn, common.OpLogLambdaServiceDim).WithMemUsed(lambdacontext.MemoryLimitInMB)
+	defer func() {
+		operation.Stop().Log(err)
+	}()
+
+	return alertsArchiver.Archive(ctx, time.Now().UTC())
+}
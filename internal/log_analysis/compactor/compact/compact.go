@@ -0,0 +1,532 @@
+package compact
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/glue/glueiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/internal/log_analysis/partitionlock"
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+const (
+	// Compacted partitions are written as "generations" under the awsglue.CompactedPrefix of the table, outside of the
+	// partition prefixes so the log processor keeps writing (late) data where it always does.
+	// Swapping the Glue partition location to a new generation is atomic for queries.
+	// lists the log processor objects merged into a generation, written next to the generation
+	manifestSuffix = ".manifest.json"
+
+	// the (compressed) size we aim for when merging objects, larger objects are copied as is
+	targetObjectBytes = 128 * 1024 * 1024
+	// partitions with fewer small objects are left alone
+	minObjectsToCompact = 2
+
+	deleteBatchSize = 1000 // the most keys DeleteObjects takes
+
+	// lockOwner owns the partition locks taken by the compactor
+	lockOwner = "panther-log-compactor"
+)
+
+// Compactor merges the many small objects the log processor writes in a partition into a few large ones
+type Compactor struct {
+	GlueClient glueiface.GlueAPI
+	S3Client   s3iface.S3API
+	Uploader   s3manageriface.UploaderAPI
+	// Locks keep the data deletion out of the partitions being compacted
+	Locks *partitionlock.Locker
+}
+
+// Stats about the compaction of a partition
+type Stats struct {
+	InputObjects  int
+	InputBytes    int64
+	OutputObjects int
+	OutputBytes   int64
+}
+
+// manifest records the log processor objects merged into a generation so they are never merged twice,
+// even if deleting them failed
+type manifest struct {
+	Sources []string `json:"sources"`
+}
+
+// Compact merges the objects of the partition of the table holding hour into a new generation and swaps the
+// partition to it. The partition must be past the lateness horizon. Data arriving later is appended to the
+// generation as is, the generation is never rewritten.
+// Returns nil stats if there was nothing to do. Tables using partition projection are never compacted because
+// Athena ignores their partition locations.
+// A partition locked by a running data deletion is not compacted, an error is returned so it is retried.
+func (c *Compactor) Compact(table *awsglue.GlueTableMetadata, hour time.Time) (*Stats, error) {
+	projected, err := table.IsPartitionProjected(c.GlueClient)
+	if err != nil || projected {
+		return nil, err
+	}
+	partition, err := table.GetPartition(c.GlueClient, hour)
+	if err != nil || partition == nil { // no partition, no data
+		return nil, err
+	}
+	bucket, currentPrefix, err := parseLocation(aws.StringValue(partition.StorageDescriptor.Location))
+	if err != nil {
+		return nil, err
+	}
+	logPrefix := table.GetPartitionPrefix(hour)
+	generationsPrefix := table.GetCompactedPartitionPrefix(hour)
+
+	locked, err := c.Locks.Acquire(logPrefix, lockOwner)
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, errors.Errorf("partition %s is locked by a data deletion", logPrefix)
+	}
+	defer func() {
+		if err := c.Locks.Release(logPrefix, lockOwner); err != nil {
+			zap.L().Warn("failed to unlock partition", zap.String("prefix", logPrefix), zap.Error(err))
+		}
+	}()
+
+	rawObjects, err := c.listObjects(bucket, logPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if currentPrefix != logPrefix {
+		return c.appendLateObjects(bucket, currentPrefix, rawObjects)
+	}
+
+	// large objects are copied as is
+	toMerge, toCopy := partitionObjects(rawObjects, func(object *s3.Object) bool {
+		return aws.Int64Value(object.Size) < targetObjectBytes/2
+	})
+	if len(toMerge) < minObjectsToCompact {
+		return nil, nil
+	}
+
+	generation := time.Now().UTC().Format("20060102T150405Z") + "-" + uuid.New().String()
+	newPrefix := generationsPrefix + generation + "/"
+	stats := &Stats{}
+	if err = c.writeGeneration(bucket, newPrefix, toCopy, toMerge, stats); err != nil {
+		// the new generation is not visible, remove what was written (best effort, the next compaction removes leftovers)
+		if written, listErr := c.listObjects(bucket, newPrefix); listErr == nil {
+			_ = c.deleteObjects(bucket, written)
+		}
+		return nil, err
+	}
+	newManifestKey := generationsPrefix + generation + manifestSuffix
+	if err = c.writeManifest(bucket, newManifestKey, objectKeys(rawObjects)); err != nil {
+		return nil, err
+	}
+
+	// the swap
+	if err = table.UpdatePartitionLocation(c.GlueClient, partition, "s3://"+bucket+"/"+newPrefix); err != nil {
+		return nil, err
+	}
+
+	// nothing below is visible anymore: the merged objects and leftovers of failed compactions
+	garbage := rawObjects
+	generations, err := c.listObjects(bucket, generationsPrefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range generations {
+		if !strings.HasPrefix(*object.Key, newPrefix) && *object.Key != newManifestKey {
+			garbage = append(garbage, object)
+		}
+	}
+	if err = c.deleteObjects(bucket, garbage); err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("compacted partition",
+		zap.String("table", table.TableName()),
+		zap.Time("hour", hour),
+		zap.String("location", newPrefix),
+		zap.Int("inputObjects", stats.InputObjects),
+		zap.Int64("inputBytes", stats.InputBytes),
+		zap.Int("outputObjects", stats.OutputObjects),
+		zap.Int64("outputBytes", stats.OutputBytes))
+	return stats, nil
+}
+
+// appendLateObjects copies the objects written to the log prefix of a compacted partition into its current
+// generation, where they are visible again, then deletes them. The cost of late data is the size of the late data,
+// not the size of the partition.
+func (c *Compactor) appendLateObjects(bucket, currentPrefix string, rawObjects []*s3.Object) (*Stats, error) {
+	manifestKey := strings.TrimSuffix(currentPrefix, "/") + manifestSuffix
+	merged, err := c.readManifest(bucket, manifestKey)
+	if err != nil {
+		return nil, err
+	}
+	// objects already in the current generation are only waiting to be deleted
+	lateObjects, staleObjects := partitionObjects(rawObjects, func(object *s3.Object) bool {
+		_, found := merged[*object.Key]
+		return !found
+	})
+	if len(lateObjects) == 0 {
+		return nil, c.deleteObjects(bucket, staleObjects)
+	}
+
+	stats := &Stats{}
+	// the copies keep the name of the object, copying again after a failure overwrites them
+	if err = c.writeGeneration(bucket, currentPrefix, lateObjects, nil, stats); err != nil {
+		return nil, err
+	}
+	sources := make([]string, 0, len(merged)+len(lateObjects))
+	for source := range merged {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	if err = c.writeManifest(bucket, manifestKey, append(sources, objectKeys(lateObjects)...)); err != nil {
+		return nil, err
+	}
+	if err = c.deleteObjects(bucket, rawObjects); err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("appended late objects to compacted partition",
+		zap.String("location", currentPrefix),
+		zap.Int("inputObjects", stats.InputObjects),
+		zap.Int64("inputBytes", stats.InputBytes))
+	return stats, nil
+}
+
+func objectKeys(objects []*s3.Object) []string {
+	keys := make([]string, len(objects))
+	for i, object := range objects {
+		keys[i] = *object.Key
+	}
+	return keys
+}
+
+// parseLocation returns the bucket and prefix (with a trailing '/') of a partition location
+func parseLocation(location string) (bucket, prefix string, err error) {
+	parsed, err := url.Parse(location)
+	if err != nil || parsed.Scheme != "s3" {
+		return "", "", errors.Errorf("cannot parse partition location %s", location)
+	}
+	prefix = strings.TrimPrefix(parsed.Path, "/")
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return parsed.Host, prefix, nil
+}
+
+// partitionObjects splits objects in those for which f is true and the others
+func partitionObjects(objects []*s3.Object, f func(object *s3.Object) bool) (selected, others []*s3.Object) {
+	for _, object := range objects {
+		if f(object) {
+			selected = append(selected, object)
+		} else {
+			others = append(others, object)
+		}
+	}
+	return selected, others
+}
+
+// writeGeneration copies the large objects and merges the small ones under prefix
+func (c *Compactor) writeGeneration(bucket, prefix string, toCopy, toMerge []*s3.Object, stats *Stats) error {
+	for _, object := range toCopy {
+		key := prefix + path.Base(*object.Key)
+		_, err := c.S3Client.CopyObject(&s3.CopyObjectInput{
+			Bucket:     &bucket,
+			Key:        &key,
+			CopySource: aws.String((&url.URL{Path: bucket + "/" + *object.Key}).EscapedPath()),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to copy s3://%s/%s", bucket, *object.Key)
+		}
+		stats.InputObjects++
+		stats.InputBytes += aws.Int64Value(object.Size)
+		stats.OutputObjects++
+		stats.OutputBytes += aws.Int64Value(object.Size)
+	}
+
+	// merge in key order, which is time order for log processor objects
+	sort.Slice(toMerge, func(i, j int) bool { return *toMerge[i].Key < *toMerge[j].Key })
+	var writer *objectWriter
+	parts := 0
+	for _, object := range toMerge {
+		if writer == nil {
+			writer = c.newObjectWriter(bucket, fmt.Sprintf("%s%d.json.gz", prefix, parts))
+			parts++
+		}
+		if err := c.mergeObject(bucket, object, writer); err != nil {
+			writer.abort(err)
+			return err
+		}
+		stats.InputObjects++
+		stats.InputBytes += aws.Int64Value(object.Size)
+		if writer.bytes() >= targetObjectBytes {
+			if err := c.closeObjectWriter(writer, stats); err != nil {
+				return err
+			}
+			writer = nil
+		}
+	}
+	if writer != nil {
+		return c.closeObjectWriter(writer, stats)
+	}
+	return nil
+}
+
+// mergeObject appends the events of a gzip'd JSON lines object to writer
+func (c *Compactor) mergeObject(bucket string, object *s3.Object, writer *objectWriter) error {
+	output, err := c.S3Client.GetObject(&s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    object.Key,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get s3://%s/%s", bucket, *object.Key)
+	}
+	defer output.Body.Close()
+
+	gzipReader, err := gzip.NewReader(output.Body)
+	if err != nil {
+		if err == io.EOF { // empty object
+			return nil
+		}
+		return errors.Wrapf(err, "failed to read s3://%s/%s", bucket, *object.Key)
+	}
+	if _, err = io.Copy(writer, gzipReader); err != nil {
+		return errors.Wrapf(err, "failed to merge s3://%s/%s", bucket, *object.Key)
+	}
+	return writer.endLine()
+}
+
+func (c *Compactor) closeObjectWriter(writer *objectWriter, stats *Stats) error {
+	if err := writer.close(); err != nil {
+		return errors.Wrapf(err, "failed to write %s", writer.key)
+	}
+	stats.OutputObjects++
+	stats.OutputBytes += writer.bytes()
+	return nil
+}
+
+// objectWriter gzip's what is written to it into a new S3 object, uploading as it goes
+type objectWriter struct {
+	key        string
+	gzipWriter *gzip.Writer
+	pipeWriter *io.PipeWriter
+	counter    *countingWriter
+	lastByte   byte
+	done       chan error
+}
+
+func (c *Compactor) newObjectWriter(bucket, key string) *objectWriter {
+	pipeReader, pipeWriter := io.Pipe()
+	writer := &objectWriter{
+		key:        key,
+		pipeWriter: pipeWriter,
+		counter:    &countingWriter{writer: pipeWriter},
+		lastByte:   '\n',
+		done:       make(chan error, 1),
+	}
+	writer.gzipWriter = gzip.NewWriter(writer.counter)
+	go func() {
+		_, err := c.Uploader.Upload(&s3manager.UploadInput{
+			Bucket: &bucket,
+			Key:    &key,
+			Body:   pipeReader,
+		})
+		pipeReader.CloseWithError(err) // unblock the writer if the upload failed
+		writer.done <- err
+	}()
+	return writer
+}
+
+func (w *objectWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		w.lastByte = p[len(p)-1]
+	}
+	return w.gzipWriter.Write(p)
+}
+
+// endLine makes sure the next object merged starts on a new line
+func (w *objectWriter) endLine() error {
+	if w.lastByte == '\n' {
+		return nil
+	}
+	_, err := w.Write([]byte{'\n'})
+	return err
+}
+
+// bytes returns the compressed bytes written so far
+func (w *objectWriter) bytes() int64 {
+	return w.counter.count
+}
+
+func (w *objectWriter) close() error {
+	if err := w.gzipWriter.Close(); err != nil {
+		w.abort(err)
+		return err
+	}
+	if err := w.pipeWriter.Close(); err != nil {
+		return err
+	}
+	return <-w.done
+}
+
+// abort stops the upload
+func (w *objectWriter) abort(err error) {
+	_ = w.pipeWriter.CloseWithError(err)
+	<-w.done
+}
+
+type countingWriter struct {
+	writer io.Writer
+	count  int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.count += int64(n)
+	return n, err
+}
+
+func (c *Compactor) listObjects(bucket, prefix string) (objects []*s3.Object, err error) {
+	err = c.S3Client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: &bucket,
+		Prefix: &prefix,
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		objects = append(objects, page.Contents...)
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list s3://%s/%s", bucket, prefix)
+	}
+	return objects, nil
+}
+
+// deleteObjects deletes all the versions of the objects. The bucket is versioned: deleting only the current
+// versions would keep the merged data as noncurrent versions.
+func (c *Compactor) deleteObjects(bucket string, objects []*s3.Object) error {
+	// the versions are listed by "directory", the objects are in a few of them
+	keysByPrefix := make(map[string]map[string]struct{})
+	for _, object := range objects {
+		prefix := path.Dir(*object.Key) + "/"
+		if keysByPrefix[prefix] == nil {
+			keysByPrefix[prefix] = make(map[string]struct{})
+		}
+		keysByPrefix[prefix][*object.Key] = struct{}{}
+	}
+
+	var versions []*s3.ObjectIdentifier
+	for prefix, keys := range keysByPrefix {
+		err := c.S3Client.ListObjectVersionsPages(&s3.ListObjectVersionsInput{
+			Bucket: &bucket,
+			Prefix: aws.String(prefix),
+		}, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+			for _, version := range page.Versions {
+				if _, found := keys[*version.Key]; found {
+					versions = append(versions, &s3.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+				}
+			}
+			for _, marker := range page.DeleteMarkers {
+				if _, found := keys[*marker.Key]; found {
+					versions = append(versions, &s3.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to list versions of s3://%s/%s", bucket, prefix)
+		}
+	}
+
+	for start := 0; start < len(versions); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(versions) {
+			end = len(versions)
+		}
+		output, err := c.S3Client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: &bucket,
+			Delete: &s3.Delete{Objects: versions[start:end], Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete objects from %s", bucket)
+		}
+		if len(output.Errors) > 0 {
+			return errors.Errorf("failed to delete %d objects from %s, first error for %s: %s", len(output.Errors), bucket,
+				aws.StringValue(output.Errors[0].Key), aws.StringValue(output.Errors[0].Message))
+		}
+	}
+	return nil
+}
+
+// readManifest returns the set of objects merged into a generation, empty if there is no manifest
+func (c *Compactor) readManifest(bucket, key string) (map[string]struct{}, error) {
+	merged := make(map[string]struct{})
+	output, err := c.S3Client.GetObject(&s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return merged, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get manifest s3://%s/%s", bucket, key)
+	}
+	defer output.Body.Close()
+
+	var generationManifest manifest
+	if err = jsoniter.NewDecoder(output.Body).Decode(&generationManifest); err != nil {
+		return nil, errors.Wrapf(err, "failed to read manifest s3://%s/%s", bucket, key)
+	}
+	for _, source := range generationManifest.Sources {
+		merged[source] = struct{}{}
+	}
+	return merged, nil
+}
+
+func (c *Compactor) writeManifest(bucket, key string, merged []string) error {
+	generationManifest := manifest{Sources: merged}
+	body, err := jsoniter.Marshal(&generationManifest)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal manifest")
+	}
+	_, err = c.S3Client.PutObject(&s3.PutObjectInput{
+		Bucket:      &bucket,
+		Key:         &key,
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to write manifest s3://%s/%s", bucket, key)
+	}
+	return nil
+}
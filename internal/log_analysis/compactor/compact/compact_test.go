@@ -0,0 +1,475 @@
+package compact
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/aws/aws-sdk-go/service/glue/glueiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+	"github.com/panther-labs/panther/internal/log_analysis/partitionlock"
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+const (
+	testBucket    = "panther-processeddata"
+	testLogPrefix = "logs/test_log/year=2020/month=01/day=31/hour=23/"
+)
+
+var (
+	testTable = awsglue.NewGlueTableMetadata(models.LogData, "Test.Log", "test", awsglue.GlueTableHourly, struct{}{})
+	testHour  = time.Date(2020, 1, 31, 23, 0, 0, 0, time.UTC)
+)
+
+func TestCompact(t *testing.T) {
+	compactor, store, catalog := newTestCompactor(testLogPrefix)
+	store.putGzip(testLogPrefix+"20200131T230100Z-a.json.gz", `{"n":1}`+"\n"+`{"n":2}`+"\n")
+	store.putGzip(testLogPrefix+"20200131T230200Z-b.json.gz", `{"n":3}`) // no final new line
+	store.putGzip(testLogPrefix+"20200131T230300Z-c.json.gz", `{"n":4}`+"\n")
+
+	stats, err := compactor.Compact(testTable, testHour)
+	require.NoError(t, err)
+	assert.Equal(t, &Stats{InputObjects: 3, InputBytes: stats.InputBytes, OutputObjects: 1, OutputBytes: stats.OutputBytes}, stats)
+
+	location := catalog.prefix(t)
+	assert.True(t, strings.HasPrefix(location, "logs/test_log/compacted/year=2020/month=01/day=31/hour=23/"), location)
+	assert.Empty(t, store.keys(testLogPrefix))        // the merged objects are removed
+	assert.Empty(t, store.versionKeys(testLogPrefix)) // for good, the bucket is versioned
+	assert.Equal(t, []string{location + "0.json.gz"}, store.keys(location))
+	assert.Equal(t, `{"n":1}`+"\n"+`{"n":2}`+"\n"+`{"n":3}`+"\n"+`{"n":4}`+"\n", store.gunzip(t, location+"0.json.gz"))
+	assert.Contains(t, store.get(strings.TrimSuffix(location, "/")+manifestSuffix), testLogPrefix+"20200131T230200Z-b.json.gz")
+}
+
+func TestCompactLateData(t *testing.T) {
+	compactor, store, catalog := newTestCompactor(testLogPrefix)
+	store.putGzip(testLogPrefix+"20200131T230100Z-a.json.gz", `{"n":1}`+"\n")
+	store.putGzip(testLogPrefix+"20200131T230200Z-b.json.gz", `{"n":2}`+"\n")
+	_, err := compactor.Compact(testTable, testHour)
+	require.NoError(t, err)
+	firstLocation := catalog.prefix(t)
+
+	// late data lands in the log prefix, it is not visible until appended to the generation
+	store.putGzip(testLogPrefix+"20200201T050000Z-c.json.gz", `{"n":3}`+"\n")
+	stats, err := compactor.Compact(testTable, testHour)
+	require.NoError(t, err)
+	require.NotNil(t, stats)
+	assert.Equal(t, &Stats{InputObjects: 1, InputBytes: stats.InputBytes, OutputObjects: 1, OutputBytes: stats.InputBytes}, stats)
+
+	// the generation is not rewritten
+	location := catalog.prefix(t)
+	assert.Equal(t, firstLocation, location)
+	assert.Empty(t, store.keys(testLogPrefix))
+	assert.Empty(t, store.versionKeys(testLogPrefix))
+	assert.Equal(t, []string{location + "0.json.gz", location + "20200201T050000Z-c.json.gz"}, store.keys(location))
+	assert.Equal(t, `{"n":1}`+"\n"+`{"n":2}`+"\n", store.gunzip(t, location+"0.json.gz"))
+	assert.Equal(t, `{"n":3}`+"\n", store.gunzip(t, location+"20200201T050000Z-c.json.gz"))
+	manifestKey := strings.TrimSuffix(location, "/") + manifestSuffix
+	assert.Contains(t, store.get(manifestKey), testLogPrefix+"20200131T230100Z-a.json.gz")
+	assert.Contains(t, store.get(manifestKey), testLogPrefix+"20200201T050000Z-c.json.gz")
+
+	// the late object is not appended twice if deleting it failed
+	store.putGzip(testLogPrefix+"20200201T050000Z-c.json.gz", `{"n":3}`+"\n")
+	stats, err = compactor.Compact(testTable, testHour)
+	require.NoError(t, err)
+	assert.Nil(t, stats)
+	assert.Empty(t, store.keys(testLogPrefix))
+	assert.Len(t, store.keys(location), 2)
+}
+
+func TestCompactDeletesPreviousGenerations(t *testing.T) {
+	compactor, store, catalog := newTestCompactor(testLogPrefix)
+	// the leftovers of a compaction which failed before the swap
+	leftover := "logs/test_log/compacted/year=2020/month=01/day=31/hour=23/20200201T000000Z-x/0.json.gz"
+	store.putGzip(leftover, `{"n":0}`+"\n")
+	store.putGzip(testLogPrefix+"20200131T230100Z-a.json.gz", `{"n":1}`+"\n")
+	store.putGzip(testLogPrefix+"20200131T230200Z-b.json.gz", `{"n":2}`+"\n")
+
+	_, err := compactor.Compact(testTable, testHour)
+	require.NoError(t, err)
+	assert.False(t, store.has(leftover))
+	assert.NotContains(t, store.versionKeys("logs/test_log/compacted/"), leftover)
+	assert.Equal(t, `{"n":1}`+"\n"+`{"n":2}`+"\n", store.gunzip(t, catalog.prefix(t)+"0.json.gz"))
+}
+
+func TestCompactMergedObjectsNotDeleted(t *testing.T) {
+	compactor, store, catalog := newTestCompactor(testLogPrefix)
+	store.putGzip(testLogPrefix+"20200131T230100Z-a.json.gz", `{"n":1}`+"\n")
+	store.putGzip(testLogPrefix+"20200131T230200Z-b.json.gz", `{"n":2}`+"\n")
+	_, err := compactor.Compact(testTable, testHour)
+	require.NoError(t, err)
+	location := catalog.prefix(t)
+
+	// as if deleting the merged objects had failed, they must not be merged again
+	store.putGzip(testLogPrefix+"20200131T230100Z-a.json.gz", `{"n":1}`+"\n")
+	stats, err := compactor.Compact(testTable, testHour)
+	require.NoError(t, err)
+	assert.Nil(t, stats)
+	assert.Equal(t, location, catalog.prefix(t))
+	assert.Empty(t, store.keys(testLogPrefix))
+	assert.Equal(t, `{"n":1}`+"\n"+`{"n":2}`+"\n", store.gunzip(t, location+"0.json.gz"))
+}
+
+func TestCompactLocked(t *testing.T) {
+	compactor, store, catalog := newTestCompactor(testLogPrefix)
+	store.putGzip(testLogPrefix+"20200131T230100Z-a.json.gz", `{"n":1}`+"\n")
+	store.putGzip(testLogPrefix+"20200131T230200Z-b.json.gz", `{"n":2}`+"\n")
+	locks := compactor.Locks.Client.(*fakeLocks)
+	locks.lockedByDeletion = true
+
+	_, err := compactor.Compact(testTable, testHour)
+	require.Error(t, err) // retried later
+	assert.Equal(t, testLogPrefix, catalog.prefix(t))
+	assert.Len(t, store.keys(testLogPrefix), 2)
+
+	locks.lockedByDeletion = false
+	_, err = compactor.Compact(testTable, testHour)
+	require.NoError(t, err)
+	assert.NotEqual(t, testLogPrefix, catalog.prefix(t))
+	assert.Equal(t, 1, locks.acquired)
+	assert.Equal(t, 1, locks.released)
+}
+
+func TestCompactNothingToDo(t *testing.T) {
+	compactor, store, catalog := newTestCompactor(testLogPrefix)
+	store.putGzip(testLogPrefix+"20200131T230100Z-a.json.gz", `{"n":1}`+"\n")
+	stats, err := compactor.Compact(testTable, testHour)
+	require.NoError(t, err)
+	assert.Nil(t, stats)
+	assert.Equal(t, testLogPrefix, catalog.prefix(t))
+	assert.Equal(t, []string{testLogPrefix + "20200131T230100Z-a.json.gz"}, store.keys(testLogPrefix))
+
+	// no partition
+	catalog.partition = nil
+	stats, err = compactor.Compact(testTable, testHour)
+	require.NoError(t, err)
+	assert.Nil(t, stats)
+}
+
+func TestCompactProjectedTable(t *testing.T) {
+	compactor, store, catalog := newTestCompactor(testLogPrefix)
+	catalog.projected = true // Athena reads the log prefix, whatever the partition location
+	store.putGzip(testLogPrefix+"20200131T230100Z-a.json.gz", `{"n":1}`+"\n")
+	store.putGzip(testLogPrefix+"20200131T230200Z-b.json.gz", `{"n":2}`+"\n")
+	stats, err := compactor.Compact(testTable, testHour)
+	require.NoError(t, err)
+	assert.Nil(t, stats)
+	assert.Equal(t, testLogPrefix, catalog.prefix(t))
+	assert.Len(t, store.keys(testLogPrefix), 2)
+}
+
+func TestCompactLargeObjectsCopied(t *testing.T) {
+	compactor, store, catalog := newTestCompactor(testLogPrefix)
+	store.putGzip(testLogPrefix+"20200131T230100Z-a.json.gz", `{"n":1}`+"\n")
+	store.putGzip(testLogPrefix+"20200131T230200Z-b.json.gz", `{"n":2}`+"\n")
+	store.putGzip(testLogPrefix+"20200131T230300Z-c.json.gz", `{"n":3}`+"\n")
+	store.sizes[testLogPrefix+"20200131T230300Z-c.json.gz"] = targetObjectBytes // pretend it is large
+
+	stats, err := compactor.Compact(testTable, testHour)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.InputObjects)
+	assert.Equal(t, 2, stats.OutputObjects)
+	location := catalog.prefix(t)
+	assert.Equal(t, []string{location + "0.json.gz", location + "20200131T230300Z-c.json.gz"}, store.keys(location))
+	assert.Equal(t, `{"n":3}`+"\n", store.gunzip(t, location+"20200131T230300Z-c.json.gz"))
+}
+
+func TestCompactUploadFails(t *testing.T) {
+	compactor, store, catalog := newTestCompactor(testLogPrefix)
+	store.putGzip(testLogPrefix+"20200131T230100Z-a.json.gz", `{"n":1}`+"\n")
+	store.putGzip(testLogPrefix+"20200131T230200Z-b.json.gz", `{"n":2}`+"\n")
+	compactor.Uploader.(*fakeUploader).err = awserr.New("InternalError", "upload failed", nil)
+
+	_, err := compactor.Compact(testTable, testHour)
+	require.Error(t, err)
+	assert.Equal(t, testLogPrefix, catalog.prefix(t)) // not swapped
+	assert.Len(t, store.keys(testLogPrefix), 2)
+}
+
+func newTestCompactor(prefix string) (*Compactor, *fakeS3, *fakeGlue) {
+	store := &fakeS3{
+		objects:  make(map[string][]byte),
+		sizes:    make(map[string]int64),
+		versions: make(map[string][]fakeVersion),
+	}
+	catalog := &fakeGlue{
+		partition: &glue.Partition{
+			Values: []*string{aws.String("2020"), aws.String("01"), aws.String("31"), aws.String("23")},
+			StorageDescriptor: &glue.StorageDescriptor{
+				Location: aws.String("s3://" + testBucket + "/" + prefix),
+			},
+		},
+	}
+	return &Compactor{
+		GlueClient: catalog,
+		S3Client:   store,
+		Uploader:   &fakeUploader{store: store},
+		Locks:      &partitionlock.Locker{Client: &fakeLocks{}, TableName: "locks"},
+	}, store, catalog
+}
+
+// fakeLocks is a lock table where a data deletion may hold the lock of the partition
+type fakeLocks struct {
+	dynamodbiface.DynamoDBAPI
+	lockedByDeletion bool
+	acquired         int
+	released         int
+}
+
+func (m *fakeLocks) UpdateItem(*dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	if m.lockedByDeletion {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "locked", nil)
+	}
+	m.acquired++
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (m *fakeLocks) DeleteItem(*dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	m.released++
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// fakeGlue holds one partition
+type fakeGlue struct {
+	glueiface.GlueAPI
+	partition *glue.Partition
+	projected bool
+}
+
+func (m *fakeGlue) GetTable(*glue.GetTableInput) (*glue.GetTableOutput, error) {
+	table := &glue.TableData{}
+	if m.projected {
+		table.Parameters = map[string]*string{awsglue.ProjectionEnabledParameter: aws.String("true")}
+	}
+	return &glue.GetTableOutput{Table: table}, nil
+}
+
+func (m *fakeGlue) GetPartition(*glue.GetPartitionInput) (*glue.GetPartitionOutput, error) {
+	if m.partition == nil {
+		return nil, awserr.New(glue.ErrCodeEntityNotFoundException, "not found", nil)
+	}
+	return &glue.GetPartitionOutput{Partition: m.partition}, nil
+}
+
+func (m *fakeGlue) UpdatePartition(input *glue.UpdatePartitionInput) (*glue.UpdatePartitionOutput, error) {
+	m.partition = &glue.Partition{
+		Values:            input.PartitionInput.Values,
+		StorageDescriptor: input.PartitionInput.StorageDescriptor,
+	}
+	return &glue.UpdatePartitionOutput{}, nil
+}
+
+// prefix returns the key prefix of the partition location
+func (m *fakeGlue) prefix(t *testing.T) string {
+	location, err := url.Parse(*m.partition.StorageDescriptor.Location)
+	require.NoError(t, err)
+	require.Equal(t, testBucket, location.Host)
+	return strings.TrimPrefix(location.Path, "/")
+}
+
+// fakeS3 is an in memory versioned bucket
+type fakeS3 struct {
+	s3iface.S3API
+	objects  map[string][]byte
+	sizes    map[string]int64         // overrides the size of objects
+	versions map[string][]fakeVersion // all the versions of the keys, the latest last
+}
+
+type fakeVersion struct {
+	id     string
+	marker bool
+}
+
+func (m *fakeS3) put(key string, body []byte) {
+	m.objects[key] = body
+	delete(m.sizes, key)
+	m.addVersion(key, false)
+}
+
+func (m *fakeS3) addVersion(key string, marker bool) {
+	m.versions[key] = append(m.versions[key], fakeVersion{id: fmt.Sprintf("v%d", len(m.versions[key])), marker: marker})
+}
+
+// versionKeys returns the keys having versions or delete markers
+func (m *fakeS3) versionKeys(prefix string) (keys []string) {
+	for key := range m.versions {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *fakeS3) putGzip(key, body string) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	_, _ = writer.Write([]byte(body))
+	_ = writer.Close()
+	m.put(key, buffer.Bytes())
+}
+
+func (m *fakeS3) has(key string) bool {
+	_, found := m.objects[key]
+	return found
+}
+
+func (m *fakeS3) get(key string) string {
+	return string(m.objects[key])
+}
+
+func (m *fakeS3) gunzip(t *testing.T, key string) string {
+	require.True(t, m.has(key), key)
+	reader, err := gzip.NewReader(bytes.NewReader(m.objects[key]))
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func (m *fakeS3) keys(prefix string) (keys []string) {
+	for key := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *fakeS3) ListObjectsV2Pages(input *s3.ListObjectsV2Input, f func(*s3.ListObjectsV2Output, bool) bool) error {
+	page := &s3.ListObjectsV2Output{}
+	for _, key := range m.keys(*input.Prefix) {
+		size, found := m.sizes[key]
+		if !found {
+			size = int64(len(m.objects[key]))
+		}
+		page.Contents = append(page.Contents, &s3.Object{Key: aws.String(key), Size: aws.Int64(size)})
+	}
+	f(page, true)
+	return nil
+}
+
+func (m *fakeS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	if !m.has(*input.Key) {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "no such key", nil)
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(m.objects[*input.Key]))}, nil
+}
+
+func (m *fakeS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.put(*input.Key, body)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *fakeS3) CopyObject(input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
+	source, err := url.PathUnescape(*input.CopySource)
+	if err != nil {
+		return nil, err
+	}
+	sourceKey := strings.TrimPrefix(source, *input.Bucket+"/")
+	m.put(*input.Key, m.objects[sourceKey])
+	return &s3.CopyObjectOutput{}, nil
+}
+
+func (m *fakeS3) ListObjectVersionsPages(input *s3.ListObjectVersionsInput,
+	f func(*s3.ListObjectVersionsOutput, bool) bool) error {
+
+	page := &s3.ListObjectVersionsOutput{}
+	for _, key := range m.versionKeys(*input.Prefix) {
+		for _, version := range m.versions[key] {
+			if version.marker {
+				page.DeleteMarkers = append(page.DeleteMarkers,
+					&s3.DeleteMarkerEntry{Key: aws.String(key), VersionId: aws.String(version.id)})
+			} else {
+				page.Versions = append(page.Versions, &s3.ObjectVersion{Key: aws.String(key), VersionId: aws.String(version.id)})
+			}
+		}
+	}
+	f(page, true)
+	return nil
+}
+
+// DeleteObjects adds a delete marker without a version id, removes the version otherwise
+func (m *fakeS3) DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	for _, object := range input.Delete.Objects {
+		key := *object.Key
+		if object.VersionId == nil {
+			delete(m.objects, key)
+			m.addVersion(key, true)
+			continue
+		}
+		var remaining []fakeVersion
+		for _, version := range m.versions[key] {
+			if version.id != *object.VersionId {
+				remaining = append(remaining, version)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(m.versions, key)
+		} else {
+			m.versions[key] = remaining
+		}
+		if len(remaining) == 0 || remaining[len(remaining)-1].marker {
+			delete(m.objects, key)
+		}
+	}
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+type fakeUploader struct {
+	s3manageriface.UploaderAPI
+	store *fakeS3
+	err   error
+}
+
+func (m *fakeUploader) Upload(input *s3manager.UploadInput, _ ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.store.put(*input.Key, body)
+	return &s3manager.UploadOutput{}, nil
+}
@@ -0,0 +1,123 @@
+package compact
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/registry"
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+const (
+	// an hour is compacted once it ended this long ago, when the log processor is not expected to write more data
+	// for it. Data arriving later is appended to the compacted partition as it arrives.
+	LatenessHorizon = 24 * time.Hour
+
+	// the number of closed hours compacted by a scheduled run, more than one so a late run does not skip an hour
+	scheduledHours = 3
+)
+
+// Partition identifies an hour of data of a table
+type Partition struct {
+	Table *awsglue.GlueTableMetadata
+	Hour  time.Time
+}
+
+// IsClosed returns true if hour is past the lateness horizon at time now
+func IsClosed(hour, now time.Time) bool {
+	return !hour.Add(time.Hour + LatenessHorizon).After(now)
+}
+
+// ScheduledPartitions returns the partitions of all log tables for the latest closed hours at time now
+func ScheduledPartitions(now time.Time) (partitions []*Partition) {
+	lastClosedHour := now.UTC().Add(-LatenessHorizon).Truncate(time.Hour).Add(-time.Hour)
+	for _, table := range registry.AvailableTables() {
+		for i := 0; i < scheduledHours; i++ {
+			partitions = append(partitions, &Partition{
+				Table: table,
+				Hour:  lastClosedHour.Add(-time.Duration(i) * time.Hour),
+			})
+		}
+	}
+	return partitions
+}
+
+// NotifiedPartitions returns the closed partitions that have new (late) objects according to the
+// S3 notifications of the log processor in the SQS event
+func NotifiedPartitions(event events.SQSEvent, now time.Time) (partitions []*Partition) {
+	tables := make(map[string]*awsglue.GlueTableMetadata)
+	for _, table := range registry.AvailableTables() {
+		tables[table.TableName()] = table
+	}
+
+	seen := make(map[string]struct{})
+	for _, record := range event.Records {
+		notification := &models.S3Notification{}
+		if err := jsoniter.UnmarshalFromString(record.Body, notification); err != nil {
+			zap.L().Error("failed to unmarshal record", zap.Error(errors.WithStack(err)))
+			continue
+		}
+		for _, eventRecord := range notification.Records {
+			gluePartition, err := awsglue.GetPartitionFromS3(eventRecord.S3.Bucket.Name, eventRecord.S3.Object.Key)
+			if err != nil {
+				zap.L().Error("failed to get partition information from notification",
+					zap.String("key", eventRecord.S3.Object.Key), zap.Error(errors.WithStack(err)))
+				continue
+			}
+			if gluePartition.GetDatabase() != awsglue.LogProcessingDatabaseName {
+				continue
+			}
+			table, found := tables[gluePartition.GetTable()]
+			if !found || gluePartition.GetHour().IsZero() || !IsClosed(gluePartition.GetHour(), now) {
+				continue // open partitions are compacted when they close
+			}
+			partitionPrefix := table.GetPartitionPrefix(gluePartition.GetHour())
+			if _, ok := seen[partitionPrefix]; ok {
+				continue
+			}
+			seen[partitionPrefix] = struct{}{}
+			partitions = append(partitions, &Partition{
+				Table: table,
+				Hour:  gluePartition.GetHour(),
+			})
+		}
+	}
+	return partitions
+}
+
+// CompactPartitions compacts each partition, continuing on errors and returning the last one
+func (c *Compactor) CompactPartitions(partitions []*Partition) (err error) {
+	for _, partition := range partitions {
+		if _, compactErr := c.Compact(partition.Table, partition.Hour); compactErr != nil {
+			zap.L().Error("failed to compact partition",
+				zap.String("table", partition.Table.TableName()),
+				zap.Time("hour", partition.Hour),
+				zap.Error(compactErr))
+			err = compactErr
+		}
+	}
+	return err
+}
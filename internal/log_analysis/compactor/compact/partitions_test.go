@@ -0,0 +1,75 @@
+package compact
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/registry"
+)
+
+func TestIsClosed(t *testing.T) {
+	now := time.Date(2020, 2, 1, 12, 30, 0, 0, time.UTC)
+	assert.True(t, IsClosed(time.Date(2020, 1, 31, 11, 0, 0, 0, time.UTC), now))
+	assert.False(t, IsClosed(time.Date(2020, 1, 31, 12, 0, 0, 0, time.UTC), now))
+	assert.False(t, IsClosed(time.Date(2020, 2, 1, 11, 0, 0, 0, time.UTC), now))
+}
+
+func TestScheduledPartitions(t *testing.T) {
+	now := time.Date(2020, 2, 1, 12, 30, 0, 0, time.UTC)
+	partitions := ScheduledPartitions(now)
+	require.Len(t, partitions, scheduledHours*len(registry.AvailableTables()))
+	assert.Equal(t, time.Date(2020, 1, 31, 11, 0, 0, 0, time.UTC), partitions[0].Hour)
+	assert.Equal(t, time.Date(2020, 1, 31, 10, 0, 0, 0, time.UTC), partitions[1].Hour)
+	assert.Equal(t, time.Date(2020, 1, 31, 9, 0, 0, 0, time.UTC), partitions[2].Hour)
+	for _, partition := range partitions {
+		assert.True(t, IsClosed(partition.Hour, now))
+	}
+}
+
+func TestNotifiedPartitions(t *testing.T) {
+	now := time.Date(2020, 2, 2, 12, 30, 0, 0, time.UTC)
+	event := events.SQSEvent{
+		Records: []events.SQSMessage{
+			notificationMessage(t, "logs/aws_vpcflow/year=2020/month=01/day=31/hour=23/20200131T230000Z-a.json.gz"),
+			notificationMessage(t, "logs/aws_vpcflow/year=2020/month=01/day=31/hour=23/20200131T230000Z-b.json.gz"), // same partition
+			notificationMessage(t, "logs/aws_vpcflow/year=2020/month=02/day=01/hour=12/20200201T120000Z-c.json.gz"), // within the lateness horizon
+			notificationMessage(t, "rules/aws_vpcflow/year=2020/month=01/day=31/hour=23/rule_id=Test/a.json.gz"),    // not logs
+			notificationMessage(t, "logs/unknown_table/year=2020/month=01/day=31/hour=23/20200131T230000Z-a.json.gz"),
+			{Body: "not json"},
+		},
+	}
+	partitions := NotifiedPartitions(event, now)
+	require.Len(t, partitions, 1)
+	assert.Equal(t, "AWS.VPCFlow", partitions[0].Table.LogType())
+	assert.Equal(t, time.Date(2020, 1, 31, 23, 0, 0, 0, time.UTC), partitions[0].Hour)
+}
+
+func notificationMessage(t *testing.T, key string) events.SQSMessage {
+	body, err := jsoniter.MarshalToString(models.NewS3ObjectPutNotification("panther-processeddata", key, 100))
+	require.NoError(t, err)
+	return events.SQSMessage{Body: body}
+}
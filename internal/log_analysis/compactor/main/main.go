@@ -0,0 +1,85 @@
+package main
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/internal/log_analysis/compactor/compact"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
+	"github.com/panther-labs/panther/internal/log_analysis/partitionlock"
+	"github.com/panther-labs/panther/pkg/lambdalogger"
+)
+
+const (
+	maxRetries = 20 // the Glue API throttles easily
+)
+
+type envConfig struct {
+	PartitionLocksTable string `required:"true" split_words:"true"`
+}
+
+var (
+	awsSession = session.Must(session.NewSession(aws.NewConfig().WithMaxRetries(maxRetries)))
+	compactor  *compact.Compactor
+)
+
+func main() {
+	var env envConfig
+	envconfig.MustProcess("", &env)
+	compactor = &compact.Compactor{
+		GlueClient: glue.New(awsSession),
+		S3Client:   s3.New(awsSession),
+		Uploader:   s3manager.NewUploader(awsSession),
+		Locks: &partitionlock.Locker{
+			Client:    dynamodb.New(awsSession),
+			TableName: env.PartitionLocksTable,
+		},
+	}
+	lambda.Start(handle)
+}
+
+// handle is invoked by the hourly schedule (no records) or with S3 notifications from the log processor.
+// The function has a concurrency of 1 so a partition is never compacted by two invocations at the same time.
+func handle(ctx context.Context, event events.SQSEvent) (err error) {
+	lc, _ := lambdalogger.ConfigureGlobal(ctx, nil)
+	operation := common.OpLogManager.Start(lc.InvokedFunctionArn, common.OpLogLambdaServiceDim).WithMemUsed(lambdacontext.MemoryLimitInMB)
+	defer func() {
+		operation.Stop().Log(err, zap.Int("sqsMessageCount", len(event.Records)))
+	}()
+
+	now := time.Now().UTC()
+	if len(event.Records) == 0 {
+		return compactor.CompactPartitions(compact.ScheduledPartitions(now))
+	}
+	return compactor.CompactPartitions(compact.NotifiedPartitions(event, now))
+}
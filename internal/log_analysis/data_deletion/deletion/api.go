@@ -0,0 +1,175 @@
+package deletion
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/api/lambda/deletion/models"
+	"github.com/panther-labs/panther/pkg/awsathena"
+	"github.com/panther-labs/panther/pkg/awsglue"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+const (
+	deletionsPrefix = "data_deletion/" // prefix in the Athena results bucket
+	reportLinkTTL   = time.Hour
+)
+
+// API has all of the handlers as receiver methods.
+type API struct{}
+
+// StartDataDeletion starts the query finding the objects to rewrite and returns without waiting.
+func (API) StartDataDeletion(input *models.StartDataDeletionInput) (*models.StartDataDeletionOutput, error) {
+	view := indicatorView(*input.IndicatorType)
+	if view == nil {
+		return nil, &genericapi.InvalidInputError{Message: "unknown indicator type " + *input.IndicatorType}
+	}
+	sql := pathsSQL(deletionTables(), view.Column, *input.Indicator, *input.StartTime, *input.EndTime)
+	if sql == "" {
+		return nil, &genericapi.InvalidInputError{Message: "no table has the " + view.Column + " column"}
+	}
+	if err := checkGlacier(deletionTables(), view.Column, *input.StartTime); err != nil {
+		return nil, err
+	}
+
+	item := &deletionItem{
+		DeletionID:    uuid.New().String(),
+		UserID:        *input.UserID,
+		IndicatorType: *input.IndicatorType,
+		Indicator:     *input.Indicator,
+		StartTime:     input.StartTime.UTC(),
+		EndTime:       input.EndTime.UTC(),
+		Reason:        *input.Reason,
+		CreatedAt:     now(),
+		Status:        models.DeletionQuerying,
+	}
+	resultsPath := "s3://" + env.AthenaResultsBucket + "/" + deletionsPrefix + item.DeletionID + "/"
+	startOutput, err := awsathena.StartQuery(athenaClient, awsglue.LogProcessingDatabaseName, sql, aws.String(resultsPath))
+	if err != nil {
+		return nil, err
+	}
+	item.QueryID = *startOutput.QueryExecutionId
+	if err = putDeletion(item); err != nil {
+		// the deletion would never be processed
+		_, _ = awsathena.StopQuery(athenaClient, item.QueryID)
+		return nil, err
+	}
+
+	zap.L().Info("data deletion started",
+		zap.String("deletionId", item.DeletionID),
+		zap.String("userId", item.UserID),
+		zap.String("indicatorType", item.IndicatorType),
+		zap.String("reason", item.Reason))
+	return deletionStatus(item)
+}
+
+// GetDataDeletion returns a deletion, with a link to its report once it succeeded.
+func (API) GetDataDeletion(input *models.GetDataDeletionInput) (*models.GetDataDeletionOutput, error) {
+	item, err := getDeletion(*input.DeletionID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, &genericapi.DoesNotExistError{Message: "deletion " + *input.DeletionID + " does not exist"}
+	}
+	return deletionStatus(item)
+}
+
+// ProcessDataDeletions advances the active deletions until the processing time is over.
+func (API) ProcessDataDeletions(_ *models.ProcessDataDeletionsInput) error {
+	items, err := listActiveDeletions()
+	if err != nil {
+		return err
+	}
+	deadline := now().Add(processingTime)
+	for _, item := range items {
+		claimed, err := claimDeletion(item)
+		if err != nil {
+			return err
+		}
+		if !claimed { // another lambda is processing it
+			continue
+		}
+
+		if err = processDeletion(item, deadline); err != nil {
+			zap.L().Error("data deletion failed", zap.String("deletionId", item.DeletionID), zap.Error(err))
+			item.Status = models.DeletionFailed
+			item.Error = aws.String(err.Error())
+			item.CompletedAt = aws.Time(now())
+		}
+		item.LeaseExpiresAt = 0 // release
+		if err = putDeletion(item); err != nil {
+			return err
+		}
+		if now().After(deadline) {
+			break
+		}
+	}
+	return nil
+}
+
+func indicatorView(indicatorType string) *awsglue.IndicatorView {
+	for i := range awsglue.IndicatorViews {
+		if awsglue.IndicatorViews[i].IndicatorType == indicatorType {
+			return &awsglue.IndicatorViews[i]
+		}
+	}
+	return nil
+}
+
+func deletionStatus(item *deletionItem) (*models.DataDeletion, error) {
+	result := &models.DataDeletion{
+		DeletionID:      aws.String(item.DeletionID),
+		UserID:          aws.String(item.UserID),
+		IndicatorType:   aws.String(item.IndicatorType),
+		Indicator:       aws.String(item.Indicator),
+		StartTime:       aws.Time(item.StartTime),
+		EndTime:         aws.Time(item.EndTime),
+		Reason:          aws.String(item.Reason),
+		CreatedAt:       aws.Time(item.CreatedAt),
+		CompletedAt:     item.CompletedAt,
+		Status:          aws.String(item.Status),
+		Error:           item.Error,
+		ObjectCount:     item.ObjectCount,
+		DeletedRows:     item.DeletedRows,
+		ReportSignature: item.ReportSignature,
+	}
+	if item.Status != models.DeletionSucceeded {
+		return result, nil
+	}
+
+	request, _ := s3Client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(env.AthenaResultsBucket),
+		Key:    aws.String(reportKey(item.DeletionID)),
+	})
+	url, err := request.Presign(reportLinkTTL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to presign report of deletion %s", item.DeletionID)
+	}
+	result.ReportURL = aws.String(url)
+	result.SigningKeyID = aws.String(env.SigningKeyID)
+	return result, nil
+}
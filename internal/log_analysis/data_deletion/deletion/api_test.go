@@ -0,0 +1,506 @@
+package deletion
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/deletion/models"
+	"github.com/panther-labs/panther/internal/log_analysis/partitionlock"
+	"github.com/panther-labs/panther/pkg/awsglue"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+const (
+	testDeletionID = "0a4e5b6c-1d2e-4f3a-8b4c-5d6e7f8a9b0c"
+	testQueryID    = "query-id"
+)
+
+var testNow = time.Date(2020, 3, 5, 12, 0, 0, 0, time.UTC)
+
+type athenaMock struct {
+	athenaiface.AthenaAPI
+	mock.Mock
+}
+
+func (m *athenaMock) StartQueryExecution(input *athena.StartQueryExecutionInput) (*athena.StartQueryExecutionOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.StartQueryExecutionOutput), args.Error(1)
+}
+
+func (m *athenaMock) GetQueryExecution(input *athena.GetQueryExecutionInput) (*athena.GetQueryExecutionOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.GetQueryExecutionOutput), args.Error(1)
+}
+
+func (m *athenaMock) GetQueryResults(input *athena.GetQueryResultsInput) (*athena.GetQueryResultsOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.GetQueryResultsOutput), args.Error(1)
+}
+
+type ddbMock struct {
+	dynamodbiface.DynamoDBAPI
+	mock.Mock
+}
+
+func (m *ddbMock) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.PutItemOutput), args.Error(1)
+}
+
+func (m *ddbMock) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.GetItemOutput), args.Error(1)
+}
+
+func (m *ddbMock) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.UpdateItemOutput), args.Error(1)
+}
+
+func (m *ddbMock) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.DeleteItemOutput), args.Error(1)
+}
+
+func (m *ddbMock) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	args := m.Called(input, fn)
+	fn(args.Get(0).(*dynamodb.ScanOutput), true)
+	return args.Error(1)
+}
+
+type kmsMock struct {
+	kmsiface.KMSAPI
+	mock.Mock
+}
+
+func (m *kmsMock) Sign(input *kms.SignInput) (*kms.SignOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*kms.SignOutput), args.Error(1)
+}
+
+// testSession presigns offline with static credentials
+func testSession() *session.Session {
+	return session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		Region:      aws.String("us-east-1"),
+	}))
+}
+
+func setupMocks() (*athenaMock, *ddbMock, *kmsMock, *s3Mock) {
+	env = envConfig{
+		AthenaResultsBucket: "results",
+		DeletionsTable:      "deletions",
+		PartitionLocksTable: "locks",
+		ProcessedDataBucket: testBucket,
+		SigningKeyID:        "key-id",
+	}
+	now = func() time.Time { return testNow }
+	deletionTables = testTables
+	mockAthena, mockDdb, mockKms, mockS3 := &athenaMock{}, &ddbMock{}, &kmsMock{}, &s3Mock{}
+	athenaClient, ddbClient, kmsClient, s3Client = mockAthena, mockDdb, mockKms, mockS3
+	locks = &partitionlock.Locker{Client: mockDdb, TableName: "locks"}
+	return mockAthena, mockDdb, mockKms, mockS3
+}
+
+func testItem(status string) *deletionItem {
+	return &deletionItem{
+		DeletionID:    testDeletionID,
+		UserID:        "user",
+		IndicatorType: "ip_address",
+		Indicator:     "1.2.3.4",
+		StartTime:     testStart,
+		EndTime:       testEnd,
+		Reason:        "erasure request",
+		CreatedAt:     testNow.Add(-time.Hour),
+		Status:        status,
+		QueryID:       testQueryID,
+	}
+}
+
+func marshalItem(t *testing.T, item *deletionItem) map[string]*dynamodb.AttributeValue {
+	ddbItem, err := dynamodbattribute.MarshalMap(item)
+	require.NoError(t, err)
+	return ddbItem
+}
+
+func storedItem(t *testing.T, mockDdb *ddbMock) *deletionItem {
+	var item deletionItem
+	for _, call := range mockDdb.Calls {
+		if input, ok := call.Arguments.Get(0).(*dynamodb.PutItemInput); ok {
+			require.NoError(t, dynamodbattribute.UnmarshalMap(input.Item, &item))
+		}
+	}
+	return &item
+}
+
+func TestStartDataDeletion(t *testing.T) {
+	mockAthena, mockDdb, _, mockS3 := setupMocks()
+	mockS3.On("GetBucketLifecycleConfiguration", mock.Anything).Return(
+		nil, awserr.New("NoSuchLifecycleConfiguration", "none", nil))
+	mockAthena.On("StartQueryExecution", mock.Anything).Return(
+		&athena.StartQueryExecutionOutput{QueryExecutionId: aws.String(testQueryID)}, nil)
+	mockDdb.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+
+	output, err := API{}.StartDataDeletion(&models.StartDataDeletionInput{
+		UserID:        aws.String("user"),
+		IndicatorType: aws.String("ip_address"),
+		Indicator:     aws.String("1.2.3.4"),
+		StartTime:     aws.Time(testStart),
+		EndTime:       aws.Time(testEnd),
+		Reason:        aws.String("erasure request"),
+	})
+	require.NoError(t, err)
+	mockAthena.AssertExpectations(t)
+	mockDdb.AssertExpectations(t)
+
+	assert.Equal(t, models.DeletionQuerying, *output.Status)
+	assert.Nil(t, output.ReportURL)
+	startInput := mockAthena.Calls[0].Arguments.Get(0).(*athena.StartQueryExecutionInput)
+	assert.Equal(t, awsglue.LogProcessingDatabaseName, *startInput.QueryExecutionContext.Database)
+	assert.Equal(t, "s3://results/data_deletion/"+*output.DeletionID+"/", *startInput.ResultConfiguration.OutputLocation)
+	assert.Contains(t, *startInput.QueryString, "contains(p_any_ip_addresses, '1.2.3.4')")
+
+	item := storedItem(t, mockDdb)
+	assert.Equal(t, *output.DeletionID, item.DeletionID)
+	assert.Equal(t, testQueryID, item.QueryID)
+	assert.Equal(t, testNow, item.CreatedAt)
+}
+
+func TestStartDataDeletionNoColumn(t *testing.T) {
+	mockAthena, _, _, _ := setupMocks()
+	_, err := API{}.StartDataDeletion(&models.StartDataDeletionInput{
+		UserID:        aws.String("user"),
+		IndicatorType: aws.String("domain_name"),
+		Indicator:     aws.String("example.com"),
+		StartTime:     aws.Time(testStart),
+		EndTime:       aws.Time(testEnd),
+		Reason:        aws.String("erasure request"),
+	})
+	assert.Equal(t, &genericapi.InvalidInputError{Message: "no table has the p_any_domain_names column"}, err)
+	mockAthena.AssertNotCalled(t, "StartQueryExecution", mock.Anything)
+}
+
+func TestStartDataDeletionGlacier(t *testing.T) {
+	mockAthena, _, _, mockS3 := setupMocks()
+	mockS3.On("GetBucketLifecycleConfiguration", &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(testBucket),
+	}).Return(&s3.GetBucketLifecycleConfigurationOutput{
+		Rules: []*s3.LifecycleRule{
+			{
+				Filter:      &s3.LifecycleRuleFilter{Prefix: aws.String("logs/test_events/")},
+				Status:      aws.String(s3.ExpirationStatusEnabled),
+				Transitions: []*s3.Transition{{Days: aws.Int64(2), StorageClass: aws.String(s3.TransitionStorageClassGlacier)}},
+			},
+		},
+	}, nil)
+
+	input := &models.StartDataDeletionInput{
+		UserID:        aws.String("user"),
+		IndicatorType: aws.String("ip_address"),
+		Indicator:     aws.String("1.2.3.4"),
+		StartTime:     aws.Time(testStart),
+		EndTime:       aws.Time(testEnd),
+		Reason:        aws.String("erasure request"),
+	}
+	_, err := API{}.StartDataDeletion(input)
+	assert.Equal(t, &genericapi.InvalidInputError{
+		Message: "the data of panther_logs.test_events older than 2 days is archived in Glacier and cannot be deleted, " +
+			"the start time must be after " + testNow.AddDate(0, 0, -2).Format(time.RFC3339),
+	}, err)
+	mockAthena.AssertNotCalled(t, "StartQueryExecution", mock.Anything)
+}
+
+func TestGetDataDeletion(t *testing.T) {
+	_, mockDdb, _, _ := setupMocks()
+	s3Client = &s3Mock{}
+	item := testItem(models.DeletionSucceeded)
+	item.CompletedAt = aws.Time(testNow)
+	item.DeletedRows = aws.Int64(2)
+	item.ReportSignature = aws.String("signature")
+	mockDdb.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{Item: marshalItem(t, item)}, nil)
+
+	output, err := API{}.GetDataDeletion(&models.GetDataDeletionInput{DeletionID: aws.String(testDeletionID)})
+	require.NoError(t, err)
+	assert.Equal(t, models.DeletionSucceeded, *output.Status)
+	assert.Equal(t, int64(2), *output.DeletedRows)
+	assert.Equal(t, "signature", *output.ReportSignature)
+	assert.Equal(t, "key-id", *output.SigningKeyID)
+	assert.Contains(t, *output.ReportURL, "results.s3.amazonaws.com/data_deletion/"+testDeletionID+"/report.json")
+}
+
+func TestGetDataDeletionDoesNotExist(t *testing.T) {
+	_, mockDdb, _, _ := setupMocks()
+	mockDdb.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{}, nil)
+	_, err := API{}.GetDataDeletion(&models.GetDataDeletionInput{DeletionID: aws.String(testDeletionID)})
+	assert.Equal(t, &genericapi.DoesNotExistError{Message: "deletion " + testDeletionID + " does not exist"}, err)
+}
+
+func TestProcessDataDeletionsClaimed(t *testing.T) {
+	_, mockDdb, _, _ := setupMocks()
+	mockDdb.On("ScanPages", mock.Anything, mock.Anything).Return(&dynamodb.ScanOutput{
+		Items: []map[string]*dynamodb.AttributeValue{marshalItem(t, testItem(models.DeletionQuerying))},
+	}, nil)
+	mockDdb.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{},
+		awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "claimed", nil))
+
+	require.NoError(t, API{}.ProcessDataDeletions(&models.ProcessDataDeletionsInput{}))
+	mockDdb.AssertExpectations(t)
+	mockDdb.AssertNotCalled(t, "PutItem", mock.Anything)
+}
+
+func TestProcessDataDeletionsRunning(t *testing.T) {
+	mockAthena, mockDdb, _, _ := setupMocks()
+	mockDdb.On("ScanPages", mock.Anything, mock.Anything).Return(&dynamodb.ScanOutput{
+		Items: []map[string]*dynamodb.AttributeValue{marshalItem(t, testItem(models.DeletionQuerying))},
+	}, nil)
+	mockDdb.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil)
+	mockDdb.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+	mockAthena.On("GetQueryExecution", mock.Anything).Return(&athena.GetQueryExecutionOutput{
+		QueryExecution: &athena.QueryExecution{
+			Status: &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateRunning)},
+		},
+	}, nil)
+
+	require.NoError(t, API{}.ProcessDataDeletions(&models.ProcessDataDeletionsInput{}))
+	item := storedItem(t, mockDdb)
+	assert.Equal(t, models.DeletionQuerying, item.Status)
+	assert.Zero(t, item.LeaseExpiresAt)
+}
+
+func TestProcessDataDeletionsQueryFailed(t *testing.T) {
+	mockAthena, mockDdb, _, _ := setupMocks()
+	mockDdb.On("ScanPages", mock.Anything, mock.Anything).Return(&dynamodb.ScanOutput{
+		Items: []map[string]*dynamodb.AttributeValue{marshalItem(t, testItem(models.DeletionQuerying))},
+	}, nil)
+	mockDdb.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil)
+	mockDdb.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+	mockAthena.On("GetQueryExecution", mock.Anything).Return(&athena.GetQueryExecutionOutput{
+		QueryExecution: &athena.QueryExecution{
+			Status: &athena.QueryExecutionStatus{
+				State:             aws.String(athena.QueryExecutionStateFailed),
+				StateChangeReason: aws.String("syntax error"),
+			},
+		},
+	}, nil)
+
+	require.NoError(t, API{}.ProcessDataDeletions(&models.ProcessDataDeletionsInput{}))
+	item := storedItem(t, mockDdb)
+	assert.Equal(t, models.DeletionFailed, item.Status)
+	assert.Equal(t, "query "+testQueryID+" failed: syntax error", *item.Error)
+	assert.Equal(t, testNow, *item.CompletedAt)
+}
+
+func TestProcessDataDeletions(t *testing.T) {
+	mockAthena, mockDdb, mockKms, mockS3 := setupMocks()
+	mockDdb.On("ScanPages", mock.Anything, mock.Anything).Return(&dynamodb.ScanOutput{
+		Items: []map[string]*dynamodb.AttributeValue{marshalItem(t, testItem(models.DeletionQuerying))},
+	}, nil)
+	mockDdb.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil)
+	mockDdb.On("DeleteItem", mock.Anything).Return(&dynamodb.DeleteItemOutput{}, nil)
+	mockDdb.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+	mockAthena.On("GetQueryExecution", mock.Anything).Return(&athena.GetQueryExecutionOutput{
+		QueryExecution: &athena.QueryExecution{
+			Status: &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateSucceeded)},
+		},
+	}, nil)
+	mockAthena.On("GetQueryResults", mock.Anything).Return(&athena.GetQueryResultsOutput{
+		ResultSet: &athena.ResultSet{
+			Rows: []*athena.Row{
+				{Data: []*athena.Datum{{VarCharValue: aws.String("path")}}},
+				{Data: []*athena.Datum{{VarCharValue: aws.String(testPath)}}},
+			},
+		},
+	}, nil)
+
+	objectBody := gzipLines(t, matchingRow, otherIPRow)
+	mockS3.On("GetObject", &s3.GetObjectInput{Bucket: aws.String(testBucket), Key: aws.String(testKey)}).Return(
+		&s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(objectBody)), VersionId: aws.String("v1")}, nil)
+	mockS3.On("PutObject", mock.Anything).Return(&s3.PutObjectOutput{VersionId: aws.String("v2")}, nil)
+	mockS3.On("ListObjectVersionsPages", mock.Anything, mock.Anything).Return(&s3.ListObjectVersionsOutput{}, nil)
+	mockKms.On("Sign", mock.Anything).Return(&kms.SignOutput{Signature: []byte("signature")}, nil)
+
+	require.NoError(t, API{}.ProcessDataDeletions(&models.ProcessDataDeletionsInput{}))
+	mockS3.AssertExpectations(t)
+	mockKms.AssertExpectations(t)
+
+	expectedObject := &models.DeletedObject{Path: testPath, Rows: 2, DeletedRows: 1, Done: true}
+	var manifest []*models.DeletedObject
+	require.NoError(t, jsoniter.Unmarshal(mockS3.bodies["data_deletion/"+testDeletionID+"/manifest.json"], &manifest))
+	assert.Equal(t, []*models.DeletedObject{expectedObject}, manifest)
+	assert.Equal(t, otherIPRow+"\n", gunzip(t, mockS3.bodies[testKey]))
+
+	reportBody := mockS3.bodies["data_deletion/"+testDeletionID+"/report.json"]
+	var report models.DeletionReport
+	require.NoError(t, jsoniter.Unmarshal(reportBody, &report))
+	assert.Equal(t, models.DeletionReport{
+		DeletionID:    testDeletionID,
+		RequestedBy:   "user",
+		Reason:        "erasure request",
+		IndicatorType: "ip_address",
+		Indicator:     "1.2.3.4",
+		StartTime:     testStart,
+		EndTime:       testEnd,
+		CreatedAt:     testNow.Add(-time.Hour),
+		CompletedAt:   testNow,
+		DeletedRows:   1,
+		Objects:       []*models.DeletedObject{expectedObject},
+		PurgedPrefixes: []string{
+			"logs/test_events/compacted/year=2020/month=03/day=01/hour=23/",
+			"logs/test_events/year=2020/month=03/day=01/hour=23/",
+		},
+		OutOfScope: models.DeletionOutOfScope,
+	}, report)
+
+	digest := sha256.Sum256(reportBody)
+	mockKms.AssertCalled(t, "Sign", &kms.SignInput{
+		KeyId:            aws.String("key-id"),
+		Message:          digest[:],
+		MessageType:      aws.String(kms.MessageTypeDigest),
+		SigningAlgorithm: aws.String(kms.SigningAlgorithmSpecRsassaPssSha256),
+	})
+
+	item := storedItem(t, mockDdb)
+	assert.Equal(t, models.DeletionSucceeded, item.Status)
+	assert.Equal(t, int64(1), *item.ObjectCount)
+	assert.Equal(t, int64(1), *item.DeletedRows)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("signature")), *item.ReportSignature)
+	assert.Zero(t, item.LeaseExpiresAt)
+
+	// the partition was locked while the object was rewritten
+	lockKey := map[string]*dynamodb.AttributeValue{
+		"prefix": {S: aws.String("logs/test_events/year=2020/month=03/day=01/hour=23/")},
+	}
+	mockDdb.AssertCalled(t, "UpdateItem", mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+		return *input.TableName == "locks" && assert.ObjectsAreEqual(lockKey, input.Key)
+	}))
+	mockDdb.AssertCalled(t, "DeleteItem", mock.MatchedBy(func(input *dynamodb.DeleteItemInput) bool {
+		return *input.TableName == "locks" && assert.ObjectsAreEqual(lockKey, input.Key)
+	}))
+}
+
+func TestProcessDataDeletionsDeadline(t *testing.T) {
+	_, mockDdb, _, mockS3 := setupMocks()
+	item := testItem(models.DeletionDeleting)
+	mockDdb.On("ScanPages", mock.Anything, mock.Anything).Return(&dynamodb.ScanOutput{
+		Items: []map[string]*dynamodb.AttributeValue{marshalItem(t, item)},
+	}, nil)
+	mockDdb.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil)
+	mockDdb.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+	mockS3.On("PutObject", mock.Anything).Return(&s3.PutObjectOutput{}, nil)
+	require.NoError(t, writeManifest(testDeletionID, []*models.DeletedObject{{Path: testPath}}))
+
+	// the processing time is over before the first object
+	calls := 0
+	now = func() time.Time {
+		calls++
+		return testNow.Add(time.Duration(calls) * processingTime)
+	}
+	require.NoError(t, API{}.ProcessDataDeletions(&models.ProcessDataDeletionsInput{}))
+	mockS3.AssertNotCalled(t, "GetObject", mock.Anything)
+	assert.Equal(t, models.DeletionDeleting, storedItem(t, mockDdb).Status)
+}
+
+func TestProcessDataDeletionsPartitionLocked(t *testing.T) {
+	_, mockDdb, _, mockS3 := setupMocks()
+	mockDdb.On("ScanPages", mock.Anything, mock.Anything).Return(&dynamodb.ScanOutput{
+		Items: []map[string]*dynamodb.AttributeValue{marshalItem(t, testItem(models.DeletionDeleting))},
+	}, nil)
+	mockDdb.On("UpdateItem", mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+		return *input.TableName == "locks"
+	})).Return(&dynamodb.UpdateItemOutput{}, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "locked", nil))
+	mockDdb.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil)
+	mockDdb.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+	mockS3.On("PutObject", mock.Anything).Return(&s3.PutObjectOutput{}, nil)
+	require.NoError(t, writeManifest(testDeletionID, []*models.DeletedObject{{Path: testPath}}))
+
+	// the compactor holds the lock, the deletion resumes later
+	require.NoError(t, API{}.ProcessDataDeletions(&models.ProcessDataDeletionsInput{}))
+	mockS3.AssertNotCalled(t, "GetObject", mock.Anything)
+	mockDdb.AssertNotCalled(t, "DeleteItem", mock.Anything)
+	assert.Equal(t, models.DeletionDeleting, storedItem(t, mockDdb).Status)
+}
+
+func TestProcessDataDeletionsMissingObject(t *testing.T) {
+	_, mockDdb, mockKms, mockS3 := setupMocks()
+	mockDdb.On("ScanPages", mock.Anything, mock.Anything).Return(&dynamodb.ScanOutput{
+		Items: []map[string]*dynamodb.AttributeValue{marshalItem(t, testItem(models.DeletionDeleting))},
+	}, nil)
+	mockDdb.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil)
+	mockDdb.On("DeleteItem", mock.Anything).Return(&dynamodb.DeleteItemOutput{}, nil)
+	mockDdb.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+	mockS3.On("PutObject", mock.Anything).Return(&s3.PutObjectOutput{VersionId: aws.String("v2")}, nil)
+	mockS3.On("ListObjectVersionsPages", mock.Anything, mock.Anything).Return(&s3.ListObjectVersionsOutput{}, nil)
+	mockKms.On("Sign", mock.Anything).Return(&kms.SignOutput{Signature: []byte("signature")}, nil)
+	require.NoError(t, writeManifest(testDeletionID, []*models.DeletedObject{{Path: testPath}}))
+
+	// the compactor merged the object into a generation after the query
+	generationPrefix := "logs/test_events/compacted/year=2020/month=03/day=01/hour=23/"
+	compactedKey := generationPrefix + "20200303T000000Z-g/0.json.gz"
+	mockS3.On("GetObject", &s3.GetObjectInput{Bucket: aws.String(testBucket), Key: aws.String(testKey)}).Return(
+		nil, awserr.New(s3.ErrCodeNoSuchKey, "not found", nil))
+	mockS3.On("GetObject", &s3.GetObjectInput{Bucket: aws.String(testBucket), Key: aws.String(compactedKey)}).Return(
+		&s3.GetObjectOutput{
+			Body:      ioutil.NopCloser(bytes.NewReader(gzipLines(t, matchingRow, otherIPRow))),
+			VersionId: aws.String("v1"),
+		}, nil)
+	mockS3.On("ListObjectsV2Pages", mock.MatchedBy(func(input *s3.ListObjectsV2Input) bool {
+		return *input.Prefix == generationPrefix
+	}), mock.Anything).Return(&s3.ListObjectsV2Output{
+		Contents: []*s3.Object{{Key: aws.String(compactedKey)}, {Key: aws.String(generationPrefix + "20200303T000000Z-g.manifest.json")}},
+	}, nil)
+	mockS3.On("ListObjectsV2Pages", mock.Anything, mock.Anything).Return(&s3.ListObjectsV2Output{}, nil)
+
+	require.NoError(t, API{}.ProcessDataDeletions(&models.ProcessDataDeletionsInput{}))
+	mockS3.AssertExpectations(t)
+
+	var manifest []*models.DeletedObject
+	require.NoError(t, jsoniter.Unmarshal(mockS3.bodies["data_deletion/"+testDeletionID+"/manifest.json"], &manifest))
+	assert.Equal(t, []*models.DeletedObject{
+		{Path: testPath, Missing: true, Done: true},
+		{Path: "s3://" + testBucket + "/" + compactedKey, Rows: 2, DeletedRows: 1, Done: true},
+	}, manifest)
+	assert.Equal(t, otherIPRow+"\n", gunzip(t, mockS3.bodies[compactedKey]))
+	item := storedItem(t, mockDdb)
+	assert.Equal(t, models.DeletionSucceeded, item.Status)
+	assert.Equal(t, int64(2), *item.ObjectCount)
+	assert.Equal(t, int64(1), *item.DeletedRows)
+}
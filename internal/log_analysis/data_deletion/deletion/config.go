@@ -0,0 +1,71 @@
+package deletion
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/kelseyhightower/envconfig"
+
+	"github.com/panther-labs/panther/internal/log_analysis/partitionlock"
+)
+
+var (
+	env          envConfig
+	awsSession   *session.Session
+	athenaClient athenaiface.AthenaAPI
+	ddbClient    dynamodbiface.DynamoDBAPI
+	kmsClient    kmsiface.KMSAPI
+	s3Client     s3iface.S3API
+	locks        *partitionlock.Locker
+)
+
+type envConfig struct {
+	AthenaResultsBucket string `required:"true" split_words:"true"`
+	DeletionsTable      string `required:"true" split_words:"true"`
+	PartitionLocksTable string `required:"true" split_words:"true"`
+	ProcessedDataBucket string `required:"true" split_words:"true"`
+	SigningKeyID        string `required:"true" split_words:"true"`
+}
+
+// Setup parses the environment and builds the AWS clients.
+func Setup() {
+	envconfig.MustProcess("", &env)
+
+	awsSession = session.Must(session.NewSession())
+	athenaClient = athena.New(awsSession)
+	ddbClient = dynamodb.New(awsSession)
+	kmsClient = kms.New(awsSession)
+	s3Client = s3.New(awsSession)
+	locks = &partitionlock.Locker{Client: ddbClient, TableName: env.PartitionLocksTable}
+}
+
+// now is replaced in tests
+var now = func() time.Time {
+	return time.Now().UTC()
+}
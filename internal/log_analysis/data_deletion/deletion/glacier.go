@@ -0,0 +1,94 @@
+package deletion
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+
+	"github.com/panther-labs/panther/pkg/awsglue"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+// checkGlacier returns an InvalidInputError if objects of a table with the column may have moved to Glacier since start.
+// The log retention settings move them with lifecycle rules of the processed data bucket. Objects in Glacier can be
+// neither queried by Athena nor rewritten, their rows would not be found.
+// Objects are transitioned by their age, which is never more than the age of their events.
+func checkGlacier(tables []*awsglue.GlueTableMetadata, column string, start time.Time) error {
+	output, err := s3Client.GetBucketLifecycleConfiguration(
+		&s3.GetBucketLifecycleConfigurationInput{Bucket: aws.String(env.ProcessedDataBucket)})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "NoSuchLifecycleConfiguration" {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to get lifecycle configuration of %s", env.ProcessedDataBucket)
+	}
+
+	for _, rule := range output.Rules {
+		if aws.StringValue(rule.Status) != s3.ExpirationStatusEnabled {
+			continue
+		}
+		days := glacierDays(rule)
+		cutoff := now().AddDate(0, 0, -int(days))
+		if days == 0 || start.After(cutoff) {
+			continue
+		}
+		prefix := rulePrefix(rule)
+		for _, table := range tables {
+			if table.AnyColumns()[column] && strings.HasPrefix(table.Prefix(), prefix) {
+				return &genericapi.InvalidInputError{Message: "the data of " + table.DatabaseName() + "." +
+					table.TableName() + " older than " + strconv.FormatInt(days, 10) + " days is archived in Glacier " +
+					"and cannot be deleted, the start time must be after " + cutoff.Format(time.RFC3339)}
+			}
+		}
+	}
+	return nil
+}
+
+// glacierDays returns the age at which the rule moves objects to Glacier, 0 if it does not
+func glacierDays(rule *s3.LifecycleRule) int64 {
+	for _, transition := range rule.Transitions {
+		switch aws.StringValue(transition.StorageClass) {
+		case s3.TransitionStorageClassGlacier, s3.TransitionStorageClassDeepArchive:
+			return aws.Int64Value(transition.Days)
+		}
+	}
+	return 0
+}
+
+func rulePrefix(rule *s3.LifecycleRule) string {
+	if rule.Filter != nil {
+		if rule.Filter.Prefix != nil {
+			return *rule.Filter.Prefix
+		}
+		if rule.Filter.And != nil {
+			return aws.StringValue(rule.Filter.And.Prefix)
+		}
+		if rule.Filter.Tag != nil {
+			return "" // tagged objects of any table
+		}
+	}
+	return aws.StringValue(rule.Prefix)
+}
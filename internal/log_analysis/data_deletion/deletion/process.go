@@ -0,0 +1,343 @@
+package deletion
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/s3"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/api/lambda/deletion/models"
+	"github.com/panther-labs/panther/pkg/awsathena"
+)
+
+const resultsPageSize = 1000 // the limit of GetQueryResults
+
+// processingTime bounds an invocation well within the lambda timeout, the next invocation resumes the deletions
+const processingTime = 10 * time.Minute
+
+// processDeletion advances a claimed deletion, the item is updated and stored by the caller
+func processDeletion(item *deletionItem, deadline time.Time) error {
+	if item.Status == models.DeletionQuerying {
+		done, err := finishQuery(item)
+		if err != nil || !done {
+			return err
+		}
+	}
+
+	// the manifest has the objects to rewrite and tracks the progress, it is stored after each object
+	objects, err := readManifest(item.DeletionID)
+	if err != nil {
+		return err
+	}
+	view := indicatorView(item.IndicatorType)
+	if view == nil {
+		return errors.Errorf("unknown indicator type %s", item.IndicatorType)
+	}
+	m := &matcher{column: view.Column, indicator: item.Indicator, start: item.StartTime, end: item.EndTime}
+	// the compactor must not merge the objects of a partition while they are rewritten
+	locked := make(map[string]struct{})
+	defer releaseLocks(item.DeletionID, locked)
+	// objects are appended while iterating when a partition is rescanned
+	for i := 0; i < len(objects); i++ {
+		object := objects[i]
+		if object.Done {
+			continue
+		}
+		if now().After(deadline) {
+			return nil // resumed by the next invocation
+		}
+		var acquired bool
+		if acquired, err = lockPartition(item.DeletionID, object, locked); err != nil {
+			return err
+		}
+		if !acquired {
+			return nil // the partition is being compacted, resumed by the next invocation
+		}
+		if err = rewriteObject(object, m); err != nil {
+			return err
+		}
+		if object.Missing {
+			// merged by the compactor since the query, the rows are in other objects of the partition
+			if objects, err = rescanPartition(object, objects); err != nil {
+				return err
+			}
+			item.ObjectCount = aws.Int64(int64(len(objects)))
+		}
+		object.Done = true
+		if err = writeManifest(item.DeletionID, objects); err != nil {
+			return err
+		}
+	}
+
+	// the processed data bucket is versioned, the rows also live in the noncurrent versions of the partitions
+	prefixes, err := affectedPrefixes(objects)
+	if err != nil {
+		return err
+	}
+	for _, prefix := range prefixes {
+		if now().After(deadline) {
+			return nil // resumed by the next invocation, purging again is harmless
+		}
+		if err = purgeNoncurrentVersions(env.ProcessedDataBucket, prefix); err != nil {
+			return err
+		}
+	}
+	return writeReport(item, objects, prefixes)
+}
+
+// lockPartition locks the partition of the object for the deletion unless it is locked already
+func lockPartition(deletionID string, object *models.DeletedObject, locked map[string]struct{}) (bool, error) {
+	_, key, err := parseS3Path(object.Path)
+	if err != nil {
+		return false, err
+	}
+	logPrefix, _, err := partitionPrefixes(key)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := locked[logPrefix]; ok {
+		return true, nil
+	}
+	acquired, err := locks.Acquire(logPrefix, deletionID)
+	if err != nil || !acquired {
+		return false, err
+	}
+	locked[logPrefix] = struct{}{}
+	return true, nil
+}
+
+// releaseLocks unlocks the partitions at the end of an invocation, leaving them locked only delays compaction
+func releaseLocks(deletionID string, locked map[string]struct{}) {
+	for prefix := range locked {
+		if err := locks.Release(prefix, deletionID); err != nil {
+			zap.L().Warn("failed to unlock partition", zap.String("prefix", prefix), zap.Error(err))
+		}
+	}
+}
+
+// rescanPartition appends the current objects of the partition of the missing object to the objects,
+// unless they are there already. They are rewritten like the objects found by the query.
+func rescanPartition(missing *models.DeletedObject, objects []*models.DeletedObject) ([]*models.DeletedObject, error) {
+	_, key, err := parseS3Path(missing.Path)
+	if err != nil {
+		return nil, err
+	}
+	logPrefix, generationsPrefix, err := partitionPrefixes(key)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]struct{}, len(objects))
+	for _, object := range objects {
+		known[object.Path] = struct{}{}
+	}
+	for _, prefix := range []string{logPrefix, generationsPrefix} {
+		paths, err := listDataObjects(env.ProcessedDataBucket, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			if _, ok := known[path]; !ok {
+				known[path] = struct{}{}
+				objects = append(objects, &models.DeletedObject{Path: path})
+			}
+		}
+	}
+	return objects, nil
+}
+
+// affectedPrefixes returns the sorted prefixes of the partitions of the objects, with their compacted generations
+func affectedPrefixes(objects []*models.DeletedObject) ([]string, error) {
+	unique := make(map[string]struct{})
+	for _, object := range objects {
+		_, key, err := parseS3Path(object.Path)
+		if err != nil {
+			return nil, err
+		}
+		logPrefix, generationsPrefix, err := partitionPrefixes(key)
+		if err != nil {
+			return nil, err
+		}
+		unique[logPrefix] = struct{}{}
+		unique[generationsPrefix] = struct{}{}
+	}
+	prefixes := make([]string, 0, len(unique))
+	for prefix := range unique {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes, nil
+}
+
+// finishQuery moves the deletion to deleting with the objects found by its query, it returns false while the
+// query is running
+func finishQuery(item *deletionItem) (bool, error) {
+	executionOutput, err := awsathena.Status(athenaClient, item.QueryID)
+	if err != nil {
+		return false, err
+	}
+	status := executionOutput.QueryExecution.Status
+	switch aws.StringValue(status.State) {
+	case athena.QueryExecutionStateSucceeded:
+	case athena.QueryExecutionStateFailed, athena.QueryExecutionStateCancelled:
+		return false, errors.Errorf("query %s failed: %s", item.QueryID, aws.StringValue(status.StateChangeReason))
+	default:
+		return false, nil
+	}
+
+	var objects []*models.DeletedObject
+	var nextToken *string
+	for {
+		resultsOutput, err := awsathena.Results(athenaClient, item.QueryID, nextToken, aws.Int64(resultsPageSize))
+		if err != nil {
+			return false, err
+		}
+		for _, row := range resultsOutput.ResultSet.Rows {
+			if len(row.Data) == 0 {
+				continue
+			}
+			path := aws.StringValue(row.Data[0].VarCharValue)
+			if path == "" || path == "path" { // the first page starts with the column name
+				continue
+			}
+			objects = append(objects, &models.DeletedObject{Path: path})
+		}
+		if resultsOutput.NextToken == nil {
+			break
+		}
+		nextToken = resultsOutput.NextToken
+	}
+
+	if err = writeManifest(item.DeletionID, objects); err != nil {
+		return false, err
+	}
+	item.Status = models.DeletionDeleting
+	item.ObjectCount = aws.Int64(int64(len(objects)))
+	return true, nil
+}
+
+func manifestKey(deletionID string) string {
+	return deletionsPrefix + deletionID + "/manifest.json"
+}
+
+func reportKey(deletionID string) string {
+	return deletionsPrefix + deletionID + "/report.json"
+}
+
+func readManifest(deletionID string) ([]*models.DeletedObject, error) {
+	output, err := s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(env.AthenaResultsBucket),
+		Key:    aws.String(manifestKey(deletionID)),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get manifest of deletion %s", deletionID)
+	}
+	defer output.Body.Close()
+	body, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read manifest of deletion %s", deletionID)
+	}
+	var objects []*models.DeletedObject
+	if err = jsoniter.Unmarshal(body, &objects); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal manifest of deletion %s", deletionID)
+	}
+	return objects, nil
+}
+
+func writeManifest(deletionID string, objects []*models.DeletedObject) error {
+	body, err := jsoniter.Marshal(objects)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal manifest of deletion %s", deletionID)
+	}
+	_, err = s3Client.PutObject(&s3.PutObjectInput{
+		Body:        bytes.NewReader(body),
+		Bucket:      aws.String(env.AthenaResultsBucket),
+		ContentType: aws.String("application/json"),
+		Key:         aws.String(manifestKey(deletionID)),
+	})
+	return errors.Wrapf(err, "failed to store manifest of deletion %s", deletionID)
+}
+
+// writeReport stores the report of the deletion and signs its SHA-256 digest with the signing key
+func writeReport(item *deletionItem, objects []*models.DeletedObject, purgedPrefixes []string) error {
+	report := &models.DeletionReport{
+		DeletionID:     item.DeletionID,
+		RequestedBy:    item.UserID,
+		Reason:         item.Reason,
+		IndicatorType:  item.IndicatorType,
+		Indicator:      item.Indicator,
+		StartTime:      item.StartTime,
+		EndTime:        item.EndTime,
+		CreatedAt:      item.CreatedAt,
+		CompletedAt:    now(),
+		Objects:        objects,
+		PurgedPrefixes: purgedPrefixes,
+		OutOfScope:     models.DeletionOutOfScope,
+	}
+	if report.Objects == nil {
+		report.Objects = []*models.DeletedObject{}
+	}
+	for _, object := range objects {
+		report.DeletedRows += object.DeletedRows
+	}
+	body, err := jsoniter.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal report of deletion %s", item.DeletionID)
+	}
+
+	digest := sha256.Sum256(body)
+	signOutput, err := kmsClient.Sign(&kms.SignInput{
+		KeyId:            aws.String(env.SigningKeyID),
+		Message:          digest[:],
+		MessageType:      aws.String(kms.MessageTypeDigest),
+		SigningAlgorithm: aws.String(kms.SigningAlgorithmSpecRsassaPssSha256),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to sign report of deletion %s", item.DeletionID)
+	}
+	signature := base64.StdEncoding.EncodeToString(signOutput.Signature)
+
+	_, err = s3Client.PutObject(&s3.PutObjectInput{
+		Body:        bytes.NewReader(body),
+		Bucket:      aws.String(env.AthenaResultsBucket),
+		ContentType: aws.String("application/json"),
+		Key:         aws.String(reportKey(item.DeletionID)),
+		Metadata:    map[string]*string{"signature": aws.String(signature)},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to store report of deletion %s", item.DeletionID)
+	}
+
+	item.Status = models.DeletionSucceeded
+	item.CompletedAt = aws.Time(report.CompletedAt)
+	item.DeletedRows = aws.Int64(report.DeletedRows)
+	item.ReportSignature = aws.String(signature)
+	return nil
+}
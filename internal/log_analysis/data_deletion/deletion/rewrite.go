@@ -0,0 +1,281 @@
+package deletion
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+
+	"github.com/panther-labs/panther/api/lambda/deletion/models"
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+// eventTimeLayout is the layout of p_event_time in the processed logs
+const eventTimeLayout = "2006-01-02 15:04:05.000000000"
+
+// matcher selects the rows to delete
+type matcher struct {
+	column    string // the p_any column
+	indicator string
+	start     time.Time
+	end       time.Time
+}
+
+func (m *matcher) matches(line []byte) bool {
+	eventTime, err := time.Parse(eventTimeLayout, jsoniter.Get(line, "p_event_time").ToString())
+	if err != nil || eventTime.Before(m.start) || !eventTime.Before(m.end) {
+		return false
+	}
+	values := jsoniter.Get(line, m.column)
+	for i := 0; i < values.Size(); i++ {
+		if values.Get(i).ToString() == m.indicator {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteObject replaces the gzipped JSON lines object with a version without the matching rows, and deletes the
+// previous versions of the object. The object is deleted if all rows match. A missing object is marked Missing.
+//
+// Rewriting an object again deletes nothing more, the previous versions are deleted anyway in case an earlier
+// rewrite stopped before deleting them.
+func rewriteObject(object *models.DeletedObject, m *matcher) error {
+	bucket, key, err := parseS3Path(object.Path)
+	if err != nil {
+		return err
+	}
+	if bucket != env.ProcessedDataBucket {
+		return errors.Errorf("%s is not in the processed data bucket", object.Path)
+	}
+
+	getOutput, err := s3Client.GetObject(&s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			// merged by the compactor since the query, or removed by an earlier rewrite which was not recorded:
+			// the caller rescans the partition, the rows are not known to be deleted
+			object.Missing = true
+			return deleteVersions(bucket, key, "")
+		}
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "InvalidObjectState" {
+			return errors.Errorf("%s is archived in Glacier and cannot be rewritten", object.Path)
+		}
+		return errors.Wrapf(err, "failed to get %s", object.Path)
+	}
+	defer getOutput.Body.Close()
+	kept, rows, deleted, err := filterRows(getOutput.Body, m)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", object.Path)
+	}
+	if deleted > 0 || object.Rows == 0 { // keep the counts of an earlier rewrite
+		object.Rows, object.DeletedRows = rows, deleted
+	}
+
+	currentVersion := aws.StringValue(getOutput.VersionId)
+	switch {
+	case deleted > 0 && rows == deleted:
+		object.Removed = true
+		currentVersion = "" // delete all versions
+	case deleted > 0:
+		putOutput, err := s3Client.PutObject(&s3.PutObjectInput{
+			Body:            bytes.NewReader(kept),
+			Bucket:          aws.String(bucket),
+			ContentEncoding: getOutput.ContentEncoding,
+			ContentType:     getOutput.ContentType,
+			Key:             aws.String(key),
+			Metadata:        getOutput.Metadata,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to rewrite %s", object.Path)
+		}
+		currentVersion = aws.StringValue(putOutput.VersionId)
+	}
+	return deleteVersions(bucket, key, currentVersion)
+}
+
+// filterRows returns the gzipped rows which do not match, the number of rows and the number of matching rows
+func filterRows(body io.Reader, m *matcher) ([]byte, int64, int64, error) {
+	gzipReader, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	var kept bytes.Buffer
+	gzipWriter := gzip.NewWriter(&kept)
+	reader := bufio.NewReader(gzipReader)
+	var rows, deleted int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			rows++
+			if m.matches(line) {
+				deleted++
+			} else if _, writeErr := gzipWriter.Write(line); writeErr != nil {
+				return nil, 0, 0, writeErr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, 0, err
+		}
+	}
+	if err = gzipWriter.Close(); err != nil {
+		return nil, 0, 0, err
+	}
+	return kept.Bytes(), rows, deleted, nil
+}
+
+// listDataObjects returns the paths of the current data objects under prefix, not the compactor manifests
+func listDataObjects(bucket, prefix string) ([]string, error) {
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucket), Prefix: aws.String(prefix)}
+	var paths []string
+	err := s3Client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			if strings.HasSuffix(aws.StringValue(object.Key), ".gz") {
+				paths = append(paths, "s3://"+bucket+"/"+aws.StringValue(object.Key))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list objects under s3://%s/%s", bucket, prefix)
+	}
+	return paths, nil
+}
+
+// deleteVersions deletes the versions of the object except the current version, all versions if it is empty
+func deleteVersions(bucket, key, currentVersion string) error {
+	input := &s3.ListObjectVersionsInput{Bucket: aws.String(bucket), Prefix: aws.String(key)}
+	var versions []*s3.ObjectIdentifier
+	err := s3Client.ListObjectVersionsPages(input, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		for _, version := range page.Versions {
+			if aws.StringValue(version.Key) == key && aws.StringValue(version.VersionId) != currentVersion {
+				versions = append(versions, &s3.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+			}
+		}
+		for _, marker := range page.DeleteMarkers {
+			if aws.StringValue(marker.Key) == key {
+				versions = append(versions, &s3.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list versions of s3://%s/%s", bucket, key)
+	}
+	return deleteObjectVersions(bucket, key, versions)
+}
+
+// purgeNoncurrentVersions deletes the noncurrent versions and the delete markers of all objects under prefix.
+// They hold the rows of objects the compactor merged and of the generations it replaced.
+func purgeNoncurrentVersions(bucket, prefix string) error {
+	input := &s3.ListObjectVersionsInput{Bucket: aws.String(bucket), Prefix: aws.String(prefix)}
+	var versions, markers []*s3.ObjectIdentifier
+	err := s3Client.ListObjectVersionsPages(input, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		for _, version := range page.Versions {
+			if !aws.BoolValue(version.IsLatest) {
+				versions = append(versions, &s3.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+			}
+		}
+		for _, marker := range page.DeleteMarkers {
+			markers = append(markers, &s3.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		return true
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list versions under s3://%s/%s", bucket, prefix)
+	}
+	// the versions go first, removing a delete marker before them would make a deleted object visible again
+	return deleteObjectVersions(bucket, prefix, append(versions, markers...))
+}
+
+// deleteObjectVersions deletes the versions in batches, path only describes them in errors
+func deleteObjectVersions(bucket, path string, versions []*s3.ObjectIdentifier) error {
+	for len(versions) > 0 {
+		batch := versions
+		if len(batch) > maxDeleteObjects {
+			batch = batch[:maxDeleteObjects]
+		}
+		versions = versions[len(batch):]
+		output, err := s3Client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3.Delete{Objects: batch, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete versions of s3://%s/%s", bucket, path)
+		}
+		if len(output.Errors) > 0 {
+			return errors.Errorf("failed to delete version %s of s3://%s/%s: %s", aws.StringValue(output.Errors[0].VersionId),
+				bucket, aws.StringValue(output.Errors[0].Key), aws.StringValue(output.Errors[0].Message))
+		}
+	}
+	return nil
+}
+
+// partitionPrefixes returns the prefix the log processor writes the partition of the object to and the prefix
+// the compactor writes the generations of the partition to, such as
+// logs/aws_cloudtrail/year=2020/month=03/day=01/hour=23/ and logs/aws_cloudtrail/compacted/year=2020/month=03/day=01/hour=23/
+func partitionPrefixes(key string) (string, string, error) {
+	parts := strings.Split(key, "/")
+	start := -1
+	for i, part := range parts {
+		if strings.HasPrefix(part, "year=") {
+			start = i
+			break
+		}
+	}
+	if start < 1 {
+		return "", "", errors.Errorf("%s is not in a partition", key)
+	}
+	end := start + 1
+	for end < len(parts)-1 && (strings.HasPrefix(parts[end], "month=") ||
+		strings.HasPrefix(parts[end], "day=") || strings.HasPrefix(parts[end], "hour=")) {
+
+		end++
+	}
+	tablePrefix := strings.Join(parts[:start], "/") + "/"
+	tablePrefix = strings.TrimSuffix(tablePrefix, awsglue.CompactedPrefix)
+	partition := strings.Join(parts[start:end], "/") + "/"
+	return tablePrefix + partition, tablePrefix + awsglue.CompactedPrefix + partition, nil
+}
+
+const maxDeleteObjects = 1000 // the limit of DeleteObjects
+
+// parseS3Path returns the bucket and key of an s3:// path
+func parseS3Path(path string) (string, string, error) {
+	if !strings.HasPrefix(path, "s3://") {
+		return "", "", errors.Errorf("invalid S3 path %s", path)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(path, "s3://"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.Errorf("invalid S3 path %s", path)
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,311 @@
+package deletion
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/deletion/models"
+)
+
+const (
+	testBucket = "processed"
+	testKey    = "logs/test_events/year=2020/month=03/day=01/hour=23/20200301T233000Z-0.json.gz"
+	testPath   = "s3://" + testBucket + "/" + testKey
+)
+
+type s3Mock struct {
+	s3iface.S3API
+	mock.Mock
+	bodies map[string][]byte // the bodies put by key
+}
+
+// GetObject returns the objects put in the results bucket, such as the manifests
+func (m *s3Mock) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	if body, ok := m.bodies[*input.Key]; ok && *input.Bucket == env.AthenaResultsBucket {
+		return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+	}
+	args := m.Called(input)
+	output, _ := args.Get(0).(*s3.GetObjectOutput)
+	return output, args.Error(1)
+}
+
+func (m *s3Mock) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	if m.bodies == nil {
+		m.bodies = make(map[string][]byte)
+	}
+	m.bodies[*input.Key] = body
+	args := m.Called(input)
+	return args.Get(0).(*s3.PutObjectOutput), args.Error(1)
+}
+
+func (m *s3Mock) ListObjectVersionsPages(input *s3.ListObjectVersionsInput,
+	fn func(*s3.ListObjectVersionsOutput, bool) bool) error {
+
+	args := m.Called(input, fn)
+	fn(args.Get(0).(*s3.ListObjectVersionsOutput), true)
+	return args.Error(1)
+}
+
+func (m *s3Mock) GetBucketLifecycleConfiguration(
+	input *s3.GetBucketLifecycleConfigurationInput) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+
+	args := m.Called(input)
+	output, _ := args.Get(0).(*s3.GetBucketLifecycleConfigurationOutput)
+	return output, args.Error(1)
+}
+
+func (m *s3Mock) ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	args := m.Called(input, fn)
+	fn(args.Get(0).(*s3.ListObjectsV2Output), true)
+	return args.Error(1)
+}
+
+func (m *s3Mock) DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*s3.DeleteObjectsOutput), args.Error(1)
+}
+
+func (m *s3Mock) GetObjectRequest(input *s3.GetObjectInput) (*request.Request, *s3.GetObjectOutput) {
+	return s3.New(testSession()).GetObjectRequest(input)
+}
+
+func gzipLines(t *testing.T, lines ...string) []byte {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	for _, line := range lines {
+		_, err := writer.Write([]byte(line + "\n"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func gunzip(t *testing.T, body []byte) string {
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	result, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	return string(result)
+}
+
+const (
+	matchingRow = `{"name":"a","p_event_time":"2020-03-01 23:45:00.000000000","p_any_ip_addresses":["1.2.3.4","5.6.7.8"]}`
+	otherIPRow  = `{"name":"b","p_event_time":"2020-03-01 23:45:00.000000000","p_any_ip_addresses":["5.6.7.8"]}`
+	earlyRow    = `{"name":"c","p_event_time":"2020-03-01 23:15:00.000000000","p_any_ip_addresses":["1.2.3.4"]}`
+)
+
+func testMatcher() *matcher {
+	return &matcher{column: "p_any_ip_addresses", indicator: "1.2.3.4", start: testStart, end: testEnd}
+}
+
+func TestMatcher(t *testing.T) {
+	m := testMatcher()
+	assert.True(t, m.matches([]byte(matchingRow)))
+	assert.False(t, m.matches([]byte(otherIPRow)))
+	assert.False(t, m.matches([]byte(earlyRow)))
+	assert.False(t, m.matches([]byte(`{"name":"d"}`)))
+}
+
+func TestRewriteObject(t *testing.T) {
+	env.ProcessedDataBucket = testBucket
+	mockS3 := &s3Mock{}
+	s3Client = mockS3
+
+	mockS3.On("GetObject", &s3.GetObjectInput{Bucket: aws.String(testBucket), Key: aws.String(testKey)}).Return(
+		&s3.GetObjectOutput{
+			Body:            ioutil.NopCloser(bytes.NewReader(gzipLines(t, matchingRow, otherIPRow, earlyRow))),
+			ContentEncoding: aws.String("gzip"),
+			ContentType:     aws.String("application/json"),
+			VersionId:       aws.String("v1"),
+		}, nil)
+	mockS3.On("PutObject", mock.Anything).Return(&s3.PutObjectOutput{VersionId: aws.String("v2")}, nil)
+	mockS3.On("ListObjectVersionsPages", mock.Anything, mock.Anything).Return(&s3.ListObjectVersionsOutput{
+		Versions: []*s3.ObjectVersion{
+			{Key: aws.String(testKey), VersionId: aws.String("v1")},
+			{Key: aws.String(testKey), VersionId: aws.String("v2")},
+			{Key: aws.String(testKey + ".other"), VersionId: aws.String("v3")},
+		},
+	}, nil)
+	mockS3.On("DeleteObjects", &s3.DeleteObjectsInput{
+		Bucket: aws.String(testBucket),
+		Delete: &s3.Delete{
+			Objects: []*s3.ObjectIdentifier{{Key: aws.String(testKey), VersionId: aws.String("v1")}},
+			Quiet:   aws.Bool(true),
+		},
+	}).Return(&s3.DeleteObjectsOutput{}, nil)
+
+	object := &models.DeletedObject{Path: testPath}
+	require.NoError(t, rewriteObject(object, testMatcher()))
+	mockS3.AssertExpectations(t)
+	assert.Equal(t, &models.DeletedObject{Path: testPath, Rows: 3, DeletedRows: 1}, object)
+	assert.Equal(t, otherIPRow+"\n"+earlyRow+"\n", gunzip(t, mockS3.bodies[testKey]))
+	putInput := mockS3.Calls[1].Arguments.Get(0).(*s3.PutObjectInput)
+	assert.Equal(t, "gzip", *putInput.ContentEncoding)
+	assert.Equal(t, "application/json", *putInput.ContentType)
+}
+
+func TestRewriteObjectAllRows(t *testing.T) {
+	env.ProcessedDataBucket = testBucket
+	mockS3 := &s3Mock{}
+	s3Client = mockS3
+
+	mockS3.On("GetObject", mock.Anything).Return(&s3.GetObjectOutput{
+		Body:      ioutil.NopCloser(bytes.NewReader(gzipLines(t, matchingRow))),
+		VersionId: aws.String("v1"),
+	}, nil)
+	mockS3.On("ListObjectVersionsPages", mock.Anything, mock.Anything).Return(&s3.ListObjectVersionsOutput{
+		Versions:      []*s3.ObjectVersion{{Key: aws.String(testKey), VersionId: aws.String("v1")}},
+		DeleteMarkers: []*s3.DeleteMarkerEntry{{Key: aws.String(testKey), VersionId: aws.String("m1")}},
+	}, nil)
+	mockS3.On("DeleteObjects", &s3.DeleteObjectsInput{
+		Bucket: aws.String(testBucket),
+		Delete: &s3.Delete{
+			Objects: []*s3.ObjectIdentifier{
+				{Key: aws.String(testKey), VersionId: aws.String("v1")},
+				{Key: aws.String(testKey), VersionId: aws.String("m1")},
+			},
+			Quiet: aws.Bool(true),
+		},
+	}).Return(&s3.DeleteObjectsOutput{}, nil)
+
+	object := &models.DeletedObject{Path: testPath}
+	require.NoError(t, rewriteObject(object, testMatcher()))
+	mockS3.AssertExpectations(t)
+	mockS3.AssertNotCalled(t, "PutObject", mock.Anything)
+	assert.Equal(t, &models.DeletedObject{Path: testPath, Rows: 1, DeletedRows: 1, Removed: true}, object)
+}
+
+func TestRewriteObjectMissing(t *testing.T) {
+	env.ProcessedDataBucket = testBucket
+	mockS3 := &s3Mock{}
+	s3Client = mockS3
+
+	mockS3.On("GetObject", mock.Anything).Return(nil, awserr.New(s3.ErrCodeNoSuchKey, "not found", nil))
+	mockS3.On("ListObjectVersionsPages", mock.Anything, mock.Anything).Return(&s3.ListObjectVersionsOutput{}, nil)
+
+	object := &models.DeletedObject{Path: testPath}
+	require.NoError(t, rewriteObject(object, testMatcher()))
+	mockS3.AssertExpectations(t)
+	mockS3.AssertNotCalled(t, "DeleteObjects", mock.Anything)
+	// not reported as removed, the rows may live in other objects
+	assert.Equal(t, &models.DeletedObject{Path: testPath, Missing: true}, object)
+}
+
+func TestRewriteObjectGlacier(t *testing.T) {
+	env.ProcessedDataBucket = testBucket
+	mockS3 := &s3Mock{}
+	s3Client = mockS3
+
+	mockS3.On("GetObject", mock.Anything).Return(nil, awserr.New("InvalidObjectState", "archived", nil))
+	err := rewriteObject(&models.DeletedObject{Path: testPath}, testMatcher())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "archived in Glacier")
+	mockS3.AssertNotCalled(t, "DeleteObjects", mock.Anything)
+}
+
+func TestRewriteObjectOtherBucket(t *testing.T) {
+	env.ProcessedDataBucket = testBucket
+	s3Client = &s3Mock{}
+	err := rewriteObject(&models.DeletedObject{Path: "s3://other/" + testKey}, testMatcher())
+	assert.EqualError(t, err, "s3://other/"+testKey+" is not in the processed data bucket")
+}
+
+func TestPurgeNoncurrentVersions(t *testing.T) {
+	mockS3 := &s3Mock{}
+	s3Client = mockS3
+
+	const prefix = "logs/test_events/year=2020/month=03/day=01/hour=23/"
+	mockS3.On("ListObjectVersionsPages", &s3.ListObjectVersionsInput{
+		Bucket: aws.String(testBucket),
+		Prefix: aws.String(prefix),
+	}, mock.Anything).Return(&s3.ListObjectVersionsOutput{
+		Versions: []*s3.ObjectVersion{
+			{Key: aws.String(testKey), VersionId: aws.String("v2"), IsLatest: aws.Bool(true)},
+			{Key: aws.String(testKey), VersionId: aws.String("v1"), IsLatest: aws.Bool(false)},
+			{Key: aws.String(prefix + "merged.json.gz"), VersionId: aws.String("v3"), IsLatest: aws.Bool(false)},
+		},
+		DeleteMarkers: []*s3.DeleteMarkerEntry{
+			{Key: aws.String(prefix + "merged.json.gz"), VersionId: aws.String("m1"), IsLatest: aws.Bool(true)},
+		},
+	}, nil)
+	mockS3.On("DeleteObjects", &s3.DeleteObjectsInput{
+		Bucket: aws.String(testBucket),
+		Delete: &s3.Delete{
+			Objects: []*s3.ObjectIdentifier{
+				{Key: aws.String(testKey), VersionId: aws.String("v1")},
+				{Key: aws.String(prefix + "merged.json.gz"), VersionId: aws.String("v3")},
+				{Key: aws.String(prefix + "merged.json.gz"), VersionId: aws.String("m1")},
+			},
+			Quiet: aws.Bool(true),
+		},
+	}).Return(&s3.DeleteObjectsOutput{}, nil)
+
+	require.NoError(t, purgeNoncurrentVersions(testBucket, prefix))
+	mockS3.AssertExpectations(t)
+}
+
+func TestPartitionPrefixes(t *testing.T) {
+	const (
+		logPrefix         = "logs/test_events/year=2020/month=03/day=01/hour=23/"
+		generationsPrefix = "logs/test_events/compacted/year=2020/month=03/day=01/hour=23/"
+	)
+	for _, key := range []string{testKey, generationsPrefix + "20200302T010000Z-1234/0.json.gz"} {
+		actualLogPrefix, actualGenerationsPrefix, err := partitionPrefixes(key)
+		require.NoError(t, err)
+		assert.Equal(t, logPrefix, actualLogPrefix, key)
+		assert.Equal(t, generationsPrefix, actualGenerationsPrefix, key)
+	}
+
+	actualLogPrefix, actualGenerationsPrefix, err := partitionPrefixes("rules/test_events/year=2020/month=03/day=01/0.json.gz")
+	require.NoError(t, err)
+	assert.Equal(t, "rules/test_events/year=2020/month=03/day=01/", actualLogPrefix)
+	assert.Equal(t, "rules/test_events/compacted/year=2020/month=03/day=01/", actualGenerationsPrefix)
+
+	_, _, err = partitionPrefixes("year=2020/month=03/0.json.gz")
+	assert.Error(t, err)
+}
+
+func TestParseS3Path(t *testing.T) {
+	bucket, key, err := parseS3Path(testPath)
+	require.NoError(t, err)
+	assert.Equal(t, testBucket, bucket)
+	assert.Equal(t, testKey, key)
+
+	for _, path := range []string{"processed/key", "s3://processed", "s3:///key", "s3://processed/"} {
+		_, _, err = parseS3Path(path)
+		assert.Error(t, err, path)
+	}
+}
@@ -0,0 +1,72 @@
+package deletion
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	logmodels "github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/registry"
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+const athenaTimeLayout = "2006-01-02 15:04:05.000"
+
+// deletionTables are the log and rule match tables, replaced in tests
+var deletionTables = func() []*awsglue.GlueTableMetadata {
+	var tables []*awsglue.GlueTableMetadata
+	for _, table := range registry.AvailableTables() {
+		// the rule match tables share the same structure as the logs
+		ruleTable := awsglue.NewGlueTableMetadata(
+			logmodels.RuleData, table.LogType(), table.Description(), awsglue.GlueTableHourly, table.EventStruct())
+		tables = append(tables, table, ruleTable)
+	}
+	return tables
+}
+
+// pathsSQL returns the query of the S3 paths of the objects with rows having the indicator in the p_any column and an
+// event time in [start, end). An empty string is returned if no table has the column.
+func pathsSQL(tables []*awsglue.GlueTableMetadata, column, indicator string, start, end time.Time) string {
+	tables = append([]*awsglue.GlueTableMetadata(nil), tables...)
+	sort.Slice(tables, func(i, j int) bool {
+		if tables[i].DatabaseName() != tables[j].DatabaseName() {
+			return tables[i].DatabaseName() < tables[j].DatabaseName()
+		}
+		return tables[i].TableName() < tables[j].TableName()
+	})
+
+	var selects []string
+	for _, table := range tables {
+		if !table.AnyColumns()[column] {
+			continue
+		}
+		selects = append(selects, `SELECT DISTINCT "$path" AS path`+
+			" FROM "+table.DatabaseName()+"."+table.TableName()+
+			" WHERE "+table.PartitionPredicate(start, end)+
+			" AND p_event_time >= timestamp '"+start.UTC().Format(athenaTimeLayout)+"'"+
+			" AND p_event_time < timestamp '"+end.UTC().Format(athenaTimeLayout)+"'"+
+			" AND contains("+column+", '"+strings.Replace(indicator, "'", "''", -1)+"')")
+	}
+	if len(selects) == 0 {
+		return ""
+	}
+	return strings.Join(selects, "\nUNION\n") + "\nORDER BY path"
+}
@@ -0,0 +1,69 @@
+package deletion
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+type testEvent struct {
+	Name        *string   `json:"name"`
+	IPAddresses *[]string `json:"p_any_ip_addresses,omitempty"`
+}
+
+var (
+	testStart = time.Date(2020, 3, 1, 23, 30, 0, 0, time.UTC)
+	testEnd   = time.Date(2020, 3, 2, 1, 0, 0, 0, time.UTC)
+)
+
+func testTables() []*awsglue.GlueTableMetadata {
+	return []*awsglue.GlueTableMetadata{
+		awsglue.NewGlueTableMetadata(models.LogData, "Test.Events", "test", awsglue.GlueTableHourly, &testEvent{}),
+		awsglue.NewGlueTableMetadata(models.RuleData, "Test.Events", "test", awsglue.GlueTableHourly, &testEvent{}),
+		awsglue.NewGlueTableMetadata(models.LogData, "Test.Other", "test", awsglue.GlueTableHourly, struct{}{}),
+	}
+}
+
+func TestPathsSQL(t *testing.T) {
+	partitions := "((year=2020 AND month=3 AND day=1 AND hour=23) OR (year=2020 AND month=3 AND day=2 AND hour=0))"
+	where := " WHERE " + partitions +
+		" AND p_event_time >= timestamp '2020-03-01 23:30:00.000'" +
+		" AND p_event_time < timestamp '2020-03-02 01:00:00.000'" +
+		" AND contains(p_any_ip_addresses, '1.2.3.4')"
+	expected := `SELECT DISTINCT "$path" AS path FROM panther_logs.test_events` + where +
+		"\nUNION\n" +
+		`SELECT DISTINCT "$path" AS path FROM panther_rule_matches.test_events` + where +
+		"\nORDER BY path"
+	assert.Equal(t, expected, pathsSQL(testTables(), "p_any_ip_addresses", "1.2.3.4", testStart, testEnd))
+}
+
+func TestPathsSQLEscape(t *testing.T) {
+	sql := pathsSQL(testTables(), "p_any_ip_addresses", "1.2.3.4') OR (1=1", testStart, testEnd)
+	assert.Contains(t, sql, "contains(p_any_ip_addresses, '1.2.3.4'') OR (1=1')")
+}
+
+func TestPathsSQLNoColumn(t *testing.T) {
+	assert.Equal(t, "", pathsSQL(testTables(), "p_any_domain_names", "example.com", testStart, testEnd))
+}
@@ -0,0 +1,164 @@
+package deletion
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/pkg/errors"
+
+	"github.com/panther-labs/panther/api/lambda/deletion/models"
+)
+
+const (
+	deletionsTablePartitionKey = "id"
+
+	// a deletion is processed by one lambda at a time, the lease outlives the lambda timeout
+	leaseDuration = 20 * time.Minute
+)
+
+// deletionItem is a deletion stored in the deletions table, the items do not expire because they are the audit
+// log of deletions
+type deletionItem struct {
+	DeletionID    string     `json:"id"`
+	UserID        string     `json:"userId"`
+	IndicatorType string     `json:"indicatorType"`
+	Indicator     string     `json:"indicator"`
+	StartTime     time.Time  `json:"startTime"`
+	EndTime       time.Time  `json:"endTime"`
+	Reason        string     `json:"reason"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	CompletedAt   *time.Time `json:"completedAt,omitempty"`
+	Status        string     `json:"status"`
+	Error         *string    `json:"error,omitempty"`
+	// QueryID is the Athena query finding the objects with matching rows
+	QueryID         string  `json:"queryId"`
+	ObjectCount     *int64  `json:"objectCount,omitempty"`
+	DeletedRows     *int64  `json:"deletedRows,omitempty"`
+	ReportSignature *string `json:"reportSignature,omitempty"`
+	// LeaseExpiresAt is set while a lambda processes the deletion
+	LeaseExpiresAt int64 `json:"leaseExpiresAt,omitempty"`
+}
+
+func (item *deletionItem) active() bool {
+	return item.Status == models.DeletionQuerying || item.Status == models.DeletionDeleting
+}
+
+func putDeletion(item *deletionItem) error {
+	ddbItem, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal deletion")
+	}
+	_, err = ddbClient.PutItem(&dynamodb.PutItemInput{
+		Item:      ddbItem,
+		TableName: aws.String(env.DeletionsTable),
+	})
+	return errors.Wrapf(err, "failed to store deletion %s", item.DeletionID)
+}
+
+// getDeletion returns nil if the deletion does not exist
+func getDeletion(deletionID string) (*deletionItem, error) {
+	output, err := ddbClient.GetItem(&dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			deletionsTablePartitionKey: {S: aws.String(deletionID)},
+		},
+		TableName: aws.String(env.DeletionsTable),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get deletion %s", deletionID)
+	}
+	if len(output.Item) == 0 {
+		return nil, nil
+	}
+	item := &deletionItem{}
+	if err = dynamodbattribute.UnmarshalMap(output.Item, item); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal deletion %s", deletionID)
+	}
+	return item, nil
+}
+
+// listActiveDeletions returns the deletions which are querying or deleting
+func listActiveDeletions() ([]*deletionItem, error) {
+	filter := expression.Name("status").In(
+		expression.Value(models.DeletionQuerying), expression.Value(models.DeletionDeleting))
+	scanExpression, err := expression.NewBuilder().WithFilter(filter).Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build expression")
+	}
+	input := &dynamodb.ScanInput{
+		ExpressionAttributeNames:  scanExpression.Names(),
+		ExpressionAttributeValues: scanExpression.Values(),
+		FilterExpression:          scanExpression.Filter(),
+		TableName:                 aws.String(env.DeletionsTable),
+	}
+
+	var items []*deletionItem
+	var unmarshalErr error
+	err = ddbClient.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		var pageItems []*deletionItem
+		if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageItems); unmarshalErr != nil {
+			return false
+		}
+		items = append(items, pageItems...)
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to scan deletions table")
+	}
+	if unmarshalErr != nil {
+		return nil, errors.Wrap(unmarshalErr, "failed to unmarshal deletions")
+	}
+	return items, nil
+}
+
+// claimDeletion takes the lease of the deletion, it returns false if another lambda holds it
+func claimDeletion(item *deletionItem) (bool, error) {
+	leaseExpiresAt := now().Add(leaseDuration).Unix()
+	condition := expression.Name("leaseExpiresAt").AttributeNotExists().
+		Or(expression.Name("leaseExpiresAt").LessThan(expression.Value(now().Unix())))
+	update := expression.Set(expression.Name("leaseExpiresAt"), expression.Value(leaseExpiresAt))
+	updateExpression, err := expression.NewBuilder().WithCondition(condition).WithUpdate(update).Build()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to build expression")
+	}
+
+	_, err = ddbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		ConditionExpression:       updateExpression.Condition(),
+		ExpressionAttributeNames:  updateExpression.Names(),
+		ExpressionAttributeValues: updateExpression.Values(),
+		Key: map[string]*dynamodb.AttributeValue{
+			deletionsTablePartitionKey: {S: aws.String(item.DeletionID)},
+		},
+		TableName:        aws.String(env.DeletionsTable),
+		UpdateExpression: updateExpression.Update(),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to claim deletion %s", item.DeletionID)
+	}
+	item.LeaseExpiresAt = leaseExpiresAt
+	return true, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/panther-labs/panther/api/lambda/deletion/models"
+	"github.com/panther-labs/panther/internal/log_analysis/data_deletion/deletion"
+	"github.com/panther-labs/panther/pkg/genericapi"
+	"github.com/panther-labs/panther/pkg/lambdalogger"
+)
+
+var router = genericapi.NewRouter("log_analysis", "data_deletion", nil, deletion.API{})
+
+func lambdaHandler(ctx context.Context, input *models.LambdaInput) (interface{}, error) {
+	lambdalogger.ConfigureGlobal(ctx, nil)
+	output, err := router.Handle(input)
+	if err != nil {
+		switch err.(type) {
+		case *genericapi.InvalidInputError, *genericapi.DoesNotExistError:
+			// client errors are returned as is
+		default:
+			err = &genericapi.InternalError{Message: err.Error()}
+		}
+	}
+	return output, err
+}
+
+func main() {
+	deletion.Setup()
+	lambda.Start(lambdaHandler)
+}
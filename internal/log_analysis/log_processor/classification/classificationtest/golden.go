@@ -0,0 +1,142 @@
+package classificationtest
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// used for test code that should NOT be in production code
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/classification"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/registry"
+)
+
+const (
+	// InputFileExt is the extension of the sample input files (one log per line)
+	InputFileExt = ".log"
+	// ExpectedFileExt is the extension of the files with the expected events (a JSON array), next to the input files
+	ExpectedFileExt = ".json"
+
+	pantherRowIDField     = "p_row_id"
+	pantherParseTimeField = "p_parse_time"
+	pantherEventTimeField = "p_event_time"
+)
+
+// RunGoldenFiles classifies the sample files under dir and compares the resulting events with the expected files.
+// The layout is dir/<LogType>/<sample>.log with the expected events in dir/<LogType>/<sample>.json.
+// Every line of a sample must be classified as the LogType of its directory.
+// If update is true the expected files are (re)written instead of compared.
+func RunGoldenFiles(t *testing.T, dir string, update bool) {
+	logTypeDirs, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	for _, logTypeDir := range logTypeDirs {
+		if !logTypeDir.IsDir() {
+			continue
+		}
+		logType := logTypeDir.Name()
+		t.Run(logType, func(t *testing.T) {
+			_, found := registry.AvailableParsers().Elements()[logType]
+			require.True(t, found, "directory %s is not a registered LogType", logType)
+
+			inputPaths, err := filepath.Glob(filepath.Join(dir, logType, "*"+InputFileExt))
+			require.NoError(t, err)
+			require.NotEmpty(t, inputPaths, "no samples for %s", logType)
+			for _, inputPath := range inputPaths {
+				inputPath := inputPath
+				t.Run(filepath.Base(inputPath), func(t *testing.T) {
+					RunGoldenFile(t, logType, inputPath, update)
+				})
+			}
+		})
+	}
+}
+
+// RunGoldenFile classifies a single sample file and compares the resulting events with the expected file
+func RunGoldenFile(t *testing.T, logType, inputPath string, update bool) {
+	events := ClassifyFile(t, logType, inputPath)
+	actualJSON, err := json.MarshalIndent(events, "", "  ")
+	require.NoError(t, err)
+
+	expectedPath := strings.TrimSuffix(inputPath, InputFileExt) + ExpectedFileExt
+	if update {
+		require.NoError(t, ioutil.WriteFile(expectedPath, append(actualJSON, '\n'), 0644))
+		return
+	}
+
+	expectedJSON, err := ioutil.ReadFile(expectedPath)
+	require.NoError(t, err, "missing expected file, run the test with -update to create it")
+	require.JSONEq(t, string(expectedJSON), string(actualJSON))
+}
+
+// ClassifyFile runs each line of the input file through a Classifier and returns the events as they are
+// written to S3 (minus the volatile fields), requiring that every line is classified as logType
+func ClassifyFile(t *testing.T, logType, inputPath string) []map[string]interface{} {
+	f, err := os.Open(inputPath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	classifier := classification.NewClassifier()
+	events := []map[string]interface{}{}
+	stream := bufio.NewReader(f)
+	for lineNum := 1; ; lineNum++ {
+		line, err := stream.ReadString(common.EventDelimiter)
+		if err != nil && err != io.EOF {
+			require.NoError(t, err)
+		}
+		if strings.TrimSpace(line) != "" {
+			result := classifier.Classify(line)
+			require.NotNil(t, result.LogType, "line %d of %s was not classified", lineNum, inputPath)
+			require.Equal(t, logType, *result.LogType, "line %d of %s was misclassified", lineNum, inputPath)
+			for _, event := range result.Events {
+				events = append(events, normalizeEvent(t, event.Event()))
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return events
+}
+
+// normalizeEvent serializes the event as it is written to S3 and removes the fields that change every run
+func normalizeEvent(t *testing.T, event interface{}) map[string]interface{} {
+	data, err := jsoniter.Marshal(event)
+	require.NoError(t, err)
+	var normalized map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &normalized))
+
+	// when the event has no time of its own, Panther sets it to the parse time
+	if normalized[pantherEventTimeField] == normalized[pantherParseTimeField] {
+		delete(normalized, pantherEventTimeField)
+	}
+	delete(normalized, pantherRowIDField)
+	delete(normalized, pantherParseTimeField)
+	return normalized
+}
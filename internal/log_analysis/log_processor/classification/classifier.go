@@ -61,6 +61,17 @@ func NewClassifier() ClassifierAPI {
 	}
 }
 
+// NewClassifierForLogType returns a new instance of a ClassifierAPI implementation that only
+// tries the parser of the provided log type (used when the log type of the data is known)
+func NewClassifierForLogType(logType string) ClassifierAPI {
+	parserQueue := &ParserPriorityQueue{}
+	parserQueue.initializeLogType(logType)
+	return &Classifier{
+		parsers:     parserQueue,
+		parserStats: make(map[string]*ParserStats),
+	}
+}
+
 // Classifier is the struct responsible for classifying logs
 type Classifier struct {
 	parsers *ParserPriorityQueue
@@ -195,3 +206,21 @@ type ParserStats struct {
 	EventCount             uint64 // output records
 	LogType                string
 }
+
+// Add accumulates the counts of other into s
+func (s *ClassifierStats) Add(other *ClassifierStats) {
+	s.ClassifyTimeMicroseconds += other.ClassifyTimeMicroseconds
+	s.BytesProcessedCount += other.BytesProcessedCount
+	s.LogLineCount += other.LogLineCount
+	s.EventCount += other.EventCount
+	s.SuccessfullyClassifiedCount += other.SuccessfullyClassifiedCount
+	s.ClassificationFailureCount += other.ClassificationFailureCount
+}
+
+// Add accumulates the counts of other into s
+func (s *ParserStats) Add(other *ParserStats) {
+	s.ParserTimeMicroseconds += other.ParserTimeMicroseconds
+	s.BytesProcessedCount += other.BytesProcessedCount
+	s.LogLineCount += other.LogLineCount
+	s.EventCount += other.EventCount
+}
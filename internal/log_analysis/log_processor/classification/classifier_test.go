@@ -176,6 +176,33 @@ func TestClassifyNoMatch(t *testing.T) {
 	require.Nil(t, classifier.ParserStats()[failingParser.LogType()])
 }
 
+func TestClassifyForLogTypeOnlyUsesThatParser(t *testing.T) {
+	succeedingParser := &mockParser{}
+	otherParser := &mockParser{}
+
+	succeedingParser.On("Parse", mock.Anything).Return([]*parsers.PantherLog{{}})
+	succeedingParser.On("LogType").Return("success")
+	otherParser.On("Parse", mock.Anything).Return([]*parsers.PantherLog{{}})
+	otherParser.On("LogType").Return("other")
+
+	testRegistry := NewTestRegistry()
+	parserRegistry = testRegistry // re-bind as interface
+	testRegistry.Add(&registry.LogParserMetadata{Parser: succeedingParser})
+	testRegistry.Add(&registry.LogParserMetadata{Parser: otherParser})
+
+	classifier := NewClassifierForLogType("success")
+	result := classifier.Classify("log")
+	require.Equal(t, aws.String("success"), result.LogType)
+	succeedingParser.AssertNumberOfCalls(t, "Parse", 1)
+	otherParser.AssertNotCalled(t, "Parse", mock.Anything)
+
+	// unknown log type, nothing can be classified
+	classifier = NewClassifierForLogType("unknown")
+	result = classifier.Classify("log")
+	require.Nil(t, result.LogType)
+	require.Equal(t, uint64(1), classifier.Stats().ClassificationFailureCount)
+}
+
 func TestClassifyParserPanic(t *testing.T) {
 	// uncomment to see the logs produced
 	/*
@@ -41,6 +41,19 @@ func (q *ParserPriorityQueue) initialize() {
 	}
 }
 
+// initializeLogType adds only the parser of the provided log type to the priority queue
+// If the log type is not registered the queue will be empty
+func (q *ParserPriorityQueue) initializeLogType(logType string) {
+	parserMetadata, found := parserRegistry.Elements()[logType]
+	if !found {
+		return
+	}
+	q.items = append(q.items, &ParserQueueItem{
+		parser:  parserMetadata.Parser.New(),
+		penalty: 1,
+	})
+}
+
 // ParserQueueItem contains all the information needed to initialize a schema.
 type ParserQueueItem struct {
 	parser parsers.LogParser
@@ -28,11 +28,31 @@ import (
 const (
 	MaxRetries     = 20 // setting Max Retries to a higher number - we'd like to retry VERY hard before failing.
 	EventDelimiter = '\n'
+
+	// CloudTrail collects logs for 5 mins or until the max file size of 45MB has been reached.
+	// Because CT files are "document" JSON and all on 1 line we currently need to read ALL the uncompressed data into memory.
+	// FIXME: we should switch to streaming JSON reader
+	LargestAllInMemFileMB     = 45
+	ProcessingExpansionFactor = 4 // because we convert all the records and parse
+	MinStreamProcessingMemMB  = LargestAllInMemFileMB * ProcessingExpansionFactor
+
+	// the fraction of lambda memory we set aside for processing data streams concurrently (the rest is for output buffers)
+	streamProcessingMemFraction = 4
 )
 
 // Session AWS Session that can be used by components of the system
 var Session = session.Must(session.NewSession(aws.NewConfig().WithMaxRetries(MaxRetries)))
 
+// StreamProcessingMemMB returns the memory (in MB) set aside for processing data streams concurrently.
+// It is never less than what is needed to process the largest file we expect on its own.
+func StreamProcessingMemMB(lambdaSizeMB int) int {
+	memMB := lambdaSizeMB / streamProcessingMemFraction
+	if memMB < MinStreamProcessingMemMB {
+		return MinStreamProcessingMemMB
+	}
+	return memMB
+}
+
 // DataStream represents a data stream that read by the processor
 type DataStream struct {
 	Reader io.Reader
@@ -44,12 +64,24 @@ type DataStream struct {
 
 // Used in a DataStream as meta data to describe the data
 type DataStreamHints struct {
-	S3 *S3DataStreamHints // if nil, no hint
+	S3   *S3DataStreamHints   // if nil, no hint
+	File *FileDataStreamHints // if nil, no hint
 }
 
 // Used in a DataStreamHints as meta data to describe the S3 object backing the stream
 type S3DataStreamHints struct {
-	Bucket      string
-	Key         string
-	ContentType string
+	Bucket        string
+	Key           string
+	ContentType   string
+	ContentLength int64 // size of the object as stored in S3 (before decompression), 0 if unknown
+	// the source integration the object belongs to
+	SourceIntegrationID    string
+	SourceIntegrationLabel string
+}
+
+// Used in a DataStreamHints as meta data to describe the local file backing the stream (used by devtools)
+type FileDataStreamHints struct {
+	Path          string
+	ContentType   string
+	ContentLength int64 // size of the file (before decompression)
 }
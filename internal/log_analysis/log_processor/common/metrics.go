@@ -0,0 +1,51 @@
+package common
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"os"
+
+	"github.com/panther-labs/panther/pkg/metrics"
+)
+
+// names for CloudWatch embedded metrics
+const (
+	MetricsNamespace = "Panther"
+
+	// dimensions
+	MetricsLogTypeDim           = "LogType"
+	MetricsSourceIntegrationDim = "SourceIntegration"
+
+	// properties, written with the metrics but not dimensions
+	MetricsSourceIntegrationLabelProperty = "SourceIntegrationLabel"
+
+	// metrics
+	MetricsBytesProcessed         = "BytesProcessed"
+	MetricsEventsProcessed        = "EventsProcessed"
+	MetricsClassificationFailures = "ClassificationFailures"
+	MetricsParseTime              = "ParseTime"
+)
+
+/*
+MetricsLogger writes the metrics to stdout, in the lambda these end up in CloudWatch Logs where the
+metrics are extracted and can be graphed and alarmed on, e.g., the metric
+Panther/ClassificationFailures with dimension SourceIntegration=<source integration id> can be used to alarm on
+a spike in classification failures for one source. Integration ids are used because labels can be renamed.
+*/
+var MetricsLogger = metrics.NewLogger(MetricsNamespace, os.Stdout)
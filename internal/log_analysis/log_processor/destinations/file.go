@@ -0,0 +1,140 @@
+package destinations
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers"
+)
+
+const (
+	fileDirPerm  = 0755
+	filePermBits = 0644
+
+	maxFileBufferMemUsageBytes = 4 * maxS3BufferSizeBytes // no lambda here, just keep memory reasonable
+)
+
+// FileDestination writes normalized events to the local filesystem using the same
+// partitioned layout and gzip'd JSON format as the S3Destination. Useful for running offline and in tests.
+type FileDestination struct {
+	// rootDir is the directory that plays the role of the bucket
+	rootDir string
+	// thresholds for ejection
+	maxBufferedMemBytes uint64 // max will hold in buffers before ejection
+}
+
+// CreateFileDestination returns a Destination writing under rootDir
+func CreateFileDestination(rootDir string) Destination {
+	zap.L().Debug("created file destination", zap.String("rootDir", rootDir))
+	return &FileDestination{
+		rootDir:             rootDir,
+		maxBufferedMemBytes: maxFileBufferMemUsageBytes,
+	}
+}
+
+// SendEvents stores events in files under rootDir.
+// It continuously reads events from parsedEventChannel, groups them in batches per log type and hour,
+// and writes them to the appropriate path. If the method encounters an error
+// it writes an error to the errChan and continues until channel is closed (skipping events).
+func (destination *FileDestination) SendEvents(parsedEventChannel chan *parsers.PantherLog, errChan chan error) {
+	failed := false // set to true on error and loop will drain channel
+	bufferSet := newS3EventBufferSet()
+	eventsProcessed := 0
+	for event := range parsedEventChannel {
+		if failed { // drain channel
+			continue
+		}
+
+		data, err := jsoniter.Marshal(event.Event())
+		if err != nil {
+			failed = true
+			errChan <- errors.Wrap(err, "failed to marshall log parser event for file")
+			continue
+		}
+
+		buffer := bufferSet.getBuffer(event)
+		if err = bufferSet.addEvent(buffer, data); err != nil {
+			failed = true
+			errChan <- err
+			continue
+		}
+
+		if buffer.bytes >= maxS3BufferSizeBytes {
+			bufferSet.removeBuffer(buffer)
+			if err = destination.writeData(buffer); err != nil {
+				failed = true
+				errChan <- err
+				continue
+			}
+		}
+
+		if bufferSet.totalBufferedMemBytes >= destination.maxBufferedMemBytes {
+			if largestBuffer := bufferSet.largestBuffer(); largestBuffer != nil {
+				bufferSet.removeBuffer(largestBuffer)
+				if err = destination.writeData(largestBuffer); err != nil {
+					failed = true
+					errChan <- err
+					continue
+				}
+			}
+		}
+
+		eventsProcessed++
+	}
+
+	if failed {
+		return
+	}
+
+	// write the remaining buffers
+	if err := bufferSet.apply(destination.writeData); err != nil {
+		errChan <- err
+	}
+
+	zap.L().Debug("finished writing files", zap.Int("events", eventsProcessed))
+}
+
+// writeData writes the buffer to a file under rootDir, the path is the same as the S3 object key
+func (destination *FileDestination) writeData(buffer *s3EventBuffer) error {
+	if buffer.events == 0 { // skip empty buffers
+		return nil
+	}
+
+	payload, err := buffer.read()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(destination.rootDir, filepath.FromSlash(getS3ObjectKey(buffer.logType, buffer.hour)))
+	if err = os.MkdirAll(filepath.Dir(path), fileDirPerm); err != nil {
+		return errors.Wrapf(err, "failed to create directory for %s", path)
+	}
+	if err = ioutil.WriteFile(path, payload, filePermBits); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	zap.L().Debug("wrote file", zap.String("path", path), zap.Int("events", buffer.events))
+	return nil
+}
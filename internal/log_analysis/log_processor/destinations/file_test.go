@@ -0,0 +1,120 @@
+package destinations
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers"
+)
+
+func TestFileDestinationWritesPartitionedFiles(t *testing.T) {
+	initTest()
+
+	rootDir, err := ioutil.TempDir("", "file_destination")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	destination := CreateFileDestination(rootDir)
+	eventChannel := make(chan *parsers.PantherLog, 3)
+
+	testEvent := newSimpleTestEvent()
+	registerMockParser(testLogType, testEvent)
+
+	eventChannel <- testEvent
+	eventChannel <- testEvent
+	eventChannel <- newTestEvent(testLogType, refTimePlusHour)
+
+	runSendEvents(t, destination, eventChannel, false)
+
+	expectedLine, err := jsoniter.MarshalToString(testEvent.Event())
+	require.NoError(t, err)
+
+	files := readFiles(t, rootDir)
+	require.Equal(t, 2, len(files))
+	for path, lines := range files {
+		switch {
+		case strings.HasPrefix(path, expectedS3Prefix):
+			assert.Equal(t, []string{expectedLine, expectedLine}, lines)
+		case strings.HasPrefix(path, expectedS3Prefix2):
+			assert.Equal(t, 1, len(lines))
+		default:
+			t.Errorf("unexpected file %s", path)
+		}
+		assert.True(t, strings.HasSuffix(path, ".json.gz"))
+	}
+}
+
+func TestFileDestinationFailsIfDirectoryNotWritable(t *testing.T) {
+	initTest()
+
+	rootDir, err := ioutil.TempDir("", "file_destination")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	// a file where the destination expects a directory
+	rootFile := filepath.Join(rootDir, "file")
+	require.NoError(t, ioutil.WriteFile(rootFile, []byte("test"), filePermBits))
+
+	destination := CreateFileDestination(rootFile)
+	eventChannel := make(chan *parsers.PantherLog, 1)
+
+	testEvent := newSimpleTestEvent()
+	registerMockParser(testLogType, testEvent)
+
+	eventChannel <- testEvent
+
+	runSendEvents(t, destination, eventChannel, true)
+}
+
+// returns the lines of each gzip'd file under rootDir keyed by the path relative to rootDir
+func readFiles(t *testing.T, rootDir string) map[string][]string {
+	files := make(map[string][]string)
+	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+		gzipReader, err := gzip.NewReader(f)
+		require.NoError(t, err)
+
+		relPath, err := filepath.Rel(rootDir, path)
+		require.NoError(t, err)
+		relPath = filepath.ToSlash(relPath)
+		scanner := bufio.NewScanner(gzipReader)
+		for scanner.Scan() {
+			files[relPath] = append(files[relPath], scanner.Text())
+		}
+		return scanner.Err()
+	})
+	require.NoError(t, err)
+	return files
+}
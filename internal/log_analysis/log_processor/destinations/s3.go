@@ -77,23 +77,10 @@ func init() {
 }
 
 // the largest we let total size of compressed output buffers get before calling sendData() to write to S3 in bytes
-// NOTE: this presumes processing 1 file at a time
+// NOTE: this accounts for the memory the processor uses to process files concurrently
 func maxS3BufferMemUsageBytes(lambdaSizeMB int) uint64 {
-	const (
-		/*
-					NOTE:
-					  "More specifically CloudTrail will collect logs for 5 mins or until the max file size of 45MB has been reached.
-					  An important thing worth noting is that these logs get compressed before being sent to S3, once the file size
-					  limit is met or the time limit has been exceeded"
-				    Because CT files are "document" JSON and all on 1 line we currently need to read ALL the uncompressed data into memory.
-			        FIXME: we should switch to streaming JSON reader
-					Below we set the lower bound on memory to be 45MB * 3 (because we convert all the records and parse) plus some for overhead
-		*/
-		largestAllInMemFileMB     = 45
-		processingExpansionFactor = 4
-		memoryFootprint           = largestAllInMemFileMB * processingExpansionFactor
-		minimumScratchMemMB       = 5 // how much overhead is needed to process a file
-	)
+	const minimumScratchMemMB = 5 // how much overhead is needed to process a file
+	memoryFootprint := common.StreamProcessingMemMB(lambdaSizeMB)
 	maxBufferUsageMB := lambdaSizeMB - memUsedAtStartupMB - memoryFootprint - minimumScratchMemMB
 	if maxBufferUsageMB < 5 {
 		panic(fmt.Sprintf("available memory too small for log processing, increase lambda size from %dMB", lambdaSizeMB))
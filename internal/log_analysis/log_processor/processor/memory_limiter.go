@@ -0,0 +1,107 @@
+package processor
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
+)
+
+const (
+	bytesPerMB = 1024 * 1024
+
+	// how much we expect gzip'd log data to grow when decompressed
+	gzipExpansionFactor = 10
+
+	// the least memory we account for a stream, this also bounds the number of concurrent streams
+	minStreamMemBytes = 1 * bytesPerMB
+)
+
+// memoryLimiter bounds the estimated memory used by data streams processed concurrently
+type memoryLimiter struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	budget   uint64
+	inFlight uint64
+}
+
+func newMemoryLimiter(budgetBytes uint64) *memoryLimiter {
+	limiter := &memoryLimiter{
+		budget: budgetBytes,
+	}
+	limiter.cond = sync.NewCond(&limiter.mu)
+	return limiter
+}
+
+// newLambdaMemoryLimiter returns a memoryLimiter with a budget based on the memory size of the lambda
+func newLambdaMemoryLimiter() *memoryLimiter {
+	return newMemoryLimiter(uint64(common.StreamProcessingMemMB(lambdacontext.MemoryLimitInMB)) * bytesPerMB)
+}
+
+// acquire blocks until n bytes are available in the budget.
+// A request larger than the budget is clamped, so it runs once everything else has finished.
+func (l *memoryLimiter) acquire(n uint64) uint64 {
+	if n > l.budget {
+		n = l.budget
+	}
+	l.mu.Lock()
+	for l.inFlight+n > l.budget {
+		l.cond.Wait()
+	}
+	l.inFlight += n
+	l.mu.Unlock()
+	return n
+}
+
+// release returns n bytes (as returned by acquire()) to the budget
+func (l *memoryLimiter) release(n uint64) {
+	l.mu.Lock()
+	l.inFlight -= n
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// streamMemBytes estimates the memory needed to process a data stream.
+// Streams of unknown size are assumed to need the whole budget so they are processed alone.
+func (l *memoryLimiter) streamMemBytes(dataStream *common.DataStream) uint64 {
+	var contentType string
+	var contentLength int64
+	switch {
+	case dataStream.Hints.S3 != nil:
+		contentType, contentLength = dataStream.Hints.S3.ContentType, dataStream.Hints.S3.ContentLength
+	case dataStream.Hints.File != nil:
+		contentType, contentLength = dataStream.Hints.File.ContentType, dataStream.Hints.File.ContentLength
+	}
+	if contentLength <= 0 {
+		return l.budget
+	}
+	memBytes := uint64(contentLength)
+	if strings.HasPrefix(contentType, "application/x-gzip") {
+		memBytes *= gzipExpansionFactor
+	}
+	memBytes *= common.ProcessingExpansionFactor
+	if memBytes < minStreamMemBytes {
+		return minStreamMemBytes
+	}
+	return memBytes
+}
@@ -0,0 +1,106 @@
+package processor
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
+)
+
+func TestMemoryLimiterStreamMemBytes(t *testing.T) {
+	limiter := newMemoryLimiter(100 * bytesPerMB)
+
+	// no hints, needs the whole budget
+	assert.Equal(t, limiter.budget, limiter.streamMemBytes(&common.DataStream{}))
+
+	// unknown size, needs the whole budget
+	assert.Equal(t, limiter.budget, limiter.streamMemBytes(&common.DataStream{
+		Hints: common.DataStreamHints{S3: &common.S3DataStreamHints{}},
+	}))
+
+	// small files get the minimum
+	assert.Equal(t, uint64(minStreamMemBytes), limiter.streamMemBytes(&common.DataStream{
+		Hints: common.DataStreamHints{S3: &common.S3DataStreamHints{ContentLength: 10}},
+	}))
+
+	// plain text
+	assert.Equal(t, uint64(2*bytesPerMB*common.ProcessingExpansionFactor), limiter.streamMemBytes(&common.DataStream{
+		Hints: common.DataStreamHints{S3: &common.S3DataStreamHints{
+			ContentType:   "text/plain; charset=utf-8",
+			ContentLength: 2 * bytesPerMB,
+		}},
+	}))
+
+	// gzip
+	assert.Equal(t, uint64(2*bytesPerMB*common.ProcessingExpansionFactor*gzipExpansionFactor),
+		limiter.streamMemBytes(&common.DataStream{
+			Hints: common.DataStreamHints{S3: &common.S3DataStreamHints{
+				ContentType:   "application/x-gzip",
+				ContentLength: 2 * bytesPerMB,
+			}},
+		}))
+}
+
+func TestMemoryLimiterAcquireClampsToBudget(t *testing.T) {
+	limiter := newMemoryLimiter(10)
+	assert.Equal(t, uint64(10), limiter.acquire(100))
+	limiter.release(10)
+	assert.Equal(t, uint64(0), limiter.inFlight)
+}
+
+func TestMemoryLimiterBoundsConcurrency(t *testing.T) {
+	const (
+		budget    = 4
+		nRequests = 20
+	)
+	limiter := newMemoryLimiter(budget)
+
+	var inFlight, maxInFlight int32
+	var wg sync.WaitGroup
+	for i := 0; i < nRequests; i++ {
+		n := limiter.acquire(1)
+		wg.Add(1)
+		go func() {
+			defer func() {
+				atomic.AddInt32(&inFlight, -1)
+				limiter.release(n)
+				wg.Done()
+			}()
+			current := atomic.AddInt32(&inFlight, 1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, uint64(0), limiter.inFlight)
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(budget))
+}
@@ -23,6 +23,7 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -31,6 +32,7 @@ import (
 	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
 	"github.com/panther-labs/panther/internal/log_analysis/log_processor/destinations"
 	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers"
+	"github.com/panther-labs/panther/pkg/metrics"
 	"github.com/panther-labs/panther/pkg/oplog"
 )
 
@@ -53,12 +55,42 @@ var (
 // Process orchestrates the tasks of parsing logs, classification, normalization
 // and forwarding the logs to the appropriate destination. Any errors will cause Lambda invocation to fail
 func Process(dataStreams []*common.DataStream, destination destinations.Destination) error {
-	return process(dataStreams, destination, NewProcessor)
+	return process(dataStreams, destination, NewProcessor, newLambdaMemoryLimiter())
+}
+
+// ProcessWithStats is like Process() but also returns the classifier stats aggregated over all data streams
+// and the per parser stats as a map of LogType -> stats (used by devtools)
+func ProcessWithStats(dataStreams []*common.DataStream, destination destinations.Destination) (
+	*classification.ClassifierStats, map[string]*classification.ParserStats, error) {
+
+	var mu sync.Mutex
+	var processors []*Processor
+	newProcessorFunc := func(dataStream *common.DataStream) *Processor {
+		processor := NewProcessor(dataStream)
+		mu.Lock()
+		processors = append(processors, processor)
+		mu.Unlock()
+		return processor
+	}
+	err := process(dataStreams, destination, newProcessorFunc, newLambdaMemoryLimiter())
+
+	stats := &classification.ClassifierStats{}
+	parserStats := make(map[string]*classification.ParserStats)
+	for _, processor := range processors {
+		stats.Add(processor.classifier.Stats())
+		for logType, processorParserStats := range processor.classifier.ParserStats() {
+			if _, found := parserStats[logType]; !found {
+				parserStats[logType] = &classification.ParserStats{LogType: logType}
+			}
+			parserStats[logType].Add(processorParserStats)
+		}
+	}
+	return stats, parserStats, err
 }
 
 // entry point to allow customizing processor for testing
 func process(dataStreams []*common.DataStream, destination destinations.Destination,
-	newProcessorFunc func(*common.DataStream) *Processor) error {
+	newProcessorFunc func(*common.DataStream) *Processor, limiter *memoryLimiter) error {
 
 	zap.L().Debug("processing data streams", zap.Int("numDataStreams", len(dataStreams)))
 	parsedEventChannel := make(chan *parsers.PantherLog, ParsedEventBufferSize)
@@ -82,15 +114,30 @@ func process(dataStreams []*common.DataStream, destination destinations.Destinat
 		errorsWg.Done()
 	}()
 
-	// it is important to process the streams serially to manage memory!
+	// streams are processed concurrently as long as their estimated memory fits in the budget,
+	// large files (e.g., CloudTrail) will be processed alone to manage memory!
+	var processWg sync.WaitGroup
+	var failed int32 // set to 1 on the first error, no new streams are started after that
 	for _, dataStream := range dataStreams {
-		processor := newProcessorFunc(dataStream)
-		err := processor.run(parsedEventChannel)
-		if err != nil {
-			errorChannel <- err
+		memBytes := limiter.acquire(limiter.streamMemBytes(dataStream))
+		if atomic.LoadInt32(&failed) != 0 {
+			limiter.release(memBytes)
 			break
 		}
+		processWg.Add(1)
+		go func(dataStream *common.DataStream, memBytes uint64) {
+			defer func() {
+				limiter.release(memBytes)
+				processWg.Done()
+			}()
+			processor := newProcessorFunc(dataStream) // each stream has its own classifier and stats
+			if err := processor.run(parsedEventChannel); err != nil {
+				atomic.StoreInt32(&failed, 1)
+				errorChannel <- err
+			}
+		}(dataStream, memBytes)
 	}
+	processWg.Wait() // wait for all streams to finish writing to parsedEventChannel
 
 	// Close the channel after all goroutines have finished writing to it.
 	// The Destination that is reading the channel will terminate
@@ -143,6 +190,10 @@ func (p *Processor) classifyLogLine(line string) *classification.ClassifierResul
 				zap.Uint64("lineNum", p.classifier.Stats().LogLineCount),
 				zap.String("bucket", p.input.Hints.S3.Bucket),
 				zap.String("key", p.input.Hints.S3.Key))
+		} else if p.input.Hints.File != nil {
+			p.operation.LogWarn(errors.New("failed to classify log line"),
+				zap.Uint64("lineNum", p.classifier.Stats().LogLineCount),
+				zap.String("path", p.input.Hints.File.Path))
 		}
 	}
 	return result
@@ -160,18 +211,86 @@ func (p *Processor) logStats(err error) {
 	for _, parserStats := range p.classifier.ParserStats() {
 		p.operation.Log(err, zap.Any(statsKey, *parserStats))
 	}
+	p.logMetrics()
+}
+
+// logMetrics emits the classifier and parser stats as CloudWatch embedded metrics
+func (p *Processor) logMetrics() {
+	// the label is a property, the dimension is the id which does not change when the source is renamed
+	var sourceDimensions []metrics.Dimension
+	if p.input.Hints.S3 != nil && p.input.Hints.S3.SourceIntegrationID != "" {
+		sourceDimensions = append(sourceDimensions,
+			metrics.Dimension{
+				Name:  common.MetricsSourceIntegrationDim,
+				Value: p.input.Hints.S3.SourceIntegrationID,
+			},
+			metrics.Dimension{
+				Name:  common.MetricsSourceIntegrationLabelProperty,
+				Value: p.input.Hints.S3.SourceIntegrationLabel,
+			})
+	}
+
+	// there is no log type for lines that failed to classify, so these are only by source
+	classifierDimensionSets := [][]string{{}}
+	if len(sourceDimensions) > 0 {
+		classifierDimensionSets = [][]string{{common.MetricsSourceIntegrationDim}}
+	}
+	err := p.metricsLogger.LogDimensionSets(sourceDimensions, classifierDimensionSets, metrics.Metric{
+		Name:  common.MetricsClassificationFailures,
+		Unit:  metrics.UnitCount,
+		Value: float64(p.classifier.Stats().ClassificationFailureCount),
+	})
+	if err != nil {
+		zap.L().Warn("failed to log metrics", zap.Error(err))
+	}
+
+	// published by log type and (if known) by log type and source
+	dimensionSets := [][]string{{common.MetricsLogTypeDim}}
+	if len(sourceDimensions) > 0 {
+		dimensionSets = append(dimensionSets, []string{common.MetricsLogTypeDim, common.MetricsSourceIntegrationDim})
+	}
+	for _, parserStats := range p.classifier.ParserStats() {
+		dimensions := append([]metrics.Dimension{{Name: common.MetricsLogTypeDim, Value: parserStats.LogType}},
+			sourceDimensions...)
+		err := p.metricsLogger.LogDimensionSets(dimensions, dimensionSets,
+			metrics.Metric{
+				Name:  common.MetricsBytesProcessed,
+				Unit:  metrics.UnitBytes,
+				Value: float64(parserStats.BytesProcessedCount),
+			},
+			metrics.Metric{
+				Name:  common.MetricsEventsProcessed,
+				Unit:  metrics.UnitCount,
+				Value: float64(parserStats.EventCount),
+			},
+			metrics.Metric{
+				Name:  common.MetricsParseTime,
+				Unit:  metrics.UnitMicroseconds,
+				Value: float64(parserStats.ParserTimeMicroseconds),
+			},
+		)
+		if err != nil {
+			zap.L().Warn("failed to log metrics", zap.Error(err))
+		}
+	}
 }
 
 type Processor struct {
-	input      *common.DataStream
-	classifier classification.ClassifierAPI
-	operation  *oplog.Operation
+	input         *common.DataStream
+	classifier    classification.ClassifierAPI
+	operation     *oplog.Operation
+	metricsLogger *metrics.Logger
 }
 
 func NewProcessor(input *common.DataStream) *Processor {
+	classifier := classification.NewClassifier()
+	if input.LogType != nil { // the log type is known, no need to try the other parsers
+		classifier = classification.NewClassifierForLogType(*input.LogType)
+	}
 	return &Processor{
-		input:      input,
-		classifier: classification.NewClassifier(),
-		operation:  common.OpLogManager.Start(operationName),
+		input:         input,
+		classifier:    classifier,
+		operation:     common.OpLogManager.Start(operationName),
+		metricsLogger: common.MetricsLogger,
 	}
 }
@@ -19,6 +19,7 @@ package processor
  */
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 	"testing"
@@ -28,6 +29,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
@@ -37,6 +39,7 @@ import (
 	"github.com/panther-labs/panther/internal/log_analysis/log_processor/destinations"
 	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers"
 	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers/timestamp"
+	"github.com/panther-labs/panther/pkg/metrics"
 	"github.com/panther-labs/panther/pkg/oplog"
 )
 
@@ -102,7 +105,7 @@ func TestProcess(t *testing.T) {
 	mockClassifier.standardMocks(mockStats, mockParserStats)
 
 	newProcessorFunc := func(*common.DataStream) *Processor { return p }
-	err := process([]*common.DataStream{dataStream}, destination, newProcessorFunc)
+	err := process([]*common.DataStream{dataStream}, destination, newProcessorFunc, newTestMemoryLimiter())
 	require.NoError(t, err)
 	require.Equal(t, testLogEvents, destination.nEvents)
 }
@@ -123,7 +126,7 @@ func TestProcessDataStreamError(t *testing.T) {
 	mockClassifier.standardMocks(mockStats, mockParserStats)
 
 	newProcessorFunc := func(*common.DataStream) *Processor { return p }
-	err := process([]*common.DataStream{dataStream}, destination, newProcessorFunc)
+	err := process([]*common.DataStream{dataStream}, destination, newProcessorFunc, newTestMemoryLimiter())
 	require.Error(t, err)
 
 	// confirm error log is as expected
@@ -199,7 +202,7 @@ func TestProcessDestinationError(t *testing.T) {
 	mockClassifier.standardMocks(mockStats, mockParserStats)
 
 	newProcessorFunc := func(*common.DataStream) *Processor { return p }
-	err := process([]*common.DataStream{dataStream}, destination, newProcessorFunc)
+	err := process([]*common.DataStream{dataStream}, destination, newProcessorFunc, newTestMemoryLimiter())
 	require.Error(t, err)
 }
 
@@ -208,6 +211,98 @@ func TestProcessDestinationErrorNoChannelBuffers(t *testing.T) {
 	TestProcessDestinationError(t)
 }
 
+func TestProcessConcurrentStreams(t *testing.T) {
+	destination := (&testDestination{}).standardMock()
+
+	const nStreams = 4
+	dataStreams := make([]*common.DataStream, nStreams)
+	processors := make(map[*common.DataStream]*Processor, nStreams)
+	for i := range dataStreams {
+		dataStreams[i] = makeDataStream()
+		dataStreams[i].Hints.S3 = &common.S3DataStreamHints{
+			Bucket:        testBucket,
+			Key:           fmt.Sprintf("%s%d", testKey, i),
+			ContentType:   testContentType,
+			ContentLength: 1024, // small, all should fit in the budget
+		}
+		p := NewProcessor(dataStreams[i])
+		mockClassifier := &testClassifier{}
+		mockClassifier.standardMocks(&classification.ClassifierStats{}, map[string]*classification.ParserStats{})
+		p.classifier = mockClassifier
+		processors[dataStreams[i]] = p
+	}
+
+	newProcessorFunc := func(dataStream *common.DataStream) *Processor { return processors[dataStream] }
+	err := process(dataStreams, destination, newProcessorFunc, newTestMemoryLimiter())
+	require.NoError(t, err)
+	require.Equal(t, nStreams*testLogEvents, destination.nEvents)
+	for _, p := range processors {
+		p.classifier.(*testClassifier).AssertNumberOfCalls(t, "Classify", int(testLogLines))
+	}
+}
+
+func TestProcessMetrics(t *testing.T) {
+	destination := (&testDestination{}).standardMock()
+
+	dataStream := makeDataStream()
+	dataStream.Hints.S3 = &common.S3DataStreamHints{
+		Bucket:                 testBucket,
+		Key:                    testKey,
+		ContentType:            testContentType,
+		SourceIntegrationID:    "testSourceId",
+		SourceIntegrationLabel: "testSource",
+	}
+	p := NewProcessor(dataStream)
+	var metricsBuffer bytes.Buffer
+	p.metricsLogger = metrics.NewLogger(common.MetricsNamespace, &metricsBuffer)
+	mockClassifier := &testClassifier{}
+	p.classifier = mockClassifier
+
+	mockStats := &classification.ClassifierStats{
+		ClassifyTimeMicroseconds:    1,
+		BytesProcessedCount:         (testLogLines) * uint64(len(testLogLine)),
+		LogLineCount:                testLogLines,
+		EventCount:                  testLogLines - 1,
+		SuccessfullyClassifiedCount: testLogLines - 1,
+		ClassificationFailureCount:  1,
+	}
+	mockParserStats := map[string]*classification.ParserStats{
+		testLogType: {
+			ParserTimeMicroseconds: 7,
+			BytesProcessedCount:    (testLogLines - 1) * uint64(len(testLogLine)),
+			LogLineCount:           testLogLines - 1,
+			EventCount:             testLogLines - 1,
+			LogType:                testLogType,
+		},
+	}
+	mockClassifier.standardMocks(mockStats, mockParserStats)
+
+	newProcessorFunc := func(*common.DataStream) *Processor { return p }
+	err := process([]*common.DataStream{dataStream}, destination, newProcessorFunc, newTestMemoryLimiter())
+	require.NoError(t, err)
+
+	records := strings.Split(strings.TrimSpace(metricsBuffer.String()), "\n")
+	require.Equal(t, 2, len(records))
+
+	// classifier metrics only by source
+	classifierRecord := gjson.Parse(records[0])
+	assert.Equal(t, `[["SourceIntegration"]]`, classifierRecord.Get("_aws.CloudWatchMetrics.0.Dimensions").Raw)
+	assert.Equal(t, "testSourceId", classifierRecord.Get(common.MetricsSourceIntegrationDim).String())
+	assert.Equal(t, "testSource", classifierRecord.Get(common.MetricsSourceIntegrationLabelProperty).String())
+	assert.Equal(t, int64(1), classifierRecord.Get(common.MetricsClassificationFailures).Int())
+
+	// parser metrics by log type and by log type and source
+	parserRecord := gjson.Parse(records[1])
+	assert.Equal(t, `[["LogType"],["LogType","SourceIntegration"]]`,
+		parserRecord.Get("_aws.CloudWatchMetrics.0.Dimensions").Raw)
+	assert.Equal(t, testLogType, parserRecord.Get(common.MetricsLogTypeDim).String())
+	assert.Equal(t, "testSourceId", parserRecord.Get(common.MetricsSourceIntegrationDim).String())
+	assert.Equal(t, "testSource", parserRecord.Get(common.MetricsSourceIntegrationLabelProperty).String())
+	assert.Equal(t, int64(testLogLines-1), parserRecord.Get(common.MetricsEventsProcessed).Int())
+	assert.Equal(t, int64((testLogLines-1)*uint64(len(testLogLine))), parserRecord.Get(common.MetricsBytesProcessed).Int())
+	assert.Equal(t, int64(7), parserRecord.Get(common.MetricsParseTime).Int())
+}
+
 // test we properly log parse failures so we can see which file and where in the file there was a failure
 func TestProcessClassifyFailure(t *testing.T) {
 	logs := mockLogger()
@@ -249,7 +344,7 @@ func TestProcessClassifyFailure(t *testing.T) {
 	mockClassifier.On("ParserStats", mock.Anything).Return(mockParserStats)
 
 	newProcessorFunc := func(*common.DataStream) *Processor { return p }
-	err := process([]*common.DataStream{dataStream}, destination, newProcessorFunc)
+	err := process([]*common.DataStream{dataStream}, destination, newProcessorFunc, newTestMemoryLimiter())
 	require.NoError(t, err)
 
 	actual := logs.AllUntimed()
@@ -402,6 +497,11 @@ func makeDataStream() (dataStream *common.DataStream) {
 	return
 }
 
+// a budget big enough to process a few small test streams concurrently
+func newTestMemoryLimiter() *memoryLimiter {
+	return newMemoryLimiter(8 * minStreamMemBytes)
+}
+
 var errFailingReader = errors.New("failed")
 
 type failingReader struct{}
@@ -0,0 +1,112 @@
+package sources
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/common"
+)
+
+// ReadFiles returns a DataStream for each plain text or gzip'd file under path (a file or a directory).
+// If logType is not nil, the data is assumed to be of that type. Hidden files and directories are skipped.
+// The caller must close the returned files once the data streams have been processed.
+func ReadFiles(path string, logType *string) (result []*common.DataStream, files []*os.File, err error) {
+	var paths []string
+	err = filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath != path && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			paths = append(paths, filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to list files in %s", path)
+	}
+	sort.Strings(paths) // process in a predictable order
+
+	defer func() {
+		if err != nil {
+			for _, f := range files {
+				f.Close() // nolint:errcheck
+			}
+			files = nil
+		}
+	}()
+	for _, filePath := range paths {
+		var dataStream *common.DataStream
+		var f *os.File
+		dataStream, f, err = readFile(filePath, logType)
+		if f != nil {
+			files = append(files, f)
+		}
+		if err != nil {
+			return nil, files, err
+		}
+		result = append(result, dataStream)
+	}
+	zap.L().Debug("read files", zap.String("path", path), zap.Int("numFiles", len(result)))
+	return result, files, nil
+}
+
+func readFile(filePath string, logType *string) (*common.DataStream, *os.File, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to open %s", filePath)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, f, errors.Wrapf(err, "failed to stat %s", filePath)
+	}
+
+	streamReader, contentType, err := newStreamReader(f)
+	if err != nil {
+		return nil, f, errors.Wrapf(err, "failed to read %s", filePath)
+	}
+	if streamReader == nil {
+		return nil, f, errors.Errorf("unsupported content type %s for %s", contentType, filePath)
+	}
+
+	dataStream := &common.DataStream{
+		Reader: streamReader,
+		Hints: common.DataStreamHints{
+			File: &common.FileDataStreamHints{
+				Path:          filePath,
+				ContentType:   contentType,
+				ContentLength: info.Size(),
+			},
+		},
+		LogType: logType,
+	}
+	return dataStream, f, nil
+}
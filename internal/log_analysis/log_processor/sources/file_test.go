@@ -0,0 +1,82 @@
+package sources
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "read_files")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	const plainData = "plain line 1\nplain line 2\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.log"), []byte(plainData), 0644))
+
+	const gzipData = "gzip line 1\n"
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	_, err = writer.Write([]byte(gzipData))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "b.log.gz"), buffer.Bytes(), 0644))
+
+	// skipped
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".hidden"), []byte(plainData), 0644))
+
+	logType := aws.String("Test.Type")
+	dataStreams, files, err := ReadFiles(dir, logType)
+	require.NoError(t, err)
+	defer func() {
+		for _, f := range files {
+			f.Close() // nolint:errcheck
+		}
+	}()
+	require.Equal(t, 2, len(dataStreams))
+	require.Equal(t, 2, len(files))
+
+	assert.Equal(t, filepath.Join(dir, "a.log"), dataStreams[0].Hints.File.Path)
+	assert.Equal(t, int64(len(plainData)), dataStreams[0].Hints.File.ContentLength)
+	assert.Equal(t, logType, dataStreams[0].LogType)
+	data, err := ioutil.ReadAll(dataStreams[0].Reader)
+	require.NoError(t, err)
+	assert.Equal(t, plainData, string(data))
+
+	assert.Equal(t, filepath.Join(dir, "sub", "b.log.gz"), dataStreams[1].Hints.File.Path)
+	assert.Equal(t, "application/x-gzip", dataStreams[1].Hints.File.ContentType)
+	data, err = ioutil.ReadAll(dataStreams[1].Reader)
+	require.NoError(t, err)
+	assert.Equal(t, gzipData, string(data))
+}
+
+func TestReadFilesMissing(t *testing.T) {
+	_, _, err := ReadFiles("/does/not/exist", nil)
+	require.Error(t, err)
+}
@@ -139,23 +139,63 @@ func readS3Object(s3Object *S3ObjectInfo) (dataStream *common.DataStream, err er
 			s3Object.S3Bucket, s3Object.S3ObjectKey)
 		return nil, err
 	}
+	defer func() {
+		// the data stream reads the body until the object is processed, it is closed here only on failure
+		if err != nil {
+			_ = output.Body.Close()
+		}
+	}()
 
-	bufferedReader := bufio.NewReader(output.Body)
+	streamReader, contentType, err := newStreamReader(output.Body)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to read S3 payload for s3://%s/%s",
+			s3Object.S3Bucket, s3Object.S3ObjectKey)
+		return nil, err
+	}
+
+	// getS3Client() found the integration above, so this is cached
+	integration, err := getSourceIntegration(s3Object)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to get source integration for s3://%s/%s",
+			s3Object.S3Bucket, s3Object.S3ObjectKey)
+		return nil, err
+	}
+	var integrationID, integrationLabel string
+	if integration != nil {
+		integrationID = aws.StringValue(integration.IntegrationID)
+		integrationLabel = aws.StringValue(integration.IntegrationLabel)
+	}
+
+	dataStream = &common.DataStream{
+		Reader: streamReader,
+		Hints: common.DataStreamHints{
+			S3: &common.S3DataStreamHints{
+				Bucket:                 s3Object.S3Bucket,
+				Key:                    s3Object.S3ObjectKey,
+				ContentType:            contentType,
+				ContentLength:          aws.Int64Value(output.ContentLength),
+				SourceIntegrationID:    integrationID,
+				SourceIntegrationLabel: integrationLabel,
+			},
+		},
+	}
+	return dataStream, err
+}
+
+// newStreamReader detects the content type of the data and returns a reader for the (decompressed) data
+func newStreamReader(reader io.Reader) (streamReader io.Reader, contentType string, err error) {
+	bufferedReader := bufio.NewReader(reader)
 
 	// We peek into the file header to identify the content type
 	// http.DetectContentType only uses up to the first 512 bytes
 	headerBytes, err := bufferedReader.Peek(512)
 	if err != nil {
 		if err != bufio.ErrBufferFull && err != io.EOF { // EOF or ErrBufferFull means file is shorter than n
-			err = errors.Wrapf(err, "failed to Peek() in S3 payload for s3://%s/%s",
-				s3Object.S3Bucket, s3Object.S3ObjectKey)
-			return nil, err
+			return nil, "", errors.Wrap(err, "failed to Peek()")
 		}
 		err = nil // not really an error
 	}
-	contentType := http.DetectContentType(headerBytes)
-
-	var streamReader io.Reader
+	contentType = http.DetectContentType(headerBytes)
 
 	// Checking for prefix because the returned type can have also charset used
 	if strings.HasPrefix(contentType, "text/plain") {
@@ -165,24 +205,11 @@ func readS3Object(s3Object *S3ObjectInfo) (dataStream *common.DataStream, err er
 		var gzipReader *gzip.Reader
 		gzipReader, err = gzip.NewReader(bufferedReader)
 		if err != nil {
-			err = errors.Wrapf(err, "failed to created gzip reader for s3://%s/%s",
-				s3Object.S3Bucket, s3Object.S3ObjectKey)
-			return nil, err
+			return nil, "", errors.Wrap(err, "failed to created gzip reader")
 		}
 		streamReader = gzipReader
 	}
-
-	dataStream = &common.DataStream{
-		Reader: streamReader,
-		Hints: common.DataStreamHints{
-			S3: &common.S3DataStreamHints{
-				Bucket:      s3Object.S3Bucket,
-				Key:         s3Object.S3ObjectKey,
-				ContentType: contentType,
-			},
-		},
-	}
-	return dataStream, err
+	return streamReader, contentType, nil
 }
 
 // ParseNotification parses a message received
@@ -166,6 +166,17 @@ func getAwsCredentials(roleArn string) *credentials.Credentials {
 // It will return error if it encountered an issue retrieving the role.
 // It will return nil result if role for such object doesn't exist.
 func getRoleArn(s3Object *S3ObjectInfo) (*string, error) {
+	integration, err := getSourceIntegration(s3Object)
+	if err != nil || integration == nil {
+		return nil, err
+	}
+	return integration.LogProcessingRole, nil
+}
+
+// Returns the source integration a given S3 object belongs to
+// It will return error if it encountered an issue retrieving the integrations.
+// It will return nil result if the integration for such object doesn't exist.
+func getSourceIntegration(s3Object *S3ObjectInfo) (*models.SourceIntegration, error) {
 	now := time.Now() // No need to be UTC. We care about relative time
 	if sourceCache.cacheUpdateTime.Add(sourceCacheDuration).Before(now) {
 		// we need to update the cache
@@ -186,10 +197,10 @@ func getRoleArn(s3Object *S3ObjectInfo) (*string, error) {
 	for _, integration := range sourceCache.sources {
 		if aws.StringValue(integration.S3Bucket) == s3Object.S3Bucket {
 			if integration.S3Prefix == nil { // no prefix configured
-				return integration.LogProcessingRole, nil
+				return integration, nil
 			}
 			if strings.HasPrefix(s3Object.S3ObjectKey, aws.StringValue(integration.S3Prefix)) {
-				return integration.LogProcessingRole, nil
+				return integration, nil
 			}
 		}
 	}
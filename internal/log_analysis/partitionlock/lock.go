@@ -0,0 +1,101 @@
+package partitionlock
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/pkg/errors"
+)
+
+// Locker takes leases on the partitions of the data lake, so the objects of a partition are never rewritten by
+// the data deletion while the compactor merges them and vice versa.
+// The lock items are keyed by the partition prefix of the log processor, such as
+// logs/aws_cloudtrail/year=2020/month=03/day=01/hour=23/, and expire with their lease.
+type Locker struct {
+	Client    dynamodbiface.DynamoDBAPI
+	TableName string
+}
+
+// Lease is how long a lock is held if it is not released, it outlives the lambda timeouts
+const Lease = 20 * time.Minute
+
+// Acquire locks the partition for owner, it returns false if another owner holds the lock.
+// An owner can acquire a lock it holds again, which extends the lease.
+func (l *Locker) Acquire(prefix, owner string) (bool, error) {
+	now := time.Now()
+	condition := expression.Name("prefix").AttributeNotExists().
+		Or(expression.Name("owner").Equal(expression.Value(owner))).
+		Or(expression.Name("expiresAt").LessThan(expression.Value(now.Unix())))
+	update := expression.Set(expression.Name("owner"), expression.Value(owner)).
+		Set(expression.Name("expiresAt"), expression.Value(now.Add(Lease).Unix()))
+	updateExpression, err := expression.NewBuilder().WithCondition(condition).WithUpdate(update).Build()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to build expression")
+	}
+
+	_, err = l.Client.UpdateItem(&dynamodb.UpdateItemInput{
+		ConditionExpression:       updateExpression.Condition(),
+		ExpressionAttributeNames:  updateExpression.Names(),
+		ExpressionAttributeValues: updateExpression.Values(),
+		Key: map[string]*dynamodb.AttributeValue{
+			"prefix": {S: aws.String(prefix)},
+		},
+		TableName:        aws.String(l.TableName),
+		UpdateExpression: updateExpression.Update(),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to lock partition %s", prefix)
+	}
+	return true, nil
+}
+
+// Release unlocks the partition if owner holds the lock
+func (l *Locker) Release(prefix, owner string) error {
+	condition := expression.Name("owner").Equal(expression.Value(owner))
+	conditionExpression, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return errors.Wrap(err, "failed to build expression")
+	}
+
+	_, err = l.Client.DeleteItem(&dynamodb.DeleteItemInput{
+		ConditionExpression:       conditionExpression.Condition(),
+		ExpressionAttributeNames:  conditionExpression.Names(),
+		ExpressionAttributeValues: conditionExpression.Values(),
+		Key: map[string]*dynamodb.AttributeValue{
+			"prefix": {S: aws.String(prefix)},
+		},
+		TableName: aws.String(l.TableName),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil // the lease expired and another owner took the lock
+		}
+		return errors.Wrapf(err, "failed to unlock partition %s", prefix)
+	}
+	return nil
+}
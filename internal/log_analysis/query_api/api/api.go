@@ -0,0 +1,130 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/kelseyhightower/envconfig"
+
+	"github.com/panther-labs/panther/api/lambda/query/models"
+	"github.com/panther-labs/panther/internal/log_analysis/query_api/table"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+const (
+	resultsPrefix = "query_api/" // prefix in the Athena results bucket
+)
+
+// API has all of the handlers as receiver methods.
+type API struct{}
+
+var (
+	env          envConfig
+	awsSession   *session.Session
+	queriesDB    table.API
+	athenaClient athenaiface.AthenaAPI
+	s3Client     s3iface.S3API
+	s3Uploader   s3manageriface.UploaderAPI
+	lambdaClient lambdaiface.LambdaAPI
+)
+
+type envConfig struct {
+	QueriesTableName    string `required:"true" split_words:"true"`
+	UserIndexName       string `required:"true" split_words:"true"`
+	ExportsTableName    string `required:"true" split_words:"true"`
+	AthenaResultsBucket string `required:"true" split_words:"true"`
+}
+
+// Setup parses the environment and builds the AWS clients.
+func Setup() {
+	envconfig.MustProcess("", &env)
+
+	awsSession = session.Must(session.NewSession())
+	queriesDB = &table.QueriesTable{
+		TableName:                env.QueriesTableName,
+		UserIDStartTimeIndexName: env.UserIndexName,
+		ExportsTableName:         env.ExportsTableName,
+		Client:                   dynamodb.New(awsSession),
+	}
+	athenaClient = athena.New(awsSession)
+	s3Client = s3.New(awsSession)
+	s3Uploader = s3manager.NewUploader(awsSession)
+	lambdaClient = lambda.New(awsSession)
+}
+
+// getUserQuery returns the query if it was started by the user
+func getUserQuery(userID, queryID string) (*table.QueryItem, error) {
+	item, err := queriesDB.GetQuery(queryID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil || item.UserID != userID { // do not reveal the queries of other users
+		return nil, &genericapi.DoesNotExistError{Message: "query " + queryID + " does not exist"}
+	}
+	return item, nil
+}
+
+// queryStatus combines the stored query and its Athena execution (can be nil)
+func queryStatus(item *table.QueryItem, execution *athena.QueryExecution) *models.QueryStatus {
+	status := &models.QueryStatus{
+		QueryID:      aws.String(item.QueryID),
+		DatabaseName: aws.String(item.DatabaseName),
+		SQL:          aws.String(item.SQL),
+		StartTime:    aws.Time(item.StartTime),
+		Status:       aws.String(models.QueryRunning),
+	}
+	if execution == nil || execution.Status == nil {
+		return status
+	}
+
+	switch aws.StringValue(execution.Status.State) {
+	case athena.QueryExecutionStateSucceeded:
+		status.Status = aws.String(models.QuerySucceeded)
+	case athena.QueryExecutionStateFailed:
+		status.Status = aws.String(models.QueryFailed)
+		status.SQLError = execution.Status.StateChangeReason
+	case athena.QueryExecutionStateCancelled:
+		status.Status = aws.String(models.QueryCancelled)
+	}
+	if execution.Status.CompletionDateTime != nil {
+		status.EndTime = aws.Time(execution.Status.CompletionDateTime.UTC())
+	}
+	if execution.Statistics != nil {
+		status.DataScannedBytes = execution.Statistics.DataScannedInBytes
+		status.ExecutionTimeMilli = execution.Statistics.EngineExecutionTimeInMillis
+	}
+	return status
+}
+
+// now is replaced in tests
+var now = func() time.Time {
+	return time.Now().UTC()
+}
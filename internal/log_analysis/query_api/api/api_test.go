@@ -0,0 +1,489 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/query/models"
+	"github.com/panther-labs/panther/internal/log_analysis/query_api/table"
+	"github.com/panther-labs/panther/pkg/awsglue"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+const (
+	testUserID  = "f6cfad0a-9bb0-4681-9503-02c54cc979c7"
+	testQueryID = "6b8ab9f1-16b6-4cc8-9bd6-35e5a1b4b3e3"
+)
+
+var (
+	testStartTime = time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	testItem      = &table.QueryItem{
+		QueryID:      testQueryID,
+		UserID:       testUserID,
+		DatabaseName: "panther_logs",
+		SQL:          "SELECT * FROM aws_cloudtrail",
+		StartTime:    testStartTime,
+	}
+)
+
+func setupMocks() (*tableMock, *athenaMock) {
+	env.AthenaResultsBucket = "athena-results"
+	now = func() time.Time { return testStartTime }
+	tableMock, athenaMock := &tableMock{}, &athenaMock{}
+	queriesDB, athenaClient = tableMock, athenaMock
+	return tableMock, athenaMock
+}
+
+func TestExecuteQuery(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	athenaMock.On("StartQueryExecution", mock.Anything).
+		Return(&athena.StartQueryExecutionOutput{QueryExecutionId: aws.String(testQueryID)}, nil).Once()
+	tableMock.On("PutQuery", testItem).Return(nil).Once()
+
+	result, err := API{}.ExecuteQuery(&models.ExecuteQueryInput{
+		UserID:       aws.String(testUserID),
+		DatabaseName: aws.String("panther_logs"),
+		SQL:          aws.String("SELECT * FROM aws_cloudtrail"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, testQueryID, *result.QueryID)
+	assert.Equal(t, models.QueryRunning, *result.Status)
+	startInput := athenaMock.Calls[0].Arguments.Get(0).(*athena.StartQueryExecutionInput)
+	assert.Equal(t, "panther_logs", *startInput.QueryExecutionContext.Database)
+	assert.Equal(t, "s3://athena-results/query_api/", *startInput.ResultConfiguration.OutputLocation)
+	athenaMock.AssertExpectations(t)
+	tableMock.AssertExpectations(t)
+}
+
+func TestExecuteQueryNotReadOnly(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	_, err := API{}.ExecuteQuery(&models.ExecuteQueryInput{
+		UserID:       aws.String(testUserID),
+		DatabaseName: aws.String("panther_logs"),
+		SQL:          aws.String("DROP TABLE aws_cloudtrail"),
+	})
+	require.Error(t, err)
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+	athenaMock.AssertExpectations(t) // nothing started
+	tableMock.AssertExpectations(t)
+}
+
+func TestGetQueryStatus(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	tableMock.On("GetQuery", testQueryID).Return(testItem, nil).Once()
+	athenaMock.On("GetQueryExecution", mock.Anything).Return(&athena.GetQueryExecutionOutput{
+		QueryExecution: &athena.QueryExecution{
+			QueryExecutionId: aws.String(testQueryID),
+			Status: &athena.QueryExecutionStatus{
+				State:              aws.String(athena.QueryExecutionStateFailed),
+				StateChangeReason:  aws.String("SYNTAX_ERROR: line 1:8: Column 'x' cannot be resolved"),
+				CompletionDateTime: aws.Time(testStartTime.Add(time.Second)),
+			},
+			Statistics: &athena.QueryExecutionStatistics{
+				DataScannedInBytes:          aws.Int64(0),
+				EngineExecutionTimeInMillis: aws.Int64(100),
+			},
+		},
+	}, nil).Once()
+
+	result, err := API{}.GetQueryStatus(&models.GetQueryStatusInput{
+		UserID:  aws.String(testUserID),
+		QueryID: aws.String(testQueryID),
+	})
+	require.NoError(t, err)
+	expected := &models.QueryStatus{
+		QueryID:            aws.String(testQueryID),
+		DatabaseName:       aws.String("panther_logs"),
+		SQL:                aws.String("SELECT * FROM aws_cloudtrail"),
+		StartTime:          aws.Time(testStartTime),
+		EndTime:            aws.Time(testStartTime.Add(time.Second)),
+		Status:             aws.String(models.QueryFailed),
+		SQLError:           aws.String("SYNTAX_ERROR: line 1:8: Column 'x' cannot be resolved"),
+		DataScannedBytes:   aws.Int64(0),
+		ExecutionTimeMilli: aws.Int64(100),
+	}
+	assert.Equal(t, expected, result)
+	athenaMock.AssertExpectations(t)
+	tableMock.AssertExpectations(t)
+}
+
+func TestGetQueryStatusOtherUser(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	tableMock.On("GetQuery", testQueryID).Return(testItem, nil).Once()
+	_, err := API{}.GetQueryStatus(&models.GetQueryStatusInput{
+		UserID:  aws.String("43808de4-fbae-4f90-a9b4-1e4982d65287"),
+		QueryID: aws.String(testQueryID),
+	})
+	assert.IsType(t, &genericapi.DoesNotExistError{}, err)
+	athenaMock.AssertExpectations(t)
+
+	tableMock.On("GetQuery", testQueryID).Return((*table.QueryItem)(nil), nil).Once()
+	_, err = API{}.GetQueryStatus(&models.GetQueryStatusInput{
+		UserID:  aws.String(testUserID),
+		QueryID: aws.String(testQueryID),
+	})
+	assert.IsType(t, &genericapi.DoesNotExistError{}, err)
+}
+
+func TestGetQueryResults(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	tableMock.On("GetQuery", testQueryID).Return(testItem, nil)
+	athenaMock.On("GetQueryExecution", mock.Anything).Return(succeededExecution(), nil)
+	columns := []*athena.ColumnInfo{
+		{Name: aws.String("eventname"), Type: aws.String("varchar")},
+		{Name: aws.String("count"), Type: aws.String("bigint")},
+	}
+	athenaMock.On("GetQueryResults", &athena.GetQueryResultsInput{
+		QueryExecutionId: aws.String(testQueryID),
+		MaxResults:       aws.Int64(2),
+	}).Return(&athena.GetQueryResultsOutput{
+		ResultSet: &athena.ResultSet{
+			ResultSetMetadata: &athena.ResultSetMetadata{ColumnInfo: columns},
+			Rows: []*athena.Row{
+				{Data: []*athena.Datum{{VarCharValue: aws.String("eventname")}, {VarCharValue: aws.String("count")}}},
+				{Data: []*athena.Datum{{VarCharValue: aws.String("ConsoleLogin")}, {VarCharValue: aws.String("3")}}},
+			},
+		},
+		NextToken: aws.String("next"),
+	}, nil).Once()
+	athenaMock.On("GetQueryResults", &athena.GetQueryResultsInput{
+		QueryExecutionId: aws.String(testQueryID),
+		MaxResults:       aws.Int64(2),
+		NextToken:        aws.String("next"),
+	}).Return(&athena.GetQueryResultsOutput{
+		ResultSet: &athena.ResultSet{
+			ResultSetMetadata: &athena.ResultSetMetadata{ColumnInfo: columns},
+			Rows: []*athena.Row{
+				{Data: []*athena.Datum{{}, {VarCharValue: aws.String("1")}}},
+			},
+		},
+	}, nil).Once()
+
+	input := &models.GetQueryResultsInput{
+		UserID:   aws.String(testUserID),
+		QueryID:  aws.String(testQueryID),
+		PageSize: aws.Int64(2),
+	}
+	result, err := API{}.GetQueryResults(input)
+	require.NoError(t, err)
+	assert.Equal(t, models.QuerySucceeded, *result.Status)
+	assert.Equal(t, []*models.Column{
+		{Name: aws.String("eventname"), Type: aws.String("varchar")},
+		{Name: aws.String("count"), Type: aws.String("bigint")},
+	}, result.Columns)
+	assert.Equal(t, [][]*string{{aws.String("ConsoleLogin"), aws.String("3")}}, result.Rows) // header skipped
+	assert.Equal(t, aws.String("next"), result.PaginationToken)
+
+	input.PaginationToken = result.PaginationToken
+	result, err = API{}.GetQueryResults(input)
+	require.NoError(t, err)
+	assert.Equal(t, [][]*string{{nil, aws.String("1")}}, result.Rows)
+	assert.Nil(t, result.PaginationToken)
+	athenaMock.AssertExpectations(t)
+}
+
+func TestGetQueryResultsRunning(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	tableMock.On("GetQuery", testQueryID).Return(testItem, nil)
+	athenaMock.On("GetQueryExecution", mock.Anything).Return(&athena.GetQueryExecutionOutput{
+		QueryExecution: &athena.QueryExecution{
+			QueryExecutionId: aws.String(testQueryID),
+			Status:           &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateQueued)},
+		},
+	}, nil)
+
+	result, err := API{}.GetQueryResults(&models.GetQueryResultsInput{
+		UserID:  aws.String(testUserID),
+		QueryID: aws.String(testQueryID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.QueryRunning, *result.Status)
+	assert.Empty(t, result.Rows)
+	athenaMock.AssertExpectations(t) // no results read
+}
+
+func TestCancelQuery(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	tableMock.On("GetQuery", testQueryID).Return(testItem, nil)
+	athenaMock.On("StopQueryExecution", &athena.StopQueryExecutionInput{QueryExecutionId: aws.String(testQueryID)}).
+		Return(&athena.StopQueryExecutionOutput{}, nil).Once()
+	athenaMock.On("GetQueryExecution", mock.Anything).Return(&athena.GetQueryExecutionOutput{
+		QueryExecution: &athena.QueryExecution{
+			QueryExecutionId: aws.String(testQueryID),
+			Status:           &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateCancelled)},
+		},
+	}, nil)
+
+	result, err := API{}.CancelQuery(&models.CancelQueryInput{
+		UserID:  aws.String(testUserID),
+		QueryID: aws.String(testQueryID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.QueryCancelled, *result.Status)
+	athenaMock.AssertExpectations(t)
+}
+
+func TestListQueries(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	expiredItem := *testItem
+	expiredItem.QueryID = "11111111-16b6-4cc8-9bd6-35e5a1b4b3e3"
+	tableMock.On("ListByUser", testUserID, (*string)(nil), aws.Int(2)).
+		Return([]*table.QueryItem{testItem, &expiredItem}, aws.String("lastKey"), nil).Once()
+	athenaMock.On("BatchGetQueryExecution", &athena.BatchGetQueryExecutionInput{
+		QueryExecutionIds: []*string{aws.String(testQueryID), aws.String(expiredItem.QueryID)},
+	}).Return(&athena.BatchGetQueryExecutionOutput{
+		QueryExecutions: []*athena.QueryExecution{succeededExecution().QueryExecution},
+		UnprocessedQueryExecutionIds: []*athena.UnprocessedQueryExecutionId{
+			{QueryExecutionId: aws.String(expiredItem.QueryID), ErrorMessage: aws.String("not found")},
+		},
+	}, nil).Once()
+
+	result, err := API{}.ListQueries(&models.ListQueriesInput{
+		UserID:   aws.String(testUserID),
+		PageSize: aws.Int(2),
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Queries, 2)
+	assert.Equal(t, models.QuerySucceeded, *result.Queries[0].Status)
+	assert.Equal(t, models.QueryFailed, *result.Queries[1].Status)
+	assert.Equal(t, "not found", *result.Queries[1].SQLError)
+	assert.Equal(t, aws.String("lastKey"), result.LastEvaluatedKey)
+	athenaMock.AssertExpectations(t)
+	tableMock.AssertExpectations(t)
+}
+
+func TestListQueriesEmpty(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	tableMock.On("ListByUser", testUserID, (*string)(nil), (*int)(nil)).
+		Return([]*table.QueryItem{}, (*string)(nil), nil).Once()
+
+	result, err := API{}.ListQueries(&models.ListQueriesInput{UserID: aws.String(testUserID)})
+	require.NoError(t, err)
+	assert.Empty(t, result.Queries)
+	athenaMock.AssertExpectations(t) // no executions read
+}
+
+func TestSearchIndicators(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	searchTables = func() []*awsglue.GlueTableMetadata { return []*awsglue.GlueTableMetadata{testTable} }
+	athenaMock.On("StartQueryExecution", mock.Anything).
+		Return(&athena.StartQueryExecutionOutput{QueryExecutionId: aws.String(testQueryID)}, nil).Once()
+	tableMock.On("PutQuery", mock.Anything).Return(nil).Once()
+
+	result, err := API{}.SearchIndicators(&models.SearchIndicatorsInput{
+		UserID:     aws.String(testUserID),
+		Indicators: &models.Indicators{DomainNames: []string{"malicious.example.com"}},
+		StartTime:  aws.Time(testSearchStart),
+		EndTime:    aws.Time(testSearchEnd),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, testQueryID, *result.QueryID)
+	assert.Equal(t, "panther_logs", *result.DatabaseName)
+	startInput := athenaMock.Calls[0].Arguments.Get(0).(*athena.StartQueryExecutionInput)
+	assert.Contains(t, *startInput.QueryString, "arrays_overlap(p_any_domain_names, ARRAY['malicious.example.com'])")
+	item := tableMock.Calls[0].Arguments.Get(0).(*table.QueryItem)
+	assert.Equal(t, *startInput.QueryString, item.SQL) // in the query history of the user
+	athenaMock.AssertExpectations(t)
+	tableMock.AssertExpectations(t)
+}
+
+func TestSearchIndicatorsInvalid(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	searchTables = func() []*awsglue.GlueTableMetadata { return []*awsglue.GlueTableMetadata{testTable} }
+	input := &models.SearchIndicatorsInput{
+		UserID:     aws.String(testUserID),
+		Indicators: &models.Indicators{},
+		StartTime:  aws.Time(testSearchStart),
+		EndTime:    aws.Time(testSearchEnd),
+	}
+	_, err := API{}.SearchIndicators(input)
+	assert.Equal(t, &genericapi.InvalidInputError{Message: "at least one indicator is required"}, err)
+
+	input.Indicators.AWSAccountIDs = []string{"123456789012"} // testTable has no AWS fields
+	_, err = API{}.SearchIndicators(input)
+	assert.Equal(t, &genericapi.InvalidInputError{Message: "no log table has fields for the indicators"}, err)
+	athenaMock.AssertExpectations(t) // nothing started
+	tableMock.AssertExpectations(t)
+}
+
+func TestGetIndicatorSearchResults(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	tableMock.On("GetQuery", testQueryID).Return(testItem, nil)
+	athenaMock.On("GetQueryExecution", mock.Anything).Return(succeededExecution(), nil)
+	var columns []*athena.ColumnInfo
+	for _, name := range hitColumns {
+		columns = append(columns, &athena.ColumnInfo{Name: aws.String(name), Type: aws.String("varchar")})
+	}
+	hitRow := func(logType, eventTime, rowID, matches string) *athena.Row {
+		return &athena.Row{Data: []*athena.Datum{
+			{VarCharValue: aws.String(logType)},
+			{VarCharValue: aws.String(eventTime)},
+			{VarCharValue: aws.String(rowID)},
+			{VarCharValue: aws.String(matches)},
+		}}
+	}
+	athenaMock.On("GetQueryResults", mock.Anything).Return(&athena.GetQueryResultsOutput{
+		ResultSet: &athena.ResultSet{
+			ResultSetMetadata: &athena.ResultSetMetadata{ColumnInfo: columns},
+			Rows: []*athena.Row{
+				hitRow("p_log_type", "p_event_time", "p_row_id", "matches"),
+				hitRow("AWS.CloudTrail", "2020-04-01 23:30:00.000", "1", `["192.0.2.10"]`),
+				hitRow("AWS.CloudTrail", "2020-04-01 23:10:00.000", "2", `["192.0.2.10","192.0.2.11"]`),
+				hitRow("AWS.VPCFlow", "2020-04-02 00:05:00.000", "3", `["192.0.2.11"]`),
+			},
+		},
+		NextToken: aws.String("next"),
+	}, nil).Once()
+
+	result, err := API{}.GetIndicatorSearchResults(&models.GetIndicatorSearchResultsInput{
+		UserID:  aws.String(testUserID),
+		QueryID: aws.String(testQueryID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.QuerySucceeded, *result.Status)
+	assert.Equal(t, []*models.LogTypeHits{
+		{
+			LogType: aws.String("AWS.CloudTrail"),
+			Hits: []*models.IndicatorHit{
+				{
+					EventTime: aws.Time(time.Date(2020, 4, 1, 23, 30, 0, 0, time.UTC)),
+					RowID:     aws.String("1"),
+					Matches:   []string{"192.0.2.10"},
+				},
+				{
+					EventTime: aws.Time(time.Date(2020, 4, 1, 23, 10, 0, 0, time.UTC)),
+					RowID:     aws.String("2"),
+					Matches:   []string{"192.0.2.10", "192.0.2.11"},
+				},
+			},
+		},
+		{
+			LogType: aws.String("AWS.VPCFlow"),
+			Hits: []*models.IndicatorHit{
+				{
+					EventTime: aws.Time(time.Date(2020, 4, 2, 0, 5, 0, 0, time.UTC)),
+					RowID:     aws.String("3"),
+					Matches:   []string{"192.0.2.11"},
+				},
+			},
+		},
+	}, result.LogTypes)
+	assert.Equal(t, aws.String("next"), result.PaginationToken)
+	athenaMock.AssertExpectations(t)
+}
+
+func TestGetIndicatorSearchResultsNotSearch(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	tableMock.On("GetQuery", testQueryID).Return(testItem, nil)
+	athenaMock.On("GetQueryExecution", mock.Anything).Return(succeededExecution(), nil)
+	athenaMock.On("GetQueryResults", mock.Anything).Return(&athena.GetQueryResultsOutput{
+		ResultSet: &athena.ResultSet{
+			ResultSetMetadata: &athena.ResultSetMetadata{ColumnInfo: []*athena.ColumnInfo{
+				{Name: aws.String("eventname"), Type: aws.String("varchar")},
+			}},
+		},
+	}, nil).Once()
+
+	_, err := API{}.GetIndicatorSearchResults(&models.GetIndicatorSearchResultsInput{
+		UserID:  aws.String(testUserID),
+		QueryID: aws.String(testQueryID),
+	})
+	require.Error(t, err)
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+}
+
+func succeededExecution() *athena.GetQueryExecutionOutput {
+	return &athena.GetQueryExecutionOutput{
+		QueryExecution: &athena.QueryExecution{
+			QueryExecutionId: aws.String(testQueryID),
+			Status:           &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateSucceeded)},
+		},
+	}
+}
+
+type tableMock struct {
+	table.API
+	mock.Mock
+}
+
+func (m *tableMock) PutQuery(item *table.QueryItem) error {
+	args := m.Called(item)
+	return args.Error(0)
+}
+
+func (m *tableMock) GetQuery(queryID string) (*table.QueryItem, error) {
+	args := m.Called(queryID)
+	return args.Get(0).(*table.QueryItem), args.Error(1)
+}
+
+func (m *tableMock) ListByUser(userID string, startKey *string, pageSize *int) ([]*table.QueryItem, *string, error) {
+	args := m.Called(userID, startKey, pageSize)
+	return args.Get(0).([]*table.QueryItem), args.Get(1).(*string), args.Error(2)
+}
+
+func (m *tableMock) PutExport(item *table.ExportItem) error {
+	args := m.Called(item)
+	return args.Error(0)
+}
+
+func (m *tableMock) GetExport(exportID string) (*table.ExportItem, error) {
+	args := m.Called(exportID)
+	return args.Get(0).(*table.ExportItem), args.Error(1)
+}
+
+type athenaMock struct {
+	athenaiface.AthenaAPI
+	mock.Mock
+}
+
+func (m *athenaMock) StartQueryExecution(input *athena.StartQueryExecutionInput) (*athena.StartQueryExecutionOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.StartQueryExecutionOutput), args.Error(1)
+}
+
+func (m *athenaMock) GetQueryExecution(input *athena.GetQueryExecutionInput) (*athena.GetQueryExecutionOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.GetQueryExecutionOutput), args.Error(1)
+}
+
+func (m *athenaMock) GetQueryResults(input *athena.GetQueryResultsInput) (*athena.GetQueryResultsOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.GetQueryResultsOutput), args.Error(1)
+}
+
+func (m *athenaMock) StopQueryExecution(input *athena.StopQueryExecutionInput) (*athena.StopQueryExecutionOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.StopQueryExecutionOutput), args.Error(1)
+}
+
+func (m *athenaMock) BatchGetQueryExecution(
+	input *athena.BatchGetQueryExecutionInput) (*athena.BatchGetQueryExecutionOutput, error) {
+
+	args := m.Called(input)
+	return args.Get(0).(*athena.BatchGetQueryExecutionOutput), args.Error(1)
+}
@@ -0,0 +1,113 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+
+	logmodels "github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+	"github.com/panther-labs/panther/api/lambda/query/models"
+	"github.com/panther-labs/panther/pkg/awsglue"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+// timelineColumns are the columns of the entity timeline results
+var timelineColumns = []string{"p_event_time", "log_type", "p_row_id"}
+
+// QueryEntityTimeline starts a query for the events of an entity and returns without waiting for it to finish.
+func (API) QueryEntityTimeline(input *models.QueryEntityTimelineInput) (*models.QueryEntityTimelineOutput, error) {
+	var view *awsglue.IndicatorView
+	for i := range awsglue.IndicatorViews {
+		if awsglue.IndicatorViews[i].IndicatorType == *input.EntityType {
+			view = &awsglue.IndicatorViews[i]
+			break
+		}
+	}
+	if view == nil {
+		return nil, &genericapi.InvalidInputError{Message: "unknown entity type " + *input.EntityType}
+	}
+
+	sql := entityTimelineSQL(view, *input.Entity, *input.StartTime, *input.EndTime)
+	return startQuery(*input.UserID, awsglue.ViewsDatabaseName, sql)
+}
+
+// GetEntityTimelineResults returns the status of an entity timeline query and a page of its events once it succeeded.
+func (API) GetEntityTimelineResults(
+	input *models.GetEntityTimelineResultsInput) (*models.GetEntityTimelineResultsOutput, error) {
+
+	item, err := getUserQuery(*input.UserID, *input.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	results, err := queryResults(item, input.PaginationToken, input.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	output := &models.GetEntityTimelineResultsOutput{
+		QueryStatus:     results.QueryStatus,
+		PaginationToken: results.PaginationToken,
+	}
+	if *output.Status != models.QuerySucceeded {
+		return output, nil
+	}
+	if !hasColumns(results.Columns, timelineColumns) {
+		return nil, &genericapi.InvalidInputError{Message: "query " + item.QueryID + " is not an entity timeline"}
+	}
+
+	output.Events = make([]*models.TimelineEvent, len(results.Rows))
+	for i, row := range results.Rows {
+		if output.Events[i], err = parseTimelineEvent(row); err != nil {
+			return nil, err
+		}
+	}
+	return output, nil
+}
+
+// entityTimelineSQL returns the query of the events with the entity in the p_any column of the view, oldest first
+func entityTimelineSQL(view *awsglue.IndicatorView, entity string, start, end time.Time) string {
+	// the views have the hourly partition columns of the log tables
+	partitions := awsglue.NewGlueTableMetadata(logmodels.LogData, view.ViewName, "", awsglue.GlueTableHourly, nil)
+	return "SELECT " + strings.Join(timelineColumns, ", ") +
+		" FROM " + awsglue.ViewsDatabaseName + "." + view.ViewName +
+		" WHERE " + partitions.PartitionPredicate(start, end) +
+		" AND p_event_time >= timestamp '" + start.UTC().Format(athenaTimeLayout) + "'" +
+		" AND p_event_time < timestamp '" + end.UTC().Format(athenaTimeLayout) + "'" +
+		" AND indicator = '" + strings.Replace(entity, "'", "''", -1) + "'" +
+		" ORDER BY p_event_time, log_type, p_row_id"
+}
+
+// parseTimelineEvent returns the event of a row of the entity timeline results
+func parseTimelineEvent(row []*string) (*models.TimelineEvent, error) {
+	if len(row) != len(timelineColumns) || row[0] == nil || row[1] == nil || row[2] == nil {
+		return nil, errors.New("invalid entity timeline row")
+	}
+	eventTime, err := time.Parse(athenaTimeLayout, *row[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid p_event_time")
+	}
+	return &models.TimelineEvent{
+		EventTime: aws.Time(eventTime),
+		LogType:   row[1],
+		RowID:     row[2],
+	}, nil
+}
@@ -0,0 +1,139 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/query/models"
+	"github.com/panther-labs/panther/internal/log_analysis/query_api/table"
+	"github.com/panther-labs/panther/pkg/awsglue"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+func TestEntityTimelineSQL(t *testing.T) {
+	start := time.Date(2020, 4, 1, 22, 30, 0, 0, time.UTC)
+	end := time.Date(2020, 4, 2, 0, 0, 0, 0, time.UTC)
+	view := &awsglue.IndicatorViews[0]
+	assert.Equal(t, "SELECT p_event_time, log_type, p_row_id FROM panther_views.all_ip_addresses"+
+		" WHERE ((year=2020 AND month=4 AND day=1 AND hour=22) OR (year=2020 AND month=4 AND day=1 AND hour=23))"+
+		" AND p_event_time >= timestamp '2020-04-01 22:30:00.000'"+
+		" AND p_event_time < timestamp '2020-04-02 00:00:00.000'"+
+		" AND indicator = 'it''s'"+
+		" ORDER BY p_event_time, log_type, p_row_id",
+		entityTimelineSQL(view, "it's", start, end))
+}
+
+func TestQueryEntityTimeline(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	athenaMock.On("StartQueryExecution", mock.Anything).
+		Return(&athena.StartQueryExecutionOutput{QueryExecutionId: aws.String(testQueryID)}, nil).Once()
+	tableMock.On("PutQuery", mock.Anything).Return(nil).Once()
+
+	result, err := API{}.QueryEntityTimeline(&models.QueryEntityTimelineInput{
+		UserID:     aws.String(testUserID),
+		EntityType: aws.String("aws_instance_id"),
+		Entity:     aws.String("i-0123456789abcdef0"),
+		StartTime:  aws.Time(testSearchStart),
+		EndTime:    aws.Time(testSearchEnd),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "panther_views", *result.DatabaseName)
+	startInput := athenaMock.Calls[0].Arguments.Get(0).(*athena.StartQueryExecutionInput)
+	assert.Equal(t, "panther_views", *startInput.QueryExecutionContext.Database)
+	assert.Contains(t, *startInput.QueryString, "FROM panther_views.all_aws_instance_ids")
+	assert.Contains(t, *startInput.QueryString, "indicator = 'i-0123456789abcdef0'")
+	item := tableMock.Calls[0].Arguments.Get(0).(*table.QueryItem)
+	assert.Equal(t, *startInput.QueryString, item.SQL) // in the query history of the user
+	athenaMock.AssertExpectations(t)
+	tableMock.AssertExpectations(t)
+
+	_, err = API{}.QueryEntityTimeline(&models.QueryEntityTimelineInput{
+		UserID:     aws.String(testUserID),
+		EntityType: aws.String("username"),
+		Entity:     aws.String("root"),
+		StartTime:  aws.Time(testSearchStart),
+		EndTime:    aws.Time(testSearchEnd),
+	})
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+}
+
+func TestGetEntityTimelineResults(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	tableMock.On("GetQuery", testQueryID).Return(testItem, nil)
+	athenaMock.On("GetQueryExecution", mock.Anything).Return(succeededExecution(), nil)
+	columns := []*athena.ColumnInfo{
+		{Name: aws.String("p_event_time"), Type: aws.String("timestamp")},
+		{Name: aws.String("log_type"), Type: aws.String("varchar")},
+		{Name: aws.String("p_row_id"), Type: aws.String("varchar")},
+	}
+	athenaMock.On("GetQueryResults", mock.Anything).Return(&athena.GetQueryResultsOutput{
+		ResultSet: &athena.ResultSet{
+			ResultSetMetadata: &athena.ResultSetMetadata{ColumnInfo: columns},
+			Rows: []*athena.Row{
+				{Data: []*athena.Datum{
+					{VarCharValue: aws.String("p_event_time")}, {VarCharValue: aws.String("log_type")}, {VarCharValue: aws.String("p_row_id")},
+				}},
+				{Data: []*athena.Datum{
+					{VarCharValue: aws.String("2020-04-01 12:00:00.000")}, {VarCharValue: aws.String("AWS.VPCFlow")}, {VarCharValue: aws.String("row1")},
+				}},
+				{Data: []*athena.Datum{
+					{VarCharValue: aws.String("2020-04-01 12:05:00.000")}, {VarCharValue: aws.String("AWS.CloudTrail")}, {VarCharValue: aws.String("row2")},
+				}},
+			},
+		},
+	}, nil).Once()
+
+	result, err := API{}.GetEntityTimelineResults(&models.GetEntityTimelineResultsInput{
+		UserID:  aws.String(testUserID),
+		QueryID: aws.String(testQueryID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*models.TimelineEvent{
+		{EventTime: aws.Time(time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)), LogType: aws.String("AWS.VPCFlow"), RowID: aws.String("row1")},
+		{EventTime: aws.Time(time.Date(2020, 4, 1, 12, 5, 0, 0, time.UTC)), LogType: aws.String("AWS.CloudTrail"), RowID: aws.String("row2")},
+	}, result.Events)
+	athenaMock.AssertExpectations(t)
+}
+
+func TestGetEntityTimelineResultsNotTimeline(t *testing.T) {
+	tableMock, athenaMock := setupMocks()
+	tableMock.On("GetQuery", testQueryID).Return(testItem, nil)
+	athenaMock.On("GetQueryExecution", mock.Anything).Return(succeededExecution(), nil)
+	athenaMock.On("GetQueryResults", mock.Anything).Return(&athena.GetQueryResultsOutput{
+		ResultSet: &athena.ResultSet{
+			ResultSetMetadata: &athena.ResultSetMetadata{ColumnInfo: []*athena.ColumnInfo{
+				{Name: aws.String("eventname"), Type: aws.String("varchar")},
+			}},
+		},
+	}, nil).Once()
+
+	_, err := API{}.GetEntityTimelineResults(&models.GetEntityTimelineResultsInput{
+		UserID:  aws.String(testUserID),
+		QueryID: aws.String(testQueryID),
+	})
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+}
@@ -0,0 +1,60 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/panther-labs/panther/api/lambda/query/models"
+	"github.com/panther-labs/panther/internal/log_analysis/query_api/table"
+	"github.com/panther-labs/panther/pkg/awsathena"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+// ExecuteQuery starts a query and returns without waiting for it to finish.
+func (API) ExecuteQuery(input *models.ExecuteQueryInput) (*models.ExecuteQueryOutput, error) {
+	if err := awsathena.ValidateReadOnlySQL(*input.SQL); err != nil {
+		return nil, &genericapi.InvalidInputError{Message: err.Error()}
+	}
+
+	return startQuery(*input.UserID, *input.DatabaseName, *input.SQL)
+}
+
+// startQuery starts the query in Athena and adds it to the query history of the user
+func startQuery(userID, databaseName, sql string) (*models.QueryStatus, error) {
+	resultsPath := "s3://" + env.AthenaResultsBucket + "/" + resultsPrefix
+	startOutput, err := awsathena.StartQuery(athenaClient, databaseName, sql, aws.String(resultsPath))
+	if err != nil {
+		return nil, err
+	}
+
+	item := &table.QueryItem{
+		QueryID:      *startOutput.QueryExecutionId,
+		UserID:       userID,
+		DatabaseName: databaseName,
+		SQL:          sql,
+		StartTime:    now(),
+	}
+	if err = queriesDB.PutQuery(item); err != nil {
+		// the query would not be visible to the user
+		_, _ = awsathena.StopQuery(athenaClient, item.QueryID)
+		return nil, err
+	}
+	return queryStatus(item, nil), nil
+}
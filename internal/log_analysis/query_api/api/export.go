@@ -0,0 +1,346 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/api/lambda/query/models"
+	"github.com/panther-labs/panther/internal/log_analysis/query_api/table"
+	"github.com/panther-labs/panther/pkg/awsathena"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+const (
+	exportsPrefix                = resultsPrefix + "exports/"
+	defaultLinkExpirationMinutes = 60
+	// links are valid at most for an hour when the signing credentials do not report when they expire, such as
+	// the credentials of the Lambda environment
+	maxUnknownLinkLifetime = time.Hour
+	// longer than the Lambda timeout, CSV and JSON lines exports still running after it have failed
+	exportTimeout = 20 * time.Minute
+)
+
+// ExportQueryResults writes all the results of a succeeded query to the results bucket.
+func (API) ExportQueryResults(input *models.ExportQueryResultsInput) (*models.ExportQueryResultsOutput, error) {
+	item, err := getUserQuery(*input.UserID, *input.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	executionOutput, err := awsathena.Status(athenaClient, item.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	if status := queryStatus(item, executionOutput.QueryExecution); *status.Status != models.QuerySucceeded {
+		return nil, &genericapi.InvalidInputError{Message: "query " + item.QueryID + " has not succeeded"}
+	}
+
+	exportID := uuid.New().String()
+	export := &table.ExportItem{
+		ExportID:     exportID,
+		UserID:       item.UserID,
+		QueryID:      item.QueryID,
+		DatabaseName: item.DatabaseName,
+		SQL:          item.SQL,
+		Format:       *input.Format,
+		Compressed:   input.Compress,
+		CreatedAt:    now(),
+		Prefix:       exportsPrefix + exportID + "/",
+	}
+
+	if export.Format == models.ExportParquet {
+		if err = startUnload(export); err != nil {
+			return nil, err
+		}
+	} else {
+		export.Status = models.QueryRunning
+	}
+
+	if err = queriesDB.PutExport(export); err != nil {
+		if export.UnloadQueryID != nil {
+			// the export would not be visible to the user
+			_, _ = awsathena.StopQuery(athenaClient, *export.UnloadQueryID)
+		}
+		return nil, err
+	}
+	if export.UnloadQueryID == nil {
+		if err = startExport(export); err != nil {
+			export.Status = models.QueryFailed
+			export.Error = aws.String("the export could not be started")
+			_ = queriesDB.PutExport(export)
+			return nil, err
+		}
+	}
+	zap.L().Info("query results exported",
+		zap.String("userId", export.UserID),
+		zap.String("exportId", export.ExportID),
+		zap.String("queryId", export.QueryID),
+		zap.String("format", export.Format),
+		zap.String("sql", export.SQL))
+
+	return exportStatus(export, input.LinkExpirationMinutes)
+}
+
+// GetQueryExport returns an export of the user, with new links once it succeeded.
+func (API) GetQueryExport(input *models.GetQueryExportInput) (*models.GetQueryExportOutput, error) {
+	export, err := queriesDB.GetExport(*input.ExportID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil || export.UserID != *input.UserID { // do not reveal the exports of other users
+		return nil, &genericapi.DoesNotExistError{Message: "export " + *input.ExportID + " does not exist"}
+	}
+	return exportStatus(export, input.LinkExpirationMinutes)
+}
+
+// RunQueryExport writes the files of a CSV or JSON lines export and records whether it succeeded.
+func (API) RunQueryExport(input *models.RunQueryExportInput) error {
+	export, err := queriesDB.GetExport(*input.ExportID)
+	if err != nil {
+		return err
+	}
+	if export == nil || export.Status != models.QueryRunning {
+		// asynchronous invocations can be delivered again after the export finished
+		zap.L().Warn("export is not running", zap.String("exportId", *input.ExportID))
+		return nil
+	}
+
+	if err = uploadResults(export); err != nil {
+		// the failure is returned to the user, retrying the invocation would not help
+		zap.L().Error("query results export failed", zap.String("exportId", export.ExportID), zap.Error(err))
+		export.Status = models.QueryFailed
+		export.Error = aws.String(err.Error())
+	} else {
+		export.Status = models.QuerySucceeded
+	}
+	return queriesDB.PutExport(export)
+}
+
+// startExport invokes the lambda asynchronously to write the files of the export, which can take longer
+// than the callers of the API wait for
+func startExport(export *table.ExportItem) error {
+	payload, err := jsoniter.Marshal(&models.LambdaInput{
+		RunQueryExport: &models.RunQueryExportInput{ExportID: aws.String(export.ExportID)},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal export "+export.ExportID)
+	}
+	_, err = lambdaClient.Invoke(&lambda.InvokeInput{
+		FunctionName:   aws.String(lambdacontext.FunctionName),
+		InvocationType: aws.String(lambda.InvocationTypeEvent),
+		Payload:        payload,
+	})
+	return errors.Wrapf(err, "failed to start export %s", export.ExportID)
+}
+
+// uploadResults streams the results of the query to a file under the export prefix
+func uploadResults(export *table.ExportItem) error {
+	key := export.Prefix + export.QueryID + "." + export.Format
+	contentType := "text/csv"
+	if export.Format == models.ExportJSONL {
+		contentType = "application/x-ndjson"
+	}
+	if export.Compressed {
+		key += ".gz"
+		contentType = "application/gzip"
+	}
+
+	reader, writer := io.Pipe()
+	rowCounts := make(chan int64, 1)
+	go func() {
+		var w io.Writer = writer
+		var gzipWriter *gzip.Writer
+		if export.Compressed {
+			gzipWriter = gzip.NewWriter(writer)
+			w = gzipWriter
+		}
+		rowCount, err := awsathena.ExportResults(athenaClient, export.QueryID, export.Format, w)
+		if err == nil && gzipWriter != nil {
+			err = gzipWriter.Close()
+		}
+		rowCounts <- rowCount
+		_ = writer.CloseWithError(err) // the upload fails if the export failed
+	}()
+
+	_, err := s3Uploader.Upload(&s3manager.UploadInput{
+		Body:               reader,
+		Bucket:             aws.String(env.AthenaResultsBucket),
+		Key:                aws.String(key),
+		ContentType:        aws.String(contentType),
+		ContentDisposition: aws.String("attachment; filename=\"" + key[strings.LastIndex(key, "/")+1:] + "\""),
+	})
+	if err != nil {
+		// stop the export if the upload failed before reading everything
+		_ = reader.CloseWithError(err)
+		return errors.Wrapf(err, "failed to export results of %s", export.QueryID)
+	}
+	export.RowCount = aws.Int64(<-rowCounts)
+	return nil
+}
+
+// startUnload runs the query again with an Athena UNLOAD writing Parquet files under the export prefix.
+// Athena only writes the results of a query as CSV, the rows of the UNLOAD are those of the new execution.
+func startUnload(export *table.ExportItem) error {
+	tokens, err := awsathena.TokenizeSQL(export.SQL)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 || tokens[0].Quoted || (tokens[0].Text != "select" && tokens[0].Text != "with") {
+		return &genericapi.InvalidInputError{Message: "only the results of SELECT and WITH statements can be exported as Parquet"}
+	}
+
+	sql := strings.TrimSpace(export.SQL)
+	for strings.HasSuffix(sql, ";") {
+		sql = strings.TrimSpace(strings.TrimSuffix(sql, ";"))
+	}
+	properties := "format = 'PARQUET'"
+	if export.Compressed {
+		properties += ", compression = 'GZIP'"
+	}
+	// the new lines end a trailing comment of the query
+	unloadSQL := "UNLOAD (\n" + sql + "\n) TO 's3://" + env.AthenaResultsBucket + "/" + export.Prefix + "' WITH (" + properties + ")"
+
+	resultsPath := "s3://" + env.AthenaResultsBucket + "/" + resultsPrefix
+	startOutput, err := awsathena.StartQuery(athenaClient, export.DatabaseName, unloadSQL, aws.String(resultsPath))
+	if err != nil {
+		return err
+	}
+	export.UnloadQueryID = startOutput.QueryExecutionId
+	return nil
+}
+
+// exportStatus returns the export with links to its files once it succeeded
+func exportStatus(export *table.ExportItem, linkExpirationMinutes *int64) (*models.QueryExport, error) {
+	result := &models.QueryExport{
+		ExportID:   aws.String(export.ExportID),
+		QueryID:    aws.String(export.QueryID),
+		Format:     aws.String(export.Format),
+		Compressed: export.Compressed,
+		CreatedAt:  aws.Time(export.CreatedAt),
+		Status:     aws.String(models.QuerySucceeded),
+		RowCount:   export.RowCount,
+		Files:      []*models.ExportFile{},
+	}
+
+	switch export.Status {
+	case models.QueryRunning:
+		result.Status = aws.String(models.QueryRunning)
+		if now().Sub(export.CreatedAt) > exportTimeout {
+			result.Status = aws.String(models.QueryFailed)
+			result.Error = aws.String("the export timed out")
+		}
+		return result, nil
+	case models.QueryFailed:
+		result.Status = aws.String(models.QueryFailed)
+		result.Error = export.Error
+		return result, nil
+	}
+
+	if export.UnloadQueryID != nil {
+		executionOutput, err := awsathena.Status(athenaClient, *export.UnloadQueryID)
+		if err != nil {
+			return nil, err
+		}
+		status := executionOutput.QueryExecution.Status
+		switch aws.StringValue(status.State) {
+		case athena.QueryExecutionStateSucceeded:
+		case athena.QueryExecutionStateFailed, athena.QueryExecutionStateCancelled:
+			result.Status = aws.String(models.QueryFailed)
+			result.Error = status.StateChangeReason
+			return result, nil
+		default:
+			result.Status = aws.String(models.QueryRunning)
+			return result, nil
+		}
+	}
+
+	if linkExpirationMinutes == nil {
+		linkExpirationMinutes = aws.Int64(defaultLinkExpirationMinutes)
+	}
+	requestedDuration := time.Duration(*linkExpirationMinutes) * time.Minute
+	linkDuration := requestedDuration
+	listInput := &s3.ListObjectsV2Input{
+		Bucket: aws.String(env.AthenaResultsBucket),
+		Prefix: aws.String(export.Prefix),
+	}
+	var presignErr error
+	err := s3Client.ListObjectsV2Pages(listInput, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			request, _ := s3Client.GetObjectRequest(&s3.GetObjectInput{
+				Bucket: aws.String(env.AthenaResultsBucket),
+				Key:    object.Key,
+			})
+			duration, err := linkLifetime(request.Config.Credentials, requestedDuration)
+			if err != nil {
+				presignErr = err
+				return false
+			}
+			if duration < linkDuration {
+				linkDuration = duration
+			}
+			url, err := request.Presign(duration)
+			if err != nil {
+				presignErr = errors.Wrapf(err, "failed to presign %s", *object.Key)
+				return false
+			}
+			result.Files = append(result.Files, &models.ExportFile{URL: aws.String(url), SizeBytes: object.Size})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list files of export %s", export.ExportID)
+	}
+	if presignErr != nil {
+		return nil, presignErr
+	}
+	result.LinkExpiration = aws.Time(now().Add(linkDuration))
+	return result, nil
+}
+
+// linkLifetime caps the lifetime of a download link at the remaining lifetime of the credentials signing it: the
+// links are signed with the temporary credentials of the Lambda role and stop working when they expire.
+func linkLifetime(signer *credentials.Credentials, requested time.Duration) (time.Duration, error) {
+	// refreshes expired credentials
+	if _, err := signer.Get(); err != nil {
+		return 0, errors.Wrap(err, "failed to get the credentials signing the links")
+	}
+	remaining := maxUnknownLinkLifetime
+	if expiresAt, err := signer.ExpiresAt(); err == nil {
+		remaining = expiresAt.Sub(now())
+	}
+	if requested > remaining {
+		return remaining, nil
+	}
+	return requested, nil
+}
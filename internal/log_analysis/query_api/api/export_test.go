@@ -0,0 +1,414 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/query/models"
+	"github.com/panther-labs/panther/internal/log_analysis/query_api/table"
+	"github.com/panther-labs/panther/pkg/genericapi"
+	"github.com/panther-labs/panther/pkg/testutils"
+)
+
+const testExportID = "0a4e5b6c-1d2e-4f3a-8b4c-5d6e7f8a9b0c"
+
+type s3Mock struct {
+	s3iface.S3API
+	mock.Mock
+	signer *s3.S3 // presigns offline with static credentials
+}
+
+func (m *s3Mock) ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	args := m.Called(input, fn)
+	fn(args.Get(0).(*s3.ListObjectsV2Output), true)
+	return args.Error(1)
+}
+
+func (m *s3Mock) GetObjectRequest(input *s3.GetObjectInput) (*request.Request, *s3.GetObjectOutput) {
+	return m.signer.GetObjectRequest(input)
+}
+
+type uploaderMock struct {
+	s3manageriface.UploaderAPI
+	mock.Mock
+	body []byte
+}
+
+func (m *uploaderMock) Upload(input *s3manager.UploadInput, options ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.body = body
+	args := m.Called(input)
+	return args.Get(0).(*s3manager.UploadOutput), args.Error(1)
+}
+
+func setupExportMocks() (*tableMock, *athenaMock, *s3Mock, *uploaderMock) {
+	tableMock, athenaMock := setupMocks()
+	awsSession := session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}))
+	s3Mock, uploaderMock := &s3Mock{signer: s3.New(awsSession)}, &uploaderMock{}
+	s3Client, s3Uploader = s3Mock, uploaderMock
+	return tableMock, athenaMock, s3Mock, uploaderMock
+}
+
+func mockExportResults(athenaMock *athenaMock) {
+	athenaMock.On("GetQueryResults", mock.Anything).Return(&athena.GetQueryResultsOutput{
+		ResultSet: &athena.ResultSet{
+			ResultSetMetadata: &athena.ResultSetMetadata{ColumnInfo: []*athena.ColumnInfo{
+				{Name: aws.String("eventname"), Type: aws.String("varchar")},
+			}},
+			Rows: []*athena.Row{
+				{Data: []*athena.Datum{{VarCharValue: aws.String("eventname")}}},
+				{Data: []*athena.Datum{{VarCharValue: aws.String("ConsoleLogin")}}},
+			},
+		},
+	}, nil).Once()
+}
+
+func TestExportQueryResultsCSV(t *testing.T) {
+	tableMock, athenaMock, _, _ := setupExportMocks()
+	lambdaMock := &testutils.LambdaMock{}
+	lambdaClient = lambdaMock
+	tableMock.On("GetQuery", testQueryID).Return(testItem, nil).Once()
+	athenaMock.On("GetQueryExecution", mock.Anything).Return(succeededExecution(), nil).Once()
+	tableMock.On("PutExport", mock.Anything).Return(nil).Once()
+	lambdaMock.On("Invoke", mock.Anything).Return(&lambda.InvokeOutput{}, nil).Once()
+
+	result, err := API{}.ExportQueryResults(&models.ExportQueryResultsInput{
+		UserID:   aws.String(testUserID),
+		QueryID:  aws.String(testQueryID),
+		Format:   aws.String(models.ExportCSV),
+		Compress: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.QueryRunning, *result.Status)
+	assert.True(t, result.Compressed)
+	assert.Empty(t, result.Files)
+
+	// the export is recorded with the user before the results are written
+	export := tableMock.Calls[1].Arguments.Get(0).(*table.ExportItem)
+	assert.Equal(t, testUserID, export.UserID)
+	assert.Equal(t, testItem.SQL, export.SQL)
+	assert.Equal(t, *result.ExportID, export.ExportID)
+	assert.Equal(t, models.QueryRunning, export.Status)
+
+	invokeInput := lambdaMock.Calls[0].Arguments.Get(0).(*lambda.InvokeInput)
+	assert.Equal(t, lambda.InvocationTypeEvent, *invokeInput.InvocationType)
+	var payload models.LambdaInput
+	require.NoError(t, jsoniter.Unmarshal(invokeInput.Payload, &payload))
+	assert.Equal(t, export.ExportID, *payload.RunQueryExport.ExportID)
+	athenaMock.AssertExpectations(t)
+	tableMock.AssertExpectations(t)
+	lambdaMock.AssertExpectations(t)
+}
+
+func TestRunQueryExport(t *testing.T) {
+	tableMock, athenaMock, s3Mock, uploaderMock := setupExportMocks()
+	export := &table.ExportItem{
+		ExportID:   testExportID,
+		UserID:     testUserID,
+		QueryID:    testQueryID,
+		Format:     models.ExportCSV,
+		Compressed: true,
+		CreatedAt:  testStartTime,
+		Prefix:     "query_api/exports/" + testExportID + "/",
+		Status:     models.QueryRunning,
+	}
+	tableMock.On("GetExport", testExportID).Return(export, nil)
+	mockExportResults(athenaMock)
+	uploaderMock.On("Upload", mock.Anything).Return(&s3manager.UploadOutput{}, nil).Once()
+	tableMock.On("PutExport", export).Return(nil).Once()
+
+	require.NoError(t, API{}.RunQueryExport(&models.RunQueryExportInput{ExportID: aws.String(testExportID)}))
+	assert.Equal(t, models.QuerySucceeded, export.Status)
+	assert.Equal(t, int64(1), *export.RowCount)
+
+	uploadInput := uploaderMock.Calls[0].Arguments.Get(0).(*s3manager.UploadInput)
+	assert.Equal(t, "athena-results", *uploadInput.Bucket)
+	assert.Equal(t, "query_api/exports/"+testExportID+"/"+testQueryID+".csv.gz", *uploadInput.Key)
+	assert.Equal(t, "application/gzip", *uploadInput.ContentType)
+	gzipReader, err := gzip.NewReader(bytes.NewReader(uploaderMock.body))
+	require.NoError(t, err)
+	csv, err := ioutil.ReadAll(gzipReader)
+	require.NoError(t, err)
+	assert.Equal(t, "eventname\nConsoleLogin\n", string(csv))
+
+	// the succeeded export has download links
+	s3Mock.On("ListObjectsV2Pages", mock.Anything, mock.Anything).Return(&s3.ListObjectsV2Output{
+		Contents: []*s3.Object{{Key: uploadInput.Key, Size: aws.Int64(42)}},
+	}, nil).Once()
+	result, err := API{}.GetQueryExport(&models.GetQueryExportInput{
+		UserID:   aws.String(testUserID),
+		ExportID: aws.String(testExportID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.QuerySucceeded, *result.Status)
+	assert.Equal(t, int64(1), *result.RowCount)
+	require.Len(t, result.Files, 1)
+	assert.Contains(t, *result.Files[0].URL, "X-Amz-Expires=3600")
+	assert.Equal(t, int64(42), *result.Files[0].SizeBytes)
+	assert.Equal(t, testStartTime.Add(time.Hour), *result.LinkExpiration)
+
+	// a repeated invocation does not export again
+	require.NoError(t, API{}.RunQueryExport(&models.RunQueryExportInput{ExportID: aws.String(testExportID)}))
+	athenaMock.AssertExpectations(t)
+	tableMock.AssertExpectations(t)
+	s3Mock.AssertExpectations(t)
+	uploaderMock.AssertExpectations(t)
+}
+
+func TestRunQueryExportFailed(t *testing.T) {
+	tableMock, athenaMock, _, uploaderMock := setupExportMocks()
+	export := &table.ExportItem{
+		ExportID:  testExportID,
+		UserID:    testUserID,
+		QueryID:   testQueryID,
+		Format:    models.ExportJSONL,
+		CreatedAt: testStartTime,
+		Prefix:    "query_api/exports/" + testExportID + "/",
+		Status:    models.QueryRunning,
+	}
+	tableMock.On("GetExport", testExportID).Return(export, nil)
+	mockExportResults(athenaMock)
+	uploaderMock.On("Upload", mock.Anything).Return(&s3manager.UploadOutput{}, errors.New("access denied")).Once()
+	tableMock.On("PutExport", export).Return(nil).Once()
+
+	require.NoError(t, API{}.RunQueryExport(&models.RunQueryExportInput{ExportID: aws.String(testExportID)}))
+	result, err := API{}.GetQueryExport(&models.GetQueryExportInput{
+		UserID:   aws.String(testUserID),
+		ExportID: aws.String(testExportID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.QueryFailed, *result.Status)
+	assert.Contains(t, *result.Error, "access denied")
+	assert.Empty(t, result.Files)
+	tableMock.AssertExpectations(t)
+}
+
+func TestGetQueryExportTimedOut(t *testing.T) {
+	tableMock, _, _, _ := setupExportMocks()
+	export := &table.ExportItem{
+		ExportID:  testExportID,
+		UserID:    testUserID,
+		QueryID:   testQueryID,
+		Format:    models.ExportCSV,
+		CreatedAt: testStartTime,
+		Status:    models.QueryRunning,
+	}
+	tableMock.On("GetExport", testExportID).Return(export, nil)
+
+	input := &models.GetQueryExportInput{UserID: aws.String(testUserID), ExportID: aws.String(testExportID)}
+	result, err := API{}.GetQueryExport(input)
+	require.NoError(t, err)
+	assert.Equal(t, models.QueryRunning, *result.Status)
+
+	now = func() time.Time { return testStartTime.Add(exportTimeout + time.Minute) }
+	result, err = API{}.GetQueryExport(input)
+	require.NoError(t, err)
+	assert.Equal(t, models.QueryFailed, *result.Status)
+	assert.Equal(t, "the export timed out", *result.Error)
+}
+
+func TestExportQueryResultsParquet(t *testing.T) {
+	tableMock, athenaMock, _, _ := setupExportMocks()
+	tableMock.On("GetQuery", testQueryID).Return(testItem, nil).Once()
+	athenaMock.On("GetQueryExecution", &athena.GetQueryExecutionInput{QueryExecutionId: aws.String(testQueryID)}).
+		Return(succeededExecution(), nil).Once()
+	athenaMock.On("StartQueryExecution", mock.Anything).
+		Return(&athena.StartQueryExecutionOutput{QueryExecutionId: aws.String("unloadId")}, nil).Once()
+	tableMock.On("PutExport", mock.Anything).Return(nil).Once()
+	athenaMock.On("GetQueryExecution", &athena.GetQueryExecutionInput{QueryExecutionId: aws.String("unloadId")}).
+		Return(&athena.GetQueryExecutionOutput{QueryExecution: &athena.QueryExecution{
+			Status: &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateRunning)},
+		}}, nil).Once()
+
+	result, err := API{}.ExportQueryResults(&models.ExportQueryResultsInput{
+		UserID:  aws.String(testUserID),
+		QueryID: aws.String(testQueryID),
+		Format:  aws.String(models.ExportParquet),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.QueryRunning, *result.Status)
+	assert.Empty(t, result.Files)
+
+	startInput := athenaMock.Calls[1].Arguments.Get(0).(*athena.StartQueryExecutionInput)
+	assert.Regexp(t, "^UNLOAD \\(\nSELECT \\* FROM aws_cloudtrail\n\\) TO 's3://athena-results/query_api/exports/[0-9a-f-]{36}/' "+
+		"WITH \\(format = 'PARQUET'\\)$", *startInput.QueryString)
+	assert.Equal(t, "panther_logs", *startInput.QueryExecutionContext.Database)
+	export := tableMock.Calls[1].Arguments.Get(0).(*table.ExportItem)
+	assert.Equal(t, "unloadId", *export.UnloadQueryID)
+	athenaMock.AssertExpectations(t)
+	tableMock.AssertExpectations(t)
+}
+
+func TestExportQueryResultsNotSucceeded(t *testing.T) {
+	tableMock, athenaMock, _, _ := setupExportMocks()
+	tableMock.On("GetQuery", testQueryID).Return(testItem, nil).Once()
+	athenaMock.On("GetQueryExecution", mock.Anything).Return(&athena.GetQueryExecutionOutput{
+		QueryExecution: &athena.QueryExecution{
+			Status: &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateRunning)},
+		},
+	}, nil).Once()
+
+	_, err := API{}.ExportQueryResults(&models.ExportQueryResultsInput{
+		UserID:  aws.String(testUserID),
+		QueryID: aws.String(testQueryID),
+		Format:  aws.String(models.ExportJSONL),
+	})
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+	athenaMock.AssertExpectations(t)
+	tableMock.AssertExpectations(t)
+}
+
+func TestExportQueryResultsParquetNotSelect(t *testing.T) {
+	tableMock, athenaMock, _, _ := setupExportMocks()
+	describe := *testItem
+	describe.SQL = "DESCRIBE aws_cloudtrail"
+	tableMock.On("GetQuery", testQueryID).Return(&describe, nil).Once()
+	athenaMock.On("GetQueryExecution", mock.Anything).Return(succeededExecution(), nil).Once()
+
+	_, err := API{}.ExportQueryResults(&models.ExportQueryResultsInput{
+		UserID:  aws.String(testUserID),
+		QueryID: aws.String(testQueryID),
+		Format:  aws.String(models.ExportParquet),
+	})
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+	athenaMock.AssertExpectations(t) // no UNLOAD
+	tableMock.AssertExpectations(t)
+}
+
+func TestGetQueryExport(t *testing.T) {
+	tableMock, athenaMock, s3Mock, _ := setupExportMocks()
+	export := &table.ExportItem{
+		ExportID:      testExportID,
+		UserID:        testUserID,
+		QueryID:       testQueryID,
+		Format:        models.ExportParquet,
+		CreatedAt:     testStartTime,
+		Prefix:        "query_api/exports/" + testExportID + "/",
+		UnloadQueryID: aws.String("unloadId"),
+	}
+	tableMock.On("GetExport", testExportID).Return(export, nil)
+	athenaMock.On("GetQueryExecution", mock.Anything).Return(succeededExecution(), nil).Once()
+	s3Mock.On("ListObjectsV2Pages", &s3.ListObjectsV2Input{
+		Bucket: aws.String("athena-results"),
+		Prefix: aws.String("query_api/exports/" + testExportID + "/"),
+	}, mock.Anything).Return(&s3.ListObjectsV2Output{
+		Contents: []*s3.Object{
+			{Key: aws.String("query_api/exports/" + testExportID + "/part-1"), Size: aws.Int64(10)},
+			{Key: aws.String("query_api/exports/" + testExportID + "/part-2"), Size: aws.Int64(20)},
+		},
+	}, nil).Once()
+
+	result, err := API{}.GetQueryExport(&models.GetQueryExportInput{
+		UserID:                aws.String(testUserID),
+		ExportID:              aws.String(testExportID),
+		LinkExpirationMinutes: aws.Int64(5),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.QuerySucceeded, *result.Status)
+	require.Len(t, result.Files, 2)
+	assert.Contains(t, *result.Files[1].URL, "/query_api/exports/"+testExportID+"/part-2?")
+	assert.Contains(t, *result.Files[1].URL, "X-Amz-Expires=300")
+	assert.Nil(t, result.RowCount)
+
+	// other users cannot see the export
+	_, err = API{}.GetQueryExport(&models.GetQueryExportInput{
+		UserID:   aws.String("43808de4-fbae-4f90-a9b4-1e4982d65287"),
+		ExportID: aws.String(testExportID),
+	})
+	assert.IsType(t, &genericapi.DoesNotExistError{}, err)
+	athenaMock.AssertExpectations(t)
+	s3Mock.AssertExpectations(t)
+}
+
+// expiringProvider returns credentials expiring ten minutes after the test start time
+type expiringProvider struct {
+	credentials.Expiry
+}
+
+func (p *expiringProvider) Retrieve() (credentials.Value, error) {
+	p.SetExpiration(testStartTime.Add(10*time.Minute), 0)
+	return credentials.Value{AccessKeyID: "id", SecretAccessKey: "secret", SessionToken: "token"}, nil
+}
+
+// The links do not outlive the credentials signing them
+func TestGetQueryExportLinkLifetime(t *testing.T) {
+	tableMock, _, s3Mock, _ := setupExportMocks()
+	export := &table.ExportItem{
+		ExportID:  testExportID,
+		UserID:    testUserID,
+		QueryID:   testQueryID,
+		Format:    models.ExportCSV,
+		CreatedAt: testStartTime,
+		Prefix:    "query_api/exports/" + testExportID + "/",
+		Status:    models.QuerySucceeded,
+	}
+	tableMock.On("GetExport", testExportID).Return(export, nil)
+	s3Mock.On("ListObjectsV2Pages", mock.Anything, mock.Anything).Return(&s3.ListObjectsV2Output{
+		Contents: []*s3.Object{{Key: aws.String("query_api/exports/" + testExportID + "/part-1"), Size: aws.Int64(10)}},
+	}, nil)
+	input := &models.GetQueryExportInput{
+		UserID:                aws.String(testUserID),
+		ExportID:              aws.String(testExportID),
+		LinkExpirationMinutes: aws.Int64(120),
+	}
+
+	// the credentials do not report when they expire
+	result, err := API{}.GetQueryExport(input)
+	require.NoError(t, err)
+	require.Len(t, result.Files, 1)
+	assert.Contains(t, *result.Files[0].URL, "X-Amz-Expires=3600")
+	assert.Equal(t, testStartTime.Add(time.Hour), *result.LinkExpiration)
+
+	s3Mock.signer = s3.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewCredentials(&expiringProvider{}),
+	})))
+	result, err = API{}.GetQueryExport(input)
+	require.NoError(t, err)
+	require.Len(t, result.Files, 1)
+	assert.Contains(t, *result.Files[0].URL, "X-Amz-Expires=600")
+	assert.Equal(t, testStartTime.Add(10*time.Minute), *result.LinkExpiration)
+}
@@ -0,0 +1,95 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+
+	"github.com/panther-labs/panther/api/lambda/query/models"
+	"github.com/panther-labs/panther/internal/log_analysis/query_api/table"
+	"github.com/panther-labs/panther/pkg/awsathena"
+)
+
+const defaultResultsPageSize = 100
+
+// GetQueryResults returns the status of a query and a page of its results once it succeeded.
+func (API) GetQueryResults(input *models.GetQueryResultsInput) (*models.GetQueryResultsOutput, error) {
+	item, err := getUserQuery(*input.UserID, *input.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	return queryResults(item, input.PaginationToken, input.PageSize)
+}
+
+// queryResults returns the status of the query and a page of its results once it succeeded
+func queryResults(item *table.QueryItem, paginationToken *string, pageSize *int64) (*models.GetQueryResultsOutput, error) {
+	executionOutput, err := awsathena.Status(athenaClient, item.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.GetQueryResultsOutput{
+		QueryStatus: *queryStatus(item, executionOutput.QueryExecution),
+	}
+	if *result.Status != models.QuerySucceeded {
+		return result, nil
+	}
+
+	if pageSize == nil {
+		pageSize = aws.Int64(defaultResultsPageSize)
+	}
+	resultsOutput, err := awsathena.Results(athenaClient, item.QueryID, paginationToken, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var columnInfo []*athena.ColumnInfo
+	if resultsOutput.ResultSet.ResultSetMetadata != nil {
+		columnInfo = resultsOutput.ResultSet.ResultSetMetadata.ColumnInfo
+	}
+	for _, column := range columnInfo {
+		result.Columns = append(result.Columns, &models.Column{Name: column.Name, Type: column.Type})
+	}
+	rows := resultsOutput.ResultSet.Rows
+	if paginationToken == nil && len(rows) > 0 && isHeader(rows[0], columnInfo) {
+		rows = rows[1:] // the first page of SELECT results starts with the column names
+	}
+	result.Rows = make([][]*string, len(rows))
+	for i, row := range rows {
+		result.Rows[i] = make([]*string, len(row.Data))
+		for j, datum := range row.Data {
+			result.Rows[i][j] = datum.VarCharValue
+		}
+	}
+	result.PaginationToken = resultsOutput.NextToken
+	return result, nil
+}
+
+// isHeader returns true if the row has the names of the columns
+func isHeader(row *athena.Row, columnInfo []*athena.ColumnInfo) bool {
+	if len(row.Data) != len(columnInfo) {
+		return false
+	}
+	for i, datum := range row.Data {
+		if aws.StringValue(datum.VarCharValue) != aws.StringValue(columnInfo[i].Name) {
+			return false
+		}
+	}
+	return true
+}
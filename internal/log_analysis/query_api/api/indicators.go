@@ -0,0 +1,104 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/panther-labs/panther/api/lambda/query/models"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/registry"
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+const athenaTimeLayout = "2006-01-02 15:04:05.000"
+
+// indicatorColumns maps the indicators to the p_any columns they are searched in
+var indicatorColumns = []struct {
+	name   string
+	values func(indicators *models.Indicators) []string
+}{
+	{"p_any_ip_addresses", func(i *models.Indicators) []string { return i.IPAddresses }},
+	{"p_any_domain_names", func(i *models.Indicators) []string { return i.DomainNames }},
+	{"p_any_sha1_hashes", func(i *models.Indicators) []string { return i.SHA1Hashes }},
+	{"p_any_md5_hashes", func(i *models.Indicators) []string { return i.MD5Hashes }},
+	{"p_any_aws_arns", func(i *models.Indicators) []string { return i.AWSARNs }},
+	{"p_any_aws_account_ids", func(i *models.Indicators) []string { return i.AWSAccountIDs }},
+}
+
+// indicatorSearchSQL returns the UNION of the searches of all tables having a p_any column of the indicators,
+// an empty string is returned if no table has one
+func indicatorSearchSQL(tables []*awsglue.GlueTableMetadata, indicators *models.Indicators, start, end time.Time) string {
+	tables = append([]*awsglue.GlueTableMetadata(nil), tables...)
+	sort.Slice(tables, func(i, j int) bool { return tables[i].LogType() < tables[j].LogType() })
+
+	var selects []string
+	for _, table := range tables {
+		if tableSQL := tableSearchSQL(table, indicators, start, end); tableSQL != "" {
+			selects = append(selects, tableSQL)
+		}
+	}
+	if len(selects) == 0 {
+		return ""
+	}
+	return strings.Join(selects, "\nUNION ALL\n") + "\nORDER BY p_log_type, p_event_time DESC"
+}
+
+// tableSearchSQL returns the search of one table, an empty string is returned if the table has none of the p_any columns
+func tableSearchSQL(table *awsglue.GlueTableMetadata, indicators *models.Indicators, start, end time.Time) string {
+	columns := table.AnyColumns()
+	var matches, predicates []string
+	for _, column := range indicatorColumns {
+		values := column.values(indicators)
+		if len(values) == 0 || !columns[column.name] {
+			continue
+		}
+		array := arrayLiteral(values)
+		matches = append(matches, "filter("+array+", x -> contains("+column.name+", x))")
+		predicates = append(predicates, "arrays_overlap("+column.name+", "+array+")")
+	}
+	if len(predicates) == 0 {
+		return ""
+	}
+
+	matched := matches[0]
+	if len(matches) > 1 { // concat needs two or more arrays
+		matched = "concat(" + strings.Join(matches, ", ") + ")"
+	}
+	return "SELECT p_log_type, p_event_time, p_row_id, json_format(CAST(" + matched + " AS JSON)) AS matches" +
+		" FROM " + table.DatabaseName() + "." + table.TableName() +
+		" WHERE " + table.PartitionPredicate(start, end) +
+		" AND p_event_time >= timestamp '" + start.UTC().Format(athenaTimeLayout) + "'" +
+		" AND p_event_time < timestamp '" + end.UTC().Format(athenaTimeLayout) + "'" +
+		" AND (" + strings.Join(predicates, " OR ") + ")"
+}
+
+// arrayLiteral returns an SQL array of the strings
+func arrayLiteral(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		// values are validated, quotes are escaped anyway
+		quoted[i] = "'" + strings.Replace(value, "'", "''", -1) + "'"
+	}
+	return "ARRAY[" + strings.Join(quoted, ",") + "]"
+}
+
+// searchTables are the tables searched for indicators, replaced in tests
+var searchTables = registry.AvailableTables
@@ -0,0 +1,84 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+	querymodels "github.com/panther-labs/panther/api/lambda/query/models"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers/awslogs"
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+type testAWSEvent struct {
+	Name *string `json:"name"`
+	awslogs.AWSPantherLog
+}
+
+type testEvent struct {
+	Name *string `json:"name"`
+	parsers.PantherLog
+}
+
+var (
+	testAWSTable    = awsglue.NewGlueTableMetadata(models.LogData, "AWS.Test", "test", awsglue.GlueTableHourly, &testAWSEvent{})
+	testTable       = awsglue.NewGlueTableMetadata(models.LogData, "Other.Test", "test", awsglue.GlueTableHourly, &testEvent{})
+	testNoAnyTable  = awsglue.NewGlueTableMetadata(models.LogData, "NoAny.Test", "test", awsglue.GlueTableHourly, struct{}{})
+	testSearchStart = time.Date(2020, 4, 1, 23, 0, 0, 0, time.UTC)
+	testSearchEnd   = time.Date(2020, 4, 2, 1, 0, 0, 0, time.UTC)
+)
+
+func TestIndicatorSearchSQL(t *testing.T) {
+	indicators := &querymodels.Indicators{
+		IPAddresses:   []string{"192.0.2.10", "192.0.2.11"},
+		AWSAccountIDs: []string{"123456789012"},
+	}
+	sql := indicatorSearchSQL([]*awsglue.GlueTableMetadata{testTable, testNoAnyTable, testAWSTable}, indicators,
+		testSearchStart, testSearchEnd)
+	expected := `SELECT p_log_type, p_event_time, p_row_id, json_format(CAST(concat(` +
+		`filter(ARRAY['192.0.2.10','192.0.2.11'], x -> contains(p_any_ip_addresses, x)), ` +
+		`filter(ARRAY['123456789012'], x -> contains(p_any_aws_account_ids, x))) AS JSON)) AS matches ` +
+		`FROM panther_logs.aws_test WHERE ((year=2020 AND month=4 AND day=1 AND hour=23) OR (year=2020 AND month=4 AND day=2 AND hour=0)) ` +
+		`AND p_event_time >= timestamp '2020-04-01 23:00:00.000' AND p_event_time < timestamp '2020-04-02 01:00:00.000' ` +
+		`AND (arrays_overlap(p_any_ip_addresses, ARRAY['192.0.2.10','192.0.2.11']) OR ` +
+		`arrays_overlap(p_any_aws_account_ids, ARRAY['123456789012']))` +
+		"\nUNION ALL\n" +
+		`SELECT p_log_type, p_event_time, p_row_id, json_format(CAST(` +
+		`filter(ARRAY['192.0.2.10','192.0.2.11'], x -> contains(p_any_ip_addresses, x)) AS JSON)) AS matches ` +
+		`FROM panther_logs.other_test WHERE ((year=2020 AND month=4 AND day=1 AND hour=23) OR (year=2020 AND month=4 AND day=2 AND hour=0)) ` +
+		`AND p_event_time >= timestamp '2020-04-01 23:00:00.000' AND p_event_time < timestamp '2020-04-02 01:00:00.000' ` +
+		`AND (arrays_overlap(p_any_ip_addresses, ARRAY['192.0.2.10','192.0.2.11']))` +
+		"\nORDER BY p_log_type, p_event_time DESC"
+	assert.Equal(t, expected, sql)
+}
+
+func TestIndicatorSearchSQLNoTables(t *testing.T) {
+	indicators := &querymodels.Indicators{AWSARNs: []string{"arn:aws:iam::123456789012:role/test"}}
+	assert.Empty(t, indicatorSearchSQL([]*awsglue.GlueTableMetadata{testTable, testNoAnyTable}, indicators,
+		testSearchStart, testSearchEnd))
+}
+
+func TestArrayLiteral(t *testing.T) {
+	assert.Equal(t, `ARRAY['a','it''s']`, arrayLiteral([]string{"a", "it's"}))
+}
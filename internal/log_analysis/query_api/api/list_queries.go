@@ -0,0 +1,70 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/pkg/errors"
+
+	"github.com/panther-labs/panther/api/lambda/query/models"
+)
+
+// ListQueries returns a page of the queries of the user, newest first, with their current status.
+func (API) ListQueries(input *models.ListQueriesInput) (*models.ListQueriesOutput, error) {
+	items, lastEvaluatedKey, err := queriesDB.ListByUser(*input.UserID, input.ExclusiveStartKey, input.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ListQueriesOutput{
+		Queries:          make([]*models.QueryStatus, len(items)),
+		LastEvaluatedKey: lastEvaluatedKey,
+	}
+	if len(items) == 0 {
+		return result, nil
+	}
+
+	// the page size is at most 50, the most executions BatchGetQueryExecution returns
+	ids := make([]*string, len(items))
+	for i, item := range items {
+		ids[i] = aws.String(item.QueryID)
+	}
+	batchOutput, err := athenaClient.BatchGetQueryExecution(&athena.BatchGetQueryExecutionInput{QueryExecutionIds: ids})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get query executions")
+	}
+	executions := make(map[string]*athena.QueryExecution, len(batchOutput.QueryExecutions))
+	for _, execution := range batchOutput.QueryExecutions {
+		executions[aws.StringValue(execution.QueryExecutionId)] = execution
+	}
+	unprocessed := make(map[string]*string, len(batchOutput.UnprocessedQueryExecutionIds))
+	for _, id := range batchOutput.UnprocessedQueryExecutionIds {
+		unprocessed[aws.StringValue(id.QueryExecutionId)] = id.ErrorMessage
+	}
+
+	for i, item := range items {
+		result.Queries[i] = queryStatus(item, executions[item.QueryID])
+		if errorMessage, found := unprocessed[item.QueryID]; found { // Athena does not know the query anymore
+			result.Queries[i].Status = aws.String(models.QueryFailed)
+			result.Queries[i].SQLError = errorMessage
+		}
+	}
+	return result, nil
+}
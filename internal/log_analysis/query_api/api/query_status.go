@@ -0,0 +1,53 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/panther-labs/panther/api/lambda/query/models"
+	"github.com/panther-labs/panther/pkg/awsathena"
+)
+
+// GetQueryStatus returns the status of a query.
+func (API) GetQueryStatus(input *models.GetQueryStatusInput) (*models.GetQueryStatusOutput, error) {
+	item, err := getUserQuery(*input.UserID, *input.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	executionOutput, err := awsathena.Status(athenaClient, item.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	return queryStatus(item, executionOutput.QueryExecution), nil
+}
+
+// CancelQuery stops a running query, the returned status can still be running while Athena stops it.
+func (API) CancelQuery(input *models.CancelQueryInput) (*models.CancelQueryOutput, error) {
+	item, err := getUserQuery(*input.UserID, *input.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = awsathena.StopQuery(athenaClient, item.QueryID); err != nil {
+		return nil, err
+	}
+	executionOutput, err := awsathena.Status(athenaClient, item.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	return queryStatus(item, executionOutput.QueryExecution), nil
+}
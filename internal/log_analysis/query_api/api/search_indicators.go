@@ -0,0 +1,125 @@
+package api
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+
+	"github.com/panther-labs/panther/api/lambda/query/models"
+	"github.com/panther-labs/panther/pkg/awsglue"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+// hitColumns are the columns of the indicator search results
+var hitColumns = []string{"p_log_type", "p_event_time", "p_row_id", "matches"}
+
+// SearchIndicators starts a search for the indicators across all log tables and returns without waiting for it to finish.
+func (API) SearchIndicators(input *models.SearchIndicatorsInput) (*models.SearchIndicatorsOutput, error) {
+	count := 0
+	for _, column := range indicatorColumns {
+		count += len(column.values(input.Indicators))
+	}
+	if count == 0 {
+		return nil, &genericapi.InvalidInputError{Message: "at least one indicator is required"}
+	}
+
+	sql := indicatorSearchSQL(searchTables(), input.Indicators, *input.StartTime, *input.EndTime)
+	if sql == "" {
+		return nil, &genericapi.InvalidInputError{Message: "no log table has fields for the indicators"}
+	}
+	return startQuery(*input.UserID, awsglue.LogProcessingDatabaseName, sql)
+}
+
+// GetIndicatorSearchResults returns the status of an indicator search and a page of its hits once it succeeded.
+func (API) GetIndicatorSearchResults(
+	input *models.GetIndicatorSearchResultsInput) (*models.GetIndicatorSearchResultsOutput, error) {
+
+	item, err := getUserQuery(*input.UserID, *input.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	results, err := queryResults(item, input.PaginationToken, input.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	output := &models.GetIndicatorSearchResultsOutput{
+		QueryStatus:     results.QueryStatus,
+		PaginationToken: results.PaginationToken,
+	}
+	if *output.Status != models.QuerySucceeded {
+		return output, nil
+	}
+	if !hasColumns(results.Columns, hitColumns) {
+		return nil, &genericapi.InvalidInputError{Message: "query " + item.QueryID + " is not an indicator search"}
+	}
+
+	output.LogTypes = []*models.LogTypeHits{}
+	var logTypeHits *models.LogTypeHits
+	for _, row := range results.Rows {
+		logType, hit, err := parseHit(row)
+		if err != nil {
+			return nil, err
+		}
+		if logTypeHits == nil || *logTypeHits.LogType != logType { // rows are ordered by log type
+			logTypeHits = &models.LogTypeHits{LogType: aws.String(logType)}
+			output.LogTypes = append(output.LogTypes, logTypeHits)
+		}
+		logTypeHits.Hits = append(logTypeHits.Hits, hit)
+	}
+	return output, nil
+}
+
+// hasColumns returns true if the results have the named columns, in order
+func hasColumns(columns []*models.Column, names []string) bool {
+	if len(columns) != len(names) {
+		return false
+	}
+	for i, column := range columns {
+		if aws.StringValue(column.Name) != names[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHit returns the log type and the hit of a row of the indicator search results
+func parseHit(row []*string) (string, *models.IndicatorHit, error) {
+	if len(row) != len(hitColumns) || row[0] == nil || row[1] == nil || row[2] == nil {
+		return "", nil, errors.New("invalid indicator search row")
+	}
+	eventTime, err := time.Parse(athenaTimeLayout, *row[1])
+	if err != nil {
+		return "", nil, errors.Wrap(err, "invalid p_event_time")
+	}
+	hit := &models.IndicatorHit{
+		EventTime: aws.Time(eventTime),
+		RowID:     row[2],
+		Matches:   []string{},
+	}
+	if row[3] != nil {
+		if err = jsoniter.UnmarshalFromString(*row[3], &hit.Matches); err != nil {
+			return "", nil, errors.Wrap(err, "invalid matches")
+		}
+	}
+	return *row[0], hit, nil
+}
@@ -0,0 +1,65 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/pkg/errors"
+)
+
+// PutExport stores an export, the items do not expire because they are the audit log of exports
+func (table *QueriesTable) PutExport(item *ExportItem) error {
+	ddbItem, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return errors.Wrap(err, "MarshalMap() failed for: "+item.ExportID)
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      ddbItem,
+		TableName: aws.String(table.ExportsTableName),
+	}
+	if _, err = table.Client.PutItem(input); err != nil {
+		return errors.Wrap(err, "PutItem() failed for: "+item.ExportID)
+	}
+	return nil
+}
+
+// GetExport retrieves an ExportItem from DDB, nil if it does not exist
+func (table *QueriesTable) GetExport(exportID string) (*ExportItem, error) {
+	input := &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			QueryIDKey: {S: aws.String(exportID)},
+		},
+		TableName: aws.String(table.ExportsTableName),
+	}
+	ddbResult, err := table.Client.GetItem(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetItem() failed for: "+exportID)
+	}
+	if len(ddbResult.Item) == 0 {
+		return nil, nil
+	}
+
+	item := &ExportItem{}
+	if err = dynamodbattribute.UnmarshalMap(ddbResult.Item, item); err != nil {
+		return nil, errors.Wrap(err, "UnmarshalMap() failed for: "+exportID)
+	}
+	return item, nil
+}
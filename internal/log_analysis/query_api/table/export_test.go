@@ -0,0 +1,65 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutGetExport(t *testing.T) {
+	table, mockDdbClient := newTestTable()
+	item := &ExportItem{
+		ExportID:     "0a4e5b6c-1d2e-4f3a-8b4c-5d6e7f8a9b0c",
+		UserID:       testItem.UserID,
+		QueryID:      testItem.QueryID,
+		DatabaseName: testItem.DatabaseName,
+		SQL:          testItem.SQL,
+		Format:       "csv",
+		Compressed:   true,
+		CreatedAt:    time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC),
+		Prefix:       "query_api/exports/0a4e5b6c-1d2e-4f3a-8b4c-5d6e7f8a9b0c/",
+		RowCount:     aws.Int64(10),
+	}
+	mockDdbClient.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil).Once()
+	require.NoError(t, table.PutExport(item))
+	putInput := mockDdbClient.Calls[0].Arguments.Get(0).(*dynamodb.PutItemInput)
+	assert.Equal(t, "exportsTableName", *putInput.TableName)
+	assert.NotContains(t, putInput.Item, "expiresAt")
+
+	mockDdbClient.On("GetItem", &dynamodb.GetItemInput{
+		Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String(item.ExportID)}},
+		TableName: aws.String("exportsTableName"),
+	}).Return(&dynamodb.GetItemOutput{Item: putInput.Item}, nil).Once()
+	result, err := table.GetExport(item.ExportID)
+	require.NoError(t, err)
+	assert.Equal(t, item, result)
+
+	mockDdbClient.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{}, nil).Once()
+	result, err = table.GetExport("missing")
+	require.NoError(t, err)
+	assert.Nil(t, result)
+	mockDdbClient.AssertExpectations(t)
+}
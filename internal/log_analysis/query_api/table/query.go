@@ -0,0 +1,143 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+// lastEvaluatedKey holds the key of the user index where a listing stopped
+type lastEvaluatedKey struct {
+	QueryID   string `json:"id"`
+	UserID    string `json:"userId"`
+	StartTime string `json:"startTime"`
+}
+
+// PutQuery stores a new query, the item expires with the Athena query results
+func (table *QueriesTable) PutQuery(item *QueryItem) error {
+	item.ExpiresAt = item.StartTime.Add(itemRetention).Unix()
+	ddbItem, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return errors.Wrap(err, "MarshalMap() failed for: "+item.QueryID)
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      ddbItem,
+		TableName: aws.String(table.TableName),
+	}
+	if _, err = table.Client.PutItem(input); err != nil {
+		return errors.Wrap(err, "PutItem() failed for: "+item.QueryID)
+	}
+	return nil
+}
+
+// GetQuery retrieves a QueryItem from DDB, nil if it does not exist
+func (table *QueriesTable) GetQuery(queryID string) (*QueryItem, error) {
+	input := &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			QueryIDKey: {S: aws.String(queryID)},
+		},
+		TableName: aws.String(table.TableName),
+	}
+	ddbResult, err := table.Client.GetItem(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetItem() failed for: "+queryID)
+	}
+	if len(ddbResult.Item) == 0 {
+		return nil, nil
+	}
+
+	item := &QueryItem{}
+	if err = dynamodbattribute.UnmarshalMap(ddbResult.Item, item); err != nil {
+		return nil, errors.Wrap(err, "UnmarshalMap() failed for: "+queryID)
+	}
+	return item, nil
+}
+
+// ListByUser returns a page of the queries of the user ordered by startTime descending, last evaluated key, any error
+func (table *QueriesTable) ListByUser(userID string, exclusiveStartKey *string, pageSize *int) (
+	items []*QueryItem, lastKey *string, err error) {
+
+	keyCondition := expression.Key(UserIDKey).Equal(expression.Value(userID))
+	queryExpression, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to build expression")
+	}
+
+	var queryResultsLimit *int64
+	if pageSize != nil {
+		queryResultsLimit = aws.Int64(int64(*pageSize))
+	}
+
+	var queryExclusiveStartKey map[string]*dynamodb.AttributeValue
+	if exclusiveStartKey != nil {
+		startKey := &lastEvaluatedKey{}
+		if err = jsoniter.UnmarshalFromString(*exclusiveStartKey, startKey); err != nil {
+			return nil, nil, &genericapi.InvalidInputError{Message: "invalid exclusiveStartKey: " + err.Error()}
+		}
+		if startKey.UserID != userID { // keys of other users would list their queries
+			return nil, nil, &genericapi.InvalidInputError{Message: "exclusiveStartKey is not for user " + userID}
+		}
+		queryExclusiveStartKey = map[string]*dynamodb.AttributeValue{
+			QueryIDKey:   {S: aws.String(startKey.QueryID)},
+			UserIDKey:    {S: aws.String(startKey.UserID)},
+			StartTimeKey: {S: aws.String(startKey.StartTime)},
+		}
+	}
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:                 aws.String(table.TableName),
+		IndexName:                 aws.String(table.UserIDStartTimeIndexName),
+		ScanIndexForward:          aws.Bool(false),
+		ExpressionAttributeNames:  queryExpression.Names(),
+		ExpressionAttributeValues: queryExpression.Values(),
+		KeyConditionExpression:    queryExpression.KeyCondition(),
+		ExclusiveStartKey:         queryExclusiveStartKey,
+		Limit:                     queryResultsLimit,
+	}
+	queryOutput, err := table.Client.Query(queryInput)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Query() failed for: "+userID)
+	}
+
+	if err = dynamodbattribute.UnmarshalListOfMaps(queryOutput.Items, &items); err != nil {
+		return nil, nil, errors.Wrap(err, "UnmarshalListOfMaps() failed")
+	}
+
+	// more queries are available
+	if len(queryOutput.LastEvaluatedKey) > 0 {
+		key := &lastEvaluatedKey{
+			QueryID:   aws.StringValue(queryOutput.LastEvaluatedKey[QueryIDKey].S),
+			UserID:    aws.StringValue(queryOutput.LastEvaluatedKey[UserIDKey].S),
+			StartTime: aws.StringValue(queryOutput.LastEvaluatedKey[StartTimeKey].S),
+		}
+		serialized, err := jsoniter.MarshalToString(key)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to Marshal LastEvaluatedKey")
+		}
+		lastKey = &serialized
+	}
+	return items, lastKey, nil
+}
@@ -0,0 +1,151 @@
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+var testItem = &QueryItem{
+	QueryID:      "6b8ab9f1-16b6-4cc8-9bd6-35e5a1b4b3e3",
+	UserID:       "f6cfad0a-9bb0-4681-9503-02c54cc979c7",
+	DatabaseName: "panther_logs",
+	SQL:          "SELECT 1",
+	StartTime:    time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC),
+}
+
+func newTestTable() (*QueriesTable, *mockDynamoDB) {
+	mockDdbClient := &mockDynamoDB{}
+	return &QueriesTable{
+		TableName:                "queriesTableName",
+		UserIDStartTimeIndexName: "userIdStartTimeIndexName",
+		ExportsTableName:         "exportsTableName",
+		Client:                   mockDdbClient,
+	}, mockDdbClient
+}
+
+func TestPutQuery(t *testing.T) {
+	table, mockDdbClient := newTestTable()
+	mockDdbClient.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil).Once()
+
+	item := *testItem
+	require.NoError(t, table.PutQuery(&item))
+	putInput := mockDdbClient.Calls[0].Arguments.Get(0).(*dynamodb.PutItemInput)
+	assert.Equal(t, "queriesTableName", *putInput.TableName)
+	assert.Equal(t, "1588334400", *putInput.Item["expiresAt"].N) // 30 days later
+	assert.Equal(t, testItem.QueryID, *putInput.Item["id"].S)
+}
+
+func TestGetQuery(t *testing.T) {
+	table, mockDdbClient := newTestTable()
+	ddbItem, err := dynamodbattribute.MarshalMap(testItem)
+	require.NoError(t, err)
+	mockDdbClient.On("GetItem", &dynamodb.GetItemInput{
+		Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String(testItem.QueryID)}},
+		TableName: aws.String("queriesTableName"),
+	}).Return(&dynamodb.GetItemOutput{Item: ddbItem}, nil).Once()
+
+	result, err := table.GetQuery(testItem.QueryID)
+	require.NoError(t, err)
+	assert.Equal(t, testItem, result)
+}
+
+func TestGetQueryDoesNotExist(t *testing.T) {
+	table, mockDdbClient := newTestTable()
+	mockDdbClient.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{}, nil).Once()
+	result, err := table.GetQuery(testItem.QueryID)
+	require.NoError(t, err)
+	assert.Nil(t, result)
+
+	mockDdbClient.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{}, errors.New("test")).Once()
+	_, err = table.GetQuery(testItem.QueryID)
+	assert.Error(t, err)
+}
+
+func TestListByUser(t *testing.T) {
+	table, mockDdbClient := newTestTable()
+	ddbItem, err := dynamodbattribute.MarshalMap(testItem)
+	require.NoError(t, err)
+	lastKey := map[string]*dynamodb.AttributeValue{
+		"id":        {S: aws.String(testItem.QueryID)},
+		"userId":    {S: aws.String(testItem.UserID)},
+		"startTime": {S: aws.String("2020-04-01T12:00:00Z")},
+	}
+	mockDdbClient.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{
+		Items:            []map[string]*dynamodb.AttributeValue{ddbItem},
+		LastEvaluatedKey: lastKey,
+	}, nil).Once()
+
+	items, lastEvaluatedKey, err := table.ListByUser(testItem.UserID, nil, aws.Int(1))
+	require.NoError(t, err)
+	assert.Equal(t, []*QueryItem{testItem}, items)
+	require.NotNil(t, lastEvaluatedKey)
+	queryInput := mockDdbClient.Calls[0].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, "userIdStartTimeIndexName", *queryInput.IndexName)
+	assert.False(t, *queryInput.ScanIndexForward)
+	assert.Equal(t, int64(1), *queryInput.Limit)
+
+	// the next page starts at the last evaluated key
+	mockDdbClient.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
+	items, nextKey, err := table.ListByUser(testItem.UserID, lastEvaluatedKey, aws.Int(1))
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	assert.Nil(t, nextKey)
+	queryInput = mockDdbClient.Calls[1].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, lastKey, queryInput.ExclusiveStartKey)
+
+	// the key cannot be used by another user
+	_, _, err = table.ListByUser("43808de4-fbae-4f90-a9b4-1e4982d65287", lastEvaluatedKey, aws.Int(1))
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+	_, _, err = table.ListByUser(testItem.UserID, aws.String("notakey"), aws.Int(1))
+	assert.IsType(t, &genericapi.InvalidInputError{}, err)
+	mockDdbClient.AssertExpectations(t)
+}
+
+type mockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	mock.Mock
+}
+
+func (m *mockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.GetItemOutput), args.Error(1)
+}
+
+func (m *mockDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.PutItemOutput), args.Error(1)
+}
+
+func (m *mockDynamoDB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.QueryOutput), args.Error(1)
+}
@@ -0,0 +1,86 @@
+// Package table manages the Dynamo calls of the query history.
+package table
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+const (
+	QueryIDKey   = "id"
+	UserIDKey    = "userId"
+	StartTimeKey = "startTime"
+
+	// Athena keeps the query history for 45 days, the results bucket expires results after 30 days
+	itemRetention = 30 * 24 * time.Hour
+)
+
+// API defines the interface for the queries table which can be used for mocking.
+type API interface {
+	PutQuery(*QueryItem) error
+	GetQuery(string) (*QueryItem, error)
+	ListByUser(string, *string, *int) ([]*QueryItem, *string, error)
+	PutExport(*ExportItem) error
+	GetExport(string) (*ExportItem, error)
+}
+
+// QueriesTable encapsulates a connection to the Dynamo queries and exports tables.
+type QueriesTable struct {
+	TableName                string
+	UserIDStartTimeIndexName string
+	ExportsTableName         string
+	Client                   dynamodbiface.DynamoDBAPI
+}
+
+// The QueriesTable must satisfy the API interface.
+var _ API = (*QueriesTable)(nil)
+
+// QueryItem is a DDB representation of a query started by a user, the query id is the Athena query execution id
+type QueryItem struct {
+	QueryID      string    `json:"id"`
+	UserID       string    `json:"userId"`
+	DatabaseName string    `json:"databaseName"`
+	SQL          string    `json:"sql"`
+	StartTime    time.Time `json:"startTime"`
+	ExpiresAt    int64     `json:"expiresAt"` // DDB TTL
+}
+
+// ExportItem is a DDB representation of an export of query results, it is the audit record of who exported what.
+type ExportItem struct {
+	ExportID     string    `json:"id"`
+	UserID       string    `json:"userId"`
+	QueryID      string    `json:"queryId"`
+	DatabaseName string    `json:"databaseName"`
+	SQL          string    `json:"sql"`
+	Format       string    `json:"format"`
+	Compressed   bool      `json:"compressed"`
+	CreatedAt    time.Time `json:"createdAt"`
+	// Prefix of the exported files in the results bucket
+	Prefix string `json:"prefix"`
+	// Status and Error are set for CSV and JSON lines exports, which are written asynchronously
+	Status string  `json:"status,omitempty"`
+	Error  *string `json:"error,omitempty"`
+	// RowCount is set for CSV and JSON lines exports
+	RowCount *int64 `json:"rowCount,omitempty"`
+	// UnloadQueryID is the Athena query writing a Parquet export
+	UnloadQueryID *string `json:"unloadQueryId,omitempty"`
+}
@@ -0,0 +1,265 @@
+package reconciler
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/glue/glueiface"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+	orgmodels "github.com/panther-labs/panther/api/lambda/organization/models"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/registry"
+	"github.com/panther-labs/panther/pkg/awsglue"
+	"github.com/panther-labs/panther/pkg/genericapi"
+)
+
+const (
+	organizationAPI = "panther-organization-api"
+
+	// lifecycle rules managed by the reconciler, other rules of the bucket are left untouched
+	ruleIDPrefix = "panther-retention-"
+
+	// the processed data bucket is versioned, objects deleted by other functions are removed for good after this delay
+	noncurrentVersionDays = 1
+
+	deleteBatchSize = 1000 // the most keys DeleteObjects takes
+)
+
+// Reconciler applies the log retention settings to the processed data bucket and the Glue catalog
+type Reconciler struct {
+	Bucket       string
+	GlueClient   glueiface.GlueAPI
+	LambdaClient lambdaiface.LambdaAPI
+	S3Client     s3iface.S3API
+}
+
+// Reconcile replaces the retention lifecycle rules of the bucket, deletes the Glue partitions of expired data and
+// all the versions of their objects.
+//
+// The retention of a log type applies to its logs and to its rule matches. Data expires with the time of its partition,
+// not with the age of its objects: compacted and late objects of a partition are written long after its hour.
+func (r *Reconciler) Reconcile(now time.Time) error {
+	retention, err := r.getRetention()
+	if err != nil {
+		return err
+	}
+
+	var rules []*s3.LifecycleRule
+	var tables []*tableRetention
+	parsers := registry.AvailableParsers().Elements()
+	for _, logRetention := range retention {
+		parser, ok := parsers[*logRetention.LogType]
+		if !ok {
+			zap.L().Warn("ignoring retention of unknown log type", zap.String("logType", *logRetention.LogType))
+			continue
+		}
+		logTable := parser.GlueTableMetadata
+		ruleTable := awsglue.NewGlueTableMetadata(
+			models.RuleData, logTable.LogType(), logTable.Description(), awsglue.GlueTableHourly, logTable.EventStruct())
+		for _, table := range []*awsglue.GlueTableMetadata{logTable, ruleTable} {
+			rules = append(rules, lifecycleRule(table, logRetention))
+			tables = append(tables, &tableRetention{table: table, expireDays: *logRetention.ExpireDays})
+		}
+	}
+
+	if err := r.putLifecycleRules(rules); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		cutoff := now.Add(-time.Duration(table.expireDays) * 24 * time.Hour)
+		deleted, err := table.table.DeletePartitionsBefore(r.GlueClient, cutoff)
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			zap.L().Info("deleted expired partitions",
+				zap.String("database", table.table.DatabaseName()),
+				zap.String("table", table.table.TableName()),
+				zap.Int("count", deleted))
+		}
+		deleted, err = r.deleteExpiredObjects(table.table, cutoff)
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			zap.L().Info("deleted expired objects",
+				zap.String("prefix", table.table.Prefix()),
+				zap.Int("count", deleted))
+		}
+	}
+	return nil
+}
+
+// deleteExpiredObjects deletes all the versions of the objects in the partitions of the table ending before cutoff,
+// both those of the log processor and the generations of the compactor. Keys sort in partition time order under each
+// prefix, the listing stops at the first partition which is not expired.
+func (r *Reconciler) deleteExpiredObjects(table *awsglue.GlueTableMetadata, cutoff time.Time) (int, error) {
+	// a partition is expired if its hour ended before the cutoff
+	end := cutoff.Truncate(time.Hour)
+	prefixes := []struct{ prefix, endPrefix string }{
+		{table.Prefix() + awsglue.CompactedPrefix, table.GetCompactedPartitionPrefix(end)},
+		{table.Prefix() + "year=", table.GetPartitionPrefix(end)},
+	}
+
+	deleted := 0
+	for _, p := range prefixes {
+		var versions []*s3.ObjectIdentifier
+		var deleteErr error
+		flush := func() {
+			if deleteErr == nil && len(versions) > 0 {
+				deleteErr = r.deleteVersions(versions)
+				deleted += len(versions)
+				versions = nil
+			}
+		}
+		err := r.S3Client.ListObjectVersionsPages(&s3.ListObjectVersionsInput{
+			Bucket: aws.String(r.Bucket),
+			Prefix: aws.String(p.prefix),
+		}, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+			expired := true
+			for _, version := range page.Versions {
+				if expired = *version.Key < p.endPrefix; expired {
+					versions = append(versions, &s3.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+				}
+			}
+			for _, marker := range page.DeleteMarkers {
+				if *marker.Key < p.endPrefix {
+					versions = append(versions, &s3.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+				}
+			}
+			if len(versions) >= deleteBatchSize {
+				flush()
+			}
+			return expired && deleteErr == nil
+		})
+		if err != nil {
+			return deleted, errors.Wrapf(err, "failed to list versions of s3://%s/%s", r.Bucket, p.prefix)
+		}
+		flush()
+		if deleteErr != nil {
+			return deleted, deleteErr
+		}
+	}
+	return deleted, nil
+}
+
+func (r *Reconciler) deleteVersions(versions []*s3.ObjectIdentifier) error {
+	for start := 0; start < len(versions); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(versions) {
+			end = len(versions)
+		}
+		output, err := r.S3Client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(r.Bucket),
+			Delete: &s3.Delete{Objects: versions[start:end], Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete objects from %s", r.Bucket)
+		}
+		if len(output.Errors) > 0 {
+			return errors.Errorf("failed to delete %d objects from %s, first error for %s: %s", len(output.Errors), r.Bucket,
+				aws.StringValue(output.Errors[0].Key), aws.StringValue(output.Errors[0].Message))
+		}
+	}
+	return nil
+}
+
+type tableRetention struct {
+	table      *awsglue.GlueTableMetadata
+	expireDays int64
+}
+
+func (r *Reconciler) getRetention() ([]*orgmodels.LogRetention, error) {
+	input := &orgmodels.LambdaInput{GetSettings: &orgmodels.GetSettingsInput{}}
+	var settings orgmodels.GeneralSettings
+	if err := genericapi.Invoke(r.LambdaClient, organizationAPI, input, &settings); err != nil {
+		return nil, errors.Wrap(err, "failed to get the log retention settings")
+	}
+	return settings.LogRetention, nil
+}
+
+// lifecycleRule removes the noncurrent versions of the objects of the table, optionally moving them to Glacier first.
+// Objects are not expired by age, the reconciler deletes them with their partitions.
+func lifecycleRule(table *awsglue.GlueTableMetadata, retention *orgmodels.LogRetention) *s3.LifecycleRule {
+	rule := &s3.LifecycleRule{
+		ID:     aws.String(ruleIDPrefix + strings.TrimSuffix(strings.ReplaceAll(table.Prefix(), "/", "-"), "-")),
+		Filter: &s3.LifecycleRuleFilter{Prefix: aws.String(table.Prefix())},
+		Status: aws.String(s3.ExpirationStatusEnabled),
+		NoncurrentVersionExpiration: &s3.NoncurrentVersionExpiration{
+			NoncurrentDays: aws.Int64(noncurrentVersionDays),
+		},
+	}
+	if retention.GlacierDays != nil {
+		rule.Transitions = []*s3.Transition{{
+			Days:         retention.GlacierDays,
+			StorageClass: aws.String(s3.TransitionStorageClassGlacier),
+		}}
+	}
+	return rule
+}
+
+// putLifecycleRules replaces the retention rules of the bucket, keeping the rules which are not managed here
+func (r *Reconciler) putLifecycleRules(rules []*s3.LifecycleRule) error {
+	var existing []*s3.LifecycleRule
+	output, err := r.S3Client.GetBucketLifecycleConfiguration(
+		&s3.GetBucketLifecycleConfigurationInput{Bucket: aws.String(r.Bucket)})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != "NoSuchLifecycleConfiguration" {
+			return errors.Wrapf(err, "failed to get lifecycle configuration of %s", r.Bucket)
+		}
+	} else {
+		existing = output.Rules
+	}
+
+	var result []*s3.LifecycleRule
+	for _, rule := range existing {
+		if !strings.HasPrefix(aws.StringValue(rule.ID), ruleIDPrefix) {
+			result = append(result, rule)
+		}
+	}
+	result = append(result, rules...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return aws.StringValue(result[i].ID) < aws.StringValue(result[j].ID)
+	})
+
+	if len(result) == 0 {
+		if len(existing) == 0 {
+			return nil
+		}
+		_, err = r.S3Client.DeleteBucketLifecycle(&s3.DeleteBucketLifecycleInput{Bucket: aws.String(r.Bucket)})
+		return errors.Wrapf(err, "failed to delete lifecycle configuration of %s", r.Bucket)
+	}
+
+	_, err = r.S3Client.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(r.Bucket),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{Rules: result},
+	})
+	return errors.Wrapf(err, "failed to put lifecycle configuration of %s", r.Bucket)
+}
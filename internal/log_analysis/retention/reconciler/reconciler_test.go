@@ -0,0 +1,227 @@
+package reconciler
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/aws/aws-sdk-go/service/glue/glueiface"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+	orgmodels "github.com/panther-labs/panther/api/lambda/organization/models"
+	"github.com/panther-labs/panther/pkg/awsglue"
+	"github.com/panther-labs/panther/pkg/testutils"
+)
+
+type mockS3 struct {
+	s3iface.S3API
+	mock.Mock
+}
+
+func (m *mockS3) GetBucketLifecycleConfiguration(
+	input *s3.GetBucketLifecycleConfigurationInput) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+
+	args := m.Called(input)
+	return args.Get(0).(*s3.GetBucketLifecycleConfigurationOutput), args.Error(1)
+}
+
+func (m *mockS3) PutBucketLifecycleConfiguration(
+	input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+
+	args := m.Called(input)
+	return args.Get(0).(*s3.PutBucketLifecycleConfigurationOutput), args.Error(1)
+}
+
+func (m *mockS3) DeleteBucketLifecycle(input *s3.DeleteBucketLifecycleInput) (*s3.DeleteBucketLifecycleOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*s3.DeleteBucketLifecycleOutput), args.Error(1)
+}
+
+func (m *mockS3) ListObjectVersionsPages(input *s3.ListObjectVersionsInput,
+	f func(*s3.ListObjectVersionsOutput, bool) bool) error {
+
+	args := m.Called(input, f)
+	f(args.Get(0).(*s3.ListObjectVersionsOutput), true)
+	return args.Error(1)
+}
+
+func (m *mockS3) DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*s3.DeleteObjectsOutput), args.Error(1)
+}
+
+type mockGlue struct {
+	glueiface.GlueAPI
+	mock.Mock
+}
+
+func (m *mockGlue) GetTable(input *glue.GetTableInput) (*glue.GetTableOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*glue.GetTableOutput), args.Error(1)
+}
+
+func mockSettings(settings *orgmodels.GeneralSettings) *testutils.LambdaMock {
+	payload, _ := jsoniter.Marshal(settings)
+	mockLambda := &testutils.LambdaMock{}
+	mockLambda.On("Invoke", mock.Anything).Return(&lambda.InvokeOutput{Payload: payload}, nil).Once()
+	return mockLambda
+}
+
+func TestReconcile(t *testing.T) {
+	mockLambda := mockSettings(&orgmodels.GeneralSettings{
+		LogRetention: []*orgmodels.LogRetention{
+			{LogType: aws.String("AWS.VPCFlow"), ExpireDays: aws.Int64(90), GlacierDays: aws.Int64(30)},
+			{LogType: aws.String("Unknown.Type"), ExpireDays: aws.Int64(30)},
+		},
+	})
+	otherRule := &s3.LifecycleRule{ID: aws.String("cleanup"), Status: aws.String("Enabled")}
+	mockS3Client := &mockS3{}
+	mockS3Client.On("GetBucketLifecycleConfiguration", mock.Anything).Return(
+		&s3.GetBucketLifecycleConfigurationOutput{Rules: []*s3.LifecycleRule{
+			otherRule,
+			{ID: aws.String("panther-retention-logs-aws_cloudtrail")}, // no longer configured
+		}}, nil).Once()
+	mockS3Client.On("PutBucketLifecycleConfiguration", mock.Anything).Return(
+		&s3.PutBucketLifecycleConfigurationOutput{}, nil).Once()
+	mockS3Client.On("ListObjectVersionsPages", mock.Anything, mock.Anything).Return(
+		&s3.ListObjectVersionsOutput{}, nil).Times(4) // compacted and log prefixes of both tables
+	mockGlueClient := &mockGlue{}
+	projectedTable := &glue.GetTableOutput{Table: &glue.TableData{
+		Parameters: map[string]*string{awsglue.ProjectionEnabledParameter: aws.String("true")},
+	}}
+	mockGlueClient.On("GetTable", mock.Anything).Return(projectedTable, nil).Twice()
+
+	reconciler := &Reconciler{
+		Bucket:       "bucket",
+		GlueClient:   mockGlueClient,
+		LambdaClient: mockLambda,
+		S3Client:     mockS3Client,
+	}
+	require.NoError(t, reconciler.Reconcile(time.Now()))
+	mockLambda.AssertExpectations(t)
+	mockS3Client.AssertExpectations(t)
+	mockGlueClient.AssertExpectations(t)
+
+	input := mockS3Client.Calls[1].Arguments.Get(0).(*s3.PutBucketLifecycleConfigurationInput)
+	assert.Equal(t, "bucket", *input.Bucket)
+	rules := input.LifecycleConfiguration.Rules
+	require.Len(t, rules, 3)
+	assert.Equal(t, otherRule, rules[0])
+	assert.Equal(t, "panther-retention-logs-aws_vpcflow", *rules[1].ID)
+	assert.Equal(t, "logs/aws_vpcflow/", *rules[1].Filter.Prefix)
+	assert.Nil(t, rules[1].Expiration) // expired by partition time
+	assert.Equal(t, int64(1), *rules[1].NoncurrentVersionExpiration.NoncurrentDays)
+	assert.Equal(t, int64(30), *rules[1].Transitions[0].Days)
+	assert.Equal(t, "panther-retention-rules-aws_vpcflow", *rules[2].ID)
+	assert.Equal(t, "rules/aws_vpcflow/", *rules[2].Filter.Prefix)
+
+	tableInput := mockGlueClient.Calls[0].Arguments.Get(0).(*glue.GetTableInput)
+	assert.Equal(t, "panther_logs", *tableInput.DatabaseName)
+	assert.Equal(t, "aws_vpcflow", *tableInput.Name)
+}
+
+func TestReconcileNoRetention(t *testing.T) {
+	mockS3Client := &mockS3{}
+	mockS3Client.On("GetBucketLifecycleConfiguration", mock.Anything).Return(
+		&s3.GetBucketLifecycleConfigurationOutput{Rules: []*s3.LifecycleRule{
+			{ID: aws.String("panther-retention-logs-aws_cloudtrail")},
+		}}, nil).Once()
+	mockS3Client.On("DeleteBucketLifecycle", mock.Anything).Return(&s3.DeleteBucketLifecycleOutput{}, nil).Once()
+
+	reconciler := &Reconciler{
+		Bucket:       "bucket",
+		LambdaClient: mockSettings(&orgmodels.GeneralSettings{}),
+		S3Client:     mockS3Client,
+	}
+	require.NoError(t, reconciler.Reconcile(time.Now()))
+	mockS3Client.AssertExpectations(t)
+}
+
+func TestReconcileNoLifecycleConfiguration(t *testing.T) {
+	mockS3Client := &mockS3{}
+	mockS3Client.On("GetBucketLifecycleConfiguration", mock.Anything).Return(
+		(*s3.GetBucketLifecycleConfigurationOutput)(nil), awserr.New("NoSuchLifecycleConfiguration", "none", nil)).Once()
+
+	// nothing to put or delete
+	reconciler := &Reconciler{
+		Bucket:       "bucket",
+		LambdaClient: mockSettings(&orgmodels.GeneralSettings{}),
+		S3Client:     mockS3Client,
+	}
+	require.NoError(t, reconciler.Reconcile(time.Now()))
+	mockS3Client.AssertExpectations(t)
+}
+
+func TestDeleteExpiredObjects(t *testing.T) {
+	table := awsglue.NewGlueTableMetadata(models.LogData, "Test.Log", "test", awsglue.GlueTableHourly, struct{}{})
+	cutoff := time.Date(2020, 2, 1, 12, 30, 0, 0, time.UTC)
+	compactedPage := &s3.ListObjectVersionsOutput{
+		Versions: []*s3.ObjectVersion{
+			{Key: aws.String("logs/test_log/compacted/year=2020/month=02/day=01/hour=10/gen/0.json.gz"), VersionId: aws.String("1")},
+			{Key: aws.String("logs/test_log/compacted/year=2020/month=02/day=01/hour=11/gen/0.json.gz"), VersionId: aws.String("2")},
+			{Key: aws.String("logs/test_log/compacted/year=2020/month=02/day=01/hour=12/gen/0.json.gz"), VersionId: aws.String("3")},
+		},
+		DeleteMarkers: []*s3.DeleteMarkerEntry{
+			{Key: aws.String("logs/test_log/compacted/year=2020/month=02/day=01/hour=11/old/0.json.gz"), VersionId: aws.String("4")},
+			{Key: aws.String("logs/test_log/compacted/year=2020/month=02/day=01/hour=12/old/0.json.gz"), VersionId: aws.String("5")},
+		},
+	}
+	logPage := &s3.ListObjectVersionsOutput{
+		Versions: []*s3.ObjectVersion{
+			{Key: aws.String("logs/test_log/year=2020/month=02/day=01/hour=12/late.json.gz"), VersionId: aws.String("6")},
+		},
+	}
+	mockS3Client := &mockS3{}
+	mockS3Client.On("ListObjectVersionsPages", &s3.ListObjectVersionsInput{
+		Bucket: aws.String("bucket"),
+		Prefix: aws.String("logs/test_log/compacted/"),
+	}, mock.Anything).Return(compactedPage, nil).Once()
+	mockS3Client.On("ListObjectVersionsPages", &s3.ListObjectVersionsInput{
+		Bucket: aws.String("bucket"),
+		Prefix: aws.String("logs/test_log/year="),
+	}, mock.Anything).Return(logPage, nil).Once()
+	mockS3Client.On("DeleteObjects", &s3.DeleteObjectsInput{
+		Bucket: aws.String("bucket"),
+		Delete: &s3.Delete{
+			Objects: []*s3.ObjectIdentifier{
+				{Key: compactedPage.Versions[0].Key, VersionId: aws.String("1")},
+				{Key: compactedPage.Versions[1].Key, VersionId: aws.String("2")},
+				{Key: compactedPage.DeleteMarkers[0].Key, VersionId: aws.String("4")},
+			},
+			Quiet: aws.Bool(true),
+		},
+	}).Return(&s3.DeleteObjectsOutput{}, nil).Once()
+
+	reconciler := &Reconciler{Bucket: "bucket", S3Client: mockS3Client}
+	deleted, err := reconciler.deleteExpiredObjects(table, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+	mockS3Client.AssertExpectations(t)
+}
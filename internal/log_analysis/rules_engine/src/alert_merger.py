@@ -16,9 +16,9 @@
 
 import hashlib
 import os
-from dataclasses import dataclass
+from dataclasses import dataclass, field
 from datetime import datetime
-from typing import Optional
+from typing import Dict, List, Optional
 
 import boto3
 
@@ -38,6 +38,16 @@ _ALERT_COUNT_ATTR_NAME = 'alertCount'
 _ALERT_EVENT_COUNT = 'eventCount'
 _ALERT_LOG_TYPES = 'logTypes'
 _ALERT_TITLE = 'title'
+_ALERT_EVENT_SAMPLES = 'eventSamples'
+
+# The entities of the matched events used to correlate alerts into incidents, by event field
+ENTITY_ATTR_NAMES = {
+    'p_any_ip_addresses': 'ipAddresses',
+    'p_any_aws_account_ids': 'awsAccountIds',
+    'p_any_aws_instance_ids': 'awsInstanceIds',
+}
+# Maximum number of entities of each type stored for an alert, the alert item must stay within the DDB item size limit
+_MAX_STORED_ENTITIES = 500
 
 
 # pylint: disable=too-many-instance-attributes
@@ -52,6 +62,10 @@ class MatchingGroupInfo:
     num_matches: int
     title: Optional[str]
     processing_time: datetime
+    # The entities of the matched events by attribute name
+    entities: Dict[str, List[str]] = field(default_factory=dict)
+    # The first matched events as JSON, delivered with the notification of a new alert
+    event_samples: List[str] = field(default_factory=list)
 
 
 def _generate_dedup_key(rule_id: str, dedup: str) -> str:
@@ -138,6 +152,24 @@ def _update_get_conditional(group_info: MatchingGroupInfo) -> AlertInfo:
     if group_info.title:
         expression_attribute_values[':11'] = {'S': group_info.title}
 
+    # The entities and event samples of a new alert replace the ones of the previous alert
+    removed_attributes = []
+    for index, attr_name in enumerate(ENTITY_ATTR_NAMES.values(), start=12):
+        expresion_attribute_names['#{}'.format(index)] = attr_name
+        if group_info.entities.get(attr_name):
+            update_expression += ', #{0}=:{0}'.format(index)
+            expression_attribute_values[':{}'.format(index)] = {'SS': group_info.entities[attr_name]}
+        else:
+            removed_attributes.append('#{}'.format(index))
+    expresion_attribute_names['#15'] = _ALERT_EVENT_SAMPLES
+    if group_info.event_samples:
+        update_expression += ', #15=:15'
+        expression_attribute_values[':15'] = {'L': [{'S': sample} for sample in group_info.event_samples]}
+    else:
+        removed_attributes.append('#15')
+    if removed_attributes:
+        update_expression += '\nREMOVE ' + ', '.join(removed_attributes)
+
     response = _DDB_CLIENT.update_item(
         TableName=_DDB_TABLE_NAME,
         Key={_PARTITION_KEY_NAME: {
@@ -155,37 +187,57 @@ def _update_get_conditional(group_info: MatchingGroupInfo) -> AlertInfo:
     return AlertInfo(alert_id=alert_id, alert_creation_time=group_info.processing_time, alert_update_time=group_info.processing_time)
 
 
-def _update_get(group_info: MatchingGroupInfo) -> AlertInfo:
+def _update_get(group_info: MatchingGroupInfo, add_entities: bool = True) -> AlertInfo:
     """Updates the following attributes in DDB:
     1. Alert event account - it adds the new events to existing
     2. Alert Update Time - it sets it to given time
+    3. The entities of the alert - they are added to the existing ones until one of the sets is full
     """
 
-    response = _DDB_CLIENT.update_item(
-        TableName=_DDB_TABLE_NAME,
-        Key={_PARTITION_KEY_NAME: {
-            'S': _generate_dedup_key(group_info.rule_id, group_info.dedup)
-        }},
-        # Setting proper value to alertUpdateTime. Increase event count
-        UpdateExpression='SET #1=:1\nADD #2 :2, #3 :3',
-        ExpressionAttributeNames={
-            '#1': _ALERT_UPDATE_TIME_ATTR_NAME,
-            '#2': _ALERT_EVENT_COUNT,
-            '#3': _ALERT_LOG_TYPES
+    update_expression = 'SET #1=:1\nADD #2 :2, #3 :3'
+    expression_attribute_names = {'#1': _ALERT_UPDATE_TIME_ATTR_NAME, '#2': _ALERT_EVENT_COUNT, '#3': _ALERT_LOG_TYPES}
+    expression_attribute_values = {
+        ':1': {
+            'N': group_info.processing_time.strftime('%s')
         },
-        ExpressionAttributeValues={
-            ':1': {
-                'N': group_info.processing_time.strftime('%s')
-            },
-            ':2': {
-                'N': '{}'.format(group_info.num_matches)
-            },
-            ':3': {
-                'SS': [group_info.log_type]
-            },
+        ':2': {
+            'N': '{}'.format(group_info.num_matches)
         },
-        ReturnValues='ALL_NEW'
-    )
+        ':3': {
+            'SS': [group_info.log_type]
+        },
+    }
+
+    # Add the new entities to the ones of the alert, as long as none of the sets is full
+    conditions = []
+    for index, attr_name in enumerate(ENTITY_ATTR_NAMES.values(), start=4):
+        if add_entities and group_info.entities.get(attr_name):
+            update_expression += ', #{0} :{0}'.format(index)
+            expression_attribute_names['#{}'.format(index)] = attr_name
+            expression_attribute_values[':{}'.format(index)] = {'SS': group_info.entities[attr_name]}
+            conditions.append('(attribute_not_exists(#{0}) OR size(#{0}) < :max)'.format(index))
+
+    update_args = {}
+    if conditions:
+        update_args['ConditionExpression'] = ' AND '.join(conditions)
+        expression_attribute_values[':max'] = {'N': '{}'.format(_MAX_STORED_ENTITIES)}
+
+    try:
+        response = _DDB_CLIENT.update_item(
+            TableName=_DDB_TABLE_NAME,
+            Key={_PARTITION_KEY_NAME: {
+                'S': _generate_dedup_key(group_info.rule_id, group_info.dedup)
+            }},
+            # Setting proper value to alertUpdateTime. Increase event count
+            UpdateExpression=update_expression,
+            ExpressionAttributeNames=expression_attribute_names,
+            ExpressionAttributeValues=expression_attribute_values,
+            ReturnValues='ALL_NEW',
+            **update_args
+        )
+    except _DDB_CLIENT.exceptions.ConditionalCheckFailedException:
+        # An entity set is full, the alert is updated without the new entities
+        return _update_get(group_info, add_entities=False)
     alert_count = response['Attributes'][_ALERT_COUNT_ATTR_NAME]['N']
     alert_creation_time = response['Attributes'][_ALERT_CREATION_TIME_ATTR_NAME]['N']
     return AlertInfo(
@@ -23,12 +23,12 @@ import uuid
 from dataclasses import asdict, dataclass
 from datetime import datetime
 from io import BytesIO
-from typing import Dict, List, Optional
+from typing import Any, Dict, List, Optional, Set
 
 import boto3
 
 from . import AlertInfo, EventMatch, OutputGroupingKey
-from .alert_merger import MatchingGroupInfo, update_get_alert_info
+from .alert_merger import ENTITY_ATTR_NAMES, MatchingGroupInfo, update_get_alert_info
 from .logging import get_logger
 
 _KEY_FORMAT = 'rules/{}/year={:d}/month={:02d}/day={:02d}/hour={:02d}/rule_id={}/{}-{}.json.gz'
@@ -36,12 +36,25 @@ _KEY_FORMAT = 'rules/{}/year={:d}/month={:02d}/day={:02d}/hour={:02d}/rule_id={}
 _MAX_BYTES_IN_MEMORY = 100000000
 _S3_KEY_DATE_FORMAT = '%Y%m%dT%H%M%SZ'
 _DATE_FORMAT = '%Y-%m-%d %H:%M:%S.%f000'
+# Maximum number of entities of each type stored for a batch of matched events
+_MAX_ENTITIES = 50
+# Number of matched events of a new alert delivered with its notifications
+_MAX_EVENT_SAMPLES = int(os.environ.get('ALERT_EVENT_SAMPLES', '3'))
+# Maximum length of the JSON of an event sample, longer samples are truncated
+_MAX_SAMPLE_LENGTH = 1000
+# The top-level event fields included in the samples, all the fields if not set
+_SAMPLE_FIELDS = [name for name in os.environ.get('ALERT_SAMPLE_FIELDS', '').split(',') if name]
+# The event fields, at any depth, whose values are redacted in the samples
+_REDACTED_FIELDS = {name for name in os.environ.get('ALERT_SAMPLE_REDACTED_FIELDS', '').split(',') if name}
+_REDACTED_VALUE = 'REDACTED'
 _S3_BUCKET = os.environ['S3_BUCKET']
 _SNS_TOPIC_ARN = os.environ['NOTIFICATIONS_TOPIC']
+_ALERT_EVENTS_TABLE = os.environ['ALERT_EVENTS_TABLE']
 
 # AWS Clients
 _S3_CLIENT = boto3.client('s3')
 _SNS_CLIENT = boto3.client('sns')
+_DDB_CLIENT = boto3.client('dynamodb')
 
 _LOGGER = get_logger()
 
@@ -125,7 +138,9 @@ def _write_to_s3(time: datetime, key: OutputGroupingKey, events: List[EventMatch
         dedup_period_mins=events[0].dedup_period_mins,
         num_matches=len(events),
         title=events[0].title,
-        processing_time=time
+        processing_time=time,
+        entities=_get_entities(events),
+        event_samples=_get_event_samples(events)
     )
     alert_info = update_get_alert_info(group_info)
     data_stream = BytesIO()
@@ -145,6 +160,9 @@ def _write_to_s3(time: datetime, key: OutputGroupingKey, events: List[EventMatch
     # Write data to S3
     _S3_CLIENT.put_object(Bucket=_S3_BUCKET, ContentType='gzip', Body=data_stream, Key=object_key)
 
+    # Index the object by alert, the alerts API reads the events of an alert from its objects directly
+    _index_alert_events(alert_info.alert_id, object_key, key.log_type, len(events))
+
     # Send notification to SNS topic
     notification = _s3_put_object_notification(_S3_BUCKET, object_key, byte_size)
 
@@ -165,6 +183,67 @@ def _write_to_s3(time: datetime, key: OutputGroupingKey, events: List[EventMatch
     )
 
 
+def _index_alert_events(alert_id: str, object_key: str, log_type: str, num_events: int) -> None:
+    """Stores an object holding matched events of an alert in the alert events index
+
+    Objects are written for a single alert, every row of the object is an event of the alert: no row offsets are stored.
+    """
+    _DDB_CLIENT.put_item(
+        TableName=_ALERT_EVENTS_TABLE,
+        Item={
+            'alertId': {
+                'S': alert_id
+            },
+            'objectKey': {
+                'S': object_key
+            },
+            'logType': {
+                'S': log_type
+            },
+            'eventCount': {
+                'N': str(num_events)
+            },
+        }
+    )
+
+
+def _get_entities(events: List[EventMatch]) -> Dict[str, List[str]]:
+    """Collects the entities of the matched events, used to correlate alerts of different rules"""
+    entities: Dict[str, Set[str]] = collections.defaultdict(set)
+    for match in events:
+        for event_field, attr_name in ENTITY_ATTR_NAMES.items():
+            values = match.event.get(event_field)
+            if not isinstance(values, list):
+                continue
+            for value in values:
+                if isinstance(value, str) and value and len(entities[attr_name]) < _MAX_ENTITIES:
+                    entities[attr_name].add(value)
+    return {attr_name: sorted(values) for attr_name, values in entities.items() if values}
+
+
+def _get_event_samples(events: List[EventMatch]) -> List[str]:
+    """Returns the first events as JSON, with the configured fields and redactions, to be sent with the alert"""
+    samples = []
+    for match in events[:_MAX_EVENT_SAMPLES]:
+        event = match.event
+        if _SAMPLE_FIELDS:
+            event = {name: event[name] for name in _SAMPLE_FIELDS if name in event}
+        sample = json.dumps(_redact(event), default=str)
+        if len(sample) > _MAX_SAMPLE_LENGTH:
+            sample = sample[:_MAX_SAMPLE_LENGTH] + '...'
+        samples.append(sample)
+    return samples
+
+
+def _redact(value: Any) -> Any:
+    """Replaces the values of the redacted fields"""
+    if isinstance(value, dict):
+        return {key: _REDACTED_VALUE if key in _REDACTED_FIELDS else _redact(item) for key, item in value.items()}
+    if isinstance(value, list):
+        return [_redact(item) for item in value]
+    return value
+
+
 def _s3_put_object_notification(bucket: str, key: str, byte_size: int) -> Dict[str, list]:
     """The notification that will be sent to the SNS topic when we create a new object in S3.
 
@@ -28,6 +28,7 @@ _RESPONSE_MOCK.json.return_value = {'policies': []}
 
 _ENV_VARIABLES_MOCK = {
     'ALERTS_DEDUP_TABLE': 'table_name',
+    'ALERT_EVENTS_TABLE': 'alert_events_table',
     'ANALYSIS_API_FQDN': 'analysis_fqdn',
     'S3_BUCKET': 's3_bucket',
     'NOTIFICATIONS_TOPIC': 'sns_topic',
@@ -27,8 +27,15 @@ import boto3
 from . import mock_to_return, DDB_MOCK, S3_MOCK, SNS_MOCK
 from ..src import EventMatch
 
-with mock.patch.dict(os.environ, {'ALERTS_DEDUP_TABLE': 'table_name', 'S3_BUCKET': 's3_bucket', 'NOTIFICATIONS_TOPIC': 'sns_topic'}), \
+_ENV_VARIABLES_MOCK = {
+    'ALERTS_DEDUP_TABLE': 'table_name',
+    'ALERT_EVENTS_TABLE': 'alert_events_table',
+    'S3_BUCKET': 's3_bucket',
+    'NOTIFICATIONS_TOPIC': 'sns_topic'
+}
+with mock.patch.dict(os.environ, _ENV_VARIABLES_MOCK), \
      mock.patch.object(boto3, 'client', side_effect=mock_to_return) as mock_boto:
+    from ..src import output as output_module
     from ..src.output import MatchedEventsBuffer
 
 
@@ -68,7 +75,11 @@ class TestMatchedEventsBuffer(TestCase):
                 '#7': 'alertUpdateTime',
                 '#8': 'eventCount',
                 '#9': 'logTypes',
-                '#10': 'ruleVersion'
+                '#10': 'ruleVersion',
+                '#12': 'ipAddresses',
+                '#13': 'awsAccountIds',
+                '#14': 'awsInstanceIds',
+                '#15': 'eventSamples'
             },
             ExpressionAttributeValues={
                 ':1': {
@@ -97,6 +108,11 @@ class TestMatchedEventsBuffer(TestCase):
                 },
                 ':10': {
                     'S': 'rule_version'
+                },
+                ':15': {
+                    'L': [{
+                        'S': '{"data_key": "data_value"}'
+                    }]
                 }
             },
             Key={
@@ -107,7 +123,7 @@ class TestMatchedEventsBuffer(TestCase):
             },
             ReturnValues='ALL_NEW',
             TableName='table_name',
-            UpdateExpression='ADD #3 :3\nSET #4=:4, #5=:5, #6=:6, #7=:7, #8=:8, #9=:9, #10=:10'
+            UpdateExpression='ADD #3 :3\nSET #4=:4, #5=:5, #6=:6, #7=:7, #8=:8, #9=:9, #10=:10, #15=:15\nREMOVE #12, #13, #14'
         )
 
         S3_MOCK.put_object.assert_called_once_with(Body=mock.ANY, Bucket='s3_bucket', ContentType='gzip', Key=mock.ANY)
@@ -130,6 +146,25 @@ class TestMatchedEventsBuffer(TestCase):
         # Actual event
         self.assertEqual(content['data_key'], 'data_value')
 
+        # The object is indexed by alert
+        DDB_MOCK.put_item.assert_called_once_with(
+            TableName='alert_events_table',
+            Item={
+                'alertId': {
+                    'S': hashlib.md5(b'rule_id:1:dedup').hexdigest()  # nosec
+                },
+                'objectKey': {
+                    'S': key
+                },
+                'logType': {
+                    'S': 'log_type'
+                },
+                'eventCount': {
+                    'N': '1'
+                },
+            }
+        )
+
         SNS_MOCK.publish.assert_called_once_with(
             TopicArn='sns_topic',
             Message=mock.ANY,
@@ -154,6 +189,192 @@ class TestMatchedEventsBuffer(TestCase):
         self.assertEqual(len(buffer.data), 0)
         self.assertEqual(buffer.bytes_in_memory, 0)
 
+    def test_flush_stores_event_entities(self) -> None:
+        buffer = MatchedEventsBuffer()
+        buffer.add_event(
+            EventMatch(
+                rule_id='id',
+                rule_version='version',
+                log_type='log',
+                dedup='dedup',
+                dedup_period_mins=100,
+                event={
+                    'p_any_ip_addresses': ['10.0.0.2', '10.0.0.1'],
+                    'p_any_aws_account_ids': ['123456789012']
+                }
+            )
+        )
+        buffer.add_event(
+            EventMatch(
+                rule_id='id',
+                rule_version='version',
+                log_type='log',
+                dedup='dedup',
+                dedup_period_mins=100,
+                event={'p_any_ip_addresses': ['10.0.0.1', '10.0.0.3']}
+            )
+        )
+
+        DDB_MOCK.update_item.return_value = {'Attributes': {'alertCount': {'N': '1'}}}
+        buffer.flush()
+
+        _, call_args = DDB_MOCK.update_item.call_args
+        self.assertEqual(
+            call_args['UpdateExpression'],
+            'ADD #3 :3\nSET #4=:4, #5=:5, #6=:6, #7=:7, #8=:8, #9=:9, #10=:10, #12=:12, #13=:13, #15=:15\nREMOVE #14'
+        )
+        self.assertEqual(call_args['ExpressionAttributeValues'][':12'], {'SS': ['10.0.0.1', '10.0.0.2', '10.0.0.3']})
+        self.assertEqual(call_args['ExpressionAttributeValues'][':13'], {'SS': ['123456789012']})
+        self.assertNotIn(':14', call_args['ExpressionAttributeValues'])
+
+    def test_flush_stores_event_samples(self) -> None:
+        buffer = MatchedEventsBuffer()
+        for i in range(5):
+            buffer.add_event(
+                EventMatch(
+                    rule_id='id',
+                    rule_version='version',
+                    log_type='log',
+                    dedup='dedup',
+                    dedup_period_mins=100,
+                    event={
+                        'index': i,
+                        'user': {
+                            'name': 'user',
+                            'password': 'secret'
+                        },
+                        'ignored': 'value'
+                    }
+                )
+            )
+
+        DDB_MOCK.update_item.return_value = {'Attributes': {'alertCount': {'N': '1'}}}
+        with mock.patch.object(output_module, '_SAMPLE_FIELDS', ['index', 'user']), \
+             mock.patch.object(output_module, '_REDACTED_FIELDS', {'password'}):
+            buffer.flush()
+
+        # The first events are stored with the selected fields and redactions
+        _, call_args = DDB_MOCK.update_item.call_args
+        self.assertEqual(call_args['ExpressionAttributeNames']['#15'], 'eventSamples')
+        self.assertEqual(
+            call_args['ExpressionAttributeValues'][':15'], {
+                'L':
+                    [
+                        {
+                            'S': '{{"index": {}, "user": {{"name": "user", "password": "REDACTED"}}}}'.format(i)
+                        } for i in range(3)
+                    ]
+            }
+        )
+
+    def test_flush_truncates_event_samples(self) -> None:
+        buffer = MatchedEventsBuffer()
+        buffer.add_event(
+            EventMatch(
+                rule_id='id', rule_version='version', log_type='log', dedup='dedup', dedup_period_mins=100, event={'key': 'a' * 2000}
+            )
+        )
+
+        DDB_MOCK.update_item.return_value = {'Attributes': {'alertCount': {'N': '1'}}}
+        buffer.flush()
+
+        _, call_args = DDB_MOCK.update_item.call_args
+        sample = call_args['ExpressionAttributeValues'][':15']['L'][0]['S']
+        self.assertEqual(len(sample), 1003)
+        self.assertTrue(sample.endswith('...'))
+
+    def test_flush_merges_event_entities(self) -> None:
+        buffer = MatchedEventsBuffer()
+        buffer.add_event(
+            EventMatch(
+                rule_id='id',
+                rule_version='version',
+                log_type='log',
+                dedup='dedup',
+                dedup_period_mins=100,
+                event={'p_any_aws_instance_ids': ['i-0123456789abcdef0']}
+            )
+        )
+
+        # The alert already exists, the entities are added to the ones of the alert
+        class ConditionalCheckFailedException(Exception):
+            pass
+
+        DDB_MOCK.exceptions.ConditionalCheckFailedException = ConditionalCheckFailedException
+        DDB_MOCK.update_item.side_effect = [
+            ConditionalCheckFailedException(),
+            {
+                'Attributes': {
+                    'alertCount': {
+                        'N': '1'
+                    },
+                    'alertCreationTime': {
+                        'N': '1000'
+                    }
+                }
+            },
+        ]
+        try:
+            buffer.flush()
+        finally:
+            DDB_MOCK.update_item.side_effect = None
+            del DDB_MOCK.exceptions.ConditionalCheckFailedException
+
+        self.assertEqual(DDB_MOCK.update_item.call_count, 2)
+
+        _, call_args = DDB_MOCK.update_item.call_args
+        self.assertEqual(call_args['UpdateExpression'], 'SET #1=:1\nADD #2 :2, #3 :3, #6 :6')
+        self.assertEqual(call_args['ConditionExpression'], '(attribute_not_exists(#6) OR size(#6) < :max)')
+        self.assertEqual(call_args['ExpressionAttributeNames']['#6'], 'awsInstanceIds')
+        self.assertEqual(call_args['ExpressionAttributeValues'][':6'], {'SS': ['i-0123456789abcdef0']})
+        self.assertEqual(call_args['ExpressionAttributeValues'][':max'], {'N': '500'})
+
+    def test_flush_skips_entities_once_full(self) -> None:
+        buffer = MatchedEventsBuffer()
+        buffer.add_event(
+            EventMatch(
+                rule_id='id',
+                rule_version='version',
+                log_type='log',
+                dedup='dedup',
+                dedup_period_mins=100,
+                event={'p_any_ip_addresses': ['10.0.0.1']}
+            )
+        )
+
+        # The alert already exists and its entity sets are full
+        class ConditionalCheckFailedException(Exception):
+            pass
+
+        DDB_MOCK.exceptions.ConditionalCheckFailedException = ConditionalCheckFailedException
+        DDB_MOCK.update_item.side_effect = [
+            ConditionalCheckFailedException(),
+            ConditionalCheckFailedException(),
+            {
+                'Attributes': {
+                    'alertCount': {
+                        'N': '1'
+                    },
+                    'alertCreationTime': {
+                        'N': '1000'
+                    }
+                }
+            },
+        ]
+        try:
+            buffer.flush()
+        finally:
+            DDB_MOCK.update_item.side_effect = None
+            del DDB_MOCK.exceptions.ConditionalCheckFailedException
+
+        self.assertEqual(DDB_MOCK.update_item.call_count, 3)
+
+        # The alert is updated without the entities
+        _, call_args = DDB_MOCK.update_item.call_args
+        self.assertEqual(call_args['UpdateExpression'], 'SET #1=:1\nADD #2 :2, #3 :3')
+        self.assertNotIn('ConditionExpression', call_args)
+        self.assertNotIn(':4', call_args['ExpressionAttributeValues'])
+
     def test_add_same_rule_different_log(self) -> None:
         buffer = MatchedEventsBuffer()
         buffer.add_event(
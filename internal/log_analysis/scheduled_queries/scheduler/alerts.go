@@ -0,0 +1,384 @@
+package scheduler
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"crypto/md5" // nolint(gosec)
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	analysisoperations "github.com/panther-labs/panther/api/gateway/analysis/client/operations"
+	"github.com/panther-labs/panther/api/gateway/analysis/models"
+	alertsmodels "github.com/panther-labs/panther/api/lambda/alerts/models"
+	alertModel "github.com/panther-labs/panther/internal/core/alert_delivery/models"
+	"github.com/panther-labs/panther/internal/log_analysis/alert_forwarder/forwarder"
+	alertstable "github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/pkg/awsathena"
+)
+
+const (
+	titleColumn     = "title" // optional result column overriding the alert title
+	maxResultRows   = 1000    // rows beyond this are ignored, a query should not flood the alert queue
+	resultsPageSize = 1000
+
+	// the executions whose rows were counted in an alert, so a retried execution does not count them again
+	executionIDsAttribute = "queryExecutionIds"
+	// the execution which created an alert, its retries send the notification of the alert
+	creationExecutionAttribute = "queryExecutionId"
+)
+
+// alertGroup is the set of result rows which share a deduplication string
+type alertGroup struct {
+	dedup string
+	title string
+	count int64
+}
+
+// raiseAlerts turns every result row of the finished execution into an alert.
+//
+// The state is only saved once every alert is stored and notified, a failed execution is processed again:
+// the rows are counted once per alert and the notifications of the alerts it created are sent again.
+func raiseAlerts(query *models.EnabledPolicy, state *State) error {
+	rows, err := readResults(state.ExecutionID)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	groups, err := groupRows(rows, query.DedupColumns)
+	if err != nil {
+		return err
+	}
+
+	info, err := getQueryInfo(state)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, group := range groups {
+		alertID := generateAlertID(state, int64(query.DedupPeriodMinutes), group.dedup)
+		suppression, err := getActiveSuppression(state, group, now)
+		if err != nil {
+			return err
+		}
+		created, err := storeAlert(alertID, info, state, group, suppression, now)
+		if err != nil {
+			return err
+		}
+		if !created {
+			continue
+		}
+		if suppression != nil {
+			zap.L().Info("alert suppressed", zap.String("queryId", state.QueryID),
+				zap.String("suppressionId", suppression.SuppressionID))
+			continue
+		}
+		if err := sendAlertNotification(alertID, info, state, group, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readResults returns the result rows of the execution as column name => value
+func readResults(executionID string) ([]map[string]string, error) {
+	var rows []map[string]string
+	var columns []string
+	var nextToken *string
+	for {
+		output, err := awsathena.Results(athenaClient, executionID, nextToken, aws.Int64(resultsPageSize))
+		if err != nil {
+			return nil, err
+		}
+		if columns == nil {
+			for _, info := range output.ResultSet.ResultSetMetadata.ColumnInfo {
+				columns = append(columns, aws.StringValue(info.Name))
+			}
+		}
+
+		pageRows := output.ResultSet.Rows
+		if nextToken == nil && len(pageRows) > 0 {
+			pageRows = pageRows[1:] // the first row has the column names
+		}
+		for _, row := range pageRows {
+			if len(rows) == maxResultRows {
+				zap.L().Warn("scheduled query returned too many rows, ignoring the rest",
+					zap.String("executionId", executionID), zap.Int("maxRows", maxResultRows))
+				return rows, nil
+			}
+			rows = append(rows, rowValues(columns, row))
+		}
+
+		if output.NextToken == nil {
+			return rows, nil
+		}
+		nextToken = output.NextToken
+	}
+}
+
+func rowValues(columns []string, row *athena.Row) map[string]string {
+	values := make(map[string]string, len(columns))
+	for i, datum := range row.Data {
+		if i < len(columns) {
+			values[columns[i]] = aws.StringValue(datum.VarCharValue)
+		}
+	}
+	return values
+}
+
+// groupRows deduplicates the rows on the dedup columns (all columns if none are configured), keeping the order
+func groupRows(rows []map[string]string, dedupColumns []string) ([]*alertGroup, error) {
+	var result []*alertGroup
+	groups := make(map[string]*alertGroup)
+	for _, row := range rows {
+		dedup, err := dedupString(row, dedupColumns)
+		if err != nil {
+			return nil, err
+		}
+		group, ok := groups[dedup]
+		if !ok {
+			group = &alertGroup{dedup: dedup, title: row[titleColumn]}
+			groups[dedup] = group
+			result = append(result, group)
+		}
+		group.count++
+	}
+	return result, nil
+}
+
+func dedupString(row map[string]string, dedupColumns []string) (string, error) {
+	if len(dedupColumns) == 0 {
+		dedupColumns = make([]string, 0, len(row))
+		for column := range row {
+			dedupColumns = append(dedupColumns, column)
+		}
+		sort.Strings(dedupColumns)
+	}
+
+	values := make([]string, len(dedupColumns))
+	for i, column := range dedupColumns {
+		value, ok := row[column]
+		if !ok {
+			return "", errors.Errorf("dedup column %s is not in the query results", column)
+		}
+		values[i] = value
+	}
+	return jsoniter.MarshalToString(values)
+}
+
+// generateAlertID groups the rows with the same dedup string into one alert per dedup period
+func generateAlertID(state *State, dedupPeriodMinutes int64, dedup string) string {
+	period := state.StartTime.Unix() / (dedupPeriodMinutes * 60)
+	key := state.QueryID + ":" + strconv.FormatInt(period, 10) + ":" + dedup
+	keyHash := md5.Sum([]byte(key)) // nolint(gosec)
+	return hex.EncodeToString(keyHash[:])
+}
+
+// getActiveSuppression returns a suppression of the query matching the dedup string of the group, if any.
+// Suppressions are checked like those of rules by the alert forwarder.
+func getActiveSuppression(state *State, group *alertGroup, now time.Time) (*alertstable.SuppressionItem, error) {
+	suppressionsTable := &alertstable.AlertsTable{
+		SuppressionsTableName: env.SuppressionsTable,
+		Client:                ddbClient,
+	}
+	suppression, err := suppressionsTable.GetActiveSuppression(state.QueryID, group.dedup, now)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get query suppressions")
+	}
+	return suppression, nil
+}
+
+// storeAlert adds the alert to the alerts table, or adds the events to the existing alert.
+// A new alert is stored as suppressed if suppression is set, it is not notified.
+//
+// It returns true if the alert was created by the execution, in this call or in an earlier attempt.
+func storeAlert(alertID string, info *models.ScheduledQuery, state *State, group *alertGroup,
+	suppression *alertstable.SuppressionItem, now time.Time) (bool, error) {
+
+	alert := &forwarder.Alert{
+		ID:              alertID,
+		TimePartition:   alertstable.TimePartition(alertID),
+		Status:          alertsmodels.StatusOpen,
+		Severity:        string(info.Severity),
+		RuleDisplayName: getDisplayName(info),
+		Title:           getAlertTitle(info, group),
+		AlertDedupEvent: forwarder.AlertDedupEvent{
+			RuleID:              state.QueryID,
+			RuleVersion:         state.VersionID,
+			DeduplicationString: group.dedup,
+			CreationTime:        now,
+			UpdateTime:          now,
+			EventCount:          group.count,
+			LogTypes:            info.LogTypes,
+		},
+	}
+	if suppression != nil {
+		alert.Status = alertsmodels.StatusSuppressed
+		alert.SuppressionID = &suppression.SuppressionID
+	}
+
+	item, err := dynamodbattribute.MarshalMap(alert)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to marshal alert")
+	}
+	item[executionIDsAttribute] = &dynamodb.AttributeValue{SS: aws.StringSlice([]string{state.ExecutionID})}
+	item[creationExecutionAttribute] = &dynamodb.AttributeValue{S: aws.String(state.ExecutionID)}
+	condition, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name("id"))).
+		Build()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to build alert condition")
+	}
+	_, err = ddbClient.PutItem(&dynamodb.PutItemInput{
+		ConditionExpression:      condition.Condition(),
+		ExpressionAttributeNames: condition.Names(),
+		Item:                     item,
+		TableName:                aws.String(env.AlertsTable),
+	})
+	if err == nil {
+		return true, nil
+	}
+	if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, errors.Wrap(err, "failed to store alert")
+	}
+
+	// The alert already exists: the same rows were returned earlier in this dedup period,
+	// or this execution already stored it before failing
+	update, err := expression.NewBuilder().
+		WithCondition(expression.Not(expression.Contains(expression.Name(executionIDsAttribute), state.ExecutionID))).
+		WithUpdate(expression.
+			Add(expression.Name("eventCount"), expression.Value(group.count)).
+			Add(expression.Name(executionIDsAttribute), expression.Value(&dynamodb.AttributeValue{
+				SS: aws.StringSlice([]string{state.ExecutionID}),
+			})).
+			Set(expression.Name("updateTime"), expression.Value(aws.Time(now)))).
+		Build()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to build alert update")
+	}
+	key := map[string]*dynamodb.AttributeValue{
+		"id": {S: aws.String(alertID)},
+	}
+	_, err = ddbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		ConditionExpression:       update.Condition(),
+		ExpressionAttributeNames:  update.Names(),
+		ExpressionAttributeValues: update.Values(),
+		Key:                       key,
+		TableName:                 aws.String(env.AlertsTable),
+		UpdateExpression:          update.Update(),
+	})
+	if err == nil {
+		return false, nil
+	}
+	if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, errors.Wrap(err, "failed to update alert")
+	}
+
+	// The rows of this execution are already counted, it is a retry
+	output, err := ddbClient.GetItem(&dynamodb.GetItemInput{
+		ConsistentRead:           aws.Bool(true),
+		ExpressionAttributeNames: map[string]*string{"#execution": aws.String(creationExecutionAttribute)},
+		Key:                      key,
+		ProjectionExpression:     aws.String("#execution"),
+		TableName:                aws.String(env.AlertsTable),
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get alert")
+	}
+	creationExecution := output.Item[creationExecutionAttribute]
+	return creationExecution != nil && aws.StringValue(creationExecution.S) == state.ExecutionID, nil
+}
+
+func sendAlertNotification(alertID string, info *models.ScheduledQuery, state *State, group *alertGroup, now time.Time) error {
+	alertNotification := &alertModel.Alert{
+		CreatedAt:         aws.Time(now),
+		PolicyDescription: aws.String(string(info.Description)),
+		PolicyID:          aws.String(state.QueryID),
+		PolicyVersionID:   aws.String(state.VersionID),
+		PolicyName:        getDisplayName(info),
+		Runbook:           aws.String(string(info.Runbook)),
+		Severity:          aws.String(string(info.Severity)),
+		Tags:              aws.StringSlice(info.Tags),
+		// scheduled queries are log analysis detections, they are delivered like rule alerts
+		Type:    aws.String(alertModel.RuleType),
+		AlertID: aws.String(alertID),
+		Title:   aws.String(getAlertTitle(info, group)),
+	}
+
+	msgBody, err := jsoniter.MarshalToString(alertNotification)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal alert notification")
+	}
+
+	_, err = sqsClient.SendMessage(&sqs.SendMessageInput{
+		QueueUrl:    aws.String(env.AlertingQueueURL),
+		MessageBody: aws.String(msgBody),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to send notification")
+	}
+	return nil
+}
+
+func getAlertTitle(info *models.ScheduledQuery, group *alertGroup) string {
+	if group.title != "" {
+		return group.title
+	}
+	if displayName := getDisplayName(info); displayName != nil {
+		return *displayName
+	}
+	return string(info.ID)
+}
+
+func getDisplayName(info *models.ScheduledQuery) *string {
+	if len(info.DisplayName) > 0 {
+		return aws.String(string(info.DisplayName))
+	}
+	return nil
+}
+
+// getQueryInfo fetches the version of the query which ran
+func getQueryInfo(state *State) (*models.ScheduledQuery, error) {
+	response, err := analysisClient.Operations.GetScheduledQuery(&analysisoperations.GetScheduledQueryParams{
+		QueryID:    state.QueryID,
+		VersionID:  aws.String(state.VersionID),
+		HTTPClient: httpClient,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch information for queryID [%s], version [%s]",
+			state.QueryID, state.VersionID)
+	}
+	return response.Payload, nil
+}
@@ -0,0 +1,227 @@
+package scheduler
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/gateway/analysis/models"
+	alertsmodels "github.com/panther-labs/panther/api/lambda/alerts/models"
+	alertModel "github.com/panther-labs/panther/internal/core/alert_delivery/models"
+	alertstable "github.com/panther-labs/panther/internal/log_analysis/alerts_api/table"
+	"github.com/panther-labs/panther/pkg/testutils"
+)
+
+var (
+	testInfo = &models.ScheduledQuery{
+		Description: "description",
+		DisplayName: "Root logins",
+		ID:          "Root.Logins",
+		LogTypes:    []string{"AWS.CloudTrail"},
+		Runbook:     "runbook",
+		Severity:    "HIGH",
+		Tags:        []string{"tag"},
+	}
+	testState = &State{
+		QueryID:     "Root.Logins",
+		ExecutionID: "execution",
+		VersionID:   "version",
+		StartTime:   time.Date(2020, 5, 1, 10, 30, 0, 0, time.UTC),
+	}
+)
+
+func TestGroupRows(t *testing.T) {
+	rows := []map[string]string{
+		{"user": "root", "ip": "1.1.1.1", "title": "root from 1.1.1.1"},
+		{"user": "admin", "ip": "2.2.2.2"},
+		{"user": "root", "ip": "3.3.3.3", "title": "root from 3.3.3.3"},
+	}
+
+	groups, err := groupRows(rows, []string{"user"})
+	require.NoError(t, err)
+	expected := []*alertGroup{
+		{dedup: `["root"]`, title: "root from 1.1.1.1", count: 2},
+		{dedup: `["admin"]`, count: 1},
+	}
+	assert.Equal(t, expected, groups)
+
+	// all columns, sorted by name
+	groups, err = groupRows(rows, nil)
+	require.NoError(t, err)
+	require.Len(t, groups, 3)
+	assert.Equal(t, `["1.1.1.1","root from 1.1.1.1","root"]`, groups[0].dedup)
+
+	_, err = groupRows(rows, []string{"missing"})
+	assert.Error(t, err)
+}
+
+func TestGenerateAlertID(t *testing.T) {
+	id := generateAlertID(testState, 60, `["root"]`)
+	assert.Len(t, id, 32)
+
+	// same dedup period
+	later := *testState
+	later.StartTime = testState.StartTime.Add(20 * time.Minute)
+	assert.Equal(t, id, generateAlertID(&later, 60, `["root"]`))
+	assert.NotEqual(t, id, generateAlertID(&later, 15, `["root"]`))
+
+	assert.NotEqual(t, id, generateAlertID(testState, 60, `["admin"]`))
+}
+
+func TestStoreAlertNew(t *testing.T) {
+	mockDdb := &testutils.DynamoDBMock{}
+	ddbClient = mockDdb
+	env.AlertsTable = "alerts"
+	now := time.Now().UTC()
+	group := &alertGroup{dedup: `["root"]`, count: 2}
+
+	mockDdb.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil).Once()
+
+	created, err := storeAlert("alertId", testInfo, testState, group, nil, now)
+	require.NoError(t, err)
+	assert.True(t, created)
+	mockDdb.AssertExpectations(t)
+
+	input := mockDdb.Calls[0].Arguments.Get(0).(*dynamodb.PutItemInput)
+	assert.Equal(t, "alerts", *input.TableName)
+	assert.Equal(t, "alertId", *input.Item["id"].S)
+	assert.Equal(t, "Root.Logins", *input.Item["ruleId"].S)
+	assert.Equal(t, "Root logins", *input.Item["title"].S)
+	assert.Equal(t, "2", *input.Item["eventCount"].N)
+	assert.Equal(t, []*string{aws.String("AWS.CloudTrail")}, input.Item["logTypes"].SS)
+	assert.Equal(t, []*string{aws.String("execution")}, input.Item[executionIDsAttribute].SS)
+	assert.Equal(t, "execution", *input.Item[creationExecutionAttribute].S)
+	assert.NotNil(t, input.ConditionExpression)
+}
+
+func TestStoreAlertSuppressed(t *testing.T) {
+	mockDdb := &testutils.DynamoDBMock{}
+	ddbClient = mockDdb
+	env.SuppressionsTable = "suppressions"
+	now := time.Now().UTC()
+	group := &alertGroup{dedup: `["root"]`, count: 1}
+
+	suppression, err := dynamodbattribute.MarshalMap(&alertstable.SuppressionItem{
+		RuleID:        "Root.Logins",
+		SuppressionID: "suppressionId",
+		DedupString:   aws.String(`["root"]`),
+		ExpiresAt:     now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	mockDdb.On("Query", mock.Anything).Return(&dynamodb.QueryOutput{
+		Items: []map[string]*dynamodb.AttributeValue{suppression},
+	}, nil).Once()
+	mockDdb.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil).Once()
+
+	active, err := getActiveSuppression(testState, group, now)
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	created, err := storeAlert("alertId", testInfo, testState, group, active, now)
+	require.NoError(t, err)
+	assert.True(t, created)
+	mockDdb.AssertExpectations(t)
+
+	query := mockDdb.Calls[0].Arguments.Get(0).(*dynamodb.QueryInput)
+	assert.Equal(t, "suppressions", *query.TableName)
+	input := mockDdb.Calls[1].Arguments.Get(0).(*dynamodb.PutItemInput)
+	assert.Equal(t, alertsmodels.StatusSuppressed, *input.Item["status"].S)
+	assert.Equal(t, "suppressionId", *input.Item["suppressionId"].S)
+}
+
+func TestStoreAlertExisting(t *testing.T) {
+	mockDdb := &testutils.DynamoDBMock{}
+	ddbClient = mockDdb
+	group := &alertGroup{dedup: `["root"]`, title: "custom title", count: 3}
+
+	conditionErr := awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "exists", nil)
+	mockDdb.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, conditionErr).Once()
+	mockDdb.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+
+	created, err := storeAlert("alertId", testInfo, testState, group, nil, time.Now())
+	require.NoError(t, err)
+	assert.False(t, created)
+	mockDdb.AssertExpectations(t)
+
+	input := mockDdb.Calls[1].Arguments.Get(0).(*dynamodb.UpdateItemInput)
+	assert.Equal(t, "alertId", *input.Key["id"].S)
+	assert.Contains(t, *input.UpdateExpression, "ADD")
+	assert.Contains(t, *input.ConditionExpression, "contains")
+	var names []string
+	for _, name := range input.ExpressionAttributeNames {
+		names = append(names, *name)
+	}
+	assert.Contains(t, names, executionIDsAttribute)
+}
+
+func TestStoreAlertRetry(t *testing.T) {
+	group := &alertGroup{dedup: `["root"]`, count: 3}
+	conditionErr := awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "exists", nil)
+
+	for creator, expected := range map[string]bool{"execution": true, "other": false} {
+		mockDdb := &testutils.DynamoDBMock{}
+		ddbClient = mockDdb
+		// the alert was stored and the events counted by an earlier attempt of the execution
+		mockDdb.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, conditionErr).Once()
+		mockDdb.On("UpdateItem", mock.Anything).Return(&dynamodb.UpdateItemOutput{}, conditionErr).Once()
+		mockDdb.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{
+			Item: map[string]*dynamodb.AttributeValue{creationExecutionAttribute: {S: aws.String(creator)}},
+		}, nil).Once()
+
+		created, err := storeAlert("alertId", testInfo, testState, group, nil, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, expected, created, creator)
+		mockDdb.AssertExpectations(t)
+
+		input := mockDdb.Calls[2].Arguments.Get(0).(*dynamodb.GetItemInput)
+		assert.True(t, *input.ConsistentRead)
+	}
+}
+
+func TestSendAlertNotification(t *testing.T) {
+	mockSqs := &testutils.SqsMock{}
+	sqsClient = mockSqs
+	env.AlertingQueueURL = "queueUrl"
+	now := time.Now().UTC()
+	group := &alertGroup{dedup: `["root"]`, title: "custom title", count: 1}
+
+	mockSqs.On("SendMessage", mock.Anything).Return(&sqs.SendMessageOutput{}, nil).Once()
+	require.NoError(t, sendAlertNotification("alertId", testInfo, testState, group, now))
+	mockSqs.AssertExpectations(t)
+
+	input := mockSqs.Calls[0].Arguments.Get(0).(*sqs.SendMessageInput)
+	assert.Equal(t, "queueUrl", *input.QueueUrl)
+	var alert alertModel.Alert
+	require.NoError(t, jsoniter.UnmarshalFromString(*input.MessageBody, &alert))
+	assert.Equal(t, "alertId", *alert.AlertID)
+	assert.Equal(t, "Root.Logins", *alert.PolicyID)
+	assert.Equal(t, "version", *alert.PolicyVersionID)
+	assert.Equal(t, "custom title", *alert.Title)
+	assert.Equal(t, alertModel.RuleType, *alert.Type)
+}
@@ -0,0 +1,143 @@
+package scheduler
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	analysisoperations "github.com/panther-labs/panther/api/gateway/analysis/client/operations"
+	"github.com/panther-labs/panther/api/gateway/analysis/models"
+	"github.com/panther-labs/panther/pkg/awsathena"
+	"github.com/panther-labs/panther/pkg/awsglue"
+	"github.com/panther-labs/panther/pkg/cron"
+)
+
+const resultsPrefix = "scheduled_queries/" // prefix in the Athena results bucket
+
+// Run starts the queries which are due, and raises alerts for the results of the queries which finished.
+//
+// It is invoked every minute, with a concurrency of 1 so a query is never started twice for the same schedule.
+func Run(now time.Time) error {
+	queries, err := getEnabledQueries()
+	if err != nil {
+		return err
+	}
+	states, err := listStates()
+	if err != nil {
+		return err
+	}
+
+	var result error
+	for _, query := range queries {
+		if err := runQuery(query, states[string(query.ID)], now); err != nil {
+			// keep going, a broken query should not block the others
+			zap.L().Error("failed to run scheduled query", zap.String("queryId", string(query.ID)), zap.Error(err))
+			result = err
+		}
+		delete(states, string(query.ID))
+	}
+
+	// the remaining states belong to queries which were deleted or disabled
+	for queryID, state := range states {
+		if state.ExecutionID != "" {
+			_, _ = awsathena.StopQuery(athenaClient, state.ExecutionID)
+		}
+		if err := deleteState(queryID); err != nil {
+			result = err
+		}
+	}
+	return result
+}
+
+func runQuery(query *models.EnabledPolicy, state *State, now time.Time) error {
+	schedule, err := cron.Parse(string(query.Schedule))
+	if err != nil {
+		return errors.Wrap(err, "invalid schedule")
+	}
+
+	if state == nil {
+		state = &State{QueryID: string(query.ID)}
+	}
+	if state.Schedule != string(query.Schedule) {
+		// new (or rescheduled) queries wait for their next scheduled time
+		state.Schedule = string(query.Schedule)
+		state.NextRun = schedule.Next(now)
+	}
+
+	if state.ExecutionID != "" {
+		done, err := checkExecution(query, state)
+		if err != nil || !done {
+			return err
+		}
+		state.ExecutionID = ""
+	}
+
+	if !state.NextRun.IsZero() && !now.Before(state.NextRun) {
+		resultsPath := "s3://" + env.AthenaResultsBucket + "/" + resultsPrefix
+		startOutput, err := awsathena.StartQuery(
+			athenaClient, awsglue.LogProcessingDatabaseName, string(query.SQL), aws.String(resultsPath))
+		if err != nil {
+			return errors.Wrap(err, "failed to start query")
+		}
+		state.ExecutionID = *startOutput.QueryExecutionId
+		state.VersionID = string(query.VersionID)
+		state.StartTime = state.NextRun
+		// runs missed while the query was running are skipped
+		state.NextRun = schedule.Next(now)
+	}
+
+	return putState(state)
+}
+
+// checkExecution raises the alerts of a finished execution, it returns false if the query is still running
+func checkExecution(query *models.EnabledPolicy, state *State) (bool, error) {
+	status, err := awsathena.Status(athenaClient, state.ExecutionID)
+	if err != nil {
+		return false, err
+	}
+
+	switch aws.StringValue(status.QueryExecution.Status.State) {
+	case athena.QueryExecutionStateSucceeded:
+		return true, raiseAlerts(query, state)
+	case athena.QueryExecutionStateFailed, athena.QueryExecutionStateCancelled:
+		zap.L().Warn("scheduled query did not succeed",
+			zap.String("queryId", state.QueryID),
+			zap.String("executionId", state.ExecutionID),
+			zap.String("reason", aws.StringValue(status.QueryExecution.Status.StateChangeReason)))
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func getEnabledQueries() ([]*models.EnabledPolicy, error) {
+	response, err := analysisClient.Operations.GetEnabledPolicies(&analysisoperations.GetEnabledPoliciesParams{
+		Type:       string(models.AnalysisTypeSCHEDULEDQUERY),
+		HTTPClient: httpClient,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list enabled scheduled queries")
+	}
+	return response.Payload.Policies, nil
+}
@@ -0,0 +1,173 @@
+package scheduler
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/gateway/analysis/models"
+	"github.com/panther-labs/panther/pkg/testutils"
+)
+
+type mockAthena struct {
+	athenaiface.AthenaAPI
+	mock.Mock
+}
+
+func (m *mockAthena) StartQueryExecution(input *athena.StartQueryExecutionInput) (*athena.StartQueryExecutionOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.StartQueryExecutionOutput), args.Error(1)
+}
+
+func (m *mockAthena) GetQueryExecution(input *athena.GetQueryExecutionInput) (*athena.GetQueryExecutionOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.GetQueryExecutionOutput), args.Error(1)
+}
+
+var testQuery = &models.EnabledPolicy{
+	ID:        "Root.Logins",
+	Schedule:  "*/15 * * * *",
+	SQL:       "SELECT * FROM aws_cloudtrail",
+	VersionID: "version",
+}
+
+func setupMocks() (*mockAthena, *testutils.DynamoDBMock) {
+	mockClient, mockDdb := &mockAthena{}, &testutils.DynamoDBMock{}
+	athenaClient, ddbClient = mockClient, mockDdb
+	env.StateTable = "state"
+	env.AthenaResultsBucket = "results"
+	mockDdb.On("PutItem", mock.Anything).Return(&dynamodb.PutItemOutput{}, nil).Once()
+	return mockClient, mockDdb
+}
+
+func storedState(t *testing.T, mockDdb *testutils.DynamoDBMock) *State {
+	input := mockDdb.Calls[0].Arguments.Get(0).(*dynamodb.PutItemInput)
+	assert.Equal(t, "state", *input.TableName)
+	var state State
+	require.NoError(t, dynamodbattribute.UnmarshalMap(input.Item, &state))
+	return &state
+}
+
+func TestRunQueryNew(t *testing.T) {
+	mockClient, mockDdb := setupMocks()
+	now := time.Date(2020, 5, 1, 10, 20, 0, 0, time.UTC)
+
+	require.NoError(t, runQuery(testQuery, nil, now))
+	mockClient.AssertExpectations(t)
+	mockDdb.AssertExpectations(t)
+
+	expected := &State{
+		QueryID:  "Root.Logins",
+		Schedule: "*/15 * * * *",
+		NextRun:  time.Date(2020, 5, 1, 10, 30, 0, 0, time.UTC),
+	}
+	assert.Equal(t, expected, storedState(t, mockDdb))
+}
+
+func TestRunQueryDue(t *testing.T) {
+	mockClient, mockDdb := setupMocks()
+	nextRun := time.Date(2020, 5, 1, 10, 30, 0, 0, time.UTC)
+	now := nextRun.Add(30 * time.Second)
+	state := &State{QueryID: "Root.Logins", Schedule: "*/15 * * * *", NextRun: nextRun}
+
+	mockClient.On("StartQueryExecution", mock.Anything).Return(
+		&athena.StartQueryExecutionOutput{QueryExecutionId: aws.String("executionId")}, nil).Once()
+
+	require.NoError(t, runQuery(testQuery, state, now))
+	mockClient.AssertExpectations(t)
+	mockDdb.AssertExpectations(t)
+
+	input := mockClient.Calls[0].Arguments.Get(0).(*athena.StartQueryExecutionInput)
+	assert.Equal(t, "SELECT * FROM aws_cloudtrail", *input.QueryString)
+	assert.Equal(t, "s3://results/scheduled_queries/", *input.ResultConfiguration.OutputLocation)
+
+	expected := &State{
+		QueryID:     "Root.Logins",
+		Schedule:    "*/15 * * * *",
+		NextRun:     time.Date(2020, 5, 1, 10, 45, 0, 0, time.UTC),
+		ExecutionID: "executionId",
+		VersionID:   "version",
+		StartTime:   nextRun,
+	}
+	assert.Equal(t, expected, storedState(t, mockDdb))
+}
+
+func TestRunQueryStillRunning(t *testing.T) {
+	mockClient, mockDdb := &mockAthena{}, &testutils.DynamoDBMock{}
+	athenaClient, ddbClient = mockClient, mockDdb
+	nextRun := time.Date(2020, 5, 1, 10, 45, 0, 0, time.UTC)
+	state := &State{QueryID: "Root.Logins", Schedule: "*/15 * * * *", NextRun: nextRun, ExecutionID: "executionId"}
+
+	mockClient.On("GetQueryExecution", mock.Anything).Return(&athena.GetQueryExecutionOutput{
+		QueryExecution: &athena.QueryExecution{
+			Status: &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateRunning)},
+		},
+	}, nil).Once()
+
+	// nothing is stored, even though the next run is due
+	require.NoError(t, runQuery(testQuery, state, nextRun.Add(time.Minute)))
+	mockClient.AssertExpectations(t)
+	mockDdb.AssertExpectations(t)
+}
+
+func TestRunQueryFailed(t *testing.T) {
+	mockClient, mockDdb := setupMocks()
+	nextRun := time.Date(2020, 5, 1, 10, 45, 0, 0, time.UTC)
+	state := &State{QueryID: "Root.Logins", Schedule: "*/15 * * * *", NextRun: nextRun, ExecutionID: "executionId"}
+
+	mockClient.On("GetQueryExecution", mock.Anything).Return(&athena.GetQueryExecutionOutput{
+		QueryExecution: &athena.QueryExecution{
+			Status: &athena.QueryExecutionStatus{
+				State:             aws.String(athena.QueryExecutionStateFailed),
+				StateChangeReason: aws.String("syntax error"),
+			},
+		},
+	}, nil).Once()
+
+	require.NoError(t, runQuery(testQuery, state, nextRun.Add(-time.Minute)))
+	mockClient.AssertExpectations(t)
+	mockDdb.AssertExpectations(t)
+	assert.Equal(t, "", storedState(t, mockDdb).ExecutionID)
+}
+
+func TestRunQueryRescheduled(t *testing.T) {
+	mockClient, mockDdb := setupMocks()
+	now := time.Date(2020, 5, 1, 10, 20, 0, 0, time.UTC)
+	state := &State{QueryID: "Root.Logins", Schedule: "0 * * * *", NextRun: now}
+
+	require.NoError(t, runQuery(testQuery, state, now))
+	mockClient.AssertExpectations(t)
+	assert.Equal(t, time.Date(2020, 5, 1, 10, 30, 0, 0, time.UTC), storedState(t, mockDdb).NextRun)
+}
+
+func TestRunQueryInvalidSchedule(t *testing.T) {
+	query := *testQuery
+	query.Schedule = "every minute"
+	assert.Error(t, runQuery(&query, nil, time.Now()))
+}
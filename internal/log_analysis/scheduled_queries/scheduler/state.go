@@ -0,0 +1,85 @@
+package scheduler
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/pkg/errors"
+)
+
+const stateTablePartitionKey = "id"
+
+// State is the scheduling state of a query, stored in the state table
+type State struct {
+	QueryID     string    `dynamodbav:"id,string"`
+	Schedule    string    `dynamodbav:"schedule,string"`
+	NextRun     time.Time `dynamodbav:"nextRun"`
+	ExecutionID string    `dynamodbav:"executionId,string"` // the running Athena query, empty when idle
+	VersionID   string    `dynamodbav:"versionId,string"`   // the version of the query that is running
+	StartTime   time.Time `dynamodbav:"startTime"`          // the scheduled time of the running execution
+}
+
+func listStates() (map[string]*State, error) {
+	result := make(map[string]*State)
+	input := &dynamodb.ScanInput{TableName: aws.String(env.StateTable)}
+	var unmarshalErr error
+	err := ddbClient.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		var states []*State
+		if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &states); unmarshalErr != nil {
+			return false
+		}
+		for _, state := range states {
+			result[state.QueryID] = state
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to scan state table")
+	}
+	if unmarshalErr != nil {
+		return nil, errors.Wrap(unmarshalErr, "failed to unmarshal state")
+	}
+	return result, nil
+}
+
+func putState(state *State) error {
+	item, err := dynamodbattribute.MarshalMap(state)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal state")
+	}
+	_, err = ddbClient.PutItem(&dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(env.StateTable),
+	})
+	return errors.Wrapf(err, "failed to store state of query %s", state.QueryID)
+}
+
+func deleteState(queryID string) error {
+	_, err := ddbClient.DeleteItem(&dynamodb.DeleteItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			stateTablePartitionKey: {S: aws.String(queryID)},
+		},
+		TableName: aws.String(env.StateTable),
+	})
+	return errors.Wrapf(err, "failed to delete state of query %s", queryID)
+}
@@ -0,0 +1,178 @@
+package awsathena
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// Formats of exported results
+const (
+	ExportCSV   = "csv"   // with a header row
+	ExportJSONL = "jsonl" // a JSON object per row, null values are omitted
+)
+
+const exportPageSize = 1000 // the maximum allowed by Athena
+
+// ExportResults writes all the results of a succeeded query to w, page by page. It returns the number of rows written.
+func ExportResults(client athenaiface.AthenaAPI, queryID, format string, w io.Writer) (int64, error) {
+	var (
+		writer    rowWriter
+		columns   []string
+		rows      int64
+		nextToken *string
+	)
+	for {
+		output, err := Results(client, queryID, nextToken, aws.Int64(exportPageSize))
+		if err != nil {
+			return rows, err
+		}
+		pageRows := output.ResultSet.Rows
+		if writer == nil { // first page
+			if output.ResultSet.ResultSetMetadata != nil {
+				for _, column := range output.ResultSet.ResultSetMetadata.ColumnInfo {
+					columns = append(columns, aws.StringValue(column.Name))
+				}
+			}
+			if len(pageRows) > 0 && isHeader(pageRows[0], columns) {
+				pageRows = pageRows[1:] // the first page of SELECT results starts with the column names
+			}
+			if writer, err = newRowWriter(format, columns, w); err != nil {
+				return rows, err
+			}
+		}
+
+		for _, row := range pageRows {
+			values := make([]*string, len(row.Data))
+			for i, datum := range row.Data {
+				values[i] = datum.VarCharValue
+			}
+			if err = writer.write(values); err != nil {
+				return rows, errors.Wrapf(err, "failed to write results of %s", queryID)
+			}
+			rows++
+		}
+
+		if output.NextToken == nil {
+			return rows, errors.Wrapf(writer.flush(), "failed to write results of %s", queryID)
+		}
+		nextToken = output.NextToken
+	}
+}
+
+// isHeader returns true if the row has the names of the columns
+func isHeader(row *athena.Row, columns []string) bool {
+	if len(row.Data) != len(columns) {
+		return false
+	}
+	for i, datum := range row.Data {
+		if aws.StringValue(datum.VarCharValue) != columns[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type rowWriter interface {
+	write(values []*string) error
+	flush() error
+}
+
+func newRowWriter(format string, columns []string, w io.Writer) (rowWriter, error) {
+	switch format {
+	case ExportCSV:
+		writer := &csvWriter{writer: csv.NewWriter(w)}
+		return writer, writer.write(aws.StringSlice(columns))
+	case ExportJSONL:
+		return &jsonlWriter{columns: columns, stream: jsoniter.NewStream(jsoniter.ConfigDefault, w, 4096)}, nil
+	default:
+		return nil, errors.Errorf("unknown export format %q", format)
+	}
+}
+
+// csvWriter writes null values as empty fields and escapes the values spreadsheets would evaluate as formulas
+type csvWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+func (c *csvWriter) write(values []*string) error {
+	c.record = c.record[:0]
+	for _, value := range values {
+		c.record = append(c.record, escapeFormula(aws.StringValue(value)))
+	}
+	return c.writer.Write(c.record)
+}
+
+// escapeFormula prefixes the values starting like a formula with a quote, the logged events are not trusted
+func escapeFormula(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@':
+		return "'" + value
+	default:
+		return value
+	}
+}
+
+func (c *csvWriter) flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// jsonlWriter writes the fields in the order of the columns, the values are strings as returned by Athena
+type jsonlWriter struct {
+	columns []string
+	stream  *jsoniter.Stream
+}
+
+func (j *jsonlWriter) write(values []*string) error {
+	j.stream.WriteObjectStart()
+	first := true
+	for i, value := range values {
+		if value == nil || i >= len(j.columns) {
+			continue
+		}
+		if !first {
+			j.stream.WriteMore()
+		}
+		first = false
+		j.stream.WriteObjectField(j.columns[i])
+		j.stream.WriteString(*value)
+	}
+	j.stream.WriteObjectEnd()
+	j.stream.WriteRaw("\n")
+	if j.stream.Buffered() > 4096 {
+		return j.stream.Flush()
+	}
+	return j.stream.Error
+}
+
+func (j *jsonlWriter) flush() error {
+	return j.stream.Flush()
+}
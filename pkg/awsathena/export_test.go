@@ -0,0 +1,113 @@
+package awsathena
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type athenaMock struct {
+	athenaiface.AthenaAPI
+	mock.Mock
+}
+
+func (m *athenaMock) GetQueryResults(input *athena.GetQueryResultsInput) (*athena.GetQueryResultsOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*athena.GetQueryResultsOutput), args.Error(1)
+}
+
+func row(values ...*string) *athena.Row {
+	data := make([]*athena.Datum, len(values))
+	for i, value := range values {
+		data[i] = &athena.Datum{VarCharValue: value}
+	}
+	return &athena.Row{Data: data}
+}
+
+func mockResults() *athenaMock {
+	client := &athenaMock{}
+	client.On("GetQueryResults", &athena.GetQueryResultsInput{
+		QueryExecutionId: aws.String("queryId"),
+		MaxResults:       aws.Int64(1000),
+	}).Return(&athena.GetQueryResultsOutput{
+		NextToken: aws.String("page2"),
+		ResultSet: &athena.ResultSet{
+			ResultSetMetadata: &athena.ResultSetMetadata{ColumnInfo: []*athena.ColumnInfo{
+				{Name: aws.String("name"), Type: aws.String("varchar")},
+				{Name: aws.String("count"), Type: aws.String("bigint")},
+			}},
+			Rows: []*athena.Row{
+				row(aws.String("name"), aws.String("count")),
+				row(aws.String("a, \"quoted\""), aws.String("1")),
+			},
+		},
+	}, nil).Once()
+	client.On("GetQueryResults", &athena.GetQueryResultsInput{
+		QueryExecutionId: aws.String("queryId"),
+		MaxResults:       aws.Int64(1000),
+		NextToken:        aws.String("page2"),
+	}).Return(&athena.GetQueryResultsOutput{
+		ResultSet: &athena.ResultSet{Rows: []*athena.Row{row(nil, aws.String("2"))}},
+	}, nil).Once()
+	return client
+}
+
+func TestExportResultsCSV(t *testing.T) {
+	client := mockResults()
+	var buffer bytes.Buffer
+	rows, err := ExportResults(client, "queryId", ExportCSV, &buffer)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), rows)
+	assert.Equal(t, "name,count\n\"a, \"\"quoted\"\"\",1\n,2\n", buffer.String())
+	client.AssertExpectations(t)
+}
+
+// Spreadsheets do not evaluate the values as formulas
+func TestExportResultsCSVFormulas(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, err := newRowWriter(ExportCSV, []string{"=header", "count"}, &buffer)
+	require.NoError(t, err)
+	require.NoError(t, writer.write(aws.StringSlice([]string{"=HYPERLINK(\"http://evil\")", "+1", "-1", "@SUM(A1)", "a=b", ""})))
+	require.NoError(t, writer.flush())
+	assert.Equal(t, "'=header,count\n\"'=HYPERLINK(\"\"http://evil\"\")\",'+1,'-1,'@SUM(A1),a=b,\n", buffer.String())
+}
+
+func TestExportResultsJSONL(t *testing.T) {
+	client := mockResults()
+	var buffer bytes.Buffer
+	rows, err := ExportResults(client, "queryId", ExportJSONL, &buffer)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), rows)
+	assert.Equal(t, "{\"name\":\"a, \\\"quoted\\\"\",\"count\":\"1\"}\n{\"count\":\"2\"}\n", buffer.String())
+	client.AssertExpectations(t)
+}
+
+func TestExportResultsUnknownFormat(t *testing.T) {
+	client := mockResults()
+	_, err := ExportResults(client, "queryId", "xml", &bytes.Buffer{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,387 @@
+package awsathena
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+// The SQL allowlist only lets single read-only statements over the allowed databases through. It is not a SQL parser,
+// it looks at the words of the statement outside of string literals and comments. The IAM role of the lambda is the
+// final guard.
+
+var (
+	// the statements that can be executed
+	allowedStatements = map[string]struct{}{
+		"select":   {},
+		"with":     {},
+		"show":     {},
+		"describe": {},
+	}
+
+	// keywords that are never part of a read-only statement
+	forbiddenKeywords = map[string]struct{}{
+		"alter":      {},
+		"call":       {},
+		"create":     {},
+		"deallocate": {},
+		"delete":     {},
+		"drop":       {},
+		"execute":    {},
+		"grant":      {},
+		"insert":     {},
+		"merge":      {},
+		"msck":       {},
+		"optimize":   {},
+		"prepare":    {},
+		"revoke":     {},
+		"truncate":   {},
+		"unload":     {},
+		"update":     {},
+		"vacuum":     {},
+	}
+
+	// the only databases tables can be referenced in, panther_views needs read access to the rule matches
+	// but they cannot be queried directly
+	allowedDatabases = map[string]struct{}{
+		awsglue.LogProcessingDatabaseName: {},
+		awsglue.ViewsDatabaseName:         {},
+	}
+)
+
+// SQLToken is a lower case word (keyword or identifier) or punctuation of a statement
+type SQLToken struct {
+	Text   string
+	Quoted bool // a quoted identifier, never a keyword
+}
+
+// ValidateReadOnlySQL returns an error if sql is not a single read-only statement over the allowed databases
+func ValidateReadOnlySQL(sql string) error {
+	tokens, err := TokenizeSQL(sql)
+	if err != nil {
+		return err
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].Text == ";" && !tokens[len(tokens)-1].Quoted {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return errors.New("empty statement")
+	}
+	if _, allowed := allowedStatements[tokens[0].Text]; !allowed || tokens[0].Quoted {
+		return errors.New("only SELECT, WITH, SHOW and DESCRIBE statements are allowed")
+	}
+	for _, token := range tokens {
+		if token.Quoted {
+			continue
+		}
+		if token.Text == ";" {
+			return errors.New("only a single statement is allowed")
+		}
+		if _, forbidden := forbiddenKeywords[token.Text]; forbidden {
+			return errors.Errorf("%s is not allowed, statements must be read-only", strings.ToUpper(token.Text))
+		}
+	}
+	databases, err := referencedDatabases(tokens)
+	if err != nil {
+		return err
+	}
+	for _, database := range databases {
+		if _, allowed := allowedDatabases[database]; !allowed {
+			return errors.Errorf("database %s cannot be queried", database)
+		}
+	}
+	return nil
+}
+
+// referencedDatabases returns the databases of the qualified table names of the statement, unqualified names are
+// in the database of the query. Tables follow FROM, JOIN and DESCRIBE, and the commas of a FROM clause.
+// An error is returned if a table reference is followed by words it does not know, they could hide another table.
+func referencedDatabases(tokens []SQLToken) ([]string, error) {
+	if tokens[0].Text == "show" {
+		return showDatabases(tokens), nil
+	}
+	if tokens[0].Text == "describe" {
+		return tableReferences(tokens, 1)
+	}
+	var databases []string
+	var open []int // the parentheses enclosing the token
+	for i, token := range tokens {
+		if token.Quoted {
+			continue
+		}
+		switch token.Text {
+		case "(":
+			open = append(open, i)
+			continue
+		case ")":
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			continue
+		case "from", "join":
+			// FROM in a function call such as EXTRACT(field FROM value) reads a column, subqueries start with SELECT
+			if len(open) > 0 && !startsQuery(tokens, open[len(open)-1]+1) {
+				continue
+			}
+		default:
+			continue
+		}
+		references, err := tableReferences(tokens, i+1)
+		if err != nil {
+			return nil, err
+		}
+		databases = append(databases, references...)
+	}
+	return databases, nil
+}
+
+// tableReferences returns the databases of the comma separated table references starting at tokens[start].
+// Subqueries are skipped, their tables are found by referencedDatabases.
+func tableReferences(tokens []SQLToken, start int) (databases []string, err error) {
+	for j := start; ; {
+		if j < len(tokens) && tokens[j].Text == "(" && !tokens[j].Quoted {
+			j = skipParentheses(tokens, j)
+		} else {
+			name, next := qualifiedName(tokens, j)
+			if name == nil {
+				return nil, unexpectedToken(tokens, j)
+			}
+			if next < len(tokens) && tokens[next].Text == "(" && !tokens[next].Quoted {
+				next = skipParentheses(tokens, next) // a table function such as UNNEST
+			} else if len(name) > 1 {
+				databases = append(databases, name[len(name)-2])
+			}
+			j = next
+		}
+
+		// the alias and its column names
+		if j < len(tokens) && tokens[j].Text == "as" && !tokens[j].Quoted {
+			j++
+		}
+		if j < len(tokens) && isName(tokens[j]) && !isKeyword(tokens[j], "tablesample") && !endsTableReferences(tokens[j]) {
+			j++
+			if j < len(tokens) && tokens[j].Text == "(" && !tokens[j].Quoted {
+				j = skipParentheses(tokens, j)
+			}
+		}
+		// TABLESAMPLE method (percentage)
+		if j < len(tokens) && isKeyword(tokens[j], "tablesample") {
+			j++
+			if j < len(tokens) && isName(tokens[j]) {
+				j++
+			}
+			if j >= len(tokens) || tokens[j].Text != "(" || tokens[j].Quoted {
+				return nil, unexpectedToken(tokens, j)
+			}
+			j = skipParentheses(tokens, j)
+		}
+
+		switch {
+		case j >= len(tokens) || endsTableReferences(tokens[j]):
+			return databases, nil
+		case tokens[j].Text == "," && !tokens[j].Quoted:
+			j++
+		default:
+			return nil, unexpectedToken(tokens, j)
+		}
+	}
+}
+
+// the words which may follow the table references of a FROM clause or a JOIN
+var tableReferencesEnd = map[string]struct{}{
+	")":         {},
+	"cross":     {},
+	"except":    {},
+	"fetch":     {},
+	"full":      {},
+	"group":     {},
+	"having":    {},
+	"inner":     {},
+	"intersect": {},
+	"join":      {},
+	"left":      {},
+	"limit":     {},
+	"natural":   {},
+	"offset":    {},
+	"on":        {},
+	"order":     {},
+	"outer":     {},
+	"right":     {},
+	"union":     {},
+	"using":     {},
+	"where":     {},
+	"window":    {},
+}
+
+func endsTableReferences(token SQLToken) bool {
+	_, found := tableReferencesEnd[token.Text]
+	return found && !token.Quoted
+}
+
+func isKeyword(token SQLToken, keyword string) bool {
+	return token.Text == keyword && !token.Quoted
+}
+
+// startsQuery returns true if tokens[start] starts a query, not the arguments of a function
+func startsQuery(tokens []SQLToken, start int) bool {
+	return start < len(tokens) && (isKeyword(tokens[start], "select") || isKeyword(tokens[start], "with"))
+}
+
+func unexpectedToken(tokens []SQLToken, i int) error {
+	if i >= len(tokens) {
+		return errors.New("a table is missing at the end of the statement")
+	}
+	return errors.Errorf("unexpected %s in the tables of the statement", strings.ToUpper(tokens[i].Text))
+}
+
+// showDatabases returns the databases of a SHOW statement: those of its qualified names and the unqualified names
+// after IN or FROM, except for the table of SHOW COLUMNS IN table [IN database]
+func showDatabases(tokens []SQLToken) (databases []string) {
+	inClauses := 0
+	for i := 1; i < len(tokens); {
+		isIn := !tokens[i].Quoted && (tokens[i].Text == "in" || tokens[i].Text == "from")
+		if isIn {
+			inClauses++
+			i++
+		}
+		name, next := qualifiedName(tokens, i)
+		switch {
+		case len(name) > 1:
+			databases = append(databases, name[len(name)-2])
+		case len(name) == 1 && isIn && !(tokens[1].Text == "columns" && inClauses == 1):
+			databases = append(databases, name[0])
+		}
+		if next == i {
+			next++
+		}
+		i = next
+	}
+	return databases
+}
+
+// qualifiedName returns the parts of the dotted name starting at tokens[start] and the index after it
+func qualifiedName(tokens []SQLToken, start int) ([]string, int) {
+	if start >= len(tokens) || !isName(tokens[start]) {
+		return nil, start
+	}
+	name := []string{tokens[start].Text}
+	i := start + 1
+	for i+1 < len(tokens) && tokens[i].Text == "." && !tokens[i].Quoted && isName(tokens[i+1]) {
+		name = append(name, tokens[i+1].Text)
+		i += 2
+	}
+	return name, i
+}
+
+// skipParentheses returns the index after the parenthesis closing the one at tokens[start]
+func skipParentheses(tokens []SQLToken, start int) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		if tokens[i].Quoted {
+			continue
+		}
+		switch tokens[i].Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(tokens)
+}
+
+func isName(token SQLToken) bool {
+	return token.Quoted || isWordRune([]rune(token.Text)[0])
+}
+
+// TokenizeSQL splits sql into words and punctuation, dropping comments and string literals
+func TokenizeSQL(sql string) (tokens []SQLToken, err error) {
+	runes := []rune(sql)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-': // comment until the end of the line
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := i + 2
+			for end+1 < len(runes) && !(runes[end] == '*' && runes[end+1] == '/') {
+				end++
+			}
+			if end+1 >= len(runes) {
+				return nil, errors.New("unterminated comment")
+			}
+			i = end + 2
+		case r == '\'' || r == '"' || r == '`':
+			text, next, ok := quoted(runes, i)
+			if !ok {
+				return nil, errors.Errorf("unterminated %c", r)
+			}
+			if r != '\'' { // identifiers, string literals are dropped
+				tokens = append(tokens, SQLToken{Text: strings.ToLower(text), Quoted: true})
+			}
+			i = next
+		case isWordRune(r):
+			start := i
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, SQLToken{Text: strings.ToLower(string(runes[start:i]))})
+		default:
+			tokens = append(tokens, SQLToken{Text: string(r)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// quoted returns the text between the quote at runes[start] and the closing quote (doubled quotes escape it),
+// the index after the closing quote and false if there is none
+func quoted(runes []rune, start int) (string, int, bool) {
+	quote := runes[start]
+	var text strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] != quote {
+			text.WriteRune(runes[i])
+			continue
+		}
+		if i+1 < len(runes) && runes[i+1] == quote {
+			text.WriteRune(quote)
+			i++
+			continue
+		}
+		return text.String(), i + 1, true
+	}
+	return "", len(runes), false
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
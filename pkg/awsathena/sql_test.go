@@ -0,0 +1,109 @@
+package awsathena
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSQLAllowed(t *testing.T) {
+	for _, sql := range []string{
+		"SELECT * FROM aws_cloudtrail LIMIT 10",
+		"select count(*) from aws_vpcflow where year=2020 and month=4;",
+		"WITH recent AS (SELECT * FROM all_logs WHERE p_event_time > now() - interval '1' day) SELECT * FROM recent",
+		"SHOW TABLES",
+		"DESCRIBE aws_cloudtrail",
+		"select 'drop table x; delete' as s from panther_logs.aws_s3serveraccess", // keywords in string literals
+		`select "update", "p_alert_update_time" from aws_cloudtrail`,              // quoted identifiers are names
+		"select 1 -- drop table x\n",
+		"/* delete */ select 1",
+		"select p_any_ip_addresses from panther_views.all_logs",
+		"select t.eventname, extract(hour from t.p_event_time) from panther_logs.aws_cloudtrail t", // qualified columns
+		"select * from aws_cloudtrail c join panther_views.all_logs v on c.p_row_id = v.p_row_id",
+		"select * from (select * from panther_logs.aws_vpcflow) x, panther_logs.aws_cloudtrail AS y(a, b)",
+		`select * from "awsdatacatalog"."panther_logs"."aws_cloudtrail"`,
+		"DESCRIBE panther_logs.aws_cloudtrail",
+		"SHOW TABLES IN panther_views",
+		"SHOW COLUMNS IN aws_cloudtrail FROM panther_logs",
+		"SHOW PARTITIONS panther_logs.aws_cloudtrail",
+		"select * from panther_logs.aws_cloudtrail tablesample bernoulli (10), aws_vpcflow x where x.p_row_id = 'a'",
+		"select * from aws_cloudtrail AS c TABLESAMPLE SYSTEM (50) join aws_vpcflow v on c.p_row_id = v.p_row_id",
+		"select trim(both 'x' from eventname), substring(eventname from 2 for 3) from aws_cloudtrail",
+		"select * from aws_cloudtrail cross join unnest(p_any_ip_addresses) as t(ip) left outer join aws_vpcflow v using (ip)",
+		"select * from aws_cloudtrail where p_row_id in (select p_row_id from panther_views.all_logs) order by 1",
+	} {
+		assert.NoError(t, ValidateReadOnlySQL(sql), sql)
+	}
+}
+
+func TestValidateSQLRejected(t *testing.T) {
+	for _, sql := range []string{
+		"",
+		"  ;  ",
+		"-- only a comment",
+		"DROP TABLE aws_cloudtrail",
+		"INSERT INTO aws_cloudtrail SELECT * FROM aws_cloudtrail",
+		"CREATE TABLE x AS SELECT * FROM aws_cloudtrail",
+		"select 1; drop table aws_cloudtrail",
+		"select 1; select 2",
+		"WITH x AS (SELECT 1) INSERT INTO y SELECT * FROM x",
+		"MSCK REPAIR TABLE aws_cloudtrail",
+		"SHOW CREATE TABLE aws_cloudtrail",
+		"UNLOAD (SELECT 1) TO 's3://bucket/' WITH (format = 'JSON')",
+		`"select" 1`,
+		"select * from panther_rule_matches.aws_cloudtrail",
+		`select * from "panther_rule_matches"."aws_cloudtrail"`,
+		"SHOW TABLES IN panther_rule_matches",
+		"select * from information_schema.tables",
+		"select * from panther_alerts.alerts",
+		"select * from aws_cloudtrail, panther_alerts.alerts",
+		"select * from aws_cloudtrail x, other.alerts as a, panther_alerts.alerts",
+		"select * from aws_cloudtrail where p_row_id in (select id from panther_alerts.alerts)",
+		"select * from aws_cloudtrail cross join awsdatacatalog.panther_alerts.alerts",
+		`select * from "panther_alerts" . "alerts"`,
+		"DESCRIBE panther_alerts.alerts",
+		"SHOW TABLES IN panther_alerts",
+		"SHOW COLUMNS IN alerts FROM panther_alerts",
+		"SHOW PARTITIONS panther_alerts.alerts",
+		"select * from panther_logs.aws_cloudtrail TABLESAMPLE BERNOULLI (10), otherdb.x",
+		"select * from aws_cloudtrail x tablesample system (1), panther_alerts.alerts",
+		"select * from aws_cloudtrail for system_time as of now(), panther_alerts.alerts",
+		"select * from",
+		"select 'unterminated",
+		"select 1 /* unterminated",
+	} {
+		assert.Error(t, ValidateReadOnlySQL(sql), sql)
+	}
+}
+
+func TestTokenizeSQL(t *testing.T) {
+	tokens, err := TokenizeSQL(`SELECT "Weird ""Name""", 'it''s' FROM db.t -- comment`)
+	assert.NoError(t, err)
+	assert.Equal(t, []SQLToken{
+		{Text: "select"},
+		{Text: `weird "name"`, Quoted: true},
+		{Text: ","},
+		{Text: "from"},
+		{Text: "db"},
+		{Text: "."},
+		{Text: "t"},
+	}, tokens)
+}
@@ -39,6 +39,7 @@ import (
 const (
 	logS3Prefix       = "logs"
 	ruleMatchS3Prefix = "rules"
+	alertsS3Prefix    = "alerts"
 
 	LogProcessingDatabaseName        = "panther_logs"
 	LogProcessingDatabaseDescription = "Holds tables with data from Panther log processing"
@@ -48,6 +49,9 @@ const (
 
 	ViewsDatabaseName        = "panther_views"
 	ViewsDatabaseDescription = "Holds views useful for querying Panther data"
+
+	AlertsDatabaseName        = "panther_alerts"
+	AlertsDatabaseDescription = "Holds tables with the Panther alerts archived from DynamoDB"
 )
 
 type PartitionKey struct {
@@ -83,6 +87,18 @@ func NewGlueTableMetadata(
 	}
 }
 
+// NewAlertsTableMetadata returns the metadata of a table of archived alerts, partitioned by alert creation day
+func NewAlertsTableMetadata(tableName, description string, eventStruct interface{}) *GlueTableMetadata {
+	return &GlueTableMetadata{
+		databaseName: AlertsDatabaseName,
+		tableName:    tableName,
+		description:  description,
+		timebin:      GlueTableDaily,
+		prefix:       alertsS3Prefix + "/" + tableName + "/",
+		eventStruct:  eventStruct,
+	}
+}
+
 func (gm *GlueTableMetadata) DatabaseName() string {
 	return gm.databaseName
 }
@@ -134,6 +150,15 @@ func (gm *GlueTableMetadata) GetPartitionPrefix(t time.Time) string {
 	return prefix + getTimePartitionPrefix(gm.timebin, t)
 }
 
+// CompactedPrefix is the prefix of a table under which the compactor writes the generations of its partitions,
+// outside of the partition prefixes the log processor writes to
+const CompactedPrefix = "compacted/"
+
+// GetCompactedPartitionPrefix returns the prefix holding the compacted generations of the partition of t
+func (gm *GlueTableMetadata) GetCompactedPartitionPrefix(t time.Time) string {
+	return gm.Prefix() + CompactedPrefix + getTimePartitionPrefix(gm.timebin, t)
+}
+
 // Returns the prefix of the table in S3 or error if it failed to generate it
 func getDatabase(dataType models.DataType) string {
 	if dataType == models.LogData {
@@ -157,6 +182,7 @@ func GetTableName(logType string) string {
 }
 
 // SyncPartitions updates a table's partitions using the latest table schema. Used when schemas change.
+// Tables using partition projection have no partitions, for those this is a no-op.
 func (gm *GlueTableMetadata) SyncPartitions(glueClient glueiface.GlueAPI, s3Client s3iface.S3API, startDate time.Time) error {
 	// inherit StorageDescriptor from table
 	tableInput := &glue.GetTableInput{
@@ -167,6 +193,9 @@ func (gm *GlueTableMetadata) SyncPartitions(glueClient glueiface.GlueAPI, s3Clie
 	if err != nil {
 		return err
 	}
+	if isPartitionProjected(tableOutput.Table) { // no partitions to update, they use the table schema
+		return nil
+	}
 
 	columns := tableOutput.Table.StorageDescriptor.Columns
 	if startDate.IsZero() {
@@ -253,6 +282,7 @@ func (gm *GlueTableMetadata) SyncPartitions(glueClient glueiface.GlueAPI, s3Clie
 	return <-errChan
 }
 
+// CreateJSONPartition creates the partition holding time t, a no-op for tables using partition projection
 func (gm *GlueTableMetadata) CreateJSONPartition(client glueiface.GlueAPI, t time.Time) error {
 	// inherit StorageDescriptor from table
 	tableInput := &glue.GetTableInput{
@@ -263,6 +293,9 @@ func (gm *GlueTableMetadata) CreateJSONPartition(client glueiface.GlueAPI, t tim
 	if err != nil {
 		return err
 	}
+	if isPartitionProjected(tableOutput.Table) { // Athena computes the partitions
+		return nil
+	}
 
 	// ensure this is a JSON table, use Contains() because there are multiple json serdes
 	if !strings.Contains(*tableOutput.Table.StorageDescriptor.SerdeInfo.SerializationLibrary, "json") {
@@ -301,6 +334,44 @@ func (gm *GlueTableMetadata) createPartition(client glueiface.GlueAPI, t time.Ti
 	return nil
 }
 
+// GetPartition returns the partition holding time t, nil if the partition does not exist
+func (gm *GlueTableMetadata) GetPartition(client glueiface.GlueAPI, t time.Time) (*glue.Partition, error) {
+	input := &glue.GetPartitionInput{
+		DatabaseName:    aws.String(gm.databaseName),
+		TableName:       aws.String(gm.tableName),
+		PartitionValues: gm.partitionValues(t),
+	}
+	output, err := client.GetPartition(input)
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == glue.ErrCodeEntityNotFoundException {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get partition of %s.%s for %s", gm.databaseName, gm.tableName, t)
+	}
+	return output.Partition, nil
+}
+
+// UpdatePartitionLocation points the partition to the S3 location (e.g., s3://bucket/prefix/), leaving everything else the same.
+// Queries see either the old or the new location, never a mix.
+func (gm *GlueTableMetadata) UpdatePartitionLocation(client glueiface.GlueAPI, partition *glue.Partition, location string) error {
+	storageDescriptor := *partition.StorageDescriptor // copy because we will mutate
+	storageDescriptor.Location = aws.String(location)
+	input := &glue.UpdatePartitionInput{
+		DatabaseName: aws.String(gm.databaseName),
+		TableName:    aws.String(gm.tableName),
+		PartitionInput: &glue.PartitionInput{
+			Values:            partition.Values,
+			Parameters:        partition.Parameters,
+			StorageDescriptor: &storageDescriptor,
+		},
+		PartitionValueList: partition.Values,
+	}
+	if _, err := client.UpdatePartition(input); err != nil {
+		return errors.Wrapf(err, "failed to update partition location of %s.%s to %s", gm.databaseName, gm.tableName, location)
+	}
+	return nil
+}
+
 func (gm *GlueTableMetadata) deletePartition(client glueiface.GlueAPI, t time.Time) (output *glue.DeletePartitionOutput, err error) {
 	input := &glue.DeletePartitionInput{
 		DatabaseName:    aws.String(gm.databaseName),
@@ -0,0 +1,99 @@
+package awsglue
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// partitionLevel is one of the time partition columns of a table
+type partitionLevel struct {
+	name    string
+	value   func(t time.Time) int
+	aligned func(t time.Time) bool // true if t is the start of a partition at this level
+	next    func(t time.Time) time.Time
+}
+
+var partitionLevels = []partitionLevel{
+	{
+		name:    "year",
+		value:   func(t time.Time) int { return t.Year() },
+		aligned: func(t time.Time) bool { return t.YearDay() == 1 && t.Hour() == 0 },
+		next:    func(t time.Time) time.Time { return t.AddDate(1, 0, 0) },
+	},
+	{
+		name:    "month",
+		value:   func(t time.Time) int { return int(t.Month()) },
+		aligned: func(t time.Time) bool { return t.Day() == 1 && t.Hour() == 0 },
+		next:    func(t time.Time) time.Time { return t.AddDate(0, 1, 0) },
+	},
+	{
+		name:    "day",
+		value:   func(t time.Time) int { return t.Day() },
+		aligned: func(t time.Time) bool { return t.Hour() == 0 },
+		next:    func(t time.Time) time.Time { return t.AddDate(0, 0, 1) },
+	},
+	{
+		name:    "hour",
+		value:   func(t time.Time) int { return t.Hour() },
+		aligned: func(t time.Time) bool { return true },
+		next:    func(t time.Time) time.Time { return t.Add(time.Hour) },
+	},
+}
+
+// PartitionPredicate returns an SQL predicate selecting the partitions of the table that overlap [start, end).
+// Fully covered years, months and days are selected with their coarser partition values to keep the SQL short.
+func (gm *GlueTableMetadata) PartitionPredicate(start, end time.Time) string {
+	levels := partitionLevels[:len(gm.PartitionKeys())]
+	finest := len(levels) - 1
+
+	t := truncate(start.UTC(), gm.Timebin())
+	end = end.UTC()
+	var clauses []string
+	for t.Before(end) {
+		level := finest
+		for i := 0; i < finest; i++ {
+			if levels[i].aligned(t) && !levels[i].next(t).After(end) {
+				level = i
+				break
+			}
+		}
+		terms := make([]string, 0, level+1)
+		for _, l := range levels[:level+1] {
+			terms = append(terms, l.name+"="+strconv.Itoa(l.value(t)))
+		}
+		clauses = append(clauses, "("+strings.Join(terms, " AND ")+")")
+		t = levels[level].next(t)
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")"
+}
+
+// truncate returns the start of the partition holding t
+func truncate(t time.Time, timebin GlueTableTimebin) time.Time {
+	switch timebin {
+	case GlueTableHourly:
+		return t.Truncate(time.Hour)
+	case GlueTableDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
@@ -0,0 +1,52 @@
+package awsglue
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+)
+
+var (
+	testHourlyTable = NewGlueTableMetadata(models.LogData, "Test.Log", "test", GlueTableHourly, struct{}{})
+	testDailyTable  = NewGlueTableMetadata(models.LogData, "Test.Log", "test", GlueTableDaily, struct{}{})
+)
+
+func TestPartitionPredicate(t *testing.T) {
+	start := time.Date(2020, 1, 31, 22, 30, 0, 0, time.UTC)
+	end := time.Date(2020, 2, 2, 0, 15, 0, 0, time.UTC)
+	assert.Equal(t,
+		"((year=2020 AND month=1 AND day=31 AND hour=22) OR (year=2020 AND month=1 AND day=31 AND hour=23) OR "+
+			"(year=2020 AND month=2 AND day=1) OR (year=2020 AND month=2 AND day=2 AND hour=0))",
+		testHourlyTable.PartitionPredicate(start, end))
+
+	assert.Equal(t, "((year=2020 AND month=1 AND day=31) OR (year=2020 AND month=2 AND day=1) OR (year=2020 AND month=2 AND day=2))",
+		testDailyTable.PartitionPredicate(start, end))
+}
+
+func TestPartitionPredicateCoarse(t *testing.T) {
+	start := time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "((year=2019 AND month=12) OR (year=2020) OR (year=2021 AND month=1))",
+		testHourlyTable.PartitionPredicate(start, end))
+}
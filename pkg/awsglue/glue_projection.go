@@ -0,0 +1,89 @@
+package awsglue
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Athena partition projection computes the partitions of a table from table properties instead of reading them
+// from Glue, so no partitions need to be created (https://docs.aws.amazon.com/athena/latest/ug/partition-projection.html)
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/aws/aws-sdk-go/service/glue/glueiface"
+	"github.com/pkg/errors"
+)
+
+const (
+	ProjectionEnabledParameter       = "projection.enabled"
+	StorageLocationTemplateParameter = "storage.location.template"
+
+	// Partitions are by event time so the year range also covers old and future data.
+	// Athena fails queries projecting more than 1M partitions, queries should filter on the partition columns.
+	projectionYearRange = "2000,2099"
+)
+
+// PartitionProjectionParameters returns the table parameters that enable partition projection over the partition keys,
+// the StorageLocationTemplateParameter (s3://bucket/ + PartitionLocationTemplate()) must be added by the caller
+func (gm *GlueTableMetadata) PartitionProjectionParameters() map[string]string {
+	parameters := map[string]string{
+		ProjectionEnabledParameter: "true",
+	}
+	for _, key := range gm.PartitionKeys() {
+		parameters["projection."+key.Name+".type"] = "integer"
+		switch key.Name {
+		case "year":
+			parameters["projection.year.range"] = projectionYearRange
+		case "month":
+			parameters["projection.month.range"] = "1,12"
+			parameters["projection.month.digits"] = "2"
+		case "day":
+			parameters["projection.day.range"] = "1,31"
+			parameters["projection.day.digits"] = "2"
+		case "hour":
+			parameters["projection.hour.range"] = "0,23"
+			parameters["projection.hour.digits"] = "2"
+		}
+	}
+	return parameters
+}
+
+// PartitionLocationTemplate returns the S3 prefix of the partitions with ${<partition key>} placeholders
+func (gm *GlueTableMetadata) PartitionLocationTemplate() string {
+	template := gm.Prefix()
+	for _, key := range gm.PartitionKeys() {
+		template += key.Name + "=${" + key.Name + "}/"
+	}
+	return template
+}
+
+// IsPartitionProjected returns true if the table uses partition projection, such tables have no partitions in Glue
+func (gm *GlueTableMetadata) IsPartitionProjected(client glueiface.GlueAPI) (bool, error) {
+	tableInput := &glue.GetTableInput{
+		DatabaseName: aws.String(gm.databaseName),
+		Name:         aws.String(gm.tableName),
+	}
+	tableOutput, err := client.GetTable(tableInput)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get table %s.%s", gm.databaseName, gm.tableName)
+	}
+	return isPartitionProjected(tableOutput.Table), nil
+}
+
+func isPartitionProjected(table *glue.TableData) bool {
+	return aws.StringValue(table.Parameters[ProjectionEnabledParameter]) == "true"
+}
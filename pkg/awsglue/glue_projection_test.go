@@ -0,0 +1,111 @@
+package awsglue
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+)
+
+var testGetProjectedTableOutput = &glue.GetTableOutput{
+	Table: &glue.TableData{
+		CreateTime:        aws.Time(refTime),
+		StorageDescriptor: testStorageDescriptor,
+		Parameters: map[string]*string{
+			ProjectionEnabledParameter:       aws.String("true"),
+			StorageLocationTemplateParameter: aws.String("s3://bucket/logs/test_logs/year=${year}/month=${month}/day=${day}/hour=${hour}/"),
+		},
+	},
+}
+
+func TestPartitionProjectionParameters(t *testing.T) {
+	gm := NewGlueTableMetadata(models.LogData, "Test.Logs", "Description", GlueTableHourly, partitionTestEvent{})
+	expected := map[string]string{
+		"projection.enabled":      "true",
+		"projection.year.type":    "integer",
+		"projection.year.range":   "2000,2099",
+		"projection.month.type":   "integer",
+		"projection.month.range":  "1,12",
+		"projection.month.digits": "2",
+		"projection.day.type":     "integer",
+		"projection.day.range":    "1,31",
+		"projection.day.digits":   "2",
+		"projection.hour.type":    "integer",
+		"projection.hour.range":   "0,23",
+		"projection.hour.digits":  "2",
+	}
+	assert.Equal(t, expected, gm.PartitionProjectionParameters())
+
+	gm = NewGlueTableMetadata(models.LogData, "Test.Logs", "Description", GlueTableMonthly, partitionTestEvent{})
+	assert.Len(t, gm.PartitionProjectionParameters(), 6)
+}
+
+func TestPartitionLocationTemplate(t *testing.T) {
+	gm := NewGlueTableMetadata(models.LogData, "Test.Logs", "Description", GlueTableHourly, partitionTestEvent{})
+	assert.Equal(t, "logs/test_logs/year=${year}/month=${month}/day=${day}/hour=${hour}/", gm.PartitionLocationTemplate())
+
+	gm = NewGlueTableMetadata(models.RuleData, "Test.Logs", "Description", GlueTableDaily, partitionTestEvent{})
+	assert.Equal(t, "rules/test_logs/year=${year}/month=${month}/day=${day}/", gm.PartitionLocationTemplate())
+}
+
+func TestIsPartitionProjected(t *testing.T) {
+	gm := NewGlueTableMetadata(models.LogData, "Test.Logs", "Description", GlueTableHourly, partitionTestEvent{})
+
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(testGetProjectedTableOutput, nil).Once()
+	projected, err := gm.IsPartitionProjected(glueClient)
+	require.NoError(t, err)
+	assert.True(t, projected)
+
+	glueClient.On("GetTable", mock.Anything).Return(testGetTableOutput, nil).Once()
+	projected, err = gm.IsPartitionProjected(glueClient)
+	require.NoError(t, err)
+	assert.False(t, projected)
+	glueClient.AssertExpectations(t)
+}
+
+func TestCreateJSONPartitionProjected(t *testing.T) {
+	gm := NewGlueTableMetadata(models.LogData, "Test.Logs", "Description", GlueTableHourly, partitionTestEvent{})
+
+	// no partition is created
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(testGetProjectedTableOutput, nil).Once()
+	assert.NoError(t, gm.CreateJSONPartition(glueClient, refTime))
+	glueClient.AssertExpectations(t)
+}
+
+func TestSyncPartitionsProjected(t *testing.T) {
+	gm := NewGlueTableMetadata(models.LogData, "Test.Logs", "Description", GlueTableHourly, partitionTestEvent{})
+
+	// no partition is read or updated
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(testGetProjectedTableOutput, nil).Once()
+	s3Client := &mockS3{}
+	assert.NoError(t, gm.SyncPartitions(glueClient, s3Client, time.Time{}))
+	glueClient.AssertExpectations(t)
+	s3Client.AssertExpectations(t)
+}
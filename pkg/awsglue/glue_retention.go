@@ -0,0 +1,106 @@
+package awsglue
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/aws/aws-sdk-go/service/glue/glueiface"
+	"github.com/pkg/errors"
+)
+
+const maxBatchDeletePartitions = 25 // Glue API limit
+
+// DeletePartitionsBefore deletes the partitions holding only data older than cutoff and returns how many were deleted.
+// Tables using partition projection have no partitions, for those this is a no-op.
+func (gm *GlueTableMetadata) DeletePartitionsBefore(client glueiface.GlueAPI, cutoff time.Time) (int, error) {
+	projected, err := gm.IsPartitionProjected(client)
+	if err != nil || projected {
+		return 0, err
+	}
+
+	var expired []*glue.PartitionValueList
+	input := &glue.GetPartitionsInput{
+		DatabaseName: aws.String(gm.databaseName),
+		TableName:    aws.String(gm.tableName),
+		Expression:   aws.String("year <= " + strconv.Itoa(cutoff.Year())),
+	}
+	var parseErr error
+	err = client.GetPartitionsPages(input, func(page *glue.GetPartitionsOutput, lastPage bool) bool {
+		for _, partition := range page.Partitions {
+			var start time.Time
+			if start, parseErr = partitionTime(partition.Values); parseErr != nil {
+				return false
+			}
+			if !gm.timebin.Next(start).After(cutoff) {
+				expired = append(expired, &glue.PartitionValueList{Values: partition.Values})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to list partitions of %s.%s", gm.databaseName, gm.tableName)
+	}
+	if parseErr != nil {
+		return 0, errors.Wrapf(parseErr, "invalid partition in %s.%s", gm.databaseName, gm.tableName)
+	}
+
+	deleted := 0
+	for len(expired) > 0 {
+		batch := expired
+		if len(batch) > maxBatchDeletePartitions {
+			batch = batch[:maxBatchDeletePartitions]
+		}
+		expired = expired[len(batch):]
+
+		output, err := client.BatchDeletePartition(&glue.BatchDeletePartitionInput{
+			DatabaseName:       aws.String(gm.databaseName),
+			TableName:          aws.String(gm.tableName),
+			PartitionsToDelete: batch,
+		})
+		if err != nil {
+			return deleted, errors.Wrapf(err, "failed to delete partitions of %s.%s", gm.databaseName, gm.tableName)
+		}
+		if len(output.Errors) > 0 {
+			return deleted, errors.Errorf("failed to delete %d partitions of %s.%s: %s", len(output.Errors),
+				gm.databaseName, gm.tableName, aws.StringValue(output.Errors[0].ErrorDetail.ErrorMessage))
+		}
+		deleted += len(batch)
+	}
+	return deleted, nil
+}
+
+// partitionTime returns the start of the partition from its year, month, day and hour values
+func partitionTime(values []*string) (time.Time, error) {
+	if len(values) == 0 || len(values) > 4 {
+		return time.Time{}, errors.Errorf("unexpected partition values %v", aws.StringValueSlice(values))
+	}
+	parts := []int{0, 1, 1, 0} // year, month, day, hour
+	for i, value := range values {
+		part, err := strconv.Atoi(aws.StringValue(value))
+		if err != nil {
+			return time.Time{}, errors.Wrapf(err, "unexpected partition values %v", aws.StringValueSlice(values))
+		}
+		parts[i] = part
+	}
+	return time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], 0, 0, 0, time.UTC), nil
+}
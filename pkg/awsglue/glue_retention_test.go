@@ -0,0 +1,123 @@
+package awsglue
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+)
+
+func (m *mockGlue) GetPartitionsPages(input *glue.GetPartitionsInput, fn func(*glue.GetPartitionsOutput, bool) bool) error {
+	args := m.Called(input, fn)
+	fn(args.Get(0).(*glue.GetPartitionsOutput), true)
+	return args.Error(1)
+}
+
+func (m *mockGlue) BatchDeletePartition(input *glue.BatchDeletePartitionInput) (*glue.BatchDeletePartitionOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*glue.BatchDeletePartitionOutput), args.Error(1)
+}
+
+func hourlyPartitions(start time.Time, count int) []*glue.Partition {
+	partitions := make([]*glue.Partition, count)
+	for i := range partitions {
+		t := start.Add(time.Duration(i) * time.Hour)
+		partitions[i] = &glue.Partition{Values: aws.StringSlice([]string{
+			fmt.Sprintf("%d", t.Year()), fmt.Sprintf("%02d", t.Month()), fmt.Sprintf("%02d", t.Day()), fmt.Sprintf("%02d", t.Hour()),
+		})}
+	}
+	return partitions
+}
+
+func TestDeletePartitionsBefore(t *testing.T) {
+	gm := NewGlueTableMetadata(models.LogData, "Test.Logs", "Description", GlueTableHourly, partitionTestEvent{})
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cutoff := start.Add(30*time.Hour + 30*time.Minute) // partitions of the first 30 hours are expired
+
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(testGetTableOutput, nil).Once()
+	glueClient.On("GetPartitionsPages", mock.Anything, mock.Anything).Return(
+		&glue.GetPartitionsOutput{Partitions: hourlyPartitions(start, 48)}, nil).Once()
+	glueClient.On("BatchDeletePartition", mock.Anything).Return(&glue.BatchDeletePartitionOutput{}, nil).Twice()
+
+	deleted, err := gm.DeletePartitionsBefore(glueClient, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 30, deleted)
+	glueClient.AssertExpectations(t)
+
+	getInput := glueClient.Calls[1].Arguments.Get(0).(*glue.GetPartitionsInput)
+	assert.Equal(t, "year <= 2020", *getInput.Expression)
+	assert.Len(t, glueClient.Calls[2].Arguments.Get(0).(*glue.BatchDeletePartitionInput).PartitionsToDelete, 25)
+	lastBatch := glueClient.Calls[3].Arguments.Get(0).(*glue.BatchDeletePartitionInput).PartitionsToDelete
+	require.Len(t, lastBatch, 5)
+	assert.Equal(t, []string{"2020", "01", "02", "05"}, aws.StringValueSlice(lastBatch[4].Values))
+}
+
+func TestDeletePartitionsBeforeProjected(t *testing.T) {
+	gm := NewGlueTableMetadata(models.LogData, "Test.Logs", "Description", GlueTableHourly, partitionTestEvent{})
+
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(testGetProjectedTableOutput, nil).Once()
+
+	deleted, err := gm.DeletePartitionsBefore(glueClient, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+	glueClient.AssertExpectations(t)
+}
+
+func TestDeletePartitionsBeforeErrors(t *testing.T) {
+	gm := NewGlueTableMetadata(models.LogData, "Test.Logs", "Description", GlueTableHourly, partitionTestEvent{})
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(testGetTableOutput, nil).Once()
+	glueClient.On("GetPartitionsPages", mock.Anything, mock.Anything).Return(
+		&glue.GetPartitionsOutput{Partitions: hourlyPartitions(start, 2)}, nil).Once()
+	glueClient.On("BatchDeletePartition", mock.Anything).Return(&glue.BatchDeletePartitionOutput{
+		Errors: []*glue.PartitionError{{ErrorDetail: &glue.ErrorDetail{ErrorMessage: aws.String("throttled")}}},
+	}, nil).Once()
+
+	_, err := gm.DeletePartitionsBefore(glueClient, start.Add(24*time.Hour))
+	assert.Error(t, err)
+	glueClient.AssertExpectations(t)
+}
+
+func TestPartitionTime(t *testing.T) {
+	start, err := partitionTime(aws.StringSlice([]string{"2020", "05", "01", "13"}))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 5, 1, 13, 0, 0, 0, time.UTC), start)
+
+	start, err = partitionTime(aws.StringSlice([]string{"2020", "05"}))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC), start)
+
+	_, err = partitionTime(aws.StringSlice([]string{"2020", "May"}))
+	assert.Error(t, err)
+	_, err = partitionTime(nil)
+	assert.Error(t, err)
+}
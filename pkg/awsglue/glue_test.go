@@ -90,6 +90,7 @@ func TestGlueTableMetadataLogData(t *testing.T) {
 	assert.Equal(t, "logs/my_logs_type/", gm.Prefix())
 	assert.Equal(t, partitionTestEvent{}, gm.eventStruct)
 	assert.Equal(t, "logs/my_logs_type/year=2020/month=01/day=03/hour=01/", gm.GetPartitionPrefix(refTime))
+	assert.Equal(t, "logs/my_logs_type/compacted/year=2020/month=01/day=03/hour=01/", gm.GetCompactedPartitionPrefix(refTime))
 }
 
 func TestGlueTableMetadataRuleMatches(t *testing.T) {
@@ -105,6 +106,17 @@ func TestGlueTableMetadataRuleMatches(t *testing.T) {
 	assert.Equal(t, "rules/my_rule/year=2020/month=01/day=03/hour=01/", gm.GetPartitionPrefix(refTime))
 }
 
+func TestGlueTableMetadataAlerts(t *testing.T) {
+	gm := NewAlertsTableMetadata("alerts", "description", partitionTestEvent{})
+
+	assert.Equal(t, "description", gm.Description())
+	assert.Equal(t, GlueTableDaily, gm.Timebin())
+	assert.Equal(t, "alerts", gm.TableName())
+	assert.Equal(t, AlertsDatabaseName, gm.DatabaseName())
+	assert.Equal(t, "alerts/alerts/", gm.Prefix())
+	assert.Equal(t, "alerts/alerts/year=2020/month=01/day=03/", gm.GetPartitionPrefix(refTime))
+}
+
 func TestCreateJSONPartition(t *testing.T) {
 	gm := NewGlueTableMetadata(models.LogData, "Test.Logs", "Description", GlueTableHourly, partitionTestEvent{})
 
@@ -256,6 +268,48 @@ func TestSyncPartitionsGetPartitionNonAWSError(t *testing.T) {
 	glueClient.AssertExpectations(t)
 }
 
+func TestGetPartition(t *testing.T) {
+	gm := NewGlueTableMetadata(models.LogData, "My.Logs.Type", "description", GlueTableHourly, partitionTestEvent{})
+
+	glueClient := &mockGlue{}
+	glueClient.On("GetPartition", mock.Anything).Return(testGetPartitionOutput, nil).Once()
+	partition, err := gm.GetPartition(glueClient, refTime)
+	assert.NoError(t, err)
+	assert.Equal(t, testGetPartitionOutput.Partition, partition)
+	getInput := glueClient.Calls[0].Arguments.Get(0).(*glue.GetPartitionInput)
+	assert.Equal(t, []*string{aws.String("2020"), aws.String("01"), aws.String("03"), aws.String("01")}, getInput.PartitionValues)
+
+	glueClient = &mockGlue{}
+	glueClient.On("GetPartition", mock.Anything).Return(testGetPartitionOutput, entityNotFoundError).Once()
+	partition, err = gm.GetPartition(glueClient, refTime)
+	assert.NoError(t, err)
+	assert.Nil(t, partition)
+
+	glueClient = &mockGlue{}
+	glueClient.On("GetPartition", mock.Anything).Return(testGetPartitionOutput, otherAWSError).Once()
+	_, err = gm.GetPartition(glueClient, refTime)
+	assert.Error(t, err)
+}
+
+func TestUpdatePartitionLocation(t *testing.T) {
+	gm := NewGlueTableMetadata(models.LogData, "My.Logs.Type", "description", GlueTableHourly, partitionTestEvent{})
+	partition := &glue.Partition{
+		Values:            []*string{aws.String("2020"), aws.String("01"), aws.String("03"), aws.String("01")},
+		StorageDescriptor: testStorageDescriptor,
+	}
+
+	glueClient := &mockGlue{}
+	glueClient.On("UpdatePartition", mock.Anything).Return(testUpdatePartitionOutput, nil).Once()
+	assert.NoError(t, gm.UpdatePartitionLocation(glueClient, partition, "s3://testbucket/logs/table/compacted/"))
+	glueClient.AssertExpectations(t)
+
+	updateInput := glueClient.Calls[0].Arguments.Get(0).(*glue.UpdatePartitionInput)
+	assert.Equal(t, "s3://testbucket/logs/table/compacted/", *updateInput.PartitionInput.StorageDescriptor.Location)
+	assert.Equal(t, testStorageDescriptor.SerdeInfo, updateInput.PartitionInput.StorageDescriptor.SerdeInfo)
+	assert.Equal(t, partition.Values, updateInput.PartitionValueList)
+	assert.Equal(t, "s3://testbucket/logs/table", *testStorageDescriptor.Location) // not mutated
+}
+
 type mockGlue struct {
 	glueiface.GlueAPI
 	mock.Mock
@@ -0,0 +1,79 @@
+package awsglue
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"reflect"
+	"strings"
+)
+
+const anyColumnPrefix = "p_any_"
+
+// AllIndicatorsViewName is the view with a row per indicator of the p_any columns of all log tables.
+// Its columns are indicator, indicator_type, log_type, p_event_time, p_row_id and the partition columns.
+const AllIndicatorsViewName = "all_indicators"
+
+// IndicatorView is a view with a row per indicator of one p_any column of all log tables, it has the columns
+// of the AllIndicatorsViewName view.
+type IndicatorView struct {
+	Column        string // the p_any column
+	IndicatorType string // the value of the indicator_type column
+	ViewName      string
+}
+
+// IndicatorViews are the views of the p_any columns
+var IndicatorViews = []IndicatorView{
+	{Column: "p_any_ip_addresses", IndicatorType: "ip_address", ViewName: "all_ip_addresses"},
+	{Column: "p_any_domain_names", IndicatorType: "domain_name", ViewName: "all_domain_names"},
+	{Column: "p_any_sha1_hashes", IndicatorType: "sha1_hash", ViewName: "all_sha1_hashes"},
+	{Column: "p_any_md5_hashes", IndicatorType: "md5_hash", ViewName: "all_md5_hashes"},
+	{Column: "p_any_aws_account_ids", IndicatorType: "aws_account_id", ViewName: "all_aws_account_ids"},
+	{Column: "p_any_aws_instance_ids", IndicatorType: "aws_instance_id", ViewName: "all_aws_instance_ids"},
+	{Column: "p_any_aws_arns", IndicatorType: "aws_arn", ViewName: "all_aws_arns"},
+	{Column: "p_any_aws_tags", IndicatorType: "aws_tag", ViewName: "all_aws_tags"},
+}
+
+// AnyColumns returns the p_any columns of the table, including those of embedded structs like parsers.PantherLog
+func (gm *GlueTableMetadata) AnyColumns() map[string]bool {
+	columns := make(map[string]bool)
+	if gm.eventStruct != nil {
+		addAnyColumns(reflect.TypeOf(gm.eventStruct), columns)
+	}
+	return columns
+}
+
+func addAnyColumns(eventType reflect.Type, columns map[string]bool) {
+	for eventType.Kind() == reflect.Ptr {
+		eventType = eventType.Elem()
+	}
+	if eventType.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < eventType.NumField(); i++ {
+		field := eventType.Field(i)
+		if field.Anonymous {
+			addAnyColumns(field.Type, columns)
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if strings.HasPrefix(name, anyColumnPrefix) {
+			columns[name] = true
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package awsglue
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers"
+	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers/awslogs"
+)
+
+type testAWSEvent struct {
+	Name *string `json:"name"`
+	awslogs.AWSPantherLog
+}
+
+type testPantherEvent struct {
+	Name *string `json:"name"`
+	parsers.PantherLog
+}
+
+type anyTestBase struct {
+	RowID       *string   `json:"p_row_id,omitempty"`
+	IPAddresses *[]string `json:"p_any_ip_addresses,omitempty"`
+}
+
+type anyTestEvent struct {
+	Name *string `json:"name"`
+	anyTestBase
+	AccountIDs *[]string `json:"p_any_aws_account_ids,omitempty"`
+}
+
+func TestAnyColumns(t *testing.T) {
+	table := NewGlueTableMetadata(models.LogData, "AWS.Test", "test", GlueTableHourly, &testAWSEvent{})
+	assert.Equal(t, map[string]bool{
+		"p_any_ip_addresses":     true,
+		"p_any_domain_names":     true,
+		"p_any_sha1_hashes":      true,
+		"p_any_md5_hashes":       true,
+		"p_any_aws_account_ids":  true,
+		"p_any_aws_instance_ids": true,
+		"p_any_aws_arns":         true,
+		"p_any_aws_tags":         true,
+	}, table.AnyColumns())
+
+	table = NewGlueTableMetadata(models.LogData, "Other.Test", "test", GlueTableHourly, testPantherEvent{})
+	assert.Equal(t, map[string]bool{
+		"p_any_ip_addresses": true,
+		"p_any_domain_names": true,
+		"p_any_sha1_hashes":  true,
+		"p_any_md5_hashes":   true,
+	}, table.AnyColumns())
+
+	table = NewGlueTableMetadata(models.LogData, "Test.Any", "test", GlueTableHourly, &anyTestEvent{})
+	assert.Equal(t, map[string]bool{
+		"p_any_ip_addresses":    true,
+		"p_any_aws_account_ids": true,
+	}, table.AnyColumns())
+
+	table = NewGlueTableMetadata(models.LogData, "Test.Any", "test", GlueTableHourly, anyTestBase{})
+	assert.Equal(t, map[string]bool{"p_any_ip_addresses": true}, table.AnyColumns())
+
+	table = NewGlueTableMetadata(models.LogData, "Test.Any", "test", GlueTableHourly, struct{}{})
+	assert.Empty(t, table.AnyColumns())
+}
+
+func TestIndicatorViews(t *testing.T) {
+	names := make(map[string]bool)
+	for _, view := range IndicatorViews {
+		assert.Equal(t, "all_"+view.Column[len(anyColumnPrefix):], view.ViewName)
+		assert.False(t, names[view.IndicatorType])
+		names[view.IndicatorType] = true
+	}
+}
@@ -66,6 +66,11 @@ func (gp *GluePartition) GetCompression() string {
 	return gp.compression
 }
 
+// The hour of the data in the partition (zero if the partition is not hourly)
+func (gp *GluePartition) GetHour() time.Time {
+	return gp.hour
+}
+
 func (gp *GluePartition) GetPartitionColumnsInfo() []PartitionColumnInfo {
 	return gp.partitionColumns
 }
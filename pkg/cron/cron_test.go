@@ -0,0 +1,76 @@
+package cron
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2020, 4, 15, 10, 7, 30, 0, time.UTC) // a Wednesday
+
+func TestParseInvalid(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+	} {
+		_, err := Parse(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestNext(t *testing.T) {
+	for expression, expected := range map[string]time.Time{
+		"* * * * *":      time.Date(2020, 4, 15, 10, 8, 0, 0, time.UTC),
+		"0 * * * *":      time.Date(2020, 4, 15, 11, 0, 0, 0, time.UTC),
+		"*/15 * * * *":   time.Date(2020, 4, 15, 10, 15, 0, 0, time.UTC),
+		"5/20 * * * *":   time.Date(2020, 4, 15, 10, 25, 0, 0, time.UTC),
+		"0 9-17/4 * * *": time.Date(2020, 4, 15, 13, 0, 0, 0, time.UTC),
+		"30 2 * * *":     time.Date(2020, 4, 16, 2, 30, 0, 0, time.UTC),
+		"0 0 1 * *":      time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 0":      time.Date(2020, 4, 19, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":      time.Date(2020, 4, 19, 0, 0, 0, 0, time.UTC),
+		"0 0 1 1 *":      time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":     time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		"0 0 1 * 5":      time.Date(2020, 4, 17, 0, 0, 0, 0, time.UTC), // day of month or day of week
+		"0 0 30 2 *":     {},
+	} {
+		schedule, err := Parse(expression)
+		require.NoError(t, err, expression)
+		assert.Equal(t, expected, schedule.Next(testTime), expression)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	schedule, err := Parse("7,8 10 * 4 3")
+	require.NoError(t, err)
+	assert.True(t, schedule.Matches(testTime))
+	assert.False(t, schedule.Matches(testTime.Add(2*time.Minute)))
+}
@@ -0,0 +1,178 @@
+/*
+Package metrics emits metrics as CloudWatch Embedded Metric Format (EMF) records.
+
+EMF records are JSON log lines that CloudWatch Logs automatically extracts metrics from, so lambdas
+can publish custom metrics (with dimensions) without calling the CloudWatch API.
+See https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/CloudWatch_Embedded_Metric_Format_Specification.html
+
+Example:
+
+	logger := metrics.NewLogger("Panther", os.Stdout)
+	err := logger.Log(
+		[]metrics.Dimension{{Name: "LogType", Value: "AWS.CloudTrail"}},
+		metrics.Metric{Name: "EventsProcessed", Unit: metrics.UnitCount, Value: 10},
+	)
+*/
+package metrics
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// Units supported by CloudWatch (not exhaustive)
+const (
+	UnitBytes        = "Bytes"
+	UnitCount        = "Count"
+	UnitMicroseconds = "Microseconds"
+	UnitMilliseconds = "Milliseconds"
+	UnitSeconds      = "Seconds"
+	UnitNone         = "None"
+)
+
+// maximum number of dimensions in a dimension set allowed by CloudWatch
+const maxDimensions = 9
+
+// Dimension is a name/value pair that is part of the identity of a metric
+type Dimension struct {
+	Name  string
+	Value string
+}
+
+// Metric is a single measurement
+type Metric struct {
+	Name  string
+	Unit  string
+	Value float64
+}
+
+// Logger writes EMF records to a writer (in a lambda this should be stdout so the records end up in CloudWatch Logs)
+type Logger struct {
+	Namespace string
+	mu        sync.Mutex // serialize writes so records from different go routines do not interleave
+	writer    io.Writer
+	now       func() time.Time // for testing
+}
+
+// NewLogger returns a Logger for the metrics namespace that writes to writer
+func NewLogger(namespace string, writer io.Writer) *Logger {
+	return &Logger{
+		Namespace: namespace,
+		writer:    writer,
+		now:       time.Now,
+	}
+}
+
+// Log writes an EMF record for the metrics with the dimensions.
+// The metrics are published for the whole dimension set, use LogDimensionSets() to publish for several.
+func (l *Logger) Log(dimensions []Dimension, metrics ...Metric) error {
+	dimensionNames := make([]string, len(dimensions))
+	for i := range dimensions {
+		dimensionNames[i] = dimensions[i].Name
+	}
+	return l.LogDimensionSets(dimensions, [][]string{dimensionNames}, metrics...)
+}
+
+// LogDimensionSets writes an EMF record for the metrics with the dimensions, the metrics are published
+// for each of the dimension sets (the names of the dimensions to aggregate by). Dimensions which are not part
+// of any set are written as properties of the record: they are searchable in CloudWatch Logs but do not
+// create metrics.
+func (l *Logger) LogDimensionSets(dimensions []Dimension, dimensionSets [][]string, metrics ...Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	for _, dimensionSet := range dimensionSets {
+		if len(dimensionSet) > maxDimensions {
+			return errors.Errorf("too many dimensions in %v, max is %d", dimensionSet, maxDimensions)
+		}
+	}
+
+	// written with a stream rather than a map to keep the order of the fields stable
+	stream := jsoniter.ConfigDefault.BorrowStream(nil)
+	defer jsoniter.ConfigDefault.ReturnStream(stream)
+
+	stream.WriteObjectStart()
+	stream.WriteObjectField("_aws")
+	stream.WriteVal(&metadata{
+		Timestamp: l.now().UnixNano() / int64(time.Millisecond),
+		CloudWatchMetrics: []metricDirective{
+			{
+				Namespace:  l.Namespace,
+				Dimensions: dimensionSets,
+				Metrics:    newMetricDefinitions(metrics),
+			},
+		},
+	})
+	for _, dimension := range dimensions {
+		stream.WriteMore()
+		stream.WriteObjectField(dimension.Name)
+		stream.WriteString(dimension.Value)
+	}
+	for _, metric := range metrics {
+		stream.WriteMore()
+		stream.WriteObjectField(metric.Name)
+		stream.WriteFloat64(metric.Value)
+	}
+	stream.WriteObjectEnd()
+	stream.WriteRaw("\n")
+	if stream.Error != nil {
+		return errors.Wrap(stream.Error, "failed to serialize metrics")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.writer.Write(stream.Buffer()); err != nil {
+		return errors.Wrap(err, "failed to write metrics")
+	}
+	return nil
+}
+
+// the "_aws" member of an EMF record
+type metadata struct {
+	Timestamp         int64             `json:"Timestamp"` // milliseconds since epoch
+	CloudWatchMetrics []metricDirective `json:"CloudWatchMetrics"`
+}
+
+type metricDirective struct {
+	Namespace  string             `json:"Namespace"`
+	Dimensions [][]string         `json:"Dimensions"`
+	Metrics    []metricDefinition `json:"Metrics"`
+}
+
+type metricDefinition struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit,omitempty"`
+}
+
+func newMetricDefinitions(metrics []Metric) []metricDefinition {
+	definitions := make([]metricDefinition, len(metrics))
+	for i := range metrics {
+		definitions[i] = metricDefinition{
+			Name: metrics[i].Name,
+			Unit: metrics[i].Unit,
+		}
+	}
+	return definitions
+}
@@ -0,0 +1,118 @@
+package metrics
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var refTime = time.Date(2020, 1, 1, 0, 1, 1, 0, time.UTC)
+
+func newTestLogger(buffer *bytes.Buffer) *Logger {
+	logger := NewLogger("Panther", buffer)
+	logger.now = func() time.Time { return refTime }
+	return logger
+}
+
+func TestLog(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := newTestLogger(&buffer)
+
+	err := logger.Log([]Dimension{{Name: "LogType", Value: "AWS.CloudTrail"}},
+		Metric{Name: "BytesProcessed", Unit: UnitBytes, Value: 100},
+		Metric{Name: "EventsProcessed", Unit: UnitCount, Value: 2},
+	)
+	require.NoError(t, err)
+
+	expected := `{"_aws":{"Timestamp":1577836861000,"CloudWatchMetrics":[{"Namespace":"Panther",` +
+		`"Dimensions":[["LogType"]],"Metrics":[{"Name":"BytesProcessed","Unit":"Bytes"},{"Name":"EventsProcessed","Unit":"Count"}]}]},` +
+		`"LogType":"AWS.CloudTrail","BytesProcessed":100,"EventsProcessed":2}`
+	assert.JSONEq(t, expected, buffer.String())
+	assert.True(t, strings.HasSuffix(buffer.String(), "}\n")) // one record per line
+}
+
+func TestLogDimensionSets(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := newTestLogger(&buffer)
+
+	err := logger.LogDimensionSets(
+		[]Dimension{{Name: "LogType", Value: "AWS.CloudTrail"}, {Name: "SourceIntegration", Value: "prod"}},
+		[][]string{{"LogType"}, {"LogType", "SourceIntegration"}},
+		Metric{Name: "EventsProcessed", Unit: UnitCount, Value: 2},
+	)
+	require.NoError(t, err)
+
+	expected := `{"_aws":{"Timestamp":1577836861000,"CloudWatchMetrics":[{"Namespace":"Panther",` +
+		`"Dimensions":[["LogType"],["LogType","SourceIntegration"]],"Metrics":[{"Name":"EventsProcessed","Unit":"Count"}]}]},` +
+		`"LogType":"AWS.CloudTrail","SourceIntegration":"prod","EventsProcessed":2}`
+	assert.JSONEq(t, expected, buffer.String())
+}
+
+func TestLogProperties(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := newTestLogger(&buffer)
+
+	err := logger.LogDimensionSets(
+		[]Dimension{{Name: "SourceIntegration", Value: "id"}, {Name: "SourceIntegrationLabel", Value: "prod"}},
+		[][]string{{"SourceIntegration"}},
+		Metric{Name: "ClassificationFailures", Unit: UnitCount, Value: 1},
+	)
+	require.NoError(t, err)
+
+	expected := `{"_aws":{"Timestamp":1577836861000,"CloudWatchMetrics":[{"Namespace":"Panther",` +
+		`"Dimensions":[["SourceIntegration"]],"Metrics":[{"Name":"ClassificationFailures","Unit":"Count"}]}]},` +
+		`"SourceIntegration":"id","SourceIntegrationLabel":"prod","ClassificationFailures":1}`
+	assert.JSONEq(t, expected, buffer.String())
+}
+
+func TestLogNoDimensions(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := newTestLogger(&buffer)
+
+	require.NoError(t, logger.Log(nil, Metric{Name: "ClassificationFailures", Unit: UnitCount, Value: 1}))
+	expected := `{"_aws":{"Timestamp":1577836861000,"CloudWatchMetrics":[{"Namespace":"Panther",` +
+		`"Dimensions":[[]],"Metrics":[{"Name":"ClassificationFailures","Unit":"Count"}]}]},"ClassificationFailures":1}`
+	assert.JSONEq(t, expected, buffer.String())
+}
+
+func TestLogNoMetrics(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := newTestLogger(&buffer)
+
+	require.NoError(t, logger.Log([]Dimension{{Name: "LogType", Value: "AWS.CloudTrail"}}))
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestLogTooManyDimensions(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := newTestLogger(&buffer)
+
+	dimensions := make([]Dimension, maxDimensions+1)
+	for i := range dimensions {
+		dimensions[i] = Dimension{Name: string(rune('a' + i)), Value: "value"}
+	}
+	require.Error(t, logger.Log(dimensions, Metric{Name: "EventsProcessed", Unit: UnitCount, Value: 2}))
+	assert.Equal(t, 0, buffer.Len())
+}
@@ -40,6 +40,11 @@ func (m *S3Mock) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error
 	return args.Get(0).(*s3.GetObjectOutput), args.Error(1)
 }
 
+func (m *S3Mock) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*s3.PutObjectOutput), args.Error(1)
+}
+
 func (m *S3Mock) GetBucketLocation(input *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
 	args := m.Called(input)
 	return args.Get(0).(*s3.GetBucketLocationOutput), args.Error(1)
@@ -70,6 +75,39 @@ func (m *DynamoDBMock) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.Up
 	return args.Get(0).(*dynamodb.UpdateItemOutput), args.Error(1)
 }
 
+func (m *DynamoDBMock) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.GetItemOutput), args.Error(1)
+}
+
+// BatchGetItemPages passes the mocked output to the callback as a single page
+func (m *DynamoDBMock) BatchGetItemPages(
+	input *dynamodb.BatchGetItemInput,
+	pageFunc func(*dynamodb.BatchGetItemOutput, bool) bool,
+) error {
+
+	args := m.Called(input)
+	pageFunc(args.Get(0).(*dynamodb.BatchGetItemOutput), true)
+	return args.Error(1)
+}
+
+func (m *DynamoDBMock) BatchWriteItem(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.BatchWriteItemOutput), args.Error(1)
+}
+
+func (m *DynamoDBMock) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*dynamodb.QueryOutput), args.Error(1)
+}
+
+// QueryPages passes the mocked output to the callback as a single page
+func (m *DynamoDBMock) QueryPages(input *dynamodb.QueryInput, pageFunc func(*dynamodb.QueryOutput, bool) bool) error {
+	args := m.Called(input)
+	pageFunc(args.Get(0).(*dynamodb.QueryOutput), true)
+	return args.Error(1)
+}
+
 type SqsMock struct {
 	sqsiface.SQSAPI
 	mock.Mock
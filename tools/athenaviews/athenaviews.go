@@ -70,6 +70,11 @@ func generateLogViews(tables []*awsglue.GlueTableMetadata) (sqlStatements []stri
 		return nil, err
 	}
 	sqlStatements = append(sqlStatements, sqlStatement)
+	indicatorStatements, err := generateViewsIndicators(tables)
+	if err != nil {
+		return nil, err
+	}
+	sqlStatements = append(sqlStatements, indicatorStatements...)
 	// add future views here
 	return sqlStatements, nil
 }
@@ -92,21 +97,8 @@ func generateViewAllRuleMatches(tables []*awsglue.GlueTableMetadata) (sql string
 }
 
 func generateViewAllHelper(viewName string, tables []*awsglue.GlueTableMetadata, extraColumns []gluecf.Column) (sql string, err error) {
-	// validate they all have the same partition keys
-	if len(tables) > 1 {
-		// create string of partition for comparison
-		genKey := func(partitions []awsglue.PartitionKey) (key string) {
-			for _, p := range partitions {
-				key += p.Name + p.Type
-			}
-			return key
-		}
-		referenceKey := genKey(tables[0].PartitionKeys())
-		for _, table := range tables[1:] {
-			if referenceKey != genKey(table.PartitionKeys()) {
-				return "", errors.New("all tables do not share same partition keys for generateViewAllHelper()")
-			}
-		}
+	if err := validatePartitionKeys(tables); err != nil {
+		return "", errors.Wrap(err, "generateViewAllHelper() failed")
 	}
 
 	// collect the Panther fields, add "NULL" for fields not present in some tables but present in others
@@ -128,6 +120,73 @@ func generateViewAllHelper(viewName string, tables []*awsglue.GlueTableMetadata,
 	return strings.Join(sqlLines, "\n"), nil
 }
 
+// generateViewsIndicators creates a view per p_any column unnesting the indicators of all log tables, then
+// a view over all of them. The views must be created in order.
+func generateViewsIndicators(tables []*awsglue.GlueTableMetadata) (sqlStatements []string, err error) {
+	if err := validatePartitionKeys(tables); err != nil {
+		return nil, errors.Wrap(err, "generateViewsIndicators() failed")
+	}
+
+	var viewNames []string
+	for _, view := range awsglue.IndicatorViews {
+		var selects []string
+		for _, table := range tables {
+			if !table.AnyColumns()[view.Column] {
+				continue
+			}
+			selectColumns := []string{
+				"indicator",
+				"'" + view.IndicatorType + "' AS indicator_type",
+				"p_log_type AS log_type",
+				"p_event_time",
+				"p_row_id",
+			}
+			for _, partitionKey := range table.PartitionKeys() {
+				selectColumns = append(selectColumns, partitionKey.Name)
+			}
+			selects = append(selects, fmt.Sprintf("select %s from %s.%s cross join unnest(%s) AS t(indicator)",
+				strings.Join(selectColumns, ","), table.DatabaseName(), table.TableName(), view.Column))
+		}
+		if len(selects) == 0 { // no table has the column
+			continue
+		}
+		viewNames = append(viewNames, view.ViewName)
+		sqlStatements = append(sqlStatements, fmt.Sprintf("create or replace view %s.%s as\n%s\n;\n",
+			awsglue.ViewsDatabaseName, view.ViewName, strings.Join(selects, "\n\tunion all\n")))
+	}
+	if len(viewNames) == 0 {
+		return sqlStatements, nil
+	}
+
+	selects := make([]string, len(viewNames))
+	for i, viewName := range viewNames {
+		selects[i] = fmt.Sprintf("select * from %s.%s", awsglue.ViewsDatabaseName, viewName)
+	}
+	sqlStatements = append(sqlStatements, fmt.Sprintf("create or replace view %s.%s as\n%s\n;\n",
+		awsglue.ViewsDatabaseName, awsglue.AllIndicatorsViewName, strings.Join(selects, "\n\tunion all\n")))
+	return sqlStatements, nil
+}
+
+// validatePartitionKeys returns an error if the tables do not have the same partition keys
+func validatePartitionKeys(tables []*awsglue.GlueTableMetadata) error {
+	if len(tables) > 1 {
+		// create string of partition for comparison
+		genKey := func(partitions []awsglue.PartitionKey) (key string) {
+			for _, p := range partitions {
+				key += p.Name + p.Type
+			}
+			return key
+		}
+		referenceKey := genKey(tables[0].PartitionKeys())
+		for _, table := range tables[1:] {
+			if referenceKey != genKey(table.PartitionKeys()) {
+				return errors.New("all tables do not share same partition keys")
+			}
+		}
+	}
+	return nil
+}
+
 // used to collect the UNION of all Panther "p_" fields for the view for each table
 type pantherViewColumns struct {
 	allColumns     []string                       // union of all columns over all tables as sorted slice
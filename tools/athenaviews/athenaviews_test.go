@@ -70,3 +70,55 @@ func TestGenerateLogsViewsFail(t *testing.T) {
 	require.Error(t, err)
 	require.True(t, strings.Contains(err.Error(), "no tables"))
 }
+
+func TestGenerateLogViews(t *testing.T) {
+	table1 := awsglue.NewGlueTableMetadata(models.LogData, "table1", "test table1", awsglue.GlueTableHourly, &table1Event{})
+	table2 := awsglue.NewGlueTableMetadata(models.LogData, "table2", "test table2", awsglue.GlueTableHourly, &table2Event{})
+	sqlStatements, err := generateLogViews([]*awsglue.GlueTableMetadata{table1, table2})
+	require.NoError(t, err)
+	// all_logs, all_rule_matches, then the indicator views ending with all_indicators
+	require.Len(t, sqlStatements, 2+len(awsglue.IndicatorViews)+1)
+	require.True(t, strings.HasPrefix(sqlStatements[0], "create or replace view panther_views.all_logs as"))
+	require.True(t, strings.HasPrefix(sqlStatements[1], "create or replace view panther_views.all_rule_matches as"))
+	require.True(t, strings.HasPrefix(sqlStatements[2], "create or replace view panther_views.all_ip_addresses as"))
+	require.True(t, strings.HasPrefix(sqlStatements[len(sqlStatements)-1],
+		"create or replace view panther_views.all_indicators as"))
+}
+
+func TestGenerateViewsIndicators(t *testing.T) {
+	table1 := awsglue.NewGlueTableMetadata(models.LogData, "table1", "test table1", awsglue.GlueTableHourly, &table1Event{})
+	table2 := awsglue.NewGlueTableMetadata(models.LogData, "table2", "test table2", awsglue.GlueTableHourly, &table2Event{})
+	sqlStatements, err := generateViewsIndicators([]*awsglue.GlueTableMetadata{table1, table2})
+	require.NoError(t, err)
+	require.Len(t, sqlStatements, len(awsglue.IndicatorViews)+1)
+
+	// nolint (lll)
+	expectedSQL := `create or replace view panther_views.all_ip_addresses as
+select indicator,'ip_address' AS indicator_type,p_log_type AS log_type,p_event_time,p_row_id,year,month,day,hour from panther_logs.table1 cross join unnest(p_any_ip_addresses) AS t(indicator)
+	union all
+select indicator,'ip_address' AS indicator_type,p_log_type AS log_type,p_event_time,p_row_id,year,month,day,hour from panther_logs.table2 cross join unnest(p_any_ip_addresses) AS t(indicator)
+;
+`
+	require.Equal(t, expectedSQL, sqlStatements[0])
+
+	// only table2 has the AWS columns
+	// nolint (lll)
+	expectedSQL = `create or replace view panther_views.all_aws_instance_ids as
+select indicator,'aws_instance_id' AS indicator_type,p_log_type AS log_type,p_event_time,p_row_id,year,month,day,hour from panther_logs.table2 cross join unnest(p_any_aws_instance_ids) AS t(indicator)
+;
+`
+	require.Equal(t, expectedSQL, sqlStatements[5])
+
+	// the view over all indicators is created last
+	last := sqlStatements[len(sqlStatements)-1]
+	require.True(t, strings.HasPrefix(last, "create or replace view panther_views.all_indicators as\nselect * from panther_views.all_ip_addresses\n\tunion all\n"))
+	require.True(t, strings.HasSuffix(last, "select * from panther_views.all_aws_tags\n;\n"))
+}
+
+func TestGenerateViewsIndicatorsMissingColumns(t *testing.T) {
+	// views are only created for the columns of the tables
+	table1 := awsglue.NewGlueTableMetadata(models.LogData, "table1", "test table1", awsglue.GlueTableHourly, &table1Event{})
+	sqlStatements, err := generateViewsIndicators([]*awsglue.GlueTableMetadata{table1})
+	require.NoError(t, err)
+	require.Len(t, sqlStatements, 5) // ip addresses, domain names, sha1 and md5 hashes and all indicators
+}
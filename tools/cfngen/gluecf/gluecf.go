@@ -22,9 +22,11 @@ package gluecf
 
 import (
 	"reflect"
+	"strings"
 
 	jsoniter "github.com/json-iterator/go"
 
+	alertsmodels "github.com/panther-labs/panther/api/lambda/alerts/models"
 	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
 	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers"
 	"github.com/panther-labs/panther/internal/log_analysis/log_processor/parsers/numerics"
@@ -104,8 +106,9 @@ var (
 	}
 )
 
-// Output CloudFormation for all 'tables'
-func GenerateTables(tables []*awsglue.GlueTableMetadata) (cf []byte, err error) {
+// Output CloudFormation for all 'tables', with partitionProjection Athena computes the partitions
+// so they are not created in Glue. The table of archived alerts always uses partition projection.
+func GenerateTables(tables []*awsglue.GlueTableMetadata, partitionProjection bool) (cf []byte, err error) {
 	const bucketParam = "ProcessedDataBucket"
 	parameters := make(map[string]interface{})
 	parameters[bucketParam] = &cfngen.Parameter{
@@ -116,10 +119,12 @@ func GenerateTables(tables []*awsglue.GlueTableMetadata) (cf []byte, err error)
 	logsDB := NewDatabase(CatalogIDRef, awsglue.LogProcessingDatabaseName, awsglue.LogProcessingDatabaseDescription)
 	ruleMatchDB := NewDatabase(CatalogIDRef, awsglue.RuleMatchDatabaseName, awsglue.RuleMatchDatabaseDescription)
 	viewsDB := NewDatabase(CatalogIDRef, awsglue.ViewsDatabaseName, awsglue.ViewsDatabaseDescription)
+	alertsDB := NewDatabase(CatalogIDRef, awsglue.AlertsDatabaseName, awsglue.AlertsDatabaseDescription)
 	resources := map[string]interface{}{
 		cfngen.SanitizeResourceName(awsglue.LogProcessingDatabaseName): logsDB,
 		cfngen.SanitizeResourceName(awsglue.RuleMatchDatabaseName):     ruleMatchDB,
 		cfngen.SanitizeResourceName(awsglue.ViewsDatabaseName):         viewsDB,
+		cfngen.SanitizeResourceName(awsglue.AlertsDatabaseName):        alertsDB,
 	}
 
 	// output databases
@@ -136,13 +141,27 @@ func GenerateTables(tables []*awsglue.GlueTableMetadata) (cf []byte, err error)
 			Description: awsglue.ViewsDatabaseDescription,
 			Value:       cfngen.Ref{Ref: cfngen.SanitizeResourceName(awsglue.ViewsDatabaseName)},
 		},
+		"PantherAlertsDatabase": &cfngen.Output{
+			Description: awsglue.AlertsDatabaseDescription,
+			Value:       cfngen.Ref{Ref: cfngen.SanitizeResourceName(awsglue.AlertsDatabaseName)},
+		},
 	}
 
-	addTable := func(t *awsglue.GlueTableMetadata, extraColumns ...Column) {
+	addTable := func(t *awsglue.GlueTableMetadata, partitionProjection bool) {
 		location := cfngen.Sub{Sub: "s3://${" + bucketParam + "}/" + t.Prefix()}
 
-		columns := InferJSONColumns(t.EventStruct(), GlueMappings...)
-		columns = append(columns, extraColumns...)
+		columns := TableColumns(t)
+
+		var parameters map[string]interface{}
+		if partitionProjection {
+			parameters = make(map[string]interface{})
+			for key, value := range t.PartitionProjectionParameters() {
+				parameters[key] = value
+			}
+			// escape the placeholders so they are not substituted by CloudFormation
+			template := strings.Replace(t.PartitionLocationTemplate(), "${", "${!", -1)
+			parameters[awsglue.StorageLocationTemplateParameter] = cfngen.Sub{Sub: "s3://${" + bucketParam + "}/" + template}
+		}
 
 		// NOTE: currently all sources are JSONL (could add a type to LogParserMetadata struct if we need more types)
 		resources[cfngen.SanitizeResourceName(t.DatabaseName()+t.TableName())] = NewJSONLTable(&NewTableInput{
@@ -153,22 +172,36 @@ func GenerateTables(tables []*awsglue.GlueTableMetadata) (cf []byte, err error)
 			Location:      location,
 			Columns:       columns,
 			PartitionKeys: getPartitionKeys(t),
+			Parameters:    parameters,
 		})
 	}
 
 	// add tables for all parsers, and matching tables for rule matches
 	for _, table := range tables {
-		addTable(table)
+		addTable(table, partitionProjection)
 		ruleTable := awsglue.NewGlueTableMetadata(
 			models.RuleData, table.LogType(), table.Description(), awsglue.GlueTableHourly, table.EventStruct())
-		// add a matching table for rule matches, add the columns that the rules engine appends
-		addTable(ruleTable, RuleMatchColumns...)
+		// add a matching table for rule matches
+		addTable(ruleTable, partitionProjection)
 	}
 
+	// the archiver writes alerts without creating partitions
+	addTable(alertsmodels.AlertsTable, true)
+
 	// generate CF using cfngen
 	return cfngen.NewTemplate("Panther Glue Resources", parameters, resources, outputs).CloudFormation()
 }
 
+// TableColumns returns the columns of the table inferred from its event struct,
+// rule match tables also have the columns that the rules engine appends
+func TableColumns(t *awsglue.GlueTableMetadata) []Column {
+	columns := InferJSONColumns(t.EventStruct(), GlueMappings...)
+	if t.DatabaseName() == awsglue.RuleMatchDatabaseName {
+		columns = append(columns, RuleMatchColumns...)
+	}
+	return columns
+}
+
 func getPartitionKeys(t *awsglue.GlueTableMetadata) (partitions []Column) {
 	for _, partition := range t.PartitionKeys() {
 		partitions = append(partitions, Column{
@@ -46,7 +46,7 @@ func TestTablesCloudFormation(t *testing.T) {
 	table := awsglue.NewGlueTableMetadata(models.LogData, "Log.Type", "dummy", awsglue.GlueTableHourly, &dummyParserEvent{})
 	tables := []*awsglue.GlueTableMetadata{table}
 
-	cf, err := GenerateTables(tables)
+	cf, err := GenerateTables(tables, false)
 	require.NoError(t, err)
 
 	const expectedFile = "testdata/gluecf.json.cf"
@@ -57,3 +57,32 @@ func TestTablesCloudFormation(t *testing.T) {
 	require.NoError(t, err)
 	assert.JSONEq(t, string(expected), string(cf))
 }
+
+func TestTablesCloudFormationPartitionProjection(t *testing.T) {
+	table := awsglue.NewGlueTableMetadata(models.LogData, "Log.Type", "dummy", awsglue.GlueTableHourly, &dummyParserEvent{})
+	tables := []*awsglue.GlueTableMetadata{table}
+
+	cf, err := GenerateTables(tables, true)
+	require.NoError(t, err)
+
+	const expectedFile = "testdata/gluecf_projection.json.cf"
+	// uncomment to write new expected file
+	// require.NoError(t, ioutil.WriteFile(expectedFile, cf, 0644))
+
+	expected, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(cf))
+}
+
+func TestTableColumns(t *testing.T) {
+	table := awsglue.NewGlueTableMetadata(models.LogData, "Log.Type", "dummy", awsglue.GlueTableHourly, &dummyParserEvent{})
+	columns := TableColumns(table)
+	require.Len(t, columns, 4)
+	assert.Equal(t, "FirstName", columns[0].Name)
+	assert.Equal(t, "timestamp", columns[2].Type)
+
+	ruleTable := awsglue.NewGlueTableMetadata(models.RuleData, "Log.Type", "dummy", awsglue.GlueTableHourly, &dummyParserEvent{})
+	columns = TableColumns(ruleTable)
+	require.Len(t, columns, 4+len(RuleMatchColumns))
+	assert.Equal(t, RuleMatchColumns, columns[4:])
+}
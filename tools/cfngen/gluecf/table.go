@@ -60,7 +60,8 @@ type TableInput struct {
 	Name              interface{}
 	Description       interface{} `json:",omitempty"`
 	StorageDescriptor StorageDescriptor
-	PartitionKeys     []Column `json:",omitempty"`
+	PartitionKeys     []Column               `json:",omitempty"`
+	Parameters        map[string]interface{} `json:",omitempty"`
 }
 
 type TableProperties struct {
@@ -76,7 +77,9 @@ type Table struct {
 }
 
 // Core function to create a table
-func newExternalTable(catalogID, databaseName, name, description interface{}, sd *StorageDescriptor, pks []Column) (db *Table) {
+func newExternalTable(catalogID, databaseName, name, description interface{}, sd *StorageDescriptor, pks []Column,
+	parameters map[string]interface{}) (db *Table) {
+
 	db = &Table{
 		Type: "AWS::Glue::Table",
 		Properties: TableProperties{
@@ -88,6 +91,7 @@ func newExternalTable(catalogID, databaseName, name, description interface{}, sd
 				Description:       description,
 				StorageDescriptor: *sd,
 				PartitionKeys:     pks,
+				Parameters:        parameters,
 			},
 		},
 	}
@@ -104,6 +108,7 @@ type NewTableInput struct {
 	Location      interface{}
 	Columns       []Column
 	PartitionKeys []Column
+	Parameters    map[string]interface{} // table properties, e.g., for partition projection
 }
 
 func NewParquetTable(input *NewTableInput) (db *Table) {
@@ -120,7 +125,7 @@ func NewParquetTable(input *NewTableInput) (db *Table) {
 		Columns:  input.Columns,
 	}
 
-	return newExternalTable(input.CatalogID, input.DatabaseName, input.Name, input.Description, sd, input.PartitionKeys)
+	return newExternalTable(input.CatalogID, input.DatabaseName, input.Name, input.Description, sd, input.PartitionKeys, input.Parameters)
 }
 
 func NewJSONLTable(input *NewTableInput) (db *Table) {
@@ -145,5 +150,5 @@ func NewJSONLTable(input *NewTableInput) (db *Table) {
 		Columns:  input.Columns,
 	}
 
-	return newExternalTable(input.CatalogID, input.DatabaseName, input.Name, input.Description, sd, input.PartitionKeys)
+	return newExternalTable(input.CatalogID, input.DatabaseName, input.Name, input.Description, sd, input.PartitionKeys, input.Parameters)
 }
@@ -35,8 +35,13 @@ type PantherConfig struct {
 }
 
 type Infra struct {
+	AlertEventSamples            int      `yaml:"AlertEventSamples"`
+	AlertRetentionDays           int      `yaml:"AlertRetentionDays"`
+	AlertSampleFields            []string `yaml:"AlertSampleFields"`
+	AlertSampleRedactedFields    []string `yaml:"AlertSampleRedactedFields"`
 	BaseLayerVersionArns         string   `yaml:"BaseLayerVersionArns"`
 	LogProcessorLambdaMemorySize int      `yaml:"LogProcessorLambdaMemorySize"`
+	PartitionProjection          bool     `yaml:"PartitionProjection"`
 	PipLayer                     []string `yaml:"PipLayer"`
 	PythonLayerVersionArn        string   `yaml:"PythonLayerVersionArn"`
 }
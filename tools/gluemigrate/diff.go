@@ -0,0 +1,119 @@
+package gluemigrate
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/glue"
+
+	"github.com/panther-labs/panther/tools/cfngen/gluecf"
+)
+
+// ChangeKind is the kind of a column change
+type ChangeKind int
+
+const (
+	AddColumn     ChangeKind = iota // a new column
+	UpdateComment                   // the column description changed
+	WidenType                       // the type changed, existing data can be read with the new type
+	ChangeType                      // the type changed, existing data may no longer be readable (breaking)
+	DropColumn                      // the column was removed, queries using it will fail (breaking)
+)
+
+// Change is a difference between the live table and the columns inferred from the event struct
+type Change struct {
+	Kind   ChangeKind
+	Column string
+	From   string // the live type (or comment)
+	To     string // the expected type (or comment)
+}
+
+// Breaking returns true if the change must be forced
+func (c *Change) Breaking() bool {
+	return c.Kind == ChangeType || c.Kind == DropColumn
+}
+
+func (c *Change) String() string {
+	switch c.Kind {
+	case AddColumn:
+		return fmt.Sprintf("add column %s %s", c.Column, c.To)
+	case UpdateComment:
+		return fmt.Sprintf("update comment of %s", c.Column)
+	case WidenType:
+		return fmt.Sprintf("widen column %s from %s to %s", c.Column, c.From, c.To)
+	case ChangeType:
+		return fmt.Sprintf("change type of %s from %s to %s (breaking)", c.Column, c.From, c.To)
+	default:
+		return fmt.Sprintf("drop column %s %s (breaking)", c.Column, c.From)
+	}
+}
+
+// Diff returns the changes needed to migrate the live columns to the expected columns.
+//
+// Glue column names are case insensitive.
+func Diff(expected []gluecf.Column, live []*glue.Column) ([]*Change, error) {
+	liveColumns := make(map[string]*glue.Column, len(live))
+	for _, column := range live {
+		liveColumns[strings.ToLower(aws.StringValue(column.Name))] = column
+	}
+
+	var changes []*Change
+	for _, column := range expected {
+		key := strings.ToLower(column.Name)
+		liveColumn, ok := liveColumns[key]
+		if !ok {
+			changes = append(changes, &Change{Kind: AddColumn, Column: column.Name, To: column.Type})
+			continue
+		}
+		delete(liveColumns, key)
+
+		liveType := aws.StringValue(liveColumn.Type)
+		if !strings.EqualFold(liveType, column.Type) {
+			oldType, err := parseType(liveType)
+			if err != nil {
+				return nil, err
+			}
+			newType, err := parseType(column.Type)
+			if err != nil {
+				return nil, err
+			}
+			kind := ChangeType
+			if isAdditive(oldType, newType) {
+				kind = WidenType
+			}
+			changes = append(changes, &Change{Kind: kind, Column: column.Name, From: liveType, To: column.Type})
+			continue
+		}
+
+		if liveComment := aws.StringValue(liveColumn.Comment); liveComment != column.Comment {
+			changes = append(changes, &Change{Kind: UpdateComment, Column: column.Name, From: liveComment, To: column.Comment})
+		}
+	}
+
+	// the remaining live columns are not in the event struct anymore, keep the order of the table
+	for _, column := range live {
+		if _, ok := liveColumns[strings.ToLower(aws.StringValue(column.Name))]; ok {
+			changes = append(changes, &Change{Kind: DropColumn, Column: aws.StringValue(column.Name), From: aws.StringValue(column.Type)})
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,81 @@
+package gluemigrate
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/tools/cfngen/gluecf"
+)
+
+func TestDiff(t *testing.T) {
+	expected := []gluecf.Column{
+		{Name: "name", Type: "string", Comment: "the name"},
+		{Name: "count", Type: "bigint", Comment: "the count"},
+		{Name: "detail", Type: "struct<a:int,b:string>", Comment: "the detail"},
+		{Name: "ip", Type: "array<string>", Comment: "the IPs"},
+		{Name: "new", Type: "string", Comment: "a new column"},
+	}
+	live := []*glue.Column{
+		{Name: aws.String("Name"), Type: aws.String("STRING"), Comment: aws.String("the name")},
+		{Name: aws.String("count"), Type: aws.String("int"), Comment: aws.String("the count")},
+		{Name: aws.String("detail"), Type: aws.String("struct<a:int>"), Comment: aws.String("old comment")},
+		{Name: aws.String("ip"), Type: aws.String("string"), Comment: aws.String("the IPs")},
+		{Name: aws.String("old"), Type: aws.String("string"), Comment: aws.String("an old column")},
+	}
+
+	changes, err := Diff(expected, live)
+	require.NoError(t, err)
+	assert.Equal(t, []*Change{
+		{Kind: WidenType, Column: "count", From: "int", To: "bigint"},
+		{Kind: WidenType, Column: "detail", From: "struct<a:int>", To: "struct<a:int,b:string>"},
+		{Kind: ChangeType, Column: "ip", From: "string", To: "array<string>"},
+		{Kind: AddColumn, Column: "new", To: "string"},
+		{Kind: DropColumn, Column: "old", From: "string"},
+	}, changes)
+
+	var breaking []string
+	for _, change := range changes {
+		if change.Breaking() {
+			breaking = append(breaking, change.Column)
+		}
+	}
+	assert.Equal(t, []string{"ip", "old"}, breaking)
+}
+
+func TestDiffComment(t *testing.T) {
+	expected := []gluecf.Column{{Name: "name", Type: "string", Comment: "new comment"}}
+	live := []*glue.Column{{Name: aws.String("name"), Type: aws.String("string"), Comment: aws.String("old comment")}}
+	changes, err := Diff(expected, live)
+	require.NoError(t, err)
+	assert.Equal(t, []*Change{{Kind: UpdateComment, Column: "name", From: "old comment", To: "new comment"}}, changes)
+	assert.False(t, changes[0].Breaking())
+}
+
+func TestDiffInvalidType(t *testing.T) {
+	expected := []gluecf.Column{{Name: "name", Type: "string"}}
+	live := []*glue.Column{{Name: aws.String("name"), Type: aws.String("array<string")}}
+	_, err := Diff(expected, live)
+	assert.Error(t, err)
+}
@@ -0,0 +1,285 @@
+package gluemigrate
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/aws/aws-sdk-go/service/glue/glueiface"
+	"github.com/pkg/errors"
+
+	"github.com/panther-labs/panther/pkg/awsglue"
+	"github.com/panther-labs/panther/tools/cfngen/gluecf"
+)
+
+const defaultConcurrency = 10 // the Glue API is very slow
+
+// Migrator updates the schema of Glue tables and their partitions to the columns inferred from the event structs
+type Migrator struct {
+	GlueClient  glueiface.GlueAPI
+	Force       bool // apply breaking changes, they are skipped otherwise
+	Concurrency int  // partitions updated concurrently, defaults to 10
+}
+
+// Result describes a table migration
+type Result struct {
+	Changes           []*Change // the applied changes
+	Skipped           []*Change // the breaking changes which were not forced
+	UpdatedPartitions int
+}
+
+// Plan describes the work left to migrate a table
+type Plan struct {
+	Changes            []*Change
+	OutdatedPartitions int // partitions without the schema of the live table, left by an interrupted migration
+}
+
+// Plan returns the changes needed to migrate the table and the partitions not migrated yet
+func (m *Migrator) Plan(table *awsglue.GlueTableMetadata) (*Plan, error) {
+	tableData, err := m.getTable(table)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := Diff(gluecf.TableColumns(table), tableData.StorageDescriptor.Columns)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{Changes: changes}
+	if projected(tableData) {
+		return plan, nil
+	}
+	outdated, err := m.outdatedPartitions(tableData, tableData.StorageDescriptor.Columns)
+	if err != nil {
+		return nil, err
+	}
+	plan.OutdatedPartitions = len(outdated)
+	return plan, nil
+}
+
+// Migrate applies the changes to the table and updates the schema of its partitions.
+//
+// Breaking changes are skipped unless the migration is forced, the other changes are always applied.
+// It can be resumed: partitions which already have the table schema are skipped, so an interrupted
+// migration continues where it stopped when it runs again.
+func (m *Migrator) Migrate(table *awsglue.GlueTableMetadata) (*Result, error) {
+	tableData, err := m.getTable(table)
+	if err != nil {
+		return nil, err
+	}
+	live := tableData.StorageDescriptor.Columns
+	changes, err := Diff(gluecf.TableColumns(table), live)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, change := range changes {
+		if change.Breaking() && !m.Force {
+			result.Skipped = append(result.Skipped, change)
+		} else {
+			result.Changes = append(result.Changes, change)
+		}
+	}
+	columns := live
+	if len(result.Changes) > 0 {
+		columns = migratedColumns(gluecf.TableColumns(table), live, result.Skipped)
+		if err := m.updateTable(tableData, columns); err != nil {
+			return nil, err
+		}
+	}
+
+	if projected(tableData) {
+		return result, nil // no partitions, they use the table schema
+	}
+	result.UpdatedPartitions, err = m.updatePartitions(tableData, columns)
+	return result, err
+}
+
+// migratedColumns returns the expected columns, keeping the live columns of the skipped changes
+func migratedColumns(expected []gluecf.Column, live []*glue.Column, skipped []*Change) []*glue.Column {
+	liveColumns := make(map[string]*glue.Column, len(live))
+	for _, column := range live {
+		liveColumns[strings.ToLower(aws.StringValue(column.Name))] = column
+	}
+	skippedColumns := make(map[string]*Change, len(skipped))
+	for _, change := range skipped {
+		skippedColumns[strings.ToLower(change.Column)] = change
+	}
+
+	columns := make([]*glue.Column, 0, len(expected))
+	for _, column := range expected {
+		if _, ok := skippedColumns[strings.ToLower(column.Name)]; ok {
+			columns = append(columns, liveColumns[strings.ToLower(column.Name)]) // the type change is skipped
+			continue
+		}
+		columns = append(columns, &glue.Column{
+			Name:    aws.String(column.Name),
+			Type:    aws.String(column.Type),
+			Comment: aws.String(column.Comment),
+		})
+	}
+	// the dropped columns are kept at the end, in the order of the table
+	for _, column := range live {
+		if change, ok := skippedColumns[strings.ToLower(aws.StringValue(column.Name))]; ok && change.Kind == DropColumn {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func projected(tableData *glue.TableData) bool {
+	return aws.StringValue(tableData.Parameters[awsglue.ProjectionEnabledParameter]) == "true"
+}
+
+func (m *Migrator) getTable(table *awsglue.GlueTableMetadata) (*glue.TableData, error) {
+	output, err := m.GlueClient.GetTable(&glue.GetTableInput{
+		DatabaseName: aws.String(table.DatabaseName()),
+		Name:         aws.String(table.TableName()),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get table %s.%s", table.DatabaseName(), table.TableName())
+	}
+	return output.Table, nil
+}
+
+func (m *Migrator) updateTable(tableData *glue.TableData, columns []*glue.Column) error {
+	storageDescriptor := *tableData.StorageDescriptor // copy because we will mutate
+	storageDescriptor.Columns = columns
+	_, err := m.GlueClient.UpdateTable(&glue.UpdateTableInput{
+		DatabaseName: tableData.DatabaseName,
+		TableInput: &glue.TableInput{
+			Description:       tableData.Description,
+			Name:              tableData.Name,
+			Owner:             tableData.Owner,
+			Parameters:        tableData.Parameters,
+			PartitionKeys:     tableData.PartitionKeys,
+			Retention:         tableData.Retention,
+			StorageDescriptor: &storageDescriptor,
+			TableType:         tableData.TableType,
+		},
+	})
+	return errors.Wrapf(err, "failed to update table %s.%s",
+		aws.StringValue(tableData.DatabaseName), aws.StringValue(tableData.Name))
+}
+
+// outdatedPartitions returns the partitions which do not have the columns
+func (m *Migrator) outdatedPartitions(tableData *glue.TableData, columns []*glue.Column) ([]*glue.Partition, error) {
+	var outdated []*glue.Partition
+	input := &glue.GetPartitionsInput{
+		DatabaseName: tableData.DatabaseName,
+		TableName:    tableData.Name,
+	}
+	err := m.GlueClient.GetPartitionsPages(input, func(page *glue.GetPartitionsOutput, lastPage bool) bool {
+		for _, partition := range page.Partitions {
+			if !sameColumns(partition.StorageDescriptor.Columns, columns) {
+				outdated = append(outdated, partition)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list partitions of %s.%s",
+			aws.StringValue(tableData.DatabaseName), aws.StringValue(tableData.Name))
+	}
+	return outdated, nil
+}
+
+// updatePartitions sets the table schema on the partitions which do not have it yet
+func (m *Migrator) updatePartitions(tableData *glue.TableData, columns []*glue.Column) (int, error) {
+	outdated, err := m.outdatedPartitions(tableData, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	concurrency := m.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	partitions := make(chan *glue.Partition)
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		updated  int
+		firstErr error
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partition := range partitions {
+				err := m.updatePartition(tableData, partition, columns)
+				mutex.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				} else if err == nil {
+					updated++
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	for _, partition := range outdated {
+		mutex.Lock()
+		failed := firstErr != nil
+		mutex.Unlock()
+		if failed {
+			break // the updated partitions are skipped when the migration is resumed
+		}
+		partitions <- partition
+	}
+	close(partitions)
+	wg.Wait()
+	return updated, firstErr
+}
+
+func (m *Migrator) updatePartition(tableData *glue.TableData, partition *glue.Partition, columns []*glue.Column) error {
+	// leave _everything_ the same except the schema, and the serde info
+	storageDescriptor := *partition.StorageDescriptor // copy because we will mutate
+	storageDescriptor.Columns = columns
+	storageDescriptor.SerdeInfo = tableData.StorageDescriptor.SerdeInfo
+	_, err := m.GlueClient.UpdatePartition(&glue.UpdatePartitionInput{
+		DatabaseName: tableData.DatabaseName,
+		TableName:    tableData.Name,
+		PartitionInput: &glue.PartitionInput{
+			Parameters:        partition.Parameters,
+			StorageDescriptor: &storageDescriptor,
+			Values:            partition.Values,
+		},
+		PartitionValueList: partition.Values,
+	})
+	return errors.Wrapf(err, "failed to update partition %v of %s.%s", aws.StringValueSlice(partition.Values),
+		aws.StringValue(tableData.DatabaseName), aws.StringValue(tableData.Name))
+}
+
+func sameColumns(left, right []*glue.Column) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for i := range left {
+		if !strings.EqualFold(aws.StringValue(left[i].Name), aws.StringValue(right[i].Name)) ||
+			!strings.EqualFold(aws.StringValue(left[i].Type), aws.StringValue(right[i].Type)) {
+
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,236 @@
+package gluemigrate
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/aws/aws-sdk-go/service/glue/glueiface"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
+	"github.com/panther-labs/panther/pkg/awsglue"
+)
+
+type testEvent struct {
+	Name  *string `json:"name" description:"the name"`
+	Count *int64  `json:"count" description:"the count"`
+}
+
+type mockGlue struct {
+	glueiface.GlueAPI
+	mock.Mock
+}
+
+func (m *mockGlue) GetTable(input *glue.GetTableInput) (*glue.GetTableOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*glue.GetTableOutput), args.Error(1)
+}
+
+func (m *mockGlue) UpdateTable(input *glue.UpdateTableInput) (*glue.UpdateTableOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*glue.UpdateTableOutput), args.Error(1)
+}
+
+func (m *mockGlue) GetPartitionsPages(input *glue.GetPartitionsInput, fn func(*glue.GetPartitionsOutput, bool) bool) error {
+	args := m.Called(input, fn)
+	fn(args.Get(0).(*glue.GetPartitionsOutput), true)
+	return args.Error(1)
+}
+
+func (m *mockGlue) UpdatePartition(input *glue.UpdatePartitionInput) (*glue.UpdatePartitionOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*glue.UpdatePartitionOutput), args.Error(1)
+}
+
+var testTable = awsglue.NewGlueTableMetadata(models.LogData, "Test.Logs", "Description", awsglue.GlueTableHourly, &testEvent{})
+
+func liveTable(columns ...*glue.Column) *glue.GetTableOutput {
+	return &glue.GetTableOutput{Table: &glue.TableData{
+		DatabaseName: aws.String(testTable.DatabaseName()),
+		Name:         aws.String(testTable.TableName()),
+		StorageDescriptor: &glue.StorageDescriptor{
+			Columns:   columns,
+			SerdeInfo: &glue.SerDeInfo{SerializationLibrary: aws.String("org.openx.data.jsonserde.JsonSerDe")},
+		},
+	}}
+}
+
+func column(name, typ, comment string) *glue.Column {
+	return &glue.Column{Name: aws.String(name), Type: aws.String(typ), Comment: aws.String(comment)}
+}
+
+func partition(hour string, columns ...*glue.Column) *glue.Partition {
+	return &glue.Partition{
+		Values:            aws.StringSlice([]string{"2020", "01", "01", hour}),
+		StorageDescriptor: &glue.StorageDescriptor{Columns: columns, Location: aws.String("s3://bucket/" + hour)},
+	}
+}
+
+func TestMigrateAdditive(t *testing.T) {
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(liveTable(column("name", "string", "the name")), nil).Once()
+	glueClient.On("UpdateTable", mock.Anything).Return(&glue.UpdateTableOutput{}, nil).Once()
+	migrated := []*glue.Column{column("name", "string", "the name"), column("count", "bigint", "the count")}
+	glueClient.On("GetPartitionsPages", mock.Anything, mock.Anything).Return(&glue.GetPartitionsOutput{
+		Partitions: []*glue.Partition{
+			partition("00", column("name", "string", "the name")),
+			partition("01", migrated...), // already migrated by an interrupted run
+			partition("02", column("name", "string", "the name")),
+		},
+	}, nil).Once()
+	glueClient.On("UpdatePartition", mock.Anything).Return(&glue.UpdatePartitionOutput{}, nil).Twice()
+
+	migrator := &Migrator{GlueClient: glueClient, Concurrency: 2}
+	result, err := migrator.Migrate(testTable)
+	require.NoError(t, err)
+	glueClient.AssertExpectations(t)
+	assert.Equal(t, []*Change{{Kind: AddColumn, Column: "count", To: "bigint"}}, result.Changes)
+	assert.Equal(t, 2, result.UpdatedPartitions)
+
+	tableInput := glueClient.Calls[1].Arguments.Get(0).(*glue.UpdateTableInput)
+	assert.Equal(t, migrated, tableInput.TableInput.StorageDescriptor.Columns)
+	for _, call := range glueClient.Calls[3:] {
+		input := call.Arguments.Get(0).(*glue.UpdatePartitionInput)
+		assert.Equal(t, migrated, input.PartitionInput.StorageDescriptor.Columns)
+		assert.Equal(t, "org.openx.data.jsonserde.JsonSerDe", *input.PartitionInput.StorageDescriptor.SerdeInfo.SerializationLibrary)
+		assert.NotEqual(t, "01", *input.PartitionValueList[3])
+	}
+}
+
+func TestMigrateBreaking(t *testing.T) {
+	live := liveTable(column("name", "string", "the name"), column("count", "string", "the count"))
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(live, nil).Once()
+	glueClient.On("GetPartitionsPages", mock.Anything, mock.Anything).Return(&glue.GetPartitionsOutput{}, nil).Once()
+
+	migrator := &Migrator{GlueClient: glueClient}
+	result, err := migrator.Migrate(testTable)
+	require.NoError(t, err)
+	assert.Empty(t, result.Changes)
+	assert.Equal(t, []*Change{{Kind: ChangeType, Column: "count", From: "string", To: "bigint"}}, result.Skipped)
+	glueClient.AssertExpectations(t) // the table is not updated
+
+	// forced
+	glueClient = &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(live, nil).Once()
+	glueClient.On("UpdateTable", mock.Anything).Return(&glue.UpdateTableOutput{}, nil).Once()
+	glueClient.On("GetPartitionsPages", mock.Anything, mock.Anything).Return(&glue.GetPartitionsOutput{}, nil).Once()
+	migrator.GlueClient, migrator.Force = glueClient, true
+	result, err = migrator.Migrate(testTable)
+	require.NoError(t, err)
+	assert.Len(t, result.Changes, 1)
+	assert.Empty(t, result.Skipped)
+	glueClient.AssertExpectations(t)
+}
+
+func TestMigrateSkipsBreaking(t *testing.T) {
+	live := liveTable(
+		column("name", "string", "the old name"),
+		column("removed", "string", "not in the struct anymore"),
+		column("count", "string", "the count"),
+	)
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(live, nil).Once()
+	glueClient.On("UpdateTable", mock.Anything).Return(&glue.UpdateTableOutput{}, nil).Once()
+	glueClient.On("GetPartitionsPages", mock.Anything, mock.Anything).Return(&glue.GetPartitionsOutput{}, nil).Once()
+
+	result, err := (&Migrator{GlueClient: glueClient}).Migrate(testTable)
+	require.NoError(t, err)
+	glueClient.AssertExpectations(t)
+	assert.Equal(t, []*Change{{Kind: UpdateComment, Column: "name", From: "the old name", To: "the name"}}, result.Changes)
+	assert.Equal(t, []*Change{
+		{Kind: ChangeType, Column: "count", From: "string", To: "bigint"},
+		{Kind: DropColumn, Column: "removed", From: "string"},
+	}, result.Skipped)
+
+	// the additive change is applied, the live columns of the breaking changes are kept
+	tableInput := glueClient.Calls[1].Arguments.Get(0).(*glue.UpdateTableInput)
+	assert.Equal(t, []*glue.Column{
+		column("name", "string", "the name"),
+		column("count", "string", "the count"),
+		column("removed", "string", "not in the struct anymore"),
+	}, tableInput.TableInput.StorageDescriptor.Columns)
+}
+
+func TestMigrateProjected(t *testing.T) {
+	live := liveTable(column("name", "string", "the name"))
+	live.Table.Parameters = map[string]*string{awsglue.ProjectionEnabledParameter: aws.String("true")}
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(live, nil).Once()
+	glueClient.On("UpdateTable", mock.Anything).Return(&glue.UpdateTableOutput{}, nil).Once()
+
+	result, err := (&Migrator{GlueClient: glueClient}).Migrate(testTable)
+	require.NoError(t, err)
+	assert.Len(t, result.Changes, 1)
+	assert.Equal(t, 0, result.UpdatedPartitions)
+	glueClient.AssertExpectations(t)
+}
+
+func TestMigratePartitionError(t *testing.T) {
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(liveTable(column("name", "string", "the name")), nil).Once()
+	glueClient.On("UpdateTable", mock.Anything).Return(&glue.UpdateTableOutput{}, nil).Once()
+	glueClient.On("GetPartitionsPages", mock.Anything, mock.Anything).Return(&glue.GetPartitionsOutput{
+		Partitions: []*glue.Partition{partition("00", column("name", "string", "the name"))},
+	}, nil).Once()
+	glueClient.On("UpdatePartition", mock.Anything).Return(&glue.UpdatePartitionOutput{}, errors.New("throttled")).Once()
+
+	result, err := (&Migrator{GlueClient: glueClient}).Migrate(testTable)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "throttled")
+	assert.Equal(t, 0, result.UpdatedPartitions) // the table was migrated, the partition is retried on resume
+	glueClient.AssertExpectations(t)
+}
+
+func TestPlan(t *testing.T) {
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(liveTable(column("name", "string", "the name")), nil).Once()
+	glueClient.On("GetPartitionsPages", mock.Anything, mock.Anything).Return(&glue.GetPartitionsOutput{}, nil).Once()
+
+	plan, err := (&Migrator{GlueClient: glueClient}).Plan(testTable)
+	require.NoError(t, err)
+	assert.Equal(t, []*Change{{Kind: AddColumn, Column: "count", To: "bigint"}}, plan.Changes)
+	assert.Equal(t, 0, plan.OutdatedPartitions)
+	glueClient.AssertExpectations(t)
+}
+
+func TestPlanInterrupted(t *testing.T) {
+	// the table was migrated, one of its partitions was not
+	migrated := []*glue.Column{column("name", "string", "the name"), column("count", "bigint", "the count")}
+	glueClient := &mockGlue{}
+	glueClient.On("GetTable", mock.Anything).Return(liveTable(migrated...), nil).Once()
+	glueClient.On("GetPartitionsPages", mock.Anything, mock.Anything).Return(&glue.GetPartitionsOutput{
+		Partitions: []*glue.Partition{
+			partition("00", migrated...),
+			partition("01", column("name", "string", "the name")),
+		},
+	}, nil).Once()
+
+	plan, err := (&Migrator{GlueClient: glueClient}).Plan(testTable)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Changes)
+	assert.Equal(t, 1, plan.OutdatedPartitions)
+	glueClient.AssertExpectations(t)
+}
@@ -0,0 +1,195 @@
+package gluemigrate
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Parsing of Glue (Hive) column types, to tell additive schema changes from breaking ones
+
+import (
+	"fmt"
+	"strings"
+)
+
+// glueType is a parsed column type such as "bigint", "array<string>" or "struct<a:int,b:map<string,string>>"
+type glueType struct {
+	name   string        // the primitive type, or array, map or struct
+	params []*glueType   // the element type of arrays, the key and value types of maps
+	fields []structField // the fields of structs
+}
+
+type structField struct {
+	name string
+	typ  *glueType
+}
+
+// widenings lists the primitive types which can be read as a wider type
+var widenings = map[string][]string{
+	"tinyint":  {"smallint", "int", "bigint"},
+	"smallint": {"int", "bigint"},
+	"int":      {"bigint"},
+	"float":    {"double"},
+}
+
+func parseType(s string) (*glueType, error) {
+	p := &typeParser{input: strings.ToLower(strings.TrimSpace(s))}
+	result, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.input) {
+		return nil, fmt.Errorf("invalid type %q: unexpected %q", s, p.input[p.pos:])
+	}
+	return result, nil
+}
+
+type typeParser struct {
+	input string
+	pos   int
+}
+
+func (p *typeParser) parse() (*glueType, error) {
+	name := p.token()
+	if name == "" {
+		return nil, p.errorf("missing type")
+	}
+	result := &glueType{name: name}
+	switch name {
+	case "array", "map", "struct":
+		if err := p.expect('<'); err != nil {
+			return nil, err
+		}
+		for {
+			if name == "struct" {
+				fieldName := p.token()
+				if fieldName == "" {
+					return nil, p.errorf("missing field name")
+				}
+				if err := p.expect(':'); err != nil {
+					return nil, err
+				}
+				fieldType, err := p.parse()
+				if err != nil {
+					return nil, err
+				}
+				result.fields = append(result.fields, structField{name: fieldName, typ: fieldType})
+			} else {
+				param, err := p.parse()
+				if err != nil {
+					return nil, err
+				}
+				result.params = append(result.params, param)
+			}
+			if p.pos < len(p.input) && p.input[p.pos] == ',' {
+				p.pos++
+				continue
+			}
+			break
+		}
+		if err := p.expect('>'); err != nil {
+			return nil, err
+		}
+		if (name == "array" && len(result.params) != 1) || (name == "map" && len(result.params) != 2) {
+			return nil, p.errorf("wrong number of type parameters for " + name)
+		}
+	default:
+		// parameterized primitives such as decimal(10,2) or varchar(64)
+		if p.pos < len(p.input) && p.input[p.pos] == '(' {
+			end := strings.IndexByte(p.input[p.pos:], ')')
+			if end < 0 {
+				return nil, p.errorf("unterminated type parameters")
+			}
+			result.name += p.input[p.pos : p.pos+end+1]
+			p.pos += end + 1
+		}
+	}
+	return result, nil
+}
+
+// token reads a type or field name
+func (p *typeParser) token() string {
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune("<>(),:", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	return strings.Trim(strings.TrimSpace(p.input[start:p.pos]), "`")
+}
+
+func (p *typeParser) expect(c byte) error {
+	if p.pos >= len(p.input) || p.input[p.pos] != c {
+		return p.errorf(fmt.Sprintf("expected %q", c))
+	}
+	p.pos++
+	return nil
+}
+
+func (p *typeParser) errorf(message string) error {
+	return fmt.Errorf("invalid type %q at position %d: %s", p.input, p.pos, message)
+}
+
+// isAdditive returns true if data written with the old type can be read with the new type:
+// the same type, a wider number type, or structs with new fields
+func isAdditive(oldType, newType *glueType) bool {
+	if oldType.name != newType.name {
+		for _, wider := range widenings[oldType.name] {
+			if wider == newType.name {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch oldType.name {
+	case "array":
+		return isAdditive(oldType.params[0], newType.params[0])
+	case "map":
+		return oldType.params[0].String() == newType.params[0].String() && isAdditive(oldType.params[1], newType.params[1])
+	case "struct":
+		newFields := make(map[string]*glueType, len(newType.fields))
+		for _, field := range newType.fields {
+			newFields[field.name] = field.typ
+		}
+		for _, field := range oldType.fields {
+			newField, ok := newFields[field.name]
+			if !ok || !isAdditive(field.typ, newField) {
+				return false
+			}
+		}
+		return true
+	default:
+		return true
+	}
+}
+
+func (t *glueType) String() string {
+	switch t.name {
+	case "array", "map":
+		params := make([]string, len(t.params))
+		for i, param := range t.params {
+			params[i] = param.String()
+		}
+		return t.name + "<" + strings.Join(params, ",") + ">"
+	case "struct":
+		fields := make([]string, len(t.fields))
+		for i, field := range t.fields {
+			fields[i] = field.name + ":" + field.typ.String()
+		}
+		return "struct<" + strings.Join(fields, ",") + ">"
+	default:
+		return t.name
+	}
+}
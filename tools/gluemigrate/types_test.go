@@ -0,0 +1,75 @@
+package gluemigrate
+
+/**
+ * Panther is a Cloud-Native SIEM for the Modern Security Team.
+ * Copyright (C) 2020 Panther Labs Inc
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseType(t *testing.T) {
+	for _, s := range []string{
+		"bigint",
+		"decimal(10,2)",
+		"array<string>",
+		"map<string,array<int>>",
+		"struct<a:int,b:struct<c:string,d:map<string,double>>>",
+	} {
+		parsed, err := parseType(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, s, parsed.String())
+	}
+
+	parsed, err := parseType("STRUCT<`Name`:String>")
+	require.NoError(t, err)
+	assert.Equal(t, "struct<name:string>", parsed.String())
+
+	for _, s := range []string{"", "array<string", "array<string,int>", "map<string>", "struct<:int>", "bigint>"} {
+		_, err := parseType(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestIsAdditive(t *testing.T) {
+	for _, test := range []struct {
+		from, to string
+		additive bool
+	}{
+		{"string", "string", true},
+		{"int", "bigint", true},
+		{"float", "double", true},
+		{"bigint", "int", false},
+		{"string", "bigint", false},
+		{"array<int>", "array<bigint>", true},
+		{"array<int>", "array<string>", false},
+		{"map<string,int>", "map<string,bigint>", true},
+		{"map<string,int>", "map<int,int>", false},
+		{"struct<a:int>", "struct<a:int,b:string>", true},
+		{"struct<a:int,b:string>", "struct<a:int>", false},
+		{"struct<a:int>", "struct<a:string>", false},
+	} {
+		from, err := parseType(test.from)
+		require.NoError(t, err)
+		to, err := parseType(test.to)
+		require.NoError(t, err)
+		assert.Equal(t, test.additive, isAdditive(from, to), "%s -> %s", test.from, test.to)
+	}
+}
@@ -236,12 +236,12 @@ func (b Build) cfn() error {
 		return err
 	}
 
-	if err := generateGlueTables(); err != nil {
+	settings, err := config.Settings()
+	if err != nil {
 		return err
 	}
 
-	settings, err := config.Settings()
-	if err != nil {
+	if err := generateGlueTables(settings); err != nil {
 		return err
 	}
 
@@ -33,7 +33,7 @@ import (
 )
 
 // Generate Glue tables for log processor output as CloudFormation
-func generateGlueTables() error {
+func generateGlueTables(settings *config.PantherConfig) error {
 	outDir := filepath.Dir(glueTemplate)
 	if err := os.MkdirAll(outDir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory %s: %v", outDir, err)
@@ -47,7 +47,7 @@ func generateGlueTables() error {
 
 	tableResources := registry.AvailableTables()
 	logger.Debugf("deploy: cfngen: loaded %d glue tables", len(tableResources))
-	cf, err := gluecf.GenerateTables(tableResources)
+	cf, err := gluecf.GenerateTables(tableResources, settings.Infra.PartitionProjection)
 	if err != nil {
 		return fmt.Errorf("failed to generate Glue Data Catalog CloudFormation template: %v", err)
 	}
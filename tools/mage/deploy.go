@@ -378,6 +378,7 @@ func deployMainStacks(awsSession *session.Session, settings *config.PantherConfi
 			"SqsKeyId":               outputs["QueueEncryptionKeyId"],
 			"UserPoolId":             outputs["UserPoolId"],
 
+			"AlertRetentionDays":         strconv.Itoa(settings.Infra.AlertRetentionDays),
 			"CloudWatchLogRetentionDays": strconv.Itoa(settings.Monitoring.CloudWatchLogRetentionDays),
 			"Debug":                      strconv.FormatBool(settings.Monitoring.Debug),
 			"LayerVersionArns":           settings.Infra.BaseLayerVersionArns,
@@ -405,11 +406,16 @@ func deployMainStacks(awsSession *session.Session, settings *config.PantherConfi
 	go func(result chan string) {
 		deployTemplate(awsSession, logAnalysisTemplate, sourceBucket, logAnalysisStack, map[string]string{
 			"AnalysisApiId":         outputs["AnalysisApiId"],
+			"AthenaResultsBucket":   outputs["AthenaResultsBucket"],
 			"ProcessedDataBucket":   outputs["ProcessedDataBucket"],
 			"ProcessedDataTopicArn": outputs["ProcessedDataTopicArn"],
 			"PythonLayerVersionArn": outputs["PythonLayerVersionArn"],
 			"SqsKeyId":              outputs["QueueEncryptionKeyId"],
 
+			"AlertEventSamples":            strconv.Itoa(settings.Infra.AlertEventSamples),
+			"AlertRetentionDays":           strconv.Itoa(settings.Infra.AlertRetentionDays),
+			"AlertSampleFields":            strings.Join(settings.Infra.AlertSampleFields, ","),
+			"AlertSampleRedactedFields":    strings.Join(settings.Infra.AlertSampleRedactedFields, ","),
 			"CloudWatchLogRetentionDays":   strconv.Itoa(settings.Monitoring.CloudWatchLogRetentionDays),
 			"Debug":                        strconv.FormatBool(settings.Monitoring.Debug),
 			"LayerVersionArns":             settings.Infra.BaseLayerVersionArns,
@@ -21,6 +21,7 @@ package mage
 import (
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/service/cloudformation"
@@ -31,6 +32,7 @@ import (
 	"github.com/panther-labs/panther/api/lambda/core/log_analysis/log_processor/models"
 	"github.com/panther-labs/panther/internal/log_analysis/log_processor/registry"
 	"github.com/panther-labs/panther/pkg/awsglue"
+	"github.com/panther-labs/panther/tools/gluemigrate"
 )
 
 // targets for managing Glue tables
@@ -96,3 +98,77 @@ func (t Glue) Sync() {
 		}
 	}
 }
+
+// Migrate Migrate glue table and partition schemas to the current parser structs
+func (t Glue) Migrate() {
+	awsSession, err := getSession()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	migrator := &gluemigrate.Migrator{GlueClient: glue.New(awsSession)}
+
+	enteredText := promptUser("Enter regex to select a subset of tables (or <enter> for all tables): ", regexValidator)
+	matchTableName, _ := regexp.Compile(enteredText) // no error check already validated
+
+	var tables []*awsglue.GlueTableMetadata
+	for _, table := range registry.AvailableTables() {
+		// the rule match tables share the same structure as the logs
+		ruleTable := awsglue.NewGlueTableMetadata(
+			models.RuleData, table.LogType(), table.Description(), awsglue.GlueTableHourly, table.EventStruct())
+		for _, candidate := range []*awsglue.GlueTableMetadata{table, ruleTable} {
+			if matchTableName.MatchString(candidate.DatabaseName() + "." + candidate.TableName()) {
+				tables = append(tables, candidate)
+			}
+		}
+	}
+
+	// show the plan before changing anything
+	var migrations []*awsglue.GlueTableMetadata
+	breaking := false
+	for _, table := range tables {
+		name := table.DatabaseName() + "." + table.TableName()
+		plan, err := migrator.Plan(table)
+		if err != nil {
+			logger.Fatalf("failed planning %s: %v", name, err)
+		}
+		if len(plan.Changes) == 0 && plan.OutdatedPartitions == 0 {
+			continue
+		}
+		migrations = append(migrations, table)
+		logger.Infof("%s:", name)
+		for _, change := range plan.Changes {
+			if change.Breaking() {
+				breaking = true
+				logger.Warnf("  %s", change)
+			} else {
+				logger.Infof("  %s", change)
+			}
+		}
+		if plan.OutdatedPartitions > 0 {
+			logger.Infof("  update the schema of %d partitions", plan.OutdatedPartitions)
+		}
+	}
+	if len(migrations) == 0 {
+		logger.Info("all tables are up to date")
+		return
+	}
+
+	if breaking {
+		logger.Warn("breaking changes make existing data unreadable with the new schema")
+		result := promptUser("Apply the breaking changes? (yes|no) ", nonemptyValidator)
+		migrator.Force = strings.ToLower(result) == "yes"
+	}
+
+	for _, table := range migrations {
+		name := table.DatabaseName() + "." + table.TableName()
+		logger.Infof("migrating %s", name)
+		result, err := migrator.Migrate(table)
+		if err != nil {
+			logger.Fatalf("failed migrating %s (run again to resume): %v", name, err)
+		}
+		for _, change := range result.Skipped {
+			logger.Warnf("skipped %s: %s", name, change)
+		}
+		logger.Infof("migrated %s: %d changes, %d partitions updated", name, len(result.Changes), result.UpdatedPartitions)
+	}
+}